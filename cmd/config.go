@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mark3labs/kit/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	configShowOriginFlag bool
+	configScopeFlag      string
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and edit layered configuration",
+	Long: `Inspect and edit Kit's configuration.
+
+Settings are merged from these sources, lowest precedence first:
+
+  system   /etc/kit/kit.yml (or $KIT_SYSTEM_CONFIG_DIR/kit.yml)
+  user     ~/.kit.yml
+  project  .kit.yml at the repository root
+  local    .kit.local.yml at the repository root (keep it out of git)
+  env      KIT_* environment variables
+  flags    command-line flags
+
+Maps merge key by key; each entry of mcpServers and customModels replaces a
+same-named entry from a lower layer whole. Set a nested key to null to
+remove it. An explicit --config file replaces all four file layers.
+
+Examples:
+  kit config list --show-origin
+  kit config get mcpServers --show-origin
+  kit config set model anthropic/claude-opus-4-1 --scope project
+  kit config set budget.daily 5 --scope local
+  kit config validate`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Write a setting to one config layer",
+	Long: `Write a setting to the config file for --scope (default user). The key is a
+dotted path such as budget.daily; the value is parsed as YAML, so true, 5
+and [a, b] are stored as a bool, a number and a list.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List every configured setting",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigList,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the merged configuration for errors",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigValidate,
+}
+
+func init() {
+	configGetCmd.Flags().BoolVar(&configShowOriginFlag, "show-origin", false, "show where each value came from")
+	configListCmd.Flags().BoolVar(&configShowOriginFlag, "show-origin", false, "show where each value came from")
+	configSetCmd.Flags().StringVar(&configScopeFlag, "scope", string(config.ScopeUser), "layer to write: system, user, project, or local")
+
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// configEntry is one effective setting and where it came from.
+type configEntry struct {
+	Key    string
+	Value  any
+	Origin string
+}
+
+// fileOrigins returns the origin of every setting read from a config file.
+// With --config, that file is the only source.
+func fileOrigins() (map[string]config.Origin, []config.Layer, error) {
+	var layers []config.Layer
+	if configFile != "" {
+		data, err := config.ReadLayerFile(configFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		layers = []config.Layer{{Path: configFile, Data: data}}
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, nil, err
+		}
+		if layers, err = config.LoadLayers(cwd); err != nil {
+			return nil, nil, err
+		}
+	}
+	_, origins := config.MergeLayers(layers)
+	return origins, layers, nil
+}
+
+// collectConfigEntries returns the settings that are set anywhere above the
+// built-in defaults, sorted by key, with the highest-precedence source of
+// each: a changed flag, a KIT_* variable, then the winning config file.
+func collectConfigEntries(v *viper.Viper, flags *pflag.FlagSet, origins map[string]config.Origin) []configEntry {
+	keys := make(map[string]bool)
+	for k := range origins {
+		keys[k] = true
+	}
+	for _, k := range v.AllKeys() {
+		keys[k] = true
+	}
+
+	var entries []configEntry
+	for k := range keys {
+		origin := ""
+		if f := flags.Lookup(k); f != nil && f.Changed {
+			origin = "flag --" + f.Name
+		} else if env := configEnvVar(k); os.Getenv(env) != "" {
+			origin = "env " + env
+		} else if o, ok := origins[k]; ok {
+			origin = o.String()
+		}
+		if origin == "" {
+			continue
+		}
+		entries = append(entries, configEntry{Key: k, Value: v.Get(k), Origin: origin})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// configEnvVar returns the KIT_* variable viper consults for key.
+func configEnvVar(key string) string {
+	return "KIT_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// formatConfigValue renders a setting on one line: strings as-is, anything
+// else as JSON.
+func formatConfigValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func writeConfigEntries(w io.Writer, entries []configEntry, showOrigin bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, e := range entries {
+		if showOrigin {
+			_, _ = fmt.Fprintf(tw, "%s\t%s=%s\n", e.Origin, e.Key, formatConfigValue(e.Value))
+		} else {
+			_, _ = fmt.Fprintf(tw, "%s=%s\n", e.Key, formatConfigValue(e.Value))
+		}
+	}
+	return tw.Flush()
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	key := strings.ToLower(args[0])
+	if !viper.IsSet(key) {
+		return fmt.Errorf("%s is not set", args[0])
+	}
+	value := viper.Get(key)
+
+	if !configShowOriginFlag {
+		if _, isMap := value.(map[string]any); isMap {
+			out, err := yaml.Marshal(value)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(out))
+			return nil
+		}
+		fmt.Println(formatConfigValue(value))
+		return nil
+	}
+
+	origins, _, err := fileOrigins()
+	if err != nil {
+		return err
+	}
+	var matched []configEntry
+	for _, e := range collectConfigEntries(viper.GetViper(), cmd.Flags(), origins) {
+		if e.Key == key || strings.HasPrefix(e.Key, key+".") {
+			matched = append(matched, e)
+		}
+	}
+	if len(matched) == 0 {
+		matched = []configEntry{{Key: key, Value: value, Origin: "default"}}
+	}
+	return writeConfigEntries(os.Stdout, matched, true)
+}
+
+func runConfigSet(_ *cobra.Command, args []string) error {
+	path := configFile
+	if path == "" {
+		scope, err := config.ParseScope(configScopeFlag)
+		if err != nil {
+			return err
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if path, err = config.ScopePath(scope, cwd); err != nil {
+			return err
+		}
+	}
+	if err := config.SetValue(path, args[0], args[1]); err != nil {
+		return err
+	}
+	fmt.Printf("Set %s in %s\n", args[0], path)
+	return nil
+}
+
+func runConfigList(cmd *cobra.Command, _ []string) error {
+	origins, _, err := fileOrigins()
+	if err != nil {
+		return err
+	}
+	return writeConfigEntries(os.Stdout, collectConfigEntries(viper.GetViper(), cmd.Flags(), origins), configShowOriginFlag)
+}
+
+func runConfigValidate(_ *cobra.Command, _ []string) error {
+	_, layers, err := fileOrigins()
+	if err != nil {
+		return err
+	}
+	if _, err := config.LoadAndValidateConfig(); err != nil {
+		return err
+	}
+	if len(layers) == 0 {
+		fmt.Println("No config files found; built-in defaults are valid.")
+		return nil
+	}
+	for _, l := range layers {
+		scope := string(l.Scope)
+		if scope == "" {
+			scope = "config"
+		}
+		fmt.Printf("%-8s %s\n", scope, l.Path)
+	}
+	fmt.Println("Configuration is valid.")
+	return nil
+}
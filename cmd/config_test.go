@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mark3labs/kit/internal/config"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestCollectConfigEntries(t *testing.T) {
+	v := viper.New()
+	v.SetDefault("stream", true)
+	v.Set("model", "a/b")
+	v.Set("max-steps", 4)
+	v.Set("theme", "dracula")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("model", "", "")
+	if err := flags.Parse([]string{"--model", "a/b"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KIT_MAX_STEPS", "4")
+
+	origins := map[string]config.Origin{
+		"model":     {Scope: config.ScopeUser, Path: "/home/u/.kit.yml"},
+		"max-steps": {Scope: config.ScopeProject, Path: "/repo/.kit.yml"},
+		"theme":     {Scope: config.ScopeLocal, Path: "/repo/.kit.local.yml"},
+	}
+	entries := collectConfigEntries(v, flags, origins)
+
+	var buf bytes.Buffer
+	if err := writeConfigEntries(&buf, entries, true); err != nil {
+		t.Fatal(err)
+	}
+	want := "env KIT_MAX_STEPS           max-steps=4\n" +
+		"flag --model                model=a/b\n" +
+		"local /repo/.kit.local.yml  theme=dracula\n"
+	if buf.String() != want {
+		t.Errorf("entries =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestFormatConfigValue(t *testing.T) {
+	tests := []struct {
+		in   any
+		want string
+	}{
+		{"text", "text"},
+		{5, "5"},
+		{true, "true"},
+		{[]any{"a", "b"}, `["a","b"]`},
+	}
+	for _, tt := range tests {
+		if got := formatConfigValue(tt.in); got != tt.want {
+			t.Errorf("formatConfigValue(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -20,10 +20,11 @@ var extensionsListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List discovered extensions and their handlers",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		loaded, err := extensions.LoadExtensions(viper.GetStringSlice("extension"))
+		loaded, err := extensions.LoadExtensionsWithTrust(viper.GetStringSlice("extension"), extensionTrustGate())
 		if err != nil {
 			return fmt.Errorf("loading extensions: %w", err)
 		}
+		defer extensions.NewRunner(loaded).Close()
 
 		if len(loaded) == 0 {
 			fmt.Println("No extensions found.")
@@ -31,13 +32,14 @@ var extensionsListCmd = &cobra.Command{
 			fmt.Println("Extension search paths:")
 			fmt.Println("  ~/.config/kit/extensions/*.go        (global)")
 			fmt.Println("  .kit/extensions/*.go                 (project)")
+			fmt.Println("  .kit/extensions/*/kit-extension.json (project, any language)")
 			fmt.Println()
 			fmt.Println("Run 'kit extensions init' to create an example extension.")
 			return nil
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		_, _ = fmt.Fprintln(w, "EXTENSION\tEVENT\tHANDLERS\tTOOLS\tCOMMANDS")
+		_, _ = fmt.Fprintln(w, "EXTENSION\tEVENT\tHANDLERS\tTOOLS\tCOMMANDS\tCAPABILITIES")
 
 		for _, ext := range loaded {
 			totalHandlers := 0
@@ -47,18 +49,18 @@ var extensionsListCmd = &cobra.Command{
 			first := true
 			for event, handlers := range ext.Handlers {
 				if first {
-					_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n",
-						ext.Path, event, len(handlers), len(ext.Tools), len(ext.Commands))
+					_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\n",
+						ext.Path, event, len(handlers), len(ext.Tools), len(ext.Commands), ext.Capabilities)
 					first = false
 				} else {
-					_, _ = fmt.Fprintf(w, "\t%s\t%d\t\t\n",
+					_, _ = fmt.Fprintf(w, "\t%s\t%d\t\t\t\n",
 						event, len(handlers))
 				}
 			}
 			if first {
 				// Extension loaded but registered no handlers
-				_, _ = fmt.Fprintf(w, "%s\t(none)\t0\t%d\t%d\n",
-					ext.Path, len(ext.Tools), len(ext.Commands))
+				_, _ = fmt.Fprintf(w, "%s\t(none)\t0\t%d\t%d\t%s\n",
+					ext.Path, len(ext.Tools), len(ext.Commands), ext.Capabilities)
 			}
 		}
 
@@ -70,10 +72,11 @@ var extensionsValidateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate all extension files can be loaded",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		loaded, err := extensions.LoadExtensions(viper.GetStringSlice("extension"))
+		loaded, err := extensions.LoadExtensionsWithTrust(viper.GetStringSlice("extension"), extensionTrustGate())
 		if err != nil {
 			return fmt.Errorf("validation failed: %w", err)
 		}
+		defer extensions.NewRunner(loaded).Close()
 
 		fmt.Printf("Loaded %d extension(s) successfully\n", len(loaded))
 		for _, ext := range loaded {
@@ -81,8 +84,8 @@ var extensionsValidateCmd = &cobra.Command{
 			for _, h := range ext.Handlers {
 				total += len(h)
 			}
-			fmt.Printf("  %s (%d handlers, %d tools, %d commands)\n",
-				ext.Path, total, len(ext.Tools), len(ext.Commands))
+			fmt.Printf("  %s (%d handlers, %d tools, %d commands; capabilities: %s)\n",
+				ext.Path, total, len(ext.Tools), len(ext.Commands), ext.Capabilities)
 		}
 		return nil
 	},
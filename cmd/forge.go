@@ -0,0 +1,603 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+// This file holds the forge-agnostic core of `kit github run`, `kit gitlab
+// run` and `kit gitea run`: a CI job reads the event that mentioned /kit,
+// checks the author may trigger Kit, runs the agent headlessly against the
+// issue or change request, and answers with a progress comment and —
+// when the agent modified files — commits pushed to the change request's
+// branch or a new change request. Each forge supplies the event parsing and
+// API calls behind the forge interface.
+
+// commandToken is the mention that triggers Kit from a comment, mirroring the
+// guard in the generated CI configuration.
+const commandToken = "/kit"
+
+// subprocessTimeout bounds each git/gh invocation so a stalled network call or
+// an unexpected auth prompt cannot hang the Actions job indefinitely.
+const subprocessTimeout = 30 * time.Second
+
+// agentTimeout bounds the headless agent run so a runaway turn cannot block the
+// job forever. GitHub Actions jobs have their own ceiling, but a tighter bound
+// keeps feedback fast and costs predictable.
+const agentTimeout = 20 * time.Minute
+
+// botName / botEmail are the dedicated identity commits are attributed to, so
+// Kit's changes are clearly distinguishable from human authors in history.
+const (
+	botName  = "kit-agent[bot]"
+	botEmail = "kit-agent[bot]@users.noreply.github.com"
+)
+
+// dryRunEnvVars enable dry-run like --dry-run, one per forge.
+var dryRunEnvVars = []string{"KIT_GITHUB_DRY_RUN", "KIT_GITLAB_DRY_RUN", "KIT_GITEA_DRY_RUN"}
+
+// errNoTrigger is wrapped by forge.loadTrigger when the event is not an
+// actionable /kit comment.
+var errNoTrigger = errors.New("not a /kit trigger")
+
+var (
+	forgeRunModel       string
+	forgeRunDryRun      bool
+	forgeRunPushToForks bool
+)
+
+// forge is a code host Kit runs against from CI. Its methods are only called
+// outside dry-run and when available reports true, except for loadTrigger
+// and authorized, which every run needs.
+type forge interface {
+	// name identifies the forge in commands and logs: "github", "gitlab" or
+	// "gitea".
+	name() string
+	// ciEnv is the variable the forge's CI sets to "true" in every job.
+	ciEnv() string
+	// available reports whether the CLI or credentials the API calls need
+	// are present.
+	available() bool
+	// loadTrigger reads the CI event. Events that are not /kit comments
+	// return an error wrapping errNoTrigger.
+	loadTrigger() (*trigger, error)
+	// authorized reports whether the comment author has write access.
+	authorized(ctx context.Context, tr *trigger) bool
+	// react adds a reaction ("eyes", "confused" or "rocket") to the trigger
+	// comment.
+	react(ctx context.Context, tr *trigger, content string)
+	// postComment replies to the trigger and returns the reply's ID, or 0
+	// when posting failed.
+	postComment(ctx context.Context, tr *trigger, body string) int64
+	// editComment replaces the body of a reply postComment returned.
+	editComment(ctx context.Context, tr *trigger, id int64, body string)
+	// fetchChange loads the head branch and changed files of the trigger's
+	// change request.
+	fetchChange(ctx context.Context, tr *trigger) *pullContext
+	// discussion renders the comments on the issue or change request.
+	discussion(ctx context.Context, tr *trigger) string
+	// setupGit gives git credentials to fetch from and push to repoURL.
+	// They stay in the global git config, so it is only called once the
+	// agent has exited.
+	setupGit(ctx context.Context) error
+	// gitAuth returns `git -c` arguments that give a single git command the
+	// credentials setupGit would install, leaving none behind.
+	gitAuth() []string
+	// repoURL is the clone URL of a repository ("owner/name" or a GitLab
+	// project path).
+	repoURL(repo string) string
+	// commitURL links a commit in a repository.
+	commitURL(repo, sha string) string
+	// openChange opens a change request from branch onto the default branch
+	// and returns its URL.
+	openChange(ctx context.Context, tr *trigger, branch, title, body string) string
+}
+
+// reviewForge is a forge that answers `/kit review` with a native review
+// instead of a comment.
+type reviewForge interface {
+	forge
+	submitReview(ctx context.Context, tr *trigger, payload reviewPayload, out io.Writer) error
+}
+
+// trigger normalises a single invocation across forges and event kinds.
+type trigger struct {
+	forge         string // forge name, see forge.name
+	repo          string // "owner/name", or the GitLab project path
+	defaultBranch string
+	number        int    // issue or change request number (GitLab: iid)
+	isPR          bool   // true when the target is a pull or merge request
+	review        bool   // a `/kit review` on a pull request: answer with a GitHub review
+	headBranch    string // PR branch checked out for follow-up commits, once checked out
+	commentID     int64  // triggering comment id (for reactions)
+	commentKind   string // API path segment of the comment: "issues", "pulls", "merge_requests"
+	threadID      string // GitLab discussion the trigger note belongs to
+	author        string
+	authorID      int64 // GitLab user id, for the membership check
+	association   string
+	request       string // the user's instruction (comment body minus the token)
+	title         string
+	body          string
+}
+
+// target names the kind of thing that was commented on.
+func (tr *trigger) target() string {
+	switch {
+	case !tr.isPR:
+		return "issue"
+	default:
+		return tr.changeName()
+	}
+}
+
+// changeName is what the forge calls a change request.
+func (tr *trigger) changeName() string {
+	if tr.forge == "gitlab" {
+		return "merge request"
+	}
+	return "pull request"
+}
+
+// ref is how the forge refers to the target in text: "#42", or "!7" for a
+// GitLab merge request.
+func (tr *trigger) ref() string {
+	if tr.isPR && tr.forge == "gitlab" {
+		return fmt.Sprintf("!%d", tr.number)
+	}
+	return fmt.Sprintf("#%d", tr.number)
+}
+
+// forgeLive reports whether f's API calls should run, rather than be logged.
+func forgeLive(f forge) bool {
+	return !forgeDryRun() && f.available()
+}
+
+// runForge handles one CI event for f.
+func runForge(cmd *cobra.Command, f forge) error {
+	ctx := cmd.Context()
+	prefix := f.name() + " run: "
+
+	if os.Getenv(f.ciEnv()) != "true" && !forgeDryRun() {
+		return fmt.Errorf("kit %s run is meant to run inside CI (set %s=true or pass --dry-run)", f.name(), f.ciEnv())
+	}
+
+	tr, err := f.loadTrigger()
+	if errors.Is(err, errNoTrigger) {
+		// Not an actionable trigger (the CI guard normally prevents this).
+		log.Info(prefix+"nothing to do", "reason", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	tr.forge = f.name()
+	rf, canReview := f.(reviewForge)
+	tr.review = tr.review && canReview
+
+	if !f.authorized(ctx, tr) {
+		log.Warn(prefix+"ignoring /kit from unauthorized author",
+			"author", tr.author, "association", tr.association)
+		return nil
+	}
+
+	model := resolveRunModel()
+	log.Info(prefix+"handling trigger",
+		"repo", tr.repo, "number", tr.number, "pr", tr.isPR, "author", tr.author, "model", model)
+
+	// React with 👀 and post the progress comment so the human sees Kit
+	// picked up the request.
+	react(ctx, f, tr, "eyes")
+	progress := startProgress(ctx, f, tr)
+
+	var pr *pullContext
+	if tr.isPR && forgeLive(f) {
+		pr = f.fetchChange(ctx, tr)
+	}
+	// Changes requested on a change request go onto its own branch when
+	// Kit may push there; otherwise they get a new change request.
+	if pushesToHead(tr, pr) {
+		if err := checkoutPullHead(ctx, f, pr); err != nil {
+			log.Warn(prefix+"cannot check out the change request's branch, changes will go to a new one", "err", err)
+		} else {
+			tr.headBranch = pr.headRef
+		}
+	}
+	gathered := gatherContext(ctx, f, tr, pr)
+	prompt := buildPrompt(tr, gathered)
+
+	// A checked-out head is the change author's code, so the agent ignores
+	// the project config, context files, skills and agents it carries.
+	progress.working(ctx)
+	response, runErr := runAgent(ctx, model, prompt, tr.headBranch != "")
+	if runErr != nil {
+		progress.update(ctx, "⚠️ Kit hit an error while processing this request:\n\n```\n"+runErr.Error()+"\n```")
+		react(ctx, f, tr, "confused")
+		return runErr
+	}
+
+	response = strings.TrimSpace(response)
+	if response == "" {
+		response = "Kit finished without a textual response."
+	}
+
+	if tr.review {
+		review := buildReview(pr, parseAgentReview(response))
+		if err := rf.submitReview(ctx, tr, review, cmd.OutOrStdout()); err != nil {
+			log.Error(prefix+"submitting review failed, posting it as a comment", "err", err)
+			progress.update(ctx, reviewAsComment(review))
+		} else {
+			progress.update(ctx, "✅ Submitted a review.")
+		}
+		react(ctx, f, tr, "rocket")
+		return nil
+	}
+
+	var (
+		push  *branchPush
+		prURL string
+	)
+	if hasUncommittedChanges(ctx) {
+		if tr.headBranch != "" {
+			var err error
+			if push, err = pushToPullRequest(ctx, f, tr, pr, response); err != nil {
+				progress.update(ctx, "⚠️ Kit could not push its changes to `"+tr.headBranch+"`:\n\n```\n"+err.Error()+"\n```\n\n"+response)
+				react(ctx, f, tr, "confused")
+				return err
+			}
+		} else {
+			prURL = openChangeRequest(ctx, f, tr, response)
+		}
+	}
+
+	progress.update(ctx, doneComment(tr, response, push, prURL))
+	react(ctx, f, tr, "rocket")
+	return nil
+}
+
+// resolveRunModel picks the model: --model flag, then $MODEL, then the default.
+func resolveRunModel() string {
+	if m := strings.TrimSpace(forgeRunModel); m != "" {
+		return m
+	}
+	if m := strings.TrimSpace(os.Getenv("MODEL")); m != "" {
+		return m
+	}
+	return defaultGitHubModel
+}
+
+// forgeDryRun reports whether side effects should be logged instead of run.
+func forgeDryRun() bool {
+	if forgeRunDryRun {
+		return true
+	}
+	for _, name := range dryRunEnvVars {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// addRunFlags registers the flags shared by the forge run commands.
+func addRunFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&forgeRunModel, "model", "m", "", "provider/model the agent should use (falls back to $MODEL, then a default)")
+	cmd.Flags().BoolVar(&forgeRunDryRun, "dry-run", false, "log git/API side effects and skip the agent run instead of executing them")
+	cmd.Flags().BoolVar(&forgeRunPushToForks, "push-to-forks", false, "push follow-up commits to change requests from forks that allow it, instead of opening a new one")
+}
+
+// react adds a reaction to the trigger comment, logging it in dry-run.
+func react(ctx context.Context, f forge, tr *trigger, content string) {
+	if !forgeLive(f) {
+		log.Info(f.name()+" run: [dry-run] react", "content", content, "comment", tr.commentID)
+		return
+	}
+	f.react(ctx, tr, content)
+}
+
+// extractRequest pulls the instruction text out of a comment body that mentions
+// the command token. It only recognizes the token at the start of a line
+// (mirroring the workflow guard) or at the very end, so incidental mid-sentence
+// mentions like "please review /kit behavior" do not trigger the handler. It
+// returns the remainder of the matching line as the request.
+func extractRequest(body string) (string, bool) {
+	for line := range strings.SplitSeq(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		var rest string
+		switch {
+		case trimmed == commandToken:
+			return "", true
+		case strings.HasPrefix(trimmed, commandToken+" "):
+			rest = trimmed[len(commandToken):]
+		case strings.HasSuffix(trimmed, " "+commandToken):
+			return "", true
+		default:
+			continue
+		}
+		return strings.TrimSpace(rest), true
+	}
+	return "", false
+}
+
+// gatherContext assembles the issue thread or change request to give the
+// agent. It always includes the title/body from the event payload, and —
+// outside dry-run, when the forge is available — enriches with the comment
+// thread and, for a change request, the changed files and their hunks from
+// pr.
+func gatherContext(ctx context.Context, f forge, tr *trigger, pr *pullContext) string {
+	var b strings.Builder
+	target := tr.target()
+	fmt.Fprintf(&b, "%s%s %s: %s\n", strings.ToUpper(target[:1]), target[1:], tr.ref(), tr.title)
+	if strings.TrimSpace(tr.body) != "" {
+		fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(tr.body))
+	}
+
+	if !forgeLive(f) {
+		return b.String()
+	}
+
+	if pr != nil && len(pr.files) > 0 {
+		fmt.Fprintf(&b, "\n%s", formatPullFiles(pr.files))
+	}
+	if pr != nil && len(pr.prior) > 0 {
+		b.WriteString("\n## Your earlier review comments\n")
+		for _, c := range pr.prior {
+			fmt.Fprintf(&b, "- %s:%d: %s\n", c.Path, c.Line, strings.TrimSpace(strings.TrimSuffix(c.Body, reviewMarker)))
+		}
+	}
+	if comments := f.discussion(ctx, tr); comments != "" {
+		fmt.Fprintf(&b, "\n## Comments\n%s\n", strings.TrimSpace(comments))
+	}
+	return b.String()
+}
+
+// buildPrompt constructs the instruction sent to the agent.
+func buildPrompt(tr *trigger, gathered string) string {
+	target := tr.target()
+	request := tr.request
+	if request == "" {
+		request = "(no explicit instruction — review the " + target + " and respond helpfully)"
+	}
+	site := "GitHub"
+	switch tr.forge {
+	case "gitlab":
+		site = "GitLab"
+	case "gitea":
+		site = "Gitea"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are Kit, operating as an automated collaborator on the %s repository %s.\n\n", site, tr.repo)
+	fmt.Fprintf(&b, "@%s (access: %s) triggered you on %s %s with this request:\n\n", tr.author, tr.association, target, tr.ref())
+	fmt.Fprintf(&b, "%s\n\n", request)
+	fmt.Fprintf(&b, "## Context\n%s\n\n", strings.TrimSpace(gathered))
+	if tr.review {
+		b.WriteString(reviewInstructions)
+		return b.String()
+	}
+	if tr.headBranch != "" {
+		fmt.Fprintf(&b, "Carry out the request. You are on the %s's branch %s. If you modify ", target, tr.headBranch)
+		b.WriteString("files, they will be committed and pushed to that branch automatically, so you ")
+		b.WriteString("do not need to commit or push yourself. Finish with a concise summary of what you did.")
+		return b.String()
+	}
+	b.WriteString("Carry out the request. If you modify files, they will be committed to a new ")
+	fmt.Fprintf(&b, "branch and a %s will be opened automatically, so you do not need to ", tr.changeName())
+	b.WriteString("commit or push yourself. Finish with a concise summary of what you did.")
+	return b.String()
+}
+
+// runAgent drives the agent headlessly by invoking this same binary in quiet,
+// ephemeral mode against the constructed prompt, and returns its response.
+// When untrusted, the agent ignores everything the working tree could
+// configure (see --untrusted-project). In dry-run it returns a canned
+// response without spawning anything.
+func runAgent(ctx context.Context, model, prompt string, untrusted bool) (string, error) {
+	if forgeDryRun() {
+		log.Info("[dry-run] would run agent", "model", model, "promptChars", len(prompt))
+		return "[dry-run] agent response", nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil || exe == "" {
+		exe = "kit"
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, agentTimeout)
+	defer cancel()
+
+	args := []string{"--quiet", "--no-session", "--no-extensions"}
+	if untrusted {
+		args = append(args, "--untrusted-project")
+	}
+	if model != "" {
+		args = append(args, "--model", model)
+	}
+	args = append(args, prompt)
+
+	cmd := exec.CommandContext(runCtx, exe, args...)
+	cmd.Stderr = os.Stderr // surface agent progress/errors in the CI log
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("agent run failed: %w", err)
+	}
+	return string(out), nil
+}
+
+// hasUncommittedChanges reports whether the agent produced working-tree changes.
+func hasUncommittedChanges(ctx context.Context) bool {
+	if forgeDryRun() {
+		return os.Getenv("KIT_GITHUB_FAKE_DIRTY") != ""
+	}
+	return strings.TrimSpace(gitOutput(ctx, "status", "--porcelain")) != ""
+}
+
+// openChangeRequest commits the working tree as kit-agent[bot], pushes a
+// branch, and opens a pull or merge request. It returns its URL, or "" on
+// failure / dry-run.
+func openChangeRequest(ctx context.Context, f forge, tr *trigger, summary string) string {
+	branch := fmt.Sprintf("kit/issue-%d-%d", tr.number, time.Now().Unix())
+
+	_ = runGit(ctx, "checkout", "-b", branch)
+	_ = runGit(ctx, "add", "-A")
+	_ = runGit(ctx, "-c", "user.name="+botName, "-c", "user.email="+botEmail,
+		"commit", "-m", "kit: address "+tr.ref())
+
+	// The CI checkout leaves no push credentials behind (GitHub's
+	// `persist-credentials: false`, GitLab's read-only job token), so the
+	// forge sets them up and the branch is pushed by URL.
+	if forgeLive(f) {
+		_ = f.setupGit(ctx)
+	}
+	_ = runGit(ctx, "push", f.repoURL(tr.repo), "HEAD:"+branch)
+
+	title := "kit: changes for " + tr.ref()
+	body := fmt.Sprintf("Automated changes from Kit in response to %s.\n\n%s", tr.ref(), summary)
+	if !forgeLive(f) {
+		log.Info(f.name()+" run: [dry-run] would open a change request", "branch", branch, "base", tr.defaultBranch)
+		return ""
+	}
+	return f.openChange(ctx, tr, branch, title, body)
+}
+
+// pushesToHead reports whether the agent's changes for tr go onto the change
+// request's own branch. Review runs push nothing, and a branch in a fork is
+// only pushed to with --push-to-forks.
+func pushesToHead(tr *trigger, pr *pullContext) bool {
+	if pr == nil || !pr.canPush || tr.review {
+		return false
+	}
+	return pr.headRepo == tr.repo || forgeRunPushToForks
+}
+
+// checkoutPullHead checks out the change request's head branch so the
+// agent's changes can be pushed back to it. The branch is fetched by URL,
+// which works the same for branches in the base repository and in forks.
+// The fetch is authenticated on its own command line so no credentials are
+// left for the agent that runs next.
+func checkoutPullHead(ctx context.Context, f forge, pr *pullContext) error {
+	var args []string
+	if forgeLive(f) {
+		args = f.gitAuth()
+	}
+	args = append(args, "fetch", "--no-tags", f.repoURL(pr.headRepo), pr.headRef)
+	if err := runGit(ctx, args...); err != nil {
+		return err
+	}
+	return runGit(ctx, "checkout", "-B", pr.headRef, "FETCH_HEAD")
+}
+
+// pushToPullRequest commits the working tree as kit-agent[bot] on the checked
+// out change request branch and pushes it back to the branch's repository.
+// It runs after the agent has exited, so this is where push credentials are
+// set up.
+func pushToPullRequest(ctx context.Context, f forge, tr *trigger, pr *pullContext, summary string) (*branchPush, error) {
+	if err := runGit(ctx, "add", "-A"); err != nil {
+		return nil, err
+	}
+	if err := runGit(ctx, "-c", "user.name="+botName, "-c", "user.email="+botEmail,
+		"commit", "-m", commitMessage(tr, summary)); err != nil {
+		return nil, err
+	}
+	if forgeLive(f) {
+		if err := f.setupGit(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if err := runGit(ctx, "push", f.repoURL(pr.headRepo), "HEAD:"+pr.headRef); err != nil {
+		return nil, err
+	}
+	push := &branchPush{branch: pr.headRef}
+	if !forgeDryRun() {
+		push.sha = strings.TrimSpace(gitOutput(ctx, "rev-parse", "HEAD"))
+		push.url = f.commitURL(pr.headRepo, push.sha)
+	}
+	return push, nil
+}
+
+// commitMessage describes a follow-up commit: the request as the subject and
+// the agent's summary as the body.
+func commitMessage(tr *trigger, summary string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(tr.request), "\n")
+	if subject == "" {
+		subject = "address " + tr.ref()
+	}
+	if runes := []rune(subject); len(runes) > 66 {
+		subject = strings.TrimSpace(string(runes[:63])) + "..."
+	}
+	if runes := []rune(strings.TrimSpace(summary)); len(runes) > 2000 {
+		summary = string(runes[:2000]) + "…"
+	}
+	return fmt.Sprintf("kit: %s\n\n%s\n\nRequested by @%s in %s.", subject, strings.TrimSpace(summary), tr.author, tr.ref())
+}
+
+// gitCredentialHelper returns a git credential helper that answers with the
+// token in the tokenEnv environment variable. The helper names the variable
+// rather than embedding the token, so the token never lands in git config or
+// a command line.
+func gitCredentialHelper(tokenEnv string) string {
+	return fmt.Sprintf(`!f() { test "$1" = get && echo username=oauth2 && echo "password=$%s"; }; f`, tokenEnv)
+}
+
+// setupGitCredentials installs gitCredentialHelper(tokenEnv) globally, which
+// is what the CI job's git uses.
+func setupGitCredentials(ctx context.Context, tokenEnv string) error {
+	if os.Getenv(tokenEnv) == "" {
+		return fmt.Errorf("%s is not set; Kit needs it to push", tokenEnv)
+	}
+	return runCmd(ctx, "git", "config", "--global", "credential.helper", gitCredentialHelper(tokenEnv))
+}
+
+// gitCredentialArgs is gitAuth for forges whose setupGit installs
+// gitCredentialHelper(tokenEnv).
+func gitCredentialArgs(tokenEnv string) []string {
+	return []string{"-c", "credential.helper=", "-c", "credential.helper=" + gitCredentialHelper(tokenEnv)}
+}
+
+// --- thin subprocess helpers -------------------------------------------------
+
+func commandExists(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// runGit runs a mutating git command, logging instead of executing in dry-run.
+func runGit(ctx context.Context, args ...string) error {
+	if forgeDryRun() {
+		log.Info("[dry-run] git", "args", strings.Join(args, " "))
+		return nil
+	}
+	return runCmd(ctx, "git", args...)
+}
+
+// gitOutput runs a read-only git command and returns its stdout.
+func gitOutput(ctx context.Context, args ...string) string {
+	cmdCtx, cancel := context.WithTimeout(ctx, subprocessTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(cmdCtx, "git", args...).Output()
+	if err != nil {
+		log.Error("git failed", "args", strings.Join(args, " "), "err", err)
+		return ""
+	}
+	return string(out)
+}
+
+// runCmd runs a command for its side effects, surfacing failures in the log
+// and to callers that care.
+func runCmd(ctx context.Context, name string, args ...string) error {
+	cmdCtx, cancel := context.WithTimeout(ctx, subprocessTimeout)
+	defer cancel()
+	if out, err := exec.CommandContext(cmdCtx, name, args...).CombinedOutput(); err != nil {
+		log.Error("command failed", "cmd", name, "err", err, "output", strings.TrimSpace(string(out)))
+		return fmt.Errorf("%s %s: %w", name, args[0], err)
+	}
+	return nil
+}
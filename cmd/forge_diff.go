@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// This file holds the forge-agnostic view of a change request's diff: the
+// changed files with their hunks, rendered for the agent with the new-side
+// line numbers that review comments anchor to.
+
+// maxPatchChars caps the patch text given to the agent. Files past the cap
+// are still listed, without their hunks.
+const maxPatchChars = 150_000
+
+// prFile is a file changed by a pull or merge request, in the shape of the
+// GitHub pull request files API.
+type prFile struct {
+	Filename  string `json:"filename"`
+	Status    string `json:"status"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Patch     string `json:"patch"` // empty for binary or very large files
+}
+
+// pullContext is what Kit fetches about a pull or merge request before
+// running the agent.
+type pullContext struct {
+	headSHA  string
+	headRef  string // the change request's branch
+	headRepo string // repository headRef lives in; differs from the base for forks
+	// canPush reports whether Kit may push follow-up commits to headRef: the
+	// branch is in the base repository, or a fork that lets maintainers push.
+	canPush bool
+	files   []prFile
+	prior   []reviewLineComment // Kit's inline comments from earlier reviews
+}
+
+// decodeJSONLines decodes a stream of JSON values, as `gh api --jq '.[]'`
+// prints them, up to the first that does not decode.
+func decodeJSONLines[T any](data string) []T {
+	var out []T
+	dec := json.NewDecoder(strings.NewReader(data))
+	for {
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Warn("skipping undecodable API output", "err", err)
+			}
+			return out
+		}
+		out = append(out, v)
+	}
+}
+
+// formatPullFiles renders the changed files and their hunks for the agent.
+// Every line that exists on the new side is prefixed with its line number,
+// which is what review comments are anchored to; removed lines get none.
+func formatPullFiles(files []prFile) string {
+	var b strings.Builder
+	b.WriteString("## Changed files\n")
+	for _, f := range files {
+		fmt.Fprintf(&b, "- %s (%s, +%d -%d)\n", f.Filename, f.Status, f.Additions, f.Deletions)
+	}
+	b.WriteString("\n## Patches\nLeft column: line number in the new version of the file.\n")
+	budget := maxPatchChars
+	for _, f := range files {
+		fmt.Fprintf(&b, "\n### %s\n", f.Filename)
+		switch {
+		case f.Patch == "":
+			b.WriteString("(no patch: binary or too large)\n")
+		case len(f.Patch) > budget:
+			b.WriteString("(patch omitted: the change is too large to show every hunk)\n")
+		default:
+			budget -= len(f.Patch)
+			fmt.Fprintf(&b, "```diff\n%s```\n", numberPatch(f.Patch))
+		}
+	}
+	return b.String()
+}
+
+// numberPatch prefixes each line of a unified diff patch with its new-side
+// line number.
+func numberPatch(patch string) string {
+	var b strings.Builder
+	line := 0
+	for text := range strings.SplitSeq(strings.TrimSuffix(patch, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(text, "@@"):
+			line = hunkStart(text)
+			fmt.Fprintf(&b, "%s\n", text)
+		case strings.HasPrefix(text, "-"), strings.HasPrefix(text, `\`):
+			fmt.Fprintf(&b, "%6s %s\n", "", text)
+		default:
+			fmt.Fprintf(&b, "%6d %s\n", line, text)
+			line++
+		}
+	}
+	return b.String()
+}
+
+// commentableLines maps each new-side line of a patch that a review comment
+// may be anchored to — added and context lines — to the index of its hunk.
+func commentableLines(patch string) map[int]int {
+	lines := make(map[int]int)
+	hunk, line := -1, 0
+	for text := range strings.SplitSeq(strings.TrimSuffix(patch, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(text, "@@"):
+			hunk++
+			line = hunkStart(text)
+		case hunk < 0, strings.HasPrefix(text, "-"), strings.HasPrefix(text, `\`):
+		default:
+			lines[line] = hunk
+			line++
+		}
+	}
+	return lines
+}
+
+// hunkStart returns the first new-side line of a hunk header such as
+// "@@ -10,6 +12,8 @@ func f() {".
+func hunkStart(header string) int {
+	_, after, ok := strings.Cut(header, " +")
+	if !ok {
+		return 0
+	}
+	end := strings.IndexAny(after, ", ")
+	if end < 0 {
+		end = len(after)
+	}
+	n, _ := strconv.Atoi(after[:end])
+	return n
+}
+
+// decodeJSONPages decodes a stream of JSON arrays, as `glab api --paginate`
+// prints them, into one slice.
+func decodeJSONPages[T any](data string) []T {
+	var out []T
+	for _, page := range decodeJSONLines[[]T](data) {
+		out = append(out, page...)
+	}
+	return out
+}
+
+// patchStats counts the added and removed lines of a patch: hunks only, with
+// no file headers.
+func patchStats(patch string) (additions, deletions int) {
+	for line := range strings.SplitSeq(patch, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			additions++
+		case strings.HasPrefix(line, "-"):
+			deletions++
+		}
+	}
+	return additions, deletions
+}
+
+// splitUnifiedDiff splits a `git diff` of a whole change request into its
+// files, for forges whose API only serves the combined diff.
+func splitUnifiedDiff(diff string) []prFile {
+	var (
+		files []prFile
+		cur   *prFile
+		patch []string
+	)
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		cur.Patch = strings.Join(patch, "\n")
+		cur.Additions, cur.Deletions = patchStats(cur.Patch)
+		files = append(files, *cur)
+		cur, patch = nil, nil
+	}
+	for line := range strings.SplitSeq(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			cur = &prFile{Status: "modified"}
+			// "diff --git a/x b/x": the new path is the second half. Paths
+			// with spaces are set again from the +++ line below.
+			if _, after, ok := strings.Cut(line, " b/"); ok {
+				cur.Filename = after
+			}
+		case cur == nil:
+		case len(patch) > 0:
+			patch = append(patch, line)
+		case strings.HasPrefix(line, "@@"):
+			patch = append(patch, line)
+		case strings.HasPrefix(line, "new file mode"):
+			cur.Status = "added"
+		case strings.HasPrefix(line, "deleted file mode"):
+			cur.Status = "removed"
+		case strings.HasPrefix(line, "rename from"):
+			cur.Status = "renamed"
+		case strings.HasPrefix(line, "+++ b/"):
+			cur.Filename = strings.TrimPrefix(line, "+++ b/")
+		}
+	}
+	flush()
+	return files
+}
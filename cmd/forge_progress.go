@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// progressComment is the single comment Kit keeps on the triggering issue or
+// change request while it handles a request. The first update posts it;
+// later ones edit it in place, so a run leaves one comment that moves from
+// picked up to working to done instead of a trail of status comments.
+type progressComment struct {
+	f  forge
+	tr *trigger
+	id int64 // 0 until the comment was posted
+}
+
+// startProgress posts the "picked up" comment for tr.
+func startProgress(ctx context.Context, f forge, tr *trigger) *progressComment {
+	p := &progressComment{f: f, tr: tr}
+	p.update(ctx, fmt.Sprintf("👀 Picked up the request from @%s.", tr.author))
+	return p
+}
+
+// working marks the request as in progress, linking the CI run when known.
+func (p *progressComment) working(ctx context.Context) {
+	body := "⏳ Working on it…"
+	if url := ciRunURL(); url != "" {
+		body += fmt.Sprintf(" ([view run](%s))", url)
+	}
+	p.update(ctx, body)
+}
+
+// update replaces the comment's body, posting the comment if this is the
+// first update (or the earlier post failed).
+func (p *progressComment) update(ctx context.Context, body string) {
+	if !forgeLive(p.f) {
+		log.Info(p.f.name()+" run: [dry-run] progress comment", "target", p.tr.ref(), "id", p.id, "chars", len(body))
+		return
+	}
+	if p.id == 0 {
+		p.id = p.f.postComment(ctx, p.tr, body)
+		return
+	}
+	p.f.editComment(ctx, p.tr, p.id, body)
+}
+
+// branchPush records follow-up commits pushed to a change request's branch.
+type branchPush struct {
+	branch string
+	sha    string // pushed commit; empty in dry-run
+	url    string // link to the commit
+}
+
+// doneComment is the final body of the progress comment: the agent's
+// response followed by where its changes went.
+func doneComment(tr *trigger, response string, push *branchPush, prURL string) string {
+	var b strings.Builder
+	b.WriteString("✅ Done.\n\n")
+	b.WriteString(response)
+	switch {
+	case push != nil && push.sha != "":
+		fmt.Fprintf(&b, "\n\n---\nPushed [`%s`](%s) to `%s`.", shortSHA(push.sha), push.url, push.branch)
+	case push != nil:
+		fmt.Fprintf(&b, "\n\n---\nPushed the changes to `%s`.", push.branch)
+	case prURL != "":
+		fmt.Fprintf(&b, "\n\n---\nOpened a %s with the changes: %s", tr.changeName(), prURL)
+	}
+	return b.String()
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// ciRunURL links the current CI run — a GitLab job, or a GitHub or Gitea
+// Actions run — or returns "" outside one.
+func ciRunURL() string {
+	if url := os.Getenv("CI_JOB_URL"); url != "" {
+		return url
+	}
+	repo, id := os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID")
+	if repo == "" || id == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/actions/runs/%s", githubServerURL(), repo, id)
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPRHeadPushable(t *testing.T) {
+	tests := []struct {
+		name string
+		head prHead
+		want bool
+	}{
+		{"same repository", prHead{Ref: "feature", Repo: "acme/widgets"}, true},
+		{"fork allowing edits", prHead{Ref: "feature", Repo: "bob/widgets", MaintainerCanModify: true}, true},
+		{"fork without edits", prHead{Ref: "feature", Repo: "bob/widgets"}, false},
+		{"deleted fork", prHead{Ref: "feature", MaintainerCanModify: true}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.head.pushable("acme/widgets"); got != tt.want {
+			t.Errorf("%s: pushable = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPushesToHead(t *testing.T) {
+	t.Cleanup(func() { forgeRunPushToForks = false })
+	tr := &trigger{repo: "acme/widgets", isPR: true}
+	same := &pullContext{headRef: "feature", headRepo: "acme/widgets", canPush: true}
+	fork := &pullContext{headRef: "feature", headRepo: "bob/widgets", canPush: true}
+
+	if !pushesToHead(tr, same) {
+		t.Error("a branch in the base repository should be pushed to")
+	}
+	if pushesToHead(tr, fork) {
+		t.Error("a fork should not be pushed to without --push-to-forks")
+	}
+	if pushesToHead(&trigger{repo: "acme/widgets", review: true}, same) {
+		t.Error("a review should not push")
+	}
+	forgeRunPushToForks = true
+	if !pushesToHead(tr, fork) {
+		t.Error("--push-to-forks should allow a fork that accepts maintainer edits")
+	}
+	if pushesToHead(tr, &pullContext{headRef: "feature", headRepo: "bob/widgets"}) {
+		t.Error("--push-to-forks should not override a fork that refuses edits")
+	}
+}
+
+func TestPushToPullRequest_DryRun(t *testing.T) {
+	setupEvent(t, issueCommentEvent)
+	t.Setenv("GITHUB_SERVER_URL", "")
+	tr := &trigger{number: 7, author: "bob", request: "fix the failing test\nin cache_test.go", headBranch: "feature"}
+	pr := &pullContext{headRef: "feature", headRepo: "bob/widgets", canPush: true}
+
+	if err := checkoutPullHead(context.Background(), githubForge{}, pr); err != nil {
+		t.Fatalf("checkoutPullHead: %v", err)
+	}
+	push, err := pushToPullRequest(context.Background(), githubForge{}, tr, pr, "Fixed the off-by-one.")
+	if err != nil {
+		t.Fatalf("pushToPullRequest: %v", err)
+	}
+	if push.branch != "feature" || push.sha != "" {
+		t.Errorf("push = %+v", push)
+	}
+	if got := (githubForge{}).repoURL(pr.headRepo); got != "https://github.com/bob/widgets.git" {
+		t.Errorf("repoURL = %q", got)
+	}
+
+	msg := commitMessage(tr, "Fixed the off-by-one.")
+	if want := "kit: fix the failing test\n\nFixed the off-by-one.\n\nRequested by @bob in #7."; msg != want {
+		t.Errorf("commitMessage = %q, want %q", msg, want)
+	}
+	if msg := commitMessage(&trigger{number: 3}, ""); !strings.HasPrefix(msg, "kit: address #3\n") {
+		t.Errorf("commitMessage without a request = %q", msg)
+	}
+}
+
+func TestDoneComment(t *testing.T) {
+	t.Setenv("GITHUB_SERVER_URL", "https://ghe.example.com/")
+	tr := &trigger{forge: "github", isPR: true}
+	sha := "0123456789abcdef"
+	pushed := doneComment(tr, "Fixed it.", &branchPush{branch: "feature", sha: sha, url: (githubForge{}).commitURL("bob/widgets", sha)}, "")
+	for _, want := range []string{"✅ Done.", "Fixed it.", "[`0123456`](https://ghe.example.com/bob/widgets/commit/0123456789abcdef) to `feature`"} {
+		if !strings.Contains(pushed, want) {
+			t.Errorf("done comment missing %q:\n%s", want, pushed)
+		}
+	}
+	if got := doneComment(tr, "Done.", nil, "https://github.com/acme/widgets/pull/9"); !strings.Contains(got, "Opened a pull request with the changes: https://github.com/acme/widgets/pull/9") {
+		t.Errorf("done comment with a new PR = %q", got)
+	}
+	if got := doneComment(tr, "Nothing to change.", nil, ""); strings.Contains(got, "---") {
+		t.Errorf("done comment without changes = %q", got)
+	}
+	gitlab := &trigger{forge: "gitlab"}
+	if got := doneComment(gitlab, "Done.", nil, "https://gitlab.com/acme/widgets/-/merge_requests/3"); !strings.Contains(got, "Opened a merge request") {
+		t.Errorf("done comment on GitLab = %q", got)
+	}
+}
+
+func TestBuildPrompt_PullRequestBranch(t *testing.T) {
+	tr := &trigger{repo: "acme/widgets", number: 7, isPR: true, request: "fix the failing test", headBranch: "feature"}
+	prompt := buildPrompt(tr, "Pull request #7: Add caching")
+	if !strings.Contains(prompt, "pull request's branch feature") || strings.Contains(prompt, "pull request will be opened") {
+		t.Errorf("prompt should say changes are pushed to the PR branch:\n%s", prompt)
+	}
+}
+
+func TestCIRunURL(t *testing.T) {
+	t.Setenv("CI_JOB_URL", "")
+	t.Setenv("GITHUB_SERVER_URL", "")
+	t.Setenv("GITHUB_REPOSITORY", "acme/widgets")
+	t.Setenv("GITHUB_RUN_ID", "42")
+	if got := ciRunURL(); got != "https://github.com/acme/widgets/actions/runs/42" {
+		t.Errorf("ciRunURL = %q", got)
+	}
+	t.Setenv("GITHUB_RUN_ID", "")
+	if got := ciRunURL(); got != "" {
+		t.Errorf("ciRunURL outside a run = %q", got)
+	}
+	t.Setenv("CI_JOB_URL", "https://gitlab.com/acme/widgets/-/jobs/7")
+	if got := ciRunURL(); got != "https://gitlab.com/acme/widgets/-/jobs/7" {
+		t.Errorf("ciRunURL in GitLab CI = %q", got)
+	}
+}
@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+// giteaWorkflowPath is the repository-relative location of the generated
+// Gitea Actions workflow.
+const giteaWorkflowPath = ".gitea/workflows/kit.yml"
+
+// giteaWritePermissions are the repository permissions that may trigger Kit.
+var giteaWritePermissions = map[string]bool{"write": true, "admin": true, "owner": true}
+
+// giteaCmd is the parent command for the Gitea integration, the Gitea Actions
+// counterpart of githubCmd.
+var giteaCmd = &cobra.Command{
+	Use:   "gitea",
+	Short: "Set up Kit as a Gitea collaborator",
+	Long: `Set up Kit as an automated collaborator in a Gitea (or Forgejo) repository.
+
+Kit runs inside a Gitea Actions runner whenever someone comments '/kit ...'
+on an issue or pull request. It reads the comment and the issue or pull
+request, runs the agent non-interactively, and responds by posting comments,
+pushing to pull request branches and opening pull requests.
+
+Use 'kit gitea install' to scaffold the Gitea Actions workflow.`,
+}
+
+// giteaInstallCmd scaffolds the Gitea Actions workflow that runs Kit.
+var giteaInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Scaffold the Gitea Actions workflow that runs Kit",
+	Long: `Scaffold the Gitea Actions workflow that runs Kit as a collaborator.
+
+This writes .gitea/workflows/kit.yml configured to trigger when someone
+comments '/kit ...' on an issue or pull request. The workflow runs Kit in the
+kit-sandbox container with 'persist-credentials: false', and authenticates
+with the workflow's automatic GITEA_TOKEN.
+
+Gitea has no CLI for Actions secrets, so set the provider API key as a
+repository secret (Settings → Actions → Secrets) yourself.
+
+Examples:
+  kit gitea install
+  kit gitea install --model anthropic/claude-sonnet-4-5-20250929
+  kit gitea install --force`,
+	Args: cobra.NoArgs,
+	RunE: runGiteaInstall,
+}
+
+// giteaRunCmd is the runtime half of the Gitea integration, run by the
+// workflow giteaInstallCmd scaffolds.
+var giteaRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run Kit against the Gitea comment that triggered this workflow",
+	Long: `Run Kit against the Gitea comment that triggered this workflow.
+
+This command is normally run by the workflow 'kit gitea install' scaffolds;
+you rarely run it by hand. It reads the Actions event from $GITHUB_EVENT_PATH
+(Gitea's payloads follow GitHub's), verifies the author has write access,
+reacts with 👀 while it works, runs the agent non-interactively against the
+issue or pull request, and keeps a single progress comment updated in place.
+If the agent modified files on a pull request whose branch Kit may push to,
+the changes are committed as kit-agent[bot] and pushed to that branch;
+otherwise Kit pushes a new branch and opens a pull request.
+
+API calls go to $GITHUB_API_URL (or $GITHUB_SERVER_URL/api/v1), authenticated
+by $GITEA_TOKEN.
+
+Set --dry-run (or KIT_GITEA_DRY_RUN=1) to log every git/API side effect and
+skip the agent run instead of executing them.`,
+	Args: cobra.NoArgs,
+	RunE: runGiteaRun,
+}
+
+func init() {
+	addInstallFlags(giteaInstallCmd, "workflow", "")
+	addRunFlags(giteaRunCmd)
+	giteaCmd.AddCommand(giteaInstallCmd, giteaRunCmd)
+	rootCmd.AddCommand(giteaCmd)
+}
+
+func runGiteaInstall(_ *cobra.Command, _ []string) error {
+	model, secretName, err := resolveInstallModel()
+	if err != nil {
+		return err
+	}
+	if err := writeCIFile(giteaWorkflowPath, renderGiteaWorkflow(model, secretName), forgeInstallForce); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Wrote %s\n", giteaWorkflowPath)
+
+	fmt.Println("\nNext steps:")
+	fmt.Printf("  1. Commit the workflow:  git add %s && git commit -m \"ci: add kit workflow\"\n", giteaWorkflowPath)
+	fmt.Printf("  2. Set the %s repository secret (Settings → Actions → Secrets).\n", secretName)
+	fmt.Println("  3. Make sure Actions are enabled for the repository and a runner is registered.")
+	fmt.Println("  4. Comment '/kit <your request>' on an issue or pull request to trigger Kit.")
+	log.Info("gitea workflow scaffolded", "model", model, "secret", secretName)
+	return nil
+}
+
+// renderGiteaWorkflow builds the workflow YAML for the given model and
+// provider secret name. Gitea comment payloads carry no author association,
+// so `kit gitea run` checks permissions itself rather than the workflow.
+func renderGiteaWorkflow(model, secretName string) string {
+	return fmt.Sprintf(`name: kit
+on:
+  issue_comment:
+    types: [created]
+  pull_request_review_comment:
+    types: [created]
+jobs:
+  kit:
+    if: |
+      startsWith(github.event.comment.body, '/kit ') ||
+      github.event.comment.body == '/kit'
+    runs-on: ubuntu-latest
+    container:
+      image: %s
+    steps:
+      - uses: actions/checkout@v4
+        with:
+          persist-credentials: false
+      - run: kit gitea run
+        env:
+          MODEL: %s
+          GITEA_TOKEN: ${{ secrets.GITEA_TOKEN }}
+          %s: ${{ secrets.%s }}
+`, sandboxImage, model, secretName, secretName)
+}
+
+// runGiteaRun is the entry point wired to `kit gitea run`.
+func runGiteaRun(cmd *cobra.Command, _ []string) error {
+	return runForge(cmd, giteaForge{})
+}
+
+// --- Gitea API types ---------------------------------------------------------
+
+type giteaPull struct {
+	Head struct {
+		SHA  string `json:"sha"`
+		Ref  string `json:"ref"`
+		Repo *struct {
+			FullName string `json:"full_name"`
+		} `json:"repo"`
+	} `json:"head"`
+	AllowMaintainerEdit bool `json:"allow_maintainer_edit"`
+}
+
+type giteaComment struct {
+	ID   int64 `json:"id"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Body string `json:"body"`
+}
+
+// giteaForge runs Kit from Gitea Actions, calling the Gitea REST API directly:
+// the sandbox's tea CLI has no generic API command.
+type giteaForge struct{}
+
+func (giteaForge) name() string    { return "gitea" }
+func (giteaForge) ciEnv() string   { return "GITEA_ACTIONS" }
+func (giteaForge) available() bool { return os.Getenv("GITEA_TOKEN") != "" }
+
+// loadTrigger reads the Actions event, which Gitea shapes like GitHub's.
+func (giteaForge) loadTrigger() (*trigger, error) {
+	event, err := loadGitHubEvent()
+	if err != nil {
+		return nil, err
+	}
+	tr, err := buildTrigger(event)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoTrigger, err)
+	}
+	return tr, nil
+}
+
+func (g giteaForge) authorized(ctx context.Context, tr *trigger) bool {
+	if !forgeLive(g) {
+		log.Info("gitea run: [dry-run] skipping the permission check", "author", tr.author)
+		return true
+	}
+	var perm struct {
+		Permission string `json:"permission"`
+	}
+	path := fmt.Sprintf("repos/%s/collaborators/%s/permission", tr.repo, tr.author)
+	if err := giteaJSON(ctx, http.MethodGet, path, nil, &perm); err != nil {
+		return false
+	}
+	tr.association = strings.ToUpper(perm.Permission)
+	return giteaWritePermissions[perm.Permission]
+}
+
+// react adds a reaction to the trigger comment. Gitea keeps pull request
+// review comments in the same table as issue comments, so one endpoint
+// serves both.
+func (giteaForge) react(ctx context.Context, tr *trigger, content string) {
+	path := fmt.Sprintf("repos/%s/issues/comments/%d/reactions", tr.repo, tr.commentID)
+	_ = giteaJSON(ctx, http.MethodPost, path, map[string]string{"content": content}, nil)
+}
+
+func (giteaForge) postComment(ctx context.Context, tr *trigger, body string) int64 {
+	var comment giteaComment
+	path := fmt.Sprintf("repos/%s/issues/%d/comments", tr.repo, tr.number)
+	_ = giteaJSON(ctx, http.MethodPost, path, map[string]string{"body": body}, &comment)
+	return comment.ID
+}
+
+func (giteaForge) editComment(ctx context.Context, tr *trigger, id int64, body string) {
+	path := fmt.Sprintf("repos/%s/issues/comments/%d", tr.repo, id)
+	_ = giteaJSON(ctx, http.MethodPatch, path, map[string]string{"body": body}, nil)
+}
+
+// fetchChange loads the pull request's head branch and its diff, which Gitea
+// serves as one unified diff rather than per-file patches.
+func (giteaForge) fetchChange(ctx context.Context, tr *trigger) *pullContext {
+	base := fmt.Sprintf("repos/%s/pulls/%d", tr.repo, tr.number)
+	pr := &pullContext{}
+	if diff, err := giteaRequest(ctx, http.MethodGet, base+".diff", nil); err == nil {
+		pr.files = splitUnifiedDiff(string(diff))
+	}
+	var pull giteaPull
+	if err := giteaJSON(ctx, http.MethodGet, base, nil, &pull); err != nil {
+		return pr
+	}
+	head := prHead{SHA: pull.Head.SHA, Ref: pull.Head.Ref, MaintainerCanModify: pull.AllowMaintainerEdit}
+	if pull.Head.Repo != nil {
+		head.Repo = pull.Head.Repo.FullName
+	}
+	pr.headSHA, pr.headRef, pr.headRepo = head.SHA, head.Ref, head.Repo
+	pr.canPush = head.pushable(tr.repo)
+	return pr
+}
+
+func (giteaForge) discussion(ctx context.Context, tr *trigger) string {
+	var comments []giteaComment
+	path := fmt.Sprintf("repos/%s/issues/%d/comments", tr.repo, tr.number)
+	if err := giteaJSON(ctx, http.MethodGet, path, nil, &comments); err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, c := range comments {
+		fmt.Fprintf(&b, "@%s: %s\n", c.User.Login, c.Body)
+	}
+	return b.String()
+}
+
+func (giteaForge) setupGit(ctx context.Context) error {
+	return setupGitCredentials(ctx, "GITEA_TOKEN")
+}
+
+func (giteaForge) gitAuth() []string {
+	return gitCredentialArgs("GITEA_TOKEN")
+}
+
+func (giteaForge) repoURL(repo string) string {
+	return fmt.Sprintf("%s/%s.git", giteaServerURL(), repo)
+}
+
+func (giteaForge) commitURL(repo, sha string) string {
+	return fmt.Sprintf("%s/%s/commit/%s", giteaServerURL(), repo, sha)
+}
+
+func (giteaForge) openChange(ctx context.Context, tr *trigger, branch, title, body string) string {
+	var pull struct {
+		HTMLURL string `json:"html_url"`
+	}
+	req := map[string]string{"head": branch, "base": tr.defaultBranch, "title": title, "body": body}
+	_ = giteaJSON(ctx, http.MethodPost, fmt.Sprintf("repos/%s/pulls", tr.repo), req, &pull)
+	return pull.HTMLURL
+}
+
+// giteaServerURL is the Gitea web root, which Gitea Actions exports under
+// GitHub's variable name.
+func giteaServerURL() string {
+	return strings.TrimSuffix(os.Getenv("GITHUB_SERVER_URL"), "/")
+}
+
+// giteaAPIURL is the root of the Gitea REST API.
+func giteaAPIURL() string {
+	if api := strings.TrimSuffix(os.Getenv("GITHUB_API_URL"), "/"); api != "" {
+		return api
+	}
+	return giteaServerURL() + "/api/v1"
+}
+
+// giteaJSON sends body as JSON to the API path and decodes the response into
+// out, when out is non-nil.
+func giteaJSON(ctx context.Context, method, path string, body, out any) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	data, err := giteaRequest(ctx, method, path, payload)
+	if err != nil || out == nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decoding %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// giteaRequest calls the Gitea API with GITEA_TOKEN and returns the response
+// body, logging and returning an error for non-2xx responses.
+func giteaRequest(ctx context.Context, method, path string, body io.Reader) ([]byte, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, subprocessTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, method, giteaAPIURL()+"/"+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+os.Getenv("GITEA_TOKEN"))
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Error("gitea run: API request failed", "method", method, "path", path, "err", err)
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		err := fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		log.Error("gitea run: API request failed", "err", err, "body", strings.TrimSpace(string(data)))
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const giteaPullDiff = `diff --git a/cache.go b/cache.go
+index 1111111..2222222 100644
+--- a/cache.go
++++ b/cache.go
+` + reviewPatch + `
+diff --git a/docs/new file.md b/docs/new file.md
+new file mode 100644
+--- /dev/null
++++ b/docs/new file.md
+@@ -0,0 +1,2 @@
++# Cache
++-- not a header
+`
+
+func TestSplitUnifiedDiff(t *testing.T) {
+	files := splitUnifiedDiff(giteaPullDiff)
+	if len(files) != 2 {
+		t.Fatalf("files = %+v", files)
+	}
+	if files[0].Filename != "cache.go" || files[0].Patch != reviewPatch || files[0].Additions != 3 || files[0].Deletions != 2 {
+		t.Errorf("first file = %+v", files[0])
+	}
+	if files[1].Filename != "docs/new file.md" || files[1].Status != "added" || files[1].Additions != 2 {
+		t.Errorf("second file = %+v", files[1])
+	}
+}
+
+// fakeGitea serves the Gitea API endpoints the runner calls for acme/widgets
+// pull request 7 and records the comment bodies posted to it.
+func fakeGitea(t *testing.T) *[]string {
+	t.Helper()
+	var posted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/acme/widgets/collaborators/alice/permission":
+			_, _ = w.Write([]byte(`{"permission": "write"}`))
+		case "GET /repos/acme/widgets/collaborators/mallory/permission":
+			_, _ = w.Write([]byte(`{"permission": "read"}`))
+		case "GET /repos/acme/widgets/pulls/7.diff":
+			_, _ = w.Write([]byte(giteaPullDiff))
+		case "GET /repos/acme/widgets/pulls/7":
+			_, _ = w.Write([]byte(`{"head": {"sha": "abc123", "ref": "feature", "repo": {"full_name": "bob/widgets"}}, "allow_maintainer_edit": true}`))
+		case "POST /repos/acme/widgets/issues/7/comments":
+			var c giteaComment
+			_ = json.NewDecoder(r.Body).Decode(&c)
+			posted = append(posted, c.Body)
+			_, _ = w.Write([]byte(`{"id": 901}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("GITHUB_API_URL", srv.URL)
+	t.Setenv("GITEA_TOKEN", "secret")
+	t.Setenv("KIT_GITHUB_DRY_RUN", "")
+	t.Setenv("KIT_GITLAB_DRY_RUN", "")
+	t.Setenv("KIT_GITEA_DRY_RUN", "")
+	return &posted
+}
+
+func TestGiteaForge_API(t *testing.T) {
+	posted := fakeGitea(t)
+	ctx := context.Background()
+	g := giteaForge{}
+	tr := &trigger{forge: "gitea", repo: "acme/widgets", number: 7, isPR: true, author: "alice"}
+
+	if !g.authorized(ctx, tr) || tr.association != "WRITE" {
+		t.Errorf("alice should be authorized, association = %q", tr.association)
+	}
+	if g.authorized(ctx, &trigger{repo: "acme/widgets", author: "mallory"}) {
+		t.Error("read-only mallory should not be authorized")
+	}
+
+	pr := g.fetchChange(ctx, tr)
+	if pr.headSHA != "abc123" || pr.headRef != "feature" || pr.headRepo != "bob/widgets" || !pr.canPush {
+		t.Errorf("pull context = %+v", pr)
+	}
+	if len(pr.files) != 2 || pr.files[0].Filename != "cache.go" {
+		t.Errorf("files = %+v", pr.files)
+	}
+
+	if id := g.postComment(ctx, tr, "👀 Picked up"); id != 901 || len(*posted) != 1 || (*posted)[0] != "👀 Picked up" {
+		t.Errorf("postComment = %d, posted %q", id, *posted)
+	}
+}
+
+func TestRenderGiteaWorkflow(t *testing.T) {
+	out := renderGiteaWorkflow("anthropic/claude-sonnet-4-5-20250929", "ANTHROPIC_API_KEY")
+	for _, want := range []string{
+		"issue_comment:",
+		"startsWith(github.event.comment.body, '/kit ')",
+		"image: " + sandboxImage,
+		"persist-credentials: false",
+		"run: kit gitea run",
+		"MODEL: anthropic/claude-sonnet-4-5-20250929",
+		"GITEA_TOKEN: ${{ secrets.GITEA_TOKEN }}",
+		"ANTHROPIC_API_KEY: ${{ secrets.ANTHROPIC_API_KEY }}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rendered workflow missing %q\n---\n%s", want, out)
+		}
+	}
+}
@@ -22,12 +22,16 @@ const defaultGitHubModel = "anthropic/claude-sonnet-4-5-20250929"
 // GitHub Actions workflow that wires Kit into a repository as a collaborator.
 const githubWorkflowPath = ".github/workflows/kit.yml"
 
+// Flags shared by the forge install commands.
 var (
-	githubInstallModel    string
-	githubInstallForce    bool
-	githubInstallNoSecret bool
+	forgeInstallModel    string
+	forgeInstallForce    bool
+	forgeInstallNoSecret bool
 )
 
+// ghSecrets stores GitHub repository secrets.
+var ghSecrets = secretSetter{cli: "gh", args: []string{"secret", "set"}, kind: "repository secret"}
+
 // githubCmd is the parent command for GitHub integration subcommands. It groups
 // the turnkey setup tooling that wires Kit into a repository as an automated
 // collaborator/reviewer driven by GitHub Actions.
@@ -38,7 +42,8 @@ var githubCmd = &cobra.Command{
 
 Kit runs inside a GitHub Actions runner, reads the relevant context (an issue
 thread or pull request), runs the agent non-interactively, and responds by
-posting comments and opening pull requests.
+posting comments and reviews, pushing to pull request branches and opening
+pull requests.
 
 Use 'kit github install' to scaffold the GitHub Actions workflow.`,
 }
@@ -74,45 +79,60 @@ Examples:
 }
 
 func init() {
-	githubInstallCmd.Flags().StringVarP(&githubInstallModel, "model", "m", "", "provider/model to write into the workflow")
-	githubInstallCmd.Flags().BoolVar(&githubInstallForce, "force", false, "overwrite an existing workflow file")
-	githubInstallCmd.Flags().BoolVar(&githubInstallNoSecret, "no-secret", false, "skip setting the provider secret via the gh CLI")
+	addInstallFlags(githubInstallCmd, "workflow", "gh")
 
 	githubCmd.AddCommand(githubInstallCmd)
 	rootCmd.AddCommand(githubCmd)
 }
 
 func runGitHubInstall(cmd *cobra.Command, _ []string) error {
-	model, err := resolveGitHubModel()
+	model, secretName, err := resolveInstallModel()
 	if err != nil {
 		return err
 	}
 
-	provider, _, err := kit.ParseModelString(model)
-	if err != nil {
-		return fmt.Errorf("invalid model %q: %w", model, err)
-	}
-
-	secretName := providerSecretEnvVar(provider)
-
-	if err := writeGitHubWorkflow(model, secretName, githubInstallForce); err != nil {
+	if err := writeGitHubWorkflow(model, secretName, forgeInstallForce); err != nil {
 		return err
 	}
 	fmt.Printf("✅ Wrote %s\n", githubWorkflowPath)
 
-	maybeSetProviderSecret(cmd.Context(), secretName)
+	maybeSetSecret(cmd.Context(), ghSecrets, secretName)
 
 	printGitHubInstallNextSteps(secretName)
 	log.Info("github workflow scaffolded", "model", model, "secret", secretName)
 	return nil
 }
 
+// addInstallFlags registers the flags shared by the forge install commands.
+// what names the generated file and cli the forge CLI secrets are set with;
+// forges without one pass "" and get no --no-secret flag.
+func addInstallFlags(cmd *cobra.Command, what, cli string) {
+	cmd.Flags().StringVarP(&forgeInstallModel, "model", "m", "", "provider/model to write into the "+what)
+	cmd.Flags().BoolVar(&forgeInstallForce, "force", false, "overwrite an existing "+what+" file")
+	if cli != "" {
+		cmd.Flags().BoolVar(&forgeInstallNoSecret, "no-secret", false, "skip setting the provider secret via the "+cli+" CLI")
+	}
+}
+
+// resolveInstallModel determines the model to embed in the CI configuration
+// and the name of the secret holding its provider's API key.
+func resolveInstallModel() (model, secretName string, err error) {
+	if model, err = resolveGitHubModel(); err != nil {
+		return "", "", err
+	}
+	provider, _, err := kit.ParseModelString(model)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid model %q: %w", model, err)
+	}
+	return model, providerSecretEnvVar(provider), nil
+}
+
 // resolveGitHubModel determines the model to embed in the workflow. The
 // --model flag takes precedence; otherwise an interactive prompt is shown
 // (pre-filled with the default), and non-interactive runs use the default.
 func resolveGitHubModel() (string, error) {
-	if githubInstallModel != "" {
-		return strings.TrimSpace(githubInstallModel), nil
+	if forgeInstallModel != "" {
+		return strings.TrimSpace(forgeInstallModel), nil
 	}
 
 	if !isInteractive() {
@@ -186,45 +206,66 @@ jobs:
 // creating parent directories as needed. It refuses to overwrite an existing
 // file unless force is true.
 func writeGitHubWorkflow(model, secretName string, force bool) error {
-	if _, err := os.Stat(githubWorkflowPath); err == nil && !force {
-		return fmt.Errorf("%s already exists; re-run with --force to overwrite", githubWorkflowPath)
+	return writeCIFile(githubWorkflowPath, renderGitHubWorkflow(model, secretName), force)
+}
+
+// writeCIFile writes generated CI configuration to path, creating parent
+// directories as needed. It refuses to overwrite an existing file unless force
+// is true.
+func writeCIFile(path, content string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists; re-run with --force to overwrite", path)
 	} else if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("checking %s: %w", githubWorkflowPath, err)
+		return fmt.Errorf("checking %s: %w", path, err)
 	}
 
-	if err := os.MkdirAll(filepath.Dir(githubWorkflowPath), 0o755); err != nil {
-		return fmt.Errorf("creating %s: %w", filepath.Dir(githubWorkflowPath), err)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
 	}
 
-	content := renderGitHubWorkflow(model, secretName)
-	if err := os.WriteFile(githubWorkflowPath, []byte(content), 0o644); err != nil {
-		return fmt.Errorf("writing %s: %w", githubWorkflowPath, err)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
 	}
 	return nil
 }
 
-// maybeSetProviderSecret offers to set the provider API key as a repository
-// secret via the gh CLI when it is available, interactive, the secret value is
-// present in the environment, and the user did not pass --no-secret.
-func maybeSetProviderSecret(ctx context.Context, secretName string) {
-	if githubInstallNoSecret || !isInteractive() {
+// secretSetter stores a CI secret with a forge CLI that reads the value from
+// stdin.
+type secretSetter struct {
+	cli   string   // executable, e.g. "gh"
+	args  []string // arguments before the secret name
+	flags []string // arguments after it
+	kind  string   // what the forge calls a secret
+}
+
+// command returns the command line that sets the secret name.
+func (s secretSetter) command(name string) []string {
+	return append(append(append([]string{s.cli}, s.args...), name), s.flags...)
+}
+
+// maybeSetSecret offers to store the provider API key as a CI secret via the
+// forge CLI when it is available, interactive, the secret value is present in
+// the environment, and the user did not pass --no-secret.
+func maybeSetSecret(ctx context.Context, setter secretSetter, secretName string) {
+	if forgeInstallNoSecret || !isInteractive() {
 		return
 	}
 
-	if _, err := exec.LookPath("gh"); err != nil {
+	if _, err := exec.LookPath(setter.cli); err != nil {
 		return
 	}
 
+	manual := strings.Join(setter.command(secretName), " ")
 	value := os.Getenv(secretName)
 	if value == "" {
-		fmt.Printf("ℹ️  %s is not set in your environment; set the repository secret manually with:\n", secretName)
-		fmt.Printf("     gh secret set %s\n", secretName)
+		fmt.Printf("ℹ️  %s is not set in your environment; set the %s manually with:\n", secretName, setter.kind)
+		fmt.Printf("     %s\n", manual)
 		return
 	}
 
 	var confirm bool
 	if err := huh.NewConfirm().
-		Title(fmt.Sprintf("Set the %s repository secret via gh?", secretName)).
+		Title(fmt.Sprintf("Set the %s %s via %s?", secretName, setter.kind, setter.cli)).
 		Description("Uses the value from your current environment.").
 		Value(&confirm).
 		Run(); err != nil || !confirm {
@@ -233,16 +274,17 @@ func maybeSetProviderSecret(ctx context.Context, secretName string) {
 
 	// Feed the secret value via stdin rather than a command-line argument so
 	// the API key never appears in the process argument list.
-	cmd := exec.CommandContext(ctx, "gh", "secret", "set", secretName)
+	argv := setter.command(secretName)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
 	cmd.Stdin = strings.NewReader(value)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
-		fmt.Printf("⚠️  Failed to set secret via gh: %v\n", err)
-		fmt.Printf("     Set it manually with: gh secret set %s\n", secretName)
+		fmt.Printf("⚠️  Failed to set secret via %s: %v\n", setter.cli, err)
+		fmt.Printf("     Set it manually with: %s\n", manual)
 		return
 	}
-	fmt.Printf("✅ Set repository secret %s\n", secretName)
+	fmt.Printf("✅ Set %s %s\n", setter.kind, secretName)
 }
 
 // printGitHubInstallNextSteps prints the manual follow-up actions a user must
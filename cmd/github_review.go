@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// This file implements `/kit review` on pull requests: instead of a single
+// issue comment, Kit submits a GitHub review with inline comments anchored to
+// diff lines, optional suggested changes and an overall verdict. Comments
+// carry a hidden marker so a re-run can skip what an earlier review already
+// said.
+
+// reviewMarker tags the bodies of reviews and review comments Kit submits.
+// It renders as nothing on GitHub.
+const reviewMarker = "<!-- kit-review -->"
+
+// Review events accepted by the GitHub pull request reviews API.
+const (
+	reviewEventComment        = "COMMENT"
+	reviewEventApprove        = "APPROVE"
+	reviewEventRequestChanges = "REQUEST_CHANGES"
+)
+
+// reviewLineComment is an inline comment of a review, as submitted to and
+// returned by the GitHub API.
+type reviewLineComment struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line"` // 0 once the comment is outdated
+	StartLine int    `json:"start_line,omitempty"`
+	Side      string `json:"side,omitempty"`
+	StartSide string `json:"start_side,omitempty"`
+	Body      string `json:"body"`
+}
+
+// reviewPayload is the request body of POST /repos/{repo}/pulls/{n}/reviews.
+type reviewPayload struct {
+	CommitID string              `json:"commit_id,omitempty"`
+	Event    string              `json:"event"`
+	Body     string              `json:"body"`
+	Comments []reviewLineComment `json:"comments"`
+}
+
+// agentReview is the JSON block the agent ends a review with.
+type agentReview struct {
+	Verdict  string               `json:"verdict"`
+	Summary  string               `json:"summary"`
+	Comments []agentReviewComment `json:"comments"`
+}
+
+type agentReviewComment struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	StartLine int    `json:"start_line"`
+	Body      string `json:"body"`
+	// Suggestion replaces lines StartLine..Line verbatim. Nil means no
+	// suggestion; an empty string suggests deleting the lines.
+	Suggestion *string `json:"suggestion"`
+}
+
+// isReviewRequest reports whether a request asks for a review: its first
+// word is "review", as in `/kit review` or `/kit review the error handling`.
+func isReviewRequest(request string) bool {
+	fields := strings.Fields(request)
+	return len(fields) > 0 && strings.EqualFold(fields[0], "review")
+}
+
+// reviewInstructions tells the agent how to finish a review.
+const reviewInstructions = "Review the pull request. Do not modify any files: your answer is submitted " +
+	"as a GitHub review. Point out bugs, risky changes and missing tests rather than style nits, " +
+	"and do not repeat your earlier review comments. End your answer with a fenced ```json block of the form\n\n" +
+	"```json\n" +
+	`{"verdict": "comment", "summary": "overall assessment", "comments": [` + "\n" +
+	`  {"path": "dir/file.go", "line": 42, "start_line": 40, "body": "what is wrong and why", "suggestion": "replacement code"}` + "\n" +
+	"]}\n```\n\n" +
+	"verdict is one of comment, approve or request_changes. line (and start_line, for a range) " +
+	"must be numbers from the left column of the patches, within a single hunk; omit start_line " +
+	"for a single line. Only give a suggestion when you have a concrete fix: it replaces lines " +
+	"start_line..line verbatim, so keep their indentation. Omit suggestion otherwise."
+
+// parseAgentReview extracts the review from the agent's response: the last
+// ```json block, with the prose around it as the summary when the block has
+// none. A response without a valid block becomes a summary-only comment.
+func parseAgentReview(response string) agentReview {
+	start := strings.LastIndex(response, "```json")
+	if start < 0 {
+		return agentReview{Summary: strings.TrimSpace(response)}
+	}
+	body := response[start+len("```json"):]
+	end := strings.Index(body, "```")
+	if end < 0 {
+		return agentReview{Summary: strings.TrimSpace(response)}
+	}
+	var review agentReview
+	if err := json.Unmarshal([]byte(body[:end]), &review); err != nil {
+		log.Warn("github run: review block is not valid JSON", "err", err)
+		return agentReview{Summary: strings.TrimSpace(response)}
+	}
+	if strings.TrimSpace(review.Summary) == "" {
+		review.Summary = strings.TrimSpace(response[:start] + body[end+len("```"):])
+	}
+	return review
+}
+
+// buildReview turns the agent's review into the API payload. Comments not
+// anchored to a line of the diff — which GitHub would reject — move into the
+// review body; comments on a line Kit already commented on are dropped.
+func buildReview(pr *pullContext, r agentReview) reviewPayload {
+	payload := reviewPayload{Event: reviewEventComment, Comments: []reviewLineComment{}}
+	switch strings.ToLower(strings.TrimSpace(r.Verdict)) {
+	case "approve":
+		payload.Event = reviewEventApprove
+	case "request_changes", "request-changes":
+		payload.Event = reviewEventRequestChanges
+	}
+
+	anchors := make(map[string]map[int]int)
+	seen := make(map[string]bool)
+	if pr != nil {
+		payload.CommitID = pr.headSHA
+		for _, f := range pr.files {
+			anchors[f.Filename] = commentableLines(f.Patch)
+		}
+		for _, c := range pr.prior {
+			if c.Line > 0 {
+				seen[fmt.Sprintf("%s:%d", c.Path, c.Line)] = true
+			}
+		}
+	}
+
+	var notes []string
+	skipped := 0
+	for _, c := range r.Comments {
+		body := strings.TrimSpace(c.Body)
+		if c.Suggestion != nil {
+			body += "\n\n```suggestion\n" + strings.TrimSuffix(*c.Suggestion, "\n") + "\n```"
+		}
+		lines := anchors[c.Path]
+		hunk, ok := lines[c.Line]
+		if ok && c.StartLine != 0 {
+			startHunk, startOK := lines[c.StartLine]
+			ok = startOK && startHunk == hunk && c.StartLine < c.Line
+		}
+		if !ok {
+			notes = append(notes, fmt.Sprintf("- `%s:%d`: %s", c.Path, c.Line, body))
+			continue
+		}
+		if seen[fmt.Sprintf("%s:%d", c.Path, c.Line)] {
+			skipped++
+			continue
+		}
+		comment := reviewLineComment{Path: c.Path, Line: c.Line, Side: "RIGHT", Body: body + "\n\n" + reviewMarker}
+		if c.StartLine != 0 {
+			comment.StartLine, comment.StartSide = c.StartLine, "RIGHT"
+		}
+		payload.Comments = append(payload.Comments, comment)
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(r.Summary))
+	if b.Len() == 0 {
+		b.WriteString("Kit reviewed this pull request.")
+	}
+	if len(notes) > 0 {
+		fmt.Fprintf(&b, "\n\n**Other notes**\n\n%s", strings.Join(notes, "\n"))
+	}
+	if skipped > 0 {
+		fmt.Fprintf(&b, "\n\n_Skipped %d comment(s) already made in an earlier review._", skipped)
+	}
+	b.WriteString("\n\n" + reviewMarker)
+	payload.Body = b.String()
+	return payload
+}
+
+// submitReview submits the review on the trigger's pull request, or prints
+// the payload to out in dry-run. GITHUB_TOKEN may not be allowed to approve
+// or request changes, so a rejected verdict is retried as a comment review
+// that states it.
+func (g githubForge) submitReview(ctx context.Context, tr *trigger, payload reviewPayload, out io.Writer) error {
+	data, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding review: %w", err)
+	}
+	path := fmt.Sprintf("repos/%s/pulls/%d/reviews", tr.repo, tr.number)
+	if !forgeLive(g) {
+		log.Info("github run: [dry-run] review", "path", path, "event", payload.Event, "comments", len(payload.Comments))
+		fmt.Fprintln(out, string(data))
+		return nil
+	}
+
+	err = ghInput(ctx, data, "api", "-X", "POST", path, "--input", "-")
+	if err == nil || payload.Event == reviewEventComment {
+		return err
+	}
+	log.Warn("github run: review verdict rejected, retrying as a comment", "event", payload.Event, "err", err)
+	verdict := strings.ToLower(strings.ReplaceAll(payload.Event, "_", " "))
+	payload.Body = fmt.Sprintf("**Verdict: %s**\n\n%s", verdict, payload.Body)
+	payload.Event = reviewEventComment
+	if data, err = json.Marshal(payload); err != nil {
+		return fmt.Errorf("encoding review: %w", err)
+	}
+	return ghInput(ctx, data, "api", "-X", "POST", path, "--input", "-")
+}
+
+// reviewAsComment renders a review that could not be submitted as a plain
+// comment, with the inline comments listed by location.
+func reviewAsComment(payload reviewPayload) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSuffix(payload.Body, "\n\n"+reviewMarker))
+	for _, c := range payload.Comments {
+		fmt.Fprintf(&b, "\n\n**`%s:%d`**\n\n%s", c.Path, c.Line, strings.TrimSuffix(c.Body, "\n\n"+reviewMarker))
+	}
+	return b.String()
+}
+
+// ghInput runs a gh command with stdin, returning its stderr in the error.
+func ghInput(ctx context.Context, stdin []byte, args ...string) error {
+	cmdCtx, cancel := context.WithTimeout(ctx, subprocessTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(cmdCtx, "gh", args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	if _, err := cmd.Output(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return fmt.Errorf("gh %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return fmt.Errorf("gh %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
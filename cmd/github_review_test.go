@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const reviewPatch = `@@ -1,4 +1,5 @@
+ package cache
+-var size = 10
++var size = 100
++var ttl = 5
+
+ func Get() {}
+@@ -20,3 +21,3 @@ func Put() {
+ 	a := 1
+-	b := 2
++	b := 3
+ 	return`
+
+const reviewResponse = "Looks mostly fine.\n\n```json\n" + `{
+  "verdict": "request_changes",
+  "comments": [
+    {"path": "cache.go", "line": 2, "body": "Why 100?", "suggestion": "var size = 64"},
+    {"path": "cache.go", "line": 22, "start_line": 21, "body": "Rename b."},
+    {"path": "cache.go", "line": 3, "body": "Already said."},
+    {"path": "cache.go", "line": 10, "body": "Outside the diff."},
+    {"path": "cache.go", "line": 21, "start_line": 3, "body": "Spans hunks."}
+  ]
+}` + "\n```\n"
+
+func TestCommentableLines(t *testing.T) {
+	lines := commentableLines(reviewPatch)
+	want := map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0, 21: 1, 22: 1, 23: 1}
+	if len(lines) != len(want) {
+		t.Fatalf("commentableLines = %v, want %v", lines, want)
+	}
+	for line, hunk := range want {
+		if got, ok := lines[line]; !ok || got != hunk {
+			t.Errorf("line %d: hunk %d, %v; want %d", line, got, ok, hunk)
+		}
+	}
+
+	numbered := numberPatch(reviewPatch)
+	for _, want := range []string{"     2 +var size = 100", "       -var size = 10", "    22 +\tb := 3"} {
+		if !strings.Contains(numbered, want) {
+			t.Errorf("numberPatch missing %q:\n%s", want, numbered)
+		}
+	}
+}
+
+func TestBuildReview(t *testing.T) {
+	pr := &pullContext{
+		headSHA: "abc123",
+		files:   []prFile{{Filename: "cache.go", Patch: reviewPatch}},
+		prior:   []reviewLineComment{{Path: "cache.go", Line: 3, Body: "ttl is unused\n\n" + reviewMarker}},
+	}
+	review := buildReview(pr, parseAgentReview(reviewResponse))
+
+	if review.Event != reviewEventRequestChanges || review.CommitID != "abc123" {
+		t.Errorf("event = %s, commit = %s", review.Event, review.CommitID)
+	}
+	if len(review.Comments) != 2 {
+		t.Fatalf("comments = %+v, want the two anchored, new ones", review.Comments)
+	}
+	first, second := review.Comments[0], review.Comments[1]
+	if first.Line != 2 || first.Side != "RIGHT" || !strings.Contains(first.Body, "```suggestion\nvar size = 64\n```") || !strings.HasSuffix(first.Body, reviewMarker) {
+		t.Errorf("first comment = %+v", first)
+	}
+	if second.StartLine != 21 || second.Line != 22 || second.StartSide != "RIGHT" {
+		t.Errorf("range comment = %+v", second)
+	}
+	for _, want := range []string{"Looks mostly fine.", "`cache.go:10`: Outside the diff.", "Spans hunks.", "Skipped 1 comment", reviewMarker} {
+		if !strings.Contains(review.Body, want) {
+			t.Errorf("body missing %q:\n%s", want, review.Body)
+		}
+	}
+	if strings.Contains(review.Body, "Already said.") {
+		t.Errorf("body repeats a deduplicated comment:\n%s", review.Body)
+	}
+}
+
+func TestParseAgentReview_WithoutBlock(t *testing.T) {
+	review := buildReview(nil, parseAgentReview("No JSON here."))
+	if review.Event != reviewEventComment || len(review.Comments) != 0 || !strings.HasPrefix(review.Body, "No JSON here.") {
+		t.Errorf("review = %+v, want a summary-only comment", review)
+	}
+}
+
+func TestRunGitHub_ReviewDryRunPrintsPayload(t *testing.T) {
+	setupEvent(t, strings.NewReplacer(
+		`"/kit fix the broken parser"`, `"/kit review"`,
+		`"issue": {"number": 42,`, `"issue": {"pull_request": {"url": "x"}, "number": 42,`,
+	).Replace(issueCommentEvent))
+	var out bytes.Buffer
+	githubRunCmd.SetOut(&out)
+	t.Cleanup(func() { githubRunCmd.SetOut(nil) })
+
+	event, _ := loadGitHubEvent()
+	if tr, _ := buildTrigger(event); tr == nil || !tr.review {
+		t.Fatalf("trigger = %+v, want a review", tr)
+	}
+	if err := runGitHubRun(githubRunCmd, nil); err != nil {
+		t.Fatalf("runGitHubRun: %v", err)
+	}
+	var payload reviewPayload
+	if err := json.Unmarshal(out.Bytes(), &payload); err != nil {
+		t.Fatalf("dry-run output is not a review payload: %v\n%s", err, out.String())
+	}
+	if payload.Event != reviewEventComment || !strings.Contains(payload.Body, "[dry-run] agent response") {
+		t.Errorf("payload = %+v", payload)
+	}
+}
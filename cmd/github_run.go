@@ -6,33 +6,13 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
 )
 
-// commandToken is the mention that triggers Kit from a comment, mirroring the
-// `if:` guard in the generated workflow (.github/workflows/kit.yml).
-const commandToken = "/kit"
-
-// subprocessTimeout bounds each git/gh invocation so a stalled network call or
-// an unexpected auth prompt cannot hang the Actions job indefinitely.
-const subprocessTimeout = 30 * time.Second
-
-// agentTimeout bounds the headless agent run so a runaway turn cannot block the
-// job forever. GitHub Actions jobs have their own ceiling, but a tighter bound
-// keeps feedback fast and costs predictable.
-const agentTimeout = 20 * time.Minute
-
-// botName / botEmail are the dedicated identity commits are attributed to, so
-// Kit's changes are clearly distinguishable from human authors in history.
-const (
-	botName  = "kit-agent[bot]"
-	botEmail = "kit-agent[bot]@users.noreply.github.com"
-)
-
 // writeAssociations are the GitHub author_association values that imply
 // write/admin access. Only these may trigger the handler.
 var writeAssociations = map[string]bool{
@@ -41,11 +21,6 @@ var writeAssociations = map[string]bool{
 	"COLLABORATOR": true,
 }
 
-var (
-	githubRunModel  string
-	githubRunDryRun bool
-)
-
 // githubRunCmd is the runtime half of the GitHub integration. It is invoked by
 // the bundled composite action (action.yml) inside a GitHub Actions runner once
 // a collaborator comments '/kit <request>' on an issue or pull request. It reads
@@ -61,18 +36,26 @@ This command is normally invoked by the bundled composite action inside a
 GitHub Actions runner; you rarely run it by hand. It reads the triggering
 event from GITHUB_EVENT_PATH, verifies the commenter has write/admin access,
 reacts with an emoji while it works, runs the agent non-interactively against
-the issue thread or pull request, posts the response as a comment, and — if the
-agent modified files — pushes a kit-agent[bot] branch and opens a pull request.
+the issue thread or pull request, and keeps a single progress comment updated
+in place (picked up, working, done) with the response. If the agent modified
+files on a pull request whose branch Kit may push to, the changes are
+committed as kit-agent[bot] and pushed to that branch; otherwise Kit pushes a
+new kit-agent[bot] branch and opens a pull request.
+
+'/kit review' on a pull request submits a GitHub review instead: inline
+comments on the changed lines, suggested changes and a verdict (comment,
+approve or request changes). Comments Kit already made in an earlier review
+are not repeated.
 
 Set --dry-run (or KIT_GITHUB_DRY_RUN=1) to log every git/gh side effect and
-skip the agent run instead of executing them.`,
+skip the agent run instead of executing them; a review's payload is printed to
+stdout.`,
 	Args: cobra.NoArgs,
 	RunE: runGitHubRun,
 }
 
 func init() {
-	githubRunCmd.Flags().StringVarP(&githubRunModel, "model", "m", "", "provider/model the agent should use (falls back to $MODEL, then a default)")
-	githubRunCmd.Flags().BoolVar(&githubRunDryRun, "dry-run", false, "log git/gh side effects and skip the agent run instead of executing them")
+	addRunFlags(githubRunCmd)
 	githubCmd.AddCommand(githubRunCmd)
 }
 
@@ -115,102 +98,9 @@ type ghEvent struct {
 	Repository  ghRepo     `json:"repository"`
 }
 
-// trigger normalises a single invocation across issue_comment and
-// pull_request_review_comment events.
-type trigger struct {
-	repo          string
-	defaultBranch string
-	number        int    // issue or PR number
-	isPR          bool   // true when the target is a pull request
-	commentID     int64  // triggering comment id (for reactions)
-	commentKind   string // "issues" or "pulls" — reaction API path segment
-	author        string
-	association   string
-	request       string // the user's instruction (comment body minus the token)
-	title         string
-	body          string
-}
-
 // runGitHubRun is the entry point wired to `kit github run`.
 func runGitHubRun(cmd *cobra.Command, _ []string) error {
-	ctx := cmd.Context()
-
-	if !inGitHubActions() && !githubDryRun() {
-		return fmt.Errorf("kit github run is meant to run inside GitHub Actions (set GITHUB_ACTIONS=true or pass --dry-run)")
-	}
-
-	event, err := loadGitHubEvent()
-	if err != nil {
-		return err
-	}
-
-	tr, err := buildTrigger(event)
-	if err != nil {
-		// Not an actionable trigger (the workflow `if:` normally prevents this).
-		log.Info("github run: nothing to do", "reason", err)
-		return nil
-	}
-
-	if !writeAssociations[strings.ToUpper(tr.association)] {
-		log.Warn("github run: ignoring /kit from unauthorized author",
-			"author", tr.author, "association", tr.association)
-		return nil
-	}
-
-	model := resolveRunModel()
-	log.Info("github run: handling trigger",
-		"repo", tr.repo, "number", tr.number, "pr", tr.isPR, "author", tr.author, "model", model)
-
-	// React with 👀 so the human sees Kit picked up the request.
-	addReaction(ctx, tr, "eyes")
-
-	gathered := gatherContext(ctx, tr)
-	prompt := buildPrompt(tr, gathered)
-
-	response, runErr := runAgent(ctx, model, prompt)
-	if runErr != nil {
-		postComment(ctx, tr, "⚠️ Kit hit an error while processing this request:\n\n```\n"+runErr.Error()+"\n```")
-		addReaction(ctx, tr, "confused")
-		return runErr
-	}
-
-	response = strings.TrimSpace(response)
-	if response == "" {
-		response = "Kit finished without a textual response."
-	}
-
-	prURL := ""
-	if hasUncommittedChanges(ctx) {
-		prURL = openPullRequest(ctx, tr, response)
-	}
-
-	comment := response
-	if prURL != "" {
-		comment += "\n\n---\nOpened a pull request with the changes: " + prURL
-	}
-	postComment(ctx, tr, comment)
-	addReaction(ctx, tr, "rocket")
-	return nil
-}
-
-// resolveRunModel picks the model: --model flag, then $MODEL, then the default.
-func resolveRunModel() string {
-	if m := strings.TrimSpace(githubRunModel); m != "" {
-		return m
-	}
-	if m := strings.TrimSpace(os.Getenv("MODEL")); m != "" {
-		return m
-	}
-	return defaultGitHubModel
-}
-
-func inGitHubActions() bool {
-	return os.Getenv("GITHUB_ACTIONS") == "true"
-}
-
-// githubDryRun reports whether side effects should be logged instead of run.
-func githubDryRun() bool {
-	return githubRunDryRun || os.Getenv("KIT_GITHUB_DRY_RUN") != ""
+	return runForge(cmd, githubForge{})
 }
 
 // loadGitHubEvent reads and decodes the GitHub Actions event payload.
@@ -259,7 +149,7 @@ func buildTrigger(event *ghEvent) (*trigger, error) {
 		tr.number = event.Issue.Number
 		tr.title = event.Issue.Title
 		tr.body = event.Issue.Body
-		tr.isPR = len(event.Issue.PullRequest) > 0
+		tr.isPR = len(event.Issue.PullRequest) > 0 && string(event.Issue.PullRequest) != "null"
 		tr.commentKind = "issues"
 	case event.PullRequest != nil:
 		tr.number = event.PullRequest.Number
@@ -274,208 +164,129 @@ func buildTrigger(event *ghEvent) (*trigger, error) {
 	if tr.repo == "" {
 		return nil, fmt.Errorf("event is missing repository.full_name")
 	}
+	tr.review = tr.isPR && isReviewRequest(request)
 	return tr, nil
 }
 
-// extractRequest pulls the instruction text out of a comment body that mentions
-// the command token. It only recognizes the token at the start of a line
-// (mirroring the workflow guard) or at the very end, so incidental mid-sentence
-// mentions like "please review /kit behavior" do not trigger the handler. It
-// returns the remainder of the matching line as the request.
-func extractRequest(body string) (string, bool) {
-	for line := range strings.SplitSeq(body, "\n") {
-		trimmed := strings.TrimSpace(line)
-		var rest string
-		switch {
-		case trimmed == commandToken:
-			return "", true
-		case strings.HasPrefix(trimmed, commandToken+" "):
-			rest = trimmed[len(commandToken):]
-		case strings.HasSuffix(trimmed, " "+commandToken):
-			return "", true
-		default:
-			continue
-		}
-		return strings.TrimSpace(rest), true
-	}
-	return "", false
-}
+// githubForge runs Kit from GitHub Actions, using the gh CLI for the API.
+type githubForge struct{}
 
-// gatherContext assembles the issue thread or PR diff to give the agent. It
-// always includes the title/body from the event payload, and — outside dry-run,
-// when `gh` is available — enriches with the comment thread and PR diff.
-func gatherContext(ctx context.Context, tr *trigger) string {
-	var b strings.Builder
-	target := "Issue"
-	if tr.isPR {
-		target = "Pull request"
-	}
-	fmt.Fprintf(&b, "%s #%d: %s\n", target, tr.number, tr.title)
-	if strings.TrimSpace(tr.body) != "" {
-		fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(tr.body))
-	}
+func (githubForge) name() string    { return "github" }
+func (githubForge) ciEnv() string   { return "GITHUB_ACTIONS" }
+func (githubForge) available() bool { return commandExists("gh") }
 
-	if githubDryRun() || !commandExists("gh") {
-		return b.String()
+func (githubForge) loadTrigger() (*trigger, error) {
+	event, err := loadGitHubEvent()
+	if err != nil {
+		return nil, err
 	}
-
-	num := fmt.Sprint(tr.number)
-	if tr.isPR {
-		if diff := ghOutput(ctx, "pr", "diff", num, "--repo", tr.repo); diff != "" {
-			fmt.Fprintf(&b, "\n## Diff\n```diff\n%s\n```\n", strings.TrimSpace(diff))
-		}
-		if comments := ghOutput(ctx, "pr", "view", num, "--repo", tr.repo, "--json", "comments", "--jq", ".comments[] | \"@\\(.author.login): \\(.body)\""); comments != "" {
-			fmt.Fprintf(&b, "\n## Comments\n%s\n", strings.TrimSpace(comments))
-		}
-	} else {
-		if comments := ghOutput(ctx, "issue", "view", num, "--repo", tr.repo, "--json", "comments", "--jq", ".comments[] | \"@\\(.author.login): \\(.body)\""); comments != "" {
-			fmt.Fprintf(&b, "\n## Comments\n%s\n", strings.TrimSpace(comments))
-		}
+	tr, err := buildTrigger(event)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoTrigger, err)
 	}
-	return b.String()
+	return tr, nil
 }
 
-// buildPrompt constructs the instruction sent to the agent.
-func buildPrompt(tr *trigger, gathered string) string {
-	target := "issue"
-	if tr.isPR {
-		target = "pull request"
-	}
-	request := tr.request
-	if request == "" {
-		request = "(no explicit instruction — review the " + target + " and respond helpfully)"
-	}
-
-	var b strings.Builder
-	fmt.Fprintf(&b, "You are Kit, operating as an automated collaborator on the GitHub repository %s.\n\n", tr.repo)
-	fmt.Fprintf(&b, "@%s (access: %s) triggered you on %s #%d with this request:\n\n", tr.author, tr.association, target, tr.number)
-	fmt.Fprintf(&b, "%s\n\n", request)
-	fmt.Fprintf(&b, "## Context\n%s\n\n", strings.TrimSpace(gathered))
-	b.WriteString("Carry out the request. If you modify files, they will be committed to a new ")
-	b.WriteString("branch and a pull request will be opened automatically, so you do not need to ")
-	b.WriteString("commit or push yourself. Finish with a concise summary of what you did.")
-	return b.String()
+func (githubForge) authorized(_ context.Context, tr *trigger) bool {
+	return writeAssociations[strings.ToUpper(tr.association)]
 }
 
-// runAgent drives the agent headlessly by invoking this same binary in quiet,
-// ephemeral mode against the constructed prompt, and returns its response. In
-// dry-run it returns a canned response without spawning anything.
-func runAgent(ctx context.Context, model, prompt string) (string, error) {
-	if githubDryRun() {
-		log.Info("github run: [dry-run] would run agent", "model", model, "promptChars", len(prompt))
-		return "[dry-run] agent response", nil
-	}
-
-	exe, err := os.Executable()
-	if err != nil || exe == "" {
-		exe = "kit"
-	}
+func (githubForge) react(ctx context.Context, tr *trigger, content string) {
+	path := fmt.Sprintf("/repos/%s/%s/comments/%d/reactions", tr.repo, tr.commentKind, tr.commentID)
+	_ = runCmd(ctx, "gh", "api", "-X", "POST", path, "-f", "content="+content)
+}
 
-	runCtx, cancel := context.WithTimeout(ctx, agentTimeout)
-	defer cancel()
+func (githubForge) postComment(ctx context.Context, tr *trigger, body string) int64 {
+	path := fmt.Sprintf("repos/%s/issues/%d/comments", tr.repo, tr.number)
+	id, _ := strconv.ParseInt(strings.TrimSpace(ghOutput(ctx, "api", "-X", "POST", path, "-f", "body="+body, "--jq", ".id")), 10, 64)
+	return id
+}
 
-	args := []string{"--quiet", "--no-session", "--no-extensions"}
-	if model != "" {
-		args = append(args, "--model", model)
-	}
-	args = append(args, prompt)
+func (githubForge) editComment(ctx context.Context, tr *trigger, id int64, body string) {
+	path := fmt.Sprintf("repos/%s/issues/comments/%d", tr.repo, id)
+	_ = runCmd(ctx, "gh", "api", "-X", "PATCH", path, "-f", "body="+body)
+}
 
-	cmd := exec.CommandContext(runCtx, exe, args...)
-	cmd.Stderr = os.Stderr // surface agent progress/errors in the Actions log
-	out, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("agent run failed: %w", err)
-	}
-	return string(out), nil
+// prHead is the head branch of a pull request, as fetchChange asks gh for it.
+type prHead struct {
+	SHA                 string `json:"sha"`
+	Ref                 string `json:"ref"`
+	Repo                string `json:"repo"`
+	MaintainerCanModify bool   `json:"maintainer_can_modify"`
 }
 
-// hasUncommittedChanges reports whether the agent produced working-tree changes.
-func hasUncommittedChanges(ctx context.Context) bool {
-	if githubDryRun() {
-		return os.Getenv("KIT_GITHUB_FAKE_DIRTY") != ""
-	}
-	return strings.TrimSpace(gitOutput(ctx, "status", "--porcelain")) != ""
+// pushable reports whether Kit, running in baseRepo, may push to the head
+// branch: it is in baseRepo, or in a fork that allows maintainer edits. A
+// deleted fork leaves Repo empty, with nowhere to push.
+func (h prHead) pushable(baseRepo string) bool {
+	return h.Ref != "" && (h.Repo == baseRepo || (h.Repo != "" && h.MaintainerCanModify))
 }
 
-// openPullRequest commits the working tree as kit-agent[bot], pushes a branch,
-// and opens a PR. It returns the PR URL, or "" on failure / dry-run.
-func openPullRequest(ctx context.Context, tr *trigger, summary string) string {
-	branch := fmt.Sprintf("kit/issue-%d-%d", tr.number, time.Now().Unix())
-
-	runGit(ctx, "checkout", "-b", branch)
-	runGit(ctx, "add", "-A")
-	runGit(ctx, "-c", "user.name="+botName, "-c", "user.email="+botEmail,
-		"commit", "-m", fmt.Sprintf("kit: address #%d", tr.number))
-
-	// `persist-credentials: false` in the workflow means the checkout left no
-	// push credentials behind. Re-establish them from GITHUB_TOKEN via gh's git
-	// credential helper, then push over the existing origin remote.
-	if !githubDryRun() {
-		runCmd(ctx, "gh", "auth", "setup-git")
+// fetchChange loads the head branch and changed files of the trigger's pull
+// request and, for a review, Kit's earlier inline comments.
+func (githubForge) fetchChange(ctx context.Context, tr *trigger) *pullContext {
+	base := fmt.Sprintf("repos/%s/pulls/%d", tr.repo, tr.number)
+	pr := &pullContext{
+		files: decodeJSONLines[prFile](ghOutput(ctx, "api", "--paginate", base+"/files", "--jq", ".[]")),
 	}
-	runGit(ctx, "push", "origin", "HEAD:"+branch)
-
-	title := fmt.Sprintf("kit: changes for #%d", tr.number)
-	body := fmt.Sprintf("Automated changes from Kit in response to #%d.\n\n%s", tr.number, summary)
-	if githubDryRun() {
-		log.Info("github run: [dry-run] would open PR", "branch", branch, "base", tr.defaultBranch)
-		return ""
+	headFilter := "{sha: .head.sha, ref: .head.ref, repo: .head.repo.full_name, maintainer_can_modify}"
+	if heads := decodeJSONLines[prHead](ghOutput(ctx, "api", base, "--jq", headFilter)); len(heads) > 0 {
+		h := heads[0]
+		pr.headSHA, pr.headRef, pr.headRepo = h.SHA, h.Ref, h.Repo
+		pr.canPush = h.pushable(tr.repo)
 	}
-	return strings.TrimSpace(ghOutput(ctx, "pr", "create", "--repo", tr.repo,
-		"--head", branch, "--base", tr.defaultBranch, "--title", title, "--body", body))
-}
-
-// addReaction adds an emoji reaction to the trigger comment.
-func addReaction(ctx context.Context, tr *trigger, content string) {
-	path := fmt.Sprintf("/repos/%s/%s/comments/%d/reactions", tr.repo, tr.commentKind, tr.commentID)
-	if githubDryRun() || !commandExists("gh") {
-		log.Info("github run: [dry-run] react", "content", content, "path", path)
-		return
+	if tr.review {
+		filter := fmt.Sprintf(".[] | select(.body | contains(%q)) | {path, line, body}", reviewMarker)
+		pr.prior = decodeJSONLines[reviewLineComment](ghOutput(ctx, "api", "--paginate", base+"/comments", "--jq", filter))
 	}
-	runCmd(ctx, "gh", "api", "-X", "POST", path, "-f", "content="+content)
+	return pr
 }
 
-// postComment posts a comment back on the triggering issue or pull request.
-func postComment(ctx context.Context, tr *trigger, body string) {
+func (githubForge) discussion(ctx context.Context, tr *trigger) string {
 	sub := "issue"
 	if tr.isPR {
 		sub = "pr"
 	}
-	if githubDryRun() || !commandExists("gh") {
-		log.Info("github run: [dry-run] comment", "sub", sub, "number", tr.number, "chars", len(body))
-		return
-	}
-	runCmd(ctx, "gh", sub, "comment", fmt.Sprint(tr.number), "--repo", tr.repo, "--body", body)
+	return ghOutput(ctx, sub, "view", strconv.Itoa(tr.number), "--repo", tr.repo, "--json", "comments", "--jq", ".comments[] | \"@\\(.author.login): \\(.body)\"")
 }
 
-// --- thin subprocess helpers -------------------------------------------------
+// setupGit re-establishes push credentials from GITHUB_TOKEN via gh's git
+// credential helper: `persist-credentials: false` in the workflow means the
+// checkout left none behind.
+func (githubForge) setupGit(ctx context.Context) error {
+	return runCmd(ctx, "gh", "auth", "setup-git")
+}
 
-func commandExists(name string) bool {
-	_, err := exec.LookPath(name)
-	return err == nil
+// gitAuth points a single git command at gh's credential helper, which is
+// what `gh auth setup-git` installs.
+func (githubForge) gitAuth() []string {
+	return []string{"-c", "credential.helper=", "-c", "credential.helper=!gh auth git-credential"}
 }
 
-// runGit runs a mutating git command, logging instead of executing in dry-run.
-func runGit(ctx context.Context, args ...string) {
-	if githubDryRun() {
-		log.Info("github run: [dry-run] git", "args", strings.Join(args, " "))
-		return
-	}
-	runCmd(ctx, "git", args...)
+func (githubForge) repoURL(repo string) string {
+	return fmt.Sprintf("%s/%s.git", githubServerURL(), repo)
 }
 
-// gitOutput runs a read-only git command and returns its stdout.
-func gitOutput(ctx context.Context, args ...string) string {
-	cmdCtx, cancel := context.WithTimeout(ctx, subprocessTimeout)
-	defer cancel()
-	out, err := exec.CommandContext(cmdCtx, "git", args...).Output()
-	if err != nil {
-		log.Error("github run: git failed", "args", strings.Join(args, " "), "err", err)
-		return ""
+func (githubForge) commitURL(repo, sha string) string {
+	return fmt.Sprintf("%s/%s/commit/%s", githubServerURL(), repo, sha)
+}
+
+func (githubForge) openChange(ctx context.Context, tr *trigger, branch, title, body string) string {
+	return strings.TrimSpace(ghOutput(ctx, "pr", "create", "--repo", tr.repo,
+		"--head", branch, "--base", tr.defaultBranch, "--title", title, "--body", body))
+}
+
+// githubServerURL is the GitHub web root, which differs on GitHub Enterprise
+// (and on Gitea, whose Actions set the same variable).
+func githubServerURL() string {
+	if url := strings.TrimSuffix(os.Getenv("GITHUB_SERVER_URL"), "/"); url != "" {
+		return url
 	}
-	return string(out)
+	return "https://github.com"
 }
 
+// --- gh helpers --------------------------------------------------------------
+
 // ghOutput runs a gh command and returns its stdout.
 func ghOutput(ctx context.Context, args ...string) string {
 	cmdCtx, cancel := context.WithTimeout(ctx, subprocessTimeout)
@@ -487,12 +298,3 @@ func ghOutput(ctx context.Context, args ...string) string {
 	}
 	return string(out)
 }
-
-// runCmd runs a command for its side effects, surfacing failures in the log.
-func runCmd(ctx context.Context, name string, args ...string) {
-	cmdCtx, cancel := context.WithTimeout(ctx, subprocessTimeout)
-	defer cancel()
-	if out, err := exec.CommandContext(cmdCtx, name, args...).CombinedOutput(); err != nil {
-		log.Error("github run: command failed", "cmd", name, "err", err, "output", strings.TrimSpace(string(out)))
-	}
-}
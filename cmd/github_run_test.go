@@ -21,8 +21,8 @@ func setupEvent(t *testing.T, payload string) {
 	t.Setenv("KIT_GITHUB_DRY_RUN", "1")
 	t.Setenv("GITHUB_EVENT_PATH", path)
 	t.Cleanup(func() {
-		githubRunModel = ""
-		githubRunDryRun = false
+		forgeRunModel = ""
+		forgeRunDryRun = false
 	})
 }
 
@@ -87,7 +87,7 @@ func TestBuildPrompt_ContainsContext(t *testing.T) {
 	event, _ := loadGitHubEvent()
 	tr, _ := buildTrigger(event)
 
-	prompt := buildPrompt(tr, gatherContext(context.Background(), tr))
+	prompt := buildPrompt(tr, gatherContext(context.Background(), githubForge{}, tr, nil))
 	for _, want := range []string{
 		"fix the broken parser",         // the request
 		"acme/widgets",                  // the repo
@@ -162,18 +162,18 @@ func TestRunGitHub_RequiresActionsOrDryRun(t *testing.T) {
 	// Neither GITHUB_ACTIONS nor dry-run set → must error rather than act.
 	t.Setenv("GITHUB_ACTIONS", "")
 	t.Setenv("KIT_GITHUB_DRY_RUN", "")
-	githubRunDryRun = false
-	t.Cleanup(func() { githubRunDryRun = false })
+	forgeRunDryRun = false
+	t.Cleanup(func() { forgeRunDryRun = false })
 	if err := runGitHubRun(githubRunCmd, nil); err == nil {
 		t.Fatal("expected an error when run outside Actions without --dry-run")
 	}
 }
 
 func TestResolveRunModel(t *testing.T) {
-	t.Cleanup(func() { githubRunModel = "" })
+	t.Cleanup(func() { forgeRunModel = "" })
 
 	t.Setenv("MODEL", "")
-	githubRunModel = ""
+	forgeRunModel = ""
 	if got := resolveRunModel(); got != defaultGitHubModel {
 		t.Errorf("default model = %q, want %q", got, defaultGitHubModel)
 	}
@@ -183,7 +183,7 @@ func TestResolveRunModel(t *testing.T) {
 		t.Errorf("MODEL env model = %q, want openai/gpt-5", got)
 	}
 
-	githubRunModel = "anthropic/claude-sonnet-4-5"
+	forgeRunModel = "anthropic/claude-sonnet-4-5"
 	if got := resolveRunModel(); got != "anthropic/claude-sonnet-4-5" {
 		t.Errorf("flag model = %q, want anthropic/claude-sonnet-4-5", got)
 	}
@@ -0,0 +1,461 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+// gitlabCIPath is the repository-relative location of the generated GitLab CI
+// job. It is a separate file for the project's .gitlab-ci.yml to include, so
+// installing never rewrites an existing pipeline.
+const gitlabCIPath = ".gitlab/kit.gitlab-ci.yml"
+
+// sandboxImage is the container image the generated GitLab and Gitea jobs run
+// in: it ships kit, git and the forge CLIs (deploy/sandbox).
+const sandboxImage = "ghcr.io/mark3labs/kit-sandbox:latest"
+
+// gitlabMinAccess is the lowest GitLab access level that may trigger Kit:
+// Developer, the first role that can push.
+const gitlabMinAccess = 30
+
+// gitlabRoles names the access levels gitlabMinAccess admits.
+var gitlabRoles = map[int]string{30: "DEVELOPER", 40: "MAINTAINER", 50: "OWNER"}
+
+// glabVariables stores GitLab CI/CD variables.
+var glabVariables = secretSetter{cli: "glab", args: []string{"variable", "set"}, flags: []string{"--masked"}, kind: "CI/CD variable"}
+
+// gitlabCmd is the parent command for the GitLab integration, the GitLab CI
+// counterpart of githubCmd.
+var gitlabCmd = &cobra.Command{
+	Use:   "gitlab",
+	Short: "Set up Kit as a GitLab collaborator",
+	Long: `Set up Kit as an automated collaborator in a GitLab project.
+
+Kit runs as a GitLab CI job started by a project webhook whenever someone
+comments '/kit ...' on an issue or merge request. It reads the note and the
+issue or merge request, runs the agent non-interactively, and responds by
+replying in the thread, pushing to merge request branches and opening merge
+requests.
+
+Use 'kit gitlab install' to scaffold the CI job.`,
+}
+
+// gitlabInstallCmd scaffolds the GitLab CI job that runs Kit.
+var gitlabInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Scaffold the GitLab CI job that runs Kit",
+	Long: `Scaffold the GitLab CI job that runs Kit as a collaborator.
+
+This writes .gitlab/kit.gitlab-ci.yml, a job for your .gitlab-ci.yml to
+include. GitLab CI cannot start pipelines from comments directly, so the job
+runs on pipelines triggered by a project webhook for comment events; the
+webhook payload reaches the job as $TRIGGER_PAYLOAD. The printed next steps
+walk through creating the trigger token and the webhook.
+
+The job needs two masked CI/CD variables: the provider API key, and
+GITLAB_TOKEN, a project access token with the api and write_repository scopes
+that Kit replies and pushes with. If the GitLab CLI ('glab') is detected on
+your PATH, you will be offered the option to set the provider key.
+
+Examples:
+  kit gitlab install
+  kit gitlab install --model anthropic/claude-sonnet-4-5-20250929
+  kit gitlab install --force --no-secret`,
+	Args: cobra.NoArgs,
+	RunE: runGitLabInstall,
+}
+
+// gitlabRunCmd is the runtime half of the GitLab integration, run by the job
+// gitlabInstallCmd scaffolds.
+var gitlabRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run Kit against the GitLab note that triggered this pipeline",
+	Long: `Run Kit against the GitLab note that triggered this pipeline.
+
+This command is normally run by the job 'kit gitlab install' scaffolds; you
+rarely run it by hand. It reads the comment (note) webhook payload from
+$TRIGGER_PAYLOAD, verifies the author has at least the Developer role, reacts
+with an emoji while it works, runs the agent non-interactively against the
+issue or merge request, and keeps a single reply in the note's thread updated
+in place (picked up, working, done). If the agent modified files on a merge
+request whose branch Kit may push to, the changes are committed as
+kit-agent[bot] and pushed to that branch; otherwise Kit pushes a new branch and
+opens a merge request.
+
+API calls go through glab, authenticated by $GITLAB_TOKEN.
+
+Set --dry-run (or KIT_GITLAB_DRY_RUN=1) to log every git/glab side effect and
+skip the agent run instead of executing them.`,
+	Args: cobra.NoArgs,
+	RunE: runGitLabRun,
+}
+
+func init() {
+	addInstallFlags(gitlabInstallCmd, "CI job", "glab")
+	addRunFlags(gitlabRunCmd)
+	gitlabCmd.AddCommand(gitlabInstallCmd, gitlabRunCmd)
+	rootCmd.AddCommand(gitlabCmd)
+}
+
+func runGitLabInstall(cmd *cobra.Command, _ []string) error {
+	model, secretName, err := resolveInstallModel()
+	if err != nil {
+		return err
+	}
+	if err := writeCIFile(gitlabCIPath, renderGitLabCI(model, secretName), forgeInstallForce); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Wrote %s\n", gitlabCIPath)
+
+	maybeSetSecret(cmd.Context(), glabVariables, secretName)
+
+	fmt.Println("\nNext steps:")
+	fmt.Printf("  1. Include the job from .gitlab-ci.yml and commit both:\n       include:\n         - local: %s\n", gitlabCIPath)
+	fmt.Printf("  2. Add the masked CI/CD variables %s and GITLAB_TOKEN (a project access token,\n", secretName)
+	fmt.Println("     Developer role, api + write_repository scopes) under Settings → CI/CD → Variables.")
+	fmt.Println("  3. Create a pipeline trigger token under Settings → CI/CD → Pipeline trigger tokens.")
+	fmt.Println("  4. Add a webhook under Settings → Webhooks for \"Comments\" events with the URL")
+	fmt.Println("       <gitlab>/api/v4/projects/<project id>/ref/<default branch>/trigger/pipeline?token=<trigger token>")
+	fmt.Println("  5. Comment '/kit <your request>' on an issue or merge request to trigger Kit.")
+	log.Info("gitlab CI job scaffolded", "model", model, "secret", secretName)
+	return nil
+}
+
+// renderGitLabCI builds the CI job for the given model and provider secret.
+func renderGitLabCI(model, secretName string) string {
+	return fmt.Sprintf(`# Runs Kit when someone comments "/kit ..." on an issue or merge request.
+#
+# GitLab CI cannot start pipelines from comments, so a project webhook for
+# comment events calls the pipeline trigger API; the note arrives in
+# $TRIGGER_PAYLOAD. Include this file from .gitlab-ci.yml:
+#
+#   include:
+#     - local: %s
+#
+# Needs the masked CI/CD variables %s and GITLAB_TOKEN (a project access token
+# with the Developer role and the api and write_repository scopes).
+kit:
+  image: %s
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "trigger" && $TRIGGER_PAYLOAD
+  variables:
+    MODEL: %s
+    GITLAB_HOST: $CI_SERVER_URL
+  script:
+    - kit gitlab run
+`, gitlabCIPath, secretName, sandboxImage, model)
+}
+
+// runGitLabRun is the entry point wired to `kit gitlab run`.
+func runGitLabRun(cmd *cobra.Command, _ []string) error {
+	return runForge(cmd, gitlabForge{})
+}
+
+// --- GitLab event types ------------------------------------------------------
+
+type glUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type glNoteable struct {
+	IID         int    `json:"iid"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// glNoteEvent is the payload of a GitLab comment ("Note Hook") webhook.
+type glNoteEvent struct {
+	ObjectKind string `json:"object_kind"`
+	User       glUser `json:"user"`
+	Project    struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		DefaultBranch     string `json:"default_branch"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		ID           int64  `json:"id"`
+		Note         string `json:"note"`
+		NoteableType string `json:"noteable_type"`
+		DiscussionID string `json:"discussion_id"`
+	} `json:"object_attributes"`
+	Issue        *glNoteable `json:"issue"`
+	MergeRequest *glNoteable `json:"merge_request"`
+}
+
+// buildGitLabTrigger normalises a note event into a trigger, or returns an
+// error when the note is not an actionable `/kit` comment.
+func buildGitLabTrigger(event *glNoteEvent) (*trigger, error) {
+	if event.ObjectKind != "note" {
+		return nil, fmt.Errorf("event is a %q, not a note", event.ObjectKind)
+	}
+	request, ok := extractRequest(event.ObjectAttributes.Note)
+	if !ok {
+		return nil, fmt.Errorf("note does not contain the %q command", commandToken)
+	}
+
+	tr := &trigger{
+		repo:          event.Project.PathWithNamespace,
+		defaultBranch: event.Project.DefaultBranch,
+		commentID:     event.ObjectAttributes.ID,
+		threadID:      event.ObjectAttributes.DiscussionID,
+		author:        event.User.Username,
+		authorID:      event.User.ID,
+		request:       request,
+	}
+	if tr.defaultBranch == "" {
+		tr.defaultBranch = "main"
+	}
+
+	var target *glNoteable
+	switch event.ObjectAttributes.NoteableType {
+	case "Issue":
+		target, tr.commentKind = event.Issue, "issues"
+	case "MergeRequest":
+		target, tr.commentKind, tr.isPR = event.MergeRequest, "merge_requests", true
+	default:
+		return nil, fmt.Errorf("notes on %s are not supported", event.ObjectAttributes.NoteableType)
+	}
+	if target == nil {
+		return nil, fmt.Errorf("event has no %s", event.ObjectAttributes.NoteableType)
+	}
+	tr.number, tr.title, tr.body = target.IID, target.Title, target.Description
+
+	if tr.repo == "" {
+		return nil, fmt.Errorf("event is missing project.path_with_namespace")
+	}
+	tr.review = tr.isPR && isReviewRequest(request)
+	return tr, nil
+}
+
+// --- GitLab API types --------------------------------------------------------
+
+type glMergeRequest struct {
+	SHA                string `json:"sha"`
+	SourceBranch       string `json:"source_branch"`
+	SourceProjectID    int64  `json:"source_project_id"`
+	TargetProjectID    int64  `json:"target_project_id"`
+	AllowCollaboration bool   `json:"allow_collaboration"`
+}
+
+type glDiff struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	Diff        string `json:"diff"`
+	NewFile     bool   `json:"new_file"`
+	RenamedFile bool   `json:"renamed_file"`
+	DeletedFile bool   `json:"deleted_file"`
+}
+
+type glDiscussion struct {
+	Notes []struct {
+		Author   glUser `json:"author"`
+		Body     string `json:"body"`
+		System   bool   `json:"system"`
+		Position *struct {
+			NewPath string `json:"new_path"`
+			NewLine int    `json:"new_line"`
+		} `json:"position"`
+	} `json:"notes"`
+}
+
+// gitlabFiles converts merge request diffs to the forge-agnostic file list.
+func gitlabFiles(diffs []glDiff) []prFile {
+	files := make([]prFile, 0, len(diffs))
+	for _, d := range diffs {
+		f := prFile{Filename: d.NewPath, Status: "modified", Patch: d.Diff}
+		switch {
+		case d.NewFile:
+			f.Status = "added"
+		case d.DeletedFile:
+			f.Status = "removed"
+		case d.RenamedFile:
+			f.Status = "renamed"
+		}
+		f.Additions, f.Deletions = patchStats(d.Diff)
+		files = append(files, f)
+	}
+	return files
+}
+
+// formatGitLabDiscussions renders discussions as "@user: body" lines, with the
+// file and line of diff notes. System notes ("added 1 commit") are skipped.
+func formatGitLabDiscussions(discussions []glDiscussion) string {
+	var b strings.Builder
+	for _, d := range discussions {
+		for _, n := range d.Notes {
+			if n.System {
+				continue
+			}
+			if n.Position != nil && n.Position.NewPath != "" {
+				fmt.Fprintf(&b, "@%s on %s:%d: %s\n", n.Author.Username, n.Position.NewPath, n.Position.NewLine, n.Body)
+				continue
+			}
+			fmt.Fprintf(&b, "@%s: %s\n", n.Author.Username, n.Body)
+		}
+	}
+	return b.String()
+}
+
+// gitlabForge runs Kit from GitLab CI, using the glab CLI for the API.
+type gitlabForge struct{}
+
+func (gitlabForge) name() string    { return "gitlab" }
+func (gitlabForge) ciEnv() string   { return "GITLAB_CI" }
+func (gitlabForge) available() bool { return commandExists("glab") }
+
+func (gitlabForge) loadTrigger() (*trigger, error) {
+	path := os.Getenv("TRIGGER_PAYLOAD")
+	if path == "" {
+		return nil, fmt.Errorf("TRIGGER_PAYLOAD is not set; the pipeline must be started by the comment webhook")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading trigger payload: %w", err)
+	}
+	var event glNoteEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("parsing trigger payload: %w", err)
+	}
+	tr, err := buildGitLabTrigger(&event)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoTrigger, err)
+	}
+	return tr, nil
+}
+
+// authorized checks the author's project membership, inherited ones
+// included. The note payload carries no role, so this costs an API call.
+func (g gitlabForge) authorized(ctx context.Context, tr *trigger) bool {
+	if !forgeLive(g) {
+		log.Info("gitlab run: [dry-run] skipping the membership check", "author", tr.author)
+		return true
+	}
+	var member struct {
+		AccessLevel int `json:"access_level"`
+	}
+	out := glabOutput(ctx, "api", fmt.Sprintf("%s/members/all/%d", gitlabProject(tr), tr.authorID))
+	if err := json.Unmarshal([]byte(out), &member); err != nil {
+		return false
+	}
+	tr.association = gitlabRoles[member.AccessLevel]
+	return member.AccessLevel >= gitlabMinAccess
+}
+
+func (gitlabForge) react(ctx context.Context, tr *trigger, content string) {
+	path := fmt.Sprintf("%s/notes/%d/award_emoji", gitlabNoteable(tr), tr.commentID)
+	_ = runCmd(ctx, "glab", "api", "-X", "POST", path, "-f", "name="+content)
+}
+
+// postComment replies in the trigger note's thread.
+func (gitlabForge) postComment(ctx context.Context, tr *trigger, body string) int64 {
+	path := gitlabNoteable(tr) + "/notes"
+	if tr.threadID != "" {
+		path = fmt.Sprintf("%s/discussions/%s/notes", gitlabNoteable(tr), tr.threadID)
+	}
+	var note struct {
+		ID int64 `json:"id"`
+	}
+	_ = json.Unmarshal([]byte(glabOutput(ctx, "api", "-X", "POST", path, "-f", "body="+body)), &note)
+	return note.ID
+}
+
+func (gitlabForge) editComment(ctx context.Context, tr *trigger, id int64, body string) {
+	path := fmt.Sprintf("%s/notes/%d", gitlabNoteable(tr), id)
+	_ = runCmd(ctx, "glab", "api", "-X", "PUT", path, "-f", "body="+body)
+}
+
+// fetchChange loads the merge request's source branch and diffs. A source
+// branch in a fork is pushable when the author allowed commits from members
+// who can merge.
+func (gitlabForge) fetchChange(ctx context.Context, tr *trigger) *pullContext {
+	base := gitlabNoteable(tr)
+	pr := &pullContext{
+		files: gitlabFiles(decodeJSONPages[glDiff](glabOutput(ctx, "api", "--paginate", base+"/diffs"))),
+	}
+	var mr glMergeRequest
+	if err := json.Unmarshal([]byte(glabOutput(ctx, "api", base)), &mr); err != nil {
+		return pr
+	}
+	head := prHead{SHA: mr.SHA, Ref: mr.SourceBranch, Repo: tr.repo, MaintainerCanModify: mr.AllowCollaboration}
+	if mr.SourceProjectID != mr.TargetProjectID {
+		var source struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		}
+		_ = json.Unmarshal([]byte(glabOutput(ctx, "api", "projects/"+strconv.FormatInt(mr.SourceProjectID, 10))), &source)
+		head.Repo = source.PathWithNamespace
+	}
+	pr.headSHA, pr.headRef, pr.headRepo = head.SHA, head.Ref, head.Repo
+	pr.canPush = head.pushable(tr.repo)
+	return pr
+}
+
+func (gitlabForge) discussion(ctx context.Context, tr *trigger) string {
+	return formatGitLabDiscussions(decodeJSONPages[glDiscussion](glabOutput(ctx, "api", "--paginate", gitlabNoteable(tr)+"/discussions")))
+}
+
+// setupGit authenticates git with GITLAB_TOKEN: the CI job token the checkout
+// used cannot push.
+func (gitlabForge) setupGit(ctx context.Context) error {
+	return setupGitCredentials(ctx, "GITLAB_TOKEN")
+}
+
+func (gitlabForge) gitAuth() []string {
+	return gitCredentialArgs("GITLAB_TOKEN")
+}
+
+func (gitlabForge) repoURL(repo string) string {
+	return fmt.Sprintf("%s/%s.git", gitlabServerURL(), repo)
+}
+
+func (gitlabForge) commitURL(repo, sha string) string {
+	return fmt.Sprintf("%s/%s/-/commit/%s", gitlabServerURL(), repo, sha)
+}
+
+func (gitlabForge) openChange(ctx context.Context, tr *trigger, branch, title, body string) string {
+	var mr struct {
+		WebURL string `json:"web_url"`
+	}
+	_ = json.Unmarshal([]byte(glabOutput(ctx, "api", "-X", "POST", gitlabProject(tr)+"/merge_requests",
+		"-f", "source_branch="+branch, "-f", "target_branch="+tr.defaultBranch,
+		"-f", "title="+title, "-f", "description="+body, "-f", "remove_source_branch=true")), &mr)
+	return mr.WebURL
+}
+
+// gitlabProject is the API path of the trigger's project.
+func gitlabProject(tr *trigger) string {
+	return "projects/" + url.PathEscape(tr.repo)
+}
+
+// gitlabNoteable is the API path of the trigger's issue or merge request.
+func gitlabNoteable(tr *trigger) string {
+	return fmt.Sprintf("%s/%s/%d", gitlabProject(tr), tr.commentKind, tr.number)
+}
+
+// gitlabServerURL is the GitLab web root CI runs against.
+func gitlabServerURL() string {
+	if server := strings.TrimSuffix(os.Getenv("CI_SERVER_URL"), "/"); server != "" {
+		return server
+	}
+	return "https://gitlab.com"
+}
+
+// glabOutput runs a glab command and returns its stdout.
+func glabOutput(ctx context.Context, args ...string) string {
+	cmdCtx, cancel := context.WithTimeout(ctx, subprocessTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(cmdCtx, "glab", args...).Output()
+	if err != nil {
+		log.Error("gitlab run: glab failed", "args", strings.Join(args, " "), "err", err)
+		return ""
+	}
+	return string(out)
+}
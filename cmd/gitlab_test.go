@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const mergeRequestNoteEvent = `{
+  "object_kind": "note",
+  "user": {"id": 17, "username": "alice"},
+  "project": {"path_with_namespace": "acme/widgets", "default_branch": "main"},
+  "object_attributes": {
+    "id": 555,
+    "note": "/kit add a test for the cache",
+    "noteable_type": "MergeRequest",
+    "discussion_id": "d41d8cd9"
+  },
+  "merge_request": {"iid": 7, "title": "Add caching", "description": "Speeds things up."}
+}`
+
+// setupGitLabEvent writes a note payload to a temp file, points
+// TRIGGER_PAYLOAD at it, and forces dry-run + GitLab CI mode.
+func setupGitLabEvent(t *testing.T, payload string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(path, []byte(payload), 0o644); err != nil {
+		t.Fatalf("write payload: %v", err)
+	}
+	t.Setenv("GITLAB_CI", "true")
+	t.Setenv("KIT_GITLAB_DRY_RUN", "1")
+	t.Setenv("TRIGGER_PAYLOAD", path)
+	t.Cleanup(func() {
+		forgeRunModel = ""
+		forgeRunDryRun = false
+	})
+}
+
+func TestBuildGitLabTrigger(t *testing.T) {
+	var event glNoteEvent
+	if err := json.Unmarshal([]byte(mergeRequestNoteEvent), &event); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := buildGitLabTrigger(&event)
+	if err != nil {
+		t.Fatalf("buildGitLabTrigger: %v", err)
+	}
+	if tr.repo != "acme/widgets" || tr.number != 7 || !tr.isPR || tr.commentKind != "merge_requests" ||
+		tr.threadID != "d41d8cd9" || tr.authorID != 17 || tr.request != "add a test for the cache" {
+		t.Errorf("unexpected trigger: %+v", tr)
+	}
+	tr.forge = "gitlab"
+	if tr.ref() != "!7" || tr.changeName() != "merge request" {
+		t.Errorf("ref = %s, changeName = %s", tr.ref(), tr.changeName())
+	}
+	if got := gitlabNoteable(tr); got != "projects/acme%2Fwidgets/merge_requests/7" {
+		t.Errorf("gitlabNoteable = %s", got)
+	}
+
+	event.ObjectKind = "issue"
+	if _, err := buildGitLabTrigger(&event); err == nil {
+		t.Error("expected an error for a non-note event")
+	}
+}
+
+func TestRunGitLab_DryRun(t *testing.T) {
+	setupGitLabEvent(t, mergeRequestNoteEvent)
+	if err := runGitLabRun(gitlabRunCmd, nil); err != nil {
+		t.Fatalf("runGitLabRun: %v", err)
+	}
+
+	setupGitLabEvent(t, strings.Replace(mergeRequestNoteEvent, "/kit add a test for the cache", "lgtm", 1))
+	if err := runGitLabRun(gitlabRunCmd, nil); err != nil {
+		t.Fatalf("runGitLabRun should be a no-op without /kit, got: %v", err)
+	}
+}
+
+func TestGitLabFiles(t *testing.T) {
+	files := gitlabFiles([]glDiff{
+		{NewPath: "cache.go", Diff: reviewPatch},
+		{NewPath: "new.go", NewFile: true, Diff: "@@ -0,0 +1 @@\n+package cache"},
+	})
+	if len(files) != 2 {
+		t.Fatalf("files = %+v", files)
+	}
+	if files[0].Status != "modified" || files[0].Additions != 3 || files[0].Deletions != 2 {
+		t.Errorf("first file = %+v", files[0])
+	}
+	if files[1].Status != "added" || files[1].Additions != 1 {
+		t.Errorf("second file = %+v", files[1])
+	}
+}
+
+func TestFormatGitLabDiscussions(t *testing.T) {
+	discussions := decodeJSONPages[glDiscussion](`[{"notes": [
+  {"author": {"username": "bob"}, "body": "Why 100?", "position": {"new_path": "cache.go", "new_line": 2}},
+  {"author": {"username": "alice"}, "body": "added 1 commit", "system": true}
+]}]
+[{"notes": [{"author": {"username": "alice"}, "body": "Looks good."}]}]`)
+	got := formatGitLabDiscussions(discussions)
+	want := "@bob on cache.go:2: Why 100?\n@alice: Looks good.\n"
+	if got != want {
+		t.Errorf("formatGitLabDiscussions = %q, want %q", got, want)
+	}
+}
+
+func TestRenderGitLabCI(t *testing.T) {
+	out := renderGitLabCI("anthropic/claude-sonnet-4-5-20250929", "ANTHROPIC_API_KEY")
+	for _, want := range []string{
+		"local: .gitlab/kit.gitlab-ci.yml",
+		`$CI_PIPELINE_SOURCE == "trigger" && $TRIGGER_PAYLOAD`,
+		"image: " + sandboxImage,
+		"MODEL: anthropic/claude-sonnet-4-5-20250929",
+		"ANTHROPIC_API_KEY and GITLAB_TOKEN",
+		"kit gitlab run",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rendered CI job missing %q\n---\n%s", want, out)
+		}
+	}
+}
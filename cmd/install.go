@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os/exec"
 
+	"charm.land/huh/v2"
 	"github.com/charmbracelet/log"
 	"github.com/mark3labs/kit/internal/extensions"
 	"github.com/spf13/cobra"
@@ -25,6 +26,10 @@ The install command downloads and installs Kit extensions from git repositories.
 Extensions are stored in the global extensions directory by default, or in the
 project's .kit/git/ directory when using the --local flag.
 
+Before installing, each extension is listed with the capabilities it declares
+(events, tools, ui, network, filesystem, exec). Extensions that declare none
+get full access and are marked as such.
+
 When a repo contains multiple extensions, an interactive multi-select is shown
 so you can choose which to install. Use --all to skip selection and install everything.
 
@@ -117,8 +122,21 @@ func runInstallPackage(installer *extensions.Installer, source *extensions.GitSo
 		scopeStr = "locally in .kit/git/"
 	}
 
+	printInstallPreview(source, previews)
+
 	// Single extension or --all flag: install everything directly
 	if len(previews) == 1 || installAllFlag {
+		if !installAllFlag && isInteractive() {
+			var confirm bool
+			if err := huh.NewConfirm().
+				Title(fmt.Sprintf("Install %s %s?", previews[0].Name, scopeStr)).
+				Description("Capabilities: " + previews[0].Capabilities.String()).
+				Value(&confirm).
+				Run(); err != nil || !confirm {
+				fmt.Println("Install cancelled.")
+				return nil
+			}
+		}
 		if err := installer.Install(source, scope); err != nil {
 			return fmt.Errorf("install failed: %w", err)
 		}
@@ -156,6 +174,22 @@ func runInstallPackage(installer *extensions.Installer, source *extensions.GitSo
 	return nil
 }
 
+// printInstallPreview lists the extensions found in source with the
+// capabilities each declares, so the user sees what they grant before
+// anything is installed.
+func printInstallPreview(source *extensions.GitSource, previews []extensions.ExtensionPreview) {
+	fmt.Printf("Extensions in %s:\n", source.String())
+	for _, p := range previews {
+		fmt.Printf("  %s  %s\n", p.Name, p.Path)
+		if p.CapabilitiesErr != "" {
+			fmt.Printf("    capabilities: invalid (%s)\n", p.CapabilitiesErr)
+		} else {
+			fmt.Printf("    capabilities: %s\n", p.Capabilities)
+		}
+	}
+	fmt.Println()
+}
+
 func runUpdate(installer *extensions.Installer, source *extensions.GitSource, scope extensions.InstallScope) error {
 	// Find the installed package
 	existingScope, installed := installer.IsInstalled(source)
@@ -34,7 +34,7 @@ func multiSelectForInstall(previews []extensions.ExtensionPreview) ([]string, er
 	// Build options for huh MultiSelect
 	options := make([]huh.Option[string], len(previews))
 	for i, p := range previews {
-		label := fmt.Sprintf("%s  %s", p.Name, p.Path)
+		label := fmt.Sprintf("%s  %s  [%s]", p.Name, p.Path, p.Capabilities)
 		options[i] = huh.NewOption(label, p.Path).Selected(true)
 	}
 
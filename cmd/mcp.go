@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/mark3labs/kit/internal/config"
+	"github.com/mark3labs/kit/internal/mcpserver"
+	"github.com/mark3labs/kit/internal/prompts"
+)
+
+var (
+	mcpTransport string // --transport: stdio or http
+	mcpAddr      string // --addr: listen address for http
+	mcpEndpoint  string // --endpoint: URL path for http
+	mcpToken     string // --token: bearer token for http
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Use Kit over the Model Context Protocol",
+	Long:  "Commands for running Kit as an MCP server that other agents and IDEs can delegate to.",
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve Kit's tools, agent, prompts and sessions over MCP",
+	Long: `Start Kit as an MCP server.
+
+The server exposes:
+  - the core tools (bash, read, write, edit, apply_patch, grep, find, ls,
+    bash_output, bash_kill), subject to the "permissions" policy; calls the
+    policy would ask about are denied
+  - run_agent, which runs a full Kit turn with the configured model, skills,
+    extensions and MCP servers, reporting progress notifications; pass the
+    returned session_id to continue the conversation
+  - prompt templates as MCP prompts
+  - sessions saved for the current directory as kit://sessions/{id} resources
+
+By default the server speaks MCP over stdio. Use --transport http to serve
+streamable HTTP on --addr at --endpoint instead. HTTP clients must send
+"Authorization: Bearer <token>" with the token from --token or
+$KIT_MCP_TOKEN; without either, a random token is generated and printed at
+startup. Requests whose Host or Origin is not a loopback address are
+rejected.`,
+	Example: `  kit mcp serve
+  kit mcp serve -m anthropic/claude-sonnet-4-5-20250929
+  kit mcp serve --transport http --addr 127.0.0.1:8765`,
+	RunE: runMCPServe,
+}
+
+func init() {
+	mcpServeCmd.Flags().StringVar(&mcpTransport, "transport", "stdio", "transport to serve: stdio or http")
+	mcpServeCmd.Flags().StringVar(&mcpAddr, "addr", "127.0.0.1:8765", "listen address for the http transport")
+	mcpServeCmd.Flags().StringVar(&mcpEndpoint, "endpoint", "/mcp", "URL path for the http transport")
+	mcpServeCmd.Flags().StringVar(&mcpToken, "token", "", "bearer token http clients must send (default $KIT_MCP_TOKEN, else a generated one)")
+	mcpCmd.AddCommand(mcpServeCmd)
+	rootCmd.AddCommand(mcpCmd)
+}
+
+func runMCPServe(cmd *cobra.Command, _ []string) error {
+	if mcpTransport != "stdio" && mcpTransport != "http" {
+		return fmt.Errorf("unknown transport %q: use stdio or http", mcpTransport)
+	}
+	// stdout carries the protocol in stdio mode, so logs go to stderr.
+	log.SetOutput(os.Stderr)
+	if debugMode {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	cfg, err := config.LoadAndValidateConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	var templates []*prompts.PromptTemplate
+	if !noPromptTemplates {
+		homeDir, _ := os.UserHomeDir()
+		tpls, _, err := prompts.LoadAll(prompts.LoadOptions{
+			Cwd:             cwd,
+			HomeDir:         homeDir,
+			ExtraPaths:      promptTemplatePaths,
+			ConfigPaths:     viper.GetStringSlice("prompts"),
+			IncludeDefaults: true,
+		})
+		if err != nil {
+			log.Warn("failed to load some prompt templates", "error", err)
+		}
+		templates = tpls
+	}
+
+	srv, err := mcpserver.New(mcpserver.Options{
+		Cwd:         cwd,
+		Version:     rootCmd.Version,
+		Permissions: cfg.Permissions,
+		Sandbox:     cfg.Sandbox,
+		Prompts:     templates,
+	})
+	if err != nil {
+		return err
+	}
+	defer srv.Close()
+
+	if mcpTransport == "stdio" {
+		// ServeStdio stops on SIGINT/SIGTERM by cancelling its context.
+		if err := srv.ServeStdio(); err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return nil
+	}
+
+	token := mcpToken
+	if token == "" {
+		token = os.Getenv("KIT_MCP_TOKEN")
+	}
+	generated := token == ""
+	if generated {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return fmt.Errorf("generating a bearer token: %w", err)
+		}
+		token = hex.EncodeToString(buf)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "kit: serving MCP on http://%s%s\n", mcpAddr, mcpEndpoint)
+	if generated {
+		fmt.Fprintf(os.Stderr, "kit: clients must send \"Authorization: Bearer %s\"\n", token)
+	}
+	return srv.ServeHTTP(ctx, mcpAddr, mcpEndpoint, token)
+}
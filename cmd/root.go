@@ -31,6 +31,7 @@ var (
 	configFile       string
 	systemPromptFile string
 	modelFlag        string
+	fallbackModels   []string
 	providerURL      string
 	providerAPIKey   string
 	providerWire     string
@@ -43,8 +44,12 @@ var (
 	mcpResourceReader ui.MCPResourceReader
 	quietFlag         bool
 	jsonFlag          bool
+	outputFormat      string // --output-format: text, json or stream-json
+	inputFormat       string // --input-format: text or stream-json
 	noExitFlag        bool
 	maxSteps          int
+	maxCost           float64
+	maxSubagents      int
 	streamFlag        bool // Enable streaming output
 	autoCompactFlag   bool // Enable auto-compaction near context limit
 
@@ -56,6 +61,13 @@ var (
 	resumeFlag    bool // --resume / -r: interactive session picker
 	noSessionFlag bool // --no-session: ephemeral mode, no persistence
 
+	// File checkpoints
+	noCheckpointsFlag  bool // --no-checkpoints: don't snapshot files before write/edit
+	gitCheckpointsFlag bool // --git-checkpoints: also snapshot the tracked git tree
+
+	// Bash sandbox
+	sandboxFlag string // --sandbox: none, bwrap, docker or podman
+
 	// Model generation parameters
 	maxTokens        int
 	temperature      float32
@@ -74,6 +86,7 @@ var (
 	noExtensionsFlag     bool
 	noCoreToolsFlag      bool
 	includeCoreToolsFlag []string
+	untrustedProjectFlag bool
 	excludeCoreToolsFlag []string
 	extensionPaths       []string
 
@@ -259,24 +272,34 @@ func init() {
 	}
 
 	rootCmd.PersistentFlags().
-		StringVar(&configFile, "config", "", "config file (default is $HOME/.kit.yml)")
+		StringVar(&configFile, "config", "", "config file to use instead of the merged ~/.kit.yml and project files")
 	rootCmd.PersistentFlags().
 		StringVar(&systemPromptFile, "system-prompt", "", "system prompt text or path to text file")
 
 	rootCmd.PersistentFlags().
 		StringVarP(&modelFlag, "model", "m", "anthropic/claude-sonnet-4-5-20250929",
 			"model to use (format: provider/model)")
+	rootCmd.PersistentFlags().
+		StringSliceVar(&fallbackModels, "fallback-model", nil, "model to switch to when the current one fails with an overload, rate-limit, context-overflow or auth error (repeatable; tried in order)")
 	rootCmd.PersistentFlags().
 		BoolVar(&debugMode, "debug", false, "enable debug logging")
 
 	rootCmd.PersistentFlags().
 		BoolVar(&quietFlag, "quiet", false, "suppress all output (non-interactive mode only)")
 	rootCmd.PersistentFlags().
-		BoolVar(&jsonFlag, "json", false, "output response as JSON (non-interactive mode only)")
+		BoolVar(&jsonFlag, "json", false, "output response as JSON (non-interactive mode only; same as --output-format json)")
+	rootCmd.PersistentFlags().
+		StringVar(&outputFormat, "output-format", formatText, "non-interactive output format: text, json, or stream-json (one JSON event per line)")
+	rootCmd.PersistentFlags().
+		StringVar(&inputFormat, "input-format", formatText, "input format: text, or stream-json to read user and steer messages from stdin (requires --output-format stream-json)")
 	rootCmd.PersistentFlags().
 		BoolVar(&noExitFlag, "no-exit", false, "enter interactive mode after non-interactive prompt completes")
 	rootCmd.PersistentFlags().
 		IntVar(&maxSteps, "max-steps", 0, "maximum number of agent steps (0 for unlimited)")
+	rootCmd.PersistentFlags().
+		Float64Var(&maxCost, "max-cost", 0, "stop the agent once this run has spent this many US dollars (0 for unlimited)")
+	rootCmd.PersistentFlags().
+		IntVar(&maxSubagents, "max-parallel-subagents", 0, "maximum number of subagents running at once (0 for the default of 4)")
 	rootCmd.PersistentFlags().
 		BoolVar(&streamFlag, "stream", true, "enable streaming output for faster response display")
 	rootCmd.PersistentFlags().
@@ -289,8 +312,16 @@ func init() {
 		BoolVarP(&resumeFlag, "resume", "r", false, "interactive session picker")
 	rootCmd.PersistentFlags().
 		BoolVar(&noSessionFlag, "no-session", false, "ephemeral mode — no session persistence")
+	rootCmd.PersistentFlags().
+		BoolVar(&noCheckpointsFlag, "no-checkpoints", false, "don't snapshot files before write/edit (disables file restore in /rewind)")
+	rootCmd.PersistentFlags().
+		BoolVar(&gitCheckpointsFlag, "git-checkpoints", false, "also snapshot the tracked git tree each turn so /rewind can undo bash changes")
+	rootCmd.PersistentFlags().
+		StringVar(&sandboxFlag, "sandbox", "", "run bash commands in a sandbox: none, bwrap, docker or podman")
 	rootCmd.PersistentFlags().
 		BoolVar(&noExtensionsFlag, "no-extensions", false, "disable all extensions")
+	rootCmd.PersistentFlags().
+		BoolVar(&untrustedProjectFlag, "untrusted-project", false, "ignore the project's .kit.yml, .kit.local.yml, context files, skills, agents and extensions")
 	rootCmd.PersistentFlags().
 		BoolVar(&noCoreToolsFlag, "no-core-tools", false, "disable all built-in core tools (bash, read, write, edit, grep, find, ls, subagent)")
 	rootCmd.PersistentFlags().
@@ -340,9 +371,15 @@ func init() {
 	// Bind flags to viper for config file support
 	_ = viper.BindPFlag("system-prompt", rootCmd.PersistentFlags().Lookup("system-prompt"))
 	_ = viper.BindPFlag("no-session", rootCmd.PersistentFlags().Lookup("no-session"))
+	_ = viper.BindPFlag("no-checkpoints", rootCmd.PersistentFlags().Lookup("no-checkpoints"))
+	_ = viper.BindPFlag("git-checkpoints", rootCmd.PersistentFlags().Lookup("git-checkpoints"))
+	_ = viper.BindPFlag("sandbox.backend", rootCmd.PersistentFlags().Lookup("sandbox"))
 	_ = viper.BindPFlag("model", rootCmd.PersistentFlags().Lookup("model"))
+	_ = viper.BindPFlag("fallback-models", rootCmd.PersistentFlags().Lookup("fallback-model"))
 	_ = viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
 	_ = viper.BindPFlag("max-steps", rootCmd.PersistentFlags().Lookup("max-steps"))
+	_ = viper.BindPFlag("max-cost", rootCmd.PersistentFlags().Lookup("max-cost"))
+	_ = viper.BindPFlag("max-parallel-subagents", rootCmd.PersistentFlags().Lookup("max-parallel-subagents"))
 	_ = viper.BindPFlag("stream", rootCmd.PersistentFlags().Lookup("stream"))
 	_ = viper.BindPFlag("auto-compact", rootCmd.PersistentFlags().Lookup("auto-compact"))
 
@@ -361,6 +398,7 @@ func init() {
 	_ = viper.BindPFlag("main-gpu", rootCmd.PersistentFlags().Lookup("main-gpu"))
 	_ = viper.BindPFlag("tls-skip-verify", rootCmd.PersistentFlags().Lookup("tls-skip-verify"))
 	_ = viper.BindPFlag("no-extensions", rootCmd.PersistentFlags().Lookup("no-extensions"))
+	_ = viper.BindPFlag("untrusted-project", rootCmd.PersistentFlags().Lookup("untrusted-project"))
 	_ = viper.BindPFlag("no-core-tools", rootCmd.PersistentFlags().Lookup("no-core-tools"))
 	_ = viper.BindPFlag("include-core-tools", rootCmd.PersistentFlags().Lookup("include-core-tools"))
 	_ = viper.BindPFlag("exclude-core-tools", rootCmd.PersistentFlags().Lookup("exclude-core-tools"))
@@ -746,15 +784,52 @@ func shortcutListProviderForUI(k *kit.Kit) func() []ui.ShortcutInfo {
 	}
 }
 
+// headless reports whether Kit runs without the TUI: with a prompt on the
+// command line, or driven by stream-json messages on stdin.
+func headless() bool {
+	return positionalPrompt != "" || inputFormat == formatStreamJSON
+}
+
 // validateModeFlags rejects invalid flag combinations for the root command.
 func validateModeFlags() error {
+	switch outputFormat {
+	case formatText, formatJSON, formatStreamJSON:
+	default:
+		return fmt.Errorf("invalid --output-format %q (want text, json or stream-json)", outputFormat)
+	}
+	switch inputFormat {
+	case formatText, formatStreamJSON:
+	default:
+		return fmt.Errorf("invalid --input-format %q (want text or stream-json)", inputFormat)
+	}
+	if jsonFlag {
+		if outputFormat != formatText && outputFormat != formatJSON {
+			return fmt.Errorf("--json conflicts with --output-format %s", outputFormat)
+		}
+		outputFormat = formatJSON
+	}
+	if inputFormat == formatStreamJSON {
+		if outputFormat != formatStreamJSON {
+			return fmt.Errorf("--input-format stream-json requires --output-format stream-json")
+		}
+		if noExitFlag {
+			return fmt.Errorf("--input-format stream-json and --no-exit cannot be used together")
+		}
+		return nil
+	}
+	if outputFormat == formatStreamJSON && positionalPrompt == "" {
+		return fmt.Errorf("--output-format stream-json requires a prompt or --input-format stream-json")
+	}
+	if outputFormat == formatStreamJSON && noExitFlag {
+		return fmt.Errorf("--output-format stream-json and --no-exit cannot be used together")
+	}
 	if quietFlag && positionalPrompt == "" {
 		return fmt.Errorf("--quiet requires a prompt (e.g. kit \"your question\" --quiet)")
 	}
-	if jsonFlag && positionalPrompt == "" {
+	if outputFormat == formatJSON && positionalPrompt == "" {
 		return fmt.Errorf("--json requires a prompt (e.g. kit \"your question\" --json)")
 	}
-	if jsonFlag && noExitFlag {
+	if outputFormat == formatJSON && noExitFlag {
 		return fmt.Errorf("--json and --no-exit flags cannot be used together")
 	}
 	if noExitFlag && positionalPrompt == "" {
@@ -905,21 +980,22 @@ func runNormalMode(ctx context.Context) error {
 	var appInstancePtr *app.App
 
 	kitOpts := &kit.Options{
-		Quiet:            quietFlag,
-		Debug:            debugMode,
-		NoSession:        viper.GetBool("no-session"),
-		Continue:         continueFlag,
-		SessionPath:      sessionPath,
-		AutoCompact:      autoCompactFlag,
-		MCPAuthHandler:   authHandler,
-		DisableCoreTools: viper.GetBool("no-core-tools"),
-		CoreToolList:     coreToolList,
-		NoSkills:         noSkillsFlag,
-		NoAgents:         noAgentsFlag,
-		Skills:           skillsPaths,
-		SkillsDir:        skillsDir,
-		SkillsDisable:    skillsDisable,
-		SkillTrustPrompt: skillTrustPrompt(),
+		Quiet:                quietFlag,
+		Debug:                debugMode,
+		NoSession:            viper.GetBool("no-session"),
+		Continue:             continueFlag,
+		SessionPath:          sessionPath,
+		AutoCompact:          autoCompactFlag,
+		MCPAuthHandler:       authHandler,
+		DisableCoreTools:     viper.GetBool("no-core-tools"),
+		CoreToolList:         coreToolList,
+		NoSkills:             noSkillsFlag,
+		NoAgents:             noAgentsFlag,
+		Skills:               skillsPaths,
+		SkillsDir:            skillsDir,
+		SkillsDisable:        skillsDisable,
+		SkillTrustPrompt:     skillTrustPrompt(),
+		ExtensionTrustPrompt: extensionTrustPrompt(),
 		// This callback is called when each MCP server finishes loading.
 		// We use a closure that captures appInstancePtr which is set after
 		// app.New() is called below.
@@ -940,7 +1016,7 @@ func runNormalMode(ctx context.Context) error {
 		// When --resume is combined with interactive mode, the TUI session
 		// picker will be shown at startup. For non-interactive mode, fall
 		// back to auto-selecting the most recent session.
-		if positionalPrompt != "" {
+		if headless() {
 			sessions, _ := kit.ListSessions("")
 			if len(sessions) > 0 {
 				kitOpts.SessionPath = sessions[0].Path
@@ -1036,14 +1112,14 @@ func runNormalMode(ctx context.Context) error {
 		// interactive TUI: headless runs have no chrome to size, and
 		// terminalSize()'s 80x24 fallback would contradict the documented
 		// (0, 0) that GetTerminalSize reports outside the TUI.
-		if positionalPrompt == "" {
+		if !headless() {
 			kitInstance.Extensions().SetTerminalSize(terminalSize())
 		}
 		extCtx := buildInteractiveExtensionContext(extensionContextDeps{
 			ctx:          ctx,
 			cwd:          cwd,
 			modelName:    modelName,
-			interactive:  positionalPrompt == "",
+			interactive:  !headless(),
 			kitInstance:  kitInstance,
 			appInstance:  appInstance,
 			usageTracker: usageTracker,
@@ -1297,6 +1373,11 @@ func runNormalMode(ctx context.Context) error {
 		ui.UpdateUsageTrackerForModel(usageTracker, modelString, viper.GetString("provider-api-key"))
 		return nil
 	}
+	// Failover switches models from inside a turn; price the rest of the
+	// session with the fallback model. The app layer updates the status bar.
+	kitInstance.OnModelFailover(func(ev kit.ModelFailoverEvent) {
+		ui.UpdateUsageTrackerForModel(usageTracker, ev.To, viper.GetString("provider-api-key"))
+	})
 	emitModelChangeForUI := func(newModel, previousModel, source string) {
 		kitInstance.Extensions().EmitModelChange(newModel, previousModel, source)
 	}
@@ -1411,6 +1492,7 @@ func runNormalMode(ctx context.Context) error {
 	// function signatures readable.
 	deps := runModeDeps{
 		appInstance:              appInstance,
+		kitInstance:              kitInstance,
 		cli:                      cli,
 		modelName:                modelName,
 		providerName:             parsedProvider,
@@ -1459,8 +1541,11 @@ func runNormalMode(ctx context.Context) error {
 	}
 
 	// Check if running in non-interactive mode
+	if outputFormat == formatStreamJSON {
+		return runStreamJSONMode(ctx, deps, positionalPrompt, inputFormat == formatStreamJSON)
+	}
 	if positionalPrompt != "" {
-		return runNonInteractiveModeApp(ctx, deps, positionalPrompt, quietFlag, jsonFlag, noExitFlag)
+		return runNonInteractiveModeApp(ctx, deps, positionalPrompt, quietFlag, outputFormat == formatJSON, noExitFlag)
 	}
 
 	// Quiet mode is not allowed in interactive mode
@@ -1485,28 +1570,7 @@ func runNonInteractiveModeApp(ctx context.Context, deps runModeDeps, prompt stri
 	appInstance := deps.appInstance
 	cli := deps.cli
 	modelName := deps.modelName
-	// Expand @file references in the prompt before sending to the agent.
-	// Text files are XML-inlined; binary files are extracted as multimodal parts.
-	var fileParts []kit.LLMFilePart
-	if cwd, err := os.Getwd(); err == nil {
-		result := ui.ProcessFileAttachments(prompt, cwd, mcpResourceReader)
-		prompt = result.ProcessedText
-		for _, fp := range result.FileParts {
-			fileParts = append(fileParts, kit.LLMFilePart{
-				Filename:  fp.Filename,
-				Data:      fp.Data,
-				MediaType: fp.MediaType,
-			})
-		}
-	}
-	// Also include binary files from processPositionalArgs (CLI @file args).
-	for _, fp := range positionalFiles {
-		fileParts = append(fileParts, kit.LLMFilePart{
-			Filename:  fp.Filename,
-			Data:      fp.Data,
-			MediaType: fp.MediaType,
-		})
-	}
+	prompt, fileParts := expandPromptFiles(prompt)
 
 	if jsonOutput {
 		// JSON mode: no intermediate display, structured JSON output.
@@ -1556,6 +1620,32 @@ func runNonInteractiveModeApp(ctx context.Context, deps runModeDeps, prompt stri
 	return nil
 }
 
+// expandPromptFiles expands @file references in a command-line prompt. Text
+// files are XML-inlined; binary files, including the @file arguments
+// collected by processPositionalArgs, are returned as multimodal parts.
+func expandPromptFiles(prompt string) (string, []kit.LLMFilePart) {
+	var fileParts []kit.LLMFilePart
+	if cwd, err := os.Getwd(); err == nil {
+		result := ui.ProcessFileAttachments(prompt, cwd, mcpResourceReader)
+		prompt = result.ProcessedText
+		for _, fp := range result.FileParts {
+			fileParts = append(fileParts, kit.LLMFilePart{
+				Filename:  fp.Filename,
+				Data:      fp.Data,
+				MediaType: fp.MediaType,
+			})
+		}
+	}
+	for _, fp := range positionalFiles {
+		fileParts = append(fileParts, kit.LLMFilePart{
+			Filename:  fp.Filename,
+			Data:      fp.Data,
+			MediaType: fp.MediaType,
+		})
+	}
+	return prompt, fileParts
+}
+
 // runModeDeps bundles the shared dependencies that runNormalMode wires up
 // once and threads to both runNonInteractiveModeApp and
 // runInteractiveModeBubbleTea. Grouping them into a single struct keeps the
@@ -1574,6 +1664,7 @@ func terminalSize() (int, int) {
 
 type runModeDeps struct {
 	appInstance              *app.App
+	kitInstance              *kit.Kit
 	cli                      *ui.CLI // non-interactive only
 	modelName                string
 	providerName             string
@@ -0,0 +1,484 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	stdlog "log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mark3labs/kit/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sessionsAllFlag       bool
+	sessionsJSONFlag      bool
+	sessionsFormatFlag    string
+	sessionsOutputFlag    string
+	sessionsOlderThanFlag string
+	sessionsKeepNamedFlag bool
+	sessionsDryRunFlag    bool
+)
+
+// shortIDLen is how much of a session ID the tables print. Any unique prefix
+// is accepted wherever a session ID is.
+const shortIDLen = 8
+
+// maxSearchMatches caps the matches printed per session in table output.
+const maxSearchMatches = 3
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List, inspect and clean up saved sessions",
+	Long: `Manage the sessions Kit saves under ~/.kit/sessions without starting the TUI.
+
+Commands act on the sessions of the current directory; pass --all to cover
+every directory. A session is named by its ID, any unique prefix of it (as
+'kit sessions list' prints), or the path of its .jsonl file.
+
+Examples:
+  kit sessions list --all
+  kit sessions show 3f2a9c1e
+  kit sessions export 3f2a9c1e --format markdown -o transcript.md
+  kit sessions search "rate limiter" --all
+  kit sessions prune --older-than 30d --keep-named --all`,
+	PersistentPreRun: func(*cobra.Command, []string) {
+		// Opening a session logs tree diagnostics through the standard
+		// logger; keep them out of scripted output unless debugging.
+		if !debugMode {
+			stdlog.SetOutput(io.Discard)
+		}
+	},
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved sessions, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  runSessionsList,
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Print a session's transcript as Markdown",
+	Long: `Print the transcript of a session's current branch as Markdown. Tool calls
+and results are truncated and reasoning is omitted.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSessionsShow,
+}
+
+var sessionsExportCmd = &cobra.Command{
+	Use:   "export <id>",
+	Short: "Write a session to stdout or a file",
+	Long: `Write a session to stdout, or to --output. The jsonl format is the session
+file itself, resumable with 'kit sessions import'; markdown is the transcript
+'kit sessions show' prints.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSessionsExport,
+}
+
+var sessionsImportCmd = &cobra.Command{
+	Use:   "import <file.jsonl>...",
+	Short: "Copy session files into the current directory's sessions",
+	Long: `Copy exported or shared session files into the current directory's session
+store, so they show up in 'kit sessions list' and the /resume picker. A
+session whose ID is already stored is skipped with an error.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSessionsImport,
+}
+
+var sessionsRmCmd = &cobra.Command{
+	Use:     "rm <id>...",
+	Aliases: []string{"delete"},
+	Short:   "Delete sessions",
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    runSessionsRm,
+}
+
+var sessionsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete sessions that have been idle for a while",
+	Long: `Delete sessions whose last activity is older than --older-than, an age
+like 30d, 4w or 12h, or a date like 2006-01-02.
+
+Examples:
+  kit sessions prune --older-than 30d --dry-run
+  kit sessions prune --older-than 8w --keep-named --all`,
+	Args: cobra.NoArgs,
+	RunE: runSessionsPrune,
+}
+
+var sessionsSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Find sessions whose history mentions a query",
+	Long: `Find sessions with an entry containing every word of the query, ignoring case.
+Words match by prefix, so "rate lim" finds "rate limiter". Message text, tool
+calls and their arguments (including file paths), labels and session names are
+searched on every branch of a session, not only the current one.
+
+Searches use an index cached in ~/.kit/session-index.json that is brought up
+to date incrementally, so only sessions written since the last search are read.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSessionsSearch,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{sessionsListCmd, sessionsPruneCmd, sessionsSearchCmd} {
+		c.Flags().BoolVar(&sessionsAllFlag, "all", false, "cover the sessions of every directory, not just the current one")
+	}
+	sessionsListCmd.Flags().BoolVar(&sessionsJSONFlag, "json", false, "print the sessions as JSON")
+	sessionsSearchCmd.Flags().BoolVar(&sessionsJSONFlag, "json", false, "print the matches as JSON")
+	sessionsExportCmd.Flags().StringVar(&sessionsFormatFlag, "format", "jsonl", "output format: jsonl or markdown")
+	sessionsExportCmd.Flags().StringVarP(&sessionsOutputFlag, "output", "o", "", "write to this file instead of stdout")
+	sessionsPruneCmd.Flags().StringVar(&sessionsOlderThanFlag, "older-than", "30d", "delete sessions idle since this age (30d, 4w, 12h) or date (2006-01-02)")
+	sessionsPruneCmd.Flags().BoolVar(&sessionsKeepNamedFlag, "keep-named", false, "keep sessions that have a name")
+	sessionsPruneCmd.Flags().BoolVar(&sessionsDryRunFlag, "dry-run", false, "print the sessions that would be deleted without deleting them")
+
+	sessionsCmd.AddCommand(sessionsListCmd, sessionsShowCmd, sessionsExportCmd, sessionsImportCmd,
+		sessionsRmCmd, sessionsPruneCmd, sessionsSearchCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
+
+// sessionJSON is the --json form of a session.
+type sessionJSON struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name,omitempty"`
+	Path            string    `json:"path"`
+	Cwd             string    `json:"cwd"`
+	Created         time.Time `json:"created"`
+	Modified        time.Time `json:"modified"`
+	MessageCount    int       `json:"message_count"`
+	FirstMessage    string    `json:"first_message,omitempty"`
+	ParentSessionID string    `json:"parent_session_id,omitempty"`
+}
+
+func newSessionJSON(info session.SessionInfo) sessionJSON {
+	return sessionJSON{
+		ID:              info.ID,
+		Name:            info.Name,
+		Path:            info.Path,
+		Cwd:             info.Cwd,
+		Created:         info.Created,
+		Modified:        info.Modified,
+		MessageCount:    info.MessageCount,
+		FirstMessage:    info.FirstMessage,
+		ParentSessionID: info.ParentSessionID,
+	}
+}
+
+// listScopedSessions returns the current directory's sessions, or every
+// session with --all.
+func listScopedSessions() ([]session.SessionInfo, error) {
+	if sessionsAllFlag {
+		return session.ListAllSessions()
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return session.ListSessions(cwd)
+}
+
+// resolveSession finds the session ref names: a session file path, an exact
+// ID, or a unique ID prefix.
+func resolveSession(ref string) (session.SessionInfo, error) {
+	if strings.HasSuffix(ref, ".jsonl") {
+		if _, err := os.Stat(ref); err == nil {
+			return session.ReadSessionInfo(ref)
+		}
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return session.SessionInfo{}, err
+	}
+	if path, err := session.FindSessionPathByID(cwd, ref); err == nil {
+		return session.ReadSessionInfo(path)
+	}
+
+	all, err := session.ListAllSessions()
+	if err != nil {
+		return session.SessionInfo{}, err
+	}
+	var matches []session.SessionInfo
+	for _, info := range all {
+		if strings.HasPrefix(info.ID, ref) {
+			matches = append(matches, info)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return session.SessionInfo{}, fmt.Errorf("session %q not found", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return session.SessionInfo{}, fmt.Errorf("session prefix %q is ambiguous: it matches %d sessions", ref, len(matches))
+	}
+}
+
+func runSessionsList(cmd *cobra.Command, _ []string) error {
+	infos, err := listScopedSessions()
+	if err != nil {
+		return err
+	}
+	return writeSessionList(cmd.OutOrStdout(), infos, sessionsJSONFlag, sessionsAllFlag)
+}
+
+// writeSessionList prints sessions as an aligned table, with each session's
+// directory when they span several, or as a JSON array.
+func writeSessionList(w io.Writer, infos []session.SessionInfo, asJSON, withCwd bool) error {
+	if asJSON {
+		out := make([]sessionJSON, 0, len(infos))
+		for _, info := range infos {
+			out = append(out, newSessionJSON(info))
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	if len(infos) == 0 {
+		_, err := fmt.Fprintln(w, "No sessions found.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "ID\tMODIFIED\tMESSAGES\tTITLE"
+	if withCwd {
+		header += "\tDIRECTORY"
+	}
+	_, _ = fmt.Fprintln(tw, header)
+	for _, info := range infos {
+		row := fmt.Sprintf("%s\t%s\t%d\t%s", shortID(info.ID), info.Modified.Local().Format("2006-01-02 15:04"), info.MessageCount, info.Title())
+		if withCwd {
+			row += "\t" + info.Cwd
+		}
+		_, _ = fmt.Fprintln(tw, row)
+	}
+	return tw.Flush()
+}
+
+func runSessionsShow(cmd *cobra.Command, args []string) error {
+	info, err := resolveSession(args[0])
+	if err != nil {
+		return err
+	}
+	text, err := sessionTranscript(info)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(cmd.OutOrStdout(), text)
+	return err
+}
+
+// sessionTranscript renders the current branch of a session as Markdown.
+func sessionTranscript(info session.SessionInfo) (string, error) {
+	tm, err := session.OpenTreeSession(info.Path)
+	if err != nil {
+		return "", fmt.Errorf("open session: %w", err)
+	}
+	defer func() { _ = tm.Close() }()
+	messages, _, _ := tm.BuildContext()
+	return session.Transcript(info.Title(), messages), nil
+}
+
+func runSessionsExport(cmd *cobra.Command, args []string) error {
+	info, err := resolveSession(args[0])
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch sessionsFormatFlag {
+	case "jsonl":
+		if data, err = os.ReadFile(info.Path); err != nil {
+			return fmt.Errorf("read session file: %w", err)
+		}
+	case "markdown", "md":
+		text, err := sessionTranscript(info)
+		if err != nil {
+			return err
+		}
+		data = []byte(text)
+	default:
+		return fmt.Errorf("invalid --format %q (want jsonl or markdown)", sessionsFormatFlag)
+	}
+
+	if sessionsOutputFlag == "" {
+		_, err = cmd.OutOrStdout().Write(data)
+		return err
+	}
+	if err := os.WriteFile(sessionsOutputFlag, data, 0o644); err != nil {
+		return fmt.Errorf("write export file %q: %w", sessionsOutputFlag, err)
+	}
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Session exported to: %s (%d bytes)\n", sessionsOutputFlag, len(data))
+	return nil
+}
+
+func runSessionsImport(cmd *cobra.Command, args []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	var failed int
+	for _, src := range args {
+		dst, err := session.ImportSession(src, cwd)
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Failed to import %s: %v\n", src, err)
+			failed++
+			continue
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Imported %s → %s\n", src, dst)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sessions failed to import", failed, len(args))
+	}
+	return nil
+}
+
+func runSessionsRm(cmd *cobra.Command, args []string) error {
+	// Resolve every reference before deleting anything, so a typo in the
+	// last one does not leave the command half done.
+	infos := make([]session.SessionInfo, 0, len(args))
+	for _, ref := range args {
+		info, err := resolveSession(ref)
+		if err != nil {
+			return err
+		}
+		infos = append(infos, info)
+	}
+	for _, info := range infos {
+		if err := session.DeleteSession(info.Path); err != nil {
+			return fmt.Errorf("delete session %s: %w", info.ID, err)
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Deleted %s (%s)\n", shortID(info.ID), info.Title())
+	}
+	return nil
+}
+
+func runSessionsPrune(cmd *cobra.Command, _ []string) error {
+	cutoff, err := parseSince(sessionsOlderThanFlag, time.Now())
+	if err != nil || cutoff.IsZero() {
+		return fmt.Errorf("invalid --older-than %q (want an age like 30d or a date like 2006-01-02)", sessionsOlderThanFlag)
+	}
+	infos, err := listScopedSessions()
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	stale := pruneCandidates(infos, cutoff, sessionsKeepNamedFlag)
+	for _, info := range stale {
+		if sessionsDryRunFlag {
+			_, _ = fmt.Fprintf(out, "Would delete %s (%s, last active %s)\n", shortID(info.ID), info.Title(), info.Modified.Local().Format("2006-01-02"))
+			continue
+		}
+		if err := session.DeleteSession(info.Path); err != nil {
+			return fmt.Errorf("delete session %s: %w", info.ID, err)
+		}
+		_, _ = fmt.Fprintf(out, "Deleted %s (%s, last active %s)\n", shortID(info.ID), info.Title(), info.Modified.Local().Format("2006-01-02"))
+	}
+	verb := "Deleted"
+	if sessionsDryRunFlag {
+		verb = "Would delete"
+	}
+	_, _ = fmt.Fprintf(out, "%s %d of %d sessions.\n", verb, len(stale), len(infos))
+	return nil
+}
+
+// pruneCandidates returns the sessions last active before cutoff, leaving
+// out named ones when keepNamed is set.
+func pruneCandidates(infos []session.SessionInfo, cutoff time.Time, keepNamed bool) []session.SessionInfo {
+	var stale []session.SessionInfo
+	for _, info := range infos {
+		if !info.Modified.Before(cutoff) || (keepNamed && info.Name != "") {
+			continue
+		}
+		stale = append(stale, info)
+	}
+	return stale
+}
+
+// searchMatch is one entry of a session matching the query.
+type searchMatch struct {
+	EntryID    string   `json:"entry_id,omitempty"`
+	Kind       string   `json:"kind"` // message role, or "label", "name" or "files"
+	Snippet    string   `json:"snippet"`
+	Highlights [][2]int `json:"highlights,omitempty"`
+}
+
+// searchResult is a session with at least one match.
+type searchResult struct {
+	Session sessionJSON   `json:"session"`
+	Matches []searchMatch `json:"matches"`
+	info    session.SessionInfo
+}
+
+func runSessionsSearch(cmd *cobra.Command, args []string) error {
+	hits, err := session.SearchSessions(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	dir := ""
+	if !sessionsAllFlag {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		dir = session.DefaultSessionDir(cwd)
+	}
+
+	var results []searchResult
+	for _, hit := range hits {
+		if dir != "" && filepath.Dir(hit.Session.Path) != dir {
+			continue
+		}
+		matches := make([]searchMatch, 0, len(hit.Matches))
+		for _, m := range hit.Matches {
+			matches = append(matches, searchMatch{EntryID: m.EntryID, Kind: m.Kind, Snippet: m.Snippet, Highlights: m.Highlights})
+		}
+		results = append(results, searchResult{Session: newSessionJSON(hit.Session), Matches: matches, info: hit.Session})
+	}
+	return writeSearchResults(cmd.OutOrStdout(), results, sessionsJSONFlag)
+}
+
+// writeSearchResults prints results grouped by session, or as a JSON array.
+func writeSearchResults(w io.Writer, results []searchResult, asJSON bool) error {
+	if asJSON {
+		if results == nil {
+			results = []searchResult{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	if len(results) == 0 {
+		_, err := fmt.Fprintln(w, "No matching sessions.")
+		return err
+	}
+	for i, r := range results {
+		if i > 0 {
+			_, _ = fmt.Fprintln(w)
+		}
+		_, _ = fmt.Fprintf(w, "%s  %s  %s\n", shortID(r.info.ID), r.info.Modified.Local().Format("2006-01-02 15:04"), r.info.Title())
+		for j, m := range r.Matches {
+			if j == maxSearchMatches {
+				_, _ = fmt.Fprintf(w, "  … and %d more\n", len(r.Matches)-j)
+				break
+			}
+			_, _ = fmt.Fprintf(w, "  %s: %s\n", m.Kind, m.Snippet)
+		}
+	}
+	return nil
+}
+
+// shortID returns the prefix of a session ID the tables print.
+func shortID(id string) string {
+	if len(id) > shortIDLen {
+		return id[:shortIDLen]
+	}
+	return id
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/kit/internal/session"
+)
+
+// newTestSession creates a session for cwd under a temporary HOME holding
+// the given user messages, each answered by "ok", and returns its ID.
+func newTestSession(t *testing.T, cwd, name string, prompts ...string) string {
+	t.Helper()
+	tm, err := session.CreateTreeSession(cwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range prompts {
+		_, _ = tm.AppendLLMMessage(fantasy.NewUserMessage(p))
+		_, _ = tm.AppendLLMMessage(fantasy.Message{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{fantasy.TextPart{Text: "ok"}}})
+	}
+	if name != "" {
+		_, _ = tm.AppendSessionInfo(name)
+	}
+	id := tm.GetSessionID()
+	_ = tm.Close()
+	return id
+}
+
+// setupSessions points HOME and the working directory at temp dirs and
+// resets the sessions flags.
+func setupSessions(t *testing.T) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	cwd := t.TempDir()
+	t.Chdir(cwd)
+	t.Cleanup(func() {
+		sessionsAllFlag, sessionsJSONFlag, sessionsKeepNamedFlag, sessionsDryRunFlag = false, false, false, false
+		sessionsFormatFlag, sessionsOutputFlag, sessionsOlderThanFlag = "jsonl", "", "30d"
+	})
+	return cwd
+}
+
+func TestSessions_ListShowAndResolve(t *testing.T) {
+	cwd := setupSessions(t)
+	id := newTestSession(t, cwd, "parser work", "fix the parser")
+	newTestSession(t, cwd, "", "add a rate limiter")
+
+	var out bytes.Buffer
+	if err := writeSessionList(&out, mustList(t), true, false); err != nil {
+		t.Fatal(err)
+	}
+	var listed []sessionJSON
+	if err := json.Unmarshal(out.Bytes(), &listed); err != nil || len(listed) != 2 {
+		t.Fatalf("list --json = %s (%v)", out.String(), err)
+	}
+
+	info, err := resolveSession(id[:6])
+	if err != nil || info.ID != id {
+		t.Fatalf("resolveSession(prefix) = %+v, %v", info, err)
+	}
+	if _, err := resolveSession("zzz"); err == nil {
+		t.Error("expected an error for an unknown session")
+	}
+
+	out.Reset()
+	sessionsShowCmd.SetOut(&out)
+	t.Cleanup(func() { sessionsShowCmd.SetOut(nil) })
+	if err := runSessionsShow(sessionsShowCmd, []string{id}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "# parser work\n\n## User\n\nfix the parser\n\n## Assistant\n\nok\n"; out.String() != want {
+		t.Errorf("show = %q, want %q", out.String(), want)
+	}
+}
+
+func TestSessions_ExportImportRoundTrip(t *testing.T) {
+	cwd := setupSessions(t)
+	id := newTestSession(t, cwd, "", "fix the parser")
+	exported := filepath.Join(t.TempDir(), "export.jsonl")
+
+	sessionsOutputFlag = exported
+	if err := runSessionsExport(sessionsExportCmd, []string{id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.ImportSession(exported, cwd); err == nil {
+		t.Error("importing a stored session should fail")
+	}
+
+	info, _ := resolveSession(id)
+	if err := runSessionsRm(sessionsRmCmd, []string{id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(info.Path); !os.IsNotExist(err) {
+		t.Fatalf("session file still exists: %v", err)
+	}
+
+	if err := runSessionsImport(sessionsImportCmd, []string{exported}); err != nil {
+		t.Fatal(err)
+	}
+	if infos := mustList(t); len(infos) != 1 || infos[0].ID != id {
+		t.Errorf("after import: %+v", infos)
+	}
+}
+
+func TestPruneCandidates(t *testing.T) {
+	now := time.Now()
+	infos := []session.SessionInfo{
+		{ID: "old", Modified: now.AddDate(0, 0, -40)},
+		{ID: "old-named", Name: "keep me", Modified: now.AddDate(0, 0, -40)},
+		{ID: "recent", Modified: now.AddDate(0, 0, -2)},
+	}
+	cutoff := now.AddDate(0, 0, -30)
+	if got := pruneCandidates(infos, cutoff, false); len(got) != 2 {
+		t.Errorf("prune = %+v, want both old sessions", got)
+	}
+	if got := pruneCandidates(infos, cutoff, true); len(got) != 1 || got[0].ID != "old" {
+		t.Errorf("prune --keep-named = %+v, want only the unnamed old session", got)
+	}
+}
+
+func TestSessions_Search(t *testing.T) {
+	cwd := setupSessions(t)
+	newTestSession(t, cwd, "Limiter", "Add a token-bucket RATE limiter to the API client")
+	newTestSession(t, cwd, "", "fix the parser")
+
+	var out bytes.Buffer
+	sessionsSearchCmd.SetOut(&out)
+	t.Cleanup(func() { sessionsSearchCmd.SetOut(nil) })
+	sessionsJSONFlag = true
+	if err := runSessionsSearch(sessionsSearchCmd, []string{"rate", "limiter"}); err != nil {
+		t.Fatal(err)
+	}
+	var results []searchResult
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil || len(results) != 1 {
+		t.Fatalf("search = %s (%v)", out.String(), err)
+	}
+	if m := results[0].Matches; len(m) != 1 || m[0].Kind != "user" || !strings.Contains(m[0].Snippet, "RATE limiter") {
+		t.Errorf("matches = %+v", m)
+	}
+
+	// Sessions of other directories are only searched with --all.
+	newTestSession(t, t.TempDir(), "", "rate limiter, elsewhere")
+	out.Reset()
+	if err := runSessionsSearch(sessionsSearchCmd, []string{"rate", "limiter"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil || len(results) != 1 {
+		t.Errorf("search without --all = %s (%v)", out.String(), err)
+	}
+	sessionsAllFlag = true
+	out.Reset()
+	if err := runSessionsSearch(sessionsSearchCmd, []string{"rate", "limiter"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil || len(results) != 2 {
+		t.Errorf("search --all = %s (%v)", out.String(), err)
+	}
+}
+
+func mustList(t *testing.T) []session.SessionInfo {
+	t.Helper()
+	infos, err := listScopedSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return infos
+}
@@ -8,6 +8,8 @@ import (
 
 	"golang.org/x/term"
 
+	"github.com/mark3labs/kit/internal/extensions"
+	"github.com/mark3labs/kit/internal/trust"
 	"github.com/mark3labs/kit/pkg/kit"
 )
 
@@ -18,25 +20,47 @@ import (
 // to trust the directory before any project skill is loaded.
 //
 // It returns nil — meaning "load without prompting" — when Kit is not running
-// interactively (a non-TTY stdin, --quiet, or a non-interactive one-shot
-// prompt), so scripted and piped invocations keep their existing behaviour.
+// interactively (a non-TTY stdin, --quiet, a non-interactive one-shot
+// prompt, or stream-json input), so scripted and piped invocations keep
+// their existing behaviour.
 func skillTrustPrompt() func(projectDir string, skillCount int) kit.TrustDecision {
+	return projectTrustPrompt("skill", "skills", ".agents/skills or .kit/skills", "Load them into the agent?")
+}
+
+// extensionTrustPrompt is skillTrustPrompt for project-local extensions,
+// which run code as soon as they load.
+func extensionTrustPrompt() func(projectDir string, extensionCount int) kit.TrustDecision {
+	return projectTrustPrompt("extension", "extensions", ".kit/extensions or .kit/git", "Run them?")
+}
+
+// extensionTrustGate gates project-local extensions for the extensions
+// subcommands, which load them outside a Kit instance.
+func extensionTrustGate() extensions.ProjectTrustFunc {
+	if prompt := extensionTrustPrompt(); prompt != nil {
+		return trust.Gate("", prompt)
+	}
+	return nil
+}
+
+// projectTrustPrompt builds the interactive trust prompt shared by project
+// skills and extensions, or returns nil when Kit is not interactive.
+func projectTrustPrompt(singular, plural, where, question string) func(projectDir string, count int) kit.TrustDecision {
 	// Only prompt for interactive terminal sessions.
-	if quietFlag || positionalPrompt != "" {
+	if quietFlag || headless() {
 		return nil
 	}
 	if !term.IsTerminal(int(os.Stdin.Fd())) {
 		return nil
 	}
 
-	return func(projectDir string, skillCount int) kit.TrustDecision {
-		noun := "skills"
-		if skillCount == 1 {
-			noun = "skill"
+	return func(projectDir string, count int) kit.TrustDecision {
+		noun := plural
+		if count == 1 {
+			noun = singular
 		}
-		fmt.Printf("\nThis project provides %d %s under .agents/skills or .kit/skills:\n  %s\n",
-			skillCount, noun, projectDir)
-		fmt.Print("Load them into the agent? [t]rust always / [o]nce / [s]kip (default skip): ")
+		fmt.Printf("\nThis project provides %d %s under %s:\n  %s\n",
+			count, noun, where, projectDir)
+		fmt.Printf("%s [t]rust always / [o]nce / [s]kip (default skip): ", question)
 
 		reader := bufio.NewReader(os.Stdin)
 		line, _ := reader.ReadString('\n')
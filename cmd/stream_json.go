@@ -0,0 +1,388 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/mark3labs/kit/pkg/kit"
+)
+
+// Output and input formats for headless runs.
+const (
+	formatText       = "text"
+	formatJSON       = "json"
+	formatStreamJSON = "stream-json"
+)
+
+// streamJSONVersion is stamped on every stream-json record as "v". It is
+// bumped only when a field is removed or changes meaning; new record types
+// and new fields do not bump it, so consumers should ignore what they don't
+// know.
+const streamJSONVersion = 1
+
+// streamHeader is embedded in every stream-json output record.
+type streamHeader struct {
+	Version int    `json:"v"`
+	Type    string `json:"type"`
+}
+
+func header(typ string) streamHeader {
+	return streamHeader{Version: streamJSONVersion, Type: typ}
+}
+
+// streamUsage uses the same field names as the --json usage object.
+type streamUsage struct {
+	InputTokens         int64 `json:"input_tokens"`
+	OutputTokens        int64 `json:"output_tokens"`
+	TotalTokens         int64 `json:"total_tokens"`
+	CacheReadTokens     int64 `json:"cache_read_tokens"`
+	CacheCreationTokens int64 `json:"cache_creation_tokens"`
+}
+
+func (u *streamUsage) add(ev kit.StepUsageEvent) {
+	u.InputTokens += int64(ev.InputTokens)
+	u.OutputTokens += int64(ev.OutputTokens)
+	u.CacheReadTokens += int64(ev.CacheReadTokens)
+	u.CacheCreationTokens += int64(ev.CacheWriteTokens)
+	u.TotalTokens = u.InputTokens + u.OutputTokens
+}
+
+type (
+	streamInit struct {
+		streamHeader
+		SessionID string   `json:"session_id,omitempty"`
+		Model     string   `json:"model"`
+		Cwd       string   `json:"cwd,omitempty"`
+		Tools     []string `json:"tools"`
+	}
+	streamTurnStart struct {
+		streamHeader
+		Prompt string `json:"prompt"`
+	}
+	streamText struct {
+		streamHeader
+		Text string `json:"text"`
+	}
+	streamToolCallStart struct {
+		streamHeader
+		ToolCallID string `json:"tool_call_id"`
+		ToolName   string `json:"tool_name"`
+		ToolKind   string `json:"tool_kind,omitempty"`
+	}
+	streamToolCallDelta struct {
+		streamHeader
+		ToolCallID string `json:"tool_call_id"`
+		Delta      string `json:"delta"`
+	}
+	streamToolCall struct {
+		streamHeader
+		ToolCallID string `json:"tool_call_id"`
+		ToolName   string `json:"tool_name"`
+		ToolKind   string `json:"tool_kind,omitempty"`
+		Args       any    `json:"args"`
+	}
+	streamToolOutput struct {
+		streamHeader
+		ToolCallID string `json:"tool_call_id"`
+		ToolName   string `json:"tool_name"`
+		Chunk      string `json:"chunk"`
+		Stderr     bool   `json:"stderr,omitempty"`
+	}
+	streamToolResult struct {
+		streamHeader
+		ToolCallID string                  `json:"tool_call_id"`
+		ToolName   string                  `json:"tool_name"`
+		ToolKind   string                  `json:"tool_kind,omitempty"`
+		Result     string                  `json:"result"`
+		IsError    bool                    `json:"is_error"`
+		Metadata   *kit.ToolResultMetadata `json:"metadata,omitempty"`
+	}
+	streamStepUsage struct {
+		streamHeader
+		Usage streamUsage `json:"usage"`
+	}
+	streamCompaction struct {
+		streamHeader
+		OriginalTokens  int    `json:"original_tokens"`
+		CompactedTokens int    `json:"compacted_tokens"`
+		MessagesRemoved int    `json:"messages_removed"`
+		Error           string `json:"error,omitempty"`
+	}
+	streamRetry struct {
+		streamHeader
+		Attempt int    `json:"attempt"`
+		Error   string `json:"error,omitempty"`
+	}
+	streamModelFailover struct {
+		streamHeader
+		From   string `json:"from"`
+		To     string `json:"to"`
+		Reason string `json:"reason"`
+		Error  string `json:"error,omitempty"`
+	}
+	streamSteerConsumed struct {
+		streamHeader
+		Count int `json:"count"`
+	}
+	streamBudgetWarning struct {
+		streamHeader
+		Scope    string  `json:"scope"`
+		SpentUSD float64 `json:"spent_usd"`
+		LimitUSD float64 `json:"limit_usd"`
+		Exceeded bool    `json:"exceeded"`
+	}
+	streamError struct {
+		streamHeader
+		Error string `json:"error"`
+	}
+	streamResult struct {
+		streamHeader
+		Response   string      `json:"response"`
+		Model      string      `json:"model"`
+		StopReason string      `json:"stop_reason,omitempty"`
+		SessionID  string      `json:"session_id,omitempty"`
+		Usage      streamUsage `json:"usage"`
+		IsError    bool        `json:"is_error"`
+		Error      string      `json:"error,omitempty"`
+	}
+)
+
+// streamJSONWriter writes Kit events to w as stream-json records, one JSON
+// object per line. It is safe for concurrent use.
+type streamJSONWriter struct {
+	mu        sync.Mutex
+	enc       *json.Encoder
+	model     string
+	sessionID string
+	usage     streamUsage // accumulated over the current turn
+}
+
+func newStreamJSONWriter(w io.Writer, model, sessionID string) *streamJSONWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &streamJSONWriter{enc: enc, model: model, sessionID: sessionID}
+}
+
+// write encodes one record. Encoding errors (a closed stdout) are dropped:
+// there is nowhere left to report them.
+func (s *streamJSONWriter) write(rec any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.enc.Encode(rec)
+}
+
+// writeError writes an error record.
+func (s *streamJSONWriter) writeError(err error) {
+	s.write(streamError{streamHeader: header("error"), Error: err.Error()})
+}
+
+// handle converts a Kit event to its record and writes it. Events without
+// a stream-json representation are ignored.
+func (s *streamJSONWriter) handle(e kit.Event) {
+	switch ev := e.(type) {
+	case kit.TurnStartEvent:
+		s.mu.Lock()
+		s.usage = streamUsage{}
+		s.mu.Unlock()
+		s.write(streamTurnStart{streamHeader: header("turn_start"), Prompt: ev.Prompt})
+	case kit.MessageUpdateEvent:
+		s.write(streamText{streamHeader: header("text_delta"), Text: ev.Chunk})
+	case kit.ReasoningDeltaEvent:
+		s.write(streamText{streamHeader: header("reasoning_delta"), Text: ev.Delta})
+	case kit.ToolCallStartEvent:
+		s.write(streamToolCallStart{streamHeader: header("tool_call_start"), ToolCallID: ev.ToolCallID, ToolName: ev.ToolName, ToolKind: ev.ToolKind})
+	case kit.ToolCallDeltaEvent:
+		s.write(streamToolCallDelta{streamHeader: header("tool_call_delta"), ToolCallID: ev.ToolCallID, Delta: ev.Delta})
+	case kit.ToolCallEvent:
+		s.write(streamToolCall{streamHeader: header("tool_call"), ToolCallID: ev.ToolCallID, ToolName: ev.ToolName, ToolKind: ev.ToolKind, Args: rawArgs(ev.ToolArgs)})
+	case kit.ToolOutputEvent:
+		s.write(streamToolOutput{streamHeader: header("tool_output"), ToolCallID: ev.ToolCallID, ToolName: ev.ToolName, Chunk: ev.Chunk, Stderr: ev.IsStderr})
+	case kit.ToolResultEvent:
+		s.write(streamToolResult{
+			streamHeader: header("tool_result"),
+			ToolCallID:   ev.ToolCallID,
+			ToolName:     ev.ToolName,
+			ToolKind:     ev.ToolKind,
+			Result:       ev.Result,
+			IsError:      ev.IsError,
+			Metadata:     ev.Metadata,
+		})
+	case kit.StepUsageEvent:
+		var step streamUsage
+		step.add(ev)
+		s.mu.Lock()
+		s.usage.add(ev)
+		s.mu.Unlock()
+		s.write(streamStepUsage{streamHeader: header("step_usage"), Usage: step})
+	case kit.CompactionEvent:
+		rec := streamCompaction{
+			streamHeader:    header("compaction"),
+			OriginalTokens:  ev.OriginalTokens,
+			CompactedTokens: ev.CompactedTokens,
+			MessagesRemoved: ev.MessagesRemoved,
+		}
+		if ev.Err != nil {
+			rec.Error = ev.Err.Error()
+		}
+		s.write(rec)
+	case kit.RetryEvent:
+		s.write(streamRetry{streamHeader: header("retry"), Attempt: ev.Attempt, Error: errString(ev.Error)})
+	case kit.ModelFailoverEvent:
+		s.mu.Lock()
+		s.model = ev.To
+		s.mu.Unlock()
+		s.write(streamModelFailover{streamHeader: header("model_failover"), From: ev.From, To: ev.To, Reason: ev.Reason, Error: errString(ev.Error)})
+	case kit.SteerConsumedEvent:
+		s.write(streamSteerConsumed{streamHeader: header("steer_consumed"), Count: ev.Count})
+	case kit.BudgetWarningEvent:
+		s.write(streamBudgetWarning{streamHeader: header("budget_warning"), Scope: ev.Scope, SpentUSD: ev.SpentUSD, LimitUSD: ev.LimitUSD, Exceeded: ev.Exceeded})
+	case kit.ErrorEvent:
+		s.write(streamError{streamHeader: header("error"), Error: errString(ev.Error)})
+	case kit.TurnEndEvent:
+		s.mu.Lock()
+		usage, model := s.usage, s.model
+		s.mu.Unlock()
+		rec := streamResult{
+			streamHeader: header("result"),
+			Response:     ev.Response,
+			Model:        model,
+			StopReason:   ev.StopReason,
+			SessionID:    s.sessionID,
+			Usage:        usage,
+		}
+		if ev.Error != nil {
+			rec.IsError = true
+			rec.Error = ev.Error.Error()
+		}
+		s.write(rec)
+	}
+}
+
+// rawArgs embeds tool arguments as JSON when they are valid JSON and as a
+// string otherwise.
+func rawArgs(args string) any {
+	if args != "" && json.Valid([]byte(args)) {
+		return json.RawMessage(args)
+	}
+	return args
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// streamInput is one stream-json input record. "user" sends a message (queued
+// behind the running turn, if any); "steer" redirects the running turn
+// between steps, or starts a turn when idle.
+type streamInput struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// parseStreamInput decodes and validates one input line.
+func parseStreamInput(line []byte) (streamInput, error) {
+	var in streamInput
+	if err := json.Unmarshal(line, &in); err != nil {
+		return in, fmt.Errorf("invalid input record: %w", err)
+	}
+	switch in.Type {
+	case "user", "steer":
+	case "":
+		return in, errors.New("input record has no type")
+	default:
+		return in, fmt.Errorf("unknown input record type %q", in.Type)
+	}
+	if in.Text == "" {
+		return in, fmt.Errorf("%s record has no text", in.Type)
+	}
+	return in, nil
+}
+
+// runStreamJSONMode runs Kit headless with --output-format stream-json. The
+// prompt, if any, runs first. With readInput set, user and steer records are
+// then read from stdin until EOF, after which the last turn is awaited.
+func runStreamJSONMode(ctx context.Context, deps runModeDeps, prompt string, readInput bool) error {
+	appInstance := deps.appInstance
+	out := newStreamJSONWriter(os.Stdout, deps.modelName, deps.kitInstance.GetSessionID())
+	unsub := deps.kitInstance.Subscribe(out.handle)
+	defer unsub()
+
+	cwd, _ := os.Getwd()
+	out.write(streamInit{
+		streamHeader: header("init"),
+		SessionID:    deps.kitInstance.GetSessionID(),
+		Model:        deps.modelName,
+		Cwd:          cwd,
+		Tools:        deps.kitInstance.GetToolNames(),
+	})
+
+	var files []kit.LLMFilePart
+	if prompt != "" {
+		prompt, files = expandPromptFiles(prompt)
+	}
+
+	if !readInput {
+		// Errors inside the turn are already reported by its result record.
+		_, err := appInstance.RunOnceResultWithFiles(ctx, prompt, files)
+		return err
+	}
+
+	if prompt != "" {
+		appInstance.RunWithFiles(prompt, files)
+	}
+
+	lines := make(chan []byte)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			out.writeError(fmt.Errorf("read input: %w", err))
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			appInstance.Abort()
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				return appInstance.WaitForIdle(0)
+			}
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			in, err := parseStreamInput(line)
+			if err != nil {
+				out.writeError(err)
+				continue
+			}
+			switch in.Type {
+			case "user":
+				appInstance.Run(in.Text)
+			case "steer":
+				appInstance.Steer(in.Text)
+			}
+		}
+	}
+}
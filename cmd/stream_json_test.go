@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/kit/pkg/kit"
+)
+
+func TestStreamJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newStreamJSONWriter(&buf, "anthropic/test", "sess-1")
+
+	w.handle(kit.TurnStartEvent{Prompt: "list files"})
+	w.handle(kit.ToolCallEvent{ToolCallID: "tc1", ToolName: "ls", ToolArgs: `{"path":"."}`})
+	w.handle(kit.ToolResultEvent{ToolCallID: "tc1", ToolName: "ls", Result: "a.go"})
+	w.handle(kit.StepUsageEvent{InputTokens: 10, OutputTokens: 5})
+	w.handle(kit.MessageUpdateEvent{Chunk: "Done"})
+	w.handle(kit.MessageStartEvent{}) // no record
+	w.handle(kit.StepUsageEvent{InputTokens: 20, OutputTokens: 7, CacheReadTokens: 3})
+	w.handle(kit.TurnEndEvent{Response: "Done", StopReason: "stop"})
+	w.handle(kit.TurnEndEvent{Error: errors.New("boom")})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	wantTypes := []string{"turn_start", "tool_call", "tool_result", "step_usage", "text_delta", "step_usage", "result", "result"}
+	if len(lines) != len(wantTypes) {
+		t.Fatalf("got %d records, want %d:\n%s", len(lines), len(wantTypes), buf.String())
+	}
+	records := make([]map[string]any, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &records[i]); err != nil {
+			t.Fatalf("record %d is not JSON: %v", i, err)
+		}
+		if records[i]["v"] != float64(streamJSONVersion) || records[i]["type"] != wantTypes[i] {
+			t.Errorf("record %d = %s, want type %s", i, line, wantTypes[i])
+		}
+	}
+
+	// Tool arguments are embedded as JSON, not a string.
+	if args, ok := records[1]["args"].(map[string]any); !ok || args["path"] != "." {
+		t.Errorf("tool_call args = %v", records[1]["args"])
+	}
+
+	result := records[6]
+	if result["response"] != "Done" || result["model"] != "anthropic/test" || result["session_id"] != "sess-1" || result["is_error"] != false {
+		t.Errorf("result = %s", lines[6])
+	}
+	usage := result["usage"].(map[string]any)
+	if usage["input_tokens"] != float64(30) || usage["output_tokens"] != float64(12) || usage["cache_read_tokens"] != float64(3) {
+		t.Errorf("turn usage = %v", usage)
+	}
+
+	if failed := records[7]; failed["is_error"] != true || failed["error"] != "boom" {
+		t.Errorf("failed result = %s", lines[7])
+	}
+}
+
+func TestParseStreamInput(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    streamInput
+		wantErr string
+	}{
+		{line: `{"type":"user","text":"hi"}`, want: streamInput{Type: "user", Text: "hi"}},
+		{line: `{"v":1,"type":"steer","text":"use tabs"}`, want: streamInput{Type: "steer", Text: "use tabs"}},
+		{line: `not json`, wantErr: "invalid input record"},
+		{line: `{"text":"hi"}`, wantErr: "no type"},
+		{line: `{"type":"shutdown"}`, wantErr: "unknown input record type"},
+		{line: `{"type":"user"}`, wantErr: "no text"},
+	}
+	for _, tt := range tests {
+		got, err := parseStreamInput([]byte(tt.line))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseStreamInput(%s) error = %v, want %q", tt.line, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseStreamInput(%s) = %+v, %v", tt.line, got, err)
+		}
+	}
+}
+
+func TestValidateModeFlags_Formats(t *testing.T) {
+	reset := func() {
+		positionalPrompt, outputFormat, inputFormat = "", formatText, formatText
+		jsonFlag, quietFlag, noExitFlag = false, false, false
+	}
+	t.Cleanup(reset)
+
+	tests := []struct {
+		name    string
+		set     func()
+		wantErr string
+	}{
+		{"stream output with prompt", func() { positionalPrompt, outputFormat = "hi", formatStreamJSON }, ""},
+		{"stream output without prompt", func() { outputFormat = formatStreamJSON }, "requires a prompt"},
+		{"stream input", func() { outputFormat, inputFormat = formatStreamJSON, formatStreamJSON }, ""},
+		{"stream input needs stream output", func() { inputFormat = formatStreamJSON }, "requires --output-format stream-json"},
+		{"json conflicts", func() { positionalPrompt, jsonFlag, outputFormat = "hi", true, formatStreamJSON }, "conflicts"},
+		{"bad format", func() { outputFormat = "yaml" }, "invalid --output-format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			tt.set()
+			err := validateModeFlags()
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	reset()
+	positionalPrompt, jsonFlag = "hi", true
+	if err := validateModeFlags(); err != nil || outputFormat != formatJSON {
+		t.Errorf("--json: err %v, output format %q", err, outputFormat)
+	}
+}
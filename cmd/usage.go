@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mark3labs/kit/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageByFlag      string
+	usageSinceFlag   string
+	usageProjectFlag string
+	usageFormatFlag  string
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report token usage and cost across sessions",
+	Long: `Report the tokens and cost of every LLM call Kit has made, read from the
+usage ledger (~/.kit/usage.jsonl).
+
+Rows are grouped by day, ISO week, month, model, project or session. Costs
+use the model database's prices; calls to unpriced models and calls made
+with a subscription (OAuth) login cost nothing.
+
+Examples:
+  kit usage
+  kit usage --by week --since 90d
+  kit usage --by model --project .
+  kit usage --by session --format csv > usage.csv`,
+	Args: cobra.NoArgs,
+	RunE: runUsage,
+}
+
+func init() {
+	usageCmd.Flags().StringVar(&usageByFlag, "by", usage.ByDay, "group rows by: "+strings.Join(usage.Groupings, ", "))
+	usageCmd.Flags().StringVar(&usageSinceFlag, "since", "30d", `only count calls since a date (2006-01-02) or age (12h, 7d, 4w); "all" for everything`)
+	usageCmd.Flags().StringVar(&usageProjectFlag, "project", "", `only count calls made in this project directory ("." for the current one)`)
+	usageCmd.Flags().StringVar(&usageFormatFlag, "format", "table", "output format: table, csv, or json")
+	rootCmd.AddCommand(usageCmd)
+}
+
+func runUsage(_ *cobra.Command, _ []string) error {
+	since, err := parseSince(usageSinceFlag, time.Now())
+	if err != nil {
+		return err
+	}
+	switch usageFormatFlag {
+	case "table", "csv", "json":
+	default:
+		return fmt.Errorf("invalid --format %q (want table, csv, or json)", usageFormatFlag)
+	}
+
+	entries, err := usage.Open("").Read(usage.Filter{Since: since, Project: usageProjectFlag})
+	if err != nil {
+		return fmt.Errorf("reading usage ledger: %w", err)
+	}
+	rows, err := usage.Summarize(entries, usageByFlag)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 && usageFormatFlag == "table" {
+		fmt.Println("No usage recorded for this period.")
+		return nil
+	}
+	return writeUsageReport(os.Stdout, usageFormatFlag, usageByFlag, rows)
+}
+
+// parseSince turns --since into the start of the report window. The zero
+// time means no lower bound.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	switch {
+	case s == "" || s == "all":
+		return time.Time{}, nil
+	case strings.HasSuffix(s, "d"), strings.HasSuffix(s, "w"):
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n < 0 {
+			break
+		}
+		if strings.HasSuffix(s, "w") {
+			n *= 7
+		}
+		return usage.StartOfDay(now).AddDate(0, 0, -n), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (want a date like 2006-01-02, an age like 7d, or all)", s)
+}
+
+// writeUsageReport writes rows, followed by their total, as an aligned
+// table, CSV, or a JSON document.
+func writeUsageReport(w io.Writer, format, by string, rows []usage.Row) error {
+	total := usage.Total(rows)
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if rows == nil {
+			rows = []usage.Row{}
+		}
+		return enc.Encode(struct {
+			By    string      `json:"by"`
+			Rows  []usage.Row `json:"rows"`
+			Total usage.Row   `json:"total"`
+		}{by, rows, total})
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{by, "requests", "input_tokens", "output_tokens", "cache_read_tokens", "cache_write_tokens", "cost_usd"})
+		for _, r := range rows {
+			_ = cw.Write([]string{
+				r.Key,
+				strconv.Itoa(r.Requests),
+				strconv.FormatInt(r.InputTokens, 10),
+				strconv.FormatInt(r.OutputTokens, 10),
+				strconv.FormatInt(r.CacheReadTokens, 10),
+				strconv.FormatInt(r.CacheWriteTokens, 10),
+				strconv.FormatFloat(r.CostUSD, 'f', 6, 64),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "%s\tREQUESTS\tINPUT\tOUTPUT\tCACHE READ\tCACHE WRITE\tCOST\n", strings.ToUpper(by))
+	for _, r := range append(rows, total) {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t$%.2f\n",
+			r.Key, r.Requests, r.InputTokens, r.OutputTokens, r.CacheReadTokens, r.CacheWriteTokens, r.CostUSD)
+	}
+	return tw.Flush()
+}
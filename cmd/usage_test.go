@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/kit/internal/usage"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.Local)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"all", time.Time{}},
+		{"0d", time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)},
+		{"7d", time.Date(2026, 3, 3, 0, 0, 0, 0, time.Local)},
+		{"2w", time.Date(2026, 2, 24, 0, 0, 0, 0, time.Local)},
+		{"12h", now.Add(-12 * time.Hour)},
+		{"2026-01-05", time.Date(2026, 1, 5, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := parseSince(tt.in, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"yesterday", "-3d", "xd"} {
+		if _, err := parseSince(bad, now); err == nil {
+			t.Errorf("parseSince(%q) accepted", bad)
+		}
+	}
+}
+
+func TestWriteUsageReport(t *testing.T) {
+	rows := []usage.Row{
+		{Key: "anthropic/m1", Requests: 2, InputTokens: 100, OutputTokens: 20, CostUSD: 1.5},
+		{Key: "openai/m2", Requests: 1, InputTokens: 10, CostUSD: 0.25},
+	}
+
+	var buf bytes.Buffer
+	if err := writeUsageReport(&buf, "csv", "model", rows); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != "model,requests,input_tokens,output_tokens,cache_read_tokens,cache_write_tokens,cost_usd" ||
+		lines[1] != "anthropic/m1,2,100,20,0,0,1.500000" {
+		t.Errorf("csv =\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeUsageReport(&buf, "json", "model", rows); err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		By    string
+		Rows  []usage.Row
+		Total usage.Row
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.By != "model" || len(report.Rows) != 2 || report.Total.Requests != 3 || report.Total.CostUSD != 1.75 {
+		t.Errorf("json report = %+v", report)
+	}
+
+	buf.Reset()
+	if err := writeUsageReport(&buf, "table", "model", rows); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.HasPrefix(out, "MODEL") || !strings.Contains(out, "total") || !strings.Contains(out, "$1.75") {
+		t.Errorf("table =\n%s", out)
+	}
+}
@@ -13,10 +13,12 @@ import (
 	"os"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/charmbracelet/log"
 	acp "github.com/coder/acp-go-sdk"
 
+	"github.com/mark3labs/kit/internal/session"
 	kit "github.com/mark3labs/kit/pkg/kit"
 )
 
@@ -32,6 +34,9 @@ type Agent struct {
 	toolCallCounter atomic.Int64
 }
 
+// Agent also supports session/load.
+var _ acp.AgentLoader = (*Agent)(nil)
+
 // NewAgent creates a new ACP agent backed by Kit.
 func NewAgent() *Agent {
 	return &Agent{
@@ -71,10 +76,21 @@ func (a *Agent) Logout(_ context.Context, _ acp.LogoutRequest) (acp.LogoutRespon
 func (a *Agent) Initialize(_ context.Context, params acp.InitializeRequest) (acp.InitializeResponse, error) {
 	log.Debug("acp: initialize", "protocol_version", params.ProtocolVersion)
 
+	// Sessions read and write files, run commands and ask for permission
+	// through the client when it supports that.
+	if a.conn != nil {
+		a.registry.setClient(a.conn, params.ClientCapabilities)
+	}
+
 	return acp.InitializeResponse{
 		ProtocolVersion: acp.ProtocolVersion(1),
 		AgentCapabilities: acp.AgentCapabilities{
 			LoadSession: true,
+			SessionCapabilities: acp.SessionCapabilities{
+				List:   &acp.SessionListCapabilities{},
+				Resume: &acp.SessionResumeCapabilities{},
+				Close:  &acp.SessionCloseCapabilities{},
+			},
 			PromptCapabilities: acp.PromptCapabilities{
 				EmbeddedContext: true,
 				Image:           true,
@@ -104,9 +120,35 @@ func (a *Agent) NewSession(ctx context.Context, params acp.NewSessionRequest) (a
 
 	return acp.NewSessionResponse{
 		SessionId: acp.SessionId(sess.sessionID),
+		Modes:     sess.modeState(),
 	}, nil
 }
 
+// LoadSession reopens a session saved by an earlier agent process (or the
+// CLI) for the same working directory and replays its current branch to the
+// client as session updates before returning.
+func (a *Agent) LoadSession(ctx context.Context, params acp.LoadSessionRequest) (acp.LoadSessionResponse, error) {
+	sessionID := string(params.SessionId)
+	log.Debug("acp: load_session", "session", sessionID, "cwd", params.Cwd)
+
+	// The Kit outlives this request, so it must not inherit its
+	// cancellation.
+	sess, err := a.registry.load(context.WithoutCancel(ctx), params.Cwd, sessionID)
+	if err != nil {
+		return acp.LoadSessionResponse{}, acp.NewInvalidParams(err.Error())
+	}
+
+	for _, update := range historyUpdates(sess.kit.GetStructuredMessages()) {
+		if err := a.conn.SessionUpdate(ctx, acp.SessionNotification{
+			SessionId: params.SessionId,
+			Update:    update,
+		}); err != nil {
+			return acp.LoadSessionResponse{}, fmt.Errorf("replay history: %w", err)
+		}
+	}
+	return acp.LoadSessionResponse{Modes: sess.modeState()}, nil
+}
+
 // Prompt handles the main agent execution. It subscribes to Kit's event bus,
 // converts events to ACP session updates, and runs the prompt through Kit's
 // full turn lifecycle (hooks, LLM, tool calls, persistence).
@@ -136,7 +178,7 @@ func (a *Agent) Prompt(ctx context.Context, params acp.PromptRequest) (acp.Promp
 
 	// Create a cancellable context for this prompt turn.
 	promptCtx, cancel := context.WithCancel(ctx)
-	sess.setCancel(cancel)
+	sess.setCancel(promptCtx, cancel)
 	defer sess.clearCancel()
 
 	// Subscribe to Kit events and stream them as ACP session updates.
@@ -178,17 +220,53 @@ func (a *Agent) Cancel(_ context.Context, params acp.CancelNotification) error {
 	return nil
 }
 
-// SetSessionMode is a no-op for now — Kit doesn't have built-in session modes.
-func (a *Agent) SetSessionMode(_ context.Context, _ acp.SetSessionModeRequest) (acp.SetSessionModeResponse, error) {
+// SetSessionMode switches a session between the ask, code and read-only
+// modes, which decide which tool calls need the user's permission.
+func (a *Agent) SetSessionMode(_ context.Context, params acp.SetSessionModeRequest) (acp.SetSessionModeResponse, error) {
+	sessionID := string(params.SessionId)
+	sess, ok := a.registry.get(sessionID)
+	if !ok {
+		return acp.SetSessionModeResponse{}, acp.NewInvalidParams(fmt.Sprintf("session not found: %s", sessionID))
+	}
+
+	log.Debug("acp: set_session_mode", "session", sessionID, "mode", params.ModeId)
+	if err := sess.setMode(string(params.ModeId)); err != nil {
+		return acp.SetSessionModeResponse{}, acp.NewInvalidParams(err.Error())
+	}
 	return acp.SetSessionModeResponse{}, nil
 }
 
-// ListSessions returns an empty session list. Kit doesn't persist sessions
-// across restarts in ACP mode, so this is effectively a no-op.
-func (a *Agent) ListSessions(_ context.Context, _ acp.ListSessionsRequest) (acp.ListSessionsResponse, error) {
-	return acp.ListSessionsResponse{
-		Sessions: []acp.SessionInfo{},
-	}, nil
+// ListSessions returns the saved sessions, newest first. With a cwd filter
+// only sessions created in that directory are listed; otherwise sessions
+// from every directory are.
+func (a *Agent) ListSessions(_ context.Context, params acp.ListSessionsRequest) (acp.ListSessionsResponse, error) {
+	var infos []session.SessionInfo
+	var err error
+	if params.Cwd != nil && *params.Cwd != "" {
+		infos, err = session.ListSessions(*params.Cwd)
+	} else {
+		infos, err = session.ListAllSessions()
+	}
+	if err != nil {
+		return acp.ListSessionsResponse{}, fmt.Errorf("list sessions: %w", err)
+	}
+
+	sessions := make([]acp.SessionInfo, 0, len(infos))
+	for _, info := range infos {
+		// Subagent sessions are internal to a parent conversation.
+		if info.ParentSessionID != "" {
+			continue
+		}
+		title := sessionTitle(info)
+		updated := info.Modified.UTC().Format(time.RFC3339)
+		sessions = append(sessions, acp.SessionInfo{
+			SessionId: acp.SessionId(info.ID),
+			Cwd:       info.Cwd,
+			Title:     &title,
+			UpdatedAt: &updated,
+		})
+	}
+	return acp.ListSessionsResponse{Sessions: sessions}, nil
 }
 
 // CloseSession cancels any ongoing work for the session and frees its resources.
@@ -205,10 +283,15 @@ func (a *Agent) CloseSession(_ context.Context, params acp.CloseSessionRequest)
 	return acp.CloseSessionResponse{}, nil
 }
 
-// ResumeSession is not supported — Kit doesn't persist sessions across
-// restarts in ACP mode. Clients should use NewSession instead.
-func (a *Agent) ResumeSession(_ context.Context, _ acp.ResumeSessionRequest) (acp.ResumeSessionResponse, error) {
-	return acp.ResumeSessionResponse{}, fmt.Errorf("resume session not supported")
+// ResumeSession reopens a saved session like LoadSession but without
+// replaying its history.
+func (a *Agent) ResumeSession(ctx context.Context, params acp.ResumeSessionRequest) (acp.ResumeSessionResponse, error) {
+	log.Debug("acp: resume_session", "session", params.SessionId, "cwd", params.Cwd)
+	sess, err := a.registry.load(context.WithoutCancel(ctx), params.Cwd, string(params.SessionId))
+	if err != nil {
+		return acp.ResumeSessionResponse{}, acp.NewInvalidParams(err.Error())
+	}
+	return acp.ResumeSessionResponse{Modes: sess.modeState()}, nil
 }
 
 // SetSessionConfigOption handles session configuration changes. Currently
@@ -319,10 +402,71 @@ func (a *Agent) subscribeEvents(ctx context.Context, k *kit.Kit, sessionID acp.S
 	})
 }
 
+// historyUpdates converts a session's messages to the session updates a
+// client would have seen live: user and agent text, thoughts, and each tool
+// call with its final status and output.
+func historyUpdates(msgs []kit.StructuredMessage) []acp.SessionUpdate {
+	var updates []acp.SessionUpdate
+	for _, msg := range msgs {
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case kit.TextContent:
+				if p.Text == "" {
+					continue
+				}
+				if msg.Role == kit.RoleUser {
+					updates = append(updates, acp.UpdateUserMessageText(p.Text))
+				} else {
+					updates = append(updates, acp.UpdateAgentMessageText(p.Text))
+				}
+
+			case kit.ReasoningContent:
+				if p.Thinking != "" {
+					updates = append(updates, acp.UpdateAgentThoughtText(p.Thinking))
+				}
+
+			case kit.ToolCall:
+				updates = append(updates, acp.StartToolCall(acp.ToolCallId(p.ID), p.Name,
+					acp.WithStartStatus(acp.ToolCallStatusInProgress),
+					acp.WithStartRawInput(parseToolArgs(p.Input)),
+				))
+
+			case kit.ToolResult:
+				status := acp.ToolCallStatusCompleted
+				if p.IsError {
+					status = acp.ToolCallStatusFailed
+				}
+				updates = append(updates, acp.UpdateToolCall(acp.ToolCallId(p.ToolCallID),
+					acp.WithUpdateStatus(status),
+					acp.WithUpdateContent([]acp.ToolCallContent{
+						acp.ToolContent(acp.TextBlock(p.Content)),
+					}),
+				))
+			}
+		}
+	}
+	return updates
+}
+
 // ---------------------------------------------------------------------------
 // Helpers
 // ---------------------------------------------------------------------------
 
+// sessionTitle names a session by its display name, else its first message.
+func sessionTitle(info session.SessionInfo) string {
+	title := info.Name
+	if title == "" {
+		title = strings.Join(strings.Fields(info.FirstMessage), " ")
+	}
+	if title == "" {
+		return info.ID
+	}
+	if r := []rune(title); len(r) > 60 {
+		title = string(r[:60]) + "…"
+	}
+	return title
+}
+
 // extractPromptContent extracts text and file attachments from ACP content blocks.
 // It converts supported content blocks (image, audio, resource) to Kit's LLMFilePart.
 func extractPromptContent(blocks []acp.ContentBlock) (string, []kit.LLMFilePart) {
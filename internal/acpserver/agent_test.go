@@ -0,0 +1,96 @@
+package acpserver
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	acp "github.com/coder/acp-go-sdk"
+
+	"github.com/mark3labs/kit/internal/message"
+	"github.com/mark3labs/kit/internal/session"
+	kit "github.com/mark3labs/kit/pkg/kit"
+)
+
+func TestHistoryUpdates(t *testing.T) {
+	updates := historyUpdates([]kit.StructuredMessage{
+		{Role: kit.RoleUser, Parts: []kit.ContentPart{kit.TextContent{Text: "list files"}}},
+		{Role: kit.RoleAssistant, Parts: []kit.ContentPart{
+			kit.ReasoningContent{Thinking: "use ls"},
+			kit.TextContent{Text: "Listing."},
+			kit.ToolCall{ID: "call_1", Name: "ls", Input: `{"path":"."}`},
+		}},
+		{Role: kit.RoleTool, Parts: []kit.ContentPart{
+			kit.ToolResult{ToolCallID: "call_1", Name: "ls", Content: "boom", IsError: true},
+		}},
+		{Role: kit.RoleAssistant, Parts: []kit.ContentPart{kit.TextContent{Text: ""}, kit.Finish{Reason: "end_turn"}}},
+	})
+
+	if len(updates) != 5 {
+		t.Fatalf("got %d updates, want 5", len(updates))
+	}
+	if u := updates[0].UserMessageChunk; u == nil || u.Content.Text.Text != "list files" {
+		t.Errorf("update 0 = %+v, want user text", updates[0])
+	}
+	if u := updates[1].AgentThoughtChunk; u == nil || u.Content.Text.Text != "use ls" {
+		t.Errorf("update 1 = %+v, want thought", updates[1])
+	}
+	if u := updates[2].AgentMessageChunk; u == nil || u.Content.Text.Text != "Listing." {
+		t.Errorf("update 2 = %+v, want agent text", updates[2])
+	}
+	if u := updates[3].ToolCall; u == nil || u.ToolCallId != "call_1" || u.Title != "ls" {
+		t.Errorf("update 3 = %+v, want ls tool call", updates[3])
+	}
+	if u := updates[4].ToolCallUpdate; u == nil || u.ToolCallId != "call_1" ||
+		u.Status == nil || *u.Status != acp.ToolCallStatusFailed {
+		t.Errorf("update 4 = %+v, want failed tool call update", updates[4])
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	cwd := filepath.Join(dir, "project")
+
+	tm, err := session.CreateTreeSession(cwd)
+	if err != nil {
+		t.Fatalf("CreateTreeSession: %v", err)
+	}
+	if _, err := tm.AppendMessage(message.Message{
+		Role:  message.RoleUser,
+		Parts: []message.ContentPart{message.TextContent{Text: "fix the   build"}},
+	}); err != nil {
+		t.Fatalf("AppendMessage: %v", err)
+	}
+	id := tm.GetSessionID()
+	_ = tm.Close()
+
+	a := NewAgent()
+	resp, err := a.ListSessions(context.Background(), acp.ListSessionsRequest{Cwd: &cwd})
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(resp.Sessions) != 1 {
+		t.Fatalf("got %d sessions, want 1", len(resp.Sessions))
+	}
+	got := resp.Sessions[0]
+	if string(got.SessionId) != id || got.Cwd != cwd || got.Title == nil || *got.Title != "fix the build" || got.UpdatedAt == nil {
+		t.Errorf("session = %+v (title %v)", got, got.Title)
+	}
+
+	other := filepath.Join(dir, "other")
+	resp, err = a.ListSessions(context.Background(), acp.ListSessionsRequest{Cwd: &other})
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(resp.Sessions) != 0 {
+		t.Errorf("other cwd: got %d sessions, want 0", len(resp.Sessions))
+	}
+
+	if _, err := findSession(other, id); err == nil {
+		t.Error("findSession should not find a session from another directory")
+	}
+	if info, err := findSession(cwd, id); err != nil || info.ID != id {
+		t.Errorf("findSession = %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,120 @@
+package acpserver
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	acp "github.com/coder/acp-go-sdk"
+)
+
+// clientConn is the part of the ACP connection used to call back into the
+// client: file access, terminals, permission prompts and session updates.
+// *acp.AgentSideConnection implements it.
+type clientConn interface {
+	ReadTextFile(ctx context.Context, params acp.ReadTextFileRequest) (acp.ReadTextFileResponse, error)
+	WriteTextFile(ctx context.Context, params acp.WriteTextFileRequest) (acp.WriteTextFileResponse, error)
+	CreateTerminal(ctx context.Context, params acp.CreateTerminalRequest) (acp.CreateTerminalResponse, error)
+	TerminalOutput(ctx context.Context, params acp.TerminalOutputRequest) (acp.TerminalOutputResponse, error)
+	WaitForTerminalExit(ctx context.Context, params acp.WaitForTerminalExitRequest) (acp.WaitForTerminalExitResponse, error)
+	KillTerminal(ctx context.Context, params acp.KillTerminalRequest) (acp.KillTerminalResponse, error)
+	ReleaseTerminal(ctx context.Context, params acp.ReleaseTerminalRequest) (acp.ReleaseTerminalResponse, error)
+	RequestPermission(ctx context.Context, params acp.RequestPermissionRequest) (acp.RequestPermissionResponse, error)
+	SessionUpdate(ctx context.Context, params acp.SessionNotification) error
+}
+
+var _ clientConn = (*acp.AgentSideConnection)(nil)
+
+// terminalOutputLimit caps the output the client keeps per terminal. The
+// bash tool truncates further, keeping the tail.
+const terminalOutputLimit = 1 << 20
+
+// clientFS implements kit.FileSystem with the client's fs/read_text_file and
+// fs/write_text_file methods, so reads see unsaved editor buffers and writes
+// land in open documents.
+type clientFS struct {
+	conn    clientConn
+	session *acpSession
+}
+
+func (f *clientFS) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	resp, err := f.conn.ReadTextFile(ctx, acp.ReadTextFileRequest{
+		SessionId: acp.SessionId(f.session.sessionID),
+		Path:      path,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(resp.Content), nil
+}
+
+func (f *clientFS) WriteFile(ctx context.Context, path string, data []byte) error {
+	_, err := f.conn.WriteTextFile(ctx, acp.WriteTextFileRequest{
+		SessionId: acp.SessionId(f.session.sessionID),
+		Path:      path,
+		Content:   string(data),
+	})
+	return err
+}
+
+// clientTerminal implements kit.BashRunner by running bash commands in
+// terminals created by the client, so the user sees them in the editor.
+type clientTerminal struct {
+	conn    clientConn
+	session *acpSession
+}
+
+func (t *clientTerminal) Name() string { return "terminal" }
+
+// Command is only used for background jobs, which need a local process.
+func (t *clientTerminal) Command(context.Context, string, string) (*exec.Cmd, error) {
+	return nil, fmt.Errorf("background jobs are not available when commands run in the client's terminal")
+}
+
+func (t *clientTerminal) Run(ctx context.Context, script, workDir string) (string, int, error) {
+	sessionID := acp.SessionId(t.session.sessionID)
+	limit := terminalOutputLimit
+	req := acp.CreateTerminalRequest{
+		SessionId:       sessionID,
+		Command:         "bash",
+		Args:            []string{"-c", script},
+		OutputByteLimit: &limit,
+	}
+	if workDir != "" {
+		req.Cwd = &workDir
+	}
+	term, err := t.conn.CreateTerminal(ctx, req)
+	if err != nil {
+		return "", 0, fmt.Errorf("create terminal: %w", err)
+	}
+
+	// Clean-up requests must still go out after ctx is cancelled.
+	cleanupCtx := context.WithoutCancel(ctx)
+	defer func() {
+		_, _ = t.conn.ReleaseTerminal(cleanupCtx, acp.ReleaseTerminalRequest{SessionId: sessionID, TerminalId: term.TerminalId})
+	}()
+
+	exit, err := t.conn.WaitForTerminalExit(ctx, acp.WaitForTerminalExitRequest{SessionId: sessionID, TerminalId: term.TerminalId})
+	if err != nil {
+		_, _ = t.conn.KillTerminal(cleanupCtx, acp.KillTerminalRequest{SessionId: sessionID, TerminalId: term.TerminalId})
+		if ctx.Err() != nil {
+			return "", 0, ctx.Err()
+		}
+		return "", 0, fmt.Errorf("wait for terminal: %w", err)
+	}
+
+	out, err := t.conn.TerminalOutput(cleanupCtx, acp.TerminalOutputRequest{SessionId: sessionID, TerminalId: term.TerminalId})
+	if err != nil {
+		return "", 0, fmt.Errorf("read terminal output: %w", err)
+	}
+	output := out.Output
+	exitCode := 0
+	switch {
+	case exit.ExitCode != nil:
+		exitCode = *exit.ExitCode
+	case exit.Signal != nil:
+		output += fmt.Sprintf("\nKilled by signal %s", *exit.Signal)
+		exitCode = 1
+	}
+	return output, exitCode, nil
+}
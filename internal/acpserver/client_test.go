@@ -0,0 +1,197 @@
+package acpserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	acp "github.com/coder/acp-go-sdk"
+
+	kit "github.com/mark3labs/kit/pkg/kit"
+)
+
+// fakeClient is an in-memory clientConn. Files live in a map, terminals
+// "run" by returning canned output and permission prompts pick option.
+type fakeClient struct {
+	files    map[string]string
+	output   string
+	exitCode int
+	created  []acp.CreateTerminalRequest
+	released int
+	option   string // selected permission option; empty cancels
+	asked    []acp.RequestPermissionRequest
+}
+
+func (c *fakeClient) ReadTextFile(_ context.Context, p acp.ReadTextFileRequest) (acp.ReadTextFileResponse, error) {
+	return acp.ReadTextFileResponse{Content: c.files[p.Path]}, nil
+}
+
+func (c *fakeClient) WriteTextFile(_ context.Context, p acp.WriteTextFileRequest) (acp.WriteTextFileResponse, error) {
+	c.files[p.Path] = p.Content
+	return acp.WriteTextFileResponse{}, nil
+}
+
+func (c *fakeClient) CreateTerminal(_ context.Context, p acp.CreateTerminalRequest) (acp.CreateTerminalResponse, error) {
+	c.created = append(c.created, p)
+	return acp.CreateTerminalResponse{TerminalId: "term_1"}, nil
+}
+
+func (c *fakeClient) TerminalOutput(context.Context, acp.TerminalOutputRequest) (acp.TerminalOutputResponse, error) {
+	return acp.TerminalOutputResponse{Output: c.output}, nil
+}
+
+func (c *fakeClient) WaitForTerminalExit(context.Context, acp.WaitForTerminalExitRequest) (acp.WaitForTerminalExitResponse, error) {
+	code := c.exitCode
+	return acp.WaitForTerminalExitResponse{ExitCode: &code}, nil
+}
+
+func (c *fakeClient) KillTerminal(context.Context, acp.KillTerminalRequest) (acp.KillTerminalResponse, error) {
+	return acp.KillTerminalResponse{}, nil
+}
+
+func (c *fakeClient) ReleaseTerminal(context.Context, acp.ReleaseTerminalRequest) (acp.ReleaseTerminalResponse, error) {
+	c.released++
+	return acp.ReleaseTerminalResponse{}, nil
+}
+
+func (c *fakeClient) RequestPermission(_ context.Context, p acp.RequestPermissionRequest) (acp.RequestPermissionResponse, error) {
+	c.asked = append(c.asked, p)
+	if c.option == "" {
+		return acp.RequestPermissionResponse{Outcome: acp.RequestPermissionOutcome{Cancelled: &acp.RequestPermissionOutcomeCancelled{}}}, nil
+	}
+	return acp.RequestPermissionResponse{Outcome: acp.RequestPermissionOutcome{
+		Selected: &acp.RequestPermissionOutcomeSelected{OptionId: acp.PermissionOptionId(c.option)},
+	}}, nil
+}
+
+func (c *fakeClient) SessionUpdate(context.Context, acp.SessionNotification) error { return nil }
+
+func newTestSession(client clientConn) *acpSession {
+	return &acpSession{
+		sessionID:     "sess_1",
+		client:        client,
+		alwaysAllowed: make(map[string]bool),
+		approvedCalls: make(map[string]bool),
+	}
+}
+
+func TestClientFS(t *testing.T) {
+	client := &fakeClient{files: map[string]string{"/w/a.txt": "unsaved buffer"}}
+	fsys := &clientFS{conn: client, session: newTestSession(client)}
+	ctx := context.Background()
+
+	data, err := fsys.ReadFile(ctx, "/w/a.txt")
+	if err != nil || string(data) != "unsaved buffer" {
+		t.Fatalf("ReadFile = %q, %v", data, err)
+	}
+	if err := fsys.WriteFile(ctx, "/w/b.txt", []byte("new")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if client.files["/w/b.txt"] != "new" {
+		t.Errorf("client file = %q, want new", client.files["/w/b.txt"])
+	}
+}
+
+func TestClientTerminal_Run(t *testing.T) {
+	client := &fakeClient{output: "hello\n", exitCode: 3}
+	term := &clientTerminal{conn: client, session: newTestSession(client)}
+
+	out, code, err := term.Run(context.Background(), "echo hello; exit 3", "/w")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out != "hello\n" || code != 3 {
+		t.Errorf("Run = %q, %d; want hello, 3", out, code)
+	}
+	if len(client.created) != 1 {
+		t.Fatalf("created %d terminals, want 1", len(client.created))
+	}
+	req := client.created[0]
+	if req.SessionId != "sess_1" || req.Command != "bash" || req.Cwd == nil || *req.Cwd != "/w" {
+		t.Errorf("unexpected terminal request %+v", req)
+	}
+	if client.released != 1 {
+		t.Errorf("released %d terminals, want 1", client.released)
+	}
+}
+
+func TestCheckTool_Modes(t *testing.T) {
+	client := &fakeClient{}
+	sess := newTestSession(client)
+	read := kit.BeforeToolCallHook{ToolCallID: "c1", ToolName: "read", ToolArgs: `{}`}
+	bash := kit.BeforeToolCallHook{ToolCallID: "c2", ToolName: "bash", ToolArgs: `{"command":"ls"}`}
+
+	if err := sess.setMode("yolo"); err == nil {
+		t.Error("setMode accepted an unknown mode")
+	}
+
+	if err := sess.setMode(modeReadOnly); err != nil {
+		t.Fatal(err)
+	}
+	if res := sess.checkTool(read); res != nil {
+		t.Errorf("read-only mode blocked read: %+v", res)
+	}
+	if res := sess.checkTool(bash); res == nil || !res.Block {
+		t.Error("read-only mode allowed bash")
+	}
+
+	if err := sess.setMode(modeCode); err != nil {
+		t.Fatal(err)
+	}
+	if res := sess.checkTool(bash); res != nil {
+		t.Errorf("code mode blocked bash: %+v", res)
+	}
+	if len(client.asked) != 0 {
+		t.Errorf("asked %d times outside ask mode", len(client.asked))
+	}
+}
+
+func TestCheckTool_Ask(t *testing.T) {
+	client := &fakeClient{}
+	sess := newTestSession(client)
+	bash := kit.BeforeToolCallHook{ToolCallID: "c1", ToolName: "bash", ToolArgs: `{"command":"ls"}`}
+
+	if got := sess.modeState().CurrentModeId; got != modeAsk {
+		t.Fatalf("default mode = %q, want %q", got, modeAsk)
+	}
+
+	// A cancelled prompt rejects the call.
+	if res := sess.checkTool(bash); res == nil || !res.Block {
+		t.Fatal("cancelled permission prompt allowed bash")
+	}
+	if len(client.asked) != 1 || client.asked[0].ToolCall.ToolCallId != "c1" {
+		t.Fatalf("permission requests = %+v", client.asked)
+	}
+	if kind := client.asked[0].ToolCall.Kind; kind == nil || *kind != acp.ToolKindExecute {
+		t.Errorf("tool kind = %v, want execute", kind)
+	}
+
+	// Allowing once approves the call, and the permission policy is not
+	// asked about it again.
+	client.option = optionAllowOnce
+	if res := sess.checkTool(bash); res != nil {
+		t.Fatalf("allow once blocked bash: %+v", res)
+	}
+	respCh := make(chan kit.PermissionResponse, 1)
+	sess.answerPermission(kit.PermissionRequestEvent{ToolCallID: "c1", ToolName: "bash", ResponseCh: respCh})
+	if resp := <-respCh; resp.Decision != kit.PermissionAllowOnce {
+		t.Errorf("policy decision = %v, want allow once", resp.Decision)
+	}
+	if len(client.asked) != 2 {
+		t.Errorf("asked %d times, want 2", len(client.asked))
+	}
+
+	// Always allowing stops further prompts for the tool.
+	client.option = optionAllowAlways
+	sess.checkTool(bash)
+	client.option = ""
+	if res := sess.checkTool(bash); res != nil {
+		t.Errorf("always-allowed bash was blocked: %+v", res)
+	}
+	if len(client.asked) != 3 {
+		t.Errorf("asked %d times, want 3", len(client.asked))
+	}
+	if !strings.Contains(client.asked[2].Options[1].Name, "bash") {
+		t.Errorf("allow-always option = %q", client.asked[2].Options[1].Name)
+	}
+}
@@ -0,0 +1,196 @@
+package acpserver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	acp "github.com/coder/acp-go-sdk"
+
+	kit "github.com/mark3labs/kit/pkg/kit"
+)
+
+// Session modes. Each maps onto a set of tools the agent may use freely.
+const (
+	// modeAsk runs read-only tools freely and asks the client before any
+	// other tool call. It is the default.
+	modeAsk = "ask"
+	// modeCode runs every tool without asking.
+	modeCode = "code"
+	// modeReadOnly only allows the read-only tools.
+	modeReadOnly = "read-only"
+)
+
+var sessionModes = []acp.SessionMode{
+	{Id: modeAsk, Name: "Ask", Description: ptr("Ask before editing files or running commands")},
+	{Id: modeCode, Name: "Code", Description: ptr("Edit files and run commands without asking")},
+	{Id: modeReadOnly, Name: "Read-only", Description: ptr("Only read and search; no edits or commands")},
+}
+
+// readOnlyTools are the tools that cannot change anything: the core
+// read-only set plus the code intelligence queries and job output.
+var readOnlyTools = sync.OnceValue(func() map[string]bool {
+	names := map[string]bool{
+		"bash_output":       true,
+		"definition":        true,
+		"references":        true,
+		"hover":             true,
+		"diagnostics":       true,
+		"workspace_symbols": true,
+	}
+	for _, t := range kit.ReadOnlyTools() {
+		names[t.Info().Name] = true
+	}
+	return names
+})
+
+func ptr[T any](v T) *T { return &v }
+
+// modeState returns the modes advertised for a session.
+func (s *acpSession) modeState() *acp.SessionModeState {
+	return &acp.SessionModeState{
+		AvailableModes: sessionModes,
+		CurrentModeId:  acp.SessionModeId(s.getMode()),
+	}
+}
+
+func (s *acpSession) getMode() string {
+	s.permMu.Lock()
+	defer s.permMu.Unlock()
+	if s.mode == "" {
+		return modeAsk
+	}
+	return s.mode
+}
+
+// setMode switches the session to mode, which must be one of sessionModes.
+func (s *acpSession) setMode(mode string) error {
+	for _, m := range sessionModes {
+		if string(m.Id) == mode {
+			s.permMu.Lock()
+			s.mode = mode
+			s.permMu.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown session mode %q", mode)
+}
+
+// checkTool is a BeforeToolCall hook enforcing the session mode. In ask mode
+// it asks the client before every tool call outside the read-only set.
+func (s *acpSession) checkTool(h kit.BeforeToolCallHook) *kit.BeforeToolCallResult {
+	if readOnlyTools()[h.ToolName] {
+		return nil
+	}
+	switch s.getMode() {
+	case modeCode:
+		return nil
+	case modeReadOnly:
+		return &kit.BeforeToolCallResult{
+			Block:  true,
+			Reason: fmt.Sprintf("the %s tool is not available in read-only mode", h.ToolName),
+		}
+	}
+
+	s.permMu.Lock()
+	always := s.alwaysAllowed[h.ToolName]
+	s.permMu.Unlock()
+	if always {
+		return nil
+	}
+
+	switch s.requestPermission(h.ToolCallID, h.ToolName, h.ToolArgs) {
+	case kit.PermissionAllowSession:
+		s.permMu.Lock()
+		s.alwaysAllowed[h.ToolName] = true
+		s.permMu.Unlock()
+	case kit.PermissionAllowOnce:
+	default:
+		return &kit.BeforeToolCallResult{Block: true, Reason: fmt.Sprintf("%s call rejected by user", h.ToolName)}
+	}
+	// The permission policy may ask about the same call again; the user
+	// has already approved it.
+	s.permMu.Lock()
+	s.approvedCalls[h.ToolCallID] = true
+	s.permMu.Unlock()
+	return nil
+}
+
+// answerPermission answers a PermissionRequestEvent raised by the configured
+// permission policy by asking the client.
+func (s *acpSession) answerPermission(e kit.PermissionRequestEvent) {
+	s.permMu.Lock()
+	approved := s.approvedCalls[e.ToolCallID]
+	delete(s.approvedCalls, e.ToolCallID)
+	s.permMu.Unlock()
+	if approved {
+		e.ResponseCh <- kit.PermissionResponse{Decision: kit.PermissionAllowOnce}
+		return
+	}
+	e.ResponseCh <- kit.PermissionResponse{Decision: s.requestPermission(e.ToolCallID, e.ToolName, e.ToolArgs)}
+}
+
+// Permission option IDs offered to the client.
+const (
+	optionAllowOnce   = "allow_once"
+	optionAllowAlways = "allow_always"
+	optionReject      = "reject_once"
+)
+
+// requestPermission asks the client whether a tool call may run. Errors and
+// cancelled prompts deny the call.
+func (s *acpSession) requestPermission(toolCallID, toolName, toolArgs string) kit.PermissionDecision {
+	if s.client == nil {
+		return kit.PermissionDenyOnce
+	}
+	title := toolName
+	kind := toolKind(toolName)
+	resp, err := s.client.RequestPermission(s.promptContext(), acp.RequestPermissionRequest{
+		SessionId: acp.SessionId(s.sessionID),
+		ToolCall: acp.ToolCallUpdate{
+			ToolCallId: acp.ToolCallId(toolCallID),
+			Title:      &title,
+			Kind:       &kind,
+			RawInput:   parseToolArgs(toolArgs),
+		},
+		Options: []acp.PermissionOption{
+			{OptionId: optionAllowOnce, Name: "Allow", Kind: acp.PermissionOptionKindAllowOnce},
+			{OptionId: optionAllowAlways, Name: fmt.Sprintf("Always allow %s", toolName), Kind: acp.PermissionOptionKindAllowAlways},
+			{OptionId: optionReject, Name: "Reject", Kind: acp.PermissionOptionKindRejectOnce},
+		},
+	})
+	if err != nil || resp.Outcome.Selected == nil {
+		return kit.PermissionDenyOnce
+	}
+	switch resp.Outcome.Selected.OptionId {
+	case optionAllowOnce:
+		return kit.PermissionAllowOnce
+	case optionAllowAlways:
+		return kit.PermissionAllowSession
+	default:
+		return kit.PermissionDenyOnce
+	}
+}
+
+// toolKind classifies a tool for the client's permission prompt.
+func toolKind(toolName string) acp.ToolKind {
+	switch toolName {
+	case "bash", "bash_kill":
+		return acp.ToolKindExecute
+	case "write", "edit", "apply_patch", "rename_symbol":
+		return acp.ToolKindEdit
+	default:
+		return acp.ToolKindOther
+	}
+}
+
+// promptContext returns the context of the running prompt so a pending
+// permission request is abandoned when the prompt is cancelled.
+func (s *acpSession) promptContext() context.Context {
+	s.cancelMu.Lock()
+	defer s.cancelMu.Unlock()
+	if s.promptCtx != nil {
+		return s.promptCtx
+	}
+	return context.Background()
+}
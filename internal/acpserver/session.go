@@ -7,10 +7,12 @@ import (
 	"sync"
 
 	"github.com/charmbracelet/log"
+	acp "github.com/coder/acp-go-sdk"
 	"github.com/spf13/viper"
 
 	"github.com/mark3labs/kit/internal/extbridge"
 	"github.com/mark3labs/kit/internal/extensions"
+	"github.com/mark3labs/kit/internal/session"
 	kit "github.com/mark3labs/kit/pkg/kit"
 )
 
@@ -18,15 +20,29 @@ import (
 type acpSession struct {
 	kit       *kit.Kit
 	cancelFn  context.CancelFunc // cancels the current prompt
+	promptCtx context.Context    // context of the current prompt
 	cancelMu  sync.Mutex
 	cwd       string
 	sessionID string // Kit-generated session ID (from JSONL header)
+
+	// client receives permission requests; nil when the client is unknown.
+	client clientConn
+
+	permMu        sync.Mutex
+	mode          string          // current session mode; empty means modeAsk
+	alwaysAllowed map[string]bool // tool names the user allowed for the session
+	approvedCalls map[string]bool // tool call IDs approved by checkTool
 }
 
 // sessionRegistry is a thread-safe registry of ACP session ID → Kit sessions.
 type sessionRegistry struct {
 	mu       sync.RWMutex
 	sessions map[string]*acpSession // ACP session ID → session
+
+	// client and caps describe the connected client. Sessions route file
+	// access and commands through it when it advertises the capability.
+	client clientConn
+	caps   acp.ClientCapabilities
 }
 
 func newSessionRegistry() *sessionRegistry {
@@ -35,26 +51,81 @@ func newSessionRegistry() *sessionRegistry {
 	}
 }
 
+// setClient records the connected client and its capabilities for sessions
+// opened from now on.
+func (r *sessionRegistry) setClient(client clientConn, caps acp.ClientCapabilities) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.client = client
+	r.caps = caps
+}
+
 // create creates a new Kit instance with a persisted tree session for the
 // given working directory. The Kit-generated session ID is used as the ACP
 // session ID so the mapping is 1:1.
 func (r *sessionRegistry) create(ctx context.Context, cwd string) (*acpSession, error) {
+	return r.open(ctx, cwd, "")
+}
+
+// load returns the session with the given ID, reopening its JSONL file from
+// the session directory for cwd when it is not already live. Sessions
+// created in another working directory are not found.
+func (r *sessionRegistry) load(ctx context.Context, cwd, sessionID string) (*acpSession, error) {
+	if sess, ok := r.get(sessionID); ok {
+		if sess.cwd != cwd {
+			return nil, fmt.Errorf("session %s belongs to %s, not %s", sessionID, sess.cwd, cwd)
+		}
+		return sess, nil
+	}
+	info, err := findSession(cwd, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return r.open(ctx, cwd, info.Path)
+}
+
+// open creates a Kit instance for cwd, persisting to a new tree session or,
+// when sessionPath is set, resuming the session stored there.
+func (r *sessionRegistry) open(ctx context.Context, cwd, sessionPath string) (*acpSession, error) {
 	// Each ACP session gets its own isolated config store (CLI is left nil) so
 	// per-session SetModel / SetThinkingLevel calls cannot race or bleed across
 	// the sessionRegistry. We seed the relevant root-command flag values from
 	// the process-global store (which cobra populated from flags) so launching
 	// `kit acp -m <model> [--thinking-level ...] [--provider-url ...]` is still
 	// honored; .kit.yml and KIT_* env vars are loaded per session by kit.New.
+	r.mu.RLock()
+	client, caps := r.client, r.caps
+	r.mu.RUnlock()
+
+	// The session ID is only known once the Kit exists; the client-backed
+	// file system and terminal read it from sess when called.
+	sess := &acpSession{
+		cwd:           cwd,
+		client:        client,
+		alwaysAllowed: make(map[string]bool),
+		approvedCalls: make(map[string]bool),
+	}
+
 	streamOn := true
-	kitInstance, err := kit.New(ctx, &kit.Options{
+	opts := &kit.Options{
 		SessionDir:     cwd,
+		SessionPath:    sessionPath,
 		Quiet:          true,
 		Streaming:      &streamOn,
 		Model:          viper.GetString("model"),
 		ThinkingLevel:  viper.GetString("thinking-level"),
 		ProviderURL:    viper.GetString("provider-url"),
 		ProviderAPIKey: viper.GetString("provider-api-key"),
-	})
+	}
+	if client != nil {
+		if caps.Fs.ReadTextFile && caps.Fs.WriteTextFile {
+			opts.FileSystem = &clientFS{conn: client, session: sess}
+		}
+		if caps.Terminal {
+			opts.BashExecutor = &clientTerminal{conn: client, session: sess}
+		}
+	}
+	kitInstance, err := kit.New(ctx, opts)
 	if err != nil {
 		// Provide actionable guidance for provider auth errors, which are
 		// the most common failure mode when running via ACP.
@@ -143,19 +214,36 @@ func (r *sessionRegistry) create(ctx context.Context, cwd string) (*acpSession,
 		kitInstance.Extensions().EmitSessionStart()
 	}
 
-	sess := &acpSession{
-		kit:       kitInstance,
-		cwd:       cwd,
-		sessionID: sessionID,
-	}
+	sess.kit = kitInstance
+	sess.sessionID = sessionID
+	kitInstance.OnBeforeToolCall(kit.HookPriorityLow, sess.checkTool)
+	kitInstance.OnPermissionRequest(sess.answerPermission)
 
 	r.mu.Lock()
+	defer r.mu.Unlock()
+	// A concurrent load of the same session may have won the race.
+	if existing, ok := r.sessions[sessionID]; ok {
+		_ = kitInstance.Close()
+		return existing, nil
+	}
 	r.sessions[sessionID] = sess
-	r.mu.Unlock()
-
 	return sess, nil
 }
 
+// findSession looks up a session saved for cwd by ID.
+func findSession(cwd, sessionID string) (session.SessionInfo, error) {
+	infos, err := session.ListSessions(cwd)
+	if err != nil {
+		return session.SessionInfo{}, fmt.Errorf("list sessions: %w", err)
+	}
+	for _, info := range infos {
+		if info.ID == sessionID {
+			return info, nil
+		}
+	}
+	return session.SessionInfo{}, fmt.Errorf("session not found: %s", sessionID)
+}
+
 // get retrieves a session by ACP session ID.
 func (r *sessionRegistry) get(sessionID string) (*acpSession, bool) {
 	r.mu.RLock()
@@ -200,10 +288,11 @@ func (s *acpSession) cancelPrompt() {
 	}
 }
 
-// setCancel stores a cancel function for the current prompt.
-func (s *acpSession) setCancel(cancel context.CancelFunc) {
+// setCancel stores the context of the current prompt and its cancel function.
+func (s *acpSession) setCancel(ctx context.Context, cancel context.CancelFunc) {
 	s.cancelMu.Lock()
 	defer s.cancelMu.Unlock()
+	s.promptCtx = ctx
 	s.cancelFn = cancel
 }
 
@@ -212,4 +301,10 @@ func (s *acpSession) clearCancel() {
 	s.cancelMu.Lock()
 	defer s.cancelMu.Unlock()
 	s.cancelFn = nil
+	s.promptCtx = nil
+
+	// Approvals the permission policy never asked about are stale now.
+	s.permMu.Lock()
+	clear(s.approvedCalls)
+	s.permMu.Unlock()
 }
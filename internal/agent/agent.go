@@ -14,6 +14,7 @@ import (
 
 	"github.com/mark3labs/kit/internal/config"
 	"github.com/mark3labs/kit/internal/core"
+	"github.com/mark3labs/kit/internal/lsp"
 	"github.com/mark3labs/kit/internal/message"
 	"github.com/mark3labs/kit/internal/models"
 	"github.com/mark3labs/kit/internal/tools"
@@ -72,6 +73,25 @@ type AgentConfig struct {
 	// core tools are built from CoreToolList.
 	BashMaxTimeout int
 
+	// BashExecutor runs bash tool commands, e.g. inside a sandbox. Nil runs
+	// them on the host. Only consumed when core tools are built from
+	// CoreToolList.
+	BashExecutor core.BashExecutor
+
+	// FileSystem is where read, write and edit access files, e.g. an
+	// editor's buffers. Nil uses the local disk. Only consumed when core
+	// tools are built from CoreToolList.
+	FileSystem core.FileSystem
+
+	// BashJobs tracks background bash jobs; nil disables run_in_background.
+	// Only consumed when core tools are built from CoreToolList.
+	BashJobs *core.JobManager
+
+	// LSP runs the language servers behind the code intelligence tools.
+	// When set, those tools are added alongside the listed core tools.
+	// Only consumed when core tools are built from CoreToolList.
+	LSP *lsp.Manager
+
 	// OnMCPServerLoaded, if non-nil, is called when each MCP server finishes
 	// loading (successfully or with error). The callback receives the server
 	// name, tool count, and any error. Called from the background goroutine.
@@ -81,6 +101,10 @@ type AgentConfig struct {
 	// zero value preserves historical synchronous-only behaviour for any
 	// server that didn't advertise task support during initialize.
 	MCPTaskConfig tools.MCPTaskConfig
+
+	// MCPClientHandlers answers sampling, elicitation and roots requests
+	// from MCP servers. The zero value advertises none of them.
+	MCPClientHandlers tools.MCPClientHandlers
 }
 
 // ToolCallHandler is a function type for handling tool calls as they happen.
@@ -267,6 +291,8 @@ type Agent struct {
 	// mcpTaskConfig is stored from AgentConfig so AddMCPServer() can
 	// propagate it to a lazily-created MCPToolManager.
 	mcpTaskConfig tools.MCPTaskConfig
+	// mcpClientHandlers is stored for the same reason.
+	mcpClientHandlers tools.MCPClientHandlers
 
 	// mcpReady is closed when background MCP tool loading completes (success
 	// or failure). nil when no MCP servers are configured.
@@ -341,7 +367,22 @@ func NewAgent(ctx context.Context, agentConfig *AgentConfig) (*Agent, error) {
 		if agentConfig.BashMaxTimeout > 0 {
 			toolOpts = append(toolOpts, core.WithBashMaxTimeout(time.Duration(agentConfig.BashMaxTimeout)*time.Second))
 		}
+		if agentConfig.BashExecutor != nil {
+			toolOpts = append(toolOpts, core.WithBashExecutor(agentConfig.BashExecutor))
+		}
+		if agentConfig.FileSystem != nil {
+			toolOpts = append(toolOpts, core.WithFileSystem(agentConfig.FileSystem))
+		}
+		if agentConfig.BashJobs != nil {
+			toolOpts = append(toolOpts, core.WithJobManager(agentConfig.BashJobs))
+		}
+		if agentConfig.LSP != nil {
+			toolOpts = append(toolOpts, core.WithLSP(agentConfig.LSP))
+		}
 		coreTools = core.ListedTools(agentConfig.CoreToolList, toolOpts...)
+		if agentConfig.LSP != nil {
+			coreTools = append(coreTools, core.LSPTools(toolOpts...)...)
+		}
 	}
 
 	// Build the initial tool list: core tools + extension tools (no MCP yet).
@@ -389,6 +430,7 @@ func NewAgent(ctx context.Context, agentConfig *AgentConfig) (*Agent, error) {
 		authHandler:         agentConfig.AuthHandler,
 		tokenStoreFactory:   agentConfig.TokenStoreFactory,
 		mcpTaskConfig:       agentConfig.MCPTaskConfig,
+		mcpClientHandlers:   agentConfig.MCPClientHandlers,
 	}
 
 	// Start MCP tool loading in the background if servers are configured.
@@ -410,6 +452,7 @@ func NewAgent(ctx context.Context, agentConfig *AgentConfig) (*Agent, error) {
 		}
 		// Apply task-augmented tool execution config (zero value = no-op).
 		toolManager.SetTaskConfig(agentConfig.MCPTaskConfig)
+		toolManager.SetClientHandlers(agentConfig.MCPClientHandlers)
 		a.toolManager = toolManager
 		a.mcpReady = make(chan struct{})
 
@@ -982,7 +1025,13 @@ func (a *Agent) GenerateWithCallbacks(ctx context.Context, messages []fantasy.Me
 				// Use the retry number from the error if available; Fantasy
 				// doesn't pass a counter directly, so we approximate with a
 				// counter incremented on each call.
-				cb.OnRetry(0, err)
+				// err is nil when the retried failure was not a provider
+				// error; pass a nil interface rather than a typed nil.
+				var retryErr error
+				if err != nil {
+					retryErr = err
+				}
+				cb.OnRetry(0, retryErr)
 			}
 		}
 
@@ -1269,6 +1318,7 @@ func (a *Agent) AddMCPServer(ctx context.Context, name string, cfg config.MCPSer
 			a.toolManager.SetTokenStoreFactory(a.tokenStoreFactory)
 		}
 		a.toolManager.SetTaskConfig(a.mcpTaskConfig)
+		a.toolManager.SetClientHandlers(a.mcpClientHandlers)
 		a.toolManager.SetOnToolsChanged(func() {
 			a.rebuildFantasyAgent()
 		})
@@ -8,6 +8,7 @@ import (
 
 	"github.com/mark3labs/kit/internal/config"
 	"github.com/mark3labs/kit/internal/core"
+	"github.com/mark3labs/kit/internal/lsp"
 	"github.com/mark3labs/kit/internal/models"
 	"github.com/mark3labs/kit/internal/tools"
 )
@@ -63,11 +64,24 @@ type AgentCreationOptions struct {
 	// BashMaxTimeout caps the maximum timeout (seconds) a bash tool call may
 	// request. Zero uses the built-in default (600s).
 	BashMaxTimeout int
+	// BashExecutor runs bash tool commands. Nil runs them on the host.
+	BashExecutor core.BashExecutor
+	// FileSystem is where read, write and edit access files. Nil uses the
+	// local disk.
+	FileSystem core.FileSystem
+	// BashJobs tracks background bash jobs. Nil disables them.
+	BashJobs *core.JobManager
+	// LSP runs the language servers behind the code intelligence tools.
+	// Nil disables them.
+	LSP *lsp.Manager
 	// OnMCPServerLoaded, if non-nil, is called when each MCP server finishes
 	// loading (successfully or with error). Called from the background goroutine.
 	OnMCPServerLoaded func(serverName string, toolCount int, err error)
 	// MCPTaskConfig configures task-augmented tools/call execution.
 	MCPTaskConfig tools.MCPTaskConfig
+	// MCPClientHandlers answers sampling, elicitation and roots requests
+	// from MCP servers.
+	MCPClientHandlers tools.MCPClientHandlers
 }
 
 // CreateAgent creates an agent with optional spinner for Ollama models.
@@ -90,8 +104,13 @@ func CreateAgent(ctx context.Context, opts *AgentCreationOptions) (*Agent, error
 		NamedAgents:       opts.NamedAgents,
 		BashTimeout:       opts.BashTimeout,
 		BashMaxTimeout:    opts.BashMaxTimeout,
+		BashExecutor:      opts.BashExecutor,
+		FileSystem:        opts.FileSystem,
+		BashJobs:          opts.BashJobs,
+		LSP:               opts.LSP,
 		OnMCPServerLoaded: opts.OnMCPServerLoaded,
 		MCPTaskConfig:     opts.MCPTaskConfig,
+		MCPClientHandlers: opts.MCPClientHandlers,
 	}
 
 	var agent *Agent
@@ -33,6 +33,10 @@ const (
 	SourceProject = "project"
 )
 
+// IsolationWorktree is the Isolation value that runs an agent in its own
+// git worktree.
+const IsolationWorktree = "worktree"
+
 // Agent is a named, reusable subagent preset.
 type Agent struct {
 	// Name identifies the agent. Derived from the definition filename
@@ -59,6 +63,11 @@ type Agent struct {
 	// subagent default.
 	Timeout time.Duration
 
+	// Isolation selects where the agent works. IsolationWorktree runs it in
+	// a temporary git worktree on its own branch so its edits never touch
+	// the parent's checkout; empty shares the parent's working directory.
+	Isolation string
+
 	// Hidden excludes the agent from the subagent tool description while
 	// keeping it resolvable by name.
 	Hidden bool
@@ -85,6 +94,7 @@ type frontmatter struct {
 	Tools       []string `yaml:"tools"`
 	Temperature *float32 `yaml:"temperature"`
 	Timeout     int      `yaml:"timeout"` // seconds
+	Isolation   string   `yaml:"isolation"`
 	Hidden      bool     `yaml:"hidden"`
 	Disabled    bool     `yaml:"disabled"`
 }
@@ -111,6 +121,11 @@ func Load(path string) (*Agent, error) {
 		return nil, fmt.Errorf("agent %s: description is required in frontmatter", name)
 	}
 
+	isolation := strings.TrimSpace(fm.Isolation)
+	if isolation != "" && isolation != IsolationWorktree {
+		return nil, fmt.Errorf("agent %s: unknown isolation %q (want %q)", name, isolation, IsolationWorktree)
+	}
+
 	var timeout time.Duration
 	if fm.Timeout > 0 {
 		timeout = time.Duration(fm.Timeout) * time.Second
@@ -123,6 +138,7 @@ func Load(path string) (*Agent, error) {
 		Tools:        fm.Tools,
 		Temperature:  fm.Temperature,
 		Timeout:      timeout,
+		Isolation:    isolation,
 		Hidden:       fm.Hidden,
 		Disabled:     fm.Disabled,
 		SystemPrompt: strings.TrimSpace(body),
@@ -202,17 +218,30 @@ func LoadAgents(cwd string) ([]*Agent, error) {
 	if cwd == "" {
 		cwd, _ = os.Getwd()
 	}
-
-	type scope struct {
-		dir    string
-		source string
-	}
-	scopes := []scope{
+	return loadAgents([]agentScope{
 		{filepath.Join(cwd, ".agents", "agents"), SourceProject},
 		{filepath.Join(cwd, ".kit", "agents"), SourceProject},
-	}
+	})
+}
+
+// LoadUserAgents is LoadAgents without the project scopes, for a checkout
+// whose agent definitions are not trusted.
+func LoadUserAgents() ([]*Agent, error) {
+	return loadAgents(nil)
+}
+
+// agentScope is one directory of agent definitions and the source recorded
+// on the agents loaded from it.
+type agentScope struct {
+	dir    string
+	source string
+}
+
+// loadAgents loads the given project scopes followed by the user scope and
+// the built-ins, highest precedence first.
+func loadAgents(scopes []agentScope) ([]*Agent, error) {
 	if dir := GlobalDir(); dir != "" {
-		scopes = append(scopes, scope{dir, SourceUser})
+		scopes = append(scopes, agentScope{dir, SourceUser})
 	}
 
 	var sets [][]*Agent
@@ -28,6 +28,7 @@ model: anthropic/claude-sonnet-4
 tools: [read, grep, find, ls]
 temperature: 0.1
 timeout: 300
+isolation: worktree
 hidden: true
 disabled: false
 ---
@@ -55,6 +56,9 @@ You are in code review mode. Focus on correctness.`)
 	if a.Timeout != 300*time.Second {
 		t.Errorf("Timeout = %v, want 5m", a.Timeout)
 	}
+	if a.Isolation != IsolationWorktree {
+		t.Errorf("Isolation = %q, want worktree", a.Isolation)
+	}
 	if !a.Hidden {
 		t.Error("Hidden should be true")
 	}
@@ -77,7 +81,7 @@ func TestLoad_MinimalFrontmatter(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Load failed: %v", err)
 	}
-	if a.Model != "" || a.Tools != nil || a.Temperature != nil || a.Timeout != 0 || a.Hidden || a.Disabled {
+	if a.Model != "" || a.Tools != nil || a.Temperature != nil || a.Timeout != 0 || a.Isolation != "" || a.Hidden || a.Disabled {
 		t.Errorf("optional fields should be zero-valued: %+v", a)
 	}
 }
@@ -93,6 +97,15 @@ func TestLoad_MissingDescription(t *testing.T) {
 	}
 }
 
+func TestLoad_UnknownIsolation(t *testing.T) {
+	dir := t.TempDir()
+	path := writeAgent(t, dir, "bad.md", "---\ndescription: Helps\nisolation: container\n---\nBody.")
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "unknown isolation") {
+		t.Errorf("expected unknown isolation error, got %v", err)
+	}
+}
+
 func TestLoad_NoFrontmatter(t *testing.T) {
 	dir := t.TempDir()
 	path := writeAgent(t, dir, "plain.md", "Just a body, no frontmatter.")
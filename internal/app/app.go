@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -551,6 +552,60 @@ func (a *App) PopLastUserMessage() (string, []kit.LLMFilePart, error) {
 	return prompt, files, nil
 }
 
+// RestoreCheckpoint rewinds the conversation and the working tree to the
+// moment before the user message entryID was sent (see
+// kit.Kit.RestoreCheckpoint), then syncs the in-memory message store with
+// the new branch position. Used by /rewind.
+//
+// Returns an error when the agent is working, the app has been closed, no
+// tree session is active, or the restore fails. A partial restore still
+// returns the result describing which files were written back.
+//
+// Satisfies ui.AppController.
+func (a *App) RestoreCheckpoint(entryID string) (*kit.CheckpointRestore, error) {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("app is closed")
+	}
+	if a.busy {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("cannot rewind while the agent is working")
+	}
+	a.mu.Unlock()
+
+	ts := a.opts.TreeSession
+	if ts == nil || a.opts.Kit == nil {
+		return nil, fmt.Errorf("no tree session active; /rewind requires a session")
+	}
+
+	result, err := a.opts.Kit.RestoreCheckpoint(entryID)
+
+	a.store.Clear()
+	a.store.Replace(ts.GetLLMMessages())
+	return result, err
+}
+
+// ListBackgroundJobs returns the background bash jobs started by the agent.
+//
+// Satisfies ui.AppController.
+func (a *App) ListBackgroundJobs() []kit.BackgroundJob {
+	if a.opts.Kit == nil {
+		return nil
+	}
+	return a.opts.Kit.ListBackgroundJobs()
+}
+
+// KillBackgroundJob stops the background bash job with the given ID.
+//
+// Satisfies ui.AppController.
+func (a *App) KillBackgroundJob(id string) (kit.BackgroundJob, error) {
+	if a.opts.Kit == nil {
+		return kit.BackgroundJob{}, fmt.Errorf("no background job %q", id)
+	}
+	return a.opts.Kit.KillBackgroundJob(id)
+}
+
 // AddContextMessage adds a user-role message to the conversation history
 // without triggering an LLM response. Used by the ! shell command prefix
 // to inject command output into context so the LLM can reference it in
@@ -1183,8 +1238,9 @@ func (a *App) sendEvent(e Event) {
 // SDK events to tea.Msg events and dispatch them via sendFn. When stepUsageSeen
 // is provided, it is set to true after any non-zero StepUsageEvent is observed.
 // canPrompt reports whether sendFn actually delivers events to an interactive
-// consumer that will answer request/response events (password prompts). When
-// false, such events are answered "cancelled" immediately instead of being
+// consumer that will answer request/response events (password, permission
+// and MCP elicitation prompts). When false, such events are answered
+// "cancelled" (or "denied"/"declined") immediately instead of being
 // dispatched — dispatching into a void would leave the SDK blocked forever.
 // Returns an unsubscribe function that removes all listeners.
 func (a *App) subscribeSDKEvents(sendFn func(Event), stepUsageSeen *atomic.Bool, canPrompt bool) func() {
@@ -1259,6 +1315,43 @@ func (a *App) subscribeSDKEvents(sendFn func(Event), stepUsageSeen *atomic.Bool,
 			case <-a.rootCtx.Done():
 				ev.ResponseCh <- kit.PasswordPromptResponse{Cancelled: true}
 			}
+		case kit.PermissionRequestEvent:
+			// Same hand-off as password prompts. Without an interactive
+			// consumer the call is denied rather than left waiting.
+			if !canPrompt {
+				ev.ResponseCh <- kit.PermissionResponse{Decision: kit.PermissionDenyOnce}
+				return
+			}
+			responseCh := make(chan PermissionPromptResponse, 1)
+			sendFn(PermissionPromptEvent{
+				ToolName:   ev.ToolName,
+				Subject:    ev.Subject,
+				Rule:       ev.Rule,
+				ResponseCh: responseCh,
+			})
+			select {
+			case resp := <-responseCh:
+				ev.ResponseCh <- kit.PermissionResponse{Decision: resp.Decision}
+			case <-a.rootCtx.Done():
+				ev.ResponseCh <- kit.PermissionResponse{Decision: kit.PermissionDenyOnce}
+			}
+		case kit.MCPElicitationEvent:
+			// MCP servers asking for input get a form; without an
+			// interactive consumer the request is declined.
+			if !canPrompt {
+				ev.ResponseCh <- kit.MCPElicitationResponse{Action: kit.ElicitationDecline}
+				return
+			}
+			ev.ResponseCh <- a.elicit(ev, sendFn)
+		case kit.BudgetWarningEvent:
+			sendFn(budgetWarningMessage(ev))
+		case kit.SkillScopeEvent:
+			sendFn(SkillScopeChangedEvent{Skill: ev.Skill, Active: ev.Active})
+		case kit.ModelFailoverEvent:
+			if provider, model, err := kit.ParseModelString(ev.To); err == nil {
+				sendFn(ModelChangedEvent{ProviderName: provider, ModelName: model})
+			}
+			sendFn(modelFailoverMessage(ev))
 		case kit.TurnEndEvent:
 			a.handleTurnEnd(ev, sendFn)
 		}
@@ -1329,6 +1422,31 @@ func (a *App) formatMaxTokensTruncatedMessage() string {
 	return msg
 }
 
+// budgetWarningMessage tells the user a spend limit is close or reached.
+func budgetWarningMessage(ev kit.BudgetWarningEvent) ExtensionPrintEvent {
+	if ev.Exceeded {
+		return ExtensionPrintEvent{
+			Level: "error",
+			Text: fmt.Sprintf("Cost budget exceeded: $%.2f spent of the $%.2f %s limit. The agent has stopped.",
+				ev.SpentUSD, ev.LimitUSD, ev.Scope),
+		}
+	}
+	return ExtensionPrintEvent{
+		Level: "info",
+		Text: fmt.Sprintf("⚠ $%.2f spent of the $%.2f %s cost limit. The agent stops once it is reached.",
+			ev.SpentUSD, ev.LimitUSD, ev.Scope),
+	}
+}
+
+// modelFailoverMessage tells the user the turn moved to a fallback model.
+func modelFailoverMessage(ev kit.ModelFailoverEvent) ExtensionPrintEvent {
+	reason := strings.ReplaceAll(ev.Reason, "_", " ")
+	return ExtensionPrintEvent{
+		Level: "info",
+		Text:  fmt.Sprintf("⚠ %s failed (%s). Switched to %s to continue.", ev.From, reason, ev.To),
+	}
+}
+
 // QuitFromExtension triggers a graceful shutdown. In interactive mode it
 // sends a tea.QuitMsg to the program so the TUI exits cleanly. In
 // non-interactive mode it cancels the root context, stopping any in-flight
@@ -1469,3 +1469,95 @@ func TestBusyTransitionsSignalIdleCh(t *testing.T) {
 		t.Fatal("idleCh was never closed after drain completed")
 	}
 }
+
+// answerPrompts returns a sendFn that answers each PromptRequestEvent with
+// the next response in order and records the requests.
+func answerPrompts(responses ...PromptResponse) (func(Event), *[]PromptRequestEvent) {
+	var mu sync.Mutex
+	var requests []PromptRequestEvent
+	return func(e Event) {
+		req, ok := e.(PromptRequestEvent)
+		if !ok {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, req)
+		resp := PromptResponse{Cancelled: true}
+		if len(responses) > 0 {
+			resp, responses = responses[0], responses[1:]
+		}
+		req.ResponseCh <- resp
+	}, &requests
+}
+
+// TestElicit_form verifies that each elicitation field is prompted for with
+// a matching prompt type and that answers are converted to the field types.
+func TestElicit_form(t *testing.T) {
+	app := New(Options{}, nil)
+	defer app.Close()
+
+	sendFn, requests := answerPrompts(
+		PromptResponse{Confirmed: true},          // answer the request
+		PromptResponse{Value: "Ada"},             // name
+		PromptResponse{Value: "many"},            // age: invalid, asked again
+		PromptResponse{Value: "36"},              // age
+		PromptResponse{Index: 1, Value: "Green"}, // color
+		PromptResponse{Confirmed: true},          // subscribe
+		PromptResponse{Value: ""},                // nickname: optional, skipped
+	)
+	resp := app.elicit(kit.MCPElicitationEvent{
+		ServerName: "crm",
+		Message:    "Tell me about you",
+		Fields: []kit.ElicitationField{
+			{Name: "name", Type: "string", Required: true},
+			{Name: "age", Type: "integer"},
+			{Name: "color", Type: "string", Enum: []string{"r", "g"}, EnumNames: []string{"Red", "Green"}},
+			{Name: "subscribe", Type: "boolean"},
+			{Name: "nickname", Type: "string"},
+		},
+	}, sendFn)
+
+	if resp.Action != kit.ElicitationAccept {
+		t.Fatalf("action = %q, want accept", resp.Action)
+	}
+	want := map[string]any{"name": "Ada", "age": int64(36), "color": "g", "subscribe": true}
+	if len(resp.Content) != len(want) {
+		t.Fatalf("content = %v, want %v", resp.Content, want)
+	}
+	for k, v := range want {
+		if resp.Content[k] != v {
+			t.Errorf("content[%s] = %#v, want %#v", k, resp.Content[k], v)
+		}
+	}
+	types := []string{"confirm", "input", "input", "input", "select", "confirm", "input"}
+	if len(*requests) != len(types) {
+		t.Fatalf("got %d prompts, want %d", len(*requests), len(types))
+	}
+	for i, req := range *requests {
+		if req.PromptType != types[i] {
+			t.Errorf("prompt %d type = %q, want %q", i, req.PromptType, types[i])
+		}
+	}
+	if !strings.Contains((*requests)[0].Message, `"crm"`) {
+		t.Errorf("first prompt should name the server: %q", (*requests)[0].Message)
+	}
+}
+
+// TestElicit_declineAndCancel verifies that refusing the request declines it
+// and dismissing a field prompt cancels it.
+func TestElicit_declineAndCancel(t *testing.T) {
+	app := New(Options{}, nil)
+	defer app.Close()
+	ev := kit.MCPElicitationEvent{ServerName: "crm", Fields: []kit.ElicitationField{{Name: "name", Type: "string"}}}
+
+	sendFn, _ := answerPrompts(PromptResponse{Confirmed: false})
+	if resp := app.elicit(ev, sendFn); resp.Action != kit.ElicitationDecline {
+		t.Errorf("refused request: action = %q, want decline", resp.Action)
+	}
+
+	sendFn, _ = answerPrompts(PromptResponse{Confirmed: true}, PromptResponse{Cancelled: true})
+	if resp := app.elicit(ev, sendFn); resp.Action != kit.ElicitationCancel {
+		t.Errorf("dismissed field: action = %q, want cancel", resp.Action)
+	}
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	kit "github.com/mark3labs/kit/pkg/kit"
+)
+
+// elicit answers an MCP elicitation by walking the user through its fields
+// with the extension prompt dialogs: first a confirm naming the server and
+// its message, then one prompt per field. Booleans become confirm prompts,
+// enums select prompts and everything else text input. Dismissing any
+// prompt cancels the whole request; answering "no" to the first declines.
+//
+// Separated from subscribeSDKEvents so tests can drive it with a stubbed
+// sendFn.
+func (a *App) elicit(ev kit.MCPElicitationEvent, sendFn func(Event)) kit.MCPElicitationResponse {
+	intro := fmt.Sprintf("MCP server %q asks: %s", ev.ServerName, ev.Message)
+	resp, ok := a.ask(sendFn, PromptRequestEvent{
+		PromptType: "confirm",
+		Message:    intro + "\n\nAnswer this request?",
+		Default:    "true",
+	})
+	if !ok {
+		return kit.MCPElicitationResponse{Action: kit.ElicitationCancel}
+	}
+	if !resp.Confirmed {
+		return kit.MCPElicitationResponse{Action: kit.ElicitationDecline}
+	}
+
+	content := make(map[string]any, len(ev.Fields))
+	for _, f := range ev.Fields {
+		value, ok := a.askField(sendFn, f)
+		if !ok {
+			return kit.MCPElicitationResponse{Action: kit.ElicitationCancel}
+		}
+		if value != nil {
+			content[f.Name] = value
+		}
+	}
+	return kit.MCPElicitationResponse{Action: kit.ElicitationAccept, Content: content}
+}
+
+// askField prompts for one elicitation field until it gets a valid value.
+// It returns a nil value for a skipped optional field and false when the
+// user dismissed the prompt.
+func (a *App) askField(sendFn func(Event), f kit.ElicitationField) (any, bool) {
+	label := f.Title
+	if label == "" {
+		label = f.Name
+	}
+	if f.Description != "" {
+		label += "\n" + f.Description
+	}
+
+	switch {
+	case f.Type == "boolean":
+		def, _ := f.Default.(bool)
+		resp, ok := a.ask(sendFn, PromptRequestEvent{
+			PromptType: "confirm",
+			Message:    label,
+			Default:    strconv.FormatBool(def),
+		})
+		return resp.Confirmed, ok
+
+	case len(f.Enum) > 0:
+		options := f.Enum
+		if len(f.EnumNames) == len(f.Enum) {
+			options = f.EnumNames
+		}
+		resp, ok := a.ask(sendFn, PromptRequestEvent{
+			PromptType: "select",
+			Message:    label,
+			Options:    options,
+		})
+		if !ok || resp.Index < 0 || resp.Index >= len(f.Enum) {
+			return nil, false
+		}
+		return f.Enum[resp.Index], true
+	}
+
+	placeholder := "optional"
+	if f.Required {
+		placeholder = "required"
+	}
+	def := ""
+	if f.Default != nil {
+		def = fmt.Sprint(f.Default)
+	}
+	message := label
+	for {
+		resp, ok := a.ask(sendFn, PromptRequestEvent{
+			PromptType:  "input",
+			Message:     message,
+			Default:     def,
+			Placeholder: placeholder,
+		})
+		if !ok {
+			return nil, false
+		}
+		text := strings.TrimSpace(resp.Value)
+		if text == "" {
+			if !f.Required {
+				return nil, true
+			}
+			message = label + "\n(required)"
+			continue
+		}
+		value, err := parseFieldValue(f.Type, text)
+		if err == nil {
+			return value, true
+		}
+		message = fmt.Sprintf("%s\n(%v)", label, err)
+		def = text
+	}
+}
+
+// parseFieldValue converts text entered for a field to the field's type.
+func parseFieldValue(typ, text string) (any, error) {
+	switch typ {
+	case "integer":
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("enter a whole number")
+		}
+		return n, nil
+	case "number":
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("enter a number")
+		}
+		return n, nil
+	default:
+		return text, nil
+	}
+}
+
+// ask sends a prompt and waits for the answer. It returns false when the
+// prompt was dismissed or the app is shutting down.
+func (a *App) ask(sendFn func(Event), req PromptRequestEvent) (PromptResponse, bool) {
+	responseCh := make(chan PromptResponse, 1)
+	req.ResponseCh = responseCh
+	sendFn(req)
+	select {
+	case resp := <-responseCh:
+		return resp, !resp.Cancelled
+	case <-a.rootCtx.Done():
+		return PromptResponse{Cancelled: true}, false
+	}
+}
@@ -20,7 +20,7 @@ import kit "github.com/mark3labs/kit/pkg/kit"
 // adapts Event at the boundary; the event-producing code stays TUI-agnostic.
 //
 // Note that some events still carry a response channel (PasswordPromptEvent,
-// PromptRequestEvent, OverlayRequestEvent, NewSessionRequestEvent). Those are
+// PermissionPromptEvent, PromptRequestEvent, OverlayRequestEvent, NewSessionRequestEvent). Those are
 // not yet serialisable across a process boundary; converting them to a
 // request-id correlation scheme is deferred until an actual out-of-process
 // transport exists.
@@ -155,6 +155,27 @@ type PasswordPromptResponse struct {
 	Cancelled bool
 }
 
+// PermissionPromptEvent is sent when the permission policy requires approval
+// before a tool call runs. The TUI should ask the user and send the decision
+// back.
+type PermissionPromptEvent struct {
+	// ToolName is the tool awaiting approval.
+	ToolName string
+	// Subject is the command or path the policy matched; empty for tools
+	// without one.
+	Subject string
+	// Rule is the rule granted by the "always allow" choices.
+	Rule string
+	// ResponseCh receives the decision. The TUI must send exactly one value.
+	ResponseCh chan<- PermissionPromptResponse
+}
+
+// PermissionPromptResponse carries the user's approval decision. The zero
+// value denies the call.
+type PermissionPromptResponse struct {
+	Decision kit.PermissionDecision
+}
+
 // ResponseCompleteEvent is sent when the LLM produces a final (non-streaming) response.
 // In streaming mode, this may be empty if all content was delivered via StreamChunkEvents.
 type ResponseCompleteEvent struct {
@@ -229,7 +250,8 @@ type CompactErrorEvent struct {
 type SteerConsumedEvent struct{}
 
 // ModelChangedEvent is sent when an extension changes the active model via
-// ctx.SetModel. The TUI updates the model name shown in the status bar and
+// ctx.SetModel, or a provider error makes Kit fail over to a fallback
+// model. The TUI updates the model name shown in the status bar and
 // message attribution.
 type ModelChangedEvent struct {
 	// ProviderName is the new provider (e.g. "anthropic").
@@ -238,6 +260,16 @@ type ModelChangedEvent struct {
 	ModelName string
 }
 
+// SkillScopeChangedEvent is sent when an activated skill starts or stops
+// restricting the tools the agent may call. The TUI lists the restricting
+// skills in the status bar.
+type SkillScopeChangedEvent struct {
+	// Skill is the name of the skill.
+	Skill string
+	// Active is true when the restriction starts and false when it ends.
+	Active bool
+}
+
 // UsageUpdatedEvent is sent after each completed LLM step to notify the TUI
 // that token counts and costs have changed. The UsageTracker is updated
 // in-place before this event is sent; the TUI just needs to re-render to
@@ -431,6 +463,7 @@ func (ToolResultEvent) isAppEvent()         {}
 func (ToolOutputEvent) isAppEvent()         {}
 func (ToolCallContentEvent) isAppEvent()    {}
 func (PasswordPromptEvent) isAppEvent()     {}
+func (PermissionPromptEvent) isAppEvent()   {}
 func (ResponseCompleteEvent) isAppEvent()   {}
 func (StepCompleteEvent) isAppEvent()       {}
 func (StepErrorEvent) isAppEvent()          {}
@@ -442,6 +475,7 @@ func (CompactCompleteEvent) isAppEvent()    {}
 func (CompactErrorEvent) isAppEvent()       {}
 func (SteerConsumedEvent) isAppEvent()      {}
 func (ModelChangedEvent) isAppEvent()       {}
+func (SkillScopeChangedEvent) isAppEvent()  {}
 func (UsageUpdatedEvent) isAppEvent()       {}
 func (WidgetUpdateEvent) isAppEvent()       {}
 func (ThemeChangedEvent) isAppEvent()       {}
@@ -66,6 +66,53 @@ func (a *App) ListAllSessions() ([]SessionSummary, error) {
 	return sessionSummaries(infos), nil
 }
 
+// SessionSearchMatch is an entry of a session that matched a search.
+type SessionSearchMatch struct {
+	// EntryID is the tree entry to jump to, empty when the session name
+	// matched.
+	EntryID string
+	// Kind is the message role ("user" or "assistant"), or "label", "name"
+	// or "files".
+	Kind string
+	// Snippet is the matching text, on one line.
+	Snippet string
+	// Highlights are the [start, end) byte ranges of Snippet that match the
+	// query.
+	Highlights [][2]int
+}
+
+// SessionSearchResult is a session with the entries that matched a search.
+type SessionSearchResult struct {
+	Session SessionSummary
+	Matches []SessionSearchMatch
+}
+
+// SearchSessions runs query against the full-text index of every session on
+// disk and returns the matching sessions, newest first.
+func (a *App) SearchSessions(query string) ([]SessionSearchResult, error) {
+	results, err := session.SearchSessions(query)
+	if err != nil {
+		return nil, fmt.Errorf("search sessions: %w", err)
+	}
+	out := make([]SessionSearchResult, 0, len(results))
+	for _, r := range results {
+		matches := make([]SessionSearchMatch, 0, len(r.Matches))
+		for _, m := range r.Matches {
+			matches = append(matches, SessionSearchMatch{
+				EntryID:    m.EntryID,
+				Kind:       m.Kind,
+				Snippet:    m.Snippet,
+				Highlights: m.Highlights,
+			})
+		}
+		out = append(out, SessionSearchResult{
+			Session: sessionSummaries([]session.SessionInfo{r.Session})[0],
+			Matches: matches,
+		})
+	}
+	return out, nil
+}
+
 // DeleteSession removes a session file from disk. Deleting the file backing
 // the active session is permitted: the session keeps running in memory and
 // simply stops being discoverable.
@@ -129,6 +129,31 @@ func TestListSessionsNoSessionsYet(t *testing.T) {
 	}
 }
 
+// --------------------------------------------------------------------------
+// Searching
+// --------------------------------------------------------------------------
+
+func TestSearchSessionsProjectsResults(t *testing.T) {
+	a, tm := newPersistedApp(t)
+	appendUserMessage(t, tm, "add a rate limiter")
+	appendUserMessage(t, tm, "now fix the parser")
+
+	got, err := a.SearchSessions("parser")
+	if err != nil {
+		t.Fatalf("SearchSessions: %v", err)
+	}
+	if len(got) != 1 || got[0].Session.Path != tm.GetFilePath() || got[0].Session.MessageCount != 2 {
+		t.Fatalf("got %+v, want the one session", got)
+	}
+	m := got[0].Matches
+	if len(m) != 1 || m[0].Kind != "user" || m[0].EntryID != tm.GetLeafID() || m[0].Snippet != "now fix the parser" {
+		t.Errorf("matches = %+v", m)
+	}
+	if len(m) == 1 && (len(m[0].Highlights) != 1 || m[0].Highlights[0] != [2]int{12, 18}) {
+		t.Errorf("highlights = %v, want the word parser", m[0].Highlights)
+	}
+}
+
 // --------------------------------------------------------------------------
 // Deleting
 // --------------------------------------------------------------------------
@@ -25,6 +25,8 @@ import (
 	"strings"
 
 	"charm.land/fantasy"
+
+	"github.com/mark3labs/kit/internal/core"
 )
 
 // ---------------------------------------------------------------------------
@@ -390,7 +392,8 @@ func newFileOps() *fileOps {
 }
 
 // extractFileOps scans messages for tool calls and extracts file paths.
-// It recognises the built-in Kit tools: read, write, edit, bash, grep, find, ls.
+// It recognises the built-in Kit tools: read, write, edit, apply_patch, bash,
+// grep, find, ls.
 func extractFileOps(messages []fantasy.Message) *fileOps {
 	ops := newFileOps()
 	for _, msg := range messages {
@@ -406,6 +409,17 @@ func extractFileOps(messages []fantasy.Message) *fileOps {
 				continue
 			}
 
+			if tc.ToolName == "apply_patch" {
+				patch, _ := args["patch"].(string)
+				files, _ := core.ParsePatch(patch)
+				for _, f := range files {
+					for _, p := range f.Paths() {
+						ops.ModifiedFiles[p] = true
+					}
+				}
+				continue
+			}
+
 			path, _ := args["path"].(string)
 			if path == "" {
 				continue
@@ -533,6 +533,7 @@ func TestExtractFileOps(t *testing.T) {
 				fantasy.ToolCallPart{ToolCallID: "2", ToolName: "write", Input: `{"path":"src/out.go"}`},
 				fantasy.ToolCallPart{ToolCallID: "3", ToolName: "edit", Input: `{"path":"src/edit.go"}`},
 				fantasy.ToolCallPart{ToolCallID: "4", ToolName: "grep", Input: `{"path":"src/search"}`},
+				fantasy.ToolCallPart{ToolCallID: "5", ToolName: "apply_patch", Input: `{"patch":"*** Begin Patch\n*** Delete File: src/old.go\n*** End Patch"}`},
 			},
 		},
 	}
@@ -550,6 +551,9 @@ func TestExtractFileOps(t *testing.T) {
 	if !ops.ModifiedFiles["src/edit.go"] {
 		t.Error("edit file not tracked: src/edit.go")
 	}
+	if !ops.ModifiedFiles["src/old.go"] {
+		t.Error("apply_patch file not tracked: src/old.go")
+	}
 }
 
 func TestFileOps_MergeSlices(t *testing.T) {
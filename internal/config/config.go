@@ -8,6 +8,10 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/mark3labs/kit/internal/core"
+	"github.com/mark3labs/kit/internal/lsp"
+	"github.com/mark3labs/kit/internal/permission"
+	"github.com/mark3labs/kit/internal/usage"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
@@ -56,6 +60,11 @@ type MCPServerConfig struct {
 	// tasks/get / tasks/result until the task reaches a terminal state.
 	TasksMode string `json:"tasksMode,omitempty" yaml:"tasksMode,omitempty"`
 
+	// Sampling allows this server to request completions from Kit's current
+	// model via sampling/createMessage. Servers without it (or with
+	// Enabled false) have sampling requests refused.
+	Sampling *MCPSamplingConfig `json:"sampling,omitempty" yaml:"sampling,omitempty"`
+
 	// InProcessServer holds a live *server.MCPServer for in-process transport.
 	// When set (and Type is "inprocess"), the connection pool creates an
 	// in-process client instead of spawning a subprocess or making HTTP calls.
@@ -69,37 +78,50 @@ type MCPServerConfig struct {
 	Headers   []string       `json:"headers,omitempty"`
 }
 
+// MCPSamplingConfig controls whether an MCP server may sample Kit's model.
+type MCPSamplingConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// MaxTokens caps the output tokens of a single request. Requests asking
+	// for more (or not saying) are clamped. Zero leaves the request as is.
+	MaxTokens int `json:"maxTokens,omitempty" yaml:"maxTokens,omitempty"`
+	// Budget is the total number of tokens (input and output) the server
+	// may spend over the life of a Kit instance. Zero means unlimited.
+	Budget int `json:"budget,omitempty" yaml:"budget,omitempty"`
+}
+
 // UnmarshalJSON handles both new and legacy config formats for backward compatibility.
 // New format uses "type" field with "local", "remote", or "builtin" values.
 // Legacy format uses "transport", "command", "args", and "env" fields.
 func (s *MCPServerConfig) UnmarshalJSON(data []byte) error {
 	// First try to unmarshal as the new format
 	type newFormat struct {
-		Type              string            `json:"type"`
-		Command           []string          `json:"command,omitempty"`
-		Environment       map[string]string `json:"environment,omitempty"`
-		URL               string            `json:"url,omitempty"`
-		Headers           []string          `json:"headers,omitempty"`
-		AllowedTools      []string          `json:"allowedTools,omitempty" yaml:"allowedTools,omitempty"`
-		ExcludedTools     []string          `json:"excludedTools,omitempty" yaml:"excludedTools,omitempty"`
-		OAuthClientID     string            `json:"oauthClientId,omitempty" yaml:"oauthClientId,omitempty"`
-		OAuthClientSecret string            `json:"oauthClientSecret,omitempty" yaml:"oauthClientSecret,omitempty"`
-		OAuthScopes       []string          `json:"oauthScopes,omitempty" yaml:"oauthScopes,omitempty"`
-		NoOAuth           bool              `json:"noOAuth,omitempty" yaml:"noOAuth,omitempty"`
-		TasksMode         string            `json:"tasksMode,omitempty" yaml:"tasksMode,omitempty"`
+		Type              string             `json:"type"`
+		Command           []string           `json:"command,omitempty"`
+		Environment       map[string]string  `json:"environment,omitempty"`
+		URL               string             `json:"url,omitempty"`
+		Headers           []string           `json:"headers,omitempty"`
+		AllowedTools      []string           `json:"allowedTools,omitempty" yaml:"allowedTools,omitempty"`
+		ExcludedTools     []string           `json:"excludedTools,omitempty" yaml:"excludedTools,omitempty"`
+		OAuthClientID     string             `json:"oauthClientId,omitempty" yaml:"oauthClientId,omitempty"`
+		OAuthClientSecret string             `json:"oauthClientSecret,omitempty" yaml:"oauthClientSecret,omitempty"`
+		OAuthScopes       []string           `json:"oauthScopes,omitempty" yaml:"oauthScopes,omitempty"`
+		NoOAuth           bool               `json:"noOAuth,omitempty" yaml:"noOAuth,omitempty"`
+		TasksMode         string             `json:"tasksMode,omitempty" yaml:"tasksMode,omitempty"`
+		Sampling          *MCPSamplingConfig `json:"sampling,omitempty" yaml:"sampling,omitempty"`
 	}
 
 	// Also try legacy format
 	type legacyFormat struct {
-		Transport     string         `json:"transport,omitempty"`
-		Command       string         `json:"command,omitempty"`
-		Args          []string       `json:"args,omitempty"`
-		Env           map[string]any `json:"env,omitempty"`
-		URL           string         `json:"url,omitempty"`
-		Headers       []string       `json:"headers,omitempty"`
-		AllowedTools  []string       `json:"allowedTools,omitempty" yaml:"allowedTools,omitempty"`
-		ExcludedTools []string       `json:"excludedTools,omitempty" yaml:"excludedTools,omitempty"`
-		TasksMode     string         `json:"tasksMode,omitempty" yaml:"tasksMode,omitempty"`
+		Transport     string             `json:"transport,omitempty"`
+		Command       string             `json:"command,omitempty"`
+		Args          []string           `json:"args,omitempty"`
+		Env           map[string]any     `json:"env,omitempty"`
+		URL           string             `json:"url,omitempty"`
+		Headers       []string           `json:"headers,omitempty"`
+		AllowedTools  []string           `json:"allowedTools,omitempty" yaml:"allowedTools,omitempty"`
+		ExcludedTools []string           `json:"excludedTools,omitempty" yaml:"excludedTools,omitempty"`
+		TasksMode     string             `json:"tasksMode,omitempty" yaml:"tasksMode,omitempty"`
+		Sampling      *MCPSamplingConfig `json:"sampling,omitempty" yaml:"sampling,omitempty"`
 	}
 
 	// Try new format first
@@ -117,6 +139,7 @@ func (s *MCPServerConfig) UnmarshalJSON(data []byte) error {
 		s.OAuthScopes = newConfig.OAuthScopes
 		s.NoOAuth = newConfig.NoOAuth
 		s.TasksMode = newConfig.TasksMode
+		s.Sampling = newConfig.Sampling
 		return nil
 	}
 
@@ -138,6 +161,7 @@ func (s *MCPServerConfig) UnmarshalJSON(data []byte) error {
 	s.AllowedTools = legacyConfig.AllowedTools
 	s.ExcludedTools = legacyConfig.ExcludedTools
 	s.TasksMode = legacyConfig.TasksMode
+	s.Sampling = legacyConfig.Sampling
 
 	// Infer type from legacy format for better compatibility
 	// Only set Type when it doesn't change existing transport behavior
@@ -268,6 +292,7 @@ type LimitConfig struct {
 type Config struct {
 	MCPServers     map[string]MCPServerConfig `json:"mcpServers" yaml:"mcpServers"`
 	Model          string                     `json:"model,omitempty" yaml:"model,omitempty"`
+	FallbackModels []string                   `json:"fallback-models,omitempty" yaml:"fallback-models,omitempty"`
 	MaxSteps       int                        `json:"max-steps,omitempty" yaml:"max-steps,omitempty"`
 	Debug          bool                       `json:"debug,omitempty" yaml:"debug,omitempty"`
 	SystemPrompt   string                     `json:"system-prompt,omitempty" yaml:"system-prompt,omitempty"`
@@ -305,6 +330,37 @@ type Config struct {
 	BashTimeout    int `json:"bash-timeout,omitempty" yaml:"bash-timeout,omitempty"`
 	BashMaxTimeout int `json:"bash-max-timeout,omitempty" yaml:"bash-max-timeout,omitempty"`
 
+	// File checkpoints. NoCheckpoints stops recording file state before
+	// write/edit calls; GitCheckpoints also snapshots the tracked git tree
+	// so bash changes can be rewound.
+	NoCheckpoints  bool `json:"no-checkpoints,omitempty" yaml:"no-checkpoints,omitempty"`
+	GitCheckpoints bool `json:"git-checkpoints,omitempty" yaml:"git-checkpoints,omitempty"`
+
+	// Tool-call approval policy: allow/ask/deny rules evaluated before each
+	// tool execution. Empty means every call is allowed.
+	Permissions permission.Config `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+
+	// Bash execution backend. The zero value runs commands on the host;
+	// "bwrap", "docker" or "podman" confine them to a sandbox.
+	Sandbox core.SandboxConfig `json:"sandbox,omitempty" yaml:"sandbox,omitempty"`
+
+	// Language servers backing the code intelligence tools, keyed by name.
+	// Built-in presets (gopls, pyright, ...) need no further settings.
+	LSP lsp.Config `json:"lsp,omitempty" yaml:"lsp,omitempty"`
+
+	// Extra directories advertised to MCP servers as roots, alongside the
+	// working directory. Relative paths resolve against the working directory.
+	MCPRoots []string `json:"mcpRoots,omitempty" yaml:"mcpRoots,omitempty"`
+
+	// Spend limits for the project, checked against the usage ledger. Once
+	// a limit is reached the agent stops; MaxCost caps a single run.
+	Budget  usage.Budget `json:"budget,omitempty" yaml:"budget,omitempty"`
+	MaxCost float64      `json:"max-cost,omitempty" yaml:"max-cost,omitempty"`
+
+	// MaxParallelSubagents bounds how many subagents run at once; the rest
+	// queue. Zero means the default of 4.
+	MaxParallelSubagents int `json:"max-parallel-subagents,omitempty" yaml:"max-parallel-subagents,omitempty"`
+
 	// Per-model generation parameter overrides. Keys are "provider/model" strings
 	// (e.g. "anthropic/claude-sonnet-4-5-20250929", "openai/gpt-4o"). These
 	// settings act as model-level defaults — CLI flags and global config values
@@ -370,6 +426,10 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("server %s: invalid tasksMode %q (expected one of: auto, never, always)", serverName, serverConfig.TasksMode)
 		}
 
+		if sc := serverConfig.Sampling; sc != nil && (sc.MaxTokens < 0 || sc.Budget < 0) {
+			return fmt.Errorf("server %s: sampling maxTokens and budget must not be negative", serverName)
+		}
+
 		transport := serverConfig.GetTransportType()
 		switch transport {
 		case "stdio":
@@ -389,6 +449,19 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("server %s: unsupported transport type '%s'. Supported types: stdio, sse, streamable, inprocess", serverName, transport)
 		}
 	}
+
+	if _, err := permission.New(c.Permissions); err != nil {
+		return fmt.Errorf("permissions: %w", err)
+	}
+	if err := c.Sandbox.Validate(); err != nil {
+		return fmt.Errorf("sandbox: %w", err)
+	}
+	if err := c.LSP.Validate(); err != nil {
+		return fmt.Errorf("lsp: %w", err)
+	}
+	if err := c.Budget.Validate(); err != nil {
+		return fmt.Errorf("budget: %w", err)
+	}
 	return nil
 }
 
@@ -535,6 +608,52 @@ mcpServers:
                                            # include-/exclude-core-tools are mutually exclusive
                                            # no-core-tools has precedence
 
+# File checkpoints (used by /rewind to restore files along with the conversation)
+# no-checkpoints: false                    # true: stop snapshotting files before write/edit
+# git-checkpoints: false                   # true: also snapshot the tracked git tree so bash changes can be rewound
+
+# Bash sandbox (default: commands run directly on the host)
+# sandbox:
+#   backend: bwrap                         # none | bwrap (Linux) | docker | podman
+#   image: ghcr.io/mark3labs/kit-sandbox   # docker/podman image (default: kit-sandbox:latest)
+#   network: false                         # allow network access inside the sandbox
+#   writable-paths: ["~/go/pkg/mod"]       # extra read-write paths besides the working directory
+#   readonly-paths: [".git"]               # read-only paths (bwrap: carve-outs in writable paths)
+#   env: ["GOFLAGS", "CI=1"]               # forwarded (NAME) or set (NAME=value) variables
+
+# Language servers for the definition, references, hover, diagnostics,
+# rename_symbol and workspace_symbols tools. Edits to files a server handles
+# also report its errors and warnings. Presets: gopls, pyright, typescript,
+# rust-analyzer, clangd.
+# lsp:
+#   gopls: {}                              # use the preset as-is
+#   typescript:                            # override the preset's command
+#     command: npx
+#     args: ["typescript-language-server", "--stdio"]
+#   zls:                                   # any other stdio language server
+#     command: zls
+#     extensions: [".zig"]
+
+# Tool-call approval policy (all optional; without it every tool call runs)
+# Rules are "tool" or "tool(specifier)": bash takes a command ("git status",
+# "go test:*" prefix, or a * glob), file tools take a path glob relative to
+# the project, and mcp(server) / mcp(server__tool*) match MCP tools.
+# Deny beats ask, ask beats allow; unmatched calls use the default.
+# Non-interactive runs treat "ask" as "deny".
+# permissions:
+#   default: ask                           # allow | ask | deny (default: allow)
+#   allow:
+#     - read
+#     - grep
+#     - "bash(git status)"
+#     - "bash(go test:*)"
+#     - "write(src/**)"
+#   ask:
+#     - "mcp(github)"
+#   deny:
+#     - "bash(rm -rf:*)"
+#     - "edit(.env)"
+
 # API Configuration (can also use environment variables)
 # provider-api-key: "your-api-key"         # API key for OpenAI, Anthropic, or Google
 # provider-url: "https://api.openai.com/v1" # Base URL for OpenAI, Anthropic, or Ollama
@@ -0,0 +1,639 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Scope names a configuration file layer. Layers are merged from lowest to
+// highest precedence in the order listed by Scopes; environment variables
+// and command-line flags override all of them.
+type Scope string
+
+const (
+	// ScopeSystem is the machine-wide file, e.g. /etc/kit/kit.yml.
+	ScopeSystem Scope = "system"
+	// ScopeUser is the user's ~/.kit.yml.
+	ScopeUser Scope = "user"
+	// ScopeProject is the .kit.yml at the project root, usually committed.
+	ScopeProject Scope = "project"
+	// ScopeLocal is the untracked .kit.local.yml next to the project file.
+	ScopeLocal Scope = "local"
+)
+
+// Scopes lists the file layers from lowest to highest precedence.
+var Scopes = []Scope{ScopeSystem, ScopeUser, ScopeProject, ScopeLocal}
+
+// ParseScope validates a scope name given on the command line.
+func ParseScope(s string) (Scope, error) {
+	for _, scope := range Scopes {
+		if string(scope) == s {
+			return scope, nil
+		}
+	}
+	return "", fmt.Errorf("unknown config scope %q (want system, user, project, or local)", s)
+}
+
+// configExtensions are tried in order when looking for a layer's file.
+var configExtensions = []string{"yml", "yaml", "json"}
+
+// entryMaps are top-level maps whose entries are complete definitions. A
+// same-named entry in a higher layer replaces the lower one wholesale rather
+// than being merged field by field, so a project never runs half of a user's
+// server definition with half of its own.
+var entryMaps = map[string]bool{
+	"mcpservers":   true,
+	"custommodels": true,
+}
+
+// unionLists are lists that collect the entries of every layer instead of
+// replacing lower ones, so no layer can drop a rule another one added.
+var unionLists = map[string]bool{
+	"permissions.deny":       true,
+	"permissions.ask":        true,
+	"sandbox.readonly-paths": true,
+}
+
+// permissionRank orders permission defaults from most to least permissive.
+var permissionRank = map[string]int{"allow": 0, "ask": 1, "deny": 2}
+
+// Layer is one configuration file that takes part in the merge.
+type Layer struct {
+	Scope Scope
+	Path  string
+	Data  map[string]any
+}
+
+// Origin records where an effective setting came from.
+type Origin struct {
+	Scope Scope
+	Path  string
+}
+
+// String renders the origin as "<scope> <path>", or just the path for an
+// explicit --config file, which has no scope.
+func (o Origin) String() string {
+	if o.Scope == "" {
+		return o.Path
+	}
+	return string(o.Scope) + " " + o.Path
+}
+
+// SystemConfigDir returns the directory holding the system-wide kit.yml.
+// KIT_SYSTEM_CONFIG_DIR overrides the platform default.
+func SystemConfigDir() string {
+	if dir := os.Getenv("KIT_SYSTEM_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("ProgramData"); dir != "" {
+			return filepath.Join(dir, "kit")
+		}
+	}
+	return "/etc/kit"
+}
+
+// FindProjectRoot returns the nearest ancestor of dir (or dir itself) that
+// contains a .git entry, or dir when it is not inside a repository.
+func FindProjectRoot(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	for d := abs; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return abs
+		}
+		d = parent
+	}
+}
+
+// ScopePath returns the file holding scope's settings for a process started
+// in dir, whether or not it exists yet. An existing .yaml or .json file is
+// preferred; otherwise the .yml name is returned.
+func ScopePath(scope Scope, dir string) (string, error) {
+	var base string
+	switch scope {
+	case ScopeSystem:
+		base = filepath.Join(SystemConfigDir(), "kit")
+	case ScopeUser:
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("error finding home directory: %w", err)
+		}
+		base = filepath.Join(home, ".kit")
+	case ScopeProject:
+		base = filepath.Join(FindProjectRoot(dir), ".kit")
+	case ScopeLocal:
+		base = filepath.Join(FindProjectRoot(dir), ".kit.local")
+	default:
+		return "", fmt.Errorf("unknown config scope %q", scope)
+	}
+	for _, ext := range configExtensions {
+		if path := base + "." + ext; fileExists(path) {
+			return path, nil
+		}
+	}
+	return base + ".yml", nil
+}
+
+// LoadLayers reads every configuration file that exists for a process
+// started in dir, lowest precedence first. A file reachable from more than
+// one scope (running from a home directory that is also the project root)
+// is loaded once, at its lowest scope.
+func LoadLayers(dir string) ([]Layer, error) {
+	return loadLayers(dir, Scopes)
+}
+
+// LoadUserLayers is LoadLayers without the project and local files, for a
+// process running in a checkout whose committed settings are not trusted.
+func LoadUserLayers(dir string) ([]Layer, error) {
+	return loadLayers(dir, []Scope{ScopeSystem, ScopeUser})
+}
+
+// loadLayers reads the files of the given scopes, lowest precedence first.
+func loadLayers(dir string, scopes []Scope) ([]Layer, error) {
+	var layers []Layer
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		path, err := ScopePath(scope, dir)
+		if err != nil {
+			if scope == ScopeUser {
+				continue
+			}
+			return nil, err
+		}
+		if seen[path] || !fileExists(path) {
+			continue
+		}
+		seen[path] = true
+		data, err := ReadLayerFile(path)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, Layer{Scope: scope, Path: path, Data: data})
+	}
+	return layers, nil
+}
+
+// ReadLayerFile parses one YAML or JSON config file after ${ENV_VAR}
+// substitution. An empty file yields an empty map.
+func ReadLayerFile(path string) (map[string]any, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	content, err := (&EnvSubstituter{}).SubstituteEnvVars(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file '%s': config env substitution failed: %w", path, err)
+	}
+	data := make(map[string]any)
+	if strings.HasSuffix(path, ".json") {
+		if strings.TrimSpace(content) != "" {
+			err = json.Unmarshal([]byte(content), &data)
+		}
+	} else {
+		err = yaml.Unmarshal([]byte(content), &data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config file '%s': %w", path, err)
+	}
+	if data == nil {
+		data = make(map[string]any)
+	}
+	return data, nil
+}
+
+// MergeLayers merges layers in order and reports the origin of every leaf
+// setting, keyed by viper's lower-cased dotted key.
+//
+// Scalars and lists in a higher layer replace lower ones. Maps merge key by
+// key, except the entries of mcpServers and customModels, which replace
+// same-named entries whole. A nested key set to null removes the entry from
+// lower layers; a null top-level key (such as an empty "mcpServers:") is
+// treated as unset.
+//
+// Security settings are the exception. permissions.deny, permissions.ask and
+// sandbox.readonly-paths collect the entries of every layer. The project and
+// local files cannot loosen what the system or user files set: a
+// permissions.default they make more permissive is ignored, and once the
+// system or user file configures the sandbox they may only add read-only
+// paths to it.
+func MergeLayers(layers []Layer) (map[string]any, map[string]Origin) {
+	merged := make(map[string]any)
+	origins := make(map[string]Origin)
+	for _, l := range layers {
+		data := l.Data
+		if l.Scope == ScopeProject || l.Scope == ScopeLocal {
+			data = withoutWeakening(data, merged, origins)
+		}
+		mergeMap(merged, data, "", Origin{Scope: l.Scope, Path: l.Path}, origins)
+	}
+	return merged, origins
+}
+
+// withoutWeakening returns the settings of a project or local layer minus
+// the changes that would loosen the permissions or sandbox set by a system
+// or user layer, given what has been merged so far.
+func withoutWeakening(data, merged map[string]any, origins map[string]Origin) map[string]any {
+	out := make(map[string]any, len(data))
+	for k, v := range data {
+		out[k] = v
+	}
+	if key, perms, ok := lookupKey(out, "permissions"); ok && userSet(origins, "permissions") {
+		p, isMap := perms.(map[string]any)
+		if !isMap {
+			delete(out, key)
+		} else {
+			kept := make(map[string]any, len(p))
+			for k, v := range p {
+				kept[k] = v
+			}
+			if dk, def, ok := lookupKey(kept, "default"); ok && userSet(origins, "permissions.default") {
+				current, _ := lookupPath(merged, "permissions", "default")
+				if rankOf(def) < rankOf(current) {
+					delete(kept, dk)
+				}
+			}
+			out[key] = kept
+		}
+	}
+	if key, sandbox, ok := lookupKey(out, "sandbox"); ok && userSet(origins, "sandbox") {
+		kept := make(map[string]any)
+		if sb, isMap := sandbox.(map[string]any); isMap {
+			if rk, paths, ok := lookupKey(sb, "readonly-paths"); ok {
+				kept[rk] = paths
+			}
+		}
+		out[key] = kept
+	}
+	return out
+}
+
+// userSet reports whether a system or user layer, or an explicit --config
+// file, set p or anything below it.
+func userSet(origins map[string]Origin, p string) bool {
+	for k, o := range origins {
+		if (k == p || strings.HasPrefix(k, p+".")) && o.Scope != ScopeProject && o.Scope != ScopeLocal {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupKey finds k in m case-insensitively, as viper does.
+func lookupKey(m map[string]any, k string) (string, any, bool) {
+	for existing, v := range m {
+		if strings.EqualFold(existing, k) {
+			return existing, v, true
+		}
+	}
+	return "", nil, false
+}
+
+// lookupPath follows keys through nested maps.
+func lookupPath(m map[string]any, keys ...string) (any, bool) {
+	var v any = m
+	for _, k := range keys {
+		mm, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if _, v, ok = lookupKey(mm, k); !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// rankOf ranks a permission default; anything unrecognised counts as allow,
+// which is what the policy falls back to.
+func rankOf(v any) int {
+	s, _ := v.(string)
+	return permissionRank[strings.ToLower(strings.TrimSpace(s))]
+}
+
+func mergeMap(dst, src map[string]any, path string, o Origin, origins map[string]Origin) {
+	for k, sv := range src {
+		key := k
+		for existing := range dst {
+			if strings.EqualFold(existing, k) {
+				key = existing
+				break
+			}
+		}
+		p := joinKey(path, k)
+		if unionLists[p] {
+			if list, ok := sv.([]any); ok {
+				existing, _ := dst[key].([]any)
+				dst[key] = unionList(existing, list)
+				// The lowest layer keeps the origin, so the entries a
+				// system or user file set stay attributed to it.
+				if _, ok := origins[p]; !ok {
+					recordOrigins(origins, p, sv, o)
+				}
+			}
+			continue
+		}
+		if sv == nil {
+			if path != "" {
+				delete(dst, key)
+				forgetOrigins(origins, p)
+			}
+			continue
+		}
+		sm, srcIsMap := sv.(map[string]any)
+		dm, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap && !entryMaps[path] {
+			mergeMap(dm, sm, p, o, origins)
+			continue
+		}
+		forgetOrigins(origins, p)
+		dst[key] = cloneValue(sv)
+		recordOrigins(origins, p, sv, o)
+	}
+}
+
+// unionList appends the entries of add that dst lacks.
+func unionList(dst, add []any) []any {
+	out := slices.Clone(dst)
+	for _, v := range add {
+		if !slices.ContainsFunc(out, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+			out = append(out, cloneValue(v))
+		}
+	}
+	return out
+}
+
+func joinKey(path, k string) string {
+	if path == "" {
+		return strings.ToLower(k)
+	}
+	return path + "." + strings.ToLower(k)
+}
+
+func forgetOrigins(origins map[string]Origin, p string) {
+	for k := range origins {
+		if k == p || strings.HasPrefix(k, p+".") {
+			delete(origins, k)
+		}
+	}
+}
+
+func recordOrigins(origins map[string]Origin, p string, v any, o Origin) {
+	if m, ok := v.(map[string]any); ok && len(m) > 0 {
+		for k, child := range m {
+			recordOrigins(origins, joinKey(p, k), child, o)
+		}
+		return
+	}
+	origins[p] = o
+}
+
+func cloneValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, child := range t {
+			m[k] = cloneValue(child)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i, child := range t {
+			s[i] = cloneValue(child)
+		}
+		return s
+	}
+	return v
+}
+
+// ApplyLayers merges layers into v and points relative-path resolution at
+// the highest-precedence file. It returns the origin of each setting.
+func ApplyLayers(v *viper.Viper, layers []Layer) (map[string]Origin, error) {
+	if v == nil {
+		v = viper.GetViper()
+	}
+	merged, origins := MergeLayers(layers)
+	if len(layers) == 0 {
+		return origins, nil
+	}
+	for k, val := range ModelChain(merged) {
+		merged[k] = val
+	}
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge config files: %w", err)
+	}
+	SetConfigPath(layers[len(layers)-1].Path)
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to load merged config: %w", err)
+	}
+	return origins, nil
+}
+
+// ModelChain returns the settings a list-valued "model" stands for: an
+// ordered failover chain such as
+//
+//	model: [anthropic/claude-sonnet-4-5, openrouter/anthropic/claude-sonnet-4-5]
+//
+// becomes "model" (the first entry) plus "fallback-models" (the rest,
+// followed by any fallback-models set separately), so the rest of Kit keeps
+// reading a single model string. It returns nil when "model" is not a list.
+func ModelChain(settings map[string]any) map[string]any {
+	list, ok := settings["model"].([]any)
+	if !ok {
+		return nil
+	}
+	var chain []string
+	for _, entry := range list {
+		if s, ok := entry.(string); ok && strings.TrimSpace(s) != "" {
+			chain = append(chain, strings.TrimSpace(s))
+		}
+	}
+	if extra, ok := settings["fallback-models"].([]any); ok {
+		for _, entry := range extra {
+			if s, ok := entry.(string); ok && s != "" {
+				chain = append(chain, s)
+			}
+		}
+	}
+	if len(chain) == 0 {
+		return map[string]any{"model": ""}
+	}
+	fallbacks := make([]any, 0, len(chain)-1)
+	for _, s := range chain[1:] {
+		fallbacks = append(fallbacks, s)
+	}
+	return map[string]any{"model": chain[0], "fallback-models": fallbacks}
+}
+
+// OriginKeys returns the keys of origins in sorted order.
+func OriginKeys(origins map[string]Origin) []string {
+	keys := make([]string, 0, len(origins))
+	for k := range origins {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SetValue writes key (a dotted path such as "budget.daily") to the config
+// file at path, creating the file and any intermediate maps. value is parsed
+// as YAML, so "true", "5" and "[a, b]" become a bool, a number and a list.
+// Key segments match existing keys case-insensitively. YAML files keep
+// their comments and layout.
+func SetValue(path, key, value string) error {
+	parts := strings.Split(key, ".")
+	for _, p := range parts {
+		if p == "" {
+			return fmt.Errorf("invalid config key %q", key)
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var out []byte
+	if strings.HasSuffix(path, ".json") {
+		out, err = setJSONValue(raw, parts, value)
+	} else {
+		out, err = setYAMLValue(raw, parts, value)
+	}
+	if err != nil {
+		return fmt.Errorf("error updating config file '%s': %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0o644)
+}
+
+func setYAMLValue(raw []byte, parts []string, value string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		if root.Kind != yaml.ScalarNode || root.Tag != "!!null" {
+			return nil, fmt.Errorf("top level is not a map")
+		}
+		*root = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", HeadComment: root.HeadComment}
+	}
+
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid value %q: %w", value, err)
+	}
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if len(parsed.Content) > 0 {
+		valueNode = parsed.Content[0]
+	}
+
+	node := root
+	for i, part := range parts {
+		var child *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if strings.EqualFold(node.Content[j].Value, part) {
+				child = node.Content[j+1]
+				break
+			}
+		}
+		last := i == len(parts)-1
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part}, child)
+		}
+		if last {
+			valueNode.LineComment = child.LineComment
+			*child = *valueNode
+			break
+		}
+		if child.Kind != yaml.MappingNode {
+			*child = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		node = child
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func setJSONValue(raw []byte, parts []string, value string) ([]byte, error) {
+	data := make(map[string]any)
+	if len(bytes.TrimSpace(raw)) > 0 {
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return nil, err
+		}
+	}
+	var parsed any = value
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid value %q: %w", value, err)
+	}
+
+	node := data
+	for i, part := range parts {
+		key := part
+		for existing := range node {
+			if strings.EqualFold(existing, part) {
+				key = existing
+				break
+			}
+		}
+		if i == len(parts)-1 {
+			node[key] = parsed
+			break
+		}
+		child, ok := node[key].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			node[key] = child
+		}
+		node = child
+	}
+
+	out, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
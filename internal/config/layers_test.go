@@ -0,0 +1,290 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLayers_MergeAndOrigins(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	repo := filepath.Join(root, "repo")
+	t.Setenv("HOME", home)
+	t.Setenv("KIT_SYSTEM_CONFIG_DIR", filepath.Join(root, "etc"))
+	t.Cleanup(func() { SetConfigPath("") })
+
+	writeFile(t, filepath.Join(root, "etc", "kit.yml"), "model: system/model\nmax-steps: 10\n")
+	writeFile(t, filepath.Join(home, ".kit.yml"), `
+mcpServers:
+  fs:
+    type: local
+    command: [npx, fs]
+  shared:
+    type: remote
+    url: https://user.example
+theme: dracula
+modelSettings:
+  openai/gpt-4o:
+    temperature: 0.2
+    maxTokens: 100
+`)
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(repo, ".kit.yml"), `
+mcpServers:
+  shared:
+    type: local
+    command: [proj]
+modelSettings:
+  openai/gpt-4o:
+    temperature: 0.5
+`)
+	writeFile(t, filepath.Join(repo, ".kit.local.yml"), "mcpServers:\nmodel: local/model\nmodelSettings:\n  openai/gpt-4o:\n    maxTokens: null\n")
+
+	layers, err := LoadLayers(filepath.Join(repo, "sub", "dir"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scopes []string
+	for _, l := range layers {
+		scopes = append(scopes, string(l.Scope))
+	}
+	if got := strings.Join(scopes, ","); got != "system,user,project,local" {
+		t.Fatalf("layers = %s", got)
+	}
+
+	v := viper.New()
+	origins, err := ApplyLayers(v, layers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := v.GetString("model"); got != "local/model" {
+		t.Errorf("model = %q", got)
+	}
+	if got := v.GetInt("max-steps"); got != 10 {
+		t.Errorf("max-steps = %d, want the system value", got)
+	}
+	if got := v.GetString("theme"); got != "dracula" {
+		t.Errorf("theme = %q, want the user value to survive the project file", got)
+	}
+	// The empty "mcpServers:" in the local file leaves the servers alone;
+	// the project's "shared" replaces the user's entry whole.
+	if !v.IsSet("mcpservers.fs.command") {
+		t.Error("user server fs was dropped")
+	}
+	if v.IsSet("mcpservers.shared.url") || v.GetString("mcpservers.shared.type") != "local" {
+		t.Errorf("shared server = %v, want the project entry only", v.Get("mcpservers.shared"))
+	}
+	// modelSettings merge per field; null removes one.
+	if got := v.GetFloat64("modelsettings.openai/gpt-4o.temperature"); got != 0.5 {
+		t.Errorf("temperature = %v", got)
+	}
+	if v.IsSet("modelsettings.openai/gpt-4o.maxtokens") {
+		t.Error("maxTokens: null did not remove the user value")
+	}
+
+	want := map[string]Scope{
+		"model":                     ScopeLocal,
+		"max-steps":                 ScopeSystem,
+		"theme":                     ScopeUser,
+		"mcpservers.fs.command":     ScopeUser,
+		"mcpservers.shared.command": ScopeProject,
+		"modelsettings.openai/gpt-4o.temperature": ScopeProject,
+	}
+	for key, scope := range want {
+		if got := origins[key].Scope; got != scope {
+			t.Errorf("origin of %s = %q, want %q", key, got, scope)
+		}
+	}
+	if _, ok := origins["mcpservers.shared.url"]; ok {
+		t.Error("replaced entry kept an origin for mcpservers.shared.url")
+	}
+	if got := GetConfigPath(); got != filepath.Join(repo, ".kit.local.yml") {
+		t.Errorf("config path = %q, want the local file", got)
+	}
+}
+
+func TestApplyLayers_ModelChain(t *testing.T) {
+	t.Cleanup(func() { SetConfigPath("") })
+	dir := t.TempDir()
+	user := filepath.Join(dir, "user.yml")
+	project := filepath.Join(dir, "project.yml")
+	writeFile(t, user, "model: anthropic/claude-sonnet-4-5\nfallback-models: [ollama/qwen3]\n")
+	writeFile(t, project, "model:\n  - anthropic/claude-opus-4-1\n  - openrouter/anthropic/claude-opus-4.1\n")
+
+	var layers []Layer
+	for _, l := range []struct {
+		scope Scope
+		path  string
+	}{{ScopeUser, user}, {ScopeProject, project}} {
+		data, err := ReadLayerFile(l.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		layers = append(layers, Layer{Scope: l.scope, Path: l.path, Data: data})
+	}
+
+	v := viper.New()
+	if _, err := ApplyLayers(v, layers); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetString("model"); got != "anthropic/claude-opus-4-1" {
+		t.Errorf("model = %q", got)
+	}
+	want := "openrouter/anthropic/claude-opus-4.1,ollama/qwen3"
+	if got := strings.Join(v.GetStringSlice("fallback-models"), ","); got != want {
+		t.Errorf("fallback-models = %q, want %q", got, want)
+	}
+
+	if ModelChain(map[string]any{"model": "a/b"}) != nil {
+		t.Error("a single model is not a chain")
+	}
+}
+
+func TestMergeLayers_SecuritySettings(t *testing.T) {
+	dir := t.TempDir()
+	layer := func(scope Scope, content string) Layer {
+		path := filepath.Join(dir, string(scope)+".yml")
+		writeFile(t, path, content)
+		data, err := ReadLayerFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Layer{Scope: scope, Path: path, Data: data}
+	}
+	user := layer(ScopeUser, `
+permissions:
+  default: ask
+  deny: ["bash(rm -rf:*)"]
+sandbox:
+  backend: bwrap
+  readonly-paths: [.git]
+`)
+
+	for _, tt := range []struct {
+		project string
+		key     []string
+		want    string
+	}{
+		// A null or empty list cannot drop the user's rules; entries add up.
+		{"permissions:\n  deny: null\n", []string{"permissions", "deny"}, "[bash(rm -rf:*)]"},
+		{"permissions:\n  deny: []\n", []string{"permissions", "deny"}, "[bash(rm -rf:*)]"},
+		{"permissions:\n  deny: [\"edit(.env)\"]\n", []string{"permissions", "deny"}, "[bash(rm -rf:*) edit(.env)]"},
+		{"permissions: none\n", []string{"permissions", "deny"}, "[bash(rm -rf:*)]"},
+		// The default may only get stricter.
+		{"permissions:\n  default: allow\n", []string{"permissions", "default"}, "ask"},
+		{"permissions:\n  default: deny\n", []string{"permissions", "default"}, "deny"},
+		// The user's sandbox stays; only read-only paths can be added.
+		{"sandbox:\n  backend: none\n", []string{"sandbox", "backend"}, "bwrap"},
+		{"sandbox:\n  network: true\n", []string{"sandbox", "network"}, "<nil>"},
+		{"sandbox:\n  readonly-paths: [docs]\n", []string{"sandbox", "readonly-paths"}, "[.git docs]"},
+	} {
+		merged, _ := MergeLayers([]Layer{user, layer(ScopeProject, tt.project)})
+		got, _ := lookupPath(merged, tt.key...)
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("project %q: %s = %v, want %s", tt.project, strings.Join(tt.key, "."), got, tt.want)
+		}
+	}
+
+	// Without user settings the project file configures both freely.
+	merged, _ := MergeLayers([]Layer{layer(ScopeProject, "permissions:\n  default: allow\nsandbox:\n  backend: docker\n")})
+	if got, _ := lookupPath(merged, "sandbox", "backend"); got != "docker" {
+		t.Errorf("project sandbox backend = %v, want docker", got)
+	}
+}
+
+func TestLoadLayers_HomeIsProjectRoot(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("KIT_SYSTEM_CONFIG_DIR", filepath.Join(home, "none"))
+	writeFile(t, filepath.Join(home, ".kit.yml"), "model: a/b\n")
+
+	layers, err := LoadLayers(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 1 || layers[0].Scope != ScopeUser {
+		t.Errorf("layers = %+v, want the home file once as user", layers)
+	}
+}
+
+func TestLoadUserLayers_SkipsProject(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	repo := filepath.Join(root, "repo")
+	t.Setenv("HOME", home)
+	t.Setenv("KIT_SYSTEM_CONFIG_DIR", filepath.Join(root, "etc"))
+	writeFile(t, filepath.Join(home, ".kit.yml"), "model: a/b\n")
+	writeFile(t, filepath.Join(repo, ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(repo, ".kit.yml"), "mcpServers:\n  evil:\n    command: [sh]\n")
+	writeFile(t, filepath.Join(repo, ".kit.local.yml"), "model: c/d\n")
+
+	layers, err := LoadUserLayers(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 1 || layers[0].Scope != ScopeUser {
+		t.Errorf("layers = %+v, want only the user file", layers)
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".kit.yml")
+	writeFile(t, path, "# keep me\nmcpServers:\n\nModel: old # pinned\n")
+
+	for _, kv := range [][2]string{
+		{"model", "new/model"},
+		{"budget.daily", "5"},
+		{"mcpServers.fs.command", "[npx, fs]"},
+	} {
+		if err := SetValue(path, kv[0], kv[1]); err != nil {
+			t.Fatalf("SetValue(%s): %v", kv[0], err)
+		}
+	}
+
+	raw, _ := os.ReadFile(path)
+	out := string(raw)
+	for _, want := range []string{"# keep me", "Model: new/model # pinned", "daily: 5"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("file missing %q:\n%s", want, out)
+		}
+	}
+	data, err := ReadLayerFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers, _ := data["mcpServers"].(map[string]any)
+	if cmd, _ := servers["fs"].(map[string]any)["command"].([]any); len(cmd) != 2 {
+		t.Errorf("command = %v", servers["fs"])
+	}
+
+	jsonPath := filepath.Join(dir, ".kit.json")
+	if err := SetValue(jsonPath, "budget.monthly", "50"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ReadLayerFile(jsonPath); err != nil || data["budget"].(map[string]any)["monthly"] != float64(50) {
+		t.Errorf("json file = %v, %v", data, err)
+	}
+
+	if err := SetValue(path, "budget..daily", "1"); err == nil {
+		t.Error("SetValue accepted an empty key segment")
+	}
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -70,12 +71,17 @@ func sudoPasswordFromContext(ctx context.Context) string {
 const defaultBashTimeout = 120 * time.Second
 const maxBashTimeout = 600 * time.Second
 
+// bashWaitDelay bounds how long Wait keeps draining output after the shell
+// exits, so background processes holding the pipes open cannot hang a call.
+const bashWaitDelay = 2 * time.Second
+
 // bannedCmdRe matches bash builtin commands that are not allowed for security reasons.
 var bannedCmdRe = regexp.MustCompile(`^(alias|bg|bind|builtin|caller|command|compgen|complete|compopt|coproc|dirs|disown|enable|fc|fg|hash|help|history|jobs|kill|logout|mapfile|popd|pushd|readonly|select|set|shopt|source|suspend|times|trap|type|typeset|ulimit|umask|unalias|wait)\s`)
 
 type bashArgs struct {
-	Command string  `json:"command"`
-	Timeout float64 `json:"timeout,omitempty"`
+	Command         string  `json:"command"`
+	Timeout         float64 `json:"timeout,omitempty"`
+	RunInBackground bool    `json:"run_in_background,omitempty"`
 }
 
 // NewBashTool creates the bash core tool.
@@ -96,24 +102,48 @@ func NewBashTool(opts ...ToolOption) fantasy.AgentTool {
 		defTimeout = maxTimeout
 	}
 
+	var executor BashExecutor = hostExecutor{}
+	description := "Execute a bash command. Returns stdout and stderr. Output is truncated to the last 2000 lines or 50KB. Optionally provide a timeout in seconds."
+	if cfg.BashExecutor != nil {
+		executor = cfg.BashExecutor
+		if _, remote := executor.(BashRunner); executor.Name() != "host" && !remote {
+			description += fmt.Sprintf(" Commands run in a %s sandbox: only the working directory is writable and network access may be disabled.", executor.Name())
+		}
+	}
+
+	parameters := map[string]any{
+		"command": map[string]any{
+			"type":        "string",
+			"description": "Bash command to execute",
+		},
+		"timeout": map[string]any{
+			"type":        "number",
+			"description": fmt.Sprintf("Timeout in seconds (optional, default %ds, max %ds)", int(defTimeout.Seconds()), int(maxTimeout.Seconds())),
+		},
+	}
+	if cfg.Jobs != nil {
+		description += " Set run_in_background for dev servers, watchers and long builds: the command keeps running and a job ID is returned immediately; read its output with bash_output and stop it with bash_kill."
+		parameters["run_in_background"] = map[string]any{
+			"type":        "boolean",
+			"description": "Start the command as a background job and return its job ID without waiting (optional, timeout does not apply)",
+		}
+	}
+
 	return &coreTool{
 		info: fantasy.ToolInfo{
 			Name:        "bash",
-			Description: "Execute a bash command. Returns stdout and stderr. Output is truncated to the last 2000 lines or 50KB. Optionally provide a timeout in seconds.",
-			Parameters: map[string]any{
-				"command": map[string]any{
-					"type":        "string",
-					"description": "Bash command to execute",
-				},
-				"timeout": map[string]any{
-					"type":        "number",
-					"description": fmt.Sprintf("Timeout in seconds (optional, default %ds, max %ds)", int(defTimeout.Seconds()), int(maxTimeout.Seconds())),
-				},
-			},
-			Required: []string{"command"},
+			Description: description,
+			Parameters:  parameters,
+			Required:    []string{"command"},
 		},
 		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
-			return executeBash(ctx, call, cfg.WorkDir, defTimeout, maxTimeout)
+			if cfg.Jobs != nil {
+				var args bashArgs
+				if err := parseArgs(call.Input, &args); err == nil && args.RunInBackground {
+					return startBackgroundBash(cfg.Jobs, executor, args.Command, cfg.WorkDir)
+				}
+			}
+			return executeBashWith(ctx, call, executor, cfg.WorkDir, defTimeout, maxTimeout)
 		},
 	}
 }
@@ -169,7 +199,13 @@ func rewriteSudoForStdin(command string) string {
 	return result
 }
 
+// executeBash runs a bash tool call directly on the host.
 func executeBash(ctx context.Context, call fantasy.ToolCall, workDir string, defaultTimeout, maxTimeout time.Duration) (fantasy.ToolResponse, error) {
+	return executeBashWith(ctx, call, hostExecutor{}, workDir, defaultTimeout, maxTimeout)
+}
+
+// executeBashWith runs a bash tool call with the given executor.
+func executeBashWith(ctx context.Context, call fantasy.ToolCall, executor BashExecutor, workDir string, defaultTimeout, maxTimeout time.Duration) (fantasy.ToolResponse, error) {
 	var args bashArgs
 	if err := parseArgs(call.Input, &args); err != nil {
 		return fantasy.NewTextErrorResponse("command parameter is required"), nil
@@ -193,15 +229,27 @@ func executeBash(ctx context.Context, call fantasy.ToolCall, workDir string, def
 	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	command := args.Command
+
+	if runner, ok := executor.(BashRunner); ok {
+		return runBash(cmdCtx, runner, command, workDir)
+	}
+
+	// Sudo handling only applies on the host: sandboxed commands cannot
+	// reach the host's sudo credentials.
+	_, onHost := executor.(hostExecutor)
+
 	// Check for sudo password in context or environment
-	sudoPassword := sudoPasswordFromContext(ctx)
-	if sudoPassword == "" {
-		sudoPassword = os.Getenv("SUDO_PASSWORD")
+	var sudoPassword string
+	if onHost {
+		sudoPassword = sudoPasswordFromContext(ctx)
+		if sudoPassword == "" {
+			sudoPassword = os.Getenv("SUDO_PASSWORD")
+		}
 	}
-	command := args.Command
 
 	// If command contains sudo and we don't have a password, check if sudo needs one
-	if sudoPassword == "" && sudoCommandRe.MatchString(command) {
+	if onHost && sudoPassword == "" && sudoCommandRe.MatchString(command) {
 		// Check if sudo credentials are cached using sudo -n (non-interactive)
 		testCmd := exec.CommandContext(cmdCtx, "sudo", "-n", "true")
 		testCmd.Dir = workDir
@@ -233,18 +281,11 @@ func executeBash(ctx context.Context, call fantasy.ToolCall, workDir string, def
 		command = rewriteSudoForStdin(command)
 	}
 
-	cmd := exec.CommandContext(cmdCtx, "bash", "-c", command)
-	if workDir != "" {
-		cmd.Dir = workDir
-	}
-
-	// Ensure SHELL is set to bash so child processes (e.g. tmux) use bash
-	// rather than the user's login shell (which may be nushell, fish, etc.).
-	bashPath, err := exec.LookPath("bash")
+	cmd, err := executor.Command(cmdCtx, command, workDir)
 	if err != nil {
-		bashPath = "/bin/bash"
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to prepare command: %v", err)), nil
 	}
-	cmd.Env = append(os.Environ(), "SHELL="+bashPath)
+	cmd.WaitDelay = bashWaitDelay
 
 	// Get the output callback if present (for streaming support)
 	outputCallback := toolOutputCallbackFromContext(ctx)
@@ -258,35 +299,53 @@ func executeBash(ctx context.Context, call fantasy.ToolCall, workDir string, def
 	return executeBashBuffered(cmdCtx, call, cmd, sudoPassword)
 }
 
-// setupBashPipes opens stdout/stderr pipes (plus an optional sudo stdin),
-// starts the command, and asynchronously writes the sudo password if any.
-// Returns the readers ready for the caller to consume. If setup fails,
-// errResp is non-nil and the readers must not be used; the caller should
-// return the response directly.
-func setupBashPipes(cmd *exec.Cmd, sudoPassword string) (stdout, stderr io.Reader, errResp *fantasy.ToolResponse) {
-	stdoutPipe, err := cmd.StdoutPipe()
+// runBash runs a command with a BashRunner. Output arrives all at once, so
+// it is not streamed.
+func runBash(cmdCtx context.Context, runner BashRunner, command, workDir string) (fantasy.ToolResponse, error) {
+	output, exitCode, err := runner.Run(cmdCtx, command, workDir)
 	if err != nil {
-		r := fantasy.NewTextErrorResponse("failed to create stdout pipe")
-		return nil, nil, &r
+		if cmdCtx.Err() == context.DeadlineExceeded {
+			return fantasy.NewTextErrorResponse("command timed out"), nil
+		}
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to run command: %v", err)), nil
 	}
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		r := fantasy.NewTextErrorResponse("failed to create stderr pipe")
-		return nil, nil, &r
+	return buildBashResponse(output, "", exitCode)
+}
+
+// setupBashPipes connects stdout/stderr to in-process pipes (plus an
+// optional sudo stdin), starts the command, and asynchronously writes the
+// sudo password if any. Returns the readers ready for the caller to consume
+// and a done function to call once cmd.Wait has returned, which ends the
+// readers. If setup fails, errResp is non-nil and the readers must not be
+// used; the caller should return the response directly.
+//
+// The writers are handed to exec (rather than using cmd.StdoutPipe) so that
+// cmd.Wait only returns once all output has been copied — bounded by
+// cmd.WaitDelay when grandchild processes hold the pipes open.
+func setupBashPipes(cmd *exec.Cmd, sudoPassword string) (stdout, stderr io.Reader, done func(), errResp *fantasy.ToolResponse) {
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	done = func() {
+		_ = stdoutWriter.Close()
+		_ = stderrWriter.Close()
 	}
 
 	var stdinPipe io.WriteCloser
 	if sudoPassword != "" {
+		var err error
 		stdinPipe, err = cmd.StdinPipe()
 		if err != nil {
 			r := fantasy.NewTextErrorResponse("failed to create stdin pipe")
-			return nil, nil, &r
+			return nil, nil, nil, &r
 		}
 	}
 
 	if err := cmd.Start(); err != nil {
+		done()
 		r := fantasy.NewTextErrorResponse(fmt.Sprintf("failed to start command: %v", err))
-		return nil, nil, &r
+		return nil, nil, nil, &r
 	}
 
 	if sudoPassword != "" && stdinPipe != nil {
@@ -296,7 +355,7 @@ func setupBashPipes(cmd *exec.Cmd, sudoPassword string) (stdout, stderr io.Reade
 		}()
 	}
 
-	return stdoutPipe, stderrPipe, nil
+	return stdoutReader, stderrReader, done, nil
 }
 
 // interpretBashExit decodes cmd.Wait()'s error into an exit code, mapping
@@ -304,7 +363,9 @@ func setupBashPipes(cmd *exec.Cmd, sudoPassword string) (stdout, stderr io.Reade
 // errResp is non-nil only when the caller should short-circuit and return
 // it directly (e.g. timeout).
 func interpretBashExit(waitErr error, cmdCtx context.Context) (exitCode int, errResp *fantasy.ToolResponse) {
-	if waitErr == nil {
+	if waitErr == nil || errors.Is(waitErr, exec.ErrWaitDelay) {
+		// ErrWaitDelay means the shell exited successfully but a background
+		// process kept the output pipes open past the grace period.
 		return 0, nil
 	}
 	if exitErr, ok := waitErr.(*exec.ExitError); ok {
@@ -318,11 +379,8 @@ func interpretBashExit(waitErr error, cmdCtx context.Context) (exitCode int, err
 }
 
 // executeBashBuffered collects all output before returning (original behavior).
-// It uses explicit pipes (not cmd.Stdout) so that cmd.WaitDelay can forcibly
-// close them when grandchild processes hold pipe handles open after the
-// direct child exits.
 func executeBashBuffered(cmdCtx context.Context, _ fantasy.ToolCall, cmd *exec.Cmd, sudoPassword string) (fantasy.ToolResponse, error) {
-	stdoutPipe, stderrPipe, errResp := setupBashPipes(cmd, sudoPassword)
+	stdoutPipe, stderrPipe, done, errResp := setupBashPipes(cmd, sudoPassword)
 	if errResp != nil {
 		return *errResp, nil
 	}
@@ -341,12 +399,13 @@ func executeBashBuffered(cmdCtx context.Context, _ fantasy.ToolCall, cmd *exec.C
 		_, _ = io.Copy(&stderr, stderrPipe)
 	}()
 
-	// Wait for the process to exit first. cmd.WaitDelay ensures that if
-	// pipes remain open (held by grandchild processes), they'll be forcibly
-	// closed after the grace period, which unblocks the io.Copy goroutines.
+	// Wait for the process to exit and its output to be copied. cmd.WaitDelay
+	// ensures that if pipes remain open (held by grandchild processes),
+	// they'll be forcibly closed after the grace period.
 	waitErr := cmd.Wait()
 
-	// Wait for pipe readers to finish draining.
+	// End the readers and wait for them to finish draining.
+	done()
 	wg.Wait()
 
 	exitCode, errResp := interpretBashExit(waitErr, cmdCtx)
@@ -359,7 +418,7 @@ func executeBashBuffered(cmdCtx context.Context, _ fantasy.ToolCall, cmd *exec.C
 
 // executeBashStreaming streams output as it arrives via the callback.
 func executeBashStreaming(cmdCtx context.Context, call fantasy.ToolCall, cmd *exec.Cmd, outputCallback ToolOutputCallback, sudoPassword string) (fantasy.ToolResponse, error) {
-	stdoutPipe, stderrPipe, errResp := setupBashPipes(cmd, sudoPassword)
+	stdoutPipe, stderrPipe, done, errResp := setupBashPipes(cmd, sudoPassword)
 	if errResp != nil {
 		return *errResp, nil
 	}
@@ -389,6 +448,9 @@ func executeBashStreaming(cmdCtx context.Context, call fantasy.ToolCall, cmd *ex
 			}
 			mu.Unlock()
 		}
+		// Keep draining after a scanner error (e.g. an over-long line) so
+		// the command never blocks on a full pipe.
+		_, _ = io.Copy(io.Discard, reader)
 	}
 
 	wg.Add(2)
@@ -400,9 +462,10 @@ func executeBashStreaming(cmdCtx context.Context, call fantasy.ToolCall, cmd *ex
 	// after the grace period, which unblocks the scanners above.
 	waitErr := cmd.Wait()
 
-	// Wait for the pipe readers to finish draining. This will complete
-	// quickly since cmd.Wait() (with WaitDelay) has already ensured
-	// the pipes are closed.
+	// End the readers and wait for them to finish draining. This completes
+	// quickly since cmd.Wait() (with WaitDelay) has already copied all
+	// output.
+	done()
 	wg.Wait()
 
 	exitCode, errResp := interpretBashExit(waitErr, cmdCtx)
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+type bashOutputArgs struct {
+	JobID string  `json:"job_id"`
+	Wait  float64 `json:"wait,omitempty"`
+}
+
+type bashKillArgs struct {
+	JobID string `json:"job_id"`
+}
+
+// startBackgroundBash starts command as a background job and reports its ID.
+func startBackgroundBash(jobs *JobManager, executor BashExecutor, command, workDir string) (fantasy.ToolResponse, error) {
+	if command == "" {
+		return fantasy.NewTextErrorResponse("command parameter is required"), nil
+	}
+	if bannedCmdRe.MatchString(command) {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("command '%s' is not allowed", command)), nil
+	}
+	info, err := jobs.Start(executor, command, workDir)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to start background job: %v", err)), nil
+	}
+	return fantasy.NewTextResponse(fmt.Sprintf(
+		"Started background job %s. Use bash_output with job_id %q to read its output and bash_kill to stop it.",
+		info.ID, info.ID)), nil
+}
+
+// NewBashOutputTool creates the bash_output core tool, which reads new
+// output from a background job started with bash's run_in_background.
+func NewBashOutputTool(opts ...ToolOption) fantasy.AgentTool {
+	cfg := ApplyOptions(opts)
+	return &coreTool{
+		info: fantasy.ToolInfo{
+			Name:        "bash_output",
+			Description: "Read the output a background bash job produced since the last read, and whether it is still running. Optionally wait up to the given number of seconds for the job to finish, streaming output meanwhile.",
+			Parameters: map[string]any{
+				"job_id": map[string]any{
+					"type":        "string",
+					"description": "ID returned when the job was started",
+				},
+				"wait": map[string]any{
+					"type":        "number",
+					"description": fmt.Sprintf("Seconds to wait for the job to finish before returning (optional, default 0, max %ds)", int(maxBashTimeout.Seconds())),
+				},
+			},
+			Required: []string{"job_id"},
+		},
+		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if cfg.Jobs == nil {
+				return fantasy.NewTextErrorResponse("background jobs are not enabled"), nil
+			}
+			var args bashOutputArgs
+			if err := parseArgs(call.Input, &args); err != nil || args.JobID == "" {
+				return fantasy.NewTextErrorResponse("job_id parameter is required"), nil
+			}
+			wait := min(time.Duration(args.Wait*float64(time.Second)), maxBashTimeout)
+
+			var onChunk func(string, bool)
+			if cb := toolOutputCallbackFromContext(ctx); cb != nil {
+				onChunk = func(chunk string, isStderr bool) {
+					for line := range strings.SplitSeq(strings.TrimSuffix(chunk, "\n"), "\n") {
+						cb(call.ID, "bash_output", line, isStderr)
+					}
+				}
+			}
+			out, err := cfg.Jobs.Wait(ctx, args.JobID, wait, onChunk)
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			return fantasy.NewTextResponse(formatJobOutput(out)), nil
+		},
+	}
+}
+
+// NewBashKillTool creates the bash_kill core tool, which stops a background
+// job and every process it started.
+func NewBashKillTool(opts ...ToolOption) fantasy.AgentTool {
+	cfg := ApplyOptions(opts)
+	return &coreTool{
+		info: fantasy.ToolInfo{
+			Name:        "bash_kill",
+			Description: "Stop a background bash job and the processes it started.",
+			Parameters: map[string]any{
+				"job_id": map[string]any{
+					"type":        "string",
+					"description": "ID returned when the job was started",
+				},
+			},
+			Required: []string{"job_id"},
+		},
+		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if cfg.Jobs == nil {
+				return fantasy.NewTextErrorResponse("background jobs are not enabled"), nil
+			}
+			var args bashKillArgs
+			if err := parseArgs(call.Input, &args); err != nil || args.JobID == "" {
+				return fantasy.NewTextErrorResponse("job_id parameter is required"), nil
+			}
+			info, err := cfg.Jobs.Kill(args.JobID)
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			if info.Running {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("job %s did not exit after being killed", info.ID)), nil
+			}
+			return fantasy.NewTextResponse(fmt.Sprintf("Job %s stopped.", info.ID)), nil
+		},
+	}
+}
+
+// formatJobOutput renders a bash_output result: a status line followed by
+// the new output, truncated from the tail like bash output.
+func formatJobOutput(out JobOutput) string {
+	var b strings.Builder
+	if out.Running {
+		fmt.Fprintf(&b, "Job %s is running (%s).", out.ID, time.Since(out.StartedAt).Round(time.Second))
+	} else {
+		fmt.Fprintf(&b, "Job %s exited with code %d.", out.ID, out.ExitCode)
+	}
+	if out.Skipped > 0 {
+		fmt.Fprintf(&b, "\n[%d bytes of earlier output were dropped]", out.Skipped)
+	}
+
+	var output strings.Builder
+	output.WriteString(out.Stdout)
+	if out.Stderr != "" {
+		if output.Len() > 0 {
+			output.WriteString("\n")
+		}
+		output.WriteString("STDERR:\n")
+		output.WriteString(out.Stderr)
+	}
+	if output.Len() == 0 {
+		b.WriteString("\n(no new output)")
+		return b.String()
+	}
+	b.WriteString("\n")
+	b.WriteString(TruncateTail(output.String(), defaultMaxLines, defaultMaxBytes).Content)
+	return b.String()
+}
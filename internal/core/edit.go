@@ -3,7 +3,6 @@ package core
 import (
 	"context"
 	"fmt"
-	"os"
 	"sort"
 	"strings"
 	"unicode"
@@ -77,12 +76,19 @@ func NewEditTool(opts ...ToolOption) fantasy.AgentTool {
 			Required: []string{"path", "edits"},
 		},
 		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
-			return executeEdit(ctx, call, cfg.WorkDir)
+			resp, err := executeEditWith(ctx, call, cfg.WorkDir, cfg.fileSystem())
+			return withPostEditDiagnostics(ctx, cfg, call, resp, err)
 		},
 	}
 }
 
+// executeEdit runs an edit tool call against the local disk.
 func executeEdit(ctx context.Context, call fantasy.ToolCall, workDir string) (fantasy.ToolResponse, error) {
+	return executeEditWith(ctx, call, workDir, hostFileSystem{})
+}
+
+// executeEditWith runs an edit tool call against fsys.
+func executeEditWith(ctx context.Context, call fantasy.ToolCall, workDir string, fsys FileSystem) (fantasy.ToolResponse, error) {
 	if err := ctx.Err(); err != nil {
 		return fantasy.ToolResponse{}, err
 	}
@@ -99,7 +105,7 @@ func executeEdit(ctx context.Context, call fantasy.ToolCall, workDir string) (fa
 		return fantasy.NewTextErrorResponse(fmt.Sprintf("invalid path: %v", err)), nil
 	}
 
-	contentBytes, err := os.ReadFile(absPath)
+	contentBytes, err := fsys.ReadFile(ctx, absPath)
 	if err != nil {
 		return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to read file: %v", err)), nil
 	}
@@ -119,7 +125,7 @@ func executeEdit(ctx context.Context, call fantasy.ToolCall, workDir string) (fa
 	}
 
 	// Write the file
-	if err := os.WriteFile(absPath, []byte(newContent), 0644); err != nil {
+	if err := fsys.WriteFile(ctx, absPath, []byte(newContent)); err != nil {
 		return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to write file: %v", err)), nil
 	}
 
@@ -0,0 +1,33 @@
+package core
+
+import (
+	"context"
+	"slices"
+)
+
+// FileGuard vets the files a tool call is about to modify when they only
+// become known while the tool runs, as with rename_symbol, whose files come
+// from the language server. Returning an error stops the call before
+// anything is written.
+type FileGuard func(ctx context.Context, paths []string) error
+
+type fileGuardsCtxKey struct{}
+
+// WithFileGuard adds guard to the guards GuardFiles runs for calls made
+// with ctx. Guards run in the order they were added, so a tool wrapper adds
+// its guard before calling the tool it wraps.
+func WithFileGuard(ctx context.Context, guard FileGuard) context.Context {
+	guards, _ := ctx.Value(fileGuardsCtxKey{}).([]FileGuard)
+	return context.WithValue(ctx, fileGuardsCtxKey{}, append(slices.Clip(guards), guard))
+}
+
+// GuardFiles runs every guard in ctx over paths and returns the first error.
+func GuardFiles(ctx context.Context, paths []string) error {
+	guards, _ := ctx.Value(fileGuardsCtxKey{}).([]FileGuard)
+	for _, guard := range guards {
+		if err := guard(ctx, paths); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"os"
+)
+
+// FileSystem is where the file tools (read, write, edit, apply_patch and
+// rename_symbol) get and put file contents. Paths are absolute. The default
+// uses the local disk; an editor integration can route access through its
+// buffers instead, so the model sees unsaved changes and edits land in open
+// documents. Deleting a file, which the interface cannot express, and
+// checking whether one exists still use the disk.
+type FileSystem interface {
+	// ReadFile returns the contents of the file at path.
+	ReadFile(ctx context.Context, path string) ([]byte, error)
+	// WriteFile replaces the contents of the file at path, creating it if
+	// needed.
+	WriteFile(ctx context.Context, path string, data []byte) error
+}
+
+// hostFileSystem reads and writes the local disk.
+type hostFileSystem struct{}
+
+func (hostFileSystem) ReadFile(_ context.Context, path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (hostFileSystem) WriteFile(_ context.Context, path string, data []byte) error {
+	return os.WriteFile(path, data, 0644)
+}
+
+// fileSystem returns the configured file system, defaulting to the disk.
+func (c ToolConfig) fileSystem() FileSystem {
+	if c.FileSystem != nil {
+		return c.FileSystem
+	}
+	return hostFileSystem{}
+}
+
+// writeFileMode writes data through fs. The local disk also gets mode, even
+// for an existing file; other file systems keep their own.
+func writeFileMode(ctx context.Context, fs FileSystem, path string, data []byte, mode os.FileMode) error {
+	if _, ok := fs.(hostFileSystem); !ok {
+		return fs.WriteFile(ctx, path, data)
+	}
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+// memFileSystem keeps files in memory, standing in for editor buffers.
+type memFileSystem map[string]string
+
+func (m memFileSystem) ReadFile(_ context.Context, path string) ([]byte, error) {
+	content, ok := m[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+func (m memFileSystem) WriteFile(_ context.Context, path string, data []byte) error {
+	m[path] = string(data)
+	return nil
+}
+
+func toolCall(t *testing.T, args map[string]any) fantasy.ToolCall {
+	t.Helper()
+	input, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fantasy.ToolCall{ID: "call", Input: string(input)}
+}
+
+func TestFileTools_UseFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "buffer.go")
+	fsys := memFileSystem{path: "package main\n"}
+	opts := []ToolOption{WithWorkDir(dir), WithFileSystem(fsys)}
+
+	// The file only exists in the file system, not on disk.
+	resp, err := NewReadTool(opts...).Run(context.Background(), toolCall(t, map[string]any{"path": "buffer.go"}))
+	if err != nil || resp.IsError || !strings.Contains(resp.Content, "1: package main") {
+		t.Fatalf("read = %q (error %v, %v)", resp.Content, resp.IsError, err)
+	}
+
+	resp, err = NewEditTool(opts...).Run(context.Background(), toolCall(t, map[string]any{
+		"path":  "buffer.go",
+		"edits": []map[string]string{{"old_text": "main", "new_text": "kit"}},
+	}))
+	if err != nil || resp.IsError {
+		t.Fatalf("edit = %q (error %v, %v)", resp.Content, resp.IsError, err)
+	}
+	if fsys[path] != "package kit\n" {
+		t.Errorf("after edit = %q", fsys[path])
+	}
+
+	resp, err = NewWriteTool(opts...).Run(context.Background(), toolCall(t, map[string]any{
+		"path": "new.txt", "content": "hello",
+	}))
+	if err != nil || resp.IsError {
+		t.Fatalf("write = %q (error %v, %v)", resp.Content, resp.IsError, err)
+	}
+	if fsys[filepath.Join(dir, "new.txt")] != "hello" {
+		t.Errorf("write did not go through the file system: %v", fsys)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.txt")); !os.IsNotExist(err) {
+		t.Errorf("write should not touch the disk: %v", err)
+	}
+}
+
+func TestApplyPatch_UsesFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package saved\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The editor buffer holds unsaved changes the patch is written against.
+	fsys := memFileSystem{path: "package unsaved\n"}
+
+	patch := "*** Begin Patch\n*** Update File: main.go\n@@\n-package unsaved\n+package patched\n*** End Patch\n"
+	resp, err := NewApplyPatchTool(WithWorkDir(dir), WithFileSystem(fsys)).Run(context.Background(), toolCall(t, map[string]any{"patch": patch}))
+	if err != nil || resp.IsError {
+		t.Fatalf("apply_patch = %q (error %v, %v)", resp.Content, resp.IsError, err)
+	}
+	if fsys[path] != "package patched\n" {
+		t.Errorf("buffer = %q", fsys[path])
+	}
+	if disk, _ := os.ReadFile(path); string(disk) != "package saved\n" {
+		t.Errorf("apply_patch wrote the disk: %q", disk)
+	}
+}
@@ -0,0 +1,336 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxBackgroundJobs caps how many background jobs may run at once.
+const maxBackgroundJobs = 16
+
+// maxJobOutput is how much unread output is kept per stream of a job. Older
+// output is dropped and reported as skipped on the next read.
+const maxJobOutput = 1 << 20
+
+// JobManager tracks bash commands started in the background. Jobs outlive
+// the tool call that started them and run until they exit, are killed with
+// Kill, or the manager is shut down with KillAll.
+type JobManager struct {
+	mu     sync.Mutex
+	jobs   map[string]*backgroundJob
+	order  []string
+	nextID int
+	closed bool
+}
+
+// JobInfo is a snapshot of a background job's state.
+type JobInfo struct {
+	ID        string
+	Command   string
+	WorkDir   string
+	StartedAt time.Time
+	// EndedAt is zero while the job is running.
+	EndedAt  time.Time
+	Running  bool
+	ExitCode int
+}
+
+// JobOutput is the output a job produced since the previous read.
+type JobOutput struct {
+	JobInfo
+	Stdout string
+	Stderr string
+	// Skipped is the number of bytes dropped because they were not read
+	// before the per-stream buffer filled up.
+	Skipped int64
+}
+
+// NewJobManager returns an empty job manager.
+func NewJobManager() *JobManager {
+	return &JobManager{jobs: make(map[string]*backgroundJob)}
+}
+
+// backgroundJob is a single detached command.
+type backgroundJob struct {
+	id        string
+	command   string
+	workDir   string
+	startedAt time.Time
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	mu       sync.Mutex
+	stdout   jobStream
+	stderr   jobStream
+	endedAt  time.Time
+	exitCode int
+	// changed is closed and replaced whenever output arrives or the job
+	// exits, waking anyone waiting in JobManager.Wait.
+	changed chan struct{}
+}
+
+// jobStream buffers one output stream, tracking what has been read.
+type jobStream struct {
+	buf  []byte
+	base int64 // absolute offset of buf[0]
+	read int64 // absolute offset consumed by readers
+}
+
+func (s *jobStream) append(p []byte) {
+	s.buf = append(s.buf, p...)
+	if over := len(s.buf) - maxJobOutput; over > 0 {
+		s.buf = s.buf[over:]
+		s.base += int64(over)
+	}
+}
+
+// take returns everything not yet read and how many bytes were lost.
+func (s *jobStream) take() (string, int64) {
+	var skipped int64
+	start := s.read
+	if start < s.base {
+		skipped = s.base - start
+		start = s.base
+	}
+	out := string(s.buf[start-s.base:])
+	s.read = s.base + int64(len(s.buf))
+	// Read output is no longer needed.
+	s.buf = s.buf[:0]
+	s.base = s.read
+	return out, skipped
+}
+
+// jobWriter feeds one stream of a job from the process.
+type jobWriter struct {
+	job    *backgroundJob
+	stderr bool
+}
+
+func (w *jobWriter) Write(p []byte) (int, error) {
+	j := w.job
+	j.mu.Lock()
+	if w.stderr {
+		j.stderr.append(p)
+	} else {
+		j.stdout.append(p)
+	}
+	j.notifyLocked()
+	j.mu.Unlock()
+	return len(p), nil
+}
+
+func (j *backgroundJob) notifyLocked() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+func (j *backgroundJob) infoLocked() JobInfo {
+	info := JobInfo{
+		ID:        j.id,
+		Command:   j.command,
+		WorkDir:   j.workDir,
+		StartedAt: j.startedAt,
+		EndedAt:   j.endedAt,
+		ExitCode:  j.exitCode,
+	}
+	select {
+	case <-j.done:
+	default:
+		info.Running = true
+	}
+	return info
+}
+
+// Start runs command with executor in the background and returns its ID.
+func (m *JobManager) Start(executor BashExecutor, command, workDir string) (JobInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return JobInfo{}, errors.New("background jobs have been shut down")
+	}
+	running := 0
+	for _, j := range m.jobs {
+		select {
+		case <-j.done:
+		default:
+			running++
+		}
+	}
+	if running >= maxBackgroundJobs {
+		return JobInfo{}, fmt.Errorf("too many background jobs running (max %d); kill one with bash_kill first", maxBackgroundJobs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd, err := executor.Command(ctx, command, workDir)
+	if err != nil {
+		cancel()
+		return JobInfo{}, err
+	}
+	m.nextID++
+	job := &backgroundJob{
+		id:        "job-" + strconv.Itoa(m.nextID),
+		command:   command,
+		workDir:   cmd.Dir,
+		startedAt: time.Now(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		changed:   make(chan struct{}),
+	}
+	cmd.Stdout = &jobWriter{job: job}
+	cmd.Stderr = &jobWriter{job: job, stderr: true}
+	cmd.WaitDelay = bashWaitDelay
+	// Kill the whole process group so servers spawned by the command (npm
+	// run dev, etc.) go down with it.
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return JobInfo{}, fmt.Errorf("failed to start command: %w", err)
+	}
+	go func() {
+		waitErr := cmd.Wait()
+		exitCode := 0
+		var exitErr *exec.ExitError
+		switch {
+		case errors.As(waitErr, &exitErr):
+			exitCode = exitErr.ExitCode()
+		case waitErr != nil && !errors.Is(waitErr, exec.ErrWaitDelay):
+			exitCode = -1
+		}
+		job.mu.Lock()
+		job.endedAt = time.Now()
+		job.exitCode = exitCode
+		close(job.done)
+		job.notifyLocked()
+		job.mu.Unlock()
+		cancel()
+	}()
+
+	m.jobs[job.id] = job
+	m.order = append(m.order, job.id)
+	return JobInfo{ID: job.id, Command: command, WorkDir: job.workDir, StartedAt: job.startedAt, Running: true}, nil
+}
+
+func (m *JobManager) get(id string) (*backgroundJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("no background job %q", id)
+	}
+	return job, nil
+}
+
+// Read returns the output job id produced since the previous read.
+func (m *JobManager) Read(id string) (JobOutput, error) {
+	return m.Wait(context.Background(), id, 0, nil)
+}
+
+// Wait collects new output from job id until it exits, timeout elapses or
+// ctx is done, whichever comes first; a zero timeout returns immediately.
+// onChunk, when non-nil, receives output as it arrives.
+func (m *JobManager) Wait(ctx context.Context, id string, timeout time.Duration, onChunk func(chunk string, isStderr bool)) (JobOutput, error) {
+	job, err := m.get(id)
+	if err != nil {
+		return JobOutput{}, err
+	}
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	var out JobOutput
+	for {
+		job.mu.Lock()
+		stdout, skippedOut := job.stdout.take()
+		stderr, skippedErr := job.stderr.take()
+		out.JobInfo = job.infoLocked()
+		changed := job.changed
+		job.mu.Unlock()
+
+		out.Stdout += stdout
+		out.Stderr += stderr
+		out.Skipped += skippedOut + skippedErr
+		if onChunk != nil {
+			if stdout != "" {
+				onChunk(stdout, false)
+			}
+			if stderr != "" {
+				onChunk(stderr, true)
+			}
+		}
+		if !out.Running || deadline == nil {
+			return out, nil
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			deadline = nil // take the final output, then return
+		case <-ctx.Done():
+			deadline = nil
+		}
+	}
+}
+
+// Kill stops job id and waits briefly for it to exit.
+func (m *JobManager) Kill(id string) (JobInfo, error) {
+	job, err := m.get(id)
+	if err != nil {
+		return JobInfo{}, err
+	}
+	job.cancel()
+	select {
+	case <-job.done:
+	case <-time.After(bashWaitDelay + time.Second):
+	}
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return job.infoLocked(), nil
+}
+
+// List returns every job started by the manager, oldest first.
+func (m *JobManager) List() []JobInfo {
+	m.mu.Lock()
+	jobs := make([]*backgroundJob, 0, len(m.order))
+	for _, id := range m.order {
+		jobs = append(jobs, m.jobs[id])
+	}
+	m.mu.Unlock()
+
+	infos := make([]JobInfo, 0, len(jobs))
+	for _, job := range jobs {
+		job.mu.Lock()
+		infos = append(infos, job.infoLocked())
+		job.mu.Unlock()
+	}
+	return infos
+}
+
+// KillAll stops every running job and rejects new ones. It is called when
+// the owning session closes.
+func (m *JobManager) KillAll() {
+	m.mu.Lock()
+	m.closed = true
+	jobs := make([]*backgroundJob, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	m.mu.Unlock()
+
+	for _, job := range jobs {
+		job.cancel()
+	}
+	for _, job := range jobs {
+		select {
+		case <-job.done:
+		case <-time.After(bashWaitDelay + time.Second):
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+func TestJobManager_StartAndWait(t *testing.T) {
+	jobs := NewJobManager()
+	info, err := jobs.Start(hostExecutor{}, "echo one; echo two >&2; exit 3", "")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if info.ID != "job-1" || !info.Running {
+		t.Errorf("Start returned %+v", info)
+	}
+
+	out, err := jobs.Wait(context.Background(), info.ID, 5*time.Second, nil)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if out.Running || out.ExitCode != 3 {
+		t.Errorf("job should have exited with code 3: %+v", out.JobInfo)
+	}
+	if out.Stdout != "one\n" || out.Stderr != "two\n" {
+		t.Errorf("stdout = %q, stderr = %q", out.Stdout, out.Stderr)
+	}
+
+	// Output is only returned once.
+	out, _ = jobs.Read(info.ID)
+	if out.Stdout != "" || out.Stderr != "" {
+		t.Errorf("second read returned %q / %q, want nothing", out.Stdout, out.Stderr)
+	}
+}
+
+func TestJobManager_IncrementalOutputAndKill(t *testing.T) {
+	jobs := NewJobManager()
+	info, err := jobs.Start(hostExecutor{}, "echo ready; sleep 60", "")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	var chunks []string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && len(chunks) == 0 {
+		_, _ = jobs.Wait(context.Background(), info.ID, 200*time.Millisecond, func(chunk string, _ bool) {
+			chunks = append(chunks, chunk)
+		})
+	}
+	if len(chunks) != 1 || chunks[0] != "ready\n" {
+		t.Fatalf("chunks = %q, want [ready]", chunks)
+	}
+
+	start := time.Now()
+	killed, err := jobs.Kill(info.ID)
+	if err != nil {
+		t.Fatalf("Kill: %v", err)
+	}
+	if killed.Running {
+		t.Error("job still running after Kill")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Kill took %v", time.Since(start))
+	}
+}
+
+func TestJobManager_KillAllStopsJobs(t *testing.T) {
+	jobs := NewJobManager()
+	for range 2 {
+		if _, err := jobs.Start(hostExecutor{}, "sleep 60 & sleep 60", ""); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	}
+	jobs.KillAll()
+	for _, job := range jobs.List() {
+		if job.Running {
+			t.Errorf("job %s still running after KillAll", job.ID)
+		}
+	}
+	if _, err := jobs.Start(hostExecutor{}, "true", ""); err == nil {
+		t.Error("Start should fail after KillAll")
+	}
+	if _, err := jobs.Read("job-99"); err == nil {
+		t.Error("expected error for unknown job")
+	}
+}
+
+func TestJobStream_DropsUnreadOutput(t *testing.T) {
+	var s jobStream
+	s.append([]byte(strings.Repeat("a", maxJobOutput)))
+	s.append([]byte("bc"))
+	out, skipped := s.take()
+	if skipped != 2 || len(out) != maxJobOutput || !strings.HasSuffix(out, "bc") {
+		t.Errorf("take() = %d bytes, skipped %d", len(out), skipped)
+	}
+	s.append([]byte("d"))
+	if out, skipped := s.take(); out != "d" || skipped != 0 {
+		t.Errorf("take() = %q, skipped %d", out, skipped)
+	}
+}
+
+func TestBashTools_BackgroundRoundTrip(t *testing.T) {
+	jobs := NewJobManager()
+	defer jobs.KillAll()
+	bash := NewBashTool(WithJobManager(jobs))
+	output := NewBashOutputTool(WithJobManager(jobs))
+	kill := NewBashKillTool(WithJobManager(jobs))
+
+	if _, ok := bash.Info().Parameters["run_in_background"]; !ok {
+		t.Fatal("bash should offer run_in_background when jobs are enabled")
+	}
+	if _, ok := NewBashTool().Info().Parameters["run_in_background"]; ok {
+		t.Error("bash should not offer run_in_background without a job manager")
+	}
+
+	input, _ := json.Marshal(map[string]any{"command": "echo started; sleep 60", "run_in_background": true})
+	resp, err := bash.Run(context.Background(), fantasy.ToolCall{ID: "1", Name: "bash", Input: string(input)})
+	if err != nil || resp.IsError {
+		t.Fatalf("start: %v %s", err, resp.Content)
+	}
+	if !strings.Contains(resp.Content, "job-1") {
+		t.Fatalf("start response should name the job: %q", resp.Content)
+	}
+
+	var streamed []string
+	ctx := ContextWithToolOutputCallback(context.Background(), func(_, toolName, chunk string, _ bool) {
+		streamed = append(streamed, toolName+":"+chunk)
+	})
+	resp, _ = output.Run(ctx, fantasy.ToolCall{ID: "2", Name: "bash_output", Input: `{"job_id":"job-1","wait":1}`})
+	if !strings.Contains(resp.Content, "is running") || !strings.Contains(resp.Content, "started") {
+		t.Errorf("bash_output = %q", resp.Content)
+	}
+	if len(streamed) != 1 || streamed[0] != "bash_output:started" {
+		t.Errorf("streamed = %q", streamed)
+	}
+
+	resp, _ = kill.Run(context.Background(), fantasy.ToolCall{ID: "3", Name: "bash_kill", Input: `{"job_id":"job-1"}`})
+	if resp.IsError {
+		t.Errorf("bash_kill failed: %s", resp.Content)
+	}
+	resp, _ = output.Run(context.Background(), fantasy.ToolCall{ID: "4", Name: "bash_output", Input: `{"job_id":"job-1"}`})
+	if !strings.Contains(resp.Content, "exited") {
+		t.Errorf("bash_output after kill = %q", resp.Content)
+	}
+}
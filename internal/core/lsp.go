@@ -0,0 +1,640 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/kit/internal/lsp"
+)
+
+const (
+	// lspRequestTimeout bounds a single code intelligence query, including
+	// a cold server start.
+	lspRequestTimeout = 60 * time.Second
+	// lspDiagnosticsWait is how long the diagnostics tool waits for a
+	// server to re-check a changed file.
+	lspDiagnosticsWait = 5 * time.Second
+	// postEditDiagnosticsWait is how long edit and write wait for
+	// diagnostics before returning without them.
+	postEditDiagnosticsWait = 3 * time.Second
+	// postEditTimeout bounds the whole post-edit check, including starting
+	// the language server the first time a file type is edited.
+	postEditTimeout = 15 * time.Second
+	// lspMaxResults caps the locations or symbols listed in one result.
+	lspMaxResults = 100
+	// postEditMaxDiagnostics caps the diagnostics appended to an edit.
+	postEditMaxDiagnostics = 20
+)
+
+type lspPositionArgs struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Symbol string `json:"symbol,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+type renameSymbolArgs struct {
+	lspPositionArgs
+	NewName string `json:"new_name"`
+}
+
+type diagnosticsArgs struct {
+	Path string `json:"path,omitempty"`
+}
+
+type workspaceSymbolsArgs struct {
+	Query string `json:"query"`
+	Path  string `json:"path,omitempty"`
+}
+
+// LSPTools returns the language-server backed code intelligence tools:
+// definition, references, hover, diagnostics, rename_symbol and
+// workspace_symbols. They need a manager set with WithLSP.
+func LSPTools(opts ...ToolOption) []fantasy.AgentTool {
+	return []fantasy.AgentTool{
+		NewDefinitionTool(opts...),
+		NewReferencesTool(opts...),
+		NewHoverTool(opts...),
+		NewDiagnosticsTool(opts...),
+		NewRenameSymbolTool(opts...),
+		NewWorkspaceSymbolsTool(opts...),
+	}
+}
+
+// lspPositionParams is the parameter schema shared by tools that act on a
+// symbol at a position.
+func lspPositionParams() map[string]any {
+	return map[string]any{
+		"path": map[string]any{
+			"type":        "string",
+			"description": "File containing the symbol (relative or absolute)",
+		},
+		"line": map[string]any{
+			"type":        "number",
+			"description": "Line number of the symbol (1-indexed)",
+		},
+		"symbol": map[string]any{
+			"type":        "string",
+			"description": "The symbol as it appears on that line, e.g. a function or variable name. Its first occurrence on the line is used.",
+		},
+		"column": map[string]any{
+			"type":        "number",
+			"description": "Column of the symbol (1-indexed, in characters). Only needed when the symbol occurs more than once on the line.",
+		},
+	}
+}
+
+// NewDefinitionTool creates the definition core tool.
+func NewDefinitionTool(opts ...ToolOption) fantasy.AgentTool {
+	cfg := ApplyOptions(opts)
+	return &coreTool{
+		info: fantasy.ToolInfo{
+			Name:        "definition",
+			Description: "Find where a symbol is defined, using the language server for the file. Returns file:line:column locations with the source line.",
+			Parameters:  lspPositionParams(),
+			Required:    []string{"path", "line", "symbol"},
+		},
+		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			return runPositionQuery(ctx, call, cfg, func(ctx context.Context, c *lsp.Client, path string, pos lsp.Position) (string, error) {
+				locs, err := c.Definition(ctx, path, pos)
+				if err != nil {
+					return "", err
+				}
+				if len(locs) == 0 {
+					return "No definition found.", nil
+				}
+				return formatLocations(locs, cfg.WorkDir), nil
+			})
+		},
+	}
+}
+
+// NewReferencesTool creates the references core tool.
+func NewReferencesTool(opts ...ToolOption) fantasy.AgentTool {
+	cfg := ApplyOptions(opts)
+	return &coreTool{
+		info: fantasy.ToolInfo{
+			Name:        "references",
+			Description: fmt.Sprintf("Find every reference to a symbol across the workspace, including its declaration, using the language server for the file. Output is truncated to %d locations.", lspMaxResults),
+			Parameters:  lspPositionParams(),
+			Required:    []string{"path", "line", "symbol"},
+		},
+		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			return runPositionQuery(ctx, call, cfg, func(ctx context.Context, c *lsp.Client, path string, pos lsp.Position) (string, error) {
+				locs, err := c.References(ctx, path, pos)
+				if err != nil {
+					return "", err
+				}
+				if len(locs) == 0 {
+					return "No references found.", nil
+				}
+				return fmt.Sprintf("%d references:\n%s", len(locs), formatLocations(locs, cfg.WorkDir)), nil
+			})
+		},
+	}
+}
+
+// NewHoverTool creates the hover core tool.
+func NewHoverTool(opts ...ToolOption) fantasy.AgentTool {
+	cfg := ApplyOptions(opts)
+	return &coreTool{
+		info: fantasy.ToolInfo{
+			Name:        "hover",
+			Description: "Show the type signature and documentation of a symbol, as an editor would on hover, using the language server for the file.",
+			Parameters:  lspPositionParams(),
+			Required:    []string{"path", "line", "symbol"},
+		},
+		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			return runPositionQuery(ctx, call, cfg, func(ctx context.Context, c *lsp.Client, path string, pos lsp.Position) (string, error) {
+				text, err := c.Hover(ctx, path, pos)
+				if err != nil {
+					return "", err
+				}
+				if text == "" {
+					return "No hover information.", nil
+				}
+				return truncateHead(text, defaultMaxLines, defaultMaxBytes).Content, nil
+			})
+		},
+	}
+}
+
+// NewRenameSymbolTool creates the rename_symbol core tool.
+func NewRenameSymbolTool(opts ...ToolOption) fantasy.AgentTool {
+	cfg := ApplyOptions(opts)
+	params := lspPositionParams()
+	params["new_name"] = map[string]any{
+		"type":        "string",
+		"description": "New name for the symbol",
+	}
+	return &coreTool{
+		info: fantasy.ToolInfo{
+			Name:        "rename_symbol",
+			Description: "Rename a symbol and update every reference to it across the workspace, using the language server for the file. Files are modified on disk.",
+			Parameters:  params,
+			Required:    []string{"path", "line", "symbol", "new_name"},
+		},
+		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			var args renameSymbolArgs
+			if err := parseArgs(call.Input, &args); err != nil {
+				return fantasy.NewTextErrorResponse("failed to parse arguments: " + err.Error()), nil
+			}
+			if args.NewName == "" {
+				return fantasy.NewTextErrorResponse("new_name parameter is required"), nil
+			}
+			return runPositionQuery(ctx, call, cfg, func(ctx context.Context, c *lsp.Client, path string, pos lsp.Position) (string, error) {
+				edit, err := c.Rename(ctx, path, pos, args.NewName)
+				if err != nil {
+					return "", err
+				}
+				fs := cfg.fileSystem()
+				root := cfg.WorkDir
+				if root == "" {
+					root, _ = os.Getwd()
+				}
+				planned, err := lsp.PlanWorkspaceEdit(edit, root, func(p string) ([]byte, error) {
+					return fs.ReadFile(ctx, p)
+				})
+				if err != nil {
+					return "", fmt.Errorf("failed to apply rename: %w", err)
+				}
+				if len(planned) == 0 {
+					return "", errors.New("the language server returned no edits")
+				}
+				// The language server decides which files change, so the
+				// permission policy and checkpoints see them only now.
+				paths := make([]string, len(planned))
+				for i, f := range planned {
+					paths[i] = f.Path
+				}
+				if err := GuardFiles(ctx, paths); err != nil {
+					return "", err
+				}
+				files := make([]lsp.FileEdits, len(planned))
+				for i, f := range planned {
+					if err := fs.WriteFile(ctx, f.Path, f.Content); err != nil {
+						return "", fmt.Errorf("failed to apply rename: %w", err)
+					}
+					files[i] = f.FileEdits
+				}
+				var b strings.Builder
+				total := 0
+				for _, f := range files {
+					total += len(f.Edits)
+				}
+				fmt.Fprintf(&b, "Renamed %s to %s: %d edits in %d files", args.Symbol, args.NewName, total, len(files))
+				for _, f := range files {
+					fmt.Fprintf(&b, "\n%s (%d)", displayPath(f.Path, cfg.WorkDir), len(f.Edits))
+				}
+				return b.String(), nil
+			})
+		},
+	}
+}
+
+// NewDiagnosticsTool creates the diagnostics core tool.
+func NewDiagnosticsTool(opts ...ToolOption) fantasy.AgentTool {
+	cfg := ApplyOptions(opts)
+	return &coreTool{
+		info: fantasy.ToolInfo{
+			Name:        "diagnostics",
+			Description: "Report compiler errors, warnings and hints for a file from its language server. Without a path, lists everything the running language servers have reported so far.",
+			Parameters: map[string]any{
+				"path": map[string]any{
+					"type":        "string",
+					"description": "File to check (relative or absolute). Omit to list all known diagnostics.",
+				},
+			},
+		},
+		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if cfg.LSP == nil {
+				return fantasy.NewTextErrorResponse("no language servers are configured"), nil
+			}
+			var args diagnosticsArgs
+			if err := parseArgs(call.Input, &args); err != nil {
+				return fantasy.NewTextErrorResponse("failed to parse arguments: " + err.Error()), nil
+			}
+			byPath := make(map[string][]lsp.Diagnostic)
+			if args.Path == "" {
+				for _, c := range cfg.LSP.Running() {
+					for path, diags := range c.PublishedDiagnostics() {
+						byPath[path] = append(byPath[path], diags...)
+					}
+				}
+			} else {
+				absPath, err := resolvePathWithWorkDir(args.Path, cfg.WorkDir)
+				if err != nil {
+					return fantasy.NewTextErrorResponse(fmt.Sprintf("invalid path: %v", err)), nil
+				}
+				ctx, cancel := context.WithTimeout(ctx, lspRequestTimeout)
+				defer cancel()
+				c, err := cfg.LSP.ClientFor(ctx, absPath)
+				if err != nil {
+					return fantasy.NewTextErrorResponse(err.Error()), nil
+				}
+				diags, err := c.Diagnostics(ctx, absPath, lspDiagnosticsWait)
+				if err != nil {
+					return fantasy.NewTextErrorResponse(err.Error()), nil
+				}
+				byPath[absPath] = diags
+			}
+			if countDiagnostics(byPath) == 0 {
+				return fantasy.NewTextResponse("No diagnostics."), nil
+			}
+			return fantasy.NewTextResponse(formatDiagnostics(byPath, cfg.WorkDir, lsp.SeverityHint, lspMaxResults)), nil
+		},
+	}
+}
+
+// NewWorkspaceSymbolsTool creates the workspace_symbols core tool.
+func NewWorkspaceSymbolsTool(opts ...ToolOption) fantasy.AgentTool {
+	cfg := ApplyOptions(opts)
+	return &coreTool{
+		info: fantasy.ToolInfo{
+			Name:        "workspace_symbols",
+			Description: fmt.Sprintf("Search the workspace for functions, types, variables and other symbols by name, using the configured language servers. Output is truncated to %d symbols.", lspMaxResults),
+			Parameters: map[string]any{
+				"query": map[string]any{
+					"type":        "string",
+					"description": "Symbol name or fragment to search for",
+				},
+				"path": map[string]any{
+					"type":        "string",
+					"description": "Any file in the language to search; selects the language server. Omit to search with every configured server.",
+				},
+			},
+			Required: []string{"query"},
+		},
+		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if cfg.LSP == nil {
+				return fantasy.NewTextErrorResponse("no language servers are configured"), nil
+			}
+			var args workspaceSymbolsArgs
+			if err := parseArgs(call.Input, &args); err != nil {
+				return fantasy.NewTextErrorResponse("failed to parse arguments: " + err.Error()), nil
+			}
+			if args.Query == "" {
+				return fantasy.NewTextErrorResponse("query parameter is required"), nil
+			}
+			ctx, cancel := context.WithTimeout(ctx, lspRequestTimeout)
+			defer cancel()
+
+			servers := cfg.LSP.Servers()
+			if args.Path != "" {
+				absPath, err := resolvePathWithWorkDir(args.Path, cfg.WorkDir)
+				if err != nil {
+					return fantasy.NewTextErrorResponse(fmt.Sprintf("invalid path: %v", err)), nil
+				}
+				name, ok := cfg.LSP.ServerFor(absPath)
+				if !ok {
+					return fantasy.NewTextErrorResponse(fmt.Sprintf("no language server configured for %s files", filepath.Ext(absPath))), nil
+				}
+				servers = []string{name}
+			}
+
+			var symbols []lsp.Symbol
+			var failures []string
+			for _, name := range servers {
+				c, err := cfg.LSP.Client(ctx, name)
+				if err == nil {
+					var found []lsp.Symbol
+					found, err = c.WorkspaceSymbols(ctx, args.Query)
+					symbols = append(symbols, found...)
+				}
+				if err != nil {
+					failures = append(failures, err.Error())
+				}
+			}
+			if len(symbols) == 0 && len(failures) > 0 {
+				return fantasy.NewTextErrorResponse(strings.Join(failures, "\n")), nil
+			}
+			return fantasy.NewTextResponse(formatSymbols(symbols, failures, cfg.WorkDir)), nil
+		},
+	}
+}
+
+// runPositionQuery parses the shared position arguments, resolves them to
+// an LSP position and runs query against the file's language server.
+func runPositionQuery(ctx context.Context, call fantasy.ToolCall, cfg ToolConfig, query func(context.Context, *lsp.Client, string, lsp.Position) (string, error)) (fantasy.ToolResponse, error) {
+	if cfg.LSP == nil {
+		return fantasy.NewTextErrorResponse("no language servers are configured"), nil
+	}
+	var args lspPositionArgs
+	if err := parseArgs(call.Input, &args); err != nil {
+		return fantasy.NewTextErrorResponse("failed to parse arguments: " + err.Error()), nil
+	}
+	if args.Path == "" {
+		return fantasy.NewTextErrorResponse("path parameter is required"), nil
+	}
+	absPath, err := resolvePathWithWorkDir(args.Path, cfg.WorkDir)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("invalid path: %v", err)), nil
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to read file: %v", err)), nil
+	}
+	pos, err := symbolPosition(string(content), args.Line, args.Symbol, args.Column)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lspRequestTimeout)
+	defer cancel()
+	c, err := cfg.LSP.ClientFor(ctx, absPath)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	out, err := query(ctx, c, absPath, pos)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	return fantasy.NewTextResponse(out), nil
+}
+
+// symbolPosition converts a 1-indexed line plus either a symbol on that
+// line or a 1-indexed character column to an LSP position.
+func symbolPosition(content string, line int, symbol string, column int) (lsp.Position, error) {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return lsp.Position{}, fmt.Errorf("line %d is out of range (file has %d lines)", line, len(lines))
+	}
+	text := strings.TrimSuffix(lines[line-1], "\r")
+
+	var byteCol int
+	switch {
+	case column > 0:
+		byteCol = len(text)
+		for i := range text {
+			if column == 1 {
+				byteCol = i
+				break
+			}
+			column--
+		}
+		if symbol != "" && !strings.HasPrefix(text[byteCol:], symbol) {
+			if i := strings.Index(text, symbol); i >= 0 {
+				byteCol = i
+			}
+		}
+	case symbol != "":
+		byteCol = strings.Index(text, symbol)
+		if byteCol < 0 {
+			return lsp.Position{}, fmt.Errorf("symbol %q not found on line %d: %s", symbol, line, text)
+		}
+	default:
+		return lsp.Position{}, errors.New("symbol parameter is required")
+	}
+	return lsp.Position{Line: line - 1, Character: lsp.UTF16Len(text[:byteCol])}, nil
+}
+
+// displayPath shows path relative to the working directory when it is
+// inside it.
+func displayPath(path, workDir string) string {
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+	if rel, err := filepath.Rel(workDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// lineReader returns source lines of files, reading each file once.
+type lineReader map[string][]string
+
+// raw returns line n (0-indexed) of path, or "" if it cannot be read.
+func (r lineReader) raw(path string, n int) string {
+	lines, ok := r[path]
+	if !ok {
+		if data, err := os.ReadFile(path); err == nil {
+			lines = strings.Split(string(data), "\n")
+		}
+		r[path] = lines
+	}
+	if n < 0 || n >= len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[n], "\r")
+}
+
+// characterColumn converts an LSP position on lineText to a 1-indexed
+// character column for display.
+func characterColumn(lineText string, pos lsp.Position) int {
+	units, col := 0, 1
+	for _, r := range lineText {
+		if units >= pos.Character {
+			break
+		}
+		units += lsp.UTF16Len(string(r))
+		col++
+	}
+	return col
+}
+
+// formatLocations lists locations as path:line:column followed by the
+// trimmed source line.
+func formatLocations(locs []lsp.Location, workDir string) string {
+	lines := lineReader{}
+	var b strings.Builder
+	for i, loc := range locs {
+		if i == lspMaxResults {
+			fmt.Fprintf(&b, "\n[%d more locations not shown]", len(locs)-i)
+			break
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		path := lsp.URIToPath(loc.URI)
+		src := lines.raw(path, loc.Range.Start.Line)
+		fmt.Fprintf(&b, "%s:%d:%d: %s", displayPath(path, workDir), loc.Range.Start.Line+1,
+			characterColumn(src, loc.Range.Start), truncateLine(strings.TrimSpace(src), grepMaxLineLen))
+	}
+	return b.String()
+}
+
+func countDiagnostics(byPath map[string][]lsp.Diagnostic) int {
+	n := 0
+	for _, diags := range byPath {
+		n += len(diags)
+	}
+	return n
+}
+
+// formatDiagnostics lists diagnostics at or above minSeverity as
+// "path:line:column: severity: message (source)", most severe first within
+// each file, showing at most limit entries.
+func formatDiagnostics(byPath map[string][]lsp.Diagnostic, workDir string, minSeverity lsp.DiagnosticSeverity, limit int) string {
+	paths := make([]string, 0, len(byPath))
+	for path := range byPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	lines := lineReader{}
+	var b strings.Builder
+	shown, hidden := 0, 0
+	for _, path := range paths {
+		diags := append([]lsp.Diagnostic(nil), byPath[path]...)
+		sort.SliceStable(diags, func(i, j int) bool {
+			if diags[i].Severity != diags[j].Severity {
+				return severityRank(diags[i].Severity) < severityRank(diags[j].Severity)
+			}
+			return diags[i].Range.Start.Line < diags[j].Range.Start.Line
+		})
+		for _, d := range diags {
+			if severityRank(d.Severity) > severityRank(minSeverity) {
+				continue
+			}
+			if shown == limit {
+				hidden++
+				continue
+			}
+			if shown > 0 {
+				b.WriteString("\n")
+			}
+			src := lines.raw(path, d.Range.Start.Line)
+			fmt.Fprintf(&b, "%s:%d:%d: %s: %s", displayPath(path, workDir), d.Range.Start.Line+1,
+				characterColumn(src, d.Range.Start), d.Severity, strings.TrimSpace(d.Message))
+			if d.Source != "" {
+				fmt.Fprintf(&b, " (%s)", d.Source)
+			}
+			shown++
+		}
+	}
+	if hidden > 0 {
+		fmt.Fprintf(&b, "\n[%d more diagnostics not shown]", hidden)
+	}
+	return b.String()
+}
+
+// severityRank orders severities, treating an unset severity as an error
+// as the protocol suggests.
+func severityRank(s lsp.DiagnosticSeverity) int {
+	if s == 0 {
+		return int(lsp.SeverityError)
+	}
+	return int(s)
+}
+
+// formatSymbols lists symbols as "kind name (container) path:line:column".
+func formatSymbols(symbols []lsp.Symbol, failures []string, workDir string) string {
+	var b strings.Builder
+	if len(symbols) == 0 {
+		b.WriteString("No symbols found.")
+	}
+	for i, s := range symbols {
+		if i == lspMaxResults {
+			fmt.Fprintf(&b, "\n[%d more symbols not shown]", len(symbols)-i)
+			break
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s %s", s.Kind, s.Name)
+		if s.ContainerName != "" {
+			fmt.Fprintf(&b, " (%s)", s.ContainerName)
+		}
+		fmt.Fprintf(&b, " %s:%d", displayPath(lsp.URIToPath(s.Location.URI), workDir), s.Location.Range.Start.Line+1)
+	}
+	for _, f := range failures {
+		fmt.Fprintf(&b, "\n[%s]", f)
+	}
+	return b.String()
+}
+
+// postEditDiagnostics returns the errors and warnings the language server
+// reports for path after an edit, formatted for appending to the tool
+// result, or "" when there are none or no server handles the file.
+func postEditDiagnostics(ctx context.Context, cfg ToolConfig, path string) string {
+	if cfg.LSP == nil {
+		return ""
+	}
+	if _, ok := cfg.LSP.ServerFor(path); !ok {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, postEditTimeout)
+	defer cancel()
+	c, err := cfg.LSP.ClientFor(ctx, path)
+	if err != nil {
+		return ""
+	}
+	diags, err := c.Diagnostics(ctx, path, postEditDiagnosticsWait)
+	if err != nil {
+		return ""
+	}
+	byPath := map[string][]lsp.Diagnostic{path: diags}
+	out := formatDiagnostics(byPath, cfg.WorkDir, lsp.SeverityWarning, postEditMaxDiagnostics)
+	if out == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n\nDiagnostics from %s:\n%s", c.Name(), out)
+}
+
+// withPostEditDiagnostics appends post-edit diagnostics to a successful
+// edit or write result.
+func withPostEditDiagnostics(ctx context.Context, cfg ToolConfig, call fantasy.ToolCall, resp fantasy.ToolResponse, err error) (fantasy.ToolResponse, error) {
+	if err != nil || resp.IsError || cfg.LSP == nil {
+		return resp, err
+	}
+	var args struct {
+		Path string `json:"path"`
+	}
+	if parseArgs(call.Input, &args) != nil || args.Path == "" {
+		return resp, err
+	}
+	absPath, pathErr := resolvePathWithWorkDir(args.Path, cfg.WorkDir)
+	if pathErr != nil {
+		return resp, err
+	}
+	resp.Content += postEditDiagnostics(ctx, cfg, absPath)
+	return resp, err
+}
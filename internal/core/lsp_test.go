@@ -0,0 +1,192 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/kit/internal/lsp"
+	"github.com/mark3labs/kit/internal/lsp/lsptest"
+)
+
+func TestMain(m *testing.M) {
+	lsptest.Main()
+	os.Exit(m.Run())
+}
+
+// newFakeLSP returns a manager running the fake language server for
+// ".fake" files in a fresh directory.
+func newFakeLSP(t *testing.T) (*lsp.Manager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	exe, args, env := lsptest.Command()
+	m, err := lsp.NewManager(dir, lsp.Config{"fake": {Command: exe, Args: args, Extensions: []string{".fake"}, Env: env}})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	t.Cleanup(m.Close)
+	return m, dir
+}
+
+func runTool(t *testing.T, tool fantasy.AgentTool, args map[string]any) fantasy.ToolResponse {
+	t.Helper()
+	input, _ := json.Marshal(args)
+	resp, err := tool.Run(context.Background(), fantasy.ToolCall{ID: "1", Name: tool.Info().Name, Input: string(input)})
+	if err != nil {
+		t.Fatalf("%s: %v", tool.Info().Name, err)
+	}
+	return resp
+}
+
+func TestLSPTools_Queries(t *testing.T) {
+	m, dir := newFakeLSP(t)
+	src := "func greet\nfunc main\n  greet()\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.fake"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := []ToolOption{WithWorkDir(dir), WithLSP(m)}
+	tools := make(map[string]fantasy.AgentTool)
+	for _, tool := range LSPTools(opts...) {
+		tools[tool.Info().Name] = tool
+	}
+	pos := map[string]any{"path": "main.fake", "line": 3, "symbol": "greet"}
+
+	resp := runTool(t, tools["definition"], pos)
+	if resp.IsError || resp.Content != "main.fake:1:6: func greet" {
+		t.Errorf("definition = %q", resp.Content)
+	}
+	resp = runTool(t, tools["references"], pos)
+	if !strings.HasPrefix(resp.Content, "2 references:\nmain.fake:1:6: func greet\nmain.fake:3:3: greet()") {
+		t.Errorf("references = %q", resp.Content)
+	}
+	resp = runTool(t, tools["hover"], pos)
+	if resp.Content != "**greet**" {
+		t.Errorf("hover = %q", resp.Content)
+	}
+	resp = runTool(t, tools["workspace_symbols"], map[string]any{"query": "main"})
+	if resp.Content != "function main main.fake:2" {
+		t.Errorf("workspace_symbols = %q", resp.Content)
+	}
+	resp = runTool(t, tools["rename_symbol"], map[string]any{"path": "main.fake", "line": 1, "symbol": "greet", "new_name": "hi"})
+	if resp.IsError || !strings.Contains(resp.Content, "2 edits in 1 files") {
+		t.Errorf("rename_symbol = %q", resp.Content)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "main.fake")); string(got) != "func hi\nfunc main\n  hi()\n" {
+		t.Errorf("after rename: %q", got)
+	}
+
+	resp = runTool(t, tools["hover"], map[string]any{"path": "main.fake", "line": 3, "symbol": "nope"})
+	if !resp.IsError || !strings.Contains(resp.Content, `"nope" not found on line 3`) {
+		t.Errorf("missing symbol = %q", resp.Content)
+	}
+	resp = runTool(t, tools["hover"], map[string]any{"path": "notes.txt", "line": 1, "symbol": "x"})
+	if !resp.IsError {
+		t.Errorf("expected an error for a file without a server: %q", resp.Content)
+	}
+}
+
+func TestRenameSymbol_FileGuard(t *testing.T) {
+	m, dir := newFakeLSP(t)
+	path := filepath.Join(dir, "main.fake")
+	src := "func greet\nfunc main\n  greet()\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewRenameSymbolTool(WithWorkDir(dir), WithLSP(m))
+	input, _ := json.Marshal(map[string]any{"path": "main.fake", "line": 1, "symbol": "greet", "new_name": "hi"})
+	call := fantasy.ToolCall{ID: "1", Name: "rename_symbol", Input: string(input)}
+
+	var guarded []string
+	ctx := WithFileGuard(context.Background(), func(_ context.Context, paths []string) error {
+		guarded = paths
+		return errors.New("denied by test")
+	})
+	resp, err := tool.Run(ctx, call)
+	if err != nil || !resp.IsError || !strings.Contains(resp.Content, "denied by test") {
+		t.Fatalf("guarded rename = %+v, %v", resp, err)
+	}
+	if len(guarded) != 1 || guarded[0] != path {
+		t.Errorf("guard saw %v, want [%s]", guarded, path)
+	}
+	if got, _ := os.ReadFile(path); string(got) != src {
+		t.Errorf("a denied rename wrote the file: %q", got)
+	}
+
+	// A workspace rooted elsewhere makes every edited file an outsider.
+	outside := NewRenameSymbolTool(WithWorkDir(t.TempDir()), WithLSP(m))
+	input, _ = json.Marshal(map[string]any{"path": path, "line": 1, "symbol": "greet", "new_name": "hi"})
+	resp, _ = outside.Run(context.Background(), fantasy.ToolCall{ID: "2", Name: "rename_symbol", Input: string(input)})
+	if !resp.IsError || !strings.Contains(resp.Content, "outside the workspace") {
+		t.Errorf("rename outside the workspace = %q", resp.Content)
+	}
+}
+
+func TestEditAndWrite_AppendDiagnostics(t *testing.T) {
+	m, dir := newFakeLSP(t)
+	opts := []ToolOption{WithWorkDir(dir), WithLSP(m)}
+
+	resp := runTool(t, NewWriteTool(opts...), map[string]any{"path": "main.fake", "content": "ok\n"})
+	if resp.IsError || strings.Contains(resp.Content, "Diagnostics") {
+		t.Errorf("clean write = %q", resp.Content)
+	}
+
+	resp = runTool(t, NewEditTool(opts...), map[string]any{"path": "main.fake", "edits": []map[string]any{
+		{"old_text": "ok\n", "new_text": "ok\nstill BAD\n"},
+	}})
+	if resp.IsError || !strings.HasSuffix(resp.Content, "\n\nDiagnostics from fake:\nmain.fake:2:7: error: bad line (fake)") {
+		t.Errorf("edit = %q", resp.Content)
+	}
+
+	resp = runTool(t, NewDiagnosticsTool(opts...), map[string]any{})
+	if resp.Content != "main.fake:2:7: error: bad line (fake)" {
+		t.Errorf("diagnostics = %q", resp.Content)
+	}
+
+	// Files no server handles are left alone.
+	resp = runTool(t, NewWriteTool(opts...), map[string]any{"path": "notes.txt", "content": "BAD\n"})
+	if strings.Contains(resp.Content, "Diagnostics") {
+		t.Errorf("unhandled write = %q", resp.Content)
+	}
+}
+
+func TestLSPTools_RequireManager(t *testing.T) {
+	for _, tool := range LSPTools() {
+		resp := runTool(t, tool, map[string]any{"path": "a.go", "line": 1, "symbol": "x", "query": "x", "new_name": "y"})
+		if !resp.IsError || !strings.Contains(resp.Content, "no language servers") {
+			t.Errorf("%s without a manager = %q", tool.Info().Name, resp.Content)
+		}
+	}
+}
+
+func TestSymbolPosition(t *testing.T) {
+	content := "first\n\tx := \"😀\" + x\r\n"
+	tests := []struct {
+		name   string
+		line   int
+		symbol string
+		column int
+		want   lsp.Position
+		errs   bool
+	}{
+		{name: "symbol", line: 1, symbol: "rst", want: lsp.Position{Line: 0, Character: 2}},
+		{name: "first occurrence", line: 2, symbol: "x", want: lsp.Position{Line: 1, Character: 1}},
+		// The emoji is one character but two UTF-16 units.
+		{name: "column after emoji", line: 2, symbol: "x", column: 13, want: lsp.Position{Line: 1, Character: 13}},
+		{name: "column without symbol", line: 2, column: 2, want: lsp.Position{Line: 1, Character: 1}},
+		{name: "missing symbol", line: 1, symbol: "zzz", errs: true},
+		{name: "line out of range", line: 9, symbol: "x", errs: true},
+		{name: "nothing to locate", line: 1, errs: true},
+	}
+	for _, tt := range tests {
+		got, err := symbolPosition(content, tt.line, tt.symbol, tt.column)
+		if (err != nil) != tt.errs || got != tt.want {
+			t.Errorf("%s: got %+v, %v", tt.name, got, err)
+		}
+	}
+}
@@ -0,0 +1,913 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"charm.land/fantasy"
+
+	udiff "github.com/aymanbagabas/go-udiff"
+)
+
+// PatchOp is the kind of change a FilePatch makes to its file.
+type PatchOp string
+
+const (
+	// PatchAdd creates a new file.
+	PatchAdd PatchOp = "add"
+	// PatchDelete removes an existing file.
+	PatchDelete PatchOp = "delete"
+	// PatchUpdate modifies, and optionally renames, an existing file.
+	PatchUpdate PatchOp = "update"
+)
+
+// FilePatch is the change a patch makes to a single file.
+type FilePatch struct {
+	Op PatchOp
+	// Path is the file the change applies to, as written in the patch.
+	Path string
+	// MoveTo is the new path of a renamed file, or empty.
+	MoveTo string
+	// Hunks are the changes to an updated file in file order. An added
+	// file has a single hunk holding its content as "+" lines.
+	Hunks []PatchHunk
+}
+
+// Paths returns every path the change touches: Path and, for renames,
+// MoveTo.
+func (p FilePatch) Paths() []string {
+	if p.MoveTo != "" && p.MoveTo != p.Path {
+		return []string{p.Path, p.MoveTo}
+	}
+	return []string{p.Path}
+}
+
+// PatchHunk is one contiguous change within a file.
+type PatchHunk struct {
+	// OldStart is the 1-based line of the original file the hunk starts
+	// at, taken from a unified diff header. Zero when unknown.
+	OldStart int
+	// Anchor is a line the hunk follows, from an "@@ anchor" header in the
+	// patch envelope format. Empty when absent.
+	Anchor string
+	// Lines is the hunk body; every line starts with ' ' (context), '-'
+	// (removed) or '+' (added).
+	Lines []string
+	// AtEOF anchors the hunk at the end of the file ("*** End of File").
+	AtEOF bool
+	// OldNoEOL and NewNoEOL record "\ No newline at end of file" markers
+	// on the old and new side of the hunk.
+	OldNoEOL, NewNoEOL bool
+}
+
+// OldText returns the context and removed lines of the hunk.
+func (h PatchHunk) OldText() string { return strings.Join(h.side('-'), "\n") }
+
+// NewText returns the context and added lines of the hunk.
+func (h PatchHunk) NewText() string { return strings.Join(h.side('+'), "\n") }
+
+// side returns the context lines plus the lines marked with kind.
+func (h PatchHunk) side(kind byte) []string {
+	var out []string
+	for _, l := range h.Lines {
+		if l[0] == ' ' || l[0] == kind {
+			out = append(out, l[1:])
+		}
+	}
+	return out
+}
+
+// ParsePatch parses a multi-file patch written either as a unified diff
+// (plain or git-style, with /dev/null for added and deleted files and
+// "rename from/to" headers) or in the "*** Begin Patch" envelope format.
+func ParsePatch(text string) ([]FilePatch, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	// Tolerate a patch wrapped in a markdown code fence.
+	if len(lines) > 1 && strings.HasPrefix(lines[0], "```") && strings.TrimSpace(lines[len(lines)-1]) == "```" {
+		lines = lines[1 : len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("patch is empty")
+	}
+
+	var (
+		files []FilePatch
+		err   error
+	)
+	if strings.TrimSpace(lines[0]) == "*** Begin Patch" {
+		files, err = parseEnvelope(lines)
+	} else {
+		files, err = parseUnified(lines)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("patch contains no file changes")
+	}
+	return files, nil
+}
+
+// hunkHeaderRe matches a unified diff hunk header.
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parseEnvelope parses the "*** Begin Patch" ... "*** End Patch" format.
+func parseEnvelope(lines []string) ([]FilePatch, error) {
+	var files []FilePatch
+	for i := 1; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "*** End Patch":
+			return files, nil
+
+		case strings.HasPrefix(line, "*** Add File: "):
+			fp := FilePatch{Op: PatchAdd, Path: strings.TrimSpace(strings.TrimPrefix(line, "*** Add File: "))}
+			var h PatchHunk
+			for i++; i < len(lines) && !strings.HasPrefix(lines[i], "*** "); i++ {
+				if !strings.HasPrefix(lines[i], "+") {
+					return nil, fmt.Errorf("patch line %d: lines of added file %s must start with +", i+1, fp.Path)
+				}
+				h.Lines = append(h.Lines, lines[i])
+			}
+			fp.Hunks = []PatchHunk{h}
+			files = append(files, fp)
+
+		case strings.HasPrefix(line, "*** Delete File: "):
+			files = append(files, FilePatch{Op: PatchDelete, Path: strings.TrimSpace(strings.TrimPrefix(line, "*** Delete File: "))})
+			i++
+
+		case strings.HasPrefix(line, "*** Update File: "):
+			fp := FilePatch{Op: PatchUpdate, Path: strings.TrimSpace(strings.TrimPrefix(line, "*** Update File: "))}
+			i++
+			if i < len(lines) && strings.HasPrefix(lines[i], "*** Move to: ") {
+				fp.MoveTo = strings.TrimSpace(strings.TrimPrefix(lines[i], "*** Move to: "))
+				i++
+			}
+			for ; i < len(lines); i++ {
+				l := lines[i]
+				if strings.TrimSpace(l) == "*** End of File" {
+					if len(fp.Hunks) == 0 {
+						return nil, fmt.Errorf("patch line %d: *** End of File outside a hunk", i+1)
+					}
+					fp.Hunks[len(fp.Hunks)-1].AtEOF = true
+					continue
+				}
+				if strings.HasPrefix(l, "*** ") {
+					break
+				}
+				if strings.HasPrefix(l, "@@") {
+					h := PatchHunk{}
+					if m := hunkHeaderRe.FindStringSubmatch(l); m != nil {
+						h.OldStart = hunkOldStart(m)
+					} else {
+						h.Anchor = strings.TrimSpace(strings.TrimPrefix(l, "@@"))
+					}
+					fp.Hunks = append(fp.Hunks, h)
+					continue
+				}
+				if len(fp.Hunks) == 0 {
+					fp.Hunks = append(fp.Hunks, PatchHunk{})
+				}
+				h := &fp.Hunks[len(fp.Hunks)-1]
+				switch {
+				case l == "":
+					// Editors strip the lone space of blank context lines.
+					h.Lines = append(h.Lines, " ")
+				case l[0] == ' ' || l[0] == '-' || l[0] == '+':
+					h.Lines = append(h.Lines, l)
+				default:
+					return nil, fmt.Errorf("patch line %d: unexpected line %q in update of %s; hunk lines must start with ' ', '-' or '+'", i+1, l, fp.Path)
+				}
+			}
+			for n, h := range fp.Hunks {
+				if len(h.Lines) == 0 {
+					return nil, fmt.Errorf("update of %s: hunk %d is empty", fp.Path, n+1)
+				}
+			}
+			if len(fp.Hunks) == 0 && fp.MoveTo == "" {
+				return nil, fmt.Errorf("update of %s has no changes", fp.Path)
+			}
+			files = append(files, fp)
+
+		case strings.TrimSpace(line) == "":
+			i++
+
+		default:
+			return nil, fmt.Errorf("patch line %d: expected *** Add File, *** Delete File, *** Update File or *** End Patch, got %q", i+1, line)
+		}
+	}
+	return nil, errors.New("patch is missing *** End Patch")
+}
+
+// unifiedFile collects the headers and hunks of one file in a unified diff.
+type unifiedFile struct {
+	oldPath, newPath     string
+	gitOld, gitNew       string
+	renameFrom, renameTo string
+	isNew, isDeleted     bool
+	hunks                []PatchHunk
+}
+
+// parseUnified parses a unified diff. Text outside file headers and hunks,
+// such as commit messages and "index" lines, is ignored.
+func parseUnified(lines []string) ([]FilePatch, error) {
+	var (
+		files []FilePatch
+		cur   *unifiedFile
+	)
+	flush := func() error {
+		if cur == nil {
+			return nil
+		}
+		fp, ok, err := cur.filePatch()
+		if err != nil {
+			return err
+		}
+		if ok {
+			files = append(files, fp)
+		}
+		cur = nil
+		return nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			cur = &unifiedFile{}
+			cur.gitOld, cur.gitNew = gitHeaderPaths(strings.TrimPrefix(line, "diff --git "))
+		case cur != nil && len(cur.hunks) == 0 && strings.HasPrefix(line, "rename from "):
+			cur.renameFrom = unquotePath(strings.TrimPrefix(line, "rename from "))
+		case cur != nil && len(cur.hunks) == 0 && strings.HasPrefix(line, "rename to "):
+			cur.renameTo = unquotePath(strings.TrimPrefix(line, "rename to "))
+		case cur != nil && len(cur.hunks) == 0 && strings.HasPrefix(line, "new file mode"):
+			cur.isNew = true
+		case cur != nil && len(cur.hunks) == 0 && strings.HasPrefix(line, "deleted file mode"):
+			cur.isDeleted = true
+		case strings.HasPrefix(line, "GIT binary patch"), strings.HasPrefix(line, "Binary files "):
+			return nil, fmt.Errorf("patch line %d: binary patches are not supported", i+1)
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if cur == nil || len(cur.hunks) > 0 || cur.oldPath != "" {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+				cur = &unifiedFile{}
+			}
+			cur.oldPath = diffHeaderPath(strings.TrimPrefix(line, "--- "))
+			cur.newPath = diffHeaderPath(strings.TrimPrefix(lines[i+1], "+++ "))
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("patch line %d: hunk without a file header", i+1)
+			}
+			m := hunkHeaderRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("patch line %d: malformed hunk header %q", i+1, line)
+			}
+			h := PatchHunk{OldStart: hunkOldStart(m)}
+			oldLeft, newLeft := hunkCount(m[2]), hunkCount(m[4])
+			for i+1 < len(lines) {
+				l := lines[i+1]
+				if strings.HasPrefix(l, `\`) {
+					if len(h.Lines) > 0 {
+						switch h.Lines[len(h.Lines)-1][0] {
+						case '-':
+							h.OldNoEOL = true
+						case '+':
+							h.NewNoEOL = true
+						default:
+							h.OldNoEOL, h.NewNoEOL = true, true
+						}
+					}
+					i++
+					continue
+				}
+				if oldLeft <= 0 && newLeft <= 0 {
+					break
+				}
+				switch {
+				case l == "" || l[0] == ' ':
+					h.Lines = append(h.Lines, " "+strings.TrimPrefix(l, " "))
+					oldLeft--
+					newLeft--
+				case l[0] == '-':
+					h.Lines = append(h.Lines, l)
+					oldLeft--
+				case l[0] == '+':
+					h.Lines = append(h.Lines, l)
+					newLeft--
+				default:
+					return nil, fmt.Errorf("patch line %d: hunk is shorter than its header says", i+2)
+				}
+				i++
+			}
+			if oldLeft > 0 || newLeft > 0 {
+				// Trailing blank context lines are often lost when a patch
+				// is trimmed; restore them when the counts agree.
+				if oldLeft != newLeft {
+					return nil, fmt.Errorf("patch line %d: hunk is truncated", i+1)
+				}
+				for ; oldLeft > 0; oldLeft-- {
+					h.Lines = append(h.Lines, " ")
+				}
+			}
+			cur.hunks = append(cur.hunks, h)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// filePatch converts the collected headers into a FilePatch. ok is false
+// for entries that change nothing applicable, such as mode-only changes.
+func (u *unifiedFile) filePatch() (FilePatch, bool, error) {
+	oldPath, newPath := u.oldPath, u.newPath
+	if oldPath == "" && newPath == "" {
+		oldPath, newPath = u.gitOld, u.gitNew
+		if u.isNew {
+			oldPath = "/dev/null"
+		}
+		if u.isDeleted {
+			newPath = "/dev/null"
+		}
+	}
+	// Strip the a/ and b/ prefixes git adds, and that diff -u output
+	// produced by other tools often mimics.
+	if (oldPath == "/dev/null" || strings.HasPrefix(oldPath, "a/")) &&
+		(newPath == "/dev/null" || strings.HasPrefix(newPath, "b/")) {
+		oldPath = strings.TrimPrefix(oldPath, "a/")
+		newPath = strings.TrimPrefix(newPath, "b/")
+	}
+	if u.renameFrom != "" {
+		oldPath = u.renameFrom
+	}
+	if u.renameTo != "" {
+		newPath = u.renameTo
+	}
+
+	switch {
+	case oldPath == "/dev/null" || u.isNew:
+		if newPath == "" || newPath == "/dev/null" {
+			return FilePatch{}, false, errors.New("added file is missing its name")
+		}
+		h := PatchHunk{}
+		for _, uh := range u.hunks {
+			for _, l := range uh.Lines {
+				if l[0] == '+' {
+					h.Lines = append(h.Lines, l)
+				}
+			}
+			h.NewNoEOL = h.NewNoEOL || uh.NewNoEOL
+		}
+		return FilePatch{Op: PatchAdd, Path: newPath, Hunks: []PatchHunk{h}}, true, nil
+	case newPath == "/dev/null" || u.isDeleted:
+		if oldPath == "" || oldPath == "/dev/null" {
+			return FilePatch{}, false, errors.New("deleted file is missing its name")
+		}
+		return FilePatch{Op: PatchDelete, Path: oldPath}, true, nil
+	}
+	if oldPath == "" {
+		return FilePatch{}, false, errors.New("diff is missing its --- file header")
+	}
+	fp := FilePatch{Op: PatchUpdate, Path: oldPath, Hunks: u.hunks}
+	if newPath != "" && newPath != oldPath {
+		fp.MoveTo = newPath
+	}
+	if len(fp.Hunks) == 0 && fp.MoveTo == "" {
+		return FilePatch{}, false, nil
+	}
+	return fp, true, nil
+}
+
+// hunkOldStart returns the 1-based line a unified hunk header points at.
+// For pure insertions ("-5,0") the header names the line before the
+// insertion point.
+func hunkOldStart(m []string) int {
+	start, _ := strconv.Atoi(m[1])
+	if hunkCount(m[2]) == 0 {
+		return start + 1
+	}
+	return start
+}
+
+// hunkCount parses an optional hunk line count, which defaults to 1.
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// diffHeaderPath extracts the path from a ---/+++ header value, dropping
+// the timestamp diff -u appends after a tab.
+func diffHeaderPath(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	return unquotePath(strings.TrimSpace(s))
+}
+
+// gitHeaderPaths splits the "a/x b/x" operands of a diff --git line.
+func gitHeaderPaths(s string) (string, string) {
+	if strings.HasPrefix(s, `"`) {
+		if old, err := strconv.QuotedPrefix(s); err == nil {
+			return unquotePath(old), unquotePath(strings.TrimSpace(s[len(old):]))
+		}
+	}
+	if i := strings.Index(s, " b/"); i >= 0 {
+		return s[:i], unquotePath(s[i+1:])
+	}
+	return "", ""
+}
+
+// unquotePath undoes git's C-style quoting of unusual file names.
+func unquotePath(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, `"`) {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	return s
+}
+
+// ---------------------------------------------------------------------------
+// Applying patches
+// ---------------------------------------------------------------------------
+
+// appliedHunk is a hunk as it matched the file.
+type appliedHunk struct {
+	start   int // 1-based line in the file before the hunk was applied
+	oldText string
+	newText string
+	added   int
+	removed int
+	fuzzy   bool
+}
+
+// fileChange is the planned effect of one FilePatch on disk.
+type fileChange struct {
+	patch  FilePatch
+	path   string // absolute path of the file as it exists now
+	dest   string // absolute path after the change; equals path unless moved
+	before string
+	after  string
+	mode   os.FileMode
+	hunks  []appliedHunk
+}
+
+func (c fileChange) moved() bool { return c.dest != c.path }
+
+// applyPatchArgs holds the arguments for the apply_patch tool.
+type applyPatchArgs struct {
+	Patch string `json:"patch"`
+}
+
+// NewApplyPatchTool creates the apply_patch core tool.
+func NewApplyPatchTool(opts ...ToolOption) fantasy.AgentTool {
+	cfg := ApplyOptions(opts)
+	return &coreTool{
+		info: fantasy.ToolInfo{
+			Name: "apply_patch",
+			Description: `Apply a patch that adds, deletes, renames or modifies one or more files. Either nothing or the whole patch is applied.
+
+Accepts a unified diff (as produced by diff -u or git diff, using /dev/null for added and deleted files) or the envelope format:
+
+*** Begin Patch
+*** Add File: path/new.txt
++content line
+*** Delete File: path/old.txt
+*** Update File: path/file.go
+*** Move to: path/renamed.go
+@@ func example() {
+ context line
+-removed line
++added line
+*** End Patch
+
+Hunks need about 3 lines of unchanged context around each change. In the envelope format an "@@ text" header names a line the hunk follows, and "*** End of File" anchors a hunk at the end of the file. Paths are relative to the working directory.`,
+			Parameters: map[string]any{
+				"patch": map[string]any{
+					"type":        "string",
+					"description": "The full patch text",
+				},
+			},
+			Required: []string{"patch"},
+		},
+		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			resp, changes, err := executeApplyPatch(ctx, call, cfg.WorkDir, cfg.fileSystem())
+			if err != nil || resp.IsError {
+				return resp, err
+			}
+			for _, c := range changes {
+				if c.patch.Op != PatchDelete {
+					resp.Content += postEditDiagnostics(ctx, cfg, c.dest)
+				}
+			}
+			return resp, nil
+		},
+	}
+}
+
+func executeApplyPatch(ctx context.Context, call fantasy.ToolCall, workDir string, fs FileSystem) (fantasy.ToolResponse, []fileChange, error) {
+	if err := ctx.Err(); err != nil {
+		return fantasy.ToolResponse{}, nil, err
+	}
+	var args applyPatchArgs
+	if err := parseArgs(call.Input, &args); err != nil {
+		return fantasy.NewTextErrorResponse("failed to parse arguments: " + err.Error()), nil, nil
+	}
+	if strings.TrimSpace(args.Patch) == "" {
+		return fantasy.NewTextErrorResponse("patch parameter is required"), nil, nil
+	}
+
+	patches, err := ParsePatch(args.Patch)
+	if err != nil {
+		return fantasy.NewTextErrorResponse("invalid patch: " + err.Error()), nil, nil
+	}
+	changes, err := planPatch(ctx, fs, patches, workDir)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error() + "\nNo files were changed."), nil, nil
+	}
+	if err := commitPatch(ctx, fs, changes); err != nil {
+		return fantasy.NewTextErrorResponse(err.Error() + "\nNo files were changed."), nil, nil
+	}
+
+	resp := fantasy.NewTextResponse(patchSummary(changes))
+	return fantasy.WithResponseMetadata(resp, patchDiffMeta(changes)), changes, nil
+}
+
+// planPatch resolves every file change in memory without touching the
+// disk, so a patch that fails anywhere fails before anything is written.
+// File contents are read through fs.
+func planPatch(ctx context.Context, fs FileSystem, patches []FilePatch, workDir string) ([]fileChange, error) {
+	touched := make(map[string]bool)
+	claim := func(abs, display string) error {
+		if touched[abs] {
+			return fmt.Errorf("%s is changed more than once by the patch; combine the changes into one section", display)
+		}
+		touched[abs] = true
+		return nil
+	}
+
+	changes := make([]fileChange, 0, len(patches))
+	for _, p := range patches {
+		abs, err := resolvePathWithWorkDir(p.Path, workDir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %s: %v", p.Path, err)
+		}
+		if err := claim(abs, p.Path); err != nil {
+			return nil, err
+		}
+		c := fileChange{patch: p, path: abs, dest: abs, mode: 0o644}
+
+		if p.Op == PatchAdd {
+			if _, err := os.Lstat(abs); err == nil {
+				return nil, fmt.Errorf("cannot add %s: file already exists", p.Path)
+			}
+			h := p.Hunks[0]
+			if lines := h.side('+'); len(lines) > 0 {
+				c.after = strings.Join(lines, "\n")
+				if !h.NewNoEOL {
+					c.after += "\n"
+				}
+			}
+			changes = append(changes, c)
+			continue
+		}
+
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("cannot %s %s: %v", p.Op, p.Path, err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("cannot %s %s: not a regular file", p.Op, p.Path)
+		}
+		content, err := fs.ReadFile(ctx, abs)
+		if err != nil {
+			return nil, fmt.Errorf("cannot %s %s: %v", p.Op, p.Path, err)
+		}
+		c.before, c.mode = string(content), info.Mode().Perm()
+
+		if p.Op == PatchDelete {
+			changes = append(changes, c)
+			continue
+		}
+
+		c.after, c.hunks, err = applyHunks(c.before, p.Hunks)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", p.Path, err)
+		}
+		if p.MoveTo != "" {
+			dest, err := resolvePathWithWorkDir(p.MoveTo, workDir)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %s: %v", p.MoveTo, err)
+			}
+			if dest != abs {
+				if _, err := os.Lstat(dest); err == nil {
+					return nil, fmt.Errorf("cannot move %s to %s: destination already exists", p.Path, p.MoveTo)
+				}
+				if err := claim(dest, p.MoveTo); err != nil {
+					return nil, err
+				}
+				c.dest = dest
+			}
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// applyHunks applies hunks in order to content. Each hunk is located at or
+// after the end of the previous one: exactly first, then with the edit
+// tool's fuzzy normalization, then ignoring indentation. Line endings and
+// the lines a hunk only uses as context keep the file's own form.
+func applyHunks(content string, hunks []PatchHunk) (string, []appliedHunk, error) {
+	crlf := strings.Contains(content, "\r\n")
+	norm := strings.ReplaceAll(content, "\r\n", "\n")
+	trailingNewline := norm == "" || strings.HasSuffix(norm, "\n")
+	var lines []string
+	if norm != "" {
+		lines = strings.Split(strings.TrimSuffix(norm, "\n"), "\n")
+	}
+
+	var applied []appliedHunk
+	cursor, delta := 0, 0
+	for n, h := range hunks {
+		old := h.side('-')
+		if h.Anchor != "" {
+			idx, _ := seekLines(lines, []string{h.Anchor}, cursor, -1, false)
+			if idx < 0 {
+				return "", nil, fmt.Errorf("hunk %d: could not find the line %q", n+1, h.Anchor)
+			}
+			cursor = idx + 1
+		}
+		hint := -1
+		if h.OldStart > 0 {
+			hint = h.OldStart - 1 + delta
+		}
+
+		var pos, tier int
+		switch {
+		case len(old) > 0:
+			pos, tier = seekLines(lines, old, cursor, hint, h.AtEOF)
+			if pos < 0 {
+				return "", nil, fmt.Errorf("hunk %d does not match the file; its context and removed lines must match the current content:\n%s",
+					n+1, strings.Join(old, "\n"))
+			}
+		case hint >= 0:
+			pos = min(max(hint, cursor), len(lines))
+		case h.Anchor != "" && !h.AtEOF:
+			pos = cursor
+		default:
+			pos = len(lines)
+		}
+
+		// Build the replacement, keeping the file's text for context lines.
+		var repl []string
+		ah := appliedHunk{start: pos + 1, oldText: strings.Join(lines[pos:pos+len(old)], "\n"), fuzzy: tier > 0}
+		j := pos
+		for _, l := range h.Lines {
+			switch l[0] {
+			case ' ':
+				repl = append(repl, lines[j])
+				j++
+			case '-':
+				ah.removed++
+				j++
+			case '+':
+				repl = append(repl, l[1:])
+				ah.added++
+			}
+		}
+		ah.newText = strings.Join(repl, "\n")
+		applied = append(applied, ah)
+
+		if pos+len(old) == len(lines) {
+			if h.NewNoEOL {
+				trailingNewline = false
+			} else if h.OldNoEOL {
+				trailingNewline = true
+			}
+		}
+		lines = slices.Concat(lines[:pos], repl, lines[pos+len(old):])
+		cursor = pos + len(repl)
+		delta += len(repl) - len(old)
+	}
+
+	out := strings.Join(lines, "\n")
+	if trailingNewline && len(lines) > 0 {
+		out += "\n"
+	}
+	if crlf {
+		out = strings.ReplaceAll(out, "\n", "\r\n")
+	}
+	return out, applied, nil
+}
+
+// lineMatchers are the increasingly lenient comparisons applyHunks tries.
+var lineMatchers = []func(string) string{
+	func(s string) string { return s },
+	normalizeForFuzzy,
+	func(s string) string { return strings.TrimSpace(normalizeForFuzzy(s)) },
+}
+
+// seekLines finds want in lines at or after start and returns its index and
+// the matcher tier that found it, or -1. With a hint the occurrence closest
+// to it wins; otherwise the first does. atEOF prefers a match ending at the
+// last line.
+func seekLines(lines, want []string, start, hint int, atEOF bool) (int, int) {
+	for tier, norm := range lineMatchers {
+		w := make([]string, len(want))
+		for i, s := range want {
+			w[i] = norm(s)
+		}
+		matchAt := func(pos int) bool {
+			for i := range w {
+				if norm(lines[pos+i]) != w[i] {
+					return false
+				}
+			}
+			return true
+		}
+		if atEOF {
+			if pos := len(lines) - len(w); pos >= start && matchAt(pos) {
+				return pos, tier
+			}
+		}
+		best := -1
+		for pos := start; pos+len(w) <= len(lines); pos++ {
+			if !matchAt(pos) {
+				continue
+			}
+			if hint < 0 {
+				return pos, tier
+			}
+			if best < 0 || lineDistance(pos, hint) < lineDistance(best, hint) {
+				best = pos
+			} else if pos > hint {
+				break
+			}
+		}
+		if best >= 0 {
+			return best, tier
+		}
+	}
+	return -1, 0
+}
+
+// lineDistance returns how many lines apart a and b are.
+func lineDistance(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// commitPatch writes the planned changes in order through fs. If any write
+// fails, the changes already made are undone so the patch applies all or
+// nothing.
+func commitPatch(ctx context.Context, fs FileSystem, changes []fileChange) error {
+	var undo []func()
+	fail := func(err error) error {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+		return err
+	}
+	restore := func(c fileChange) func() {
+		return func() { _ = writeFileMode(ctx, fs, c.path, []byte(c.before), c.mode) }
+	}
+
+	for _, c := range changes {
+		if c.patch.Op == PatchDelete {
+			if err := os.Remove(c.path); err != nil {
+				return fail(fmt.Errorf("failed to delete %s: %v", c.patch.Path, err))
+			}
+			undo = append(undo, restore(c))
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(c.dest), 0o755); err != nil {
+			return fail(fmt.Errorf("failed to create directories: %v", err))
+		}
+		if err := writeFileMode(ctx, fs, c.dest, []byte(c.after), c.mode); err != nil {
+			return fail(fmt.Errorf("failed to write %s: %v", c.dest, err))
+		}
+		if c.patch.Op == PatchAdd || c.moved() {
+			dest := c.dest
+			undo = append(undo, func() { _ = os.Remove(dest) })
+		} else {
+			undo = append(undo, restore(c))
+		}
+		if c.moved() {
+			if err := os.Remove(c.path); err != nil {
+				return fail(fmt.Errorf("failed to remove %s: %v", c.patch.Path, err))
+			}
+			undo = append(undo, restore(c))
+		}
+	}
+	return nil
+}
+
+// patchSummary lists the changed files followed by a unified diff of every
+// modified file.
+func patchSummary(changes []fileChange) string {
+	var b, diffs strings.Builder
+	fuzzy := 0
+	for _, c := range changes {
+		for _, h := range c.hunks {
+			if h.fuzzy {
+				fuzzy++
+			}
+		}
+	}
+	noun := "files"
+	if len(changes) == 1 {
+		noun = "file"
+	}
+	fmt.Fprintf(&b, "Applied patch to %d %s", len(changes), noun)
+	if fuzzy > 0 {
+		fmt.Fprintf(&b, " (%d fuzzy hunks)", fuzzy)
+	}
+	b.WriteString(":")
+
+	for _, c := range changes {
+		switch {
+		case c.patch.Op == PatchAdd:
+			fmt.Fprintf(&b, "\nA %s", c.patch.Path)
+		case c.patch.Op == PatchDelete:
+			fmt.Fprintf(&b, "\nD %s", c.patch.Path)
+		case c.moved():
+			fmt.Fprintf(&b, "\nR %s -> %s", c.patch.Path, c.patch.MoveTo)
+		default:
+			fmt.Fprintf(&b, "\nM %s", c.patch.Path)
+		}
+		if c.patch.Op == PatchUpdate {
+			to := c.patch.Path
+			if c.moved() {
+				to = c.patch.MoveTo
+			}
+			before := strings.ReplaceAll(c.before, "\r\n", "\n")
+			after := strings.ReplaceAll(c.after, "\r\n", "\n")
+			diffs.WriteString(udiff.Unified(c.patch.Path, to, before, after))
+		}
+	}
+	if diffs.Len() > 0 {
+		b.WriteString("\n\n")
+		b.WriteString(strings.TrimRight(diffs.String(), "\n"))
+	}
+	return b.String()
+}
+
+// patchDiffMeta builds the file_diffs metadata for an applied patch, one
+// entry per file in the same shape the edit and write tools produce.
+func patchDiffMeta(changes []fileChange) map[string]any {
+	entries := make([]map[string]any, 0, len(changes))
+	for _, c := range changes {
+		switch c.patch.Op {
+		case PatchAdd:
+			entries = append(entries, fileDiffEntry(c.dest, "", c.after, true))
+		case PatchDelete:
+			entry := fileDiffEntry(c.path, c.before, "", false)
+			entry["additions"] = 0
+			entry["is_deleted"] = true
+			entries = append(entries, entry)
+		default:
+			var blocks []map[string]any
+			additions, deletions := 0, 0
+			for _, h := range c.hunks {
+				blocks = append(blocks, map[string]any{"old_text": h.oldText, "new_text": h.newText})
+				additions += h.added
+				deletions += h.removed
+			}
+			entry := map[string]any{
+				"path":        c.dest,
+				"additions":   additions,
+				"deletions":   deletions,
+				"diff_blocks": blocks,
+			}
+			if c.moved() {
+				entry["old_path"] = c.path
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return map[string]any{"file_diffs": entries}
+}
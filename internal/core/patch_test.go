@@ -0,0 +1,317 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePatch_Unified(t *testing.T) {
+	patch := `--- plain.txt	2024-01-01 00:00:00
++++ plain.txt	2024-01-02 00:00:00
+@@ -5,0 +6 @@
++appended
+From 1234 Mon Sep 17 00:00:00 2001
+Subject: [PATCH] example
+
+diff --git a/main.go b/main.go
+index 83db48f..bf269f4 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@ package main
+ package main
+-var x = 1
++var x = 2
+
+diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++hello
++world
+\ No newline at end of file
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/old name.txt b/renamed.txt
+similarity index 100%
+rename from old name.txt
+rename to renamed.txt
+`
+	files, err := ParsePatch(patch)
+	if err != nil {
+		t.Fatalf("ParsePatch: %v", err)
+	}
+	if len(files) != 5 {
+		t.Fatalf("got %d files: %+v", len(files), files)
+	}
+
+	// A pure insertion after line 5 starts at line 6.
+	if plain := files[0]; plain.Path != "plain.txt" || plain.Hunks[0].OldStart != 6 {
+		t.Errorf("plain diff = %+v", plain)
+	}
+	mod := files[1]
+	if mod.Op != PatchUpdate || mod.Path != "main.go" || len(mod.Hunks) != 1 {
+		t.Errorf("modify = %+v", mod)
+	}
+	// The blank line after the hunk is a stripped context line.
+	if h := mod.Hunks[0]; h.OldStart != 1 || h.OldText() != "package main\nvar x = 1\n" || h.NewText() != "package main\nvar x = 2\n" {
+		t.Errorf("modify hunk = %+v", h)
+	}
+	if add := files[2]; add.Op != PatchAdd || add.Path != "new.txt" || add.Hunks[0].NewText() != "hello\nworld" || !add.Hunks[0].NewNoEOL {
+		t.Errorf("add = %+v", add)
+	}
+	if del := files[3]; del.Op != PatchDelete || del.Path != "gone.txt" {
+		t.Errorf("delete = %+v", del)
+	}
+	if mv := files[4]; mv.Op != PatchUpdate || mv.Path != "old name.txt" || mv.MoveTo != "renamed.txt" || len(mv.Hunks) != 0 {
+		t.Errorf("rename = %+v", mv)
+	}
+}
+
+func TestParsePatch_Envelope(t *testing.T) {
+	patch := "```\n*** Begin Patch\n" +
+		"*** Add File: docs/new.md\n+# Title\n+\n" +
+		"*** Delete File: old.txt\n" +
+		"*** Update File: src/app.py\n*** Move to: src/main.py\n" +
+		"@@ def run():\n-    pass\n+    return 1\n" +
+		"@@\n last\n\n+tail\n*** End of File\n" +
+		"*** End Patch\n```"
+	files, err := ParsePatch(patch)
+	if err != nil {
+		t.Fatalf("ParsePatch: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("got %d files", len(files))
+	}
+	if add := files[0]; add.Op != PatchAdd || add.Hunks[0].NewText() != "# Title\n" {
+		t.Errorf("add = %+v", add)
+	}
+	if files[1].Op != PatchDelete || files[1].Path != "old.txt" {
+		t.Errorf("delete = %+v", files[1])
+	}
+	up := files[2]
+	if up.Path != "src/app.py" || up.MoveTo != "src/main.py" || len(up.Hunks) != 2 {
+		t.Fatalf("update = %+v", up)
+	}
+	if up.Hunks[0].Anchor != "def run():" || up.Hunks[1].Anchor != "" || !up.Hunks[1].AtEOF {
+		t.Errorf("hunks = %+v", up.Hunks)
+	}
+	if got := up.Hunks[1].OldText(); got != "last\n" {
+		t.Errorf("blank context line lost: %q", got)
+	}
+	if got := strings.Join(up.Paths(), ","); got != "src/app.py,src/main.py" {
+		t.Errorf("Paths() = %s", got)
+	}
+}
+
+func TestParsePatch_Errors(t *testing.T) {
+	for name, patch := range map[string]string{
+		"empty":          "  \n",
+		"no changes":     "just some text\n",
+		"missing end":    "*** Begin Patch\n*** Delete File: a\n",
+		"bad add line":   "*** Begin Patch\n*** Add File: a\nno plus\n*** End Patch",
+		"bad hunk line":  "*** Begin Patch\n*** Update File: a\n@@\n?x\n*** End Patch",
+		"empty update":   "*** Begin Patch\n*** Update File: a\n*** End Patch",
+		"truncated hunk": "--- a/x\n+++ b/x\n@@ -1,3 +1,2 @@\n-a\n+b\n",
+		"orphan hunk":    "@@ -1 +1 @@\n-a\n+b\n",
+		"binary":         "diff --git a/x b/x\nGIT binary patch\n",
+	} {
+		if _, err := ParsePatch(patch); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestApplyHunks(t *testing.T) {
+	hunk := func(start int, lines ...string) PatchHunk { return PatchHunk{OldStart: start, Lines: lines} }
+	tests := []struct {
+		name    string
+		content string
+		hunks   []PatchHunk
+		want    string
+		fuzzy   bool
+	}{
+		{
+			name:    "sequential hunks",
+			content: "a\nb\nc\nd\n",
+			hunks:   []PatchHunk{hunk(0, " a", "-b", "+B"), hunk(0, " c", "+c2", " d")},
+			want:    "a\nB\nc\nc2\nd\n",
+		},
+		{
+			name:    "line hint picks the closest duplicate",
+			content: "x\ny\nx\ny\n",
+			hunks:   []PatchHunk{hunk(3, " x", "-y", "+z")},
+			want:    "x\ny\nx\nz\n",
+		},
+		{
+			name:    "fuzzy context keeps the file's text",
+			content: "\tif ok {  \n\t\treturn “yes”\n\t}\n",
+			hunks:   []PatchHunk{hunk(0, " if ok {", "-    return \"yes\"", "+\t\treturn \"no\"", " }")},
+			want:    "\tif ok {  \n\t\treturn \"no\"\n\t}\n",
+			fuzzy:   true,
+		},
+		{
+			name:    "crlf is preserved",
+			content: "one\r\ntwo\r\n",
+			hunks:   []PatchHunk{hunk(1, " one", "-two", "+2")},
+			want:    "one\r\n2\r\n",
+		},
+		{
+			name:    "pure insertion at hint",
+			content: "a\nb\n",
+			hunks:   []PatchHunk{hunk(2, "+mid")},
+			want:    "a\nmid\nb\n",
+		},
+		{
+			name:    "no newline markers",
+			content: "a\nb",
+			hunks:   []PatchHunk{{OldStart: 2, Lines: []string{"-b", "+c"}, OldNoEOL: true}},
+			want:    "a\nc\n",
+		},
+		{
+			name:    "anchor and end of file",
+			content: "x\nend\nfunc f\nend\n",
+			hunks:   []PatchHunk{{Anchor: "func f", Lines: []string{" end", "+more"}, AtEOF: true}},
+			want:    "x\nend\nfunc f\nend\nmore\n",
+		},
+	}
+	for _, tt := range tests {
+		got, applied, err := applyHunks(tt.content, tt.hunks)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+		if applied[0].fuzzy != tt.fuzzy {
+			t.Errorf("%s: fuzzy = %v", tt.name, applied[0].fuzzy)
+		}
+	}
+
+	if _, _, err := applyHunks("a\nb\n", []PatchHunk{hunk(0, " a", "-zzz")}); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected a mismatch error, got %v", err)
+	}
+}
+
+func TestApplyPatchTool_MultiFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFileOrFail(t, filepath.Join(dir, "main.go"), "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n")
+	writeFileOrFail(t, filepath.Join(dir, "old.txt"), "bye\n")
+	writeFileOrFail(t, filepath.Join(dir, "a.txt"), "keep\nchange\n")
+
+	patch := `*** Begin Patch
+*** Update File: main.go
+@@ func main() {
+-	println("hi")
++	println("hello")
+*** Add File: pkg/new.go
++package pkg
+*** Delete File: old.txt
+*** Update File: a.txt
+*** Move to: b.txt
+ keep
+-change
++changed
+*** End Patch`
+	resp := runTool(t, NewApplyPatchTool(WithWorkDir(dir)), map[string]any{"patch": patch})
+	if resp.IsError {
+		t.Fatalf("apply_patch: %s", resp.Content)
+	}
+	for _, want := range []string{"Applied patch to 4 files:", "M main.go", "A pkg/new.go", "D old.txt", "R a.txt -> b.txt", "+\tprintln(\"hello\")"} {
+		if !strings.Contains(resp.Content, want) {
+			t.Errorf("result missing %q:\n%s", want, resp.Content)
+		}
+	}
+
+	files := map[string]string{
+		"main.go":    "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"pkg/new.go": "package pkg\n",
+		"b.txt":      "keep\nchanged\n",
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"old.txt", "a.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be gone: %v", name, err)
+		}
+	}
+
+	var meta struct {
+		FileDiffs []struct {
+			Path       string `json:"path"`
+			OldPath    string `json:"old_path"`
+			Additions  int    `json:"additions"`
+			Deletions  int    `json:"deletions"`
+			IsNew      bool   `json:"is_new"`
+			IsDeleted  bool   `json:"is_deleted"`
+			DiffBlocks []struct {
+				OldText string `json:"old_text"`
+				NewText string `json:"new_text"`
+			} `json:"diff_blocks"`
+		} `json:"file_diffs"`
+	}
+	if err := json.Unmarshal([]byte(resp.Metadata), &meta); err != nil || len(meta.FileDiffs) != 4 {
+		t.Fatalf("metadata = %s, %v", resp.Metadata, err)
+	}
+	m, add, del, mv := meta.FileDiffs[0], meta.FileDiffs[1], meta.FileDiffs[2], meta.FileDiffs[3]
+	if m.Additions != 1 || m.Deletions != 1 || len(m.DiffBlocks) != 1 || m.DiffBlocks[0].NewText != "\tprintln(\"hello\")" {
+		t.Errorf("modify metadata = %+v", m)
+	}
+	if !add.IsNew || add.Path != filepath.Join(dir, "pkg/new.go") {
+		t.Errorf("add metadata = %+v", add)
+	}
+	if !del.IsDeleted || del.Additions != 0 {
+		t.Errorf("delete metadata = %+v", del)
+	}
+	if mv.OldPath != filepath.Join(dir, "a.txt") || mv.Path != filepath.Join(dir, "b.txt") {
+		t.Errorf("rename metadata = %+v", mv)
+	}
+}
+
+func TestApplyPatchTool_AllOrNothing(t *testing.T) {
+	dir := t.TempDir()
+	writeFileOrFail(t, filepath.Join(dir, "a.txt"), "one\n")
+	writeFileOrFail(t, filepath.Join(dir, "b.txt"), "two\n")
+	tool := NewApplyPatchTool(WithWorkDir(dir))
+
+	for name, patch := range map[string]string{
+		"hunk mismatch": "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-one\n+1\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-nope\n+2\n",
+		"add existing":  "*** Begin Patch\n*** Delete File: a.txt\n*** Add File: b.txt\n+x\n*** End Patch",
+		"move onto":     "*** Begin Patch\n*** Update File: a.txt\n*** Move to: b.txt\n*** End Patch",
+		"missing file":  "*** Begin Patch\n*** Delete File: a.txt\n*** Delete File: c.txt\n*** End Patch",
+		"twice":         "*** Begin Patch\n*** Delete File: a.txt\n*** Update File: a.txt\n-one\n+1\n*** End Patch",
+		"parse error":   "*** Begin Patch\n*** Delete File: a.txt\n",
+	} {
+		resp := runTool(t, tool, map[string]any{"patch": patch})
+		if !resp.IsError {
+			t.Errorf("%s: expected an error, got %q", name, resp.Content)
+		}
+		if got, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(got) != "one\n" {
+			t.Errorf("%s: a.txt changed to %q", name, got)
+		}
+		if got, _ := os.ReadFile(filepath.Join(dir, "b.txt")); string(got) != "two\n" {
+			t.Errorf("%s: b.txt changed to %q", name, got)
+		}
+	}
+}
+
+func TestApplyPatchTool_AppendsDiagnostics(t *testing.T) {
+	m, dir := newFakeLSP(t)
+	patch := "*** Begin Patch\n*** Add File: main.fake\n+ok\n+BAD\n*** Add File: notes.txt\n+BAD\n*** End Patch"
+	resp := runTool(t, NewApplyPatchTool(WithWorkDir(dir), WithLSP(m)), map[string]any{"patch": patch})
+	if resp.IsError || !strings.HasSuffix(resp.Content, "\n\nDiagnostics from fake:\nmain.fake:2:1: error: bad line (fake)") {
+		t.Errorf("apply_patch = %q", resp.Content)
+	}
+}
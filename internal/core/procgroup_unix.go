@@ -0,0 +1,27 @@
+//go:build !windows
+
+package core
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts cmd in its own process group and makes context
+// cancellation kill the whole group rather than just the shell. A Cancel
+// already set by the executor (e.g. stopping a container) still runs first.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cancel := cmd.Cancel
+	cmd.Cancel = func() error {
+		var err error
+		if cancel != nil {
+			err = cancel()
+		}
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		return err
+	}
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package core
+
+import "os/exec"
+
+// setProcessGroup is a no-op on Windows; cancellation kills the shell only.
+func setProcessGroup(cmd *exec.Cmd) {}
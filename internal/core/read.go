@@ -41,12 +41,12 @@ func NewReadTool(opts ...ToolOption) fantasy.AgentTool {
 			Parallel: true,
 		},
 		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
-			return executeRead(ctx, call, cfg.WorkDir)
+			return executeRead(ctx, call, cfg.WorkDir, cfg.fileSystem())
 		},
 	}
 }
 
-func executeRead(ctx context.Context, call fantasy.ToolCall, workDir string) (fantasy.ToolResponse, error) {
+func executeRead(ctx context.Context, call fantasy.ToolCall, workDir string, fsys FileSystem) (fantasy.ToolResponse, error) {
 	if err := ctx.Err(); err != nil {
 		return fantasy.ToolResponse{}, err
 	}
@@ -63,17 +63,20 @@ func executeRead(ctx context.Context, call fantasy.ToolCall, workDir string) (fa
 		return fantasy.NewTextErrorResponse(fmt.Sprintf("invalid path: %v", err)), nil
 	}
 
-	// Check if path is a directory
+	// Check if path is a directory. Another file system may hold files
+	// that are not on disk yet (e.g. unsaved editor buffers), so only the
+	// disk requires the path to exist here.
+	_, onDisk := fsys.(hostFileSystem)
 	info, err := os.Stat(absPath)
-	if err != nil {
+	if err != nil && onDisk {
 		return fantasy.NewTextErrorResponse(fmt.Sprintf("cannot access '%s': %v", args.Path, err)), nil
 	}
 
-	if info.IsDir() {
+	if err == nil && info.IsDir() {
 		return fantasy.NewTextErrorResponse(fmt.Sprintf("'%s' is a directory, not a file. Use the ls tool to list directory contents.", args.Path)), nil
 	}
 
-	content, err := os.ReadFile(absPath)
+	content, err := fsys.ReadFile(ctx, absPath)
 	if err != nil {
 		return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to read file: %v", err)), nil
 	}
@@ -0,0 +1,353 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// BashExecutor decides where a bash tool command runs. The bash tool keeps
+// ownership of argument validation, timeouts, output capture and streaming;
+// an executor only builds the process that runs the script.
+type BashExecutor interface {
+	// Name identifies the backend: "host", "bwrap", "docker" or "podman".
+	Name() string
+	// Command returns an unstarted command that runs script with bash in
+	// workDir. Cancelling ctx must stop the script.
+	Command(ctx context.Context, script, workDir string) (*exec.Cmd, error)
+}
+
+// BashRunner is implemented by executors that run commands somewhere a
+// local process cannot reach, such as an editor terminal over ACP. The bash
+// tool calls Run instead of Command for them; their Command is only used
+// for background jobs and may return an error. Sudo prompting does not
+// apply.
+type BashRunner interface {
+	BashExecutor
+	// Run runs script with bash in workDir until it exits or ctx is done
+	// and returns its combined output and exit code.
+	Run(ctx context.Context, script, workDir string) (output string, exitCode int, err error)
+}
+
+// Sandbox backends accepted by SandboxConfig.Backend.
+const (
+	SandboxNone   = "none"
+	SandboxBwrap  = "bwrap"
+	SandboxDocker = "docker"
+	SandboxPodman = "podman"
+)
+
+// DefaultSandboxImage is the container image used by the docker and podman
+// backends when SandboxConfig.Image is empty. It is built from
+// deploy/sandbox/Dockerfile.
+const DefaultSandboxImage = "ghcr.io/mark3labs/kit-sandbox:latest"
+
+// SandboxConfig selects and configures the bash execution backend. The zero
+// value runs commands directly on the host.
+type SandboxConfig struct {
+	// Backend is "none" (or empty) for the host, "bwrap" for a
+	// bubblewrap namespace sandbox (Linux only), or "docker"/"podman" for a
+	// throwaway container.
+	Backend string `json:"backend,omitempty" yaml:"backend,omitempty" mapstructure:"backend"`
+	// Image is the container image for the docker and podman backends.
+	// Empty uses DefaultSandboxImage.
+	Image string `json:"image,omitempty" yaml:"image,omitempty" mapstructure:"image"`
+	// Network enables network access inside the sandbox. Off by default.
+	Network bool `json:"network,omitempty" yaml:"network,omitempty" mapstructure:"network"`
+	// WritablePaths are extra host paths mounted read-write in addition to
+	// the working directory. Relative paths resolve against the working
+	// directory; a leading "~/" expands to the home directory.
+	WritablePaths []string `json:"writable-paths,omitempty" yaml:"writable-paths,omitempty" mapstructure:"writable-paths"`
+	// ReadOnlyPaths are host paths mounted read-only. For bwrap, whose root
+	// is already read-only, this protects paths inside a writable mount
+	// (e.g. ".git"); for containers it exposes extra host directories.
+	ReadOnlyPaths []string `json:"readonly-paths,omitempty" yaml:"readonly-paths,omitempty" mapstructure:"readonly-paths"`
+	// Env lists the environment passed into the sandbox. "NAME" forwards
+	// the host value when set; "NAME=value" sets it explicitly. No other
+	// host variables are visible.
+	Env []string `json:"env,omitempty" yaml:"env,omitempty" mapstructure:"env"`
+}
+
+// Enabled reports whether the config selects a sandboxed backend.
+func (c SandboxConfig) Enabled() bool {
+	return c.Backend != "" && c.Backend != SandboxNone
+}
+
+// Validate checks the backend name without probing the system for it.
+func (c SandboxConfig) Validate() error {
+	switch c.Backend {
+	case "", SandboxNone, SandboxBwrap, SandboxDocker, SandboxPodman:
+		return nil
+	default:
+		return fmt.Errorf("unknown sandbox backend %q (want none, bwrap, docker or podman)", c.Backend)
+	}
+}
+
+// NewBashExecutor returns the executor selected by cfg. It fails when the
+// backend is unknown or its binary is not installed, so a misconfigured
+// sandbox never silently falls back to running on the host.
+func NewBashExecutor(cfg SandboxConfig) (BashExecutor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Backend {
+	case "", SandboxNone:
+		return hostExecutor{}, nil
+	case SandboxBwrap:
+		if runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("the bwrap sandbox is only available on Linux")
+		}
+		bin, err := exec.LookPath("bwrap")
+		if err != nil {
+			return nil, fmt.Errorf("bwrap sandbox: bubblewrap is not installed: %w", err)
+		}
+		return &bwrapExecutor{bin: bin, cfg: cfg}, nil
+	default:
+		bin, err := exec.LookPath(cfg.Backend)
+		if err != nil {
+			return nil, fmt.Errorf("%s sandbox: %w", cfg.Backend, err)
+		}
+		if cfg.Image == "" {
+			cfg.Image = DefaultSandboxImage
+		}
+		return &containerExecutor{bin: bin, cfg: cfg}, nil
+	}
+}
+
+// hostExecutor runs commands directly on the host, inheriting its
+// environment. It is the default backend.
+type hostExecutor struct{}
+
+func (hostExecutor) Name() string { return "host" }
+
+func (hostExecutor) Command(ctx context.Context, script, workDir string) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, "bash", "-c", script)
+	if workDir != "" {
+		cmd.Dir = workDir
+	}
+
+	// Ensure SHELL is set to bash so child processes (e.g. tmux) use bash
+	// rather than the user's login shell (which may be nushell, fish, etc.).
+	bashPath, err := exec.LookPath("bash")
+	if err != nil {
+		bashPath = "/bin/bash"
+	}
+	cmd.Env = append(os.Environ(), "SHELL="+bashPath)
+	return cmd, nil
+}
+
+// bwrapExecutor runs commands in a bubblewrap sandbox: the host root is
+// mounted read-only, the working directory (and any WritablePaths) are
+// writable, /tmp is a private tmpfs and every namespace — including the
+// network unless enabled — is unshared.
+type bwrapExecutor struct {
+	bin string
+	cfg SandboxConfig
+}
+
+func (e *bwrapExecutor) Name() string { return SandboxBwrap }
+
+func (e *bwrapExecutor) Command(ctx context.Context, script, workDir string) (*exec.Cmd, error) {
+	workDir, err := sandboxWorkDir(workDir)
+	if err != nil {
+		return nil, err
+	}
+	args, err := bwrapArgs(e.cfg, workDir, script)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, e.bin, args...)
+	cmd.Dir = workDir
+	return cmd, nil
+}
+
+// bwrapArgs builds the bubblewrap command line. Later mounts shadow earlier
+// ones, so the order is: read-only root, fresh /dev, /proc and /tmp, then
+// writable paths, then read-only carve-outs inside them.
+func bwrapArgs(cfg SandboxConfig, workDir, script string) ([]string, error) {
+	args := []string{
+		"--die-with-parent",
+		"--new-session",
+		"--unshare-all",
+	}
+	if cfg.Network {
+		args = append(args, "--share-net")
+	}
+	args = append(args,
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+		"--bind", workDir, workDir,
+	)
+	writable, err := resolveSandboxPaths(cfg.WritablePaths, workDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range writable {
+		args = append(args, "--bind", p, p)
+	}
+	readOnly, err := resolveSandboxPaths(cfg.ReadOnlyPaths, workDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range readOnly {
+		args = append(args, "--ro-bind", p, p)
+	}
+
+	args = append(args, "--chdir", workDir, "--clearenv")
+	for _, kv := range sandboxEnv(cfg.Env, true) {
+		name, value, _ := strings.Cut(kv, "=")
+		args = append(args, "--setenv", name, value)
+	}
+	return append(args, "--", "bash", "-c", script), nil
+}
+
+// containerExecutor runs each command in a throwaway docker or podman
+// container with the working directory bind-mounted at the same path.
+type containerExecutor struct {
+	bin string
+	cfg SandboxConfig
+}
+
+func (e *containerExecutor) Name() string { return e.cfg.Backend }
+
+func (e *containerExecutor) Command(ctx context.Context, script, workDir string) (*exec.Cmd, error) {
+	workDir, err := sandboxWorkDir(workDir)
+	if err != nil {
+		return nil, err
+	}
+	var suffix [6]byte
+	_, _ = rand.Read(suffix[:])
+	name := "kit-bash-" + hex.EncodeToString(suffix[:])
+
+	args, err := containerArgs(e.cfg, name, workDir, script, containerUser(e.cfg.Backend))
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, e.bin, args...)
+	cmd.Dir = workDir
+	// Killing the CLI client does not stop the container, so stop it by
+	// name before killing the client.
+	cmd.Cancel = func() error {
+		_ = exec.Command(e.bin, "kill", name).Run()
+		return cmd.Process.Kill()
+	}
+	return cmd, nil
+}
+
+// containerArgs builds the `docker run` / `podman run` command line.
+func containerArgs(cfg SandboxConfig, name, workDir, script, user string) ([]string, error) {
+	args := []string{"run", "--rm", "-i", "--init", "--name", name}
+	if !cfg.Network {
+		args = append(args, "--network", "none")
+	}
+	if user != "" {
+		// Files created in the bind mount stay owned by the host user.
+		args = append(args, "--user", user, "-e", "HOME=/tmp")
+	}
+	args = append(args, "-v", workDir+":"+workDir)
+	writable, err := resolveSandboxPaths(cfg.WritablePaths, workDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range writable {
+		args = append(args, "-v", p+":"+p)
+	}
+	readOnly, err := resolveSandboxPaths(cfg.ReadOnlyPaths, workDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range readOnly {
+		args = append(args, "-v", p+":"+p+":ro")
+	}
+	args = append(args, "-w", workDir)
+	for _, kv := range sandboxEnv(cfg.Env, false) {
+		args = append(args, "-e", kv)
+	}
+	return append(args, cfg.Image, "bash", "-c", script), nil
+}
+
+// containerUser returns the --user value for docker on Linux, where bind
+// mounts share host ownership. Podman maps the invoking user itself.
+func containerUser(backend string) string {
+	if backend != SandboxDocker || runtime.GOOS != "linux" || os.Getuid() == 0 {
+		return ""
+	}
+	return strconv.Itoa(os.Getuid()) + ":" + strconv.Itoa(os.Getgid())
+}
+
+// sandboxWorkDir resolves the directory commands run in, defaulting to the
+// process working directory like the host backend does implicitly.
+func sandboxWorkDir(workDir string) (string, error) {
+	if workDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("sandbox: %w", err)
+		}
+		workDir = wd
+	}
+	return filepath.Abs(workDir)
+}
+
+// resolveSandboxPaths expands "~/" and makes paths absolute against workDir.
+// Paths that do not exist are skipped, since bind mounts require a source.
+func resolveSandboxPaths(paths []string, workDir string) ([]string, error) {
+	var out []string
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if p == "~" || strings.HasPrefix(p, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("sandbox path %q: %w", p, err)
+			}
+			p = filepath.Join(home, strings.TrimPrefix(p, "~"))
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(workDir, p)
+		}
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		out = append(out, filepath.Clean(p))
+	}
+	return out, nil
+}
+
+// sandboxEnv resolves the configured environment into NAME=value pairs.
+// When withBase is true (bwrap, which runs the host's binaries) a minimal
+// base of PATH, locale and terminal settings is forwarded and caches are
+// pointed at the private /tmp, since $HOME is read-only.
+func sandboxEnv(env []string, withBase bool) []string {
+	var out []string
+	if withBase {
+		for _, name := range []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "TERM"} {
+			if v, ok := os.LookupEnv(name); ok {
+				out = append(out, name+"="+v)
+			}
+		}
+		out = append(out, "SHELL=/bin/bash", "TMPDIR=/tmp", "XDG_CACHE_HOME=/tmp/.cache")
+	}
+	for _, entry := range env {
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "=") {
+			out = append(out, entry)
+			continue
+		}
+		if v, ok := os.LookupEnv(entry); ok {
+			out = append(out, entry+"="+v)
+		}
+	}
+	return out
+}
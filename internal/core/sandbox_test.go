@@ -0,0 +1,201 @@
+package core
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSandboxConfig_Validate(t *testing.T) {
+	for _, backend := range []string{"", "none", "bwrap", "docker", "podman"} {
+		if err := (SandboxConfig{Backend: backend}).Validate(); err != nil {
+			t.Errorf("Validate(%q) = %v, want nil", backend, err)
+		}
+	}
+	if err := (SandboxConfig{Backend: "chroot"}).Validate(); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+	if _, err := NewBashExecutor(SandboxConfig{Backend: "chroot"}); err == nil {
+		t.Error("NewBashExecutor should reject unknown backends")
+	}
+}
+
+func TestNewBashExecutor_DefaultsToHost(t *testing.T) {
+	for _, backend := range []string{"", "none"} {
+		e, err := NewBashExecutor(SandboxConfig{Backend: backend})
+		if err != nil {
+			t.Fatalf("NewBashExecutor(%q): %v", backend, err)
+		}
+		if e.Name() != "host" {
+			t.Errorf("NewBashExecutor(%q).Name() = %q, want host", backend, e.Name())
+		}
+	}
+}
+
+func TestBwrapArgs(t *testing.T) {
+	work := t.TempDir()
+	extra := t.TempDir()
+	cfg := SandboxConfig{
+		Backend:       SandboxBwrap,
+		WritablePaths: []string{extra, "does-not-exist"},
+		ReadOnlyPaths: []string{"."},
+		Env:           []string{"FOO=bar"},
+	}
+
+	args, err := bwrapArgs(cfg, work, "echo hi")
+	if err != nil {
+		t.Fatalf("bwrapArgs: %v", err)
+	}
+	joined := strings.Join(args, " ")
+
+	if slices.Contains(args, "--share-net") {
+		t.Error("network should be unshared by default")
+	}
+	for _, want := range []string{
+		"--unshare-all",
+		"--ro-bind / /",
+		"--bind " + work + " " + work,
+		"--bind " + extra + " " + extra,
+		"--ro-bind " + work + " " + work,
+		"--chdir " + work,
+		"--setenv FOO bar",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("args missing %q:\n%s", want, joined)
+		}
+	}
+	if strings.Contains(joined, "does-not-exist") {
+		t.Error("missing paths should be skipped")
+	}
+	// The writable work dir must be bound after the read-only root and
+	// /tmp tmpfs so it is not shadowed by them.
+	if strings.Index(joined, "--tmpfs /tmp") > strings.Index(joined, "--bind "+work) {
+		t.Error("work dir bind must come after the /tmp tmpfs")
+	}
+	if got := args[len(args)-3:]; !slices.Equal(got, []string{"bash", "-c", "echo hi"}) {
+		t.Errorf("command = %v, want bash -c script", got)
+	}
+
+	cfg.Network = true
+	args, _ = bwrapArgs(cfg, work, "true")
+	if !slices.Contains(args, "--share-net") {
+		t.Error("Network should add --share-net")
+	}
+}
+
+func TestContainerArgs(t *testing.T) {
+	work := t.TempDir()
+	cfg := SandboxConfig{
+		Backend: SandboxDocker,
+		Image:   "example/image:1",
+		Env:     []string{"A=1"},
+	}
+
+	args, err := containerArgs(cfg, "kit-bash-test", work, "ls", "1000:1000")
+	if err != nil {
+		t.Fatalf("containerArgs: %v", err)
+	}
+	joined := strings.Join(args, " ")
+	for _, want := range []string{
+		"run --rm -i --init --name kit-bash-test",
+		"--network none",
+		"--user 1000:1000",
+		"-v " + work + ":" + work,
+		"-w " + work,
+		"-e A=1",
+		"example/image:1 bash -c ls",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("args missing %q:\n%s", want, joined)
+		}
+	}
+
+	cfg.Network = true
+	args, _ = containerArgs(cfg, "kit-bash-test", work, "ls", "")
+	if slices.Contains(args, "--network") || slices.Contains(args, "--user") {
+		t.Errorf("unexpected flags: %v", args)
+	}
+}
+
+func TestSandboxEnv(t *testing.T) {
+	t.Setenv("KIT_SANDBOX_FORWARDED", "yes")
+	t.Setenv("KIT_SANDBOX_HIDDEN", "secret")
+
+	env := sandboxEnv([]string{"KIT_SANDBOX_FORWARDED", "KIT_SANDBOX_UNSET", "X=1"}, false)
+	want := []string{"KIT_SANDBOX_FORWARDED=yes", "X=1"}
+	if !slices.Equal(env, want) {
+		t.Errorf("sandboxEnv = %v, want %v", env, want)
+	}
+	for _, kv := range sandboxEnv(nil, true) {
+		if strings.HasPrefix(kv, "KIT_SANDBOX_HIDDEN=") {
+			t.Error("host variables must not leak into the sandbox")
+		}
+	}
+}
+
+// recordingExecutor runs commands on the host but reports a sandbox name,
+// so executeBashWith treats it as sandboxed.
+type recordingExecutor struct {
+	scripts []string
+}
+
+func (r *recordingExecutor) Name() string { return "test" }
+
+func (r *recordingExecutor) Command(ctx context.Context, script, workDir string) (*exec.Cmd, error) {
+	r.scripts = append(r.scripts, script)
+	cmd := exec.CommandContext(ctx, "bash", "-c", script)
+	cmd.Dir = workDir
+	return cmd, nil
+}
+
+func TestBash_UsesExecutor(t *testing.T) {
+	rec := &recordingExecutor{}
+	tool := NewBashTool(WithBashExecutor(rec))
+	if !strings.Contains(tool.Info().Description, "test sandbox") {
+		t.Errorf("description should mention the sandbox: %q", tool.Info().Description)
+	}
+
+	resp, err := tool.Run(context.Background(), bashCall("echo sandboxed", 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "sandboxed\n" {
+		t.Errorf("expected 'sandboxed\\n', got %q", resp.Content)
+	}
+	// Sudo commands are passed through untouched: there is no host sudo
+	// prompt or -S rewrite inside a sandbox.
+	_, _ = tool.Run(context.Background(), bashCall("sudo -n true 2>/dev/null; echo ok", 0))
+	if len(rec.scripts) != 2 || rec.scripts[1] != "sudo -n true 2>/dev/null; echo ok" {
+		t.Errorf("scripts = %q", rec.scripts)
+	}
+}
+
+// remoteRunner is a BashRunner that answers every command itself.
+type remoteRunner struct {
+	recordingExecutor
+	output   string
+	exitCode int
+}
+
+func (r *remoteRunner) Run(_ context.Context, script, workDir string) (string, int, error) {
+	r.scripts = append(r.scripts, script)
+	return r.output, r.exitCode, nil
+}
+
+func TestBash_UsesRunner(t *testing.T) {
+	runner := &remoteRunner{output: "remote out", exitCode: 3}
+	tool := NewBashTool(WithBashExecutor(runner))
+
+	resp, err := tool.Run(context.Background(), bashCall("make", 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.IsError || resp.Content != "remote out\nExit code: 3" {
+		t.Errorf("response = %q (error %v)", resp.Content, resp.IsError)
+	}
+	if len(runner.scripts) != 1 || runner.scripts[0] != "make" {
+		t.Errorf("scripts = %q", runner.scripts)
+	}
+}
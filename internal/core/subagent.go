@@ -27,6 +27,11 @@ type SubagentSpawnResult struct {
 	InputTokens  int64
 	OutputTokens int64
 	Elapsed      time.Duration
+	// WorktreeBranch holds the changes of a subagent run with worktree
+	// isolation, summarized by WorktreeDiffStat. Empty when it changed
+	// nothing or was not isolated.
+	WorktreeBranch   string
+	WorktreeDiffStat string
 }
 
 // SubagentSpawnRequest carries the parameters of an in-process subagent
@@ -51,6 +56,9 @@ type SubagentSpawnRequest struct {
 	// subagent_session_id returned by a previous run) instead of starting
 	// fresh, so follow-up tasks reuse the subagent's accumulated context.
 	SessionID string
+	// Isolation optionally runs the subagent in its own git worktree
+	// ("worktree") instead of the parent's working directory.
+	Isolation string
 }
 
 // SubagentSpawnFunc is a callback that spawns an in-process subagent. The
@@ -85,6 +93,8 @@ type subagentArgs struct {
 	SystemPrompt   string `json:"system_prompt,omitempty"`
 	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
 	SessionID      string `json:"session_id,omitempty"`
+	Isolation      string `json:"isolation,omitempty"`
+	Background     bool   `json:"run_in_background,omitempty"`
 }
 
 // NamedAgentSpec summarises a named agent definition for advertisement in
@@ -111,6 +121,12 @@ The subagent runs as a separate in-process Kit instance with full tool access
 The subagent result is returned when it completes. For long-running tasks,
 consider breaking them into smaller focused subtasks.
 
+To fan out, set run_in_background: the call returns a job ID at once and
+the subagent keeps working while you continue. Start several this way, then
+call subagent_wait with their IDs to collect the results (or poll their
+status), and subagent_cancel to stop one you no longer need. Only a limited
+number of subagents run at the same time; the rest queue.
+
 Each successful run returns a subagent_session_id. Pass it back via the
 optional session_id parameter to resume that subagent for follow-up tasks —
 the subagent keeps its accumulated context (files read, findings, state), so
@@ -174,6 +190,15 @@ func NewSubagentTool(opts ...ToolOption) fantasy.AgentTool {
 					"type":        "string",
 					"description": "Optional session ID from a previous subagent run (returned as subagent_session_id). Resumes that subagent's session so the follow-up task reuses its accumulated context instead of starting fresh.",
 				},
+				"isolation": map[string]any{
+					"type":        "string",
+					"enum":        []string{"worktree"},
+					"description": "Optional. \"worktree\" runs the subagent in a temporary git worktree on its own branch so its edits do not touch your checkout; the result names the branch to merge or discard.",
+				},
+				"run_in_background": map[string]any{
+					"type":        "boolean",
+					"description": "Start the subagent in the background and return its job ID immediately. Collect the result later with subagent_wait.",
+				},
 			},
 			Required: []string{"task"},
 			Parallel: true,
@@ -226,8 +251,7 @@ func executeSubagent(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolRe
 	// (defaultSubagentTimeout / user-specified) provides the safety net.
 	spawnCtx := context.WithoutCancel(valuesContext{parent: ctx})
 
-	// Spawn in-process subagent.
-	result, err := spawner(spawnCtx, SubagentSpawnRequest{
+	req := SubagentSpawnRequest{
 		ToolCallID:   call.ID,
 		Prompt:       args.Task,
 		Agent:        args.Agent,
@@ -235,7 +259,14 @@ func executeSubagent(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolRe
 		SystemPrompt: args.SystemPrompt,
 		Timeout:      timeout,
 		SessionID:    args.SessionID,
-	})
+		Isolation:    args.Isolation,
+	}
+	if args.Background {
+		return startBackgroundSubagent(spawnCtx, req)
+	}
+
+	// Spawn in-process subagent.
+	result, err := spawner(spawnCtx, req)
 	if err != nil || result.Error != nil {
 		spawnErr := err
 		if spawnErr == nil {
@@ -246,6 +277,7 @@ func executeSubagent(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolRe
 		if result.Response != "" {
 			response += fmt.Sprintf("\n\nPartial output:\n%s", truncateResponse(result.Response, 8000))
 		}
+		response += worktreeSummary(result)
 		return fantasy.NewTextErrorResponse(response), nil
 	}
 
@@ -255,6 +287,7 @@ func executeSubagent(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolRe
 		response += fmt.Sprintf(" (tokens: %d in / %d out)", result.InputTokens, result.OutputTokens)
 	}
 	response += fmt.Sprintf("\n\nResult:\n%s", truncateResponse(result.Response, 12000))
+	response += worktreeSummary(result)
 
 	resp := fantasy.NewTextResponse(response)
 
@@ -269,6 +302,17 @@ func executeSubagent(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolRe
 	return resp, nil
 }
 
+// worktreeSummary tells the model where an isolated subagent's changes are
+// and how to take or drop them.
+func worktreeSummary(result *SubagentSpawnResult) string {
+	if result.WorktreeBranch == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n\nChanges (not in your checkout, on branch %s):\n%s\n\n"+
+		"Merge them with `git merge --squash %s && git branch -D %s`, discard them with `git branch -D %s`, or keep the branch for the user to review.",
+		result.WorktreeBranch, result.WorktreeDiffStat, result.WorktreeBranch, result.WorktreeBranch, result.WorktreeBranch)
+}
+
 // ---------------------------------------------------------------------------
 // Context helpers
 // ---------------------------------------------------------------------------
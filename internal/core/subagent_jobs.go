@@ -0,0 +1,229 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+// Subagent job states reported in SubagentJobInfo.Status.
+const (
+	SubagentJobQueued    = "queued"
+	SubagentJobRunning   = "running"
+	SubagentJobCompleted = "completed"
+	SubagentJobFailed    = "failed"
+	SubagentJobCancelled = "cancelled"
+)
+
+// defaultSubagentWait is how long subagent_wait blocks when the model does
+// not say.
+const defaultSubagentWait = 5 * time.Minute
+
+// SubagentJobInfo is a snapshot of a background subagent as reported to the
+// model.
+type SubagentJobInfo struct {
+	ID     string
+	Task   string
+	Status string
+	// Elapsed is the time since the job was started, or its total run time
+	// once it finished.
+	Elapsed time.Duration
+	// Result is set once the job finished, whether it succeeded or not.
+	Result *SubagentSpawnResult
+}
+
+// Finished reports whether the job reached a final state.
+func (j SubagentJobInfo) Finished() bool {
+	return j.Status != SubagentJobQueued && j.Status != SubagentJobRunning
+}
+
+// SubagentJobs runs subagents in the background for the subagent tools. The
+// parent Kit instance implements it and injects it into the context, like
+// SubagentSpawnFunc, so the tools need not import pkg/kit.
+type SubagentJobs interface {
+	// Start launches req in the background and returns at once.
+	Start(ctx context.Context, req SubagentSpawnRequest) (SubagentJobInfo, error)
+	// Wait blocks until every job in ids finished, timeout elapsed or ctx
+	// is done, and returns their state. Empty ids means every job; a zero
+	// timeout returns immediately.
+	Wait(ctx context.Context, ids []string, timeout time.Duration) ([]SubagentJobInfo, error)
+	// Cancel stops the job with the given ID.
+	Cancel(id string) (SubagentJobInfo, error)
+}
+
+type subagentJobsCtxKey struct{}
+
+// WithSubagentJobs stores the background subagent manager in the context so
+// that the subagent tools can start, wait for and cancel jobs.
+func WithSubagentJobs(ctx context.Context, jobs SubagentJobs) context.Context {
+	return context.WithValue(ctx, subagentJobsCtxKey{}, jobs)
+}
+
+func getSubagentJobs(ctx context.Context) SubagentJobs {
+	if jobs, ok := ctx.Value(subagentJobsCtxKey{}).(SubagentJobs); ok {
+		return jobs
+	}
+	return nil
+}
+
+// startBackgroundSubagent launches req as a background job and reports its ID.
+func startBackgroundSubagent(ctx context.Context, req SubagentSpawnRequest) (fantasy.ToolResponse, error) {
+	jobs := getSubagentJobs(ctx)
+	if jobs == nil {
+		return fantasy.NewTextErrorResponse("background subagents are not available"), nil
+	}
+	info, err := jobs.Start(ctx, req)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to start background subagent: %v", err)), nil
+	}
+	return fantasy.NewTextResponse(fmt.Sprintf(
+		"Started background subagent %s (%s). Continue with other work, then use subagent_wait with job_ids [%q] to collect its result, or subagent_cancel to stop it.",
+		info.ID, info.Status, info.ID)), nil
+}
+
+type subagentWaitArgs struct {
+	JobIDs      []string `json:"job_ids,omitempty"`
+	WaitSeconds *float64 `json:"wait_seconds,omitempty"`
+}
+
+type subagentCancelArgs struct {
+	JobID string `json:"job_id"`
+}
+
+// NewSubagentWaitTool creates the subagent_wait core tool, which collects
+// the results of background subagents started with the subagent tool's
+// run_in_background, or polls their status.
+func NewSubagentWaitTool(opts ...ToolOption) fantasy.AgentTool {
+	return &coreTool{
+		info: fantasy.ToolInfo{
+			Name:        "subagent_wait",
+			Description: "Wait for background subagents started with subagent's run_in_background to finish and return their results. Returns as soon as all listed subagents are done, or when wait_seconds elapse with the status of those still running. Pass wait_seconds 0 to poll without waiting.",
+			Parameters: map[string]any{
+				"job_ids": map[string]any{
+					"type":        "array",
+					"items":       map[string]any{"type": "string"},
+					"description": "IDs returned when the subagents were started (optional, default: every background subagent)",
+				},
+				"wait_seconds": map[string]any{
+					"type":        "number",
+					"description": fmt.Sprintf("Seconds to wait for the subagents to finish (optional, default %d, max %d; 0 polls)", int(defaultSubagentWait.Seconds()), int(maxSubagentTimeout.Seconds())),
+				},
+			},
+		},
+		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			jobs := getSubagentJobs(ctx)
+			if jobs == nil {
+				return fantasy.NewTextErrorResponse("background subagents are not available"), nil
+			}
+			var args subagentWaitArgs
+			if err := parseArgs(call.Input, &args); err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			wait := defaultSubagentWait
+			if args.WaitSeconds != nil {
+				wait = min(time.Duration(max(*args.WaitSeconds, 0)*float64(time.Second)), maxSubagentTimeout)
+			}
+			infos, err := jobs.Wait(ctx, args.JobIDs, wait)
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			resp := fantasy.NewTextResponse(formatSubagentJobs(infos))
+			sessions := make(map[string]any)
+			for _, info := range infos {
+				if info.Result != nil && info.Result.SessionID != "" {
+					sessions[info.ID] = info.Result.SessionID
+				}
+			}
+			if len(sessions) > 0 {
+				resp = fantasy.WithResponseMetadata(resp, map[string]any{"subagent_session_ids": sessions})
+			}
+			return resp, nil
+		},
+	}
+}
+
+// NewSubagentCancelTool creates the subagent_cancel core tool, which stops a
+// background subagent.
+func NewSubagentCancelTool(opts ...ToolOption) fantasy.AgentTool {
+	return &coreTool{
+		info: fantasy.ToolInfo{
+			Name:        "subagent_cancel",
+			Description: "Stop a background subagent started with subagent's run_in_background.",
+			Parameters: map[string]any{
+				"job_id": map[string]any{
+					"type":        "string",
+					"description": "ID returned when the subagent was started",
+				},
+			},
+			Required: []string{"job_id"},
+		},
+		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			jobs := getSubagentJobs(ctx)
+			if jobs == nil {
+				return fantasy.NewTextErrorResponse("background subagents are not available"), nil
+			}
+			var args subagentCancelArgs
+			if err := parseArgs(call.Input, &args); err != nil || args.JobID == "" {
+				return fantasy.NewTextErrorResponse("job_id parameter is required"), nil
+			}
+			info, err := jobs.Cancel(args.JobID)
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+			if info.Status != SubagentJobCancelled {
+				return fantasy.NewTextResponse(fmt.Sprintf("Subagent %s had already %s.", info.ID, info.Status)), nil
+			}
+			return fantasy.NewTextResponse(fmt.Sprintf("Subagent %s cancelled.", info.ID)), nil
+		},
+	}
+}
+
+// formatSubagentJobs renders a subagent_wait result: one section per job
+// and a summary line with the combined token usage.
+func formatSubagentJobs(infos []SubagentJobInfo) string {
+	if len(infos) == 0 {
+		return "No background subagents."
+	}
+	var (
+		b                   strings.Builder
+		counts              = make(map[string]int)
+		inTokens, outTokens int64
+	)
+	// Split the response budget between the jobs so one long result cannot
+	// crowd out the others.
+	budget := max(12000/len(infos), 2000)
+	for _, info := range infos {
+		counts[info.Status]++
+		fmt.Fprintf(&b, "## %s: %s (%ds)\n", info.ID, info.Status, int(info.Elapsed.Seconds()))
+		fmt.Fprintf(&b, "Task: %s\n", truncateResponse(info.Task, 200))
+		if r := info.Result; r != nil {
+			inTokens += r.InputTokens
+			outTokens += r.OutputTokens
+			if r.SessionID != "" {
+				fmt.Fprintf(&b, "Session: %s\n", r.SessionID)
+			}
+			if r.Error != nil {
+				fmt.Fprintf(&b, "Error: %v\n", r.Error)
+			}
+			if r.Response != "" {
+				fmt.Fprintf(&b, "\n%s\n", truncateResponse(r.Response, budget))
+			}
+			if summary := worktreeSummary(r); summary != "" {
+				fmt.Fprintf(&b, "%s\n", strings.TrimPrefix(summary, "\n"))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	var summary []string
+	for _, status := range []string{SubagentJobCompleted, SubagentJobFailed, SubagentJobCancelled, SubagentJobRunning, SubagentJobQueued} {
+		if counts[status] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	fmt.Fprintf(&b, "Total: %s (tokens: %d in / %d out)", strings.Join(summary, ", "), inTokens, outTokens)
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+// fakeSubagentJobs records the calls of the subagent tools and answers
+// with canned job state.
+type fakeSubagentJobs struct {
+	started  []SubagentSpawnRequest
+	waitIDs  []string
+	waitFor  time.Duration
+	infos    []SubagentJobInfo
+	canceled string
+}
+
+func (f *fakeSubagentJobs) Start(ctx context.Context, req SubagentSpawnRequest) (SubagentJobInfo, error) {
+	f.started = append(f.started, req)
+	return SubagentJobInfo{ID: "sub-1", Task: req.Prompt, Status: SubagentJobQueued}, nil
+}
+
+func (f *fakeSubagentJobs) Wait(ctx context.Context, ids []string, timeout time.Duration) ([]SubagentJobInfo, error) {
+	f.waitIDs, f.waitFor = ids, timeout
+	return f.infos, nil
+}
+
+func (f *fakeSubagentJobs) Cancel(id string) (SubagentJobInfo, error) {
+	if id != "sub-1" {
+		return SubagentJobInfo{}, errors.New("no subagent job " + id)
+	}
+	f.canceled = id
+	return SubagentJobInfo{ID: id, Status: SubagentJobCancelled}, nil
+}
+
+func TestExecuteSubagent_RunInBackground(t *testing.T) {
+	jobs := &fakeSubagentJobs{}
+	spawner := SubagentSpawnFunc(func(ctx context.Context, req SubagentSpawnRequest) (*SubagentSpawnResult, error) {
+		t.Error("background subagent must not run synchronously")
+		return &SubagentSpawnResult{}, nil
+	})
+	ctx := WithSubagentJobs(WithSubagentSpawner(context.Background(), spawner), jobs)
+
+	resp, err := executeSubagent(ctx, fantasy.ToolCall{
+		ID:    "tc1",
+		Input: `{"task":"review the diff","agent":"explore","run_in_background":true}`,
+	})
+	if err != nil {
+		t.Fatalf("executeSubagent: %v", err)
+	}
+	if resp.IsError {
+		t.Fatalf("unexpected error response: %s", resp.Content)
+	}
+	if !strings.Contains(resp.Content, "sub-1") {
+		t.Errorf("response should name the job ID, got %q", resp.Content)
+	}
+	if len(jobs.started) != 1 || jobs.started[0].Prompt != "review the diff" || jobs.started[0].Agent != "explore" {
+		t.Errorf("started = %+v, want the task and agent forwarded", jobs.started)
+	}
+}
+
+func TestSubagentWaitTool(t *testing.T) {
+	jobs := &fakeSubagentJobs{infos: []SubagentJobInfo{
+		{ID: "sub-1", Task: "security review", Status: SubagentJobCompleted, Result: &SubagentSpawnResult{
+			Response: "no issues", SessionID: "sess-1", InputTokens: 100, OutputTokens: 10,
+		}},
+		{ID: "sub-2", Task: "style review", Status: SubagentJobFailed, Result: &SubagentSpawnResult{
+			Error: errors.New("timed out"), InputTokens: 50, OutputTokens: 5,
+		}},
+		{ID: "sub-3", Task: "test review", Status: SubagentJobRunning},
+	}}
+	ctx := WithSubagentJobs(context.Background(), jobs)
+
+	resp, err := NewSubagentWaitTool().Run(ctx, fantasy.ToolCall{
+		Input: `{"job_ids":["sub-1","sub-2","sub-3"],"wait_seconds":30}`,
+	})
+	if err != nil || resp.IsError {
+		t.Fatalf("subagent_wait = %q, %v", resp.Content, err)
+	}
+	if !slices.Equal(jobs.waitIDs, []string{"sub-1", "sub-2", "sub-3"}) || jobs.waitFor != 30*time.Second {
+		t.Errorf("Wait(%v, %v), want the requested IDs and 30s", jobs.waitIDs, jobs.waitFor)
+	}
+	for _, want := range []string{"no issues", "timed out", "sub-3: running", "1 completed, 1 failed, 1 running", "150 in / 15 out"} {
+		if !strings.Contains(resp.Content, want) {
+			t.Errorf("response missing %q:\n%s", want, resp.Content)
+		}
+	}
+	if !strings.Contains(resp.Metadata, `"sub-1":"sess-1"`) {
+		t.Errorf("metadata = %s, want the child session IDs", resp.Metadata)
+	}
+
+	// Omitted wait_seconds uses the default; a poll does not wait.
+	_, _ = NewSubagentWaitTool().Run(ctx, fantasy.ToolCall{Input: `{}`})
+	if jobs.waitIDs != nil || jobs.waitFor != defaultSubagentWait {
+		t.Errorf("Wait(%v, %v), want every job and the default wait", jobs.waitIDs, jobs.waitFor)
+	}
+	_, _ = NewSubagentWaitTool().Run(ctx, fantasy.ToolCall{Input: `{"wait_seconds":0}`})
+	if jobs.waitFor != 0 {
+		t.Errorf("wait_seconds 0 waited %v", jobs.waitFor)
+	}
+}
+
+func TestSubagentCancelTool(t *testing.T) {
+	jobs := &fakeSubagentJobs{}
+	ctx := WithSubagentJobs(context.Background(), jobs)
+	tool := NewSubagentCancelTool()
+
+	resp, _ := tool.Run(ctx, fantasy.ToolCall{Input: `{"job_id":"sub-1"}`})
+	if resp.IsError || jobs.canceled != "sub-1" {
+		t.Fatalf("cancel sub-1 = %q, canceled %q", resp.Content, jobs.canceled)
+	}
+	if resp, _ := tool.Run(ctx, fantasy.ToolCall{Input: `{"job_id":"sub-9"}`}); !resp.IsError {
+		t.Errorf("cancelling an unknown job should fail, got %q", resp.Content)
+	}
+	if resp, _ := tool.Run(ctx, fantasy.ToolCall{Input: `{}`}); !resp.IsError {
+		t.Errorf("missing job_id should fail, got %q", resp.Content)
+	}
+}
+
+func TestSubagentJobTools_WithoutManager(t *testing.T) {
+	for _, tool := range []fantasy.AgentTool{NewSubagentWaitTool(), NewSubagentCancelTool()} {
+		resp, err := tool.Run(context.Background(), fantasy.ToolCall{Input: `{"job_id":"sub-1"}`})
+		if err != nil || !resp.IsError {
+			t.Errorf("%s without a job manager = %q, %v; want an error response", tool.Info().Name, resp.Content, err)
+		}
+	}
+}
@@ -155,3 +155,32 @@ func TestSubagentToolDescription_MentionsResume(t *testing.T) {
 		t.Error("tool parameters should include session_id")
 	}
 }
+
+func TestExecuteSubagent_WorktreeIsolation(t *testing.T) {
+	var captured SubagentSpawnRequest
+	spawner := SubagentSpawnFunc(func(ctx context.Context, req SubagentSpawnRequest) (*SubagentSpawnResult, error) {
+		captured = req
+		return &SubagentSpawnResult{
+			Response:         "done",
+			WorktreeBranch:   "kit/subagent-123",
+			WorktreeDiffStat: " a.go | 2 +-\n 1 file changed",
+		}, nil
+	})
+
+	ctx := WithSubagentSpawner(context.Background(), spawner)
+	resp, err := executeSubagent(ctx, fantasy.ToolCall{
+		ID:    "tc1",
+		Input: `{"task":"refactor","isolation":"worktree"}`,
+	})
+	if err != nil || resp.IsError {
+		t.Fatalf("executeSubagent = %q, %v", resp.Content, err)
+	}
+	if captured.Isolation != "worktree" {
+		t.Errorf("spawner Isolation = %q, want worktree", captured.Isolation)
+	}
+	for _, want := range []string{"kit/subagent-123", "1 file changed", "git merge --squash kit/subagent-123"} {
+		if !strings.Contains(resp.Content, want) {
+			t.Errorf("response missing %q:\n%s", want, resp.Content)
+		}
+	}
+}
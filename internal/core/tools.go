@@ -1,7 +1,11 @@
 // Package core provides the built-in core tools for KIT's coding agent.
 // These tools are direct fantasy.AgentTool implementations — no MCP layer,
-// no JSON-RPC, no serialization overhead. Core tool set: bash, read, write,
-// edit, grep, find, ls.
+// no JSON-RPC, no serialization overhead. Core tool set: bash (plus
+// bash_output and bash_kill for background jobs), read, write, edit,
+// apply_patch, grep, find, ls, and subagent (plus subagent_wait and
+// subagent_cancel for background subagents). When language servers are
+// configured, LSPTools adds definition, references, hover, diagnostics,
+// rename_symbol and workspace_symbols.
 package core
 
 import (
@@ -13,6 +17,8 @@ import (
 	"time"
 
 	"charm.land/fantasy"
+
+	"github.com/mark3labs/kit/internal/lsp"
 )
 
 // ToolOption configures tool behavior.
@@ -31,6 +37,20 @@ type ToolConfig struct {
 	// its timeout argument. Zero uses the built-in default (600s). Only the
 	// bash tool consumes this.
 	BashMaxTimeout time.Duration
+	// BashExecutor runs bash tool commands. Nil runs them directly on the
+	// host. Only the bash tool consumes this.
+	BashExecutor BashExecutor
+	// FileSystem is where the file tools access file contents. Nil
+	// uses the local disk.
+	FileSystem FileSystem
+	// Jobs tracks background bash jobs. When set, the bash tool accepts
+	// run_in_background and the bash_output / bash_kill tools operate on
+	// it; when nil background jobs are unavailable.
+	Jobs *JobManager
+	// LSP runs the language servers behind the code intelligence tools.
+	// When set, edit and write also append the server's diagnostics for
+	// the changed file to their result.
+	LSP *lsp.Manager
 }
 
 // WithWorkDir sets the working directory for file-based tools.
@@ -69,6 +89,38 @@ func WithBashMaxTimeout(d time.Duration) ToolOption {
 	}
 }
 
+// WithBashExecutor sets the backend the bash tool runs commands with, e.g.
+// a sandbox built by NewBashExecutor. Nil keeps the host backend.
+func WithBashExecutor(e BashExecutor) ToolOption {
+	return func(c *ToolConfig) {
+		c.BashExecutor = e
+	}
+}
+
+// WithFileSystem routes the file access of the file tools through fs. Nil
+// keeps the local disk.
+func WithFileSystem(fs FileSystem) ToolOption {
+	return func(c *ToolConfig) {
+		c.FileSystem = fs
+	}
+}
+
+// WithJobManager enables background bash jobs tracked by jobs. The same
+// manager must be passed to bash, bash_output and bash_kill.
+func WithJobManager(jobs *JobManager) ToolOption {
+	return func(c *ToolConfig) {
+		c.Jobs = jobs
+	}
+}
+
+// WithLSP enables the code intelligence tools and post-edit diagnostics
+// backed by the language servers of m.
+func WithLSP(m *lsp.Manager) ToolOption {
+	return func(c *ToolConfig) {
+		c.LSP = m
+	}
+}
+
 // ApplyOptions applies the given ToolOptions to a ToolConfig and returns it.
 func ApplyOptions(opts []ToolOption) ToolConfig {
 	var cfg ToolConfig
@@ -108,14 +160,19 @@ func parseArgs(input string, target any) error {
 type initTool func(...ToolOption) fantasy.AgentTool
 
 var coreTools = map[string]initTool{
-	"bash":     NewBashTool,
-	"read":     NewReadTool,
-	"write":    NewWriteTool,
-	"edit":     NewEditTool,
-	"grep":     NewGrepTool,
-	"find":     NewFindTool,
-	"ls":       NewLsTool,
-	"subagent": NewSubagentTool,
+	"bash":            NewBashTool,
+	"bash_output":     NewBashOutputTool,
+	"bash_kill":       NewBashKillTool,
+	"read":            NewReadTool,
+	"write":           NewWriteTool,
+	"edit":            NewEditTool,
+	"apply_patch":     NewApplyPatchTool,
+	"grep":            NewGrepTool,
+	"find":            NewFindTool,
+	"ls":              NewLsTool,
+	"subagent":        NewSubagentTool,
+	"subagent_wait":   NewSubagentWaitTool,
+	"subagent_cancel": NewSubagentCancelTool,
 }
 
 // ListAllCoreToolNames always returns the full list of available core
@@ -146,14 +203,17 @@ func ReadOnlyTools(opts ...ToolOption) []fantasy.AgentTool {
 	}
 }
 
-// SubagentTools returns all core tools except subagent. This prevents
+// SubagentTools returns all core tools except the subagent tools. This prevents
 // infinite recursion when a subagent is itself a Kit instance.
 func SubagentTools(opts ...ToolOption) []fantasy.AgentTool {
 	return []fantasy.AgentTool{
 		NewBashTool(opts...),
+		NewBashOutputTool(opts...),
+		NewBashKillTool(opts...),
 		NewReadTool(opts...),
 		NewWriteTool(opts...),
 		NewEditTool(opts...),
+		NewApplyPatchTool(opts...),
 		NewGrepTool(opts...),
 		NewFindTool(opts...),
 		NewLsTool(opts...),
@@ -170,5 +230,9 @@ func ListedTools(toolList []string, opts ...ToolOption) []fantasy.AgentTool {
 
 // AllTools returns all available core tools.
 func AllTools(opts ...ToolOption) []fantasy.AgentTool {
-	return append(SubagentTools(opts...), NewSubagentTool(opts...))
+	return append(SubagentTools(opts...),
+		NewSubagentTool(opts...),
+		NewSubagentWaitTool(opts...),
+		NewSubagentCancelTool(opts...),
+	)
 }
@@ -35,12 +35,13 @@ func NewWriteTool(opts ...ToolOption) fantasy.AgentTool {
 			Required: []string{"path", "content"},
 		},
 		handler: func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
-			return executeWrite(ctx, call, cfg.WorkDir)
+			resp, err := executeWrite(ctx, call, cfg.WorkDir, cfg.fileSystem())
+			return withPostEditDiagnostics(ctx, cfg, call, resp, err)
 		},
 	}
 }
 
-func executeWrite(ctx context.Context, call fantasy.ToolCall, workDir string) (fantasy.ToolResponse, error) {
+func executeWrite(ctx context.Context, call fantasy.ToolCall, workDir string, fsys FileSystem) (fantasy.ToolResponse, error) {
 	if err := ctx.Err(); err != nil {
 		return fantasy.ToolResponse{}, err
 	}
@@ -60,7 +61,7 @@ func executeWrite(ctx context.Context, call fantasy.ToolCall, workDir string) (f
 	// Read existing content before writing (for diff metadata).
 	var beforeContent string
 	isNew := true
-	if existing, readErr := os.ReadFile(absPath); readErr == nil {
+	if existing, readErr := fsys.ReadFile(ctx, absPath); readErr == nil {
 		beforeContent = string(existing)
 		isNew = false
 	}
@@ -71,7 +72,7 @@ func executeWrite(ctx context.Context, call fantasy.ToolCall, workDir string) (f
 		return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to create directories: %v", err)), nil
 	}
 
-	if err := os.WriteFile(absPath, []byte(args.Content), 0644); err != nil {
+	if err := fsys.WriteFile(ctx, absPath, []byte(args.Content)); err != nil {
 		return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to write file: %v", err)), nil
 	}
 
@@ -81,21 +82,26 @@ func executeWrite(ctx context.Context, call fantasy.ToolCall, workDir string) (f
 
 // writeDiffMeta builds the structured metadata attached to write tool responses.
 func writeDiffMeta(path, beforeContent, afterContent string, isNew bool) map[string]any {
+	return map[string]any{
+		"file_diffs": []map[string]any{fileDiffEntry(path, beforeContent, afterContent, isNew)},
+	}
+}
+
+// fileDiffEntry describes a whole-file replacement as one file_diffs entry.
+func fileDiffEntry(path, beforeContent, afterContent string, isNew bool) map[string]any {
 	additions := strings.Count(afterContent, "\n") + 1
 	deletions := 0
 	if !isNew {
 		deletions = strings.Count(beforeContent, "\n") + 1
 	}
 	return map[string]any{
-		"file_diffs": []map[string]any{{
-			"path":      path,
-			"additions": additions,
-			"deletions": deletions,
-			"is_new":    isNew,
-			"diff_blocks": []map[string]any{{
-				"old_text": beforeContent,
-				"new_text": afterContent,
-			}},
+		"path":      path,
+		"additions": additions,
+		"deletions": deletions,
+		"is_new":    isNew,
+		"diff_blocks": []map[string]any{{
+			"old_text": beforeContent,
+			"new_text": afterContent,
 		}},
 	}
 }
@@ -1325,6 +1325,7 @@ type API struct {
 	onRetry                   func(func(RetryEvent, Context))
 	onPrepareStep             func(func(PrepareStepEvent, Context) *PrepareStepResult)
 	onLLMUsage                func(func(LLMUsageEvent, Context))
+	onSkillScope              func(func(SkillScopeEvent, Context))
 }
 
 // OnToolCall registers a handler that fires before a tool executes.
@@ -1664,6 +1665,13 @@ func (a *API) OnLLMUsage(handler func(LLMUsageEvent, Context)) {
 	a.onLLMUsage(handler)
 }
 
+// OnSkillScope registers a handler that fires when a skill declaring
+// allowed-tools is activated and the agent becomes restricted to those
+// tools, and again when the restriction ends at the end of the turn.
+func (a *API) OnSkillScope(handler func(SkillScopeEvent, Context)) {
+	a.onSkillScope(handler)
+}
+
 // RegisterToolRenderer registers a custom renderer for a specific tool's
 // display in the TUI. The renderer controls the header (parameter summary)
 // and/or body (result display) of the tool's output block. If multiple
@@ -2538,14 +2546,16 @@ func (e SessionStartEvent) Type() EventType { return SessionStart }
 // SessionShutdownEvent fires when the application is closing.
 type SessionShutdownEvent struct{}
 
-// ModelChangeEvent fires after the active model is changed via ctx.SetModel().
+// ModelChangeEvent fires after the active model is changed via
+// ctx.SetModel(), interactive selection, or failover to a fallback model.
 type ModelChangeEvent struct {
 	// NewModel is the model string that was set (e.g. "anthropic/claude-sonnet-4-5-20250929").
 	NewModel string
 	// PreviousModel is the model string before the change.
 	PreviousModel string
 	// Source indicates what triggered the change: "extension" for ctx.SetModel(),
-	// "user" for interactive model selection.
+	// "user" for interactive model selection, "failover" when a provider
+	// error made Kit switch to the next fallback model mid-turn.
 	Source string
 }
 
@@ -2909,6 +2919,21 @@ type LLMUsageEvent struct {
 
 func (e LLMUsageEvent) Type() EventType { return LLMUsage }
 
+// SkillScopeEvent fires when an activated skill starts (Active true) or
+// stops restricting the tools the agent may call. While a scope is active,
+// calls to tools outside AllowedTools fail with an error result.
+type SkillScopeEvent struct {
+	// Skill is the name of the activated skill.
+	Skill string
+	// AllowedTools is the skill's allowed-tools frontmatter as written,
+	// e.g. "Bash(git:*) Read".
+	AllowedTools string
+	// Active is true when the restriction starts and false when it ends.
+	Active bool
+}
+
+func (e SkillScopeEvent) Type() EventType { return SkillScope }
+
 // ThemeColors holds the active theme's colors as "#rrggbb" hex strings, with
 // the light/dark variants already resolved for the terminal's appearance.
 // Returned by ctx.GetTheme().
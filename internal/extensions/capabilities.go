@@ -0,0 +1,473 @@
+package extensions
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/traefik/yaegi/interp"
+	"github.com/traefik/yaegi/stdlib"
+	"github.com/traefik/yaegi/stdlib/unrestricted"
+)
+
+// ---------------------------------------------------------------------------
+// Capabilities
+// ---------------------------------------------------------------------------
+//
+// An extension declares what it needs up front so users can see it before
+// installing, and so Kit can refuse everything else. A Go extension puts a
+// directive above its package clause:
+//
+//	//kit:capabilities events tools
+//	package main
+//
+// and an out-of-process extension lists them in kit-extension.json:
+//
+//	{"command": ["./ext"], "capabilities": ["events", "ui"]}
+//
+// Extensions that declare nothing keep full access, so existing extensions
+// work unchanged; previews and listings flag them as undeclared.
+
+// Capability names one kind of access an extension can be granted.
+type Capability string
+
+const (
+	// CapabilityEvents allows observing and reacting to lifecycle events.
+	CapabilityEvents Capability = "events"
+	// CapabilityTools allows registering tools and intercepting tool calls
+	// and results.
+	CapabilityTools Capability = "tools"
+	// CapabilityUI allows registering commands, shortcuts and renderers.
+	CapabilityUI Capability = "ui"
+	// CapabilityNetwork exposes net, net/http and the other networking
+	// packages.
+	CapabilityNetwork Capability = "network"
+	// CapabilityFilesystem exposes the os, io/ioutil and path/filepath
+	// functions that read or change files.
+	CapabilityFilesystem Capability = "filesystem"
+	// CapabilityExec exposes os/exec and the other ways of starting or
+	// signalling processes.
+	CapabilityExec Capability = "exec"
+)
+
+// AllCapabilities lists every capability in display order.
+func AllCapabilities() []Capability {
+	return []Capability{
+		CapabilityEvents,
+		CapabilityTools,
+		CapabilityUI,
+		CapabilityNetwork,
+		CapabilityFilesystem,
+		CapabilityExec,
+	}
+}
+
+// capabilityDirective introduces a capability declaration in Go source.
+const capabilityDirective = "//kit:capabilities"
+
+// Capabilities is what an extension declared. The zero value is an
+// undeclared set, which grants everything.
+type Capabilities struct {
+	// Declared is false for extensions without a declaration.
+	Declared bool `json:"declared"`
+	// Granted lists the declared capabilities.
+	Granted []Capability `json:"granted,omitempty"`
+}
+
+// ParseCapabilities builds a declared set from capability names. Unknown
+// names are an error rather than ignored, so a typo cannot silently leave an
+// extension without something it needs.
+func ParseCapabilities(names []string) (Capabilities, error) {
+	c := Capabilities{Declared: true}
+	for _, name := range names {
+		capability := Capability(strings.ToLower(strings.TrimSpace(name)))
+		if capability == "" {
+			continue
+		}
+		if !slices.Contains(AllCapabilities(), capability) {
+			return Capabilities{}, fmt.Errorf("unknown capability %q", name)
+		}
+		if !slices.Contains(c.Granted, capability) {
+			c.Granted = append(c.Granted, capability)
+		}
+	}
+	return c, nil
+}
+
+// Has reports whether c grants capability.
+func (c Capabilities) Has(capability Capability) bool {
+	return !c.Declared || slices.Contains(c.Granted, capability)
+}
+
+// String renders c for previews and listings.
+func (c Capabilities) String() string {
+	if !c.Declared {
+		return "undeclared (full access)"
+	}
+	if len(c.Granted) == 0 {
+		return "none"
+	}
+	names := make([]string, len(c.Granted))
+	for i, capability := range c.Granted {
+		names[i] = string(capability)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ReadCapabilities reads the declaration of the extension at path: the
+// directive in a .go file, or the manifest of an out-of-process extension.
+// An executable run without a manifest has nowhere to declare anything and
+// is undeclared.
+func ReadCapabilities(path string) (Capabilities, error) {
+	switch {
+	case strings.HasSuffix(path, ".go"):
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return Capabilities{}, err
+		}
+		return sourceCapabilities(string(src))
+	case filepath.Base(path) == RPCManifestName:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Capabilities{}, err
+		}
+		var m RPCManifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return Capabilities{}, fmt.Errorf("parsing manifest: %w", err)
+		}
+		return m.capabilities()
+	default:
+		return Capabilities{}, nil
+	}
+}
+
+// sourceCapabilities collects the capability directives above the package
+// clause of a Go extension. Several directives add up; names may be
+// separated by spaces or commas.
+func sourceCapabilities(src string) (Capabilities, error) {
+	var (
+		c     Capabilities
+		names []string
+	)
+	for line := range strings.Lines(src) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		rest, ok := strings.CutPrefix(line, capabilityDirective)
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		c.Declared = true
+		names = append(names, strings.FieldsFunc(rest, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})...)
+	}
+	if !c.Declared {
+		return c, nil
+	}
+	return ParseCapabilities(names)
+}
+
+// allow reports whether c grants capability, logging why not when it does
+// not. Denied registrations are dropped rather than failing the load so an
+// extension keeps whatever it was granted.
+func (c Capabilities) allow(capability Capability, extension, call string) bool {
+	if c.Has(capability) {
+		return true
+	}
+	log.Warn("Extension registration denied", "extension", extension, "call", call, "capability", capability)
+	return false
+}
+
+// restrictContext returns ctx with the functions c does not cover replaced
+// by stubs that log and fail. Driving the agent or making any other model
+// call needs exec, since the agent can run any tool, bash included, and
+// every call is billed; stopping the agent, moving it around the session
+// tree or changing its tools needs tools; loading skills reads files;
+// drawing or prompting in the terminal needs ui. Reading state, printing
+// and the pure helpers stay open to every extension.
+func (c Capabilities) restrictContext(ctx Context, extension string) Context {
+	if !c.Declared {
+		return ctx
+	}
+	deny := func(capability Capability, call string) error {
+		log.Warn("Extension call denied", "extension", extension, "call", call, "capability", capability)
+		return fmt.Errorf("%s needs the %q capability", call, capability)
+	}
+	if !c.Has(CapabilityExec) {
+		ctx.SendMessage = func(string) { _ = deny(CapabilityExec, "SendMessage") }
+		ctx.CancelAndSend = func(string) { _ = deny(CapabilityExec, "CancelAndSend") }
+		ctx.SendMultimodalMessage = func(string, []FilePart) { _ = deny(CapabilityExec, "SendMultimodalMessage") }
+		ctx.NewSession = func(string) error { return deny(CapabilityExec, "NewSession") }
+		ctx.SpawnSubagent = func(SubagentConfig) (*SubagentHandle, *SubagentResult, error) {
+			return nil, nil, deny(CapabilityExec, "SpawnSubagent")
+		}
+		ctx.Complete = func(CompleteRequest) (CompleteResponse, error) {
+			return CompleteResponse{}, deny(CapabilityExec, "Complete")
+		}
+		ctx.Compact = func(CompactConfig) error { return deny(CapabilityExec, "Compact") }
+		ctx.SummarizeBranch = func(string, string) string { return deny(CapabilityExec, "SummarizeBranch").Error() }
+		ctx.SetModel = func(string) error { return deny(CapabilityExec, "SetModel") }
+		ctx.InjectSkillAsContext = func(string) string { return deny(CapabilityExec, "InjectSkillAsContext").Error() }
+		ctx.InjectRawSkillAsContext = func(string) string { return deny(CapabilityExec, "InjectRawSkillAsContext").Error() }
+	}
+	if !c.Has(CapabilityTools) {
+		ctx.Abort = func() { _ = deny(CapabilityTools, "Abort") }
+		ctx.Exit = func() { _ = deny(CapabilityTools, "Exit") }
+		ctx.SetActiveTools = func([]string) { _ = deny(CapabilityTools, "SetActiveTools") }
+		ctx.ReloadExtensions = func() error { return deny(CapabilityTools, "ReloadExtensions") }
+		ctx.NavigateTo = func(string) TreeNavigationResult {
+			return TreeNavigationResult{Error: deny(CapabilityTools, "NavigateTo").Error()}
+		}
+		ctx.CollapseBranch = func(string, string, string) TreeNavigationResult {
+			return TreeNavigationResult{Error: deny(CapabilityTools, "CollapseBranch").Error()}
+		}
+	}
+	if !c.Has(CapabilityFilesystem) {
+		ctx.LoadSkill = func(string) (*Skill, string) { return nil, deny(CapabilityFilesystem, "LoadSkill").Error() }
+		ctx.LoadSkillsFromDir = func(string) SkillLoadResult {
+			return SkillLoadResult{Error: deny(CapabilityFilesystem, "LoadSkillsFromDir").Error()}
+		}
+		ctx.DiscoverSkills = func() SkillLoadResult {
+			return SkillLoadResult{Error: deny(CapabilityFilesystem, "DiscoverSkills").Error()}
+		}
+		ctx.InjectSkillAsContext = func(string) string { return deny(CapabilityFilesystem, "InjectSkillAsContext").Error() }
+		ctx.InjectRawSkillAsContext = func(string) string { return deny(CapabilityFilesystem, "InjectRawSkillAsContext").Error() }
+	}
+	if !c.Has(CapabilityUI) {
+		ctx.SetWidget = func(WidgetConfig) { _ = deny(CapabilityUI, "SetWidget") }
+		ctx.RemoveWidget = func(string) { _ = deny(CapabilityUI, "RemoveWidget") }
+		ctx.SetHeader = func(HeaderFooterConfig) { _ = deny(CapabilityUI, "SetHeader") }
+		ctx.RemoveHeader = func() { _ = deny(CapabilityUI, "RemoveHeader") }
+		ctx.SetFooter = func(HeaderFooterConfig) { _ = deny(CapabilityUI, "SetFooter") }
+		ctx.RemoveFooter = func() { _ = deny(CapabilityUI, "RemoveFooter") }
+		ctx.SetEditor = func(EditorConfig) { _ = deny(CapabilityUI, "SetEditor") }
+		ctx.ResetEditor = func() { _ = deny(CapabilityUI, "ResetEditor") }
+		ctx.SetEditorText = func(string) { _ = deny(CapabilityUI, "SetEditorText") }
+		ctx.SetUIVisibility = func(UIVisibility) { _ = deny(CapabilityUI, "SetUIVisibility") }
+		ctx.SetStatus = func(string, string, int) { _ = deny(CapabilityUI, "SetStatus") }
+		ctx.RemoveStatus = func(string) { _ = deny(CapabilityUI, "RemoveStatus") }
+		ctx.RenderMessage = func(string, string) { _ = deny(CapabilityUI, "RenderMessage") }
+		ctx.RegisterTheme = func(string, ThemeColorConfig) { _ = deny(CapabilityUI, "RegisterTheme") }
+		ctx.SetTheme = func(string) error { return deny(CapabilityUI, "SetTheme") }
+		ctx.SuspendTUI = func(func()) error { return deny(CapabilityUI, "SuspendTUI") }
+		ctx.PromptSelect = func(PromptSelectConfig) PromptSelectResult {
+			_ = deny(CapabilityUI, "PromptSelect")
+			return PromptSelectResult{Cancelled: true}
+		}
+		ctx.PromptConfirm = func(PromptConfirmConfig) PromptConfirmResult {
+			_ = deny(CapabilityUI, "PromptConfirm")
+			return PromptConfirmResult{Cancelled: true}
+		}
+		ctx.PromptInput = func(PromptInputConfig) PromptInputResult {
+			_ = deny(CapabilityUI, "PromptInput")
+			return PromptInputResult{Cancelled: true}
+		}
+		ctx.PromptMultiSelect = func(PromptMultiSelectConfig) PromptMultiSelectResult {
+			_ = deny(CapabilityUI, "PromptMultiSelect")
+			return PromptMultiSelectResult{Cancelled: true}
+		}
+		ctx.ShowOverlay = func(OverlayConfig) OverlayResult {
+			_ = deny(CapabilityUI, "ShowOverlay")
+			return OverlayResult{Cancelled: true}
+		}
+	}
+	return ctx
+}
+
+// eventCapability is the capability needed to handle events of type t.
+// Handlers that can block or rewrite tool calls and results need tools;
+// everything else needs events.
+func eventCapability(t EventType) Capability {
+	switch t {
+	case ToolCall, ToolResult:
+		return CapabilityTools
+	default:
+		return CapabilityEvents
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Interpreter symbols
+// ---------------------------------------------------------------------------
+
+// capabilityPackages maps standard library packages to the capability that
+// exposes them. A key ending in "/" covers its subpackages too.
+var capabilityPackages = map[string]Capability{
+	"net":             CapabilityNetwork,
+	"net/http":        CapabilityNetwork,
+	"net/http/":       CapabilityNetwork,
+	"net/rpc":         CapabilityNetwork,
+	"net/rpc/":        CapabilityNetwork,
+	"net/smtp":        CapabilityNetwork,
+	"crypto/tls":      CapabilityNetwork,
+	"log/syslog":      CapabilityNetwork,
+	"io/ioutil":       CapabilityFilesystem,
+	"go/build":        CapabilityFilesystem,
+	"go/importer":     CapabilityFilesystem,
+	"debug/buildinfo": CapabilityFilesystem,
+	"debug/elf":       CapabilityFilesystem,
+	"debug/macho":     CapabilityFilesystem,
+	"debug/pe":        CapabilityFilesystem,
+	"debug/plan9obj":  CapabilityFilesystem,
+	"os/exec":         CapabilityExec,
+	"syscall":         CapabilityExec,
+}
+
+// interpreterPackages prefixes the interpreter's own symbol tables, which
+// hand out every standard library function and so are never exposed to an
+// extension that declares capabilities.
+const interpreterPackages = "github.com/traefik/yaegi/"
+
+// capabilitySymbols maps individual symbols of otherwise harmless packages
+// to the capability that exposes them.
+var capabilitySymbols = map[string]map[string]Capability{
+	"os": symbolsFor(CapabilityFilesystem,
+		"Chdir", "Chmod", "Chown", "Chtimes", "CopyFS", "Create", "CreateTemp",
+		"DirFS", "Lchown", "Link", "Lstat", "Mkdir", "MkdirAll", "MkdirTemp",
+		"NewFile", "Open", "OpenFile", "OpenInRoot", "OpenRoot", "ReadDir",
+		"ReadFile", "Readlink", "Remove", "RemoveAll", "Rename", "Stat",
+		"Symlink", "Truncate", "WriteFile",
+	).with(CapabilityExec, "FindProcess", "StartProcess"),
+	"path/filepath": symbolsFor(CapabilityFilesystem, "EvalSymlinks", "Glob", "Walk", "WalkDir"),
+	"text/template": symbolsFor(CapabilityFilesystem, "ParseFiles", "ParseGlob"),
+	"html/template": symbolsFor(CapabilityFilesystem, "ParseFiles", "ParseGlob"),
+	"go/parser":     symbolsFor(CapabilityFilesystem, "ParseDir", "ParseFile"),
+	"archive/zip":   symbolsFor(CapabilityFilesystem, "OpenReader"),
+}
+
+type symbolCapabilities map[string]Capability
+
+func symbolsFor(capability Capability, names ...string) symbolCapabilities {
+	return symbolCapabilities{}.with(capability, names...)
+}
+
+func (s symbolCapabilities) with(capability Capability, names ...string) symbolCapabilities {
+	for _, name := range names {
+		s[name] = capability
+	}
+	return s
+}
+
+// packageCapability returns the capability needed to import importPath.
+func packageCapability(importPath string) (Capability, bool) {
+	if capability, ok := capabilityPackages[importPath]; ok {
+		return capability, true
+	}
+	for prefix, capability := range capabilityPackages {
+		if strings.HasSuffix(prefix, "/") && strings.HasPrefix(importPath, prefix) {
+			return capability, true
+		}
+	}
+	return "", false
+}
+
+// interpreterSymbols returns the standard library symbols an extension
+// granted c may use: the restricted stdlib without the packages and
+// functions c does not cover, plus the unrestricted exec symbols when c
+// grants exec.
+func interpreterSymbols(c Capabilities) []interp.Exports {
+	if !c.Declared {
+		return []interp.Exports{stdlib.Symbols, unrestricted.Symbols}
+	}
+	exports := []interp.Exports{filterSymbols(stdlib.Symbols, c)}
+	if c.Has(CapabilityExec) {
+		exports = append(exports, unrestricted.Symbols)
+	}
+	return exports
+}
+
+// filterSymbols copies the exports c may use. Export keys are
+// "<import path>/<package name>".
+func filterSymbols(exports interp.Exports, c Capabilities) interp.Exports {
+	out := make(interp.Exports, len(exports))
+	for key, symbols := range exports {
+		importPath := key
+		if i := strings.LastIndex(key, "/"); i > 0 {
+			importPath = key[:i]
+		}
+		if strings.HasPrefix(importPath, interpreterPackages) {
+			continue
+		}
+		if capability, ok := packageCapability(importPath); ok && !c.Has(capability) {
+			continue
+		}
+		restricted := capabilitySymbols[importPath]
+		if len(restricted) == 0 {
+			out[key] = symbols
+			continue
+		}
+		kept := make(map[string]reflect.Value, len(symbols))
+		for name, value := range symbols {
+			if capability, ok := restricted[name]; ok && !c.Has(capability) {
+				continue
+			}
+			kept[name] = value
+		}
+		out[key] = kept
+	}
+	return out
+}
+
+// checkSourceCapabilities reports the first import or package-level call in
+// src that c does not cover. The interpreter would reject it anyway; this
+// turns its "undefined" error into one naming the missing capability.
+func checkSourceCapabilities(src string, c Capabilities) error {
+	if !c.Declared {
+		return nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		// Leave syntax errors to the interpreter, which reports them with
+		// the extension's path.
+		return nil
+	}
+
+	imported := make(map[string]string) // local name → import path
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		if strings.HasPrefix(importPath, interpreterPackages) {
+			return fmt.Errorf("line %d: importing %s is not allowed once capabilities are declared", fset.Position(spec.Pos()).Line, importPath)
+		}
+		if capability, ok := packageCapability(importPath); ok && !c.Has(capability) {
+			return fmt.Errorf("line %d: importing %s needs the %q capability", fset.Position(spec.Pos()).Line, importPath, capability)
+		}
+		name := importPath[strings.LastIndex(importPath, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imported[name] = importPath
+	}
+
+	var denied error
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok || denied != nil {
+			return denied == nil
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		importPath, ok := imported[pkg.Name]
+		if !ok {
+			return true
+		}
+		if capability, ok := capabilitySymbols[importPath][sel.Sel.Name]; ok && !c.Has(capability) {
+			denied = fmt.Errorf("line %d: %s.%s needs the %q capability", fset.Position(sel.Pos()).Line, pkg.Name, sel.Sel.Name, capability)
+		}
+		return true
+	})
+	return denied
+}
@@ -0,0 +1,196 @@
+package extensions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/traefik/yaegi/stdlib"
+)
+
+func TestSourceCapabilities(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+		err  string
+	}{
+		{src: "package main\n", want: "undeclared (full access)"},
+		{src: "//kit:capabilities\npackage main\n", want: "none"},
+		{src: "// Weather tool.\n//kit:capabilities tools, network\n//kit:capabilities Events tools\npackage main\n", want: "tools, network, events"},
+		{src: "//kit:capabilitiesx tools\npackage main\n", want: "undeclared (full access)"},
+		{src: "package main\n//kit:capabilities tools\n", want: "undeclared (full access)"},
+		{src: "//kit:capabilities tools disk\npackage main\n", err: `unknown capability "disk"`},
+	}
+	for _, tt := range tests {
+		got, err := sourceCapabilities(tt.src)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: err = %v, want %q", tt.src, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%q: got %q, %v; want %q", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestFilterSymbols(t *testing.T) {
+	caps, _ := ParseCapabilities([]string{"events"})
+	filtered := filterSymbols(stdlib.Symbols, caps)
+	for _, key := range []string{"net/http/http", "net/http/httptest/httptest", "io/ioutil/ioutil", "github.com/traefik/yaegi/stdlib/stdlib"} {
+		if _, ok := filtered[key]; ok {
+			t.Errorf("%s exposed without its capability", key)
+		}
+	}
+	if _, ok := filtered["net/url/url"]; !ok {
+		t.Error("net/url should stay available")
+	}
+	if _, ok := filtered["os/os"]["ReadFile"]; ok {
+		t.Error("os.ReadFile exposed without filesystem")
+	}
+	if _, ok := filtered["os/os"]["Getenv"]; !ok {
+		t.Error("os.Getenv should stay available")
+	}
+	if _, ok := stdlib.Symbols["os/os"]["ReadFile"]; !ok {
+		t.Error("filtering modified the shared stdlib symbols")
+	}
+
+	caps, _ = ParseCapabilities([]string{"filesystem"})
+	if _, ok := filterSymbols(stdlib.Symbols, caps)["os/os"]["ReadFile"]; !ok {
+		t.Error("os.ReadFile missing with filesystem granted")
+	}
+}
+
+func writeExtensionSource(t *testing.T, src string) string {
+	t.Helper()
+	f := filepath.Join(t.TempDir(), "ext.go")
+	if err := os.WriteFile(f, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestLoadSingleExtension_CapabilitiesRestrictRegistration(t *testing.T) {
+	f := writeExtensionSource(t, `//kit:capabilities events
+package main
+
+import "kit/ext"
+
+func Init(api ext.API) {
+	api.OnToolCall(func(tc ext.ToolCallEvent, ctx ext.Context) *ext.ToolCallResult { return nil })
+	api.OnSessionStart(func(se ext.SessionStartEvent, ctx ext.Context) {})
+	api.RegisterTool(ext.ToolDef{Name: "t", Description: "d", Execute: func(string) (string, error) { return "", nil }})
+	api.RegisterCommand(ext.CommandDef{Name: "c", Execute: func(string, ext.Context) (string, error) { return "", nil }})
+}
+`)
+	ext, err := loadSingleExtension(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ext.Capabilities.String(); got != "events" {
+		t.Errorf("capabilities = %q", got)
+	}
+	if len(ext.Handlers[SessionStart]) != 1 {
+		t.Error("events capability should allow OnSessionStart")
+	}
+	if len(ext.Handlers[ToolCall]) != 0 || len(ext.Tools) != 0 || len(ext.Commands) != 0 {
+		t.Errorf("denied registrations kept: %d tool_call handlers, %d tools, %d commands",
+			len(ext.Handlers[ToolCall]), len(ext.Tools), len(ext.Commands))
+	}
+}
+
+func TestLoadSingleExtension_CapabilitiesRestrictContext(t *testing.T) {
+	for _, tt := range []struct {
+		caps, want string
+	}{
+		{caps: "events", want: "print"},
+		{caps: "events exec tools", want: "print,send,abort"},
+		{caps: "events filesystem ui", want: "print,skill,status"},
+		{caps: "", want: "print,send,abort,skill,status"},
+	} {
+		src := "package main\n\nimport \"kit/ext\"\n\nfunc Init(api ext.API) {\n" +
+			"\tapi.OnAgentEnd(func(e ext.AgentEndEvent, ctx ext.Context) {\n" +
+			"\t\tctx.Print(\"done\")\n\t\tctx.SendMessage(\"again\")\n\t\tctx.Abort()\n" +
+			"\t\tctx.LoadSkill(\"/home/user/.ssh/id_rsa\")\n\t\tctx.SetStatus(\"k\", \"v\", 0)\n\t})\n}\n"
+		if tt.caps != "" {
+			src = "//kit:capabilities " + tt.caps + "\n" + src
+		}
+		ext, err := loadSingleExtension(writeExtensionSource(t, src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var calls []string
+		runner := NewRunner([]LoadedExtension{*ext})
+		runner.SetContext(Context{
+			Print:       func(string) { calls = append(calls, "print") },
+			SendMessage: func(string) { calls = append(calls, "send") },
+			Abort:       func() { calls = append(calls, "abort") },
+			LoadSkill: func(string) (*Skill, string) {
+				calls = append(calls, "skill")
+				return nil, ""
+			},
+			SetStatus: func(string, string, int) { calls = append(calls, "status") },
+		})
+		_, _ = runner.Emit(AgentEndEvent{})
+		if got := strings.Join(calls, ","); got != tt.want {
+			t.Errorf("capabilities %q: calls = %q, want %q", tt.caps, got, tt.want)
+		}
+	}
+}
+
+func TestLoadSingleExtension_CapabilitiesRestrictSymbols(t *testing.T) {
+	tests := []struct {
+		caps, imports, body, err string
+	}{
+		{caps: "events", imports: `"net/http"`, body: `_ = http.MethodGet`, err: `importing net/http needs the "network" capability`},
+		{caps: "events", imports: `"os"`, body: `_, _ = os.ReadFile("x")`, err: `os.ReadFile needs the "filesystem" capability`},
+		{caps: "events", imports: `"os/exec"`, body: `_ = exec.Command`, err: `"exec" capability`},
+		{caps: "events", imports: `"github.com/traefik/yaegi/stdlib"`, body: `_ = stdlib.Symbols`, err: "not allowed"},
+		{caps: "events", imports: `"archive/zip"`, body: `_, _ = zip.OpenReader("x.zip")`, err: `zip.OpenReader needs the "filesystem" capability`},
+		{caps: "events", imports: `"os"`, body: `_ = os.Getenv("HOME")`},
+		{caps: "filesystem", imports: `"os"`, body: `_, _ = os.ReadFile("x")`},
+		{caps: "exec", imports: `"os/exec"`, body: `_ = exec.Command`},
+	}
+	for _, tt := range tests {
+		src := "//kit:capabilities " + tt.caps + "\npackage main\n\nimport (\n\t\"kit/ext\"\n\t" + tt.imports +
+			"\n)\n\nfunc Init(api ext.API) {\n\t" + tt.body + "\n}\n"
+		_, err := loadSingleExtension(writeExtensionSource(t, src))
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s with %s: %v", tt.body, tt.caps, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s with %s: err = %v, want %q", tt.body, tt.caps, err, tt.err)
+		}
+	}
+}
+
+func TestDiscoverExtensionPaths_ProjectTrust(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	project := t.TempDir()
+	t.Chdir(project)
+	local := filepath.Join(".kit", "extensions", "local.go")
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(local, []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var asked []int
+	deny := func(dir string, count int) bool {
+		asked = append(asked, count)
+		return false
+	}
+	if paths := discoverExtensionPaths(nil, deny); len(paths) != 0 {
+		t.Errorf("untrusted project loaded %v", paths)
+	}
+	if len(asked) != 1 || asked[0] != 1 {
+		t.Errorf("trust asked with counts %v", asked)
+	}
+	if paths := discoverExtensionPaths(nil, func(string, int) bool { return true }); len(paths) != 1 {
+		t.Errorf("trusted project loaded %v", paths)
+	}
+}
@@ -144,6 +144,11 @@ const (
 	// deltas for that single call. Extensions use it to attribute usage to
 	// specific calls/models and to drive budget enforcement between calls.
 	LLMUsage EventType = "llm_usage"
+
+	// SkillScope fires when an activated skill starts restricting the
+	// tools the agent may call, and again when the restriction ends with
+	// the turn.
+	SkillScope EventType = "skill_scope"
 )
 
 // AllEventTypes returns every supported event type.
@@ -159,7 +164,7 @@ func AllEventTypes() []EventType {
 		BeforeFork, BeforeSessionSwitch, BeforeCompact,
 		SubagentStart, SubagentChunk, SubagentEnd,
 		StepStart, StepFinish, ReasoningStart, Warnings, Source, Error, Retry,
-		PrepareStep, LLMUsage,
+		PrepareStep, LLMUsage, SkillScope,
 	}
 }
 
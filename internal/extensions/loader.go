@@ -8,8 +8,6 @@ import (
 
 	"github.com/charmbracelet/log"
 	"github.com/traefik/yaegi/interp"
-	"github.com/traefik/yaegi/stdlib"
-	"github.com/traefik/yaegi/stdlib/unrestricted"
 )
 
 // Discovery paths searched in order (lowest to highest precedence):
@@ -19,21 +17,46 @@ import (
 //	.kit/extensions/*.go                   project-local single files
 //	.kit/extensions/*/main.go              project-local subdirectories
 //
-// Explicit paths passed via --extension / -e flags are appended last.
+// A subdirectory with a kit-extension.json manifest instead of main.go
+// holds an out-of-process extension (see rpc.go). Explicit paths passed via
+// --extension / -e flags are appended last.
+
+// ProjectTrustFunc decides whether the project-local extensions found in
+// projectDir may load. count is how many were found.
+type ProjectTrustFunc func(projectDir string, count int) bool
 
 // LoadExtensions discovers and loads extensions from standard locations and
-// any extra paths. Each extension is loaded into its own Yaegi interpreter
-// for isolation. Extensions that fail to load are logged and skipped.
+// any extra paths. Each Go extension is loaded into its own Yaegi
+// interpreter for isolation; out-of-process extensions are started and
+// initialised. Extensions that fail to load are logged and skipped.
 func LoadExtensions(extraPaths []string) ([]LoadedExtension, error) {
-	paths := discoverExtensionPaths(extraPaths)
+	return LoadExtensionsWithTrust(extraPaths, nil)
+}
+
+// LoadExtensionsWithTrust is LoadExtensions with project-local extensions
+// (.kit/extensions and .kit/git) gated on trusted. A freshly cloned
+// repository can ship extensions that run as soon as Kit starts in it, so
+// they only load once the project is trusted. A nil trusted loads them
+// unconditionally; explicit paths are never gated.
+func LoadExtensionsWithTrust(extraPaths []string, trusted ProjectTrustFunc) ([]LoadedExtension, error) {
+	paths := discoverExtensionPaths(extraPaths, trusted)
 	if len(paths) == 0 {
 		return nil, nil
 	}
 
 	var loaded []LoadedExtension
 	for _, p := range paths {
-		ext, err := loadSingleExtension(p)
+		var (
+			ext *LoadedExtension
+			err error
+		)
+		if isRPCExtensionPath(p) {
+			ext, err = loadRPCExtension(p)
+		} else {
+			ext, err = loadSingleExtension(p)
+		}
 		if err != nil {
+			log.Warn("Failed to load extension", "path", p, "error", err)
 			continue
 		}
 		loaded = append(loaded, *ext)
@@ -66,8 +89,9 @@ func (ps *pathSet) add(p string) bool {
 }
 
 // discoverExtensionPaths returns deduplicated paths to extension files in
-// load-order (global first, then project-local, then explicit).
-func discoverExtensionPaths(extraPaths []string) []string {
+// load-order (global first, then project-local, then explicit). Project-local
+// extensions are skipped when trusted rejects the project.
+func discoverExtensionPaths(extraPaths []string, trusted ProjectTrustFunc) []string {
 	ps := newPathSet()
 
 	// Global extensions: $XDG_CONFIG_HOME/kit/extensions/ (default ~/.config/kit/extensions/)
@@ -82,15 +106,18 @@ func discoverExtensionPaths(extraPaths []string) []string {
 		ps.add(p)
 	}
 
-	// Project-local extensions: .kit/extensions/
-	localDir := filepath.Join(".kit", "extensions")
-	for _, p := range findExtensionsInDir(localDir) {
-		ps.add(p)
+	// Project-local extensions: .kit/extensions/ and installed git
+	// packages in .kit/git/
+	project := findExtensionsInDir(filepath.Join(".kit", "extensions"))
+	project = append(project, findExtensionsInGitPackages(filepath.Join(".kit", "git"))...)
+	if len(project) > 0 && trusted != nil {
+		cwd, _ := os.Getwd()
+		if !trusted(cwd, len(project)) {
+			log.Info("Skipping extensions of untrusted project", "dir", cwd, "count", len(project))
+			project = nil
+		}
 	}
-
-	// Project-local installed git packages: .kit/git/
-	projectGitDir := filepath.Join(".kit", "git")
-	for _, p := range findExtensionsInGitPackages(projectGitDir) {
+	for _, p := range project {
 		ps.add(p)
 	}
 
@@ -104,7 +131,7 @@ func discoverExtensionPaths(extraPaths []string) []string {
 			for _, found := range findExtensionsInDir(p) {
 				ps.add(found)
 			}
-		} else if strings.HasSuffix(p, ".go") {
+		} else if strings.HasSuffix(p, ".go") || isRPCExtensionPath(p) {
 			ps.add(p)
 		}
 	}
@@ -112,7 +139,8 @@ func discoverExtensionPaths(extraPaths []string) []string {
 	return ps.list
 }
 
-// findExtensionsInDir returns .go files in dir and main.go in immediate subdirs.
+// findExtensionsInDir returns .go files in dir and, for each immediate
+// subdir, its main.go or else its kit-extension.json.
 func findExtensionsInDir(dir string) []string {
 	info, err := os.Stat(dir)
 	if err != nil || !info.IsDir() {
@@ -132,8 +160,11 @@ func findExtensionsInDir(dir string) []string {
 			results = append(results, full)
 		} else if entry.IsDir() {
 			main := filepath.Join(full, "main.go")
+			manifest := filepath.Join(full, RPCManifestName)
 			if _, err := os.Stat(main); err == nil {
 				results = append(results, main)
+			} else if _, err := os.Stat(manifest); err == nil {
+				results = append(results, manifest)
 			}
 		}
 	}
@@ -402,6 +433,21 @@ func loadSingleExtension(path string) (*LoadedExtension, error) {
 		Handlers: make(map[EventType][]HandlerFunc),
 	}
 
+	// Read the source first: its capability declaration decides which
+	// symbols the interpreter exposes.
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	caps, err := sourceCapabilities(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("capabilities: %w", err)
+	}
+	if err := checkSourceCapabilities(string(src), caps); err != nil {
+		return nil, err
+	}
+	ext.Capabilities = caps
+
 	// Create a fresh interpreter. Yaegi runs extensions in restricted mode,
 	// where os.Getenv/os.LookupEnv/os.Environ read from a virtualized
 	// environment rather than the real one. Seed it with the process
@@ -411,11 +457,12 @@ func loadSingleExtension(path string) (*LoadedExtension, error) {
 
 	// Expose the Go stdlib. The base set covers most packages; the
 	// unrestricted set adds os/exec so extensions can spawn processes.
-	if err := i.Use(stdlib.Symbols); err != nil {
-		return nil, fmt.Errorf("loading stdlib symbols: %w", err)
-	}
-	if err := i.Use(unrestricted.Symbols); err != nil {
-		return nil, fmt.Errorf("loading unrestricted symbols: %w", err)
+	// Extensions that declare capabilities get only the packages and
+	// functions those capabilities cover.
+	for _, symbols := range interpreterSymbols(caps) {
+		if err := i.Use(symbols); err != nil {
+			return nil, fmt.Errorf("loading stdlib symbols: %w", err)
+		}
 	}
 
 	// Expose KIT's extension API types so the extension can
@@ -424,12 +471,6 @@ func loadSingleExtension(path string) (*LoadedExtension, error) {
 		return nil, fmt.Errorf("loading extension symbols: %w", err)
 	}
 
-	// Read and evaluate the extension source file.
-	src, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("reading file: %w", err)
-	}
-
 	if err := evalExtensionSource(i, string(src)); err != nil {
 		return nil, fmt.Errorf("evaluating source: %w", err)
 	}
@@ -449,9 +490,16 @@ func loadSingleExtension(path string) (*LoadedExtension, error) {
 	// Build the API object that wires typed registration methods back to
 	// the extension's internal handler map. Each method wraps the concrete
 	// handler into the internal HandlerFunc type via the notifyReg/resultReg
-	// helpers below.
+	// helpers below. Registrations the extension's capabilities do not
+	// cover are dropped with a warning, and what is registered receives a
+	// Context without the functions they do not cover.
+	restrict := func(ctx Context) Context { return caps.restrictContext(ctx, path) }
 	reg := func(event EventType, fn HandlerFunc) {
-		ext.Handlers[event] = append(ext.Handlers[event], fn)
+		if caps.allow(eventCapability(event), path, "event "+string(event)) {
+			ext.Handlers[event] = append(ext.Handlers[event], func(e Event, ctx Context) Result {
+				return fn(e, restrict(ctx))
+			})
+		}
 	}
 
 	api := API{
@@ -481,18 +529,40 @@ func loadSingleExtension(path string) (*LoadedExtension, error) {
 		onBeforeSessionSwitch: resultReg[BeforeSessionSwitchEvent, BeforeSessionSwitchResult](reg, BeforeSessionSwitch),
 		onBeforeCompact:       resultReg[BeforeCompactEvent, BeforeCompactResult](reg, BeforeCompact),
 		registerToolFn: func(tool ToolDef) {
-			ext.Tools = append(ext.Tools, tool)
+			if caps.allow(CapabilityTools, path, "RegisterTool") {
+				ext.Tools = append(ext.Tools, tool)
+			}
 		},
 		registerCmdFn: func(cmd CommandDef) {
+			if !caps.allow(CapabilityUI, path, "RegisterCommand") {
+				return
+			}
+			if execute := cmd.Execute; execute != nil {
+				cmd.Execute = func(args string, ctx Context) (string, error) {
+					return execute(args, restrict(ctx))
+				}
+			}
+			if complete := cmd.Complete; complete != nil {
+				cmd.Complete = func(prefix string, ctx Context) []string {
+					return complete(prefix, restrict(ctx))
+				}
+			}
 			ext.Commands = append(ext.Commands, cmd)
 		},
 		registerToolRendererFn: func(config ToolRenderConfig) {
-			ext.ToolRenderers = append(ext.ToolRenderers, config)
+			if caps.allow(CapabilityUI, path, "RegisterToolRenderer") {
+				ext.ToolRenderers = append(ext.ToolRenderers, config)
+			}
 		},
 		registerMessageRendererFn: func(config MessageRendererConfig) {
-			ext.MessageRenderers = append(ext.MessageRenderers, config)
+			if caps.allow(CapabilityUI, path, "RegisterMessageRenderer") {
+				ext.MessageRenderers = append(ext.MessageRenderers, config)
+			}
 		},
 		onCustomEvent: func(name string, handler func(string)) {
+			if !caps.allow(CapabilityEvents, path, "OnCustomEvent") {
+				return
+			}
 			if ext.CustomEventHandlers == nil {
 				ext.CustomEventHandlers = make(map[string][]func(string))
 			}
@@ -502,6 +572,13 @@ func loadSingleExtension(path string) (*LoadedExtension, error) {
 			ext.Options = append(ext.Options, opt)
 		},
 		registerShortcutFn: func(def ShortcutDef, handler func(Context)) {
+			if !caps.allow(CapabilityUI, path, "RegisterShortcut") {
+				return
+			}
+			if handler != nil {
+				run := handler
+				handler = func(ctx Context) { run(restrict(ctx)) }
+			}
 			if entry, ok := prepareShortcut(def, handler, path); ok {
 				ext.Shortcuts = append(ext.Shortcuts, entry)
 			}
@@ -518,6 +595,7 @@ func loadSingleExtension(path string) (*LoadedExtension, error) {
 		onRetry:          notifyReg[RetryEvent](reg, Retry),
 		onPrepareStep:    resultReg[PrepareStepEvent, PrepareStepResult](reg, PrepareStep),
 		onLLMUsage:       notifyReg[LLMUsageEvent](reg, LLMUsage),
+		onSkillScope:     notifyReg[SkillScopeEvent](reg, SkillScope),
 	}
 
 	// Call Init — the extension registers its handlers, tools, commands.
@@ -15,7 +15,7 @@ func TestDiscoverExtensionPaths_ExplicitFile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	paths := discoverExtensionPaths([]string{f})
+	paths := discoverExtensionPaths([]string{f}, nil)
 	if len(paths) == 0 {
 		t.Fatal("expected at least 1 path")
 	}
@@ -33,7 +33,7 @@ func TestDiscoverExtensionPaths_ExplicitDir(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	paths := discoverExtensionPaths([]string{dir})
+	paths := discoverExtensionPaths([]string{dir}, nil)
 	abs, _ := filepath.Abs(f)
 	if !slices.Contains(paths, abs) {
 		t.Errorf("expected %q in discovered paths %v", abs, paths)
@@ -51,7 +51,7 @@ func TestDiscoverExtensionPaths_SubdirMainGo(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	paths := discoverExtensionPaths([]string{dir})
+	paths := discoverExtensionPaths([]string{dir}, nil)
 	abs, _ := filepath.Abs(main)
 	if !slices.Contains(paths, abs) {
 		t.Errorf("expected %q in discovered paths %v", abs, paths)
@@ -66,7 +66,7 @@ func TestDiscoverExtensionPaths_Dedup(t *testing.T) {
 	}
 
 	// Pass the same file twice.
-	paths := discoverExtensionPaths([]string{f, f})
+	paths := discoverExtensionPaths([]string{f, f}, nil)
 	count := 0
 	abs, _ := filepath.Abs(f)
 	for _, p := range paths {
@@ -86,7 +86,7 @@ func TestDiscoverExtensionPaths_NonGoFileIgnored(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	paths := discoverExtensionPaths([]string{f})
+	paths := discoverExtensionPaths([]string{f}, nil)
 	for _, p := range paths {
 		abs, _ := filepath.Abs(f)
 		if p == abs {
@@ -96,7 +96,7 @@ func TestDiscoverExtensionPaths_NonGoFileIgnored(t *testing.T) {
 }
 
 func TestDiscoverExtensionPaths_NonexistentIgnored(t *testing.T) {
-	paths := discoverExtensionPaths([]string{"/nonexistent/path/ext.go"})
+	paths := discoverExtensionPaths([]string{"/nonexistent/path/ext.go"}, nil)
 	for _, p := range paths {
 		if p == "/nonexistent/path/ext.go" {
 			t.Error("nonexistent path should not be discovered")
@@ -125,6 +125,11 @@ type ExtensionPreview struct {
 	Description string `json:"description,omitempty"`
 	// IsMain indicates if this is a main.go in a subdirectory
 	IsMain bool `json:"is_main"`
+	// Capabilities is what the extension declares it needs.
+	Capabilities Capabilities `json:"capabilities"`
+	// CapabilitiesErr explains an unreadable declaration; such an extension
+	// would fail to load.
+	CapabilitiesErr string `json:"capabilities_error,omitempty"`
 }
 
 // ScanForExtensions discovers all extensions in a directory using opinionated conventions.
@@ -288,6 +293,13 @@ func ScanForExtensions(dir string) ([]ExtensionPreview, error) {
 		return nil, err
 	}
 
+	for i := range previews {
+		caps, err := ReadCapabilities(filepath.Join(dir, filepath.FromSlash(previews[i].Path)))
+		if err != nil {
+			previews[i].CapabilitiesErr = err.Error()
+		}
+		previews[i].Capabilities = caps
+	}
 	return previews, nil
 }
 
@@ -0,0 +1,742 @@
+package extensions
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// ---------------------------------------------------------------------------
+// Out-of-process extensions
+// ---------------------------------------------------------------------------
+//
+// Besides Go source run by Yaegi, an extension can be any executable that
+// speaks JSON-RPC 2.0 over stdio, one message per line. Kit starts it,
+// sends "initialize", and turns the registration it answers with into the
+// same handlers, tools, commands and shortcuts a Yaegi extension gets from
+// Init. The process is restarted if it crashes, and every call into it is
+// bounded by a timeout, so a misbehaving extension can neither take Kit
+// down nor stall the agent.
+//
+// A directory holding a kit-extension.json manifest is loaded this way; an
+// executable passed with --extension is run directly with the defaults.
+
+// RPCManifestName is the manifest file that marks a directory as an
+// out-of-process extension.
+const RPCManifestName = "kit-extension.json"
+
+// RPCProtocolVersion is sent in "initialize" and bumped on incompatible
+// protocol changes.
+const RPCProtocolVersion = 1
+
+const (
+	defaultRPCTimeout     = 5 * time.Second
+	defaultRPCToolTimeout = 10 * time.Minute
+	// maxRPCRestarts is how many crashes an extension survives before Kit
+	// gives up on it for the rest of the session.
+	maxRPCRestarts = 3
+	// rpcShutdownGrace is how long a process may take to exit after
+	// "shutdown" before it is killed.
+	rpcShutdownGrace = 2 * time.Second
+)
+
+// RPCManifest is the content of kit-extension.json.
+type RPCManifest struct {
+	// Name identifies the extension in logs. Defaults to the directory name.
+	Name string `json:"name,omitempty"`
+	// Command is the program and arguments to run, resolved relative to the
+	// manifest's directory, e.g. ["python3", "main.py"].
+	Command []string `json:"command"`
+	// Env adds variables to the process environment.
+	Env map[string]string `json:"env,omitempty"`
+	// Timeout bounds event handlers and commands (Go duration, default 5s).
+	// A handler that times out is skipped as if it returned nothing.
+	Timeout string `json:"timeout,omitempty"`
+	// ToolTimeout bounds tool executions (default 10m).
+	ToolTimeout string `json:"toolTimeout,omitempty"`
+	// Capabilities lists what the extension may register (see
+	// capabilities.go). An absent list grants everything; an empty one
+	// grants nothing.
+	Capabilities []string `json:"capabilities"`
+}
+
+// capabilities returns the declared capabilities, telling an absent list
+// (nil after decoding) from an empty one.
+func (m RPCManifest) capabilities() (Capabilities, error) {
+	if m.Capabilities == nil {
+		return Capabilities{}, nil
+	}
+	return ParseCapabilities(m.Capabilities)
+}
+
+// rpcInitializeParams is sent with "initialize".
+type rpcInitializeParams struct {
+	ProtocolVersion int         `json:"protocolVersion"`
+	CWD             string      `json:"cwd"`
+	Events          []EventType `json:"events"`
+}
+
+// rpcRegistration is the "initialize" result: everything the extension
+// would otherwise register through ext.API in Init.
+type rpcRegistration struct {
+	Name      string           `json:"name,omitempty"`
+	Events    []EventType      `json:"events,omitempty"`
+	Tools     []rpcToolDef     `json:"tools,omitempty"`
+	Commands  []rpcCommandDef  `json:"commands,omitempty"`
+	Shortcuts []rpcShortcutDef `json:"shortcuts,omitempty"`
+}
+
+type rpcToolDef struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"` // JSON Schema
+}
+
+type rpcCommandDef struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Complete, when true, routes argument tab-completion to the extension
+	// via "command/complete".
+	Complete bool `json:"complete,omitempty"`
+}
+
+type rpcShortcutDef struct {
+	Key         string `json:"key"`
+	Description string `json:"description"`
+}
+
+// rpcEventParams is sent with "event". Event carries the ext event struct
+// with its Go field names, e.g. {"ToolName": "bash", ...}.
+type rpcEventParams struct {
+	Type  EventType `json:"type"`
+	Event Event     `json:"event"`
+}
+
+type rpcToolParams struct {
+	Invocation int64           `json:"invocation"`
+	Name       string          `json:"name"`
+	Input      json.RawMessage `json:"input"`
+}
+
+type rpcToolResult struct {
+	Content string `json:"content"`
+	IsError bool   `json:"isError,omitempty"`
+}
+
+// rpcResultDecoders maps each event whose handlers may return a result to
+// a decoder for that result. Other events are sent as notifications.
+var rpcResultDecoders = map[EventType]func(json.RawMessage) (Result, error){
+	ToolCall:            decodeRPCResult[ToolCallResult],
+	ToolResult:          decodeRPCResult[ToolResultResult],
+	Input:               decodeRPCResult[InputResult],
+	BeforeAgentStart:    decodeRPCResult[BeforeAgentStartResult],
+	ContextPrepare:      decodeRPCResult[ContextPrepareResult],
+	BeforeFork:          decodeRPCResult[BeforeForkResult],
+	BeforeSessionSwitch: decodeRPCResult[BeforeSessionSwitchResult],
+	BeforeCompact:       decodeRPCResult[BeforeCompactResult],
+	PrepareStep:         decodeRPCResult[PrepareStepResult],
+}
+
+func decodeRPCResult[R Result](raw json.RawMessage) (Result, error) {
+	var r R
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// isRPCExtensionPath reports whether path names an out-of-process
+// extension: a kit-extension.json manifest, or an executable file that is
+// not Go source.
+func isRPCExtensionPath(path string) bool {
+	if filepath.Base(path) == RPCManifestName {
+		return true
+	}
+	if strings.HasSuffix(path, ".go") {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode()&0o111 != 0
+}
+
+// rpcProcess supervises one extension process and the connection to it.
+type rpcProcess struct {
+	name        string
+	dir         string
+	command     []string
+	env         []string
+	timeout     time.Duration
+	toolTimeout time.Duration
+	caps        Capabilities
+
+	mu       sync.Mutex
+	conn     *rpcConn
+	proc     *os.Process
+	stdin    io.Closer
+	exited   chan struct{}
+	closing  bool
+	restarts int
+	ctx      Context // context of the most recent call, for host methods
+
+	progressMu     sync.Mutex
+	nextInvocation int64
+	progress       map[int64]func(string)
+}
+
+// newRPCProcess builds the supervisor for path without starting it.
+func newRPCProcess(path string) (*rpcProcess, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	var m RPCManifest
+	dir := filepath.Dir(abs)
+	if filepath.Base(abs) == RPCManifestName {
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return nil, fmt.Errorf("reading manifest: %w", err)
+		}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("parsing manifest: %w", err)
+		}
+		if len(m.Command) == 0 {
+			return nil, fmt.Errorf("%s: command is required", RPCManifestName)
+		}
+		if m.Name == "" {
+			m.Name = filepath.Base(dir)
+		}
+	} else {
+		m = RPCManifest{Name: filepath.Base(abs), Command: []string{abs}}
+	}
+
+	p := &rpcProcess{
+		name:        m.Name,
+		dir:         dir,
+		command:     m.Command,
+		env:         os.Environ(),
+		timeout:     defaultRPCTimeout,
+		toolTimeout: defaultRPCToolTimeout,
+		ctx:         normalizeContext(Context{}),
+		progress:    make(map[int64]func(string)),
+	}
+	for k, v := range m.Env {
+		p.env = append(p.env, k+"="+v)
+	}
+	if m.Timeout != "" {
+		if p.timeout, err = time.ParseDuration(m.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+	if m.ToolTimeout != "" {
+		if p.toolTimeout, err = time.ParseDuration(m.ToolTimeout); err != nil {
+			return nil, fmt.Errorf("invalid toolTimeout: %w", err)
+		}
+	}
+	if p.caps, err = m.capabilities(); err != nil {
+		return nil, fmt.Errorf("%s: %w", RPCManifestName, err)
+	}
+	return p, nil
+}
+
+// start launches the process and performs the "initialize" handshake.
+func (p *rpcProcess) start() (*rpcRegistration, error) {
+	name := p.command[0]
+	if strings.Contains(name, "/") && !filepath.IsAbs(name) {
+		name = filepath.Join(p.dir, name)
+	}
+	cmd := exec.Command(name, p.command[1:]...)
+	cmd.Dir = p.dir
+	cmd.Env = p.env
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting %s: %w", p.command[0], err)
+	}
+
+	conn := newRPCConn(stdout, stdin, p.handle)
+	exited := make(chan struct{})
+	go p.logStderr(stderr)
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+		p.exitedUnexpectedly(conn)
+	}()
+
+	p.mu.Lock()
+	p.conn, p.proc, p.stdin, p.exited = conn, cmd.Process, stdin, exited
+	p.mu.Unlock()
+
+	cwd, _ := os.Getwd()
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	var reg rpcRegistration
+	err = conn.call(ctx, "initialize", rpcInitializeParams{
+		ProtocolVersion: RPCProtocolVersion,
+		CWD:             cwd,
+		Events:          AllEventTypes(),
+	}, &reg)
+	if err != nil {
+		p.close()
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	return &reg, nil
+}
+
+// logStderr forwards the extension's stderr to the debug log.
+func (p *rpcProcess) logStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log.Debug("extension stderr", "extension", p.name, "line", scanner.Text())
+	}
+}
+
+// exitedUnexpectedly restarts the process after a crash, with a growing
+// delay, until maxRPCRestarts is reached. Registrations from a restarted
+// process are ignored: Kit keeps the tools and handlers of the first start.
+func (p *rpcProcess) exitedUnexpectedly(conn *rpcConn) {
+	p.mu.Lock()
+	if p.closing || p.conn != conn {
+		p.mu.Unlock()
+		return
+	}
+	p.conn = nil
+	p.restarts++
+	attempt := p.restarts
+	p.mu.Unlock()
+
+	if attempt > maxRPCRestarts {
+		log.Warn("Extension crashed too often; disabled for this session", "extension", p.name)
+		return
+	}
+	log.Warn("Extension process exited; restarting", "extension", p.name, "attempt", attempt)
+	time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
+
+	p.mu.Lock()
+	closing := p.closing
+	p.mu.Unlock()
+	if closing {
+		return
+	}
+	if _, err := p.start(); err != nil {
+		log.Warn("Extension restart failed", "extension", p.name, "error", err)
+	}
+}
+
+// current returns the live connection, or an error while the process is
+// down.
+func (p *rpcProcess) current() (*rpcConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		return nil, fmt.Errorf("extension %s is not running", p.name)
+	}
+	return p.conn, nil
+}
+
+// call sends a request bounded by timeout.
+func (p *rpcProcess) call(timeout time.Duration, method string, params, result any) error {
+	conn, err := p.current()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return conn.call(ctx, method, params, result)
+}
+
+func (p *rpcProcess) notify(method string, params any) {
+	if conn, err := p.current(); err == nil {
+		_ = conn.notify(method, params)
+	}
+}
+
+func (p *rpcProcess) setContext(ctx Context) {
+	ctx = p.caps.restrictContext(normalizeContext(ctx), p.name)
+	p.mu.Lock()
+	p.ctx = ctx
+	p.mu.Unlock()
+}
+
+func (p *rpcProcess) currentContext() Context {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ctx
+}
+
+// close asks the process to exit and kills it if it does not.
+func (p *rpcProcess) close() {
+	p.mu.Lock()
+	if p.closing {
+		p.mu.Unlock()
+		return
+	}
+	p.closing = true
+	conn, proc, stdin, exited := p.conn, p.proc, p.stdin, p.exited
+	p.mu.Unlock()
+
+	if conn != nil {
+		_ = conn.notify("shutdown", nil)
+	}
+	if stdin != nil {
+		_ = stdin.Close()
+	}
+	if exited == nil {
+		return
+	}
+	select {
+	case <-exited:
+	case <-time.After(rpcShutdownGrace):
+		_ = proc.Kill()
+		<-exited
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Host methods
+// ---------------------------------------------------------------------------
+
+type rpcTextParams struct {
+	Text string `json:"text"`
+}
+
+type rpcWidgetParams struct {
+	ID          string          `json:"id"`
+	Placement   WidgetPlacement `json:"placement,omitempty"`
+	Text        string          `json:"text"`
+	Markdown    bool            `json:"markdown,omitempty"`
+	BorderColor string          `json:"borderColor,omitempty"`
+	NoBorder    bool            `json:"noBorder,omitempty"`
+	Priority    int             `json:"priority,omitempty"`
+}
+
+type rpcStatusParams struct {
+	Key      string `json:"key"`
+	Text     string `json:"text,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+type rpcStateParams struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+type rpcProgressParams struct {
+	Invocation int64  `json:"invocation"`
+	Text       string `json:"text"`
+}
+
+// handle serves requests and notifications from the extension. Methods
+// prefixed "ctx/" mirror the ext.Context functions of the same name.
+func (p *rpcProcess) handle(method string, raw json.RawMessage) (any, error) {
+	ctx := p.currentContext()
+	decode := func(v any) error {
+		if len(raw) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(raw, v); err != nil {
+			return &RPCError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		return nil
+	}
+
+	switch method {
+	case "ctx/print", "ctx/printInfo", "ctx/printError", "ctx/sendMessage":
+		var params rpcTextParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		fn := map[string]func(string){
+			"ctx/print":       ctx.Print,
+			"ctx/printInfo":   ctx.PrintInfo,
+			"ctx/printError":  ctx.PrintError,
+			"ctx/sendMessage": ctx.SendMessage,
+		}[method]
+		fn(params.Text)
+		return nil, nil
+	case "ctx/abort":
+		ctx.Abort()
+		return nil, nil
+	case "ctx/setWidget":
+		var params rpcWidgetParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		if params.ID == "" {
+			return nil, &RPCError{Code: rpcInvalidParams, Message: "id is required"}
+		}
+		if params.Placement == "" {
+			params.Placement = WidgetAbove
+		}
+		ctx.SetWidget(WidgetConfig{
+			ID:        params.ID,
+			Placement: params.Placement,
+			Content:   WidgetContent{Text: params.Text, Markdown: params.Markdown},
+			Style:     WidgetStyle{BorderColor: params.BorderColor, NoBorder: params.NoBorder},
+			Priority:  params.Priority,
+		})
+		return nil, nil
+	case "ctx/removeWidget":
+		var params struct {
+			ID string `json:"id"`
+		}
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		ctx.RemoveWidget(params.ID)
+		return nil, nil
+	case "ctx/setStatus", "ctx/removeStatus":
+		var params rpcStatusParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		if method == "ctx/removeStatus" {
+			ctx.RemoveStatus(params.Key)
+		} else {
+			ctx.SetStatus(params.Key, params.Text, params.Priority)
+		}
+		return nil, nil
+	case "ctx/setState", "ctx/getState":
+		var params rpcStateParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		if method == "ctx/setState" {
+			ctx.SetState(params.Key, params.Value)
+			return nil, nil
+		}
+		value, ok := ctx.GetState(params.Key)
+		return map[string]any{"value": value, "found": ok}, nil
+	case "tool/progress":
+		var params rpcProgressParams
+		if err := decode(&params); err != nil {
+			return nil, err
+		}
+		p.progressMu.Lock()
+		report := p.progress[params.Invocation]
+		p.progressMu.Unlock()
+		if report != nil {
+			report(params.Text)
+		}
+		return nil, nil
+	}
+	return nil, &RPCError{Code: rpcMethodNotFound, Message: "unknown method " + method}
+}
+
+// ---------------------------------------------------------------------------
+// Loading
+// ---------------------------------------------------------------------------
+
+// loadRPCExtension starts the extension at path and converts its
+// registration into a LoadedExtension.
+func loadRPCExtension(path string) (*LoadedExtension, error) {
+	p, err := newRPCProcess(path)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := p.start()
+	if err != nil {
+		return nil, err
+	}
+	if reg.Name != "" {
+		p.name = reg.Name
+	}
+
+	// The process cannot be sandboxed the way the interpreter is, so its
+	// network, filesystem and exec capabilities are informational; what it
+	// registers, and the Context it calls back into, are still held to its
+	// declaration.
+	ext := &LoadedExtension{
+		Path:         path,
+		Handlers:     make(map[EventType][]HandlerFunc),
+		Capabilities: p.caps,
+		rpc:          p,
+	}
+	for _, t := range reg.Events {
+		if !t.IsValid() {
+			log.Warn("Ignoring unknown extension event", "extension", p.name, "event", t)
+			continue
+		}
+		if !p.caps.allow(eventCapability(t), p.name, "event "+string(t)) {
+			continue
+		}
+		ext.Handlers[t] = append(ext.Handlers[t], p.eventHandler(t))
+	}
+	for _, def := range reg.Tools {
+		if p.caps.allow(CapabilityTools, p.name, "tool "+def.Name) {
+			ext.Tools = append(ext.Tools, p.toolDef(def))
+		}
+	}
+	for _, def := range reg.Commands {
+		if p.caps.allow(CapabilityUI, p.name, "command "+def.Name) {
+			ext.Commands = append(ext.Commands, p.commandDef(def))
+		}
+	}
+	for _, def := range reg.Shortcuts {
+		if !p.caps.allow(CapabilityUI, p.name, "shortcut "+def.Key) {
+			continue
+		}
+		key := def.Key
+		handler := func(ctx Context) {
+			p.setContext(ctx)
+			p.notify("shortcut/run", map[string]string{"key": key})
+		}
+		// Shortcuts are attributed to the extension's directory, not to
+		// the manifest file name every such extension shares.
+		if entry, ok := prepareShortcut(ShortcutDef{Key: def.Key, Description: def.Description}, handler, p.name); ok {
+			ext.Shortcuts = append(ext.Shortcuts, entry)
+		}
+	}
+	return ext, nil
+}
+
+// eventHandler forwards events of type t. Events that take a result are
+// requests bounded by the extension's timeout; a failure or timeout is
+// logged and treated as "no result". The rest are notifications.
+func (p *rpcProcess) eventHandler(t EventType) HandlerFunc {
+	decode, wantsResult := rpcResultDecoders[t]
+	return func(e Event, ctx Context) Result {
+		p.setContext(ctx)
+		params := rpcEventParams{Type: t, Event: e}
+		if !wantsResult {
+			p.notify("event", params)
+			return nil
+		}
+		var raw json.RawMessage
+		if err := p.call(p.timeout, "event", params, &raw); err != nil {
+			log.Warn("Extension event handler failed", "extension", p.name, "event", t, "error", err)
+			return nil
+		}
+		if len(raw) == 0 {
+			return nil
+		}
+		result, err := decode(raw)
+		if err != nil {
+			log.Warn("Extension returned an invalid result", "extension", p.name, "event", t, "error", err)
+			return nil
+		}
+		return result
+	}
+}
+
+func (p *rpcProcess) toolDef(def rpcToolDef) ToolDef {
+	params := "{}"
+	if len(def.Parameters) > 0 {
+		params = string(def.Parameters)
+	}
+	return ToolDef{
+		Name:        def.Name,
+		Description: def.Description,
+		Parameters:  params,
+		ExecuteWithContext: func(input string, tc ToolContext) (string, error) {
+			return p.executeTool(def.Name, input, tc)
+		},
+	}
+}
+
+// executeTool runs a tool call in the extension. The call is cancelled in
+// the extension when the agent cancels it or the tool timeout passes.
+func (p *rpcProcess) executeTool(name, input string, tc ToolContext) (string, error) {
+	conn, err := p.current()
+	if err != nil {
+		return "", err
+	}
+
+	p.progressMu.Lock()
+	p.nextInvocation++
+	invocation := p.nextInvocation
+	if tc.OnProgress != nil {
+		p.progress[invocation] = tc.OnProgress
+	}
+	p.progressMu.Unlock()
+	defer func() {
+		p.progressMu.Lock()
+		delete(p.progress, invocation)
+		p.progressMu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.toolTimeout)
+	defer cancel()
+	if tc.IsCancelled != nil {
+		go func() {
+			ticker := time.NewTicker(100 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if tc.IsCancelled() {
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	raw := json.RawMessage(input)
+	if !json.Valid(raw) {
+		raw = json.RawMessage("{}")
+	}
+	var result rpcToolResult
+	err = conn.call(ctx, "tool/execute", rpcToolParams{Invocation: invocation, Name: name, Input: raw}, &result)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "", fmt.Errorf("tool %s timed out after %s", name, p.toolTimeout)
+	}
+	if err != nil {
+		return "", err
+	}
+	if result.IsError {
+		return "", errors.New(result.Content)
+	}
+	return result.Content, nil
+}
+
+func (p *rpcProcess) commandDef(def rpcCommandDef) CommandDef {
+	cmd := CommandDef{
+		Name:        def.Name,
+		Description: def.Description,
+		Execute: func(args string, ctx Context) (string, error) {
+			p.setContext(ctx)
+			var result struct {
+				Output string `json:"output"`
+			}
+			err := p.call(p.timeout, "command/execute", map[string]string{"name": def.Name, "args": args}, &result)
+			return result.Output, err
+		},
+	}
+	if def.Complete {
+		cmd.Complete = func(prefix string, ctx Context) []string {
+			p.setContext(ctx)
+			var result struct {
+				Items []string `json:"items"`
+			}
+			if err := p.call(p.timeout, "command/complete", map[string]string{"name": def.Name, "prefix": prefix}, &result); err != nil {
+				return nil
+			}
+			return result.Items
+		}
+	}
+	return cmd
+}
@@ -0,0 +1,257 @@
+package extensions
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// errRPCClosed is returned for calls made after the extension process went
+// away.
+var errRPCClosed = errors.New("extension process exited")
+
+// RPCError is a JSON-RPC error returned by an out-of-process extension, or
+// sent to one when a host method fails.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// JSON-RPC error codes used by the host.
+const (
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+// rpcMessage is any JSON-RPC 2.0 message: request, response or notification.
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *RPCError        `json:"error,omitempty"`
+}
+
+// rpcConn speaks JSON-RPC 2.0 over a pair of streams with one message per
+// line, which any language can read with its standard line reader.
+type rpcConn struct {
+	wmu sync.Mutex
+	w   io.Writer
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan rpcMessage
+	err     error
+
+	// closed is closed when the read loop exits.
+	closed chan struct{}
+
+	// notifications feeds the worker that handles notifications one at a
+	// time, in the order they arrived.
+	notifications chan rpcMessage
+
+	// handle serves requests and notifications from the extension. It
+	// returns the result, or an error, for requests; both are ignored for
+	// notifications.
+	handle func(method string, params json.RawMessage) (any, error)
+}
+
+// newRPCConn starts reading messages from r and returns a connection
+// writing to w.
+func newRPCConn(r io.Reader, w io.Writer, handle func(method string, params json.RawMessage) (any, error)) *rpcConn {
+	c := &rpcConn{
+		w:             w,
+		pending:       make(map[int64]chan rpcMessage),
+		closed:        make(chan struct{}),
+		notifications: make(chan rpcMessage, notificationQueue),
+		handle:        handle,
+	}
+	go c.readLoop(r)
+	go c.notificationLoop()
+	return c
+}
+
+// notificationQueue is how many notifications may wait for the worker
+// before the read loop stops reading.
+const notificationQueue = 256
+
+// call sends a request and decodes its result into result, which may be
+// nil. It gives up when ctx is done, telling the extension to cancel.
+func (c *rpcConn) call(ctx context.Context, method string, params, result any) error {
+	raw, err := marshalParams(params)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan rpcMessage, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	rawID := json.RawMessage(strconv.FormatInt(id, 10))
+	if err := c.send(rpcMessage{JSONRPC: "2.0", ID: &rawID, Method: method, Params: raw}); err != nil {
+		c.forget(id)
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 || string(resp.Result) == "null" {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("decode %s result: %w", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		c.forget(id)
+		_ = c.notify("$/cancelRequest", map[string]any{"id": id})
+		return ctx.Err()
+	case <-c.closed:
+		return c.closedErr()
+	}
+}
+
+// notify sends a notification.
+func (c *rpcConn) notify(method string, params any) error {
+	raw, err := marshalParams(params)
+	if err != nil {
+		return err
+	}
+	return c.send(rpcMessage{JSONRPC: "2.0", Method: method, Params: raw})
+}
+
+func (c *rpcConn) forget(id int64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+func (c *rpcConn) closedErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return errRPCClosed
+}
+
+// reply answers a request from the extension. The result member is always
+// present on success, even when nil, as JSON-RPC requires.
+func (c *rpcConn) reply(id *json.RawMessage, result any, err error) error {
+	if err != nil {
+		rpcErr, ok := err.(*RPCError)
+		if !ok {
+			rpcErr = &RPCError{Code: rpcInternalError, Message: err.Error()}
+		}
+		return c.send(rpcMessage{JSONRPC: "2.0", ID: id, Error: rpcErr})
+	}
+	return c.send(struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id"`
+		Result  any              `json:"result"`
+	}{"2.0", id, result})
+}
+
+// send writes v as one line.
+func (c *rpcConn) send(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err = c.w.Write(append(body, '\n'))
+	return err
+}
+
+func (c *rpcConn) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			// Stray output (a print left in by the author) must not kill
+			// the connection.
+			continue
+		}
+		switch {
+		case msg.ID != nil && msg.Method != "":
+			// Answer off the read loop so a slow host method cannot stall
+			// responses to Kit's own calls.
+			go func() {
+				result, err := c.handle(msg.Method, msg.Params)
+				_ = c.reply(msg.ID, result, err)
+			}()
+		case msg.ID != nil:
+			id, convErr := strconv.ParseInt(string(*msg.ID), 10, 64)
+			if convErr != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch := c.pending[id]
+			delete(c.pending, id)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg
+			}
+		case msg.Method != "":
+			// Notifications keep their order: printing, widgets and
+			// progress updates must apply in the sequence they were sent.
+			c.notifications <- msg
+		}
+	}
+	close(c.notifications)
+
+	err := scanner.Err()
+	c.mu.Lock()
+	if err == nil {
+		err = errRPCClosed
+	}
+	c.err = err
+	c.mu.Unlock()
+	close(c.closed)
+}
+
+// notificationLoop handles notifications from the extension one at a time
+// until the read loop exits. It runs apart from the read loop so a handler
+// that calls back into the extension still receives the response.
+func (c *rpcConn) notificationLoop() {
+	for msg := range c.notifications {
+		_, _ = c.handle(msg.Method, msg.Params)
+	}
+}
+
+func marshalParams(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode %T params: %w", v, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,283 @@
+package extensions
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRPCExtensionHelper is not a real test: it is the out-of-process
+// extension the other tests start by re-running the test binary.
+func TestRPCExtensionHelper(t *testing.T) {
+	if os.Getenv("KIT_TEST_RPC_EXTENSION") != "1" {
+		return
+	}
+	var (
+		wmu    sync.Mutex
+		nextID = 1000
+	)
+	send := func(v any) {
+		data, _ := json.Marshal(v)
+		wmu.Lock()
+		_, _ = os.Stdout.Write(append(data, '\n'))
+		wmu.Unlock()
+	}
+	reply := func(id json.RawMessage, result any) {
+		send(map[string]any{"jsonrpc": "2.0", "id": id, "result": result})
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &msg) != nil || msg.Method == "" {
+			continue
+		}
+		switch msg.Method {
+		case "initialize":
+			reply(msg.ID, map[string]any{
+				"name":   "helper",
+				"events": []string{"tool_call", "agent_end", "not_an_event"},
+				"tools": []map[string]any{{
+					"name":        "shout",
+					"description": "Upper-cases text",
+					"parameters":  map[string]any{"type": "object"},
+				}},
+				"commands":  []map[string]any{{"name": "crash", "description": "Exit abruptly"}},
+				"shortcuts": []map[string]any{{"key": "ctrl+y", "description": "Say hi"}},
+			})
+		case "event":
+			var p struct {
+				Type  string `json:"type"`
+				Event struct {
+					ToolName string
+				} `json:"event"`
+			}
+			_ = json.Unmarshal(msg.Params, &p)
+			switch {
+			case p.Type == "tool_call" && p.Event.ToolName == "slow":
+				time.Sleep(2 * time.Second)
+				reply(msg.ID, nil)
+			case p.Type == "tool_call" && p.Event.ToolName == "bash":
+				reply(msg.ID, map[string]any{"Block": true, "Reason": "no shell"})
+			case p.Type == "tool_call":
+				reply(msg.ID, nil)
+			case p.Type == "agent_end":
+				nextID++
+				send(map[string]any{"jsonrpc": "2.0", "id": nextID, "method": "ctx/print", "params": map[string]string{"text": "turn over"}})
+			}
+		case "tool/execute":
+			var p struct {
+				Invocation int64 `json:"invocation"`
+				Input      struct {
+					Text string `json:"text"`
+				} `json:"input"`
+			}
+			_ = json.Unmarshal(msg.Params, &p)
+			send(map[string]any{"jsonrpc": "2.0", "method": "tool/progress", "params": map[string]any{"invocation": p.Invocation, "text": "working"}})
+			fmt.Fprintln(os.Stdout, "stray debug output")
+			reply(msg.ID, map[string]any{"content": strings.ToUpper(p.Input.Text)})
+		case "command/execute":
+			os.Exit(3)
+		case "shutdown":
+			os.Exit(0)
+		}
+	}
+	os.Exit(0)
+}
+
+func writeHelperManifest(t *testing.T, timeout string, capabilities []string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "helper")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := RPCManifest{
+		Command:      []string{os.Args[0], "-test.run=^TestRPCExtensionHelper$"},
+		Env:          map[string]string{"KIT_TEST_RPC_EXTENSION": "1"},
+		Timeout:      timeout,
+		Capabilities: capabilities,
+	}
+	data, _ := json.Marshal(manifest)
+	path := filepath.Join(dir, RPCManifestName)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRPCExtension_Lifecycle(t *testing.T) {
+	path := writeHelperManifest(t, "500ms", nil)
+	if got := findExtensionsInDir(filepath.Dir(filepath.Dir(path))); len(got) != 1 || got[0] != path {
+		t.Fatalf("findExtensionsInDir = %v", got)
+	}
+
+	ext, err := loadRPCExtension(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := NewRunner([]LoadedExtension{*ext})
+	defer runner.Close()
+
+	if len(ext.Handlers) != 2 || len(ext.Tools) != 1 || len(ext.Commands) != 1 || len(ext.Shortcuts) != 1 {
+		t.Fatalf("registration: %d events, %d tools, %d commands, %d shortcuts",
+			len(ext.Handlers), len(ext.Tools), len(ext.Commands), len(ext.Shortcuts))
+	}
+
+	printed := make(chan string, 1)
+	runner.SetContext(Context{Print: func(s string) { printed <- s }})
+
+	// Result events round-trip into the typed result.
+	result, _ := runner.Emit(ToolCallEvent{ToolName: "bash"})
+	if r, ok := result.(ToolCallResult); !ok || !r.Block || r.Reason != "no shell" {
+		t.Errorf("bash tool_call result = %#v", result)
+	}
+	if result, _ := runner.Emit(ToolCallEvent{ToolName: "read"}); result != nil {
+		t.Errorf("read tool_call result = %#v, want nil", result)
+	}
+	// A handler slower than the timeout is skipped.
+	start := time.Now()
+	if result, _ := runner.Emit(ToolCallEvent{ToolName: "slow"}); result != nil || time.Since(start) > 1500*time.Millisecond {
+		t.Errorf("slow handler returned %#v after %s", result, time.Since(start))
+	}
+
+	// Notifications reach the extension, which calls back into the host.
+	_, _ = runner.Emit(AgentEndEvent{})
+	select {
+	case got := <-printed:
+		if got != "turn over" {
+			t.Errorf("printed %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("extension never called ctx/print")
+	}
+
+	out, err := ext.Tools[0].ExecuteWithContext(`{"text":"hi"}`, ToolContext{
+		IsCancelled: func() bool { return false },
+		OnProgress:  func(string) {},
+	})
+	if err != nil || out != "HI" {
+		t.Errorf("tool = %q, %v", out, err)
+	}
+
+	// A crash is survived: the process restarts and keeps serving.
+	if _, err := ext.Commands[0].Execute("", Context{}); err == nil {
+		t.Error("crashing command reported success")
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		out, err = ext.Tools[0].ExecuteWithContext(`{"text":"again"}`, ToolContext{})
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if out != "AGAIN" {
+		t.Errorf("tool after restart = %q, %v", out, err)
+	}
+}
+
+func TestRPCExtension_Capabilities(t *testing.T) {
+	ext, err := loadRPCExtension(writeHelperManifest(t, "", []string{"events"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ext.Close()
+
+	if ext.Capabilities.String() != "events" {
+		t.Errorf("capabilities = %q", ext.Capabilities)
+	}
+	if len(ext.Handlers) != 1 || len(ext.Handlers[AgentEnd]) != 1 {
+		t.Errorf("handlers = %v, want only agent_end", ext.Handlers)
+	}
+	if len(ext.Tools) != 0 || len(ext.Commands) != 0 || len(ext.Shortcuts) != 0 {
+		t.Errorf("denied registrations kept: %d tools, %d commands, %d shortcuts",
+			len(ext.Tools), len(ext.Commands), len(ext.Shortcuts))
+	}
+
+	// The Context it calls back into lacks what events does not cover.
+	var calls []string
+	ext.rpc.setContext(Context{
+		Print:       func(string) { calls = append(calls, "print") },
+		SendMessage: func(string) { calls = append(calls, "send") },
+		Abort:       func() { calls = append(calls, "abort") },
+	})
+	for _, method := range []string{"ctx/print", "ctx/sendMessage", "ctx/abort"} {
+		if _, err := ext.rpc.handle(method, json.RawMessage(`{"text":"x"}`)); err != nil {
+			t.Errorf("%s: %v", method, err)
+		}
+	}
+	if got := strings.Join(calls, ","); got != "print" {
+		t.Errorf("calls = %q, want only print", got)
+	}
+}
+
+func TestRPCManifestErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, RPCManifestName)
+	for content, want := range map[string]string{
+		`{}`:                                 "command is required",
+		`{"command":["x"],"timeout":"soon"}`: "invalid timeout",
+		`not json`:                           "parsing manifest",
+		`{"command":["x"],"capabilities":["root"]}`: "unknown capability",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := newRPCProcess(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", content, err, want)
+		}
+	}
+}
+
+func TestRPCConn_NotificationsInOrder(t *testing.T) {
+	r, w := io.Pipe()
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	done := make(chan struct{})
+	c := newRPCConn(r, io.Discard, func(method string, params json.RawMessage) (any, error) {
+		var text string
+		_ = json.Unmarshal(params, &text)
+		// Give later notifications a chance to overtake this one.
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		got = append(got, text)
+		if len(got) == 20 {
+			close(done)
+		}
+		mu.Unlock()
+		return nil, nil
+	})
+	for i := range 20 {
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","method":"ctx/print","params":"%d"}`+"\n", i)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("notifications were not handled")
+	}
+	for i, text := range got {
+		if text != strconv.Itoa(i) {
+			t.Fatalf("notifications handled in order %v", got)
+		}
+	}
+	_ = w.Close()
+	<-c.closed
+
+	if err := c.notify("x", func() {}); err == nil {
+		t.Error("notify with unencodable params succeeded")
+	}
+}
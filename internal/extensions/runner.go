@@ -150,6 +150,19 @@ type LoadedExtension struct {
 	CustomEventHandlers map[string][]func(string) // inter-extension event bus
 	Options             []OptionDef               // registered configuration options
 	Shortcuts           []ShortcutEntry           // global keyboard shortcuts
+	Capabilities        Capabilities              // what the extension declared it needs
+
+	// rpc supervises the process of an out-of-process extension; nil for
+	// Yaegi extensions.
+	rpc *rpcProcess
+}
+
+// Close stops the extension's process when it runs out of process. It is a
+// no-op for Yaegi extensions.
+func (e *LoadedExtension) Close() {
+	if e.rpc != nil {
+		e.rpc.close()
+	}
 }
 
 // NewRunner creates a Runner from a set of loaded extensions.
@@ -981,6 +994,7 @@ func (r *Runner) SaveStateToFile(path string) error {
 func (r *Runner) Reload(exts []LoadedExtension) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.closeExtensionsLocked()
 	r.extensions = exts
 	r.invalidateShortcutsLocked()
 	r.widgets = nil
@@ -996,6 +1010,26 @@ func (r *Runner) Reload(exts []LoadedExtension) {
 	// surprising to lose on a hot-reload.
 }
 
+// Close stops the processes of out-of-process extensions. The caller is
+// responsible for emitting SessionShutdown first.
+func (r *Runner) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeExtensionsLocked()
+}
+
+func (r *Runner) closeExtensionsLocked() {
+	var wg sync.WaitGroup
+	for i := range r.extensions {
+		wg.Add(1)
+		go func(ext *LoadedExtension) {
+			defer wg.Done()
+			ext.Close()
+		}(&r.extensions[i])
+	}
+	wg.Wait()
+}
+
 // ---------------------------------------------------------------------------
 // Inter-extension event bus
 // ---------------------------------------------------------------------------
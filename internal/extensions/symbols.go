@@ -206,6 +206,7 @@ func Symbols() interp.Exports {
 			"PrepareStepEvent":    reflect.ValueOf((*PrepareStepEvent)(nil)),
 			"PrepareStepResult":   reflect.ValueOf((*PrepareStepResult)(nil)),
 			"LLMUsageEvent":       reflect.ValueOf((*LLMUsageEvent)(nil)),
+			"SkillScopeEvent":     reflect.ValueOf((*SkillScopeEvent)(nil)),
 		},
 	}
 }
@@ -215,5 +215,11 @@ func NewTestAPI(ext *LoadedExtension) API {
 				return nil
 			})
 		},
+		onSkillScope: func(h func(SkillScopeEvent, Context)) {
+			reg(SkillScope, func(e Event, c Context) Result {
+				h(e.(SkillScopeEvent), c)
+				return nil
+			})
+		},
 	}
 }
@@ -9,23 +9,26 @@ package extensions
 // pkg/kit SDK re-exports these constants.
 const (
 	ToolKindExecute  = "execute" // Shell execution (bash)
-	ToolKindEdit     = "edit"    // File modification (edit, write)
+	ToolKindEdit     = "edit"    // File modification (edit, write, apply_patch)
 	ToolKindRead     = "read"    // File reading (read, ls)
 	ToolKindSearch   = "search"  // Content/file search (grep, find)
-	ToolKindSubagent = "agent"   // Subagent spawning (subagent)
+	ToolKindSubagent = "agent"   // Subagent spawning (subagent, subagent_wait, subagent_cancel)
 )
 
 // coreToolKinds maps built-in tool names to their kind classification.
 // MCP and extension tools without an entry default to ToolKindExecute.
 var coreToolKinds = map[string]string{
-	"bash":     ToolKindExecute,
-	"edit":     ToolKindEdit,
-	"write":    ToolKindEdit,
-	"read":     ToolKindRead,
-	"ls":       ToolKindRead,
-	"grep":     ToolKindSearch,
-	"find":     ToolKindSearch,
-	"subagent": ToolKindSubagent,
+	"bash":            ToolKindExecute,
+	"edit":            ToolKindEdit,
+	"write":           ToolKindEdit,
+	"apply_patch":     ToolKindEdit,
+	"read":            ToolKindRead,
+	"ls":              ToolKindRead,
+	"grep":            ToolKindSearch,
+	"find":            ToolKindSearch,
+	"subagent":        ToolKindSubagent,
+	"subagent_wait":   ToolKindSubagent,
+	"subagent_cancel": ToolKindSubagent,
 }
 
 // ToolKindFor returns the ToolKind for a given tool name, defaulting to
@@ -13,6 +13,7 @@ import (
 	"github.com/mark3labs/kit/internal/config"
 	"github.com/mark3labs/kit/internal/core"
 	"github.com/mark3labs/kit/internal/extensions"
+	"github.com/mark3labs/kit/internal/lsp"
 	"github.com/mark3labs/kit/internal/models"
 	"github.com/mark3labs/kit/internal/tools"
 	"github.com/spf13/viper"
@@ -50,6 +51,16 @@ type AgentSetupOptions struct {
 	// BashMaxTimeout caps the maximum timeout (seconds) a bash tool call may
 	// request. Zero uses the built-in default (600s).
 	BashMaxTimeout int
+	// BashExecutor runs bash tool commands. Nil runs them on the host.
+	BashExecutor core.BashExecutor
+	// FileSystem is where read, write and edit access files. Nil uses the
+	// local disk.
+	FileSystem core.FileSystem
+	// BashJobs tracks background bash jobs. Nil disables them.
+	BashJobs *core.JobManager
+	// LSP runs the language servers behind the code intelligence tools.
+	// Nil disables them.
+	LSP *lsp.Manager
 	// ToolWrapper is an optional function that wraps tools after extension
 	// wrapping. Used by the SDK hook system. Both wrappers compose:
 	// extension wrapper runs first (inner), then this wrapper (outer).
@@ -71,6 +82,9 @@ type AgentSetupOptions struct {
 	// NoExtensions skips extension loading. When false, viper is consulted.
 	// Only meaningful when ProviderConfig is also set.
 	NoExtensions bool
+	// ExtensionTrust gates project-local extensions. Nil loads them
+	// without asking.
+	ExtensionTrust extensions.ProjectTrustFunc
 	// MaxSteps overrides the agent step limit. 0 means use viper value.
 	// Only meaningful when ProviderConfig is also set.
 	MaxSteps int
@@ -90,6 +104,9 @@ type AgentSetupOptions struct {
 	// MCPTaskConfig configures task-augmented tools/call execution. The
 	// zero value preserves historical synchronous-only behaviour.
 	MCPTaskConfig tools.MCPTaskConfig
+	// MCPClientHandlers answers sampling, elicitation and roots requests
+	// from MCP servers.
+	MCPClientHandlers tools.MCPClientHandlers
 	// Viper is the per-instance configuration store. When set, it is used for
 	// any fallback config reads (debug, no-extensions, max-steps, stream,
 	// extension paths) and is attached to the extension runner. When nil, the
@@ -227,7 +244,7 @@ func SetupAgent(ctx context.Context, opts AgentSetupOptions) (*AgentSetupResult,
 	var extCreationOpts extensionCreationOpts
 	if !noExtensions {
 		var extErr error
-		extRunner, extCreationOpts, extErr = loadExtensions(v)
+		extRunner, extCreationOpts, extErr = loadExtensions(v, opts.ExtensionTrust)
 		if extErr != nil {
 			fmt.Printf("Warning: Failed to load extensions: %v\n", extErr)
 		}
@@ -272,8 +289,13 @@ func SetupAgent(ctx context.Context, opts AgentSetupOptions) (*AgentSetupResult,
 		NamedAgents:       opts.NamedAgents,
 		BashTimeout:       opts.BashTimeout,
 		BashMaxTimeout:    opts.BashMaxTimeout,
+		BashExecutor:      opts.BashExecutor,
+		FileSystem:        opts.FileSystem,
+		BashJobs:          opts.BashJobs,
+		LSP:               opts.LSP,
 		OnMCPServerLoaded: opts.OnMCPServerLoaded,
 		MCPTaskConfig:     opts.MCPTaskConfig,
+		MCPClientHandlers: opts.MCPClientHandlers,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %w", err)
@@ -296,13 +318,14 @@ type extensionCreationOpts struct {
 // loadExtensions discovers and loads Yaegi extensions, builds the runner,
 // and returns the tool wrapper/extra tools. The supplied store is used to
 // resolve the "extension" config key and is attached to the runner so
-// extension option lookups stay isolated to this Kit instance.
-func loadExtensions(v *viper.Viper) (*extensions.Runner, extensionCreationOpts, error) {
+// extension option lookups stay isolated to this Kit instance. Project-local
+// extensions load only when trusted admits the project.
+func loadExtensions(v *viper.Viper, trusted extensions.ProjectTrustFunc) (*extensions.Runner, extensionCreationOpts, error) {
 	if v == nil {
 		v = viper.GetViper()
 	}
 	extraPaths := v.GetStringSlice("extension")
-	loaded, err := extensions.LoadExtensions(extraPaths)
+	loaded, err := extensions.LoadExtensionsWithTrust(extraPaths, trusted)
 	if err != nil {
 		return nil, extensionCreationOpts{}, err
 	}
@@ -0,0 +1,511 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shutdownTimeout bounds the polite shutdown/exit handshake before the
+// server process is killed.
+const shutdownTimeout = 3 * time.Second
+
+// diagnosticsSettle is how long Diagnostics keeps listening after the first
+// publish for a document, since servers often publish syntax errors first
+// and type errors a moment later.
+const diagnosticsSettle = 200 * time.Millisecond
+
+// maxStderrTail is how much of a server's stderr is kept for error reports.
+const maxStderrTail = 4 << 10
+
+// Client is a connection to one running language server. Documents are
+// synced from disk on every request, so edits made by other tools are
+// picked up without explicit notifications.
+type Client struct {
+	name   string
+	cfg    ServerConfig
+	root   string
+	cmd    *exec.Cmd
+	conn   *conn
+	stderr *tailBuffer
+	exited chan struct{}
+
+	mu    sync.Mutex
+	docs  map[string]*openDocument
+	diags map[string]*documentDiagnostics
+	// changed is closed and replaced whenever diagnostics are published.
+	changed chan struct{}
+}
+
+type openDocument struct {
+	version int
+	content string
+}
+
+type documentDiagnostics struct {
+	items []Diagnostic
+	// generation counts publishes, so waiters can tell a fresh report
+	// from one that predates their change.
+	generation int
+}
+
+// startClient launches the server described by cfg in root and performs
+// the initialize handshake.
+func startClient(ctx context.Context, name string, cfg ServerConfig, root string) (*Client, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Dir = root
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		name:    name,
+		cfg:     cfg,
+		root:    root,
+		cmd:     cmd,
+		stderr:  &tailBuffer{},
+		exited:  make(chan struct{}),
+		docs:    make(map[string]*openDocument),
+		diags:   make(map[string]*documentDiagnostics),
+		changed: make(chan struct{}),
+	}
+	cmd.Stderr = c.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", cfg.Command, err)
+	}
+	c.conn = newConn(stdout, stdin, c.handle)
+	go func() {
+		_ = cmd.Wait()
+		close(c.exited)
+	}()
+
+	rootURI := PathToURI(root)
+	params := map[string]any{
+		"processId":  os.Getpid(),
+		"clientInfo": map[string]any{"name": "kit"},
+		"rootUri":    rootURI,
+		"rootPath":   root,
+		"workspaceFolders": []map[string]any{
+			{"uri": rootURI, "name": filepath.Base(root)},
+		},
+		"capabilities": map[string]any{
+			"textDocument": map[string]any{
+				"synchronization":    map[string]any{"didSave": true},
+				"publishDiagnostics": map[string]any{},
+				"definition":         map[string]any{"linkSupport": true},
+				"references":         map[string]any{},
+				"hover":              map[string]any{"contentFormat": []string{"markdown", "plaintext"}},
+				"rename":             map[string]any{},
+			},
+			"workspace": map[string]any{
+				"workspaceFolders": true,
+				"configuration":    true,
+				"symbol":           map[string]any{},
+				"workspaceEdit":    map[string]any{"documentChanges": true},
+			},
+		},
+	}
+	if len(cfg.InitializationOptions) > 0 {
+		params["initializationOptions"] = cfg.InitializationOptions
+	}
+	if err := c.conn.call(ctx, "initialize", params, nil); err != nil {
+		c.kill()
+		return nil, c.wrapErr(fmt.Errorf("initialize: %w", err))
+	}
+	if err := c.conn.notify("initialized", map[string]any{}); err != nil {
+		c.kill()
+		return nil, c.wrapErr(err)
+	}
+	return c, nil
+}
+
+// Name returns the configured name of the server, e.g. "gopls".
+func (c *Client) Name() string { return c.name }
+
+// Exited reports whether the server process has stopped.
+func (c *Client) Exited() bool {
+	select {
+	case <-c.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// handle answers notifications and requests sent by the server.
+func (c *Client) handle(method string, params json.RawMessage) any {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p struct {
+			URI         string       `json:"uri"`
+			Diagnostics []Diagnostic `json:"diagnostics"`
+		}
+		if json.Unmarshal(params, &p) != nil {
+			return nil
+		}
+		c.mu.Lock()
+		d := c.diags[p.URI]
+		if d == nil {
+			d = &documentDiagnostics{}
+			c.diags[p.URI] = d
+		}
+		d.items = p.Diagnostics
+		d.generation++
+		close(c.changed)
+		c.changed = make(chan struct{})
+		c.mu.Unlock()
+	case "workspace/configuration":
+		// One (empty) settings value per requested section.
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		_ = json.Unmarshal(params, &p)
+		return make([]any, len(p.Items))
+	case "workspace/workspaceFolders":
+		return []map[string]any{{"uri": PathToURI(c.root), "name": filepath.Base(c.root)}}
+	case "workspace/applyEdit":
+		// Edits are only applied when the agent asks for them.
+		return map[string]any{"applied": false}
+	}
+	return nil
+}
+
+// sync opens path on the server, or sends its current content if it
+// changed on disk since the last request. It returns the document URI and
+// whether anything was sent.
+func (c *Client) sync(path string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	uri := PathToURI(path)
+	content := string(data)
+
+	c.mu.Lock()
+	doc := c.docs[uri]
+	switch {
+	case doc == nil:
+		c.docs[uri] = &openDocument{version: 1, content: content}
+		c.mu.Unlock()
+		err = c.conn.notify("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{
+				"uri":        uri,
+				"languageId": c.cfg.languageID(path),
+				"version":    1,
+				"text":       content,
+			},
+		})
+	case doc.content != content:
+		doc.version++
+		doc.content = content
+		version := doc.version
+		c.mu.Unlock()
+		err = c.conn.notify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": version},
+			"contentChanges": []map[string]any{{"text": content}},
+		})
+		if err == nil {
+			// Some servers only re-check on save; the file is already on
+			// disk, so say so.
+			err = c.conn.notify("textDocument/didSave", map[string]any{
+				"textDocument": map[string]any{"uri": uri},
+			})
+		}
+	default:
+		c.mu.Unlock()
+		return uri, false, nil
+	}
+	if err != nil {
+		return "", false, c.wrapErr(err)
+	}
+	return uri, true, nil
+}
+
+func positionParams(uri string, pos Position) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     pos,
+	}
+}
+
+// Definition returns where the symbol at pos in path is defined.
+func (c *Client) Definition(ctx context.Context, path string, pos Position) ([]Location, error) {
+	uri, _, err := c.sync(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	if err := c.conn.call(ctx, "textDocument/definition", positionParams(uri, pos), &raw); err != nil {
+		return nil, c.wrapErr(err)
+	}
+	return decodeLocations(raw)
+}
+
+// References returns every reference to the symbol at pos in path,
+// including its declaration.
+func (c *Client) References(ctx context.Context, path string, pos Position) ([]Location, error) {
+	uri, _, err := c.sync(path)
+	if err != nil {
+		return nil, err
+	}
+	params := positionParams(uri, pos)
+	params["context"] = map[string]any{"includeDeclaration": true}
+	var locs []Location
+	if err := c.conn.call(ctx, "textDocument/references", params, &locs); err != nil {
+		return nil, c.wrapErr(err)
+	}
+	return locs, nil
+}
+
+// Hover returns the documentation and type information the server shows
+// for the symbol at pos in path, as plain text or markdown.
+func (c *Client) Hover(ctx context.Context, path string, pos Position) (string, error) {
+	uri, _, err := c.sync(path)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		Contents json.RawMessage `json:"contents"`
+	}
+	if err := c.conn.call(ctx, "textDocument/hover", positionParams(uri, pos), &result); err != nil {
+		return "", c.wrapErr(err)
+	}
+	return hoverText(result.Contents), nil
+}
+
+// Rename computes the edits that rename the symbol at pos in path to
+// newName. The edits are not applied; see ApplyWorkspaceEdit.
+func (c *Client) Rename(ctx context.Context, path string, pos Position, newName string) (WorkspaceEdit, error) {
+	uri, _, err := c.sync(path)
+	if err != nil {
+		return WorkspaceEdit{}, err
+	}
+	params := positionParams(uri, pos)
+	params["newName"] = newName
+	var edit WorkspaceEdit
+	if err := c.conn.call(ctx, "textDocument/rename", params, &edit); err != nil {
+		return WorkspaceEdit{}, c.wrapErr(err)
+	}
+	return edit, nil
+}
+
+// WorkspaceSymbols searches the workspace for symbols matching query.
+func (c *Client) WorkspaceSymbols(ctx context.Context, query string) ([]Symbol, error) {
+	var symbols []Symbol
+	if err := c.conn.call(ctx, "workspace/symbol", map[string]any{"query": query}, &symbols); err != nil {
+		return nil, c.wrapErr(err)
+	}
+	return symbols, nil
+}
+
+// Diagnostics syncs path and returns the diagnostics the server reports
+// for it. When the document changed, or nothing has been reported for it
+// yet, it waits up to wait for a fresh report; on timeout it returns the
+// latest one, which may be empty.
+func (c *Client) Diagnostics(ctx context.Context, path string, wait time.Duration) ([]Diagnostic, error) {
+	c.mu.Lock()
+	uri := PathToURI(path)
+	before := 0
+	if d := c.diags[uri]; d != nil {
+		before = d.generation
+	}
+	c.mu.Unlock()
+
+	_, sent, err := c.sync(path)
+	if err != nil {
+		return nil, err
+	}
+	if !sent && before > 0 {
+		wait = 0
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	settling := false
+	for {
+		c.mu.Lock()
+		var items []Diagnostic
+		generation := 0
+		if d := c.diags[uri]; d != nil {
+			items, generation = d.items, d.generation
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		if generation > before && !settling {
+			// A fresh report arrived; give the server a moment to follow
+			// up before returning.
+			settling = true
+			timer.Reset(diagnosticsSettle)
+		}
+		if wait <= 0 {
+			return items, nil
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			wait = 0
+		case <-ctx.Done():
+			return items, ctx.Err()
+		case <-c.exited:
+			return items, c.wrapErr(errConnClosed)
+		}
+	}
+}
+
+// PublishedDiagnostics returns every diagnostic the server has reported so
+// far, keyed by file path, without syncing or waiting.
+func (c *Client) PublishedDiagnostics() map[string][]Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string][]Diagnostic, len(c.diags))
+	for uri, d := range c.diags {
+		if len(d.items) > 0 {
+			out[URIToPath(uri)] = d.items
+		}
+	}
+	return out
+}
+
+// Close shuts the server down, killing it if it does not exit promptly.
+func (c *Client) Close() {
+	if c.Exited() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if c.conn.call(ctx, "shutdown", nil, nil) == nil {
+		_ = c.conn.notify("exit", nil)
+	}
+	select {
+	case <-c.exited:
+	case <-ctx.Done():
+		c.kill()
+	}
+}
+
+func (c *Client) kill() {
+	if c.cmd.Process != nil {
+		_ = c.cmd.Process.Kill()
+	}
+	<-c.exited
+}
+
+// wrapErr names the server in err and, when the server has died, appends
+// the tail of its stderr, which usually explains why.
+func (c *Client) wrapErr(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if c.Exited() {
+		if tail := strings.TrimSpace(c.stderr.String()); tail != "" {
+			return fmt.Errorf("%s: %w\n%s", c.name, err, tail)
+		}
+	}
+	return fmt.Errorf("%s: %w", c.name, err)
+}
+
+// decodeLocations accepts every shape a definition result can take: null,
+// a Location, a list of Locations or a list of LocationLinks.
+func decodeLocations(raw json.RawMessage) ([]Location, error) {
+	raw = json.RawMessage(strings.TrimSpace(string(raw)))
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] == '{' {
+		var loc Location
+		if err := json.Unmarshal(raw, &loc); err != nil {
+			return nil, err
+		}
+		return []Location{loc}, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	locs := make([]Location, 0, len(items))
+	for _, item := range items {
+		var link locationLink
+		if err := json.Unmarshal(item, &link); err == nil && link.TargetURI != "" {
+			locs = append(locs, Location{URI: link.TargetURI, Range: link.TargetSelectionRange})
+			continue
+		}
+		var loc Location
+		if err := json.Unmarshal(item, &loc); err != nil {
+			return nil, err
+		}
+		locs = append(locs, loc)
+	}
+	return locs, nil
+}
+
+// hoverText flattens hover contents: MarkupContent, a MarkedString or a
+// list of MarkedStrings.
+func hoverText(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	var markup struct {
+		Kind     string `json:"kind"`
+		Language string `json:"language"`
+		Value    string `json:"value"`
+	}
+	if json.Unmarshal(raw, &markup) == nil && markup.Value != "" {
+		if markup.Language != "" {
+			return "```" + markup.Language + "\n" + strings.TrimSpace(markup.Value) + "\n```"
+		}
+		return strings.TrimSpace(markup.Value)
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) == nil {
+		parts := make([]string, 0, len(list))
+		for _, item := range list {
+			if text := hoverText(item); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	}
+	return ""
+}
+
+// tailBuffer keeps the last maxStderrTail bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - maxStderrTail; over > 0 {
+		b.buf = b.buf[over:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
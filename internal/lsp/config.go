@@ -0,0 +1,151 @@
+// Package lsp is a small Language Server Protocol client. It launches the
+// language servers configured for a workspace, keeps them running across
+// requests and exposes the handful of queries KIT's code intelligence tools
+// need: definition, references, hover, rename, workspace symbols and
+// diagnostics.
+package lsp
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ServerConfig describes how to launch a language server and which files
+// it handles. For the well-known servers in Presets every field may be
+// omitted; set fields override the preset.
+type ServerConfig struct {
+	// Command is the server executable, looked up in PATH.
+	Command string `json:"command,omitempty" yaml:"command,omitempty" mapstructure:"command"`
+	// Args are passed to Command, e.g. ["--stdio"].
+	Args []string `json:"args,omitempty" yaml:"args,omitempty" mapstructure:"args"`
+	// Extensions lists the file extensions routed to this server,
+	// including the dot (".go", ".py").
+	Extensions []string `json:"extensions,omitempty" yaml:"extensions,omitempty" mapstructure:"extensions"`
+	// LanguageID overrides the LSP language identifier sent when a file is
+	// opened. Empty derives it from the file extension.
+	LanguageID string `json:"language-id,omitempty" yaml:"language-id,omitempty" mapstructure:"language-id"`
+	// Env sets extra environment variables for the server process.
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty" mapstructure:"env"`
+	// InitializationOptions is sent verbatim in the initialize request.
+	InitializationOptions map[string]any `json:"initialization-options,omitempty" yaml:"initialization-options,omitempty" mapstructure:"initialization-options"`
+	// Disabled turns the server off without removing its configuration.
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty" mapstructure:"disabled"`
+}
+
+// Config maps server names to their configuration. It is the "lsp" block
+// of .kit.yml.
+type Config map[string]ServerConfig
+
+// Presets are the built-in launch settings for common language servers.
+// Naming one in Config with an empty body enables it as-is.
+var Presets = map[string]ServerConfig{
+	"gopls": {
+		Command:    "gopls",
+		Extensions: []string{".go"},
+	},
+	"pyright": {
+		Command:    "pyright-langserver",
+		Args:       []string{"--stdio"},
+		Extensions: []string{".py", ".pyi"},
+	},
+	"typescript": {
+		Command:    "typescript-language-server",
+		Args:       []string{"--stdio"},
+		Extensions: []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs"},
+	},
+	"rust-analyzer": {
+		Command:    "rust-analyzer",
+		Extensions: []string{".rs"},
+	},
+	"clangd": {
+		Command:    "clangd",
+		Extensions: []string{".c", ".h", ".cc", ".cpp", ".cxx", ".hpp", ".hh"},
+	},
+}
+
+// resolve fills unset fields of cfg from the preset called name, if any.
+func (cfg ServerConfig) resolve(name string) ServerConfig {
+	preset, ok := Presets[name]
+	if !ok {
+		return cfg
+	}
+	if cfg.Command == "" {
+		cfg.Command = preset.Command
+		if cfg.Args == nil {
+			cfg.Args = preset.Args
+		}
+	}
+	if len(cfg.Extensions) == 0 {
+		cfg.Extensions = preset.Extensions
+	}
+	return cfg
+}
+
+// languageID returns the LSP language identifier for path.
+func (cfg ServerConfig) languageID(path string) string {
+	if cfg.LanguageID != "" {
+		return cfg.LanguageID
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if id, ok := languageIDs[ext]; ok {
+		return id
+	}
+	return strings.TrimPrefix(ext, ".")
+}
+
+var languageIDs = map[string]string{
+	".go":   "go",
+	".py":   "python",
+	".pyi":  "python",
+	".ts":   "typescript",
+	".tsx":  "typescriptreact",
+	".js":   "javascript",
+	".mjs":  "javascript",
+	".cjs":  "javascript",
+	".jsx":  "javascriptreact",
+	".rs":   "rust",
+	".c":    "c",
+	".h":    "c",
+	".cc":   "cpp",
+	".cpp":  "cpp",
+	".cxx":  "cpp",
+	".hpp":  "cpp",
+	".hh":   "cpp",
+	".rb":   "ruby",
+	".java": "java",
+	".lua":  "lua",
+	".zig":  "zig",
+}
+
+// Validate checks that every enabled server has a command and at least one
+// extension once presets are applied, and that no extension is claimed by
+// two servers.
+func (c Config) Validate() error {
+	owners := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(c)) {
+		srv := c[name].resolve(name)
+		if srv.Disabled {
+			continue
+		}
+		if srv.Command == "" {
+			return fmt.Errorf("server %s: command is required", name)
+		}
+		if len(srv.Extensions) == 0 {
+			return fmt.Errorf("server %s: extensions are required", name)
+		}
+		for _, ext := range srv.Extensions {
+			if !strings.HasPrefix(ext, ".") {
+				return fmt.Errorf("server %s: extension %q must start with a dot", name, ext)
+			}
+			ext = strings.ToLower(ext)
+			if other, ok := owners[ext]; ok {
+				return fmt.Errorf("server %s: extension %s is already handled by %s", name, ext, other)
+			}
+			owners[ext] = name
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// errConnClosed is returned for calls made after the server went away.
+var errConnClosed = errors.New("language server connection closed")
+
+// ResponseError is a JSON-RPC error returned by a language server.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// message is any JSON-RPC 2.0 message: request, response or notification.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+// conn speaks JSON-RPC 2.0 with Content-Length framing over a pair of
+// streams, the transport every stdio language server uses.
+type conn struct {
+	wmu sync.Mutex
+	w   io.Writer
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan message
+	err     error
+
+	// closed is closed when the read loop exits.
+	closed chan struct{}
+
+	// handle receives notifications and server-to-client requests. It
+	// returns the result for requests; the value is ignored for
+	// notifications.
+	handle func(method string, params json.RawMessage) any
+}
+
+// newConn starts reading messages from r and returns a connection writing
+// to w.
+func newConn(r io.Reader, w io.Writer, handle func(method string, params json.RawMessage) any) *conn {
+	c := &conn{
+		w:       w,
+		pending: make(map[int64]chan message),
+		closed:  make(chan struct{}),
+		handle:  handle,
+	}
+	go c.readLoop(bufio.NewReader(r))
+	return c
+}
+
+// call sends a request and decodes its result into result, which may be
+// nil. It gives up when ctx is done, telling the server to cancel.
+func (c *conn) call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	rawID := json.RawMessage(strconv.FormatInt(id, 10))
+	if err := c.write(message{ID: &rawID, Method: method, Params: mustMarshal(params)}); err != nil {
+		c.forget(id)
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 || string(resp.Result) == "null" {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("decode %s result: %w", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		c.forget(id)
+		_ = c.notify("$/cancelRequest", map[string]any{"id": id})
+		return ctx.Err()
+	case <-c.closed:
+		return c.closedErr()
+	}
+}
+
+// notify sends a notification.
+func (c *conn) notify(method string, params any) error {
+	return c.write(message{Method: method, Params: mustMarshal(params)})
+}
+
+func (c *conn) forget(id int64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+func (c *conn) closedErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return errConnClosed
+}
+
+func (c *conn) write(msg message) error {
+	msg.JSONRPC = "2.0"
+	return c.send(msg)
+}
+
+// reply answers a server-to-client request. The result member is always
+// present, even when nil, as JSON-RPC requires.
+func (c *conn) reply(id *json.RawMessage, result any) error {
+	return c.send(struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id"`
+		Result  any              `json:"result"`
+	}{"2.0", id, result})
+}
+
+// send writes v as one framed message.
+func (c *conn) send(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+func (c *conn) readLoop(r *bufio.Reader) {
+	var err error
+	for {
+		var msg message
+		if msg, err = readMessage(r); err != nil {
+			break
+		}
+		switch {
+		case msg.ID != nil && msg.Method != "":
+			// Server-to-client request. Answer off the read loop so a
+			// slow handler cannot stall responses.
+			go func() {
+				result := c.handle(msg.Method, msg.Params)
+				_ = c.reply(msg.ID, result)
+			}()
+		case msg.ID != nil:
+			id, convErr := strconv.ParseInt(string(*msg.ID), 10, 64)
+			if convErr != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch := c.pending[id]
+			delete(c.pending, id)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg
+			}
+		default:
+			c.handle(msg.Method, msg.Params)
+		}
+	}
+
+	c.mu.Lock()
+	if errors.Is(err, io.EOF) {
+		err = errConnClosed
+	}
+	c.err = err
+	c.mu.Unlock()
+	close(c.closed)
+}
+
+// readMessage reads one Content-Length framed message.
+func readMessage(r *bufio.Reader) (message, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return message{}, err
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return message{}, fmt.Errorf("invalid Content-Length header %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return message{}, err
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return message{}, fmt.Errorf("invalid message: %w", err)
+	}
+	return msg, nil
+}
+
+func mustMarshal(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("lsp: cannot marshal %T: %v", v, err))
+	}
+	return data
+}
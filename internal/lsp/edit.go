@@ -0,0 +1,135 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// FileEdits are the text edits a WorkspaceEdit makes to one file.
+type FileEdits struct {
+	Path  string
+	Edits []TextEdit
+}
+
+// Files flattens edit into per-file text edits, sorted by path. It fails
+// if the edit creates, renames or deletes files, which is not supported.
+func (edit WorkspaceEdit) Files() ([]FileEdits, error) {
+	byPath := make(map[string][]TextEdit)
+	for uri, edits := range edit.Changes {
+		byPath[URIToPath(uri)] = append(byPath[URIToPath(uri)], edits...)
+	}
+	for _, raw := range edit.DocumentChanges {
+		var change textDocumentEdit
+		if err := json.Unmarshal(raw, &change); err != nil {
+			return nil, fmt.Errorf("invalid document change: %w", err)
+		}
+		if change.Kind != "" {
+			return nil, fmt.Errorf("the edit would %s a file, which is not supported", change.Kind)
+		}
+		path := URIToPath(change.TextDocument.URI)
+		byPath[path] = append(byPath[path], change.Edits...)
+	}
+
+	files := make([]FileEdits, 0, len(byPath))
+	for _, path := range slices.Sorted(maps.Keys(byPath)) {
+		files = append(files, FileEdits{Path: path, Edits: byPath[path]})
+	}
+	return files, nil
+}
+
+// ApplyTextEdits returns content with edits applied. Edits must not
+// overlap; they are applied from the end of the document backwards so
+// earlier positions stay valid.
+func ApplyTextEdits(content []byte, edits []TextEdit) ([]byte, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+	spans := make([]span, 0, len(edits))
+	for _, e := range edits {
+		start, end := Offset(content, e.Range.Start), Offset(content, e.Range.End)
+		if end < start {
+			return nil, fmt.Errorf("invalid edit range %d:%d-%d:%d",
+				e.Range.Start.Line+1, e.Range.Start.Character+1, e.Range.End.Line+1, e.Range.End.Character+1)
+		}
+		spans = append(spans, span{start, end, e.NewText})
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	for i := 1; i < len(spans); i++ {
+		if spans[i].start < spans[i-1].end {
+			return nil, fmt.Errorf("overlapping edits")
+		}
+	}
+
+	out := slices.Clone(content)
+	for i := len(spans) - 1; i >= 0; i-- {
+		s := spans[i]
+		out = slices.Concat(out[:s.start], []byte(s.text), out[s.end:])
+	}
+	return out, nil
+}
+
+// PlannedFile is one file of a WorkspaceEdit applied in memory.
+type PlannedFile struct {
+	FileEdits
+	Content []byte // the file's content after the edits
+}
+
+// PlanWorkspaceEdit applies edit in memory, reading each file with read, and
+// returns the resulting content of every file it changes. It fails if any
+// edit is invalid or any file lies outside root, so a caller can vet the
+// whole plan before writing anything.
+func PlanWorkspaceEdit(edit WorkspaceEdit, root string, read func(path string) ([]byte, error)) ([]PlannedFile, error) {
+	files, err := edit.Files()
+	if err != nil {
+		return nil, err
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	planned := make([]PlannedFile, len(files))
+	for i, f := range files {
+		rel, err := filepath.Rel(root, f.Path)
+		if err != nil || !filepath.IsAbs(f.Path) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("the edit would modify %s, outside the workspace %s", f.Path, root)
+		}
+		content, err := read(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		planned[i].FileEdits = f
+		if planned[i].Content, err = ApplyTextEdits(content, f.Edits); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Path, err)
+		}
+	}
+	return planned, nil
+}
+
+// ApplyWorkspaceEdit writes edit to disk and returns the files it changed.
+// Every file is edited in memory first, so nothing is written if any edit
+// is invalid or touches a file outside root.
+func ApplyWorkspaceEdit(edit WorkspaceEdit, root string) ([]FileEdits, error) {
+	planned, err := PlanWorkspaceEdit(edit, root, os.ReadFile)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]FileEdits, len(planned))
+	for i, f := range planned {
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(f.Path, f.Content, info.Mode().Perm()); err != nil {
+			return nil, err
+		}
+		files[i] = f.FileEdits
+	}
+	return files, nil
+}
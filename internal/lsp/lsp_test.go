@@ -0,0 +1,244 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/kit/internal/lsp/lsptest"
+)
+
+func TestMain(m *testing.M) {
+	lsptest.Main()
+	os.Exit(m.Run())
+}
+
+// fakeServerConfig launches the fake server for ".fake" files.
+func fakeServerConfig() Config {
+	exe, args, env := lsptest.Command()
+	return Config{"fake": {Command: exe, Args: args, Extensions: []string{".fake"}, Env: env}}
+}
+
+func newTestManager(t *testing.T) (*Manager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	m, err := NewManager(dir, fakeServerConfig())
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	t.Cleanup(m.Close)
+	return m, dir
+}
+
+func TestClient_Queries(t *testing.T) {
+	m, dir := newTestManager(t)
+	path := filepath.Join(dir, "main.fake")
+	src := "func greet\nfunc main\n  greet()\n  greet()\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c, err := m.ClientFor(ctx, path)
+	if err != nil {
+		t.Fatalf("ClientFor: %v", err)
+	}
+	if c.Name() != "fake" {
+		t.Errorf("Name() = %q", c.Name())
+	}
+	call := Position{Line: 2, Character: 3}
+
+	locs, err := c.Definition(ctx, path, call)
+	if err != nil {
+		t.Fatalf("Definition: %v", err)
+	}
+	if len(locs) != 1 || URIToPath(locs[0].URI) != path || locs[0].Range.Start != (Position{0, 5}) {
+		t.Errorf("Definition = %+v", locs)
+	}
+
+	refs, err := c.References(ctx, path, call)
+	if err != nil || len(refs) != 3 {
+		t.Errorf("References = %+v, %v", refs, err)
+	}
+
+	hover, err := c.Hover(ctx, path, call)
+	if err != nil || hover != "**greet**" {
+		t.Errorf("Hover = %q, %v", hover, err)
+	}
+
+	symbols, err := c.WorkspaceSymbols(ctx, "ma")
+	if err != nil || len(symbols) != 1 || symbols[0].Name != "main" || symbols[0].Kind.String() != "function" {
+		t.Errorf("WorkspaceSymbols = %+v, %v", symbols, err)
+	}
+
+	edit, err := c.Rename(ctx, path, call, "hello")
+	if err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if _, err := ApplyWorkspaceEdit(edit, t.TempDir()); err == nil || !strings.Contains(err.Error(), "outside the workspace") {
+		t.Errorf("ApplyWorkspaceEdit outside the root = %v", err)
+	}
+	files, err := ApplyWorkspaceEdit(edit, dir)
+	if err != nil || len(files) != 1 || len(files[0].Edits) != 3 {
+		t.Fatalf("ApplyWorkspaceEdit = %+v, %v", files, err)
+	}
+	got, _ := os.ReadFile(path)
+	if want := "func hello\nfunc main\n  hello()\n  hello()\n"; string(got) != want {
+		t.Errorf("after rename:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestClient_DiagnosticsFollowEdits(t *testing.T) {
+	m, dir := newTestManager(t)
+	path := filepath.Join(dir, "main.fake")
+	if err := os.WriteFile(path, []byte("ok\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := m.ClientFor(ctx, path)
+	if err != nil {
+		t.Fatalf("ClientFor: %v", err)
+	}
+
+	diags, err := c.Diagnostics(ctx, path, 5*time.Second)
+	if err != nil || len(diags) != 0 {
+		t.Fatalf("Diagnostics = %+v, %v", diags, err)
+	}
+
+	// Changing the file on disk is enough; the client resyncs it.
+	if err := os.WriteFile(path, []byte("ok\nthis is BAD\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	diags, err = c.Diagnostics(ctx, path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Diagnostics: %v", err)
+	}
+	if len(diags) != 1 || diags[0].Range.Start != (Position{1, 8}) || diags[0].Severity != SeverityError {
+		t.Fatalf("Diagnostics = %+v", diags)
+	}
+
+	// Unchanged files return the cached report immediately.
+	start := time.Now()
+	if diags, _ = c.Diagnostics(ctx, path, 5*time.Second); len(diags) != 1 {
+		t.Errorf("cached Diagnostics = %+v", diags)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("cached Diagnostics took %v", time.Since(start))
+	}
+	if published := c.PublishedDiagnostics(); len(published[path]) != 1 {
+		t.Errorf("PublishedDiagnostics = %+v", published)
+	}
+}
+
+func TestManager_KeepsServersWarm(t *testing.T) {
+	m, dir := newTestManager(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	a, err := m.ClientFor(ctx, filepath.Join(dir, "a.fake"))
+	if err != nil {
+		t.Fatalf("ClientFor: %v", err)
+	}
+	b, err := m.ClientFor(ctx, filepath.Join(dir, "sub", "B.FAKE"))
+	if err != nil {
+		t.Fatalf("ClientFor: %v", err)
+	}
+	if a != b {
+		t.Error("expected the running server to be reused")
+	}
+	if running := m.Running(); len(running) != 1 {
+		t.Errorf("Running() = %d servers", len(running))
+	}
+
+	if _, err := m.ClientFor(ctx, filepath.Join(dir, "x.txt")); err == nil {
+		t.Error("expected ErrNoServer for an unhandled extension")
+	}
+
+	// A crashed server is replaced on the next request.
+	_ = a.cmd.Process.Kill()
+	<-a.exited
+	c, err := m.ClientFor(ctx, filepath.Join(dir, "a.fake"))
+	if err != nil || c == a {
+		t.Errorf("expected a restarted server, got %p (old %p), %v", c, a, err)
+	}
+
+	m.Close()
+	if !c.Exited() {
+		t.Error("Close should stop the server")
+	}
+	if _, err := m.ClientFor(ctx, filepath.Join(dir, "a.fake")); err == nil {
+		t.Error("expected an error after Close")
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	if err := (Config{"gopls": {}, "pyright": {Args: []string{"--verbose"}}}).Validate(); err != nil {
+		t.Errorf("presets should validate: %v", err)
+	}
+	for name, cfg := range map[string]Config{
+		"no command":    {"custom": {Extensions: []string{".x"}}},
+		"no extensions": {"custom": {Command: "x-ls"}},
+		"no dot":        {"custom": {Command: "x-ls", Extensions: []string{"x"}}},
+		"duplicate":     {"gopls": {}, "other": {Command: "go-ls", Extensions: []string{".GO"}}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if err := (Config{"custom": {Disabled: true}}).Validate(); err != nil {
+		t.Errorf("disabled servers are not validated: %v", err)
+	}
+}
+
+func TestApplyTextEdits(t *testing.T) {
+	content := []byte("héllo 😀 world\nsecond\n")
+	// The emoji is two UTF-16 code units, so "world" starts at 9.
+	got, err := ApplyTextEdits(content, []TextEdit{
+		{Range: Range{Start: Position{1, 0}, End: Position{1, 6}}, NewText: "2nd"},
+		{Range: Range{Start: Position{0, 9}, End: Position{0, 14}}, NewText: "there"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "héllo 😀 there\n2nd\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	_, err = ApplyTextEdits(content, []TextEdit{
+		{Range: Range{Start: Position{0, 0}, End: Position{0, 4}}},
+		{Range: Range{Start: Position{0, 2}, End: Position{0, 6}}},
+	})
+	if err == nil {
+		t.Error("expected an error for overlapping edits")
+	}
+}
+
+func TestDecodeLocationsAndHover(t *testing.T) {
+	single := `{"uri":"file:///a.go","range":{"start":{"line":1,"character":2},"end":{"line":1,"character":3}}}`
+	for _, raw := range []string{single, "[" + single + "]"} {
+		locs, err := decodeLocations(json.RawMessage(raw))
+		if err != nil || len(locs) != 1 || locs[0].Range.Start.Line != 1 {
+			t.Errorf("decodeLocations(%s) = %+v, %v", raw, locs, err)
+		}
+	}
+	if locs, err := decodeLocations(json.RawMessage("null")); err != nil || locs != nil {
+		t.Errorf("decodeLocations(null) = %+v, %v", locs, err)
+	}
+
+	for raw, want := range map[string]string{
+		`"plain"`:                             "plain",
+		`{"kind":"markdown","value":"*md*"}`:  "*md*",
+		`{"language":"go","value":"func f"}`:  "```go\nfunc f\n```",
+		`["a",{"language":"go","value":"b"}]`: "a\n\n```go\nb\n```",
+	} {
+		if got := hoverText(json.RawMessage(raw)); got != want {
+			t.Errorf("hoverText(%s) = %q, want %q", raw, got, want)
+		}
+	}
+}
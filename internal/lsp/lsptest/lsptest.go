@@ -0,0 +1,245 @@
+// Package lsptest provides a tiny fake language server for tests. A test
+// binary re-executes itself as the server: call Main first thing in
+// TestMain and launch the server with the command returned by Command.
+//
+// The fake understands a toy language in ".fake" files: "func name"
+// declares name, and any line containing BAD is an error.
+package lsptest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Env marks a test binary re-executed as the fake server.
+const Env = "KIT_LSP_FAKE_SERVER"
+
+// Main serves LSP on stdin/stdout and exits when the process was started as
+// the fake server; otherwise it returns immediately.
+func Main() {
+	if os.Getenv(Env) != "1" {
+		return
+	}
+	Serve(os.Stdin, os.Stdout)
+	os.Exit(0)
+}
+
+// Command returns the executable, arguments and environment that launch
+// the fake server from the current test binary.
+func Command() (exe string, args []string, env map[string]string) {
+	exe, err := os.Executable()
+	if err != nil {
+		panic(err)
+	}
+	return exe, nil, map[string]string{Env: "1"}
+}
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type rng struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	URI   string `json:"uri"`
+	Range rng    `json:"range"`
+}
+
+type request struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params struct {
+		TextDocument struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+		} `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+		Position position `json:"position"`
+		NewName  string   `json:"newName"`
+		Query    string   `json:"query"`
+	} `json:"params"`
+}
+
+// server is the fake's state: the open documents by URI.
+type server struct {
+	w    io.Writer
+	docs map[string]string
+}
+
+// Serve runs the fake server until the client sends exit or closes r.
+func Serve(r io.Reader, w io.Writer) {
+	s := &server{w: w, docs: make(map[string]string)}
+	tp := textproto.NewReader(bufio.NewReader(r))
+	for {
+		header, err := tp.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		n, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			return
+		}
+		body := make([]byte, n)
+		if _, err := io.ReadFull(tp.R, body); err != nil {
+			return
+		}
+		var req request
+		if json.Unmarshal(body, &req) != nil {
+			return
+		}
+		if req.Method == "exit" {
+			return
+		}
+		s.handle(req)
+	}
+}
+
+func (s *server) send(v any) {
+	body, _ := json.Marshal(v)
+	fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *server) reply(id *json.RawMessage, result any) {
+	s.send(map[string]any{"jsonrpc": "2.0", "id": id, "result": result})
+}
+
+func (s *server) handle(req request) {
+	p := req.Params
+	uri := p.TextDocument.URI
+	word := s.wordAt(uri, p.Position)
+
+	switch req.Method {
+	case "initialize":
+		s.reply(req.ID, map[string]any{"capabilities": map[string]any{}})
+	case "initialized":
+		// Exercise a server-to-client request.
+		s.send(map[string]any{"jsonrpc": "2.0", "id": "cfg", "method": "workspace/configuration",
+			"params": map[string]any{"items": []any{map[string]any{}}}})
+	case "textDocument/didOpen":
+		s.docs[uri] = p.TextDocument.Text
+		s.publish(uri)
+	case "textDocument/didChange":
+		s.docs[uri] = p.ContentChanges[len(p.ContentChanges)-1].Text
+		s.publish(uri)
+	case "textDocument/definition":
+		// Answer with LocationLinks, the less common shape.
+		links := []map[string]any{}
+		for _, r := range s.occurrences(uri, "func "+word) {
+			r.Start.Character += 5
+			r.End.Character = r.Start.Character + utf16Len(word)
+			links = append(links, map[string]any{"targetUri": uri, "targetRange": r, "targetSelectionRange": r})
+		}
+		s.reply(req.ID, links)
+	case "textDocument/references":
+		locs := []location{}
+		for _, r := range s.occurrences(uri, word) {
+			locs = append(locs, location{URI: uri, Range: r})
+		}
+		s.reply(req.ID, locs)
+	case "textDocument/hover":
+		s.reply(req.ID, map[string]any{"contents": map[string]any{"kind": "markdown", "value": "**" + word + "**"}})
+	case "textDocument/rename":
+		edits := []map[string]any{}
+		for _, r := range s.occurrences(uri, word) {
+			edits = append(edits, map[string]any{"range": r, "newText": p.NewName})
+		}
+		s.reply(req.ID, map[string]any{"changes": map[string]any{uri: edits}})
+	case "workspace/symbol":
+		symbols := []map[string]any{}
+		for docURI, content := range s.docs {
+			for i, line := range strings.Split(content, "\n") {
+				if name, ok := strings.CutPrefix(line, "func "); ok && strings.Contains(name, p.Query) {
+					symbols = append(symbols, map[string]any{"name": name, "kind": 12,
+						"location": location{URI: docURI, Range: rng{Start: position{i, 5}, End: position{i, 5}}}})
+				}
+			}
+		}
+		s.reply(req.ID, symbols)
+	case "shutdown":
+		s.reply(req.ID, nil)
+	default:
+		if req.ID != nil && req.Method != "" {
+			s.reply(req.ID, nil)
+		}
+	}
+}
+
+// publish reports an error for every line of uri containing BAD.
+func (s *server) publish(uri string) {
+	diags := []map[string]any{}
+	for i, line := range strings.Split(s.docs[uri], "\n") {
+		if j := strings.Index(line, "BAD"); j >= 0 {
+			col := utf16Len(line[:j])
+			diags = append(diags, map[string]any{
+				"range":    rng{Start: position{i, col}, End: position{i, col + 3}},
+				"severity": 1,
+				"source":   "fake",
+				"message":  "bad line",
+			})
+		}
+	}
+	s.send(map[string]any{"jsonrpc": "2.0", "method": "textDocument/publishDiagnostics",
+		"params": map[string]any{"uri": uri, "diagnostics": diags}})
+}
+
+// occurrences returns the ranges of text in uri.
+func (s *server) occurrences(uri, text string) []rng {
+	var ranges []rng
+	if text == "" {
+		return nil
+	}
+	for i, line := range strings.Split(s.docs[uri], "\n") {
+		for off := 0; ; {
+			j := strings.Index(line[off:], text)
+			if j < 0 {
+				break
+			}
+			col := utf16Len(line[:off+j])
+			ranges = append(ranges, rng{Start: position{i, col}, End: position{i, col + utf16Len(text)}})
+			off += j + len(text)
+		}
+	}
+	return ranges
+}
+
+// wordAt returns the identifier at pos in uri.
+func (s *server) wordAt(uri string, pos position) string {
+	lines := strings.Split(s.docs[uri], "\n")
+	if pos.Line >= len(lines) {
+		return ""
+	}
+	line := []rune(lines[pos.Line])
+	// Convert the UTF-16 offset to a rune index.
+	idx, units := 0, 0
+	for idx < len(line) && units < pos.Character {
+		units += len(utf16.Encode([]rune{line[idx]}))
+		idx++
+	}
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' }
+	start, end := idx, idx
+	for start > 0 && isWord(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isWord(line[end]) {
+		end++
+	}
+	return string(line[start:end])
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
@@ -0,0 +1,166 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// startTimeout bounds launching a server and its initialize handshake.
+const startTimeout = 30 * time.Second
+
+// ErrNoServer is returned when no configured server handles a file.
+var ErrNoServer = errors.New("no language server configured")
+
+// Manager owns the language servers of one workspace. Servers start
+// lazily, the first time a file they handle is queried, and stay warm
+// until Close. A server that fails to start is not retried; one that
+// crashes later is restarted on the next request.
+type Manager struct {
+	root    string
+	servers map[string]ServerConfig
+	// byExt maps a lower-case extension to the server handling it.
+	byExt map[string]string
+
+	mu      sync.Mutex
+	running map[string]*serverEntry
+	closed  bool
+}
+
+// serverEntry is a server that has been started, or is starting.
+type serverEntry struct {
+	ready  chan struct{}
+	client *Client
+	err    error
+}
+
+// NewManager returns a manager for the servers in cfg, rooted at root.
+// Presets are applied and disabled servers dropped; no process is started.
+func NewManager(root string, cfg Config) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		root:    root,
+		servers: make(map[string]ServerConfig),
+		byExt:   make(map[string]string),
+		running: make(map[string]*serverEntry),
+	}
+	for name, srv := range cfg {
+		srv = srv.resolve(name)
+		if srv.Disabled {
+			continue
+		}
+		m.servers[name] = srv
+		for _, ext := range srv.Extensions {
+			m.byExt[strings.ToLower(ext)] = name
+		}
+	}
+	return m, nil
+}
+
+// Root returns the workspace directory the servers run in.
+func (m *Manager) Root() string { return m.root }
+
+// Servers returns the names of the enabled servers, sorted.
+func (m *Manager) Servers() []string {
+	return slices.Sorted(maps.Keys(m.servers))
+}
+
+// ServerFor returns the name of the server handling path.
+func (m *Manager) ServerFor(path string) (string, bool) {
+	name, ok := m.byExt[strings.ToLower(filepath.Ext(path))]
+	return name, ok
+}
+
+// ClientFor returns the running server for path, starting it if needed.
+// It returns an error wrapping ErrNoServer when no server handles path.
+func (m *Manager) ClientFor(ctx context.Context, path string) (*Client, error) {
+	name, ok := m.ServerFor(path)
+	if !ok {
+		return nil, fmt.Errorf("%w for %s files", ErrNoServer, filepath.Ext(path))
+	}
+	return m.Client(ctx, name)
+}
+
+// Client returns the running server called name, starting it if needed.
+func (m *Manager) Client(ctx context.Context, name string) (*Client, error) {
+	srv, ok := m.servers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w named %q", ErrNoServer, name)
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, errors.New("language servers have been shut down")
+	}
+	entry := m.running[name]
+	if entry != nil && entry.client != nil && entry.client.Exited() {
+		// Crashed since the last request: start a fresh one.
+		entry = nil
+	}
+	if entry == nil {
+		entry = &serverEntry{ready: make(chan struct{})}
+		m.running[name] = entry
+		go func() {
+			// Started detached from ctx so one cancelled tool call does
+			// not leave the server permanently failed.
+			startCtx, cancel := context.WithTimeout(context.Background(), startTimeout)
+			defer cancel()
+			entry.client, entry.err = startClient(startCtx, name, srv, m.root)
+			close(entry.ready)
+		}()
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-entry.ready:
+		return entry.client, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Running returns the servers that are currently up, without starting any.
+func (m *Manager) Running() []*Client {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var clients []*Client
+	for _, name := range slices.Sorted(maps.Keys(m.running)) {
+		entry := m.running[name]
+		select {
+		case <-entry.ready:
+			if entry.client != nil && !entry.client.Exited() {
+				clients = append(clients, entry.client)
+			}
+		default:
+		}
+	}
+	return clients
+}
+
+// Close shuts every server down. The manager cannot be used afterwards.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	m.closed = true
+	entries := slices.Collect(maps.Values(m.running))
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, entry := range entries {
+		wg.Go(func() {
+			<-entry.ready
+			if entry.client != nil {
+				entry.client.Close()
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,186 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// Position is a zero-based line and UTF-16 code unit offset in a document,
+// as defined by the Language Server Protocol.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a half-open span between two positions.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range inside a document identified by URI.
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// locationLink is the richer definition result some servers return.
+type locationLink struct {
+	TargetURI            string `json:"targetUri"`
+	TargetRange          Range  `json:"targetRange"`
+	TargetSelectionRange Range  `json:"targetSelectionRange"`
+}
+
+// DiagnosticSeverity ranks a diagnostic; lower is more severe.
+type DiagnosticSeverity int
+
+// Diagnostic severities.
+const (
+	SeverityError       DiagnosticSeverity = 1
+	SeverityWarning     DiagnosticSeverity = 2
+	SeverityInformation DiagnosticSeverity = 3
+	SeverityHint        DiagnosticSeverity = 4
+)
+
+// String returns the lower-case name of the severity.
+func (s DiagnosticSeverity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInformation:
+		return "info"
+	case SeverityHint:
+		return "hint"
+	default:
+		return "error"
+	}
+}
+
+// Diagnostic is a compiler error, warning or hint reported by a server.
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	Source   string             `json:"source,omitempty"`
+	Message  string             `json:"message"`
+}
+
+// TextEdit replaces a range of a document with new text.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// WorkspaceEdit is a set of changes across documents, as returned by a
+// rename request. Servers use either Changes or DocumentChanges.
+type WorkspaceEdit struct {
+	Changes         map[string][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []json.RawMessage     `json:"documentChanges,omitempty"`
+}
+
+// textDocumentEdit is the DocumentChanges entry that edits a file; other
+// entries (create, rename, delete) carry a "kind" instead.
+type textDocumentEdit struct {
+	Kind         string `json:"kind,omitempty"`
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Edits []TextEdit `json:"edits"`
+}
+
+// SymbolKind classifies a workspace symbol.
+type SymbolKind int
+
+var symbolKindNames = map[SymbolKind]string{
+	1: "file", 2: "module", 3: "namespace", 4: "package", 5: "class",
+	6: "method", 7: "property", 8: "field", 9: "constructor", 10: "enum",
+	11: "interface", 12: "function", 13: "variable", 14: "constant",
+	15: "string", 16: "number", 17: "boolean", 18: "array", 19: "object",
+	20: "key", 21: "null", 22: "enum member", 23: "struct", 24: "event",
+	25: "operator", 26: "type parameter",
+}
+
+// String returns the lower-case name of the kind.
+func (k SymbolKind) String() string {
+	if name, ok := symbolKindNames[k]; ok {
+		return name
+	}
+	return "symbol"
+}
+
+// Symbol is a named program element found by a workspace symbol query.
+type Symbol struct {
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	ContainerName string     `json:"containerName,omitempty"`
+	Location      Location   `json:"location"`
+}
+
+// PathToURI converts an absolute file path to a file:// URI.
+func PathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if runtime.GOOS == "windows" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// URIToPath converts a file:// URI back to a file path. Other URIs are
+// returned unchanged.
+func URIToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.FromSlash(path)
+}
+
+// UTF16Len returns the length of s in UTF-16 code units, the unit LSP uses
+// for character offsets.
+func UTF16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// Offset converts pos to a byte offset in content. Positions past the end
+// of a line or of the document are clamped.
+func Offset(content []byte, pos Position) int {
+	off := 0
+	for line := 0; line < pos.Line; line++ {
+		i := bytes.IndexByte(content[off:], '\n')
+		if i < 0 {
+			return len(content)
+		}
+		off += i + 1
+	}
+	for units := 0; units < pos.Character && off < len(content); {
+		r, size := utf8.DecodeRune(content[off:])
+		if r == '\n' {
+			break
+		}
+		if r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+		off += size
+	}
+	return off
+}
@@ -0,0 +1,217 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+
+	"github.com/mark3labs/kit/internal/extbridge"
+	kit "github.com/mark3labs/kit/pkg/kit"
+)
+
+// agent is the part of *kit.Kit that run_agent drives.
+type agent interface {
+	PromptResult(ctx context.Context, message string) (*kit.TurnResult, error)
+	Subscribe(listener kit.EventListener) func()
+	GetSessionID() string
+	Close() error
+}
+
+// agentSession is a live run_agent session. busy is held for the duration
+// of a turn so concurrent calls on one session fail fast instead of
+// interleaving.
+type agentSession struct {
+	agent agent
+	busy  sync.Mutex
+}
+
+// agentRegistry holds the live run_agent sessions by Kit session ID.
+type agentRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*agentSession
+}
+
+func newAgentRegistry() *agentRegistry {
+	return &agentRegistry{sessions: make(map[string]*agentSession)}
+}
+
+func (r *agentRegistry) get(id string) (*agentSession, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sess, ok := r.sessions[id]
+	return sess, ok
+}
+
+// add registers sess under id. When another call opened the same session
+// first, that session wins and sess is closed.
+func (r *agentRegistry) add(id string, sess *agentSession) *agentSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.sessions[id]; ok {
+		_ = sess.agent.Close()
+		return existing
+	}
+	r.sessions[id] = sess
+	return sess
+}
+
+func (r *agentRegistry) closeAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, sess := range r.sessions {
+		_ = sess.agent.Close()
+		delete(r.sessions, id)
+	}
+}
+
+// newKitAgent creates a Kit instance persisting to a tree session in the
+// server's working directory, or resuming the session at sessionPath. Model
+// and provider flags come from the process-global config store, as for
+// `kit acp`.
+func (s *Server) newKitAgent(ctx context.Context, sessionPath string) (agent, error) {
+	streamOn := true
+	k, err := kit.New(ctx, &kit.Options{
+		SessionDir:     s.opts.Cwd,
+		SessionPath:    sessionPath,
+		Quiet:          true,
+		Streaming:      &streamOn,
+		Model:          viper.GetString("model"),
+		ThinkingLevel:  viper.GetString("thinking-level"),
+		ProviderURL:    viper.GetString("provider-url"),
+		ProviderAPIKey: viper.GetString("provider-api-key"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create kit instance: %w", err)
+	}
+
+	// Extensions get the headless context; TUI-only fields stay nil and are
+	// replaced with no-ops by the runner.
+	if k.Extensions().HasExtensions() {
+		ec := extbridge.BaseContext(context.Background(), k)
+		ec.SessionID = k.GetSessionID()
+		ec.CWD = s.opts.Cwd
+		ec.Model = k.GetModelString()
+		ec.Interactive = false
+		ec.Print = func(text string) { log.Debug("extension: print", "text", text) }
+		ec.PrintInfo = func(text string) { log.Info("extension: info", "text", text) }
+		ec.PrintError = func(text string) { log.Error("extension: error", "text", text) }
+		k.Extensions().SetContext(ec)
+		k.Extensions().EmitSessionStart()
+	}
+	return k, nil
+}
+
+// addAgentTool publishes run_agent.
+func (s *Server) addAgentTool() {
+	tool := mcp.NewTool("run_agent",
+		mcp.WithDescription("Delegate a task to the Kit coding agent. Kit works in "+
+			"its own directory with its configured model, tools, skills and extensions, "+
+			"and returns its final answer. Pass the returned session_id to continue "+
+			"the same conversation in a later call."),
+		mcp.WithString("prompt", mcp.Required(), mcp.Description("The task or message for the agent")),
+		mcp.WithString("session_id", mcp.Description("Session to continue; omit to start a new one")),
+	)
+	s.mcp.AddTool(tool, s.handleRunAgent)
+}
+
+func (s *Server) handleRunAgent(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	prompt := req.GetString("prompt", "")
+	if strings.TrimSpace(prompt) == "" {
+		return mcp.NewToolResultError("prompt is required"), nil
+	}
+	sess, err := s.agentSession(ctx, req.GetString("session_id", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	sessionID := sess.agent.GetSessionID()
+	if !sess.busy.TryLock() {
+		return mcp.NewToolResultError(fmt.Sprintf("session %s is already running a turn", sessionID)), nil
+	}
+	defer sess.busy.Unlock()
+
+	unsubscribe := sess.agent.Subscribe(s.progressReporter(ctx, req))
+	result, err := sess.agent.PromptResult(ctx, prompt)
+	unsubscribe()
+	s.publishSession(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("agent turn failed: %v (session_id: %s)", err, sessionID)), nil
+	}
+
+	out := mcp.NewToolResultText(result.Response)
+	out.StructuredContent = map[string]any{
+		"response":    result.Response,
+		"session_id":  sessionID,
+		"stop_reason": result.StopReason,
+	}
+	return out, nil
+}
+
+// agentSession returns the live session for id, resuming it from disk when
+// it is not open yet. An empty id starts a new session.
+func (s *Server) agentSession(ctx context.Context, id string) (*agentSession, error) {
+	if id != "" {
+		if sess, ok := s.agents.get(id); ok {
+			return sess, nil
+		}
+	}
+	var path string
+	if id != "" {
+		info, err := s.findSession(id)
+		if err != nil {
+			return nil, err
+		}
+		path = info.Path
+	}
+	// The Kit outlives this request, so it must not inherit its
+	// cancellation.
+	a, err := s.newAgent(context.WithoutCancel(ctx), path)
+	if err != nil {
+		return nil, err
+	}
+	return s.agents.add(a.GetSessionID(), &agentSession{agent: a}), nil
+}
+
+// progressReporter returns an event listener that reports tool activity as
+// progress notifications. It does nothing when the client did not ask for
+// progress.
+func (s *Server) progressReporter(ctx context.Context, req mcp.CallToolRequest) kit.EventListener {
+	if req.Params.Meta == nil || req.Params.Meta.ProgressToken == nil {
+		return func(kit.Event) {}
+	}
+	token := req.Params.Meta.ProgressToken
+	var mu sync.Mutex
+	progress := 0
+	return func(e kit.Event) {
+		var message string
+		switch ev := e.(type) {
+		case kit.ToolCallEvent:
+			message = "Running " + ev.ToolName
+		case kit.ToolResultEvent:
+			message = ev.ToolName + " finished"
+			if ev.IsError {
+				message = ev.ToolName + " failed"
+			}
+		case kit.TurnEndEvent:
+			message = "Turn complete"
+		default:
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		mu.Lock()
+		progress++
+		n := progress
+		mu.Unlock()
+		_ = s.mcp.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+			"progressToken": token,
+			"progress":      n,
+			"message":       message,
+		})
+	}
+}
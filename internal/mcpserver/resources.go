@@ -0,0 +1,141 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/mark3labs/kit/internal/prompts"
+	"github.com/mark3labs/kit/internal/session"
+)
+
+// sessionURIPrefix prefixes the URI of every session resource.
+const sessionURIPrefix = "kit://sessions/"
+
+// addPrompts publishes the prompt templates. A template with placeholders
+// takes a single "arguments" argument, split and substituted exactly like
+// the text after /name in the TUI.
+func (s *Server) addPrompts() {
+	for _, tpl := range s.opts.Prompts {
+		var opts []mcp.PromptOption
+		if tpl.Description != "" {
+			opts = append(opts, mcp.WithPromptDescription(tpl.Description))
+		}
+		if tpl.HasArgPlaceholders() {
+			argOpts := []mcp.ArgumentOption{mcp.ArgumentDescription("Space-separated arguments; quote values containing spaces")}
+			if tpl.RequiredArgs() > 0 {
+				argOpts = append(argOpts, mcp.RequiredArgument())
+			}
+			opts = append(opts, mcp.WithArgument("arguments", argOpts...))
+		}
+		s.mcp.AddPrompt(mcp.NewPrompt(tpl.Name, opts...), promptHandler(tpl))
+	}
+}
+
+func promptHandler(tpl *prompts.PromptTemplate) server.PromptHandlerFunc {
+	return func(_ context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		args := req.Params.Arguments["arguments"]
+		if n := tpl.RequiredArgs(); n > 0 && len(prompts.ParseCommandArgs(args)) < n {
+			return nil, fmt.Errorf("prompt %s needs %d argument(s)", tpl.Name, n)
+		}
+		return mcp.NewGetPromptResult(tpl.Description, []mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(tpl.Expand(args))),
+		}), nil
+	}
+}
+
+// addSessionResources publishes the sessions saved for the working directory
+// and a template that resolves any session ID, including sessions created
+// after the server started.
+func (s *Server) addSessionResources() {
+	s.mcp.AddResourceTemplate(
+		mcp.NewResourceTemplate(sessionURIPrefix+"{id}", "Kit session",
+			mcp.WithTemplateDescription("Transcript of a Kit session by ID"),
+			mcp.WithTemplateMIMEType("text/markdown"),
+		),
+		s.readSession,
+	)
+	infos, err := session.ListSessions(s.opts.Cwd)
+	if err != nil {
+		return
+	}
+	for _, info := range infos {
+		s.addSessionResource(info)
+	}
+}
+
+// publishSession lists the session with the given ID as a resource if it is
+// not listed yet, notifying clients that the resource list changed.
+func (s *Server) publishSession(id string) {
+	if s.isPublished(id) {
+		return
+	}
+	if info, err := s.findSession(id); err == nil {
+		s.addSessionResource(info)
+	}
+}
+
+func (s *Server) isPublished(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.published[id]
+}
+
+func (s *Server) addSessionResource(info session.SessionInfo) {
+	s.mu.Lock()
+	if s.published[info.ID] {
+		s.mu.Unlock()
+		return
+	}
+	s.published[info.ID] = true
+	s.mu.Unlock()
+
+	s.mcp.AddResource(
+		mcp.NewResource(sessionURIPrefix+info.ID, info.Title(),
+			mcp.WithResourceDescription(fmt.Sprintf("%d messages, last active %s",
+				info.MessageCount, info.Modified.Format("2006-01-02 15:04"))),
+			mcp.WithMIMEType("text/markdown"),
+		),
+		s.readSession,
+	)
+}
+
+// findSession looks up a session saved for the working directory by ID.
+func (s *Server) findSession(id string) (session.SessionInfo, error) {
+	infos, err := session.ListSessions(s.opts.Cwd)
+	if err != nil {
+		return session.SessionInfo{}, fmt.Errorf("list sessions: %w", err)
+	}
+	for _, info := range infos {
+		if info.ID == id {
+			return info, nil
+		}
+	}
+	return session.SessionInfo{}, fmt.Errorf("session not found: %s", id)
+}
+
+func (s *Server) readSession(_ context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id, ok := strings.CutPrefix(req.Params.URI, sessionURIPrefix)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("not a session URI: %s", req.Params.URI)
+	}
+	info, err := s.findSession(id)
+	if err != nil {
+		return nil, err
+	}
+	tm, err := session.OpenTreeSession(info.Path)
+	if err != nil {
+		return nil, fmt.Errorf("open session: %w", err)
+	}
+	defer func() { _ = tm.Close() }()
+	messages, _, _ := tm.BuildContext()
+
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      req.Params.URI,
+		MIMEType: "text/markdown",
+		Text:     session.Transcript(info.Title(), messages),
+	}}, nil
+}
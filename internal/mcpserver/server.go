@@ -0,0 +1,286 @@
+// Package mcpserver exposes Kit over the Model Context Protocol so other
+// agents and IDEs can delegate work to it. A server publishes:
+//
+//   - the core tools (bash, read, write, edit, apply_patch, grep, find, ls
+//     and the background job tools), gated by the permission policy;
+//   - run_agent, which drives a full Kit turn — skills, extensions and MCP
+//     servers included — and reports its progress;
+//   - prompt templates as MCP prompts;
+//   - saved sessions as kit://sessions/{id} resources.
+//
+// It is the MCP counterpart of internal/acpserver and backs `kit mcp serve`.
+package mcpserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/mark3labs/kit/internal/core"
+	"github.com/mark3labs/kit/internal/permission"
+	"github.com/mark3labs/kit/internal/prompts"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Cwd is the directory tools run in, run_agent sessions are created for
+	// and sessions are listed from.
+	Cwd string
+	// Version is reported to clients during initialization.
+	Version string
+	// Permissions gates core tool calls. Calls the policy would ask about
+	// are denied: an MCP client has no one to ask.
+	Permissions permission.Config
+	// Sandbox selects where the bash tool runs commands.
+	Sandbox core.SandboxConfig
+	// Prompts are published as MCP prompts.
+	Prompts []*prompts.PromptTemplate
+}
+
+// Server is a Kit MCP server. Create one with New and serve it with
+// ServeStdio or ServeHTTP.
+type Server struct {
+	opts   Options
+	mcp    *server.MCPServer
+	policy *permission.Policy
+	jobs   *core.JobManager
+	agents *agentRegistry
+
+	// newAgent creates the agent behind a run_agent session. sessionPath
+	// is empty for a new session. Tests replace it with a fake.
+	newAgent func(ctx context.Context, sessionPath string) (agent, error)
+
+	calls atomic.Int64 // numbers core tool calls
+
+	mu        sync.Mutex
+	published map[string]bool // session IDs already listed as resources
+}
+
+// New builds a server for opts.
+func New(opts Options) (*Server, error) {
+	var policy *permission.Policy
+	if !opts.Permissions.IsZero() {
+		var err error
+		if policy, err = permission.New(opts.Permissions); err != nil {
+			return nil, fmt.Errorf("invalid permission policy: %w", err)
+		}
+	}
+	executor, err := core.NewBashExecutor(opts.Sandbox)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sandbox: %w", err)
+	}
+	if opts.Version == "" {
+		opts.Version = "dev"
+	}
+
+	s := &Server{
+		opts:      opts,
+		policy:    policy,
+		jobs:      core.NewJobManager(),
+		agents:    newAgentRegistry(),
+		published: make(map[string]bool),
+	}
+	s.newAgent = s.newKitAgent
+	s.mcp = server.NewMCPServer("kit", opts.Version,
+		server.WithToolCapabilities(false),
+		server.WithPromptCapabilities(false),
+		server.WithResourceCapabilities(false, true),
+		server.WithRecovery(),
+		server.WithInstructions("Kit is a coding agent. Call run_agent to delegate a task to it, or use its file and shell tools directly."),
+	)
+
+	toolOpts := []core.ToolOption{
+		core.WithWorkDir(opts.Cwd),
+		core.WithBashExecutor(executor),
+		core.WithJobManager(s.jobs),
+	}
+	for _, tool := range core.SubagentTools(toolOpts...) {
+		s.addCoreTool(tool)
+	}
+	s.addAgentTool()
+	s.addPrompts()
+	s.addSessionResources()
+	return s, nil
+}
+
+// MCPServer returns the underlying mcp-go server, e.g. for an in-process
+// client.
+func (s *Server) MCPServer() *server.MCPServer {
+	return s.mcp
+}
+
+// ServeStdio serves MCP over stdin/stdout until the client disconnects.
+func (s *Server) ServeStdio() error {
+	return server.ServeStdio(s.mcp)
+}
+
+// HTTPHandler returns a handler serving MCP over streamable HTTP at
+// endpoint. Every request must present token as a bearer token, name a
+// loopback Host and, when a browser sends one, a loopback Origin: the tools
+// run commands, so neither a cross-site request nor a DNS-rebound page may
+// reach them.
+func (s *Server) HTTPHandler(endpoint, token string) http.Handler {
+	_, httpServer := s.newHTTPServer(endpoint, token)
+	return httpServer.Handler
+}
+
+// newHTTPServer builds the streamable transport and the guarded http.Server
+// it listens with.
+func (s *Server) newHTTPServer(endpoint, token string) (*server.StreamableHTTPServer, *http.Server) {
+	mux := http.NewServeMux()
+	httpServer := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	mcpHTTP := server.NewStreamableHTTPServer(s.mcp,
+		server.WithEndpointPath(endpoint),
+		server.WithStreamableHTTPServer(httpServer),
+	)
+	mux.Handle(endpoint, guardHTTP(mcpHTTP, token))
+	return mcpHTTP, httpServer
+}
+
+// ServeHTTP serves MCP over streamable HTTP on addr until ctx is cancelled.
+// token may not be empty; see HTTPHandler.
+func (s *Server) ServeHTTP(ctx context.Context, addr, endpoint, token string) error {
+	if token == "" {
+		return errors.New("the http transport needs a bearer token")
+	}
+	mcpHTTP, _ := s.newHTTPServer(endpoint, token)
+	errCh := make(chan error, 1)
+	go func() { errCh <- mcpHTTP.Start(addr) }()
+	select {
+	case err := <-errCh:
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		return mcpHTTP.Shutdown(context.Background())
+	}
+}
+
+// guardHTTP rejects requests to next that do not come from a loopback Host
+// and Origin or do not carry token as their bearer token.
+func guardHTTP(next http.Handler, token string) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLoopbackHost(r.Host) {
+			http.Error(w, fmt.Sprintf("Forbidden: Host %q is not a loopback address", r.Host), http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || !isLoopbackHost(u.Host) {
+				http.Error(w, fmt.Sprintf("Forbidden: Origin %q is not a loopback address", origin), http.StatusForbidden)
+				return
+			}
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackHost reports whether a Host header or URL host, with or without
+// a port, names localhost or a loopback IP.
+func isLoopbackHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Close stops background jobs and closes every run_agent session.
+func (s *Server) Close() {
+	s.agents.closeAll()
+	s.jobs.KillAll()
+}
+
+// addCoreTool publishes a core tool under its own name and schema.
+func (s *Server) addCoreTool(tool fantasy.AgentTool) {
+	info := tool.Info()
+	schema := map[string]any{
+		"type":       "object",
+		"properties": info.Parameters,
+	}
+	if len(info.Required) > 0 {
+		schema["required"] = info.Required
+	}
+	raw, _ := json.Marshal(schema)
+
+	s.mcp.AddTool(mcp.NewToolWithRawSchema(info.Name, info.Description, raw),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			input, err := json.Marshal(req.GetArguments())
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid arguments: %v", err)), nil
+			}
+			if reason := s.checkPermission(info.Name, string(input)); reason != "" {
+				return mcp.NewToolResultError("Error: " + reason), nil
+			}
+			resp, err := tool.Run(ctx, fantasy.ToolCall{ID: fmt.Sprintf("mcp_%d", s.calls.Add(1)), Name: info.Name, Input: string(input)})
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return toolResult(resp), nil
+		})
+}
+
+// checkPermission evaluates a core tool call against the policy and returns
+// why it may not run, or "" when it may.
+func (s *Server) checkPermission(toolName, input string) string {
+	if s.policy == nil {
+		return ""
+	}
+	switch s.policy.Evaluate(permission.Request{ToolName: toolName, Input: input, WorkDir: s.opts.Cwd}) {
+	case permission.Allow:
+		return ""
+	case permission.Deny:
+		return fmt.Sprintf("%s call denied by permission policy", toolName)
+	default:
+		return fmt.Sprintf("%s call requires approval but no interactive approver is available", toolName)
+	}
+}
+
+// toolResult converts a core tool response to an MCP result.
+func toolResult(resp fantasy.ToolResponse) *mcp.CallToolResult {
+	result := &mcp.CallToolResult{IsError: resp.IsError}
+	if resp.Content != "" || len(resp.Data) == 0 {
+		result.Content = append(result.Content, mcp.NewTextContent(resp.Content))
+	}
+	if len(resp.Data) > 0 {
+		data := base64.StdEncoding.EncodeToString(resp.Data)
+		switch {
+		case strings.HasPrefix(resp.MediaType, "image/"):
+			result.Content = append(result.Content, mcp.NewImageContent(data, resp.MediaType))
+		case strings.HasPrefix(resp.MediaType, "audio/"):
+			result.Content = append(result.Content, mcp.NewAudioContent(data, resp.MediaType))
+		default:
+			result.Content = append(result.Content, mcp.NewEmbeddedResource(mcp.BlobResourceContents{
+				URI:      "kit://tool-output",
+				MIMEType: resp.MediaType,
+				Blob:     data,
+			}))
+		}
+	}
+	return result
+}
@@ -0,0 +1,394 @@
+package mcpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/mark3labs/kit/internal/permission"
+	"github.com/mark3labs/kit/internal/prompts"
+	"github.com/mark3labs/kit/internal/session"
+	kit "github.com/mark3labs/kit/pkg/kit"
+)
+
+// fakeAgent stands in for a Kit instance: each turn reports one tool call
+// and answers with the prompt echoed back.
+type fakeAgent struct {
+	id        string
+	mu        sync.Mutex
+	listeners []kit.EventListener
+	prompts   []string
+	release   chan struct{} // when set, turns block until it is closed
+}
+
+func (a *fakeAgent) PromptResult(_ context.Context, message string) (*kit.TurnResult, error) {
+	a.mu.Lock()
+	a.prompts = append(a.prompts, message)
+	listeners := append([]kit.EventListener(nil), a.listeners...)
+	a.mu.Unlock()
+	if a.release != nil {
+		<-a.release
+	}
+	for _, l := range listeners {
+		l(kit.ToolCallEvent{ToolName: "bash"})
+		l(kit.ToolResultEvent{ToolName: "bash"})
+		l(kit.TurnEndEvent{Response: "echo: " + message})
+	}
+	return &kit.TurnResult{Response: "echo: " + message, StopReason: "stop", SessionID: a.id}, nil
+}
+
+func (a *fakeAgent) Subscribe(l kit.EventListener) func() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.listeners = append(a.listeners, l)
+	i := len(a.listeners) - 1
+	return func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.listeners[i] = func(kit.Event) {}
+	}
+}
+
+func (a *fakeAgent) GetSessionID() string { return a.id }
+func (a *fakeAgent) Close() error         { return nil }
+
+// newTestServer starts a server for a fresh directory, with HOME pointed at
+// a temporary directory so sessions stay isolated, and connects a client.
+func newTestServer(t *testing.T, opts Options) (*Server, *client.Client) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	if opts.Cwd == "" {
+		opts.Cwd = t.TempDir()
+	}
+	srv, err := New(opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(srv.Close)
+
+	c, err := client.NewInProcessClient(srv.MCPServer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return srv, c
+}
+
+func callTool(t *testing.T, c *client.Client, name string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+	res, err := c.CallTool(context.Background(), req)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return res
+}
+
+func resultText(res *mcp.CallToolResult) string {
+	var parts []string
+	for _, c := range res.Content {
+		if tc, ok := c.(mcp.TextContent); ok {
+			parts = append(parts, tc.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func TestServer_CoreTools(t *testing.T) {
+	dir := t.TempDir()
+	_, c := newTestServer(t, Options{
+		Cwd: dir,
+		Permissions: permission.Config{
+			Deny: []string{"bash(rm *)"},
+			Ask:  []string{"write(secrets/**)"},
+		},
+	})
+
+	list, err := c.ListTools(context.Background(), mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, tool := range list.Tools {
+		names[tool.Name] = true
+	}
+	for _, want := range []string{"bash", "read", "write", "edit", "apply_patch", "grep", "find", "ls", "bash_output", "bash_kill", "run_agent"} {
+		if !names[want] {
+			t.Errorf("tool %s not listed", want)
+		}
+	}
+	if names["subagent"] {
+		t.Error("subagent should not be exposed")
+	}
+
+	res := callTool(t, c, "write", map[string]any{"path": "notes.txt", "content": "hello\n"})
+	if res.IsError {
+		t.Fatalf("write: %s", resultText(res))
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "notes.txt")); string(got) != "hello\n" {
+		t.Errorf("notes.txt = %q", got)
+	}
+	if res = callTool(t, c, "read", map[string]any{"path": "notes.txt"}); !strings.Contains(resultText(res), "hello") {
+		t.Errorf("read = %q", resultText(res))
+	}
+
+	res = callTool(t, c, "bash", map[string]any{"command": "rm notes.txt"})
+	if !res.IsError || !strings.Contains(resultText(res), "denied by permission policy") {
+		t.Errorf("denied bash = %q", resultText(res))
+	}
+	// Nobody can answer "ask", so the call fails closed.
+	res = callTool(t, c, "write", map[string]any{"path": "secrets/key", "content": "x"})
+	if !res.IsError || !strings.Contains(resultText(res), "no interactive approver") {
+		t.Errorf("ask write = %q", resultText(res))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secrets")); !os.IsNotExist(err) {
+		t.Error("the write should not have run")
+	}
+}
+
+func TestServer_RunAgent(t *testing.T) {
+	srv, c := newTestServer(t, Options{})
+	agents := make(map[string]*fakeAgent)
+	var created []string
+	srv.newAgent = func(_ context.Context, path string) (agent, error) {
+		a := &fakeAgent{id: "s" + string(rune('1'+len(created)))}
+		created = append(created, path)
+		agents[a.id] = a
+		return a, nil
+	}
+
+	var mu sync.Mutex
+	var progress []string
+	c.OnNotification(func(n mcp.JSONRPCNotification) {
+		if n.Method == "notifications/progress" {
+			mu.Lock()
+			progress = append(progress, n.Params.AdditionalFields["message"].(string))
+			mu.Unlock()
+		}
+	})
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "run_agent"
+	req.Params.Arguments = map[string]any{"prompt": "fix the build"}
+	req.Params.Meta = &mcp.Meta{ProgressToken: "tok"}
+	res, err := c.CallTool(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IsError || resultText(res) != "echo: fix the build" {
+		t.Fatalf("run_agent = %q", resultText(res))
+	}
+	structured, _ := res.StructuredContent.(map[string]any)
+	if structured["session_id"] != "s1" || structured["stop_reason"] != "stop" {
+		t.Errorf("structured content = %v", res.StructuredContent)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(progress)
+		mu.Unlock()
+		if n >= 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	if strings.Join(progress, ",") != "Running bash,bash finished,Turn complete" {
+		t.Errorf("progress = %q", progress)
+	}
+	mu.Unlock()
+
+	// Continuing reuses the live session.
+	res = callTool(t, c, "run_agent", map[string]any{"prompt": "again", "session_id": "s1"})
+	if res.IsError || len(created) != 1 || len(agents["s1"].prompts) != 2 {
+		t.Errorf("continue = %q, created %d agents", resultText(res), len(created))
+	}
+
+	// Unknown sessions are not silently replaced with new ones.
+	res = callTool(t, c, "run_agent", map[string]any{"prompt": "x", "session_id": "nope"})
+	if !res.IsError || !strings.Contains(resultText(res), "session not found") {
+		t.Errorf("unknown session = %q", resultText(res))
+	}
+
+	// A session runs one turn at a time.
+	agents["s1"].release = make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		callTool(t, c, "run_agent", map[string]any{"prompt": "slow", "session_id": "s1"})
+	}()
+	for {
+		agents["s1"].mu.Lock()
+		n := len(agents["s1"].prompts)
+		agents["s1"].mu.Unlock()
+		if n == 3 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	res = callTool(t, c, "run_agent", map[string]any{"prompt": "overlap", "session_id": "s1"})
+	if !res.IsError || !strings.Contains(resultText(res), "already running") {
+		t.Errorf("overlapping turn = %q", resultText(res))
+	}
+	close(agents["s1"].release)
+	<-done
+}
+
+func TestServer_Prompts(t *testing.T) {
+	_, c := newTestServer(t, Options{Prompts: []*prompts.PromptTemplate{
+		{Name: "review", Description: "Review a file", Content: "Review $1 for $2."},
+		{Name: "standup", Content: "Summarize today's work."},
+	}})
+	ctx := context.Background()
+
+	list, err := c.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil || len(list.Prompts) != 2 {
+		t.Fatalf("ListPrompts = %+v, %v", list, err)
+	}
+	for _, p := range list.Prompts {
+		if p.Name == "review" && (len(p.Arguments) != 1 || !p.Arguments[0].Required) {
+			t.Errorf("review arguments = %+v", p.Arguments)
+		}
+		if p.Name == "standup" && len(p.Arguments) != 0 {
+			t.Errorf("standup arguments = %+v", p.Arguments)
+		}
+	}
+
+	req := mcp.GetPromptRequest{}
+	req.Params.Name = "review"
+	req.Params.Arguments = map[string]string{"arguments": `main.go "race conditions"`}
+	got, err := c.GetPrompt(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := got.Messages[0].Content.(mcp.TextContent).Text; text != "Review main.go for race conditions." {
+		t.Errorf("expanded = %q", text)
+	}
+
+	req.Params.Arguments = map[string]string{"arguments": "main.go"}
+	if _, err := c.GetPrompt(ctx, req); err == nil || !strings.Contains(err.Error(), "needs 2 argument") {
+		t.Errorf("missing argument: %v", err)
+	}
+}
+
+func TestServer_SessionResources(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cwd := t.TempDir()
+	tm, err := session.CreateTreeSession(cwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = tm.AppendLLMMessage(fantasy.NewUserMessage("list the files"))
+	_, _ = tm.AppendLLMMessage(fantasy.Message{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{
+		fantasy.TextPart{Text: "Listing."},
+		fantasy.ToolCallPart{ToolCallID: "1", ToolName: "ls", Input: `{"path":"."}`},
+	}})
+	_, _ = tm.AppendLLMMessage(fantasy.Message{Role: fantasy.MessageRoleTool, Content: []fantasy.MessagePart{
+		fantasy.ToolResultPart{ToolCallID: "1", Output: fantasy.ToolResultOutputContentText{Text: "main.go"}},
+	}})
+	id := tm.GetSessionID()
+	_ = tm.Close()
+
+	srv, err := New(Options{Cwd: cwd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	c, err := client.NewInProcessClient(srv.MCPServer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_ = c.Start(ctx)
+	if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := c.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil || len(list.Resources) != 1 {
+		t.Fatalf("ListResources = %+v, %v", list, err)
+	}
+	if r := list.Resources[0]; r.URI != "kit://sessions/"+id || r.Name != "list the files" {
+		t.Errorf("resource = %+v", r)
+	}
+
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = "kit://sessions/" + id
+	res, err := c.ReadResource(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := res.Contents[0].(mcp.TextResourceContents).Text
+	want := "# list the files\n\n## User\n\nlist the files\n\n## Assistant\n\nListing.\n\nTool call `ls`:\n\n```json\n{\"path\":\".\"}\n```\n\n## Tool result\n\n```\nmain.go\n```\n"
+	if text != want {
+		t.Errorf("transcript:\n%s\nwant:\n%s", text, want)
+	}
+
+	req.Params.URI = "kit://sessions/missing"
+	if _, err := c.ReadResource(ctx, req); err == nil {
+		t.Error("expected an error for an unknown session")
+	}
+}
+
+func TestHTTPHandler_Guard(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv, err := New(Options{Cwd: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	h := srv.HTTPHandler("/mcp", "s3cret")
+
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		auth   string
+		want   int
+	}{
+		{"no token", "127.0.0.1:8765", "", "", http.StatusUnauthorized},
+		{"wrong token", "127.0.0.1:8765", "", "Bearer nope", http.StatusUnauthorized},
+		{"rebound host", "evil.example:8765", "", "Bearer s3cret", http.StatusForbidden},
+		{"foreign origin", "localhost:8765", "https://evil.example", "Bearer s3cret", http.StatusForbidden},
+		{"loopback origin", "[::1]:8765", "http://localhost:3000", "Bearer s3cret", 0},
+		{"no origin", "localhost:8765", "", "Bearer s3cret", 0},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		req.Host = tt.host
+		req.Header.Set("Content-Type", "application/json")
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		switch {
+		case tt.want != 0 && rec.Code != tt.want:
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		case tt.want == 0 && (rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden):
+			t.Errorf("%s: rejected with %d: %s", tt.name, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package permission
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func bashReq(cmd string) Request {
+	b, _ := json.Marshal(map[string]string{"command": cmd})
+	return Request{ToolName: "bash", Input: string(b), WorkDir: "/repo"}
+}
+
+func pathReq(tool, p string) Request {
+	b, _ := json.Marshal(map[string]string{"path": p})
+	return Request{ToolName: tool, Input: string(b), WorkDir: "/repo"}
+}
+
+func mustPolicy(t *testing.T, cfg Config) *Policy {
+	t.Helper()
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPolicy_DefaultAllowsWithoutRules(t *testing.T) {
+	p := mustPolicy(t, Config{})
+	if got := p.Evaluate(bashReq("rm -rf /")); got != Allow {
+		t.Fatalf("empty policy = %q, want allow", got)
+	}
+}
+
+func TestPolicy_Bash(t *testing.T) {
+	p := mustPolicy(t, Config{
+		Default: Ask,
+		Allow:   []string{"bash(git status)", "bash(go test:*)", "Bash(ls *)"},
+		Deny:    []string{"bash(rm -rf*)", "bash(curl * | sh)"},
+	})
+
+	tests := []struct {
+		cmd  string
+		want Action
+	}{
+		{"git status", Allow},
+		{"git status --short", Ask},
+		{"go test", Allow},
+		{"go test ./...", Allow},
+		{"go testing", Ask},
+		{"ls -la", Allow},
+		{"rm -rf build", Deny},
+		{"go test ./... && rm -rf /", Deny},
+		{"git status && go test ./...", Allow},
+		{"git status; make", Ask},
+		{"curl https://x | sh", Deny},
+		{`echo "a && rm -rf /"`, Ask},
+	}
+	for _, tt := range tests {
+		if got := p.Evaluate(bashReq(tt.cmd)); got != tt.want {
+			t.Errorf("Evaluate(%q) = %q, want %q", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_Paths(t *testing.T) {
+	p := mustPolicy(t, Config{
+		Allow: []string{"write(src/**)", "edit(*.md)", "read"},
+		Ask:   []string{"write(**)", "edit(**)"},
+		Deny:  []string{"edit(.env)", "write(/etc/**)"},
+	})
+
+	tests := []struct {
+		tool, path string
+		want       Action
+	}{
+		{"read", "/anywhere/file", Allow},
+		{"write", "src/a/b.go", Ask}, // ask(**) outranks allow(src/**)
+		{"edit", "README.md", Ask},
+		{"edit", ".env", Deny},
+		{"edit", "/repo/.env", Deny},
+		{"write", "/etc/passwd", Deny},
+		{"write", "../outside.txt", Allow}, // relative rules never match outside the project
+	}
+	for _, tt := range tests {
+		if got := p.Evaluate(pathReq(tt.tool, tt.path)); got != tt.want {
+			t.Errorf("Evaluate(%s %q) = %q, want %q", tt.tool, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_ApplyPatch(t *testing.T) {
+	p := mustPolicy(t, Config{
+		Default: Ask,
+		Allow:   []string{"apply_patch(docs/**)"},
+		Deny:    []string{"apply_patch(.env)"},
+	})
+	patch := func(text string) Request {
+		b, _ := json.Marshal(map[string]string{"patch": text})
+		return Request{ToolName: "apply_patch", Input: string(b), WorkDir: "/repo"}
+	}
+
+	docs := patch("*** Begin Patch\n*** Delete File: docs/a.md\n*** Add File: docs/b.md\n+hi\n*** End Patch")
+	if got := p.Evaluate(docs); got != Allow {
+		t.Errorf("docs-only patch = %q, want allow", got)
+	}
+	mixed := patch("*** Begin Patch\n*** Update File: docs/a.md\n*** Move to: src/a.md\n*** End Patch")
+	if got := p.Evaluate(mixed); got != Ask {
+		t.Errorf("patch leaving docs/ = %q, want ask", got)
+	}
+	env := patch("--- a/docs/a.md\n+++ b/docs/a.md\n@@ -1 +1 @@\n-a\n+b\n--- a/.env\n+++ b/.env\n@@ -1 +1 @@\n-a\n+b\n")
+	if got := p.Evaluate(env); got != Deny {
+		t.Errorf("patch touching .env = %q, want deny", got)
+	}
+	if got := docs.Subject(); got != "" {
+		t.Errorf("multi-file Subject() = %q, want empty", got)
+	}
+	if got := suggested(patch("*** Begin Patch\n*** Delete File: docs/a.md\n*** End Patch")); got != "apply_patch(docs/a.md)" {
+		t.Errorf("SuggestRules = %q", got)
+	}
+
+	// A multi-file patch is granted path by path, never as a bare rule.
+	if got := suggested(docs); got != "apply_patch(docs/a.md), apply_patch(docs/b.md)" {
+		t.Errorf("SuggestRules(multi-file) = %q", got)
+	}
+	if got := suggested(patch("not a patch")); got != "" {
+		t.Errorf("SuggestRules(unparseable) = %q, want none", got)
+	}
+	p = mustPolicy(t, Config{Default: Ask})
+	grant(p, mixed)
+	if got := p.Evaluate(mixed); got != Allow {
+		t.Errorf("granted multi-file patch = %q, want allow", got)
+	}
+	if got := p.Evaluate(patch("*** Begin Patch\n*** Delete File: /etc/hosts\n*** End Patch")); got != Ask {
+		t.Errorf("patch outside the granted paths = %q, want ask", got)
+	}
+}
+
+func TestPolicy_RequestPaths(t *testing.T) {
+	p := mustPolicy(t, Config{
+		Allow: []string{"rename_symbol"},
+		Deny:  []string{"rename_symbol(secrets/**)"},
+	})
+	req := pathReq("rename_symbol", "main.go")
+	if got := p.Evaluate(req); got != Allow {
+		t.Errorf("rename of main.go = %q, want allow", got)
+	}
+	req.Paths = []string{"/repo/main.go", "/repo/secrets/keys.go"}
+	if got := p.Evaluate(req); got != Deny {
+		t.Errorf("rename reaching secrets/ = %q, want deny", got)
+	}
+	req.Paths = []string{"/repo/util.go", "/repo/cmd/run.go"}
+	if got := suggested(req); got != "rename_symbol(util.go), rename_symbol(cmd/run.go)" {
+		t.Errorf("SuggestRules = %q", got)
+	}
+}
+
+func TestPolicy_MCP(t *testing.T) {
+	p := mustPolicy(t, Config{
+		Default: Allow,
+		Deny:    []string{"mcp(github__delete_*)"},
+		Ask:     []string{"mcp(github)", "slack__*"},
+	})
+
+	tests := []struct {
+		tool string
+		want Action
+	}{
+		{"github__list_issues", Ask},
+		{"github__delete_repo", Deny},
+		{"slack__post", Ask},
+		{"gitlab__list", Allow},
+	}
+	for _, tt := range tests {
+		if got := p.Evaluate(Request{ToolName: tt.tool, Input: "{}"}); got != tt.want {
+			t.Errorf("Evaluate(%q) = %q, want %q", tt.tool, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_GrantSuggestedRule(t *testing.T) {
+	p := mustPolicy(t, Config{Default: Ask, Deny: []string{"bash(rm:*)"}})
+
+	req := bashReq("echo *.go")
+	if got := p.Evaluate(req); got != Ask {
+		t.Fatalf("before grant = %q, want ask", got)
+	}
+	grant(p, req)
+	if got := p.Evaluate(req); got != Allow {
+		t.Fatalf("after grant = %q, want allow", got)
+	}
+	if got := p.Evaluate(bashReq("echo main.go")); got != Ask {
+		t.Fatalf("grant must match the exact command only, got %q", got)
+	}
+
+	// Grants never override configured deny rules.
+	p.Grant(Rule{Tool: "bash"})
+	if got := p.Evaluate(bashReq("rm -r x")); got != Deny {
+		t.Fatalf("deny rule overridden by grant: %q", got)
+	}
+
+	w := pathReq("write", "/repo/src/main.go")
+	if got := suggested(w); got != "write(src/main.go)" {
+		t.Fatalf("SuggestRules(write) = %q", got)
+	}
+	if got := suggested(Request{ToolName: "github__list", Input: "{}"}); got != "github__list" {
+		t.Fatalf("SuggestRules(mcp) = %q", got)
+	}
+}
+
+func TestPolicy_GrantSuggestedRulesRoundTrip(t *testing.T) {
+	for _, cmd := range []string{
+		"go test ./...",
+		"go test ./... && go vet ./...",
+		"cat go.mod | grep -q kit; echo $?",
+		"ls *.go || ls",
+	} {
+		p := mustPolicy(t, Config{Default: Ask})
+		req := bashReq(cmd)
+		grant(p, req)
+		if got := p.Evaluate(req); got != Allow {
+			t.Errorf("%q after granting %s = %q, want allow", cmd, suggested(req), got)
+		}
+	}
+	if got := suggested(bashReq("go test ./... && go vet ./... && go test ./...")); got != "bash(go test ./...), bash(go vet ./...)" {
+		t.Errorf("SuggestRules(compound) = %q", got)
+	}
+}
+
+// grant grants the rules SuggestRules proposes for req.
+func grant(p *Policy, req Request) {
+	for _, r := range SuggestRules(req) {
+		p.Grant(r)
+	}
+}
+
+// suggested renders the rules SuggestRules proposes for req.
+func suggested(req Request) string {
+	var specs []string
+	for _, r := range SuggestRules(req) {
+		specs = append(specs, r.String())
+	}
+	return strings.Join(specs, ", ")
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	if _, err := New(Config{Default: "sometimes"}); err == nil {
+		t.Error("expected error for invalid default")
+	}
+	if _, err := New(Config{Allow: []string{"bash(unclosed"}}); err == nil {
+		t.Error("expected error for malformed rule")
+	}
+	if _, err := New(Config{Deny: []string{"mcp"}}); err == nil {
+		t.Error("expected error for mcp rule without server")
+	}
+}
+
+func TestStore_AllowAndPersist(t *testing.T) {
+	dir := t.TempDir()
+	storePath := filepath.Join(dir, "permissions.json")
+	project := filepath.Join(dir, "repo")
+
+	s, err := Load(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Allow(project, "bash(make)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Allow(project, "bash(make)"); err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := Load(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := s2.Rules(project)
+	if len(rules) != 1 || rules[0] != "bash(make)" {
+		t.Fatalf("Rules = %v, want [bash(make)]", rules)
+	}
+
+	if err := s2.Revoke(project, "bash(make)"); err != nil {
+		t.Fatal(err)
+	}
+	s3, err := Load(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules := s3.Rules(project); len(rules) != 0 {
+		t.Fatalf("Rules after revoke = %v, want none", rules)
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "nope.json"))
+	if err != nil {
+		t.Fatalf("missing file should not error: %v", err)
+	}
+	if rules := s.Rules("/whatever"); len(rules) != 0 {
+		t.Fatal("empty store should have no rules")
+	}
+}
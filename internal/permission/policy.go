@@ -0,0 +1,519 @@
+// Package permission implements the tool-call approval policy: a set of
+// allow/ask/deny rules evaluated before every tool execution.
+//
+// Rules are written as a tool name optionally followed by a parenthesised
+// specifier, mirroring the syntax used by other coding agents:
+//
+//	read                    any call to the read tool
+//	bash(git status)        exactly "git status"
+//	bash(go test:*)         any command starting with the words "go test"
+//	bash(npm run *)         glob over the full command
+//	write(src/**)           writes under src/ (relative to the project)
+//	edit(*.md)              edits to markdown files in the project root
+//	apply_patch(docs/**)    patches touching only files under docs/
+//	mcp(github)             every tool exposed by the "github" MCP server
+//	mcp(github__create_*)   matching tools on the "github" MCP server
+//	github__*               tool-name globs work without mcp(...) too
+//
+// When several rules match a call, deny wins over ask, and ask wins over
+// allow. Calls that match no rule fall back to the policy default, which is
+// allow unless configured otherwise so that Kit behaves as before when no
+// permissions block is present.
+package permission
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/kit/internal/core"
+)
+
+// Action is the outcome of evaluating a tool call against the policy.
+type Action string
+
+const (
+	// Allow runs the tool call without asking.
+	Allow Action = "allow"
+	// Ask requires an interactive approval before the call runs. Callers
+	// that cannot prompt must treat Ask as Deny.
+	Ask Action = "ask"
+	// Deny blocks the tool call.
+	Deny Action = "deny"
+)
+
+// severity orders actions so the most restrictive one wins.
+func (a Action) severity() int {
+	switch a {
+	case Deny:
+		return 2
+	case Ask:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Config is the "permissions" block of .kit.yml.
+type Config struct {
+	// Default is the action applied to calls matching no rule. Empty means
+	// allow.
+	Default Action `json:"default,omitempty" yaml:"default,omitempty" mapstructure:"default"`
+	// Allow lists rules that run without prompting.
+	Allow []string `json:"allow,omitempty" yaml:"allow,omitempty" mapstructure:"allow"`
+	// Ask lists rules that require approval.
+	Ask []string `json:"ask,omitempty" yaml:"ask,omitempty" mapstructure:"ask"`
+	// Deny lists rules that are always blocked.
+	Deny []string `json:"deny,omitempty" yaml:"deny,omitempty" mapstructure:"deny"`
+}
+
+// IsZero reports whether the config carries no rules and no default.
+func (c Config) IsZero() bool {
+	return c.Default == "" && len(c.Allow) == 0 && len(c.Ask) == 0 && len(c.Deny) == 0
+}
+
+// Rule is a single parsed policy rule.
+type Rule struct {
+	// Tool is the lower-cased tool name or tool-name glob. The special name
+	// "mcp" matches MCP tools by server.
+	Tool string
+	// Specifier is the optional text inside the parentheses. Empty matches
+	// every call to Tool.
+	Specifier string
+	// Action is what happens when the rule matches.
+	Action Action
+}
+
+// ruleRe splits "tool(specifier)" into its parts.
+var ruleRe = regexp.MustCompile(`^([A-Za-z0-9_.*?\-]+)(?:\((.*)\))?$`)
+
+// ParseRule parses a rule written as "tool" or "tool(specifier)".
+func ParseRule(spec string, action Action) (Rule, error) {
+	spec = strings.TrimSpace(spec)
+	m := ruleRe.FindStringSubmatch(spec)
+	if m == nil {
+		return Rule{}, fmt.Errorf("invalid permission rule %q", spec)
+	}
+	r := Rule{
+		Tool:      strings.ToLower(m[1]),
+		Specifier: strings.TrimSpace(m[2]),
+		Action:    action,
+	}
+	if _, err := path.Match(r.Tool, ""); err != nil {
+		return Rule{}, fmt.Errorf("invalid permission rule %q: %w", spec, err)
+	}
+	if r.Tool == "mcp" && r.Specifier == "" {
+		return Rule{}, fmt.Errorf("invalid permission rule %q: mcp rules need a server name", spec)
+	}
+	return r, nil
+}
+
+// String renders the rule back into its textual form.
+func (r Rule) String() string {
+	if r.Specifier == "" {
+		return r.Tool
+	}
+	return r.Tool + "(" + r.Specifier + ")"
+}
+
+// Request describes a tool call to evaluate.
+type Request struct {
+	// ToolName is the name the tool is registered under.
+	ToolName string
+	// Input is the raw JSON argument object of the call.
+	Input string
+	// WorkDir is the directory relative paths are resolved against; it is
+	// also the root that path specifiers are relative to.
+	WorkDir string
+	// Paths, when set, lists every file the call modifies, and each must be
+	// allowed as with the files of an apply_patch call. Tools that learn
+	// their files while running, such as rename_symbol, set it.
+	Paths []string
+}
+
+// Subject returns the value a rule specifier is matched against: the command
+// for bash, the path for file tools, the single file an apply_patch call or
+// a request with Paths touches, and the empty string otherwise.
+func (r Request) Subject() string {
+	var args struct {
+		Command string `json:"command"`
+		Path    string `json:"path"`
+	}
+	if r.Input == "" || json.Unmarshal([]byte(r.Input), &args) != nil {
+		return ""
+	}
+	if strings.EqualFold(r.ToolName, "bash") {
+		return strings.TrimSpace(args.Command)
+	}
+	if paths, ok := r.filePaths(); ok {
+		if len(paths) == 1 {
+			return paths[0]
+		}
+		return ""
+	}
+	return args.Path
+}
+
+// filePaths returns Paths when set, or the files of an apply_patch call.
+// ok is false for other calls, which are matched on their Subject.
+func (r Request) filePaths() (paths []string, ok bool) {
+	if r.Paths != nil {
+		return r.Paths, true
+	}
+	if strings.EqualFold(r.ToolName, "apply_patch") {
+		return r.patchPaths(), true
+	}
+	return nil, false
+}
+
+// patchPaths returns every file an apply_patch call adds, deletes, modifies
+// or renames, or nil when the patch does not parse.
+func (r Request) patchPaths() []string {
+	var args struct {
+		Patch string `json:"patch"`
+	}
+	if json.Unmarshal([]byte(r.Input), &args) != nil {
+		return nil
+	}
+	files, err := core.ParsePatch(args.Patch)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Paths()...)
+	}
+	return paths
+}
+
+// Policy evaluates tool calls against an ordered rule set. Rules granted at
+// runtime (e.g. "always allow for this session") are added with Grant. A
+// Policy is safe for concurrent use.
+type Policy struct {
+	mu    sync.RWMutex
+	rules []Rule
+	def   Action
+}
+
+// New builds a policy from cfg. Invalid rules and defaults are reported as
+// errors so configuration mistakes surface at startup.
+func New(cfg Config) (*Policy, error) {
+	p := &Policy{def: Allow}
+	switch cfg.Default {
+	case "":
+	case Allow, Ask, Deny:
+		p.def = cfg.Default
+	default:
+		return nil, fmt.Errorf("invalid permissions default %q (want allow, ask or deny)", cfg.Default)
+	}
+	for _, group := range []struct {
+		specs  []string
+		action Action
+	}{{cfg.Deny, Deny}, {cfg.Ask, Ask}, {cfg.Allow, Allow}} {
+		for _, spec := range group.specs {
+			r, err := ParseRule(spec, group.action)
+			if err != nil {
+				return nil, err
+			}
+			p.rules = append(p.rules, r)
+		}
+	}
+	return p, nil
+}
+
+// Default returns the action applied to calls matching no rule.
+func (p *Policy) Default() Action {
+	return p.def
+}
+
+// Grant adds an allow rule to the policy. Configured deny and ask rules still
+// take precedence over granted rules.
+func (p *Policy) Grant(r Rule) {
+	r.Action = Allow
+	p.mu.Lock()
+	p.rules = append(p.rules, r)
+	p.mu.Unlock()
+}
+
+// Evaluate returns the action for req. For bash, compound commands are split
+// on control operators and every sub-command must be allowed for the whole
+// call to be allowed. Likewise every file an apply_patch call or a request
+// with Paths touches must be allowed.
+func (p *Policy) Evaluate(req Request) Action {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	tool := strings.ToLower(req.ToolName)
+	if paths, ok := req.filePaths(); ok {
+		if len(paths) == 0 {
+			return p.evaluate(tool, "", req.WorkDir)
+		}
+		result := Allow
+		for _, path := range paths {
+			if a := p.evaluate(tool, path, req.WorkDir); a.severity() > result.severity() {
+				result = a
+			}
+		}
+		return result
+	}
+	subject := req.Subject()
+	if tool != "bash" || subject == "" {
+		return p.evaluate(tool, subject, req.WorkDir)
+	}
+
+	// A deny rule written against the full command line (e.g. a pipe into
+	// a shell) wins even when no single segment matches it.
+	for _, r := range p.rules {
+		if r.Action == Deny && r.matches(tool, subject, req.WorkDir) {
+			return Deny
+		}
+	}
+	result := Allow
+	for _, seg := range splitCommand(subject) {
+		if a := p.evaluate(tool, seg, req.WorkDir); a.severity() > result.severity() {
+			result = a
+		}
+	}
+	return result
+}
+
+// evaluate resolves a single subject. Callers must hold p.mu.
+func (p *Policy) evaluate(tool, subject, workDir string) Action {
+	matched := false
+	result := Allow
+	for _, r := range p.rules {
+		if !r.matches(tool, subject, workDir) {
+			continue
+		}
+		matched = true
+		if r.Action.severity() > result.severity() {
+			result = r.Action
+		}
+	}
+	if !matched {
+		return p.def
+	}
+	return result
+}
+
+// matches reports whether r applies to a call of tool with subject.
+func (r Rule) matches(tool, subject, workDir string) bool {
+	if r.Tool == "mcp" {
+		server, toolGlob, ok := strings.Cut(r.Specifier, "__")
+		if !ok {
+			return strings.HasPrefix(tool, strings.ToLower(server)+"__")
+		}
+		ok, _ = path.Match(strings.ToLower(server+"__"+toolGlob), tool)
+		return ok
+	}
+	if ok, _ := path.Match(r.Tool, tool); !ok {
+		return false
+	}
+	if r.Specifier == "" {
+		return true
+	}
+	if subject == "" {
+		return false
+	}
+	if tool == "bash" {
+		return matchCommand(r.Specifier, subject)
+	}
+	return matchPath(r.Specifier, subject, workDir)
+}
+
+// matchCommand matches a bash specifier. "prefix:*" matches the prefix as a
+// whole word sequence; anything else is a glob where * spans any characters.
+func matchCommand(spec, command string) bool {
+	if prefix, ok := strings.CutSuffix(spec, ":*"); ok {
+		prefix = strings.TrimSpace(prefix)
+		if command == prefix {
+			return true
+		}
+		return strings.HasPrefix(command, prefix+" ") || strings.HasPrefix(command, prefix+"\t")
+	}
+	return globRegexp(spec, false).MatchString(command)
+}
+
+// matchPath matches a path specifier against target. Relative specifiers are
+// rooted at workDir; "~/" expands to the home directory.
+func matchPath(spec, target, workDir string) bool {
+	if rest, ok := strings.CutPrefix(spec, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			spec = filepath.Join(home, rest)
+		}
+	}
+	abs := target
+	if !filepath.IsAbs(abs) && workDir != "" {
+		abs = filepath.Join(workDir, abs)
+	}
+	abs = filepath.Clean(abs)
+
+	if filepath.IsAbs(spec) {
+		return globRegexp(filepath.ToSlash(filepath.Clean(spec)), true).MatchString(filepath.ToSlash(abs))
+	}
+	rel := filepath.Clean(target)
+	if workDir != "" {
+		r, err := filepath.Rel(workDir, abs)
+		if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			// Relative specifiers never match paths outside the project.
+			return false
+		}
+		rel = r
+	}
+	return globRegexp(strings.TrimPrefix(spec, "./"), true).MatchString(filepath.ToSlash(rel))
+}
+
+// globCache memoises compiled glob patterns.
+var globCache sync.Map
+
+// globRegexp compiles a glob into an anchored regexp. With paths set, *
+// and ? stop at "/" and ** spans directories; otherwise * matches anything.
+// A backslash makes the following character literal.
+func globRegexp(glob string, paths bool) *regexp.Regexp {
+	key := fmt.Sprintf("%t:%s", paths, glob)
+	if re, ok := globCache.Load(key); ok {
+		return re.(*regexp.Regexp)
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case c == '*' && paths && i+1 < len(glob) && glob[i+1] == '*':
+			i++
+			if i+1 < len(glob) && glob[i+1] == '/' {
+				// "**/" matches zero or more directories.
+				i++
+				b.WriteString("(?:.*/)?")
+			} else {
+				b.WriteString(".*")
+			}
+		case c == '*' && paths:
+			b.WriteString("[^/]*")
+		case c == '*':
+			b.WriteString(".*")
+		case c == '?' && paths:
+			b.WriteString("[^/]")
+		case c == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re := regexp.MustCompile(b.String())
+	globCache.Store(key, re)
+	return re
+}
+
+// splitCommand splits a shell command line on the control operators ;, &&,
+// ||, | and newlines, ignoring operators inside quotes. Empty segments are
+// dropped.
+func splitCommand(command string) []string {
+	var (
+		segs  []string
+		cur   strings.Builder
+		quote byte
+	)
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			segs = append(segs, s)
+		}
+		cur.Reset()
+	}
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' && i+1 < len(command) {
+				cur.WriteByte(c)
+				i++
+				c = command[i]
+			}
+			cur.WriteByte(c)
+		case c == '\'' || c == '"':
+			quote = c
+			cur.WriteByte(c)
+		case c == '\\' && i+1 < len(command):
+			cur.WriteByte(c)
+			i++
+			cur.WriteByte(command[i])
+		case c == ';' || c == '\n' || c == '|' || c == '&':
+			if (c == '|' || c == '&') && i+1 < len(command) && command[i+1] == c {
+				i++
+			}
+			flush()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return segs
+}
+
+// SuggestRules returns the narrowest rules that would allow req again: the
+// exact text of each sub-command for bash, since Evaluate checks every one on
+// its own, the project-relative path for file tools and for each file an
+// apply_patch call or a request with Paths touches, and the bare tool name
+// otherwise. A patch that
+// does not parse gets no rules, since a bare apply_patch rule would allow
+// patching any file.
+func SuggestRules(req Request) []Rule {
+	tool := strings.ToLower(req.ToolName)
+	if paths, ok := req.filePaths(); ok {
+		var rules []Rule
+		for _, path := range paths {
+			if r := suggestRule(tool, path, req.WorkDir); !slices.Contains(rules, r) {
+				rules = append(rules, r)
+			}
+		}
+		return rules
+	}
+	subject := req.Subject()
+	if tool != "bash" || subject == "" {
+		return []Rule{suggestRule(tool, subject, req.WorkDir)}
+	}
+	var rules []Rule
+	for _, seg := range splitCommand(subject) {
+		if r := suggestRule(tool, seg, req.WorkDir); !slices.Contains(rules, r) {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		rules = append(rules, suggestRule(tool, subject, req.WorkDir))
+	}
+	return rules
+}
+
+// suggestRule returns the rule allowing tool for exactly subject.
+func suggestRule(tool, subject, workDir string) Rule {
+	if subject == "" {
+		return Rule{Tool: tool, Action: Allow}
+	}
+	if tool != "bash" {
+		abs := subject
+		if !filepath.IsAbs(abs) && workDir != "" {
+			abs = filepath.Join(workDir, abs)
+		}
+		subject = filepath.ToSlash(filepath.Clean(abs))
+		if workDir != "" {
+			if rel, err := filepath.Rel(workDir, abs); err == nil && !strings.HasPrefix(rel, "..") {
+				subject = filepath.ToSlash(rel)
+			}
+		}
+	}
+	// Escape glob metacharacters so the rule matches only this subject.
+	subject = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`).Replace(subject)
+	return Rule{Tool: tool, Specifier: subject, Action: Allow}
+}
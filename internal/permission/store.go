@@ -0,0 +1,131 @@
+package permission
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/mark3labs/kit/internal/trust"
+)
+
+// storeFileName is the basename of the persisted per-project grants. It lives
+// alongside the trust allowlist.
+const storeFileName = "permissions.json"
+
+// Store persists "always allow for this project" decisions, keyed by project
+// directory. The zero value is not usable — construct one with Load.
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	projects map[string][]string
+}
+
+// store mirrors the on-disk JSON layout.
+type store struct {
+	Projects map[string][]string `json:"projects"`
+}
+
+// DefaultPath returns the path project grants are persisted to: the
+// directory holding the trust allowlist, respecting $XDG_CONFIG_HOME.
+// Returns the empty string when no home directory can be determined.
+func DefaultPath() string {
+	trustPath := trust.DefaultPath()
+	if trustPath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(trustPath), storeFileName)
+}
+
+// Load reads project grants from path. A missing file yields an empty store
+// (not an error). Pass an empty path to use DefaultPath.
+func Load(path string) (*Store, error) {
+	if path == "" {
+		path = DefaultPath()
+	}
+	s := &Store{path: path, projects: map[string][]string{}}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+
+	var raw store
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return s, err
+	}
+	for dir, rules := range raw.Projects {
+		s.projects[normalize(dir)] = rules
+	}
+	return s, nil
+}
+
+// Rules returns the rules granted for dir.
+func (s *Store) Rules(dir string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.projects[normalize(dir)])
+}
+
+// Allow records rule as granted for dir and persists the store to disk.
+func (s *Store) Allow(dir, rule string) error {
+	key := normalize(dir)
+	s.mu.Lock()
+	if !slices.Contains(s.projects[key], rule) {
+		s.projects[key] = append(s.projects[key], rule)
+	}
+	s.mu.Unlock()
+	return s.save()
+}
+
+// Revoke removes rule from dir's grants and persists the change.
+func (s *Store) Revoke(dir, rule string) error {
+	key := normalize(dir)
+	s.mu.Lock()
+	s.projects[key] = slices.DeleteFunc(s.projects[key], func(r string) bool { return r == rule })
+	if len(s.projects[key]) == 0 {
+		delete(s.projects, key)
+	}
+	s.mu.Unlock()
+	return s.save()
+}
+
+// save writes the store to disk, creating parent directories as needed.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	data, err := json.MarshalIndent(store{Projects: s.projects}, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o644)
+}
+
+// normalize resolves dir to an absolute, symlink-evaluated path for stable
+// comparison. It falls back to the cleaned input when resolution fails.
+func normalize(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return filepath.Clean(dir)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
@@ -0,0 +1,134 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checkpointDir returns the sidecar directory holding file snapshots for the
+// session persisted at sessionPath ("<session>.checkpoints" next to the JSONL
+// file). Blobs are named by the SHA-256 of their content, so unchanged files
+// captured by several checkpoints are stored once.
+func checkpointDir(sessionPath string) string {
+	return strings.TrimSuffix(sessionPath, ".jsonl") + ".checkpoints"
+}
+
+// PutBlob stores file content in the session's blob store and returns its
+// hash. Persisted sessions write to the sidecar directory; in-memory sessions
+// keep blobs in memory for the lifetime of the manager.
+func (tm *TreeManager) PutBlob(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if tm.filePath == "" {
+		if tm.blobs == nil {
+			tm.blobs = make(map[string][]byte)
+		}
+		if _, ok := tm.blobs[hash]; !ok {
+			tm.blobs[hash] = append([]byte(nil), data...)
+		}
+		return hash, nil
+	}
+
+	dir := checkpointDir(tm.filePath)
+	path := filepath.Join(dir, hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	// Write through a temp file so a crash never leaves a truncated blob
+	// under a valid hash.
+	tmp, err := os.CreateTemp(dir, hash+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create blob: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to store blob: %w", err)
+	}
+	return hash, nil
+}
+
+// ReadBlob returns the content stored under hash by PutBlob.
+func (tm *TreeManager) ReadBlob(hash string) ([]byte, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	if tm.filePath == "" {
+		data, ok := tm.blobs[hash]
+		if !ok {
+			return nil, fmt.Errorf("blob %q not found", hash)
+		}
+		return data, nil
+	}
+	if hash == "" || strings.ContainsAny(hash, `/\.`) {
+		return nil, fmt.Errorf("invalid blob hash %q", hash)
+	}
+	data, err := os.ReadFile(filepath.Join(checkpointDir(tm.filePath), hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return data, nil
+}
+
+// AppendCheckpoint records the prior state of the files touched while
+// answering targetID. The entry is persisted but does not move the leaf.
+func (tm *TreeManager) AppendCheckpoint(targetID string, files []FileSnapshot, gitRef string) (string, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	entry := NewCheckpointEntry(targetID, files, gitRef)
+	if err := tm.appendAndPersist(entry); err != nil {
+		return "", err
+	}
+	if err := tm.flushLocked(); err != nil {
+		return "", fmt.Errorf("failed to flush checkpoint: %w", err)
+	}
+	return entry.ID, nil
+}
+
+// CheckpointsAfter returns, in the order they were recorded, every checkpoint
+// appended after the entry with the given ID. Because each checkpoint holds
+// the state files were in before they were first modified, the earliest
+// snapshot of a path in the result is that file's content at the time
+// entryID was written — regardless of which branch later turns ran on.
+func (tm *TreeManager) CheckpointsAfter(entryID string) ([]*CheckpointEntry, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	if _, ok := tm.index[entryID]; !ok {
+		return nil, fmt.Errorf("entry %q not found", entryID)
+	}
+	var (
+		found  bool
+		result []*CheckpointEntry
+	)
+	for _, entry := range tm.entries {
+		if !found {
+			found = tm.EntryID(entry) == entryID
+			continue
+		}
+		if cp, ok := entry.(*CheckpointEntry); ok {
+			result = append(result, cp)
+		}
+	}
+	return result, nil
+}
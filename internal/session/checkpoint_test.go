@@ -0,0 +1,121 @@
+package session
+
+import (
+	"os"
+	"testing"
+)
+
+func TestBlobs_InMemory(t *testing.T) {
+	tm := InMemoryTreeSession(t.TempDir())
+
+	hash, err := tm.PutBlob([]byte("hello"))
+	if err != nil {
+		t.Fatalf("PutBlob: %v", err)
+	}
+	again, err := tm.PutBlob([]byte("hello"))
+	if err != nil {
+		t.Fatalf("PutBlob: %v", err)
+	}
+	if hash != again {
+		t.Errorf("identical content hashed differently: %q vs %q", hash, again)
+	}
+	data, err := tm.ReadBlob(hash)
+	if err != nil {
+		t.Fatalf("ReadBlob: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("ReadBlob = %q, want %q", data, "hello")
+	}
+	if _, err := tm.ReadBlob("missing"); err == nil {
+		t.Error("expected error for unknown blob")
+	}
+}
+
+func TestCheckpoints_PersistAcrossReopen(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tm, err := CreateTreeSession(t.TempDir())
+	if err != nil {
+		t.Fatalf("CreateTreeSession: %v", err)
+	}
+	first, err := tm.AppendMessage(newTestMessage("first"))
+	if err != nil {
+		t.Fatalf("AppendMessage: %v", err)
+	}
+	hash, err := tm.PutBlob([]byte("original"))
+	if err != nil {
+		t.Fatalf("PutBlob: %v", err)
+	}
+	files := []FileSnapshot{
+		{Path: "/tmp/a.txt", Existed: true, Blob: hash, Mode: 0644},
+		{Path: "/tmp/b.txt"},
+	}
+	if _, err := tm.AppendCheckpoint(first, files, ""); err != nil {
+		t.Fatalf("AppendCheckpoint: %v", err)
+	}
+	if got := tm.GetLeafID(); got != first {
+		t.Errorf("checkpoint moved the leaf: got %q, want %q", got, first)
+	}
+	path := tm.GetFilePath()
+	_ = tm.Close()
+
+	if _, err := os.Stat(checkpointDir(path)); err != nil {
+		t.Fatalf("checkpoint directory missing: %v", err)
+	}
+
+	reopened, err := OpenTreeSession(path)
+	if err != nil {
+		t.Fatalf("OpenTreeSession: %v", err)
+	}
+	defer func() { _ = reopened.Close() }()
+
+	// The checkpoint is the last line, but the leaf must stay on the message.
+	if got := reopened.GetLeafID(); got != first {
+		t.Errorf("leaf after reopen = %q, want %q", got, first)
+	}
+	checkpoints, err := reopened.CheckpointsAfter(first)
+	if err != nil {
+		t.Fatalf("CheckpointsAfter: %v", err)
+	}
+	if len(checkpoints) != 1 {
+		t.Fatalf("CheckpointsAfter returned %d checkpoints, want 1", len(checkpoints))
+	}
+	cp := checkpoints[0]
+	if cp.TargetID != first || len(cp.Files) != 2 {
+		t.Errorf("checkpoint = %+v, want target %q with 2 files", cp, first)
+	}
+	data, err := reopened.ReadBlob(cp.Files[0].Blob)
+	if err != nil {
+		t.Fatalf("ReadBlob: %v", err)
+	}
+	if string(data) != "original" {
+		t.Errorf("blob = %q, want %q", data, "original")
+	}
+}
+
+func TestCheckpointsAfter_OnlyLaterEntries(t *testing.T) {
+	tm := InMemoryTreeSession(t.TempDir())
+
+	first, _ := tm.AppendMessage(newTestMessage("first"))
+	if _, err := tm.AppendCheckpoint(first, []FileSnapshot{{Path: "/a"}}, ""); err != nil {
+		t.Fatalf("AppendCheckpoint: %v", err)
+	}
+	second, _ := tm.AppendMessage(newTestMessage("second"))
+	if _, err := tm.AppendCheckpoint(second, []FileSnapshot{{Path: "/b"}}, ""); err != nil {
+		t.Fatalf("AppendCheckpoint: %v", err)
+	}
+
+	got, err := tm.CheckpointsAfter(second)
+	if err != nil {
+		t.Fatalf("CheckpointsAfter: %v", err)
+	}
+	if len(got) != 1 || got[0].Files[0].Path != "/b" {
+		t.Errorf("CheckpointsAfter(second) = %+v, want only the /b checkpoint", got)
+	}
+	if got, _ := tm.CheckpointsAfter(first); len(got) != 2 {
+		t.Errorf("CheckpointsAfter(first) returned %d checkpoints, want 2", len(got))
+	}
+	if _, err := tm.CheckpointsAfter("missing"); err == nil {
+		t.Error("expected error for unknown entry")
+	}
+}
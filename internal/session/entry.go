@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -26,6 +27,7 @@ const (
 	EntryTypeExtensionData EntryType = "extension_data"
 	EntryTypeCompaction    EntryType = "compaction"
 	EntryTypeSystemPrompt  EntryType = "system_prompt"
+	EntryTypeCheckpoint    EntryType = "checkpoint"
 )
 
 // CurrentVersion is the session format version for JSONL tree sessions.
@@ -132,6 +134,29 @@ type SystemPromptEntry struct {
 	Provider  string    `json:"provider"`  // the provider used (e.g., "anthropic")
 }
 
+// CheckpointEntry records the state files were in before a turn modified
+// them, so the working tree can be rewound together with the conversation.
+// Like SystemPromptEntry it does NOT participate in the tree structure: it is
+// keyed by TargetID, the user message entry that started the turn. File
+// contents live in the session's blob store, addressed by hash.
+type CheckpointEntry struct {
+	Type      EntryType      `json:"type"`              // always "checkpoint"
+	ID        string         `json:"id"`                // unique entry ID
+	Timestamp time.Time      `json:"timestamp"`         // when recorded
+	TargetID  string         `json:"target_id"`         // user message entry the snapshot belongs to
+	Files     []FileSnapshot `json:"files,omitempty"`   // prior state of each touched file
+	GitRef    string         `json:"git_ref,omitempty"` // commit holding the tracked tree, if captured
+}
+
+// FileSnapshot is the prior state of a single file in a CheckpointEntry.
+// When Existed is false the file did not exist and restoring removes it.
+type FileSnapshot struct {
+	Path    string      `json:"path"`           // absolute path
+	Existed bool        `json:"existed"`        // false if the file was created afterwards
+	Blob    string      `json:"blob,omitempty"` // content hash in the blob store
+	Mode    os.FileMode `json:"mode,omitempty"` // permission bits
+}
+
 // GenerateEntryID creates a unique entry identifier (16 hex chars).
 func GenerateEntryID() string {
 	bytes := make([]byte, 8)
@@ -244,6 +269,18 @@ func NewSystemPromptEntry(content, model, provider string) *SystemPromptEntry {
 	}
 }
 
+// NewCheckpointEntry creates a CheckpointEntry.
+func NewCheckpointEntry(targetID string, files []FileSnapshot, gitRef string) *CheckpointEntry {
+	return &CheckpointEntry{
+		Type:      EntryTypeCheckpoint,
+		ID:        GenerateEntryID(),
+		Timestamp: time.Now(),
+		TargetID:  targetID,
+		Files:     files,
+		GitRef:    gitRef,
+	}
+}
+
 // --- JSONL marshaling helpers ---
 
 // MarshalEntry serializes any entry to a JSON line (no trailing newline).
@@ -329,6 +366,13 @@ func UnmarshalEntry(data []byte) (any, error) {
 		}
 		return &e, nil
 
+	case EntryTypeCheckpoint:
+		var e CheckpointEntry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checkpoint entry: %w", err)
+		}
+		return &e, nil
+
 	default:
 		return nil, fmt.Errorf("unknown entry type: %q", env.Type)
 	}
@@ -0,0 +1,677 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// indexVersion is bumped whenever what the index holds changes, so caches
+// written by older builds are rebuilt rather than misread.
+const indexVersion = 1
+
+// maxIndexedToolInput caps how much of a tool call's arguments is indexed.
+// Write and edit calls carry whole files; their head names the file.
+const maxIndexedToolInput = 4096
+
+// maxIndexedTermLen drops longer tokens: they are hashes and encoded blobs,
+// not words anyone searches for.
+const maxIndexedTermLen = 64
+
+// snippetContext is how many characters of context a search snippet keeps
+// on each side of the first matching term.
+const snippetContext = 40
+
+// SearchMatch is an entry of a session that matches a search query.
+type SearchMatch struct {
+	// EntryID is the tree entry to jump to: the message itself, or the
+	// entry a label is attached to. Empty when the session name matched.
+	EntryID string
+
+	// Kind is the message role ("user" or "assistant"), or "label", "name"
+	// or "files" for labels, session names and the file lists compaction
+	// records.
+	Kind string
+
+	// Snippet is the matching text around the first query term, on one line.
+	Snippet string
+
+	// Highlights are the [start, end) byte ranges of Snippet that match a
+	// query term.
+	Highlights [][2]int
+}
+
+// SearchResult is a session with at least one entry matching a query.
+type SearchResult struct {
+	Session SessionInfo
+	Matches []SearchMatch
+}
+
+// Index is an incremental full-text index over the JSONL sessions under a
+// sessions root. It covers user and assistant text, tool call names and
+// arguments (and with them the file paths tools touched), labels, session
+// names and the file lists compaction records.
+//
+// Each entry is tokenized once. A refresh only reads the bytes appended to a
+// session file since it was last indexed; files that shrank or were
+// rewritten are indexed again from scratch. Per-file term lists persist to a
+// JSON cache, and the inverted index is rebuilt from them in memory.
+//
+// A query matches an entry when every query term is a prefix of one of the
+// entry's words, ignoring case.
+type Index struct {
+	mu sync.Mutex
+
+	// root is the sessions root, e.g. ~/.kit/sessions.
+	root string
+
+	// cache is the JSON file the index persists to. Empty keeps the index
+	// in memory only.
+	cache string
+
+	// files maps each session file path to what was indexed from it.
+	files map[string]*indexedFile
+
+	// postings maps each term to the entries holding it, and vocab lists
+	// the terms sorted for prefix lookups. Both are derived from files on
+	// demand: postings is nil when it must be rebuilt, vocab is nil when
+	// terms were added since it was sorted.
+	postings map[string][]entryRef
+	vocab    []string
+
+	// dirty is set when files changed since the cache was written.
+	dirty bool
+}
+
+// indexedFile is what the index holds for one session file.
+type indexedFile struct {
+	Size      int64          `json:"size"` // bytes indexed: every complete line
+	ModTime   time.Time      `json:"mod_time"`
+	HeaderLen int64          `json:"header_len"` // header line length, newline included
+	Info      SessionInfo    `json:"info"`
+	Entries   []indexedEntry `json:"entries"`
+}
+
+// indexedEntry is one searchable entry. Its text is not stored: snippets
+// are cut from the line at Offset when a search matches it.
+type indexedEntry struct {
+	Offset int64    `json:"offset"`
+	Terms  []string `json:"terms"`
+}
+
+// entryRef identifies an indexed entry by file path and position.
+type entryRef struct {
+	path  string
+	entry int
+}
+
+// indexCache is the on-disk form of an Index.
+type indexCache struct {
+	Version int                     `json:"version"`
+	Files   map[string]*indexedFile `json:"files"`
+}
+
+var (
+	defaultIndexMu sync.Mutex
+	defaultIndex   *Index
+)
+
+// OpenIndex returns the index of the sessions under root, loaded from cache
+// when it holds one. A missing, corrupt or outdated cache starts an empty
+// index. Nothing under root is read until the first Refresh or Search.
+func OpenIndex(root, cache string) *Index {
+	idx := &Index{root: root, cache: cache, files: make(map[string]*indexedFile)}
+	if cache == "" {
+		return idx
+	}
+	data, err := os.ReadFile(cache)
+	if err != nil {
+		return idx
+	}
+	var c indexCache
+	if err := json.Unmarshal(data, &c); err != nil || c.Version != indexVersion || c.Files == nil {
+		return idx
+	}
+	idx.files = c.Files
+	return idx
+}
+
+// DefaultIndex returns the process-wide index of ~/.kit/sessions, cached in
+// ~/.kit/session-index.json. Sessions are indexed at query time, so writing
+// one never waits on the index.
+func DefaultIndex() (*Index, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find home directory: %w", err)
+	}
+	root := filepath.Join(home, ".kit", "sessions")
+
+	defaultIndexMu.Lock()
+	defer defaultIndexMu.Unlock()
+	if defaultIndex == nil || defaultIndex.root != root {
+		defaultIndex = OpenIndex(root, filepath.Join(home, ".kit", "session-index.json"))
+	}
+	return defaultIndex, nil
+}
+
+// SearchSessions searches every stored session with the default index.
+func SearchSessions(query string) ([]SearchResult, error) {
+	idx, err := DefaultIndex()
+	if err != nil {
+		return nil, err
+	}
+	return idx.Search(query)
+}
+
+// Refresh brings the index up to date with the session files on disk and
+// writes the cache if anything changed.
+func (idx *Index) Refresh() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if err := idx.refreshLocked(); err != nil {
+		return err
+	}
+	return idx.saveLocked()
+}
+
+// Search refreshes the index and returns the sessions with entries matching
+// every term of query, most recently active first. Empty sessions are left
+// out, as listing leaves them out.
+func (idx *Index) Search(query string) ([]SearchResult, error) {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if err := idx.refreshLocked(); err != nil {
+		return nil, err
+	}
+	// A cache that cannot be written only costs the next run a rescan.
+	_ = idx.saveLocked()
+
+	var results []SearchResult
+	for path, entries := range idx.matchLocked(terms) {
+		rec := idx.files[path]
+		if rec.Info.MessageCount == 0 {
+			continue
+		}
+		if matches := rec.matches(path, entries, terms); len(matches) > 0 {
+			results = append(results, SearchResult{Session: rec.Info, Matches: matches})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Session.Modified.After(results[j].Session.Modified)
+	})
+	return results, nil
+}
+
+// refreshLocked indexes new and grown session files and forgets deleted
+// ones. The layout matches ListAllSessions: one directory per cwd.
+func (idx *Index) refreshLocked() error {
+	dirs, err := os.ReadDir(idx.root)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read sessions directory: %w", err)
+	}
+
+	seen := make(map[string]bool, len(idx.files))
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		dirPath := filepath.Join(idx.root, dir.Name())
+		entries, err := os.ReadDir(dirPath)
+		if err != nil {
+			continue // skip unreadable directories
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
+				continue
+			}
+			fi, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			path := filepath.Join(dirPath, entry.Name())
+			seen[path] = true
+			idx.updateLocked(path, fi)
+		}
+	}
+
+	for path := range idx.files {
+		if !seen[path] {
+			idx.dropLocked(path)
+		}
+	}
+	return nil
+}
+
+// updateLocked indexes whatever path gained since it was last indexed, or
+// the whole file when what was indexed no longer matches it.
+func (idx *Index) updateLocked(path string, fi os.FileInfo) {
+	rec := idx.files[path]
+	if rec != nil && rec.Size == fi.Size() && rec.ModTime.Equal(fi.ModTime()) {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		idx.dropLocked(path)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	if rec == nil || !rec.continuesIn(f, fi.Size()) {
+		idx.dropLocked(path)
+		rec = &indexedFile{Info: SessionInfo{Path: path}}
+	}
+	first := len(rec.Entries)
+	// A read error leaves the lines before it indexed; the rest are picked
+	// up by a later refresh since Size stops short of them.
+	_ = rec.scan(f)
+	rec.ModTime = fi.ModTime()
+	if rec.Info.Modified.IsZero() {
+		rec.Info.Modified = fi.ModTime()
+	}
+
+	idx.files[path] = rec
+	idx.dirty = true
+	if idx.postings != nil {
+		idx.addPostingsLocked(path, rec, first)
+	}
+}
+
+// dropLocked forgets path. The inverted index is rebuilt on the next search.
+func (idx *Index) dropLocked(path string) {
+	if _, ok := idx.files[path]; !ok {
+		return
+	}
+	delete(idx.files, path)
+	idx.postings, idx.vocab = nil, nil
+	idx.dirty = true
+}
+
+// addPostingsLocked records the entries of rec from first on in postings.
+func (idx *Index) addPostingsLocked(path string, rec *indexedFile, first int) {
+	for i := first; i < len(rec.Entries); i++ {
+		for _, term := range rec.Entries[i].Terms {
+			if _, ok := idx.postings[term]; !ok {
+				idx.vocab = nil
+			}
+			idx.postings[term] = append(idx.postings[term], entryRef{path: path, entry: i})
+		}
+	}
+}
+
+// matchLocked returns the entries matching every term, by file path, in
+// file order.
+func (idx *Index) matchLocked(terms []string) map[string][]int {
+	if idx.postings == nil {
+		idx.postings = make(map[string][]entryRef)
+		for path, rec := range idx.files {
+			idx.addPostingsLocked(path, rec, 0)
+		}
+		idx.vocab = nil
+	}
+	if idx.vocab == nil {
+		idx.vocab = make([]string, 0, len(idx.postings))
+		for term := range idx.postings {
+			idx.vocab = append(idx.vocab, term)
+		}
+		sort.Strings(idx.vocab)
+	}
+
+	var hits map[entryRef]bool
+	for _, q := range terms {
+		found := make(map[entryRef]bool)
+		for i := sort.SearchStrings(idx.vocab, q); i < len(idx.vocab) && strings.HasPrefix(idx.vocab[i], q); i++ {
+			for _, ref := range idx.postings[idx.vocab[i]] {
+				if hits == nil || hits[ref] {
+					found[ref] = true
+				}
+			}
+		}
+		if len(found) == 0 {
+			return nil
+		}
+		hits = found
+	}
+
+	byFile := make(map[string][]int)
+	for ref := range hits {
+		byFile[ref.path] = append(byFile[ref.path], ref.entry)
+	}
+	for _, entries := range byFile {
+		sort.Ints(entries)
+	}
+	return byFile
+}
+
+// saveLocked writes the cache if the index changed since it was last
+// written. The file is replaced atomically so concurrent processes never
+// read a partial cache.
+func (idx *Index) saveLocked() error {
+	if !idx.dirty || idx.cache == "" {
+		return nil
+	}
+	data, err := json.Marshal(indexCache{Version: indexVersion, Files: idx.files})
+	if err != nil {
+		return fmt.Errorf("failed to marshal session index: %w", err)
+	}
+	dir := filepath.Dir(idx.cache)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create session index directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".session-index-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write session index: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), idx.cache)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write session index: %w", err)
+	}
+	idx.dirty = false
+	return nil
+}
+
+// continuesIn reports whether f still starts with the lines indexed in rec,
+// so indexing can resume at rec.Size. Sessions are append-only; the one
+// rewrite they see (SetParentLink) changes the header's length, and a
+// replaced or truncated file is caught by its size or line boundaries.
+func (rec *indexedFile) continuesIn(f io.ReaderAt, size int64) bool {
+	if rec.Size == 0 || size < rec.Size {
+		return false
+	}
+	return endsLine(f, rec.HeaderLen) && endsLine(f, rec.Size)
+}
+
+// endsLine reports whether the byte before off is a newline.
+func endsLine(f io.ReaderAt, off int64) bool {
+	if off <= 0 {
+		return false
+	}
+	b := make([]byte, 1)
+	_, err := f.ReadAt(b, off-1)
+	return err == nil && b[0] == '\n'
+}
+
+// scan indexes the complete lines of f from rec.Size on. A final line
+// without its newline is still being written and is left for a later scan.
+func (rec *indexedFile) scan(f io.ReaderAt) error {
+	r := bufio.NewReader(io.NewSectionReader(f, rec.Size, math.MaxInt64-rec.Size))
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		offset := rec.Size
+		rec.Size += int64(len(line))
+		if offset == 0 {
+			rec.HeaderLen = rec.Size
+			rec.readHeader(line)
+			continue
+		}
+		rec.add(offset, line)
+	}
+}
+
+// readHeader fills in the session metadata the header line carries.
+func (rec *indexedFile) readHeader(line []byte) {
+	var h SessionHeader
+	if err := json.Unmarshal(line, &h); err != nil || h.Type != EntryTypeSession {
+		return
+	}
+	rec.Info.ID = h.ID
+	rec.Info.Cwd = h.Cwd
+	rec.Info.Created = h.Timestamp
+	rec.Info.Modified = h.Timestamp
+	rec.Info.ParentSessionPath = h.ParentSession
+	rec.Info.ParentSessionID = h.ParentSessionID
+	rec.Info.SubagentTask = h.SubagentTask
+}
+
+// add updates the session metadata from the entry line at offset, as
+// extractSessionInfo does, and indexes the entry's text. Files without a
+// valid header are not sessions and are tracked but not indexed.
+func (rec *indexedFile) add(offset int64, line []byte) {
+	if rec.Info.ID == "" {
+		return
+	}
+	entry, err := UnmarshalEntry(line)
+	if err != nil {
+		return
+	}
+
+	if ts := entryTime(entry); ts.After(rec.Info.Modified) {
+		rec.Info.Modified = ts
+	}
+	switch e := entry.(type) {
+	case *MessageEntry:
+		rec.Info.MessageCount++
+		if e.Role == "user" && rec.Info.FirstMessage == "" {
+			rec.Info.FirstMessage = extractTextPreview(e.Parts)
+		}
+	case *SessionInfoEntry:
+		if e.Name != "" {
+			rec.Info.Name = e.Name
+		}
+	}
+
+	_, _, text := searchText(entry)
+	if terms := indexTerms(text); len(terms) > 0 {
+		rec.Entries = append(rec.Entries, indexedEntry{Offset: offset, Terms: terms})
+	}
+}
+
+// matches reads back the given entries of the file at path and cuts a
+// snippet around the query terms from each.
+func (rec *indexedFile) matches(path string, entries []int, terms []string) []SearchMatch {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	var matches []SearchMatch
+	for _, i := range entries {
+		offset := rec.Entries[i].Offset
+		line, err := bufio.NewReader(io.NewSectionReader(f, offset, math.MaxInt64-offset)).ReadBytes('\n')
+		if err != nil {
+			continue
+		}
+		entry, err := UnmarshalEntry(line)
+		if err != nil {
+			continue
+		}
+		id, kind, text := searchText(entry)
+		snippet, highlights := searchSnippet(text, terms)
+		matches = append(matches, SearchMatch{EntryID: id, Kind: kind, Snippet: snippet, Highlights: highlights})
+	}
+	return matches
+}
+
+// searchText returns what the index holds for an entry: the entry a match
+// jumps to, its kind (see SearchMatch) and its text. Entries with nothing
+// worth searching, such as tool results, return empty text.
+func searchText(entry any) (id, kind, text string) {
+	switch e := entry.(type) {
+	case *MessageEntry:
+		if e.Role == "user" || e.Role == "assistant" {
+			return e.ID, e.Role, messageSearchText(e.Parts)
+		}
+	case *LabelEntry:
+		return e.TargetID, "label", e.Label
+	case *SessionInfoEntry:
+		return "", "name", e.Name
+	case *CompactionEntry:
+		files := append(append([]string(nil), e.ReadFiles...), e.ModifiedFiles...)
+		return e.ID, "files", strings.Join(files, " ")
+	}
+	return "", "", ""
+}
+
+// messageSearchText joins a message's text parts with its tool calls, each
+// as the tool name followed by its (truncated) JSON arguments.
+func messageSearchText(partsJSON json.RawMessage) string {
+	var parts []struct {
+		Type string `json:"type"`
+		Data struct {
+			Text  string `json:"text"`
+			Name  string `json:"name"`
+			Input string `json:"input"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(partsJSON, &parts); err != nil {
+		return ""
+	}
+	var texts []string
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			if p.Data.Text != "" {
+				texts = append(texts, p.Data.Text)
+			}
+		case "tool_call":
+			input := p.Data.Input
+			if len(input) > maxIndexedToolInput {
+				input = strings.ToValidUTF8(input[:maxIndexedToolInput], "")
+			}
+			texts = append(texts, p.Data.Name+" "+input)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// entryTime returns an entry's timestamp.
+func entryTime(entry any) time.Time {
+	switch e := entry.(type) {
+	case *MessageEntry:
+		return e.Timestamp
+	case *ModelChangeEntry:
+		return e.Timestamp
+	case *BranchSummaryEntry:
+		return e.Timestamp
+	case *LabelEntry:
+		return e.Timestamp
+	case *SessionInfoEntry:
+		return e.Timestamp
+	case *ExtensionDataEntry:
+		return e.Timestamp
+	case *CompactionEntry:
+		return e.Timestamp
+	case *SystemPromptEntry:
+		return e.Timestamp
+	case *CheckpointEntry:
+		return e.Timestamp
+	}
+	return time.Time{}
+}
+
+// isWordRune reports whether r belongs to an indexed word. Everything else,
+// including path separators and punctuation, splits words.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// queryTerms splits a query into distinct lower-cased words.
+func queryTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, t := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool { return !isWordRune(r) }) {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// indexTerms returns the distinct words of text worth indexing.
+func indexTerms(text string) []string {
+	terms := queryTerms(text)
+	kept := terms[:0]
+	for _, t := range terms {
+		if utf8.RuneCountInString(t) <= maxIndexedTermLen {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// searchSnippet flattens text onto one line and cuts it down to the first
+// word matching a query term, with snippetContext characters either side.
+// It returns the snippet and the byte ranges of every matching word prefix
+// within it.
+func searchSnippet(text string, terms []string) (string, [][2]int) {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+
+	// Find the rune ranges of word prefixes matching a term, preferring the
+	// longest term that matches.
+	var marks [][2]int
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		word, best := strings.ToLower(string(runes[i:j])), ""
+		for _, q := range terms {
+			if strings.HasPrefix(word, q) && len(q) > len(best) {
+				best = q
+			}
+		}
+		if best != "" {
+			marks = append(marks, [2]int{i, min(i+utf8.RuneCountInString(best), j)})
+		}
+		i = j
+	}
+
+	from, to := 0, min(2*snippetContext, len(runes))
+	if len(marks) > 0 {
+		from, to = max(marks[0][0]-snippetContext, 0), min(marks[0][1]+snippetContext, len(runes))
+	}
+	prefix := ""
+	if from > 0 {
+		prefix = "…"
+	}
+	snippet := prefix + string(runes[from:to])
+	if to < len(runes) {
+		snippet += "…"
+	}
+
+	var highlights [][2]int
+	for _, m := range marks {
+		if m[0] >= to {
+			break
+		}
+		if m[0] < from {
+			continue
+		}
+		start := len(prefix) + len(string(runes[from:m[0]]))
+		highlights = append(highlights, [2]int{start, start + len(string(runes[m[0]:m[1]]))})
+	}
+	return snippet, highlights
+}
@@ -0,0 +1,143 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+// newIndexedSession creates a session for cwd under a temporary HOME with a
+// prompt, a tool call reading path, and a reply.
+func newIndexedSession(t *testing.T, cwd, prompt, path string) *TreeManager {
+	t.Helper()
+	tm, err := CreateTreeSession(cwd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = tm.Close() })
+	_, _ = tm.AppendLLMMessage(fantasy.NewUserMessage(prompt))
+	_, _ = tm.AppendLLMMessage(fantasy.Message{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{
+		fantasy.ToolCallPart{ToolCallID: "call_1", ToolName: "read", Input: `{"path":"` + path + `"}`},
+	}})
+	_, _ = tm.AppendLLMMessage(fantasy.Message{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{fantasy.TextPart{Text: "done"}}})
+	return tm
+}
+
+func TestIndex_Search(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	limiter := newIndexedSession(t, "/work/api", "Add a token-bucket RATE limiter to the API client", "internal/ratelimit/bucket.go")
+	newIndexedSession(t, "/work/ui", "fix the session picker", "internal/ui/session_selector.go")
+	promptID := limiter.GetBranch("")[0].(*MessageEntry).ID
+	_, _ = limiter.AppendLabel(promptID, "checkpoint before refactor")
+	_, _ = limiter.AppendSessionInfo("Limiter work")
+
+	idx := OpenIndex(filepath.Join(home, ".kit", "sessions"), filepath.Join(home, ".kit", "session-index.json"))
+
+	results, err := idx.Search("rate lim")
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Search(rate lim) = %+v, %v", results, err)
+	}
+	if s := results[0].Session; s.ID != limiter.GetSessionID() || s.Name != "Limiter work" || s.MessageCount != 3 {
+		t.Errorf("session = %+v", s)
+	}
+	m := results[0].Matches
+	if len(m) != 1 || m[0].Kind != "user" || m[0].EntryID != promptID {
+		t.Fatalf("matches = %+v", m)
+	}
+	var marked []string
+	for _, h := range m[0].Highlights {
+		marked = append(marked, m[0].Snippet[h[0]:h[1]])
+	}
+	if strings.Join(marked, ",") != "RATE,lim" {
+		t.Errorf("highlighted %q in %q", marked, m[0].Snippet)
+	}
+
+	for query, want := range map[string]SearchMatch{
+		"session_selector.go": {Kind: "assistant"},
+		"refactor":            {Kind: "label", EntryID: promptID},
+		"limiter work":        {Kind: "name"},
+	} {
+		results, err := idx.Search(query)
+		if err != nil || len(results) != 1 || len(results[0].Matches) != 1 {
+			t.Errorf("Search(%q) = %+v, %v", query, results, err)
+			continue
+		}
+		got := results[0].Matches[0]
+		if got.Kind != want.Kind || (want.EntryID != "" && got.EntryID != want.EntryID) {
+			t.Errorf("Search(%q) match = %+v, want %+v", query, got, want)
+		}
+	}
+
+	if results, _ := idx.Search("internal"); len(results) != 2 {
+		t.Errorf("Search(internal) found %d sessions, want 2", len(results))
+	}
+	if results, _ := idx.Search("parser"); len(results) != 0 {
+		t.Errorf("Search(parser) = %+v, want none", results)
+	}
+}
+
+func TestIndex_Incremental(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	idx, err := DefaultIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := newIndexedSession(t, "/work", "fix the parser", "parser.go")
+	path := tm.GetFilePath()
+
+	// Writing a session leaves the open default index alone; it catches up
+	// at its next query.
+	if rec := idx.files[path]; rec != nil {
+		t.Fatalf("index updated while writing: %d entries", len(rec.Entries))
+	}
+	if err := idx.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(idx.files[path].Entries); got != 3 {
+		t.Fatalf("refreshed index holds %d entries, want 3", got)
+	}
+
+	// A fresh index reads the cache and only scans what was appended since.
+	_, _ = tm.AppendLLMMessage(fantasy.NewUserMessage("now the lexer"))
+	reopened := OpenIndex(idx.root, idx.cache)
+	if got := len(reopened.files[path].Entries); got != 3 {
+		t.Fatalf("cached index holds %d entries, want 3", got)
+	}
+	if results, err := reopened.Search("lexer"); err != nil || len(results) != 1 {
+		t.Fatalf("Search(lexer) = %+v, %v", results, err)
+	}
+
+	// A rewritten file is indexed again from scratch, a deleted one dropped.
+	data, _ := os.ReadFile(path)
+	header, _, _ := strings.Cut(string(data), "\n")
+	header = strings.Replace(header, `"cwd"`, `"subagent_task":"x","cwd"`, 1)
+	if err := os.WriteFile(path, []byte(header+"\n"+strings.SplitN(string(data), "\n", 2)[1]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := reopened.Search("lexer"); len(results) != 1 || results[0].Session.SubagentTask != "x" {
+		t.Errorf("after rewrite: %+v", results)
+	}
+	if got := len(reopened.files[path].Entries); got != 4 {
+		t.Errorf("rewritten file holds %d entries, want 4", got)
+	}
+	_ = os.Remove(path)
+	if results, _ := reopened.Search("lexer"); len(results) != 0 {
+		t.Errorf("deleted session still found: %+v", results)
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	text := strings.Repeat("x ", 50) + "Needle\n\tin" + strings.Repeat(" y", 50)
+	snippet, highlights := searchSnippet(text, []string{"needle"})
+	if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") || !strings.Contains(snippet, "Needle in") {
+		t.Errorf("snippet = %q", snippet)
+	}
+	if len(highlights) != 1 || snippet[highlights[0][0]:highlights[0][1]] != "Needle" {
+		t.Errorf("highlights = %v in %q", highlights, snippet)
+	}
+}
@@ -292,9 +292,56 @@ func extractTextPreview(partsJSON json.RawMessage) string {
 	return ""
 }
 
-// DeleteSession removes a session file from disk.
+// ReadSessionInfo extracts the metadata of a single session file, as
+// ListSessions reports it. Unlike listing, it leaves empty sessions in place.
+func ReadSessionInfo(path string) (SessionInfo, error) {
+	info, err := extractSessionInfo(path)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	return *info, nil
+}
+
+// ImportSession copies the session file at src into the session directory
+// for cwd, so it is listed and resumable there, and returns the new path.
+// The file keeps its header, and with it the session ID and original working
+// directory; importing a session whose ID is already stored is an error.
+// Checkpoint snapshots are not carried over.
+func ImportSession(src, cwd string) (string, error) {
+	header, err := readSessionHeader(src)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a session file: %w", src, err)
+	}
+	if path, err := FindSessionPathByID(cwd, header.ID); err == nil {
+		return "", fmt.Errorf("session %s is already stored at %s", header.ID, path)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return "", err
+	}
+
+	dir := DefaultSessionDir(cwd)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create session directory: %w", err)
+	}
+	id := header.ID
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	dst := filepath.Join(dir, fmt.Sprintf("%s_%s.jsonl", header.Timestamp.UTC().Format("2006-01-02T15-04-05-000Z"), id))
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write session file: %w", err)
+	}
+	return dst, nil
+}
+
+// DeleteSession removes a session file from disk, along with any checkpoint
+// snapshots recorded for it.
 func DeleteSession(path string) error {
-	return os.Remove(path)
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	return os.RemoveAll(checkpointDir(path))
 }
 
 // FindSessionPathByID locates the JSONL session file whose header ID matches
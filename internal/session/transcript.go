@@ -0,0 +1,90 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+// maxTranscriptToolChars caps each tool call and result in a transcript.
+const maxTranscriptToolChars = 2000
+
+// Title names a session by its display name, else its first message, else
+// its ID. Long titles are cut to 60 characters.
+func (info SessionInfo) Title() string {
+	title := info.Name
+	if title == "" {
+		title = strings.Join(strings.Fields(info.FirstMessage), " ")
+	}
+	if title == "" {
+		return info.ID
+	}
+	if r := []rune(title); len(r) > 60 {
+		title = string(r[:60]) + "…"
+	}
+	return title
+}
+
+// Transcript renders messages as Markdown under a "# title" heading. Tool
+// calls and results are truncated; reasoning is omitted.
+func Transcript(title string, messages []fantasy.Message) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n", title)
+	for _, msg := range messages {
+		var body []string
+		for _, part := range msg.Content {
+			switch p := part.(type) {
+			case fantasy.TextPart:
+				if text := strings.TrimSpace(p.Text); text != "" {
+					body = append(body, text)
+				}
+			case fantasy.ToolCallPart:
+				body = append(body, fmt.Sprintf("Tool call `%s`:\n\n```json\n%s\n```", p.ToolName, truncateTranscript(p.Input)))
+			case fantasy.ToolResultPart:
+				body = append(body, fmt.Sprintf("```\n%s\n```", truncateTranscript(toolResultText(p.Output))))
+			}
+		}
+		if len(body) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n## %s\n\n%s\n", roleTitle(msg.Role), strings.Join(body, "\n\n"))
+	}
+	return sb.String()
+}
+
+func roleTitle(role fantasy.MessageRole) string {
+	switch role {
+	case fantasy.MessageRoleUser:
+		return "User"
+	case fantasy.MessageRoleAssistant:
+		return "Assistant"
+	case fantasy.MessageRoleTool:
+		return "Tool result"
+	case fantasy.MessageRoleSystem:
+		return "System"
+	default:
+		return string(role)
+	}
+}
+
+func toolResultText(output fantasy.ToolResultOutputContent) string {
+	switch o := output.(type) {
+	case fantasy.ToolResultOutputContentText:
+		return o.Text
+	case fantasy.ToolResultOutputContentError:
+		if o.Error != nil {
+			return "Error: " + o.Error.Error()
+		}
+	case fantasy.ToolResultOutputContentMedia:
+		return fmt.Sprintf("[%s]", o.MediaType)
+	}
+	return ""
+}
+
+func truncateTranscript(text string) string {
+	if len(text) <= maxTranscriptToolChars {
+		return text
+	}
+	return text[:maxTranscriptToolChars] + fmt.Sprintf("\n[...%d chars truncated]", len(text)-maxTranscriptToolChars)
+}
@@ -68,6 +68,10 @@ type TreeManager struct {
 	// buffer and are flushed to disk at explicit sync points (after each
 	// public Append* call, in Close, etc.) to reduce syscall overhead.
 	writer *bufio.Writer
+
+	// blobs holds checkpoint file contents for in-memory sessions. Persisted
+	// sessions store them in the sidecar directory instead (see PutBlob).
+	blobs map[string][]byte
 }
 
 // --- Constructors ---
@@ -476,9 +480,13 @@ func OpenTreeSession(path string) (*TreeManager, error) {
 		tm.addEntryToIndex(entry)
 	}
 
-	// Set leaf to the last entry.
-	if len(tm.entries) > 0 {
-		tm.leafID = tm.EntryID(tm.entries[len(tm.entries)-1])
+	// Set leaf to the last tree entry. Checkpoints are recorded after a
+	// turn's messages but sit outside the tree, so they are skipped.
+	for i := len(tm.entries) - 1; i >= 0; i-- {
+		if id := tm.EntryID(tm.entries[i]); id != "" {
+			tm.leafID = id
+			break
+		}
 	}
 
 	// Validate tree integrity and log diagnostics
@@ -100,6 +100,8 @@ func (tm *TreeManager) LogTreeDiagnostics() {
 			et = e.Type
 		case *CompactionEntry:
 			et = e.Type
+		case *CheckpointEntry:
+			et = e.Type
 		default:
 			et = "unknown"
 		}
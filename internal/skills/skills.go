@@ -61,9 +61,9 @@ type Skill struct {
 	// Metadata is an optional bag of arbitrary string key/value pairs (spec
 	// field) for client-specific annotations.
 	Metadata map[string]string `yaml:"metadata,omitempty" json:"metadata,omitempty"`
-	// AllowedTools optionally restricts which tools the skill may use. This is
-	// an experimental spec field carried for portability; Kit does not yet
-	// enforce it.
+	// AllowedTools optionally restricts which tools the agent may use once
+	// the skill is activated, as a space-separated list of permission rules
+	// such as "Bash(git:*) Read". See AllowedToolRules.
 	AllowedTools string `yaml:"allowed-tools,omitempty" json:"allowed_tools,omitempty"`
 	// DisableModelInvocation, when true, hides the skill from the
 	// model-facing catalog (spec field). The skill can still be activated
@@ -122,6 +122,56 @@ func hasError(diags []Diagnostic) bool {
 	return false
 }
 
+// toolAliases maps tool names found in other agents' allowed-tools lists to
+// the Kit tools that do the same job.
+var toolAliases = map[string]string{
+	"glob":      "find",
+	"multiedit": "edit",
+}
+
+// AllowedToolRules splits AllowedTools into permission rule strings. Entries
+// are separated by spaces or commas outside parentheses, so
+// "Bash(git log:*) Read, Grep" yields three rules, and tool names are
+// lower-cased with aliases such as Glob mapped to Kit's find. It returns nil
+// when the skill declares no restriction.
+func (s *Skill) AllowedToolRules() []string {
+	var (
+		rules []string
+		cur   strings.Builder
+		depth int
+	)
+	flush := func() {
+		rule := strings.TrimSpace(cur.String())
+		cur.Reset()
+		if rule == "" {
+			return
+		}
+		name, spec, hasSpec := strings.Cut(rule, "(")
+		name = strings.ToLower(name)
+		if alias, ok := toolAliases[name]; ok {
+			name = alias
+		}
+		if hasSpec {
+			name += "(" + spec
+		}
+		rules = append(rules, name)
+	}
+	for _, r := range s.AllowedTools {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case depth == 0 && (r == ',' || r == ' ' || r == '\t' || r == '\n'):
+			flush()
+			continue
+		}
+		cur.WriteRune(r)
+	}
+	flush()
+	return rules
+}
+
 // BaseDir returns the directory the skill was loaded from. Relative resources
 // referenced by a skill (scripts/, references/, assets/) resolve against this
 // directory.
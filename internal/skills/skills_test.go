@@ -534,3 +534,21 @@ func TestSkillResources(t *testing.T) {
 		t.Errorf("FormatResources output missing script: %q", formatted)
 	}
 }
+
+func TestSkill_AllowedToolRules(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"Bash(git:*) Read", []string{"bash(git:*)", "read"}},
+		{"Bash(git log:*), Glob,MultiEdit", []string{"bash(git log:*)", "find", "edit"}},
+		{"read\n  grep", []string{"read", "grep"}},
+	}
+	for _, tt := range tests {
+		got := (&Skill{AllowedTools: tt.in}).AllowedToolRules()
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("AllowedToolRules(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -70,6 +70,7 @@ type MCPConnectionPool struct {
 	debugLogger       DebugLogger
 	oauthFlow         *OAuthFlowRunner
 	tokenStoreFactory TokenStoreFactory // custom factory for per-server token stores (nil = default FileTokenStore)
+	clientRequests    *clientRequests   // answers sampling, elicitation and roots requests (nil = none advertised)
 }
 
 // NewMCPConnectionPool creates a new MCP connection pool with the specified configuration.
@@ -117,6 +118,14 @@ func (p *MCPConnectionPool) SetOAuthFlow(flow *OAuthFlowRunner) {
 	p.oauthFlow = flow
 }
 
+// setClientRequests sets the handlers new connections register for
+// server-initiated requests. Existing connections are unaffected.
+func (p *MCPConnectionPool) setClientRequests(r *clientRequests) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clientRequests = r
+}
+
 // GetConnection retrieves or creates a connection for the specified MCP server.
 // If a healthy, non-idle connection exists in the pool, it will be reused.
 // Otherwise, a new connection is created and added to the pool.
@@ -298,23 +307,24 @@ func (p *MCPConnectionPool) createConnection(ctx context.Context, serverName str
 // createMCPClient creates an MCP client
 func (p *MCPConnectionPool) createMCPClient(ctx context.Context, serverName string, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
 	transportType := serverConfig.GetTransportType()
+	requests := p.clientRequests.forServer(serverName, serverConfig)
 
 	switch transportType {
 	case "stdio":
-		return p.createStdioClient(ctx, serverConfig)
+		return p.createStdioClient(ctx, serverConfig, requests)
 	case "sse":
-		return p.createSSEClient(ctx, serverConfig)
+		return p.createSSEClient(ctx, serverConfig, requests)
 	case "streamable":
-		return p.createStreamableClient(ctx, serverConfig)
+		return p.createStreamableClient(ctx, serverConfig, requests)
 	case "inprocess":
-		return p.createInProcessClient(serverConfig)
+		return p.createInProcessClient(ctx, serverConfig, requests)
 	default:
 		return nil, fmt.Errorf("unsupported transport type '%s' for server %s", transportType, serverName)
 	}
 }
 
 // createStdioClient creates a STDIO client
-func (p *MCPConnectionPool) createStdioClient(ctx context.Context, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
+func (p *MCPConnectionPool) createStdioClient(ctx context.Context, serverConfig config.MCPServerConfig, requests *serverRequests) (client.MCPClient, error) {
 	var env []string
 	var command string
 	var args []string
@@ -335,9 +345,11 @@ func (p *MCPConnectionPool) createStdioClient(ctx context.Context, serverConfig
 	}
 
 	stdioTransport := transport.NewStdio(command, env, args...)
-	stdioClient := client.NewClient(stdioTransport)
+	stdioClient := client.NewClient(stdioTransport, requests.clientOptions()...)
 
-	if err := stdioTransport.Start(ctx); err != nil {
+	// Starting through the client (not just the transport) wires up its
+	// handlers for requests the server sends back, such as sampling.
+	if err := stdioClient.Start(ctx); err != nil {
 		return nil, fmt.Errorf("failed to start stdio transport: %v", err)
 	}
 
@@ -392,7 +404,7 @@ func (p *MCPConnectionPool) buildOAuthConfig(serverConfig config.MCPServerConfig
 	return cfg, nil
 }
 
-func (p *MCPConnectionPool) createSSEClient(ctx context.Context, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
+func (p *MCPConnectionPool) createSSEClient(ctx context.Context, serverConfig config.MCPServerConfig, requests *serverRequests) (client.MCPClient, error) {
 	var options []transport.ClientOption
 
 	if headers := parseHeaders(serverConfig.Headers); headers != nil {
@@ -411,10 +423,11 @@ func (p *MCPConnectionPool) createSSEClient(ctx context.Context, serverConfig co
 		options = append(options, transport.WithOAuth(*oauthCfg))
 	}
 
-	sseClient, err := client.NewSSEMCPClient(serverConfig.URL, options...)
+	sseTransport, err := transport.NewSSE(serverConfig.URL, options...)
 	if err != nil {
 		return nil, err
 	}
+	sseClient := client.NewClient(sseTransport, requests.clientOptions()...)
 
 	if err := sseClient.Start(ctx); err != nil {
 		return nil, fmt.Errorf("failed to start SSE client: %w", err)
@@ -424,7 +437,7 @@ func (p *MCPConnectionPool) createSSEClient(ctx context.Context, serverConfig co
 }
 
 // createStreamableClient creates a Streamable client
-func (p *MCPConnectionPool) createStreamableClient(ctx context.Context, serverConfig config.MCPServerConfig) (client.MCPClient, error) {
+func (p *MCPConnectionPool) createStreamableClient(ctx context.Context, serverConfig config.MCPServerConfig, requests *serverRequests) (client.MCPClient, error) {
 	var options []transport.StreamableHTTPCOption
 
 	if headers := parseHeaders(serverConfig.Headers); headers != nil {
@@ -441,10 +454,15 @@ func (p *MCPConnectionPool) createStreamableClient(ctx context.Context, serverCo
 		options = append(options, transport.WithHTTPOAuth(*oauthCfg))
 	}
 
-	streamableClient, err := client.NewStreamableHttpClient(serverConfig.URL, options...)
+	streamableTransport, err := transport.NewStreamableHTTP(serverConfig.URL, options...)
 	if err != nil {
 		return nil, err
 	}
+	clientOptions := requests.clientOptions()
+	if streamableTransport.GetSessionId() != "" {
+		clientOptions = append(clientOptions, client.WithSession())
+	}
+	streamableClient := client.NewClient(streamableTransport, clientOptions...)
 
 	if err := streamableClient.Start(ctx); err != nil {
 		return nil, fmt.Errorf("failed to start streamable HTTP client: %w", err)
@@ -457,14 +475,17 @@ func (p *MCPConnectionPool) createStreamableClient(ctx context.Context, serverCo
 // directly with an *server.MCPServer in the same process. No subprocess is
 // spawned and no network I/O occurs — calls go through JSON marshal →
 // MCPServer.HandleMessage → JSON unmarshal, all in-memory.
-func (p *MCPConnectionPool) createInProcessClient(serverConfig config.MCPServerConfig) (client.MCPClient, error) {
+func (p *MCPConnectionPool) createInProcessClient(ctx context.Context, serverConfig config.MCPServerConfig, requests *serverRequests) (client.MCPClient, error) {
 	srv, ok := serverConfig.InProcessServer.(*server.MCPServer)
 	if !ok {
 		return nil, fmt.Errorf("InProcessServer must be *server.MCPServer, got %T", serverConfig.InProcessServer)
 	}
-	inProcessClient, err := client.NewInProcessClient(srv)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create in-process client: %w", err)
+	// The server calls the transport's handlers directly; the client's own
+	// options only decide which capabilities initialize advertises.
+	inProcessTransport := transport.NewInProcessTransportWithOptions(srv, requests.inProcessOptions()...)
+	inProcessClient := client.NewClient(inProcessTransport, requests.clientOptions()...)
+	if err := inProcessClient.Start(ctx); err != nil {
+		return nil, fmt.Errorf("failed to start in-process client: %w", err)
 	}
 	return inProcessClient, nil
 }
@@ -148,6 +148,10 @@ type MCPToolManager struct {
 	// poll/timeout.
 	taskCfg MCPTaskConfig
 
+	// clientRequests answers sampling, elicitation and roots requests from
+	// servers. nil advertises none of those capabilities.
+	clientRequests *clientRequests
+
 	// onServerLoaded, if non-nil, is called when each server finishes loading.
 	// Called with server name, tool count, and error (nil on success).
 	onServerLoaded func(serverName string, toolCount int, err error)
@@ -384,6 +388,7 @@ func (m *MCPToolManager) ensureConnectionPool() {
 	}
 	m.connectionPool = NewMCPConnectionPool(DefaultConnectionPoolConfig(), debug, m.authHandler, m.tokenStoreFactory)
 	m.connectionPool.SetDebugLogger(m.debugLogger)
+	m.connectionPool.setClientRequests(m.clientRequests)
 }
 
 // LoadTools loads tools from all configured MCP servers based on the provided configuration.
@@ -409,6 +414,7 @@ func (m *MCPToolManager) LoadTools(ctx context.Context, cfg *config.Config) erro
 	}
 	m.connectionPool = NewMCPConnectionPool(DefaultConnectionPoolConfig(), cfg.Debug, m.authHandler, m.tokenStoreFactory)
 	m.connectionPool.SetDebugLogger(m.debugLogger)
+	m.connectionPool.setClientRequests(m.clientRequests)
 
 	// Load all servers in parallel. Each server connection (subprocess
 	// spawn, MCP initialize handshake, ListTools) is independent and
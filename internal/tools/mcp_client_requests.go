@@ -0,0 +1,270 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/kit/internal/config"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MCPSamplingFunc answers a server's sampling/createMessage request. It
+// returns the tokens the request spent so the manager can charge them to
+// the server's sampling budget.
+type MCPSamplingFunc func(ctx context.Context, serverName string, req mcp.CreateMessageRequest) (*mcp.CreateMessageResult, int, error)
+
+// MCPElicitationFunc answers a server's elicitation/create request, usually
+// by asking the user.
+type MCPElicitationFunc func(ctx context.Context, serverName string, req mcp.ElicitationRequest) (*mcp.ElicitationResult, error)
+
+// MCPClientHandlers answers the requests MCP servers send to Kit. Each
+// non-nil handler is advertised as the matching client capability during
+// initialize; nil handlers leave the capability out.
+type MCPClientHandlers struct {
+	// Sampling answers sampling/createMessage. It is only offered to
+	// servers whose config enables sampling, and requests are clamped to
+	// that config's maxTokens and budget before it is called.
+	Sampling MCPSamplingFunc
+	// Elicitation answers elicitation/create.
+	Elicitation MCPElicitationFunc
+	// Roots returns the roots advertised in answer to roots/list.
+	Roots func() []mcp.Root
+}
+
+// clientRequests holds the handlers for server-initiated requests and the
+// sampling tokens each server has spent. It is owned by the tool manager so
+// budgets survive reconnects and connection pool rebuilds.
+type clientRequests struct {
+	handlers MCPClientHandlers
+
+	mu    sync.Mutex
+	spent map[string]int
+}
+
+func newClientRequests(handlers MCPClientHandlers) *clientRequests {
+	return &clientRequests{handlers: handlers, spent: make(map[string]int)}
+}
+
+// serverRequests answers the requests of a single server. It implements
+// the sampling, elicitation and roots handler interfaces of both the client
+// and the in-process transport.
+type serverRequests struct {
+	requests   *clientRequests
+	serverName string
+	sampling   *config.MCPSamplingConfig // nil when sampling is not allowed
+}
+
+// forServer returns the handler a connection to serverName uses, or nil when
+// no handler applies to it.
+func (r *clientRequests) forServer(serverName string, cfg config.MCPServerConfig) *serverRequests {
+	if r == nil {
+		return nil
+	}
+	h := &serverRequests{requests: r, serverName: serverName}
+	if r.handlers.Sampling != nil && cfg.Sampling != nil && cfg.Sampling.Enabled {
+		h.sampling = cfg.Sampling
+	}
+	if h.sampling == nil && r.handlers.Elicitation == nil && r.handlers.Roots == nil {
+		return nil
+	}
+	return h
+}
+
+// clientOptions returns the client options registering h's handlers.
+func (h *serverRequests) clientOptions() []client.ClientOption {
+	if h == nil {
+		return nil
+	}
+	var opts []client.ClientOption
+	if h.sampling != nil {
+		opts = append(opts, client.WithSamplingHandler(h))
+	}
+	if h.requests.handlers.Elicitation != nil {
+		opts = append(opts, client.WithElicitationHandler(h))
+	}
+	if h.requests.handlers.Roots != nil {
+		opts = append(opts, client.WithRootsHandler(h))
+	}
+	return opts
+}
+
+// inProcessOptions returns the in-process transport options registering h's
+// handlers. The in-process server calls them directly instead of sending
+// requests over the transport.
+func (h *serverRequests) inProcessOptions() []transport.InProcessOption {
+	if h == nil {
+		return nil
+	}
+	var opts []transport.InProcessOption
+	if h.sampling != nil {
+		opts = append(opts, transport.WithSamplingHandler(h))
+	}
+	if h.requests.handlers.Elicitation != nil {
+		opts = append(opts, transport.WithElicitationHandler(h))
+	}
+	if h.requests.handlers.Roots != nil {
+		opts = append(opts, transport.WithRootsHandler(h))
+	}
+	return opts
+}
+
+// CreateMessage implements client.SamplingHandler.
+func (h *serverRequests) CreateMessage(ctx context.Context, req mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	if h.sampling == nil {
+		return nil, fmt.Errorf("sampling is not enabled for MCP server %s", h.serverName)
+	}
+	// Tool use in sampling needs the sampling.tools capability, which Kit
+	// does not advertise.
+	if len(req.Tools) > 0 || req.ToolChoice != nil {
+		return nil, fmt.Errorf("sampling with tools is not supported")
+	}
+
+	maxTokens := req.MaxTokens
+	if limit := h.sampling.MaxTokens; limit > 0 && (maxTokens <= 0 || maxTokens > limit) {
+		maxTokens = limit
+	}
+	// The prompt and the output are reserved before the call so concurrent
+	// requests cannot each spend the whole remaining budget; the reservation
+	// is settled with what the request actually spent.
+	maxTokens, reserved, err := h.requests.reserve(h.serverName, h.sampling.Budget, samplingPromptTokens(req), maxTokens)
+	if err != nil {
+		return nil, err
+	}
+	req.MaxTokens = maxTokens
+
+	result, spent, err := h.requests.handlers.Sampling(ctx, h.serverName, req)
+	h.requests.settle(h.serverName, reserved, spent)
+	return result, err
+}
+
+// samplingPromptTokens estimates the input tokens of req at roughly four
+// characters per token. Image and audio count by their encoded size.
+func samplingPromptTokens(req mcp.CreateMessageRequest) int {
+	chars := len(req.SystemPrompt)
+	for _, msg := range req.Messages {
+		blocks := []any{msg.Content}
+		if list, ok := msg.Content.([]mcp.Content); ok {
+			blocks = blocks[:0]
+			for _, c := range list {
+				blocks = append(blocks, c)
+			}
+		}
+		for _, block := range blocks {
+			switch c := block.(type) {
+			case mcp.TextContent:
+				chars += len(c.Text)
+			case *mcp.TextContent:
+				chars += len(c.Text)
+			case mcp.ImageContent:
+				chars += len(c.Data)
+			case *mcp.ImageContent:
+				chars += len(c.Data)
+			case mcp.AudioContent:
+				chars += len(c.Data)
+			case *mcp.AudioContent:
+				chars += len(c.Data)
+			}
+		}
+	}
+	return chars / 4
+}
+
+// Elicit implements client.ElicitationHandler.
+func (h *serverRequests) Elicit(ctx context.Context, req mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	if h.requests.handlers.Elicitation == nil {
+		return nil, fmt.Errorf("elicitation is not supported")
+	}
+	return h.requests.handlers.Elicitation(ctx, h.serverName, req)
+}
+
+// ListRoots implements client.RootsHandler.
+func (h *serverRequests) ListRoots(context.Context, mcp.ListRootsRequest) (*mcp.ListRootsResult, error) {
+	roots := []mcp.Root{}
+	if h.requests.handlers.Roots != nil {
+		roots = append(roots, h.requests.handlers.Roots()...)
+	}
+	return &mcp.ListRootsResult{Roots: roots}, nil
+}
+
+func (r *clientRequests) spentBy(serverName string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.spent[serverName]
+}
+
+// reserve charges serverName for a request before it runs: prompt input
+// tokens plus up to maxTokens of output, clamped to what budget leaves after
+// the prompt. It returns the output limit and the tokens reserved, which the
+// caller passes to settle. A zero budget is unlimited and reserves nothing.
+func (r *clientRequests) reserve(serverName string, budget, prompt, maxTokens int) (int, int, error) {
+	if budget <= 0 {
+		return maxTokens, 0, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	remaining := budget - r.spent[serverName]
+	if remaining <= 0 {
+		return 0, 0, fmt.Errorf("sampling budget of %d tokens for MCP server %s is exhausted", budget, serverName)
+	}
+	if prompt >= remaining {
+		return 0, 0, fmt.Errorf("sampling prompt of about %d tokens exceeds the %d tokens left in the budget of MCP server %s", prompt, remaining, serverName)
+	}
+	if maxTokens <= 0 || maxTokens > remaining-prompt {
+		maxTokens = remaining - prompt
+	}
+	r.spent[serverName] += prompt + maxTokens
+	return maxTokens, prompt + maxTokens, nil
+}
+
+// settle replaces a reservation with the tokens the request spent.
+func (r *clientRequests) settle(serverName string, reserved, spent int) {
+	spent = max(spent, 0)
+	if spent == reserved {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spent[serverName] += spent - reserved
+}
+
+// SetClientHandlers sets the handlers answering sampling, elicitation and
+// roots requests from MCP servers. Call this before LoadTools / AddServer;
+// connections opened earlier keep the capabilities they advertised.
+func (m *MCPToolManager) SetClientHandlers(handlers MCPClientHandlers) {
+	m.clientRequests = newClientRequests(handlers)
+	if m.connectionPool != nil {
+		m.connectionPool.setClientRequests(m.clientRequests)
+	}
+}
+
+// SamplingTokensSpent returns the sampling tokens serverName has spent.
+func (m *MCPToolManager) SamplingTokensSpent(serverName string) int {
+	if m.clientRequests == nil {
+		return 0
+	}
+	return m.clientRequests.spentBy(serverName)
+}
+
+// NotifyRootsChanged sends notifications/roots/list_changed to every
+// connected server so they re-request roots/list. It does nothing when no
+// roots handler is set.
+func (m *MCPToolManager) NotifyRootsChanged(ctx context.Context) error {
+	if m.clientRequests == nil || m.clientRequests.handlers.Roots == nil || m.connectionPool == nil {
+		return nil
+	}
+	var errs []error
+	for name, raw := range m.connectionPool.GetClients() {
+		c, ok := raw.(*client.Client)
+		if !ok {
+			continue
+		}
+		if err := c.RootListChanges(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("server %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,234 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/kit/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// newClientRequestServer returns an in-process server whose tools send
+// sampling, elicitation and roots requests back to the client, and a channel
+// receiving its roots/list_changed notifications.
+func newClientRequestServer() (*server.MCPServer, <-chan struct{}) {
+	srv := server.NewMCPServer("requests", "1.0.0", server.WithToolCapabilities(true))
+	changed := make(chan struct{}, 1)
+	srv.AddNotificationHandler(string(mcp.MethodNotificationRootsListChanged), func(context.Context, mcp.JSONRPCNotification) {
+		changed <- struct{}{}
+	})
+	srv.AddTool(mcp.NewTool("sample", mcp.WithNumber("max_tokens")),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			res, err := srv.RequestSampling(ctx, mcp.CreateMessageRequest{
+				CreateMessageParams: mcp.CreateMessageParams{
+					Messages:  []mcp.SamplingMessage{{Role: mcp.RoleUser, Content: mcp.NewTextContent("hi")}},
+					MaxTokens: req.GetInt("max_tokens", 0),
+				},
+			})
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return mcp.NewToolResultText(res.Content.(mcp.TextContent).Text), nil
+		})
+	srv.AddTool(mcp.NewTool("elicit"),
+		func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			res, err := srv.RequestElicitation(ctx, mcp.ElicitationRequest{Params: mcp.ElicitationParams{
+				Message:         "Name?",
+				RequestedSchema: map[string]any{"type": "object"},
+			}})
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return mcp.NewToolResultText(string(res.Action)), nil
+		})
+	srv.AddTool(mcp.NewTool("roots"),
+		func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			res, err := srv.RequestRoots(ctx, mcp.ListRootsRequest{})
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			var uris []string
+			for _, r := range res.Roots {
+				uris = append(uris, r.URI)
+			}
+			return mcp.NewToolResultText(strings.Join(uris, ",")), nil
+		})
+	return srv, changed
+}
+
+func loadClientRequestServer(t *testing.T, handlers MCPClientHandlers, sampling *config.MCPSamplingConfig) (*MCPToolManager, <-chan struct{}) {
+	t.Helper()
+	srv, changed := newClientRequestServer()
+	m := NewMCPToolManager()
+	m.SetClientHandlers(handlers)
+	cfg := &config.Config{MCPServers: map[string]config.MCPServerConfig{
+		"srv": {Type: "inprocess", InProcessServer: srv, Sampling: sampling},
+	}}
+	if err := m.LoadTools(context.Background(), cfg); err != nil {
+		t.Fatalf("LoadTools: %v", err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+	return m, changed
+}
+
+func callText(t *testing.T, m *MCPToolManager, name, input string) (string, bool) {
+	t.Helper()
+	res, err := m.ExecuteTool(context.Background(), name, input)
+	if err != nil {
+		t.Fatalf("ExecuteTool(%s): %v", name, err)
+	}
+	return res.Content, res.IsError
+}
+
+func TestClientRequests_Sampling(t *testing.T) {
+	var gotServer string
+	var gotMax []int
+	handlers := MCPClientHandlers{
+		Sampling: func(_ context.Context, serverName string, req mcp.CreateMessageRequest) (*mcp.CreateMessageResult, int, error) {
+			gotServer = serverName
+			gotMax = append(gotMax, req.MaxTokens)
+			return &mcp.CreateMessageResult{
+				SamplingMessage: mcp.SamplingMessage{Role: mcp.RoleAssistant, Content: mcp.NewTextContent("sampled")},
+				Model:           "test/model",
+			}, 60, nil
+		},
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		m, _ := loadClientRequestServer(t, handlers, nil)
+		if _, isErr := callText(t, m, "srv__sample", `{}`); !isErr {
+			t.Error("sampling should be refused when not enabled for the server")
+		}
+	})
+
+	t.Run("clamped and budgeted", func(t *testing.T) {
+		gotMax = nil
+		m, _ := loadClientRequestServer(t, handlers, &config.MCPSamplingConfig{Enabled: true, MaxTokens: 50, Budget: 100})
+
+		out, isErr := callText(t, m, "srv__sample", `{"max_tokens": 500}`)
+		if isErr || !strings.Contains(out, "sampled") {
+			t.Fatalf("first sample = %q (error %v)", out, isErr)
+		}
+		if gotServer != "srv" {
+			t.Errorf("server name = %q, want srv", gotServer)
+		}
+		// The second request is clamped to the 40 tokens left in the budget.
+		if _, isErr := callText(t, m, "srv__sample", `{}`); isErr {
+			t.Fatal("second sample should fit the remaining budget")
+		}
+		if want := []int{50, 40}; len(gotMax) != 2 || gotMax[0] != want[0] || gotMax[1] != want[1] {
+			t.Errorf("max tokens = %v, want %v", gotMax, want)
+		}
+		if got := m.SamplingTokensSpent("srv"); got != 120 {
+			t.Errorf("spent = %d, want 120", got)
+		}
+		out, isErr = callText(t, m, "srv__sample", `{}`)
+		if !isErr || !strings.Contains(out, "budget") {
+			t.Errorf("exhausted budget: got %q (error %v)", out, isErr)
+		}
+	})
+}
+
+func TestClientRequests_SamplingReservesBudget(t *testing.T) {
+	started, release := make(chan int, 2), make(chan struct{})
+	r := newClientRequests(MCPClientHandlers{
+		Sampling: func(_ context.Context, _ string, req mcp.CreateMessageRequest) (*mcp.CreateMessageResult, int, error) {
+			started <- req.MaxTokens
+			<-release
+			return &mcp.CreateMessageResult{}, 30, nil
+		},
+	})
+	h := r.forServer("srv", config.MCPServerConfig{Sampling: &config.MCPSamplingConfig{Enabled: true, Budget: 100}})
+	sample := func(prompt string) error {
+		_, err := h.CreateMessage(context.Background(), mcp.CreateMessageRequest{CreateMessageParams: mcp.CreateMessageParams{
+			Messages:  []mcp.SamplingMessage{{Role: mcp.RoleUser, Content: mcp.NewTextContent(prompt)}},
+			MaxTokens: 60,
+		}})
+		return err
+	}
+
+	// A prompt larger than the budget is refused before the model is called.
+	if err := sample(strings.Repeat("x", 400)); err == nil || !strings.Contains(err.Error(), "prompt") {
+		t.Fatalf("oversized prompt: err = %v", err)
+	}
+
+	// A request in flight holds its prompt and output: a concurrent one only
+	// gets what is left of the budget.
+	done := make(chan error, 1)
+	go func() { done <- sample(strings.Repeat("x", 40)) }() // 10 prompt tokens
+	if got := <-started; got != 60 {
+		t.Fatalf("first max tokens = %d, want 60", got)
+	}
+	go func() { done <- sample("") }()
+	if got := <-started; got != 30 {
+		t.Fatalf("concurrent max tokens = %d, want the 30 left", got)
+	}
+	if err := sample(""); err == nil || !strings.Contains(err.Error(), "exhausted") {
+		t.Errorf("third request with the budget reserved: err = %v", err)
+	}
+	close(release)
+	for range 2 {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Settled to what was actually spent.
+	if got := r.spentBy("srv"); got != 60 {
+		t.Errorf("spent = %d, want 60", got)
+	}
+}
+
+func TestClientRequests_Elicitation(t *testing.T) {
+	var gotServer, gotMessage string
+	m, _ := loadClientRequestServer(t, MCPClientHandlers{
+		Elicitation: func(_ context.Context, serverName string, req mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+			gotServer, gotMessage = serverName, req.Params.Message
+			return &mcp.ElicitationResult{ElicitationResponse: mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionDecline}}, nil
+		},
+	}, nil)
+
+	out, isErr := callText(t, m, "srv__elicit", `{}`)
+	if isErr || !strings.Contains(out, "decline") {
+		t.Fatalf("elicit = %q (error %v)", out, isErr)
+	}
+	if gotServer != "srv" || gotMessage != "Name?" {
+		t.Errorf("handler got server %q message %q", gotServer, gotMessage)
+	}
+}
+
+func TestClientRequests_Roots(t *testing.T) {
+	roots := []mcp.Root{{URI: "file:///work"}}
+	m, changed := loadClientRequestServer(t, MCPClientHandlers{
+		Roots: func() []mcp.Root { return roots },
+	}, nil)
+
+	if out, _ := callText(t, m, "srv__roots", `{}`); !strings.Contains(out, "file:///work") {
+		t.Fatalf("roots = %q", out)
+	}
+
+	roots = append(roots, mcp.Root{URI: "file:///extra"})
+	if err := m.NotifyRootsChanged(context.Background()); err != nil {
+		t.Fatalf("NotifyRootsChanged: %v", err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not receive roots/list_changed")
+	}
+	if out, _ := callText(t, m, "srv__roots", `{}`); !strings.Contains(out, "file:///extra") {
+		t.Errorf("roots after change = %q", out)
+	}
+}
+
+func TestClientRequests_NoHandlers(t *testing.T) {
+	m, _ := loadClientRequestServer(t, MCPClientHandlers{}, &config.MCPSamplingConfig{Enabled: true})
+	if _, isErr := callText(t, m, "srv__roots", `{}`); !isErr {
+		t.Error("roots should fail without a roots handler")
+	}
+	if _, isErr := callText(t, m, "srv__elicit", `{}`); !isErr {
+		t.Error("elicitation should fail without an elicitation handler")
+	}
+}
@@ -1,11 +1,13 @@
 // Package trust manages a persisted allowlist of project directories that the
-// user has marked as trusted for loading project-local skills.
+// user has marked as trusted for loading project-local skills and
+// extensions.
 //
 // Project-local skills (discovered under <project>/.agents/skills/ and
 // <project>/.kit/skills/) are injected into the system prompt. A freshly
 // cloned, untrusted repository could therefore smuggle instructions into the
-// agent the moment the user runs Kit inside it. To mitigate this prompt-
-// injection vector, project-level skill loading can be gated on an explicit
+// agent the moment the user runs Kit inside it. Project-local extensions
+// (<project>/.kit/extensions/ and <project>/.kit/git/) are worse: they run
+// code. To mitigate both, project-level loading can be gated on an explicit
 // trust decision recorded here.
 //
 // The allowlist is stored as JSON at $XDG_CONFIG_HOME/kit/trusted-projects.json
@@ -23,11 +25,12 @@ import (
 type Decision int
 
 const (
-	// Skip declines to load project skills this session and records nothing.
+	// Skip declines to load project content this session and records
+	// nothing.
 	Skip Decision = iota
-	// Trust loads project skills this session and persists the directory.
+	// Trust loads project content this session and persists the directory.
 	Trust
-	// TrustOnce loads project skills this session without persisting.
+	// TrustOnce loads project content this session without persisting.
 	TrustOnce
 )
 
@@ -136,6 +139,41 @@ func (s *Store) save() error {
 	return os.WriteFile(s.path, data, 0o644)
 }
 
+// Gate returns a check that admits directories on the allowlist at path
+// (DefaultPath when empty) and asks prompt about the rest, persisting Trust
+// decisions. Each directory is decided at most once per returned check, so
+// reloading does not prompt again.
+func Gate(path string, prompt func(dir string, count int) Decision) func(dir string, count int) bool {
+	var (
+		mu      sync.Mutex
+		decided = map[string]bool{}
+	)
+	return func(dir string, count int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		key := normalize(dir)
+		if ok, seen := decided[key]; seen {
+			return ok
+		}
+
+		store, err := Load(path)
+		ok := err == nil && store.IsTrusted(dir)
+		if !ok {
+			switch prompt(dir, count) {
+			case Trust:
+				if store != nil {
+					_ = store.Trust(dir)
+				}
+				ok = true
+			case TrustOnce:
+				ok = true
+			}
+		}
+		decided[key] = ok
+		return ok
+	}
+}
+
 // normalize resolves dir to an absolute, symlink-evaluated path for stable
 // comparison. It falls back to the cleaned input when resolution fails.
 func normalize(dir string) string {
@@ -58,3 +58,38 @@ func TestStore_MissingFileIsEmpty(t *testing.T) {
 		t.Fatal("empty store should trust nothing")
 	}
 }
+
+func TestGate(t *testing.T) {
+	dir := t.TempDir()
+	storePath := filepath.Join(dir, "trusted-projects.json")
+	once, always, skipped := filepath.Join(dir, "once"), filepath.Join(dir, "always"), filepath.Join(dir, "skipped")
+
+	prompts := 0
+	answers := map[string]Decision{once: TrustOnce, always: Trust, skipped: Skip}
+	gate := Gate(storePath, func(d string, _ int) Decision {
+		prompts++
+		return answers[d]
+	})
+	for range 2 {
+		if !gate(once, 1) || !gate(always, 1) || gate(skipped, 1) {
+			t.Fatal("gate did not follow the prompt's decisions")
+		}
+	}
+	if prompts != 3 {
+		t.Errorf("prompted %d times, want once per directory", prompts)
+	}
+
+	s, _ := Load(storePath)
+	if !s.IsTrusted(always) || s.IsTrusted(once) || s.IsTrusted(skipped) {
+		t.Error("only Trust decisions should persist")
+	}
+
+	// A fresh gate trusts the persisted directory without asking.
+	fresh := Gate(storePath, func(string, int) Decision {
+		t.Error("prompted for a trusted directory")
+		return Skip
+	})
+	if !fresh(always, 1) {
+		t.Error("persisted directory not trusted")
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"charm.land/lipgloss/v2"
 
+	"github.com/mark3labs/kit/internal/core"
 	"github.com/mark3labs/kit/internal/ui/style"
 )
 
@@ -54,12 +55,37 @@ func activityVerb(toolName, toolArgs string) string {
 		verb, target = "Writing", shortenActivityPath(argString(args, "path"))
 	case "edit":
 		verb, target = "Editing", shortenActivityPath(argString(args, "path"))
+	case "apply_patch":
+		files, err := core.ParsePatch(argString(args, "patch"))
+		switch {
+		case err != nil:
+			return "Applying patch"
+		case len(files) == 1:
+			verb, target = "Patching", shortenActivityPath(files[0].Path)
+		default:
+			return fmt.Sprintf("Patching %d files", len(files))
+		}
 	case "grep":
 		verb, target = "Searching", shortenTarget(oneLine(argString(args, "pattern")))
 	case "find":
 		verb, target = "Matching", shortenTarget(oneLine(argString(args, "pattern")))
 	case "ls":
 		verb, target = "Listing", shortenActivityPath(argString(args, "path"))
+	case "definition":
+		verb, target = "Finding definition of", shortenTarget(argString(args, "symbol"))
+	case "references":
+		verb, target = "Finding references to", shortenTarget(argString(args, "symbol"))
+	case "hover":
+		verb, target = "Inspecting", shortenTarget(argString(args, "symbol"))
+	case "rename_symbol":
+		verb, target = "Renaming", shortenTarget(argString(args, "symbol"))
+	case "workspace_symbols":
+		verb, target = "Finding symbols matching", shortenTarget(oneLine(argString(args, "query")))
+	case "diagnostics":
+		if path := argString(args, "path"); path != "" {
+			return "Checking " + shortenActivityPath(path)
+		}
+		return "Checking diagnostics"
 	case "fetch":
 		verb, target = "Fetching", shortenTarget(oneLine(argString(args, "url")))
 	case "subagent":
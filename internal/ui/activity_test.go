@@ -25,9 +25,15 @@ func TestActivityVerb(t *testing.T) {
 		{"read", "read", `{"path":"internal/ui/model.go"}`, "Reading internal/ui/model.go"},
 		{"write", "write", `{"path":"a.go"}`, "Writing a.go"},
 		{"edit", "edit", `{"path":"a.go"}`, "Editing a.go"},
+		{"patch one file", "apply_patch", `{"patch":"*** Begin Patch\n*** Delete File: a.go\n*** End Patch"}`, "Patching a.go"},
+		{"patch many files", "apply_patch", `{"patch":"--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-x\n+y\n--- a/b.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-z\n"}`, "Patching 2 files"},
+		{"patch unparsable", "apply_patch", `{"patch":"nonsense"}`, "Applying patch"},
 		{"grep", "grep", `{"pattern":"func main"}`, "Searching func main"},
 		{"find", "find", `{"pattern":"*.go"}`, "Matching *.go"},
 		{"ls", "ls", `{"path":"/tmp"}`, "Listing /tmp"},
+		{"definition", "definition", `{"path":"a.go","line":3,"symbol":"Run"}`, "Finding definition of Run"},
+		{"rename", "rename_symbol", `{"path":"a.go","line":3,"symbol":"Run","new_name":"Start"}`, "Renaming Run"},
+		{"diagnostics without path", "diagnostics", `{}`, "Checking diagnostics"},
 		{"subagent with agent", "subagent", `{"agent":"explore"}`, "Delegating to explore"},
 		{"todo", "todo", `{}`, "Updating todos"},
 		// Multi-line commands must collapse so the row stays one line.
@@ -156,6 +156,17 @@ var SlashCommands = []SlashCommand{
 		Aliases:     []string{"/n"},
 		HasArgs:     true,
 	},
+	{
+		Name:        "/jobs",
+		Description: "List background bash jobs (/jobs kill <id> to stop one)",
+		Category:    "System",
+	},
+	{
+		Name:        "/rewind",
+		Description: "Restore files and conversation to before an earlier message",
+		Category:    "Navigation",
+		Aliases:     []string{"/rw"},
+	},
 	{
 		Name:        "/name",
 		Description: "Set a display name for this session",
@@ -44,6 +44,9 @@ type TreeNodeSelectedMsg struct {
 	IsUser bool
 	// UserText is the user message text (only set when IsUser is true).
 	UserText string
+	// Rewind is true when the selector was opened by /rewind: the working
+	// tree is restored along with the conversation instead of forking.
+	Rewind bool
 }
 
 // TreeCancelledMsg is sent when the user cancels the tree selector (ESC).
@@ -153,6 +153,16 @@ func (h *CLIEventHandler) Handle(msg app.Event) {
 		}
 		h.startSpinner()
 
+	case app.PermissionPromptEvent:
+		// Non-interactive mode cannot ask for approval, so "ask" rules fail
+		// closed. Reply immediately so the tool call is not left waiting.
+		h.stopSpinner()
+		h.cli.DisplayInfo(fmt.Sprintf("%s requires approval but running non-interactively; denied", permissionLabel(e.ToolName, e.Subject)))
+		if e.ResponseCh != nil {
+			e.ResponseCh <- app.PermissionPromptResponse{}
+		}
+		h.startSpinner()
+
 	case app.StepCompleteEvent:
 		h.stopSpinner()
 
@@ -90,6 +90,7 @@ func formatToolParams(toolArgs string, maxWidth int) string {
 	bodyKeys := map[string]bool{
 		"content": true,
 		"edits":   true,
+		"patch":   true,
 		"todos":   true,
 	}
 	var remaining []string
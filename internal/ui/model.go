@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -132,6 +133,9 @@ type AppController interface {
 	// DeleteSession removes a session file from disk. Used by the session
 	// picker's delete flow.
 	DeleteSession(path string) error
+	// SearchSessions runs a full-text query over every session on disk.
+	// Used by the session picker's search.
+	SearchSessions(query string) ([]app.SessionSearchResult, error)
 	// ExportSession copies the active session's file to dstPath, deriving a
 	// name when dstPath is empty, and reports the path written and byte
 	// count. Used by /export.
@@ -175,6 +179,15 @@ type AppController interface {
 	// message in context. Returns an error if the agent is busy, no tree
 	// session is active, or no user message exists on the current branch.
 	PopLastUserMessage() (string, []kit.LLMFilePart, error)
+	// RestoreCheckpoint rewinds both the conversation and the files modified
+	// since the user message entryID to the moment before it was sent, and
+	// syncs the in-memory message store. Used by /rewind.
+	RestoreCheckpoint(entryID string) (*kit.CheckpointRestore, error)
+	// ListBackgroundJobs returns the bash jobs the agent started with
+	// run_in_background, oldest first. Used by /jobs.
+	ListBackgroundJobs() []kit.BackgroundJob
+	// KillBackgroundJob stops a background job. Used by /jobs kill.
+	KillBackgroundJob(id string) (kit.BackgroundJob, error)
 }
 
 // SkillItem holds display metadata about a loaded skill for the startup
@@ -836,6 +849,8 @@ type AppModel struct {
 
 	// thinkingLevel is the current extended thinking level.
 	thinkingLevel string
+	// scopedSkills names the activated skills currently restricting tools.
+	scopedSkills []string
 	// thinkingVisible controls whether reasoning blocks are shown or collapsed.
 	thinkingVisible bool
 	// isReasoningModel is true when the current model supports reasoning.
@@ -1609,6 +1624,11 @@ func (m *AppModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// ── Tree selector events ─────────────────────────────────────────────────
 	case uicore.TreeNodeSelectedMsg:
+		if msg.Rewind {
+			m.treeSelector = nil
+			m.state = stateInput
+			return m, m.performRewind(msg.ID, msg.IsUser)
+		}
 		// User selected a node in the tree. Branch to it and return to input.
 		if _, ok := m.appCtrl.SessionSnapshot(); ok {
 			// For user messages: branch to parent (so user can resubmit).
@@ -1678,6 +1698,9 @@ func (m *AppModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else {
 				m.renderSessionHistory()
 				m.printSystemMessage("Session loaded. Continue where you left off.")
+				if msg.EntryID != "" {
+					m.openTreeAtEntry(msg.EntryID)
+				}
 			}
 		} else {
 			m.printSystemMessage("Session switching not available.")
@@ -2658,6 +2681,12 @@ func (m *AppModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.providerName = msg.ProviderName
 		m.modelName = msg.ModelName
 
+	case app.SkillScopeChangedEvent:
+		m.scopedSkills = slices.DeleteFunc(m.scopedSkills, func(s string) bool { return s == msg.Skill })
+		if msg.Active {
+			m.scopedSkills = append(m.scopedSkills, msg.Skill)
+		}
+
 	case app.UsageUpdatedEvent:
 		// Token usage was updated after a completed LLM step. No state
 		// changes needed — the UsageTracker was already mutated in-place.
@@ -2782,6 +2811,33 @@ func (m *AppModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, m.prompt.Init())
 		}
 
+	case app.PermissionPromptEvent:
+		// Tool-call approval — show a selection prompt. If another prompt is
+		// already active, deny rather than queue: the tool is blocked on the
+		// response channel.
+		if m.state == statePrompt {
+			if msg.ResponseCh != nil {
+				msg.ResponseCh <- app.PermissionPromptResponse{}
+			}
+			return m, tea.Batch(cmds...)
+		}
+		m.prePromptState = m.state
+		m.state = statePrompt
+		permissionResponseCh := make(chan app.PromptResponse, 1)
+		m.promptResponseCh = permissionResponseCh
+		m.prompt = newPermissionPrompt(msg.ToolName, msg.Subject, msg.Rule, m.width, m.height)
+
+		go func() {
+			resp := <-permissionResponseCh
+			if msg.ResponseCh != nil {
+				msg.ResponseCh <- app.PermissionPromptResponse{Decision: permissionDecision(resp)}
+			}
+		}()
+
+		if m.prompt != nil {
+			cmds = append(cmds, m.prompt.Init())
+		}
+
 	case app.PromptRequestEvent:
 		// Extension wants to show an interactive prompt. Enter prompt state.
 		// If already in prompt state (concurrent prompt from another
@@ -3306,6 +3362,13 @@ func (m *AppModel) renderStatusBar() string {
 	if m.isReasoningModel && m.thinkingLevel != "" && m.thinkingLevel != "off" {
 		middleParts = append(middleParts, veryMuted.Render("thinking: "+m.thinkingLevel))
 	}
+	// Restricting skills are highlighted: they explain why tool calls are
+	// failing until the turn ends.
+	if len(m.scopedSkills) > 0 {
+		middleParts = append(middleParts, lipgloss.NewStyle().
+			Foreground(theme.Warning).
+			Render("skill: "+strings.Join(m.scopedSkills, ", ")))
+	}
 	if m.getStatusBarEntries != nil {
 		for _, e := range m.getStatusBarEntries() {
 			middleParts = append(middleParts, veryMuted.Render(e.Text))
@@ -4109,6 +4172,10 @@ func (m *AppModel) handleSlashCommand(sc *commands.SlashCommand, args string) te
 		return m.handleRetryCommand()
 	case "/undo":
 		return m.handleUndoCommand()
+	case "/rewind":
+		return m.handleRewindCommand()
+	case "/jobs":
+		return m.handleJobsCommand(args)
 	case "/edit":
 		return m.handleEditCommand(args)
 	case "/share":
@@ -5469,6 +5536,22 @@ func (m *AppModel) handleTreeCommand() tea.Cmd {
 	return nil
 }
 
+// openTreeAtEntry opens the tree selector with the cursor on entryID, so a
+// session picked from a search lands on the entry that matched. Nothing
+// opens when the entry is not in the session's tree.
+func (m *AppModel) openTreeAtEntry(entryID string) {
+	snap, ok := m.appCtrl.SessionSnapshot()
+	if !ok || snap.EntryCount == 0 {
+		return
+	}
+	ts := NewTreeSelector(m.appCtrl.SessionTree(), snap.LeafID, m.width, m.height)
+	if !ts.FocusEntry(entryID) {
+		return
+	}
+	m.treeSelector = ts
+	m.state = stateTreeSelector
+}
+
 // handleForkCommand creates a branch from the current position. Like /tree
 // but opens the selector directly for fork semantics.
 // Unlike /tree which shows the full tree, /fork shows only user messages
@@ -5660,6 +5743,114 @@ func (m *AppModel) performFork(targetID string, isUser bool, userText string) te
 	return nil
 }
 
+// handleRewindCommand opens the user-message selector for /rewind.
+func (m *AppModel) handleRewindCommand() tea.Cmd {
+	snap, ok := m.appCtrl.SessionSnapshot()
+	if !ok {
+		m.printSystemMessage("No tree session active.")
+		return nil
+	}
+	if snap.EntryCount == 0 {
+		m.printSystemMessage("Nothing to rewind yet.")
+		return nil
+	}
+
+	m.treeSelector = NewTreeSelectorForRewind(m.appCtrl.SessionTree(), snap.LeafID, m.width, m.height)
+	m.state = stateTreeSelector
+	return nil
+}
+
+// performRewind restores the files modified since the selected user message
+// and moves the conversation to just before it, placing the message text in
+// the input so it can be edited and resubmitted. Unlike /fork it stays in
+// the current session; the abandoned turns remain reachable via /tree.
+func (m *AppModel) performRewind(entryID string, isUser bool) tea.Cmd {
+	if !isUser {
+		m.printSystemMessage("Select a user message to rewind to.")
+		return nil
+	}
+
+	result, err := m.appCtrl.RestoreCheckpoint(entryID)
+	if result == nil {
+		m.printSystemMessage(fmt.Sprintf("Cannot rewind: %v", err))
+		return nil
+	}
+
+	m.messages = []MessageItem{}
+	m.renderSessionHistory()
+
+	if err != nil {
+		m.printSystemMessage(fmt.Sprintf("Rewind incomplete: %v", err))
+		return nil
+	}
+
+	if ic, ok := m.input.(*InputComponent); ok {
+		ic.textarea.SetValue(result.Prompt)
+		ic.textarea.CursorEnd()
+	}
+
+	files := len(result.Restored) + len(result.Removed)
+	text := "Rewound the conversation."
+	switch {
+	case result.GitRef != "":
+		text = fmt.Sprintf("Rewound the conversation, the git working tree, and %d file(s).", files)
+	case files > 0:
+		text = fmt.Sprintf("Rewound the conversation and %d file(s).", files)
+	}
+	m.printSystemMessage(text + " Edit and resubmit to continue.")
+	return nil
+}
+
+// handleJobsCommand lists the agent's background bash jobs, or stops one
+// with "/jobs kill <id>".
+func (m *AppModel) handleJobsCommand(args string) tea.Cmd {
+	if id, ok := strings.CutPrefix(args, "kill"); ok {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			m.printSystemMessage("Usage: `/jobs kill <id>`")
+			return nil
+		}
+		job, err := m.appCtrl.KillBackgroundJob(id)
+		if err != nil {
+			m.printSystemMessage(fmt.Sprintf("Failed to kill job: %v", err))
+			return nil
+		}
+		m.printSystemMessage(fmt.Sprintf("Job %s stopped.", job.ID))
+		return nil
+	}
+
+	jobs := m.appCtrl.ListBackgroundJobs()
+	if len(jobs) == 0 {
+		m.printSystemMessage("No background jobs.")
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("## Background jobs\n\n")
+	for _, job := range jobs {
+		status := fmt.Sprintf("running %s", time.Since(job.StartedAt).Round(time.Second))
+		if !job.Running {
+			status = fmt.Sprintf("exited %d", job.ExitCode)
+		}
+		fmt.Fprintf(&b, "- **%s** (%s) `%s`\n", job.ID, status, truncateJobCommand(job.Command))
+	}
+	b.WriteString("\nStop a job with `/jobs kill <id>`.")
+	m.printSystemMessage(b.String())
+	return nil
+}
+
+// truncateJobCommand shortens a job's command to one line for listings.
+func truncateJobCommand(command string) string {
+	command, _, multiline := strings.Cut(command, "\n")
+	const maxLen = 60
+	if len(command) > maxLen {
+		return command[:maxLen-3] + "..."
+	}
+	if multiline {
+		return command + " ..."
+	}
+	return command
+}
+
 // handleNameCommand sets a display name for the current session.
 // Usage: /name <new name> — sets the session name.
 //
@@ -6317,6 +6508,12 @@ func (m *AppModel) updatePromptState(msg tea.Msg) (tea.Model, tea.Cmd) {
 			msg.ResponseCh <- app.PasswordPromptResponse{Cancelled: true}
 		}
 
+	case app.PermissionPromptEvent:
+		// Another prompt is active — deny so the tool call unblocks.
+		if msg.ResponseCh != nil {
+			msg.ResponseCh <- app.PermissionPromptResponse{}
+		}
+
 	case app.OverlayRequestEvent:
 		// Can't show an overlay while a prompt is active — reject so the
 		// requesting extension goroutine unblocks.
@@ -6417,6 +6614,13 @@ func (m *AppModel) updateOverlayState(msg tea.Msg) (tea.Model, tea.Cmd) {
 			msg.ResponseCh <- app.PasswordPromptResponse{Cancelled: true}
 		}
 
+	case app.PermissionPromptEvent:
+		// Can't ask for approval while an overlay is active — deny so the
+		// tool call unblocks.
+		if msg.ResponseCh != nil {
+			msg.ResponseCh <- app.PermissionPromptResponse{}
+		}
+
 	case app.NewSessionRequestEvent:
 		// Can't switch sessions while an overlay is active — fail the request
 		// so the extension goroutine blocked on the response channel unblocks.
@@ -111,6 +111,10 @@ func (s *stubAppController) DeleteSession(path string) error {
 	return s.deleteErr
 }
 
+func (s *stubAppController) SearchSessions(string) ([]app.SessionSearchResult, error) {
+	return nil, nil
+}
+
 func (s *stubAppController) ExportSession(_ string) (string, int, error) {
 	return "", 0, app.ErrNoSession
 }
@@ -146,6 +150,16 @@ func (s *stubAppController) PopLastUserMessage() (string, []kit.LLMFilePart, err
 	return "", nil, fmt.Errorf("no user message to retry")
 }
 
+func (s *stubAppController) RestoreCheckpoint(string) (*kit.CheckpointRestore, error) {
+	return nil, fmt.Errorf("no tree session active")
+}
+
+func (s *stubAppController) ListBackgroundJobs() []kit.BackgroundJob { return nil }
+
+func (s *stubAppController) KillBackgroundJob(id string) (kit.BackgroundJob, error) {
+	return kit.BackgroundJob{}, fmt.Errorf("no background job %q", id)
+}
+
 // --------------------------------------------------------------------------
 // Stub child components
 // --------------------------------------------------------------------------
@@ -9,7 +9,9 @@ import (
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
 
+	"github.com/mark3labs/kit/internal/app"
 	"github.com/mark3labs/kit/internal/ui/style"
+	kit "github.com/mark3labs/kit/pkg/kit"
 )
 
 // ---------------------------------------------------------------------------
@@ -137,6 +139,52 @@ func newInputPrompt(message, placeholder, defaultValue string, width, height int
 	}
 }
 
+// permissionOptions are the choices offered for a tool-call approval, in the
+// order permissionDecision maps them.
+var permissionOptions = []string{
+	"Allow once",
+	"Always allow for this session",
+	"Always allow for this project",
+	"Deny",
+}
+
+// newPermissionPrompt creates a selection prompt asking whether a tool call
+// may run.
+func newPermissionPrompt(toolName, subject, rule string, width, height int) *promptOverlay {
+	message := fmt.Sprintf("Allow %s?", permissionLabel(toolName, subject))
+	if rule != "" {
+		message += fmt.Sprintf("\n\n\"Always\" grants: %s", rule)
+	}
+	return newSelectPrompt(message, permissionOptions, width, height)
+}
+
+// permissionDecision converts a permission prompt result into a decision.
+// Cancelling the prompt denies the call.
+func permissionDecision(resp app.PromptResponse) kit.PermissionDecision {
+	if resp.Cancelled {
+		return kit.PermissionDenyOnce
+	}
+	switch resp.Index {
+	case 0:
+		return kit.PermissionAllowOnce
+	case 1:
+		return kit.PermissionAllowSession
+	case 2:
+		return kit.PermissionAllowProject
+	default:
+		return kit.PermissionDenyOnce
+	}
+}
+
+// permissionLabel describes a tool call awaiting approval, e.g.
+// "bash: go test ./...".
+func permissionLabel(toolName, subject string) string {
+	if subject == "" {
+		return toolName
+	}
+	return toolName + ": " + subject
+}
+
 // newPasswordPrompt creates a prompt overlay for password input (masked).
 func newPasswordPrompt(message string, width, height int) *promptOverlay {
 	ta := textarea.New()
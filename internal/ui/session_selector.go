@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"charm.land/bubbles/v2/key"
 	tea "charm.land/bubbletea/v2"
@@ -17,7 +18,8 @@ import (
 
 // SessionSelectedMsg is sent when the user selects a session from the picker.
 type SessionSelectedMsg struct {
-	Path string // absolute path to the JSONL session file
+	Path    string // absolute path to the JSONL session file
+	EntryID string // entry a search matched, to jump to once loaded; may be empty
 }
 
 // SessionSelectorCancelledMsg is sent when the user cancels the picker.
@@ -62,8 +64,13 @@ func (m SessionFilterMode) String() string {
 // controlCharsRe matches ASCII control characters for stripping from previews.
 var controlCharsRe = regexp.MustCompile(`[\x00-\x1f\x7f]`)
 
+// minHistorySearchLen is the shortest query searched through session
+// history. Shorter queries match nearly every session, so they only filter
+// on names, first messages and directories.
+const minHistorySearchLen = 2
+
 // SessionStore is the slice of the app layer the session picker needs: it
-// lists the sessions on disk and deletes them. The picker owns the loading
+// lists, searches and deletes the sessions on disk. The picker owns the loading
 // itself (rather than being handed a list) because it reloads across scope
 // toggles and mutates the list in place after a delete.
 type SessionStore interface {
@@ -75,6 +82,9 @@ type SessionStore interface {
 	ListAllSessions() ([]app.SessionSummary, error)
 	// DeleteSession removes a session file from disk.
 	DeleteSession(path string) error
+	// SearchSessions runs a full-text query over the history of every
+	// session on disk.
+	SearchSessions(query string) ([]app.SessionSearchResult, error)
 }
 
 // SessionSelectorComponent is a Bubble Tea component that lets the user browse
@@ -94,6 +104,10 @@ type SessionSelectorComponent struct {
 	// currentPath is the active session file path for marking it in the list.
 	currentPath string
 
+	// matches holds the first history match per session path for the
+	// current search query.
+	matches map[string]app.SessionSearchMatch
+
 	popup  *PopupList
 	width  int
 	height int
@@ -141,6 +155,7 @@ func NewSessionSelector(store SessionStore, cwd string, width, height int) *Sess
 	ss.popup.FullScreen = true
 	ss.popup.FooterHint = "↑↓ nav · ↵ open · esc cancel · tab scope · ^N named · d delete · type to search"
 	ss.popup.RenderItem = ss.renderEntry
+	ss.popup.FilterFunc = ss.filterSessions
 
 	ss.rebuild()
 	return ss
@@ -239,9 +254,13 @@ func (ss *SessionSelectorComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cursor := ss.popup.Cursor()
 			if cursor < len(ss.filtered) {
 				info := ss.filtered[cursor]
+				entryID := ""
+				if match, ok := ss.searchMatch(info.Path); ok {
+					entryID = match.EntryID
+				}
 				ss.active = false
 				return ss, func() tea.Msg {
-					return SessionSelectedMsg{Path: info.Path}
+					return SessionSelectedMsg{Path: info.Path, EntryID: entryID}
 				}
 			}
 		}
@@ -333,6 +352,58 @@ func (ss *SessionSelectorComponent) syncFiltered() {
 	ss.filtered = out
 }
 
+// filterSessions is the FilterFunc handed to PopupList. Sessions whose
+// name, first message or directory match the query come first, ranked as
+// the default filter ranks them. Sessions whose history matches through the
+// search index follow in list order. Either way, a session's first history
+// match is remembered so its row can show it and selecting the row jumps
+// to it.
+func (ss *SessionSelectorComponent) filterSessions(query string, items []PopupItem) []PopupItem {
+	out := defaultFilter(query, items)
+	ss.matches = nil
+	if utf8.RuneCountInString(strings.TrimSpace(query)) < minHistorySearchLen {
+		return out
+	}
+	results, err := ss.store.SearchSessions(query)
+	if err != nil {
+		log.Warn("session picker: searching sessions failed", "query", query, "err", err)
+		return out
+	}
+	ss.matches = make(map[string]app.SessionSearchMatch, len(results))
+	for _, r := range results {
+		if len(r.Matches) > 0 {
+			ss.matches[r.Session.Path] = r.Matches[0]
+		}
+	}
+
+	listed := make(map[string]bool, len(out))
+	for _, it := range out {
+		if s, ok := it.Meta.(app.SessionSummary); ok {
+			listed[s.Path] = true
+		}
+	}
+	for _, it := range items {
+		s, ok := it.Meta.(app.SessionSummary)
+		if !ok || listed[s.Path] {
+			continue
+		}
+		if _, hit := ss.matches[s.Path]; hit {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// searchMatch returns the history match for the session at path, if the
+// current search found one.
+func (ss *SessionSelectorComponent) searchMatch(path string) (app.SessionSearchMatch, bool) {
+	if !ss.popup.IsSearching() {
+		return app.SessionSearchMatch{}, false
+	}
+	match, ok := ss.matches[path]
+	return match, ok
+}
+
 func (ss *SessionSelectorComponent) removeSession(path string) {
 	ss.cwdSessions = removeByPath(ss.cwdSessions, path)
 	ss.allSessions = removeByPath(ss.allSessions, path)
@@ -351,6 +422,8 @@ func removeByPath(sessions []app.SessionSummary, path string) []app.SessionSumma
 // renderEntry is the RenderItem callback handed to PopupList. It produces a
 // single-line entry with left-aligned message text and right-aligned
 // metadata (message count + relative time, plus optional cwd in "All" scope).
+// While a search matches the session's history, the text is followed by the
+// matching snippet with the query terms highlighted.
 //
 // When isCursor we return a plain (unstyled) string so PopupList's outer
 // row style can paint one continuous fg+bg span. Mixing inner lipgloss
@@ -384,17 +457,42 @@ func (ss *SessionSelectorComponent) renderEntry(item PopupItem, innerWidth int,
 	// Message text width: innerWidth minus indicator(2) minus right minus gap(2).
 	availForMsg := max(innerWidth-2-rightW-2, 10)
 
+	match, hasMatch := ss.searchMatch(info.Path)
+	availForName := availForMsg
+	if hasMatch {
+		// The name keeps at most a third of the room; the snippet gets the rest.
+		availForName = max(availForMsg/3, 10)
+	}
+
 	displayText := sessionDisplayName(info)
 	displayText = controlCharsRe.ReplaceAllString(displayText, " ")
 	displayText = strings.Join(strings.Fields(displayText), " ")
-	displayText = truncateRunes(displayText, availForMsg)
+	displayText = truncateRunes(displayText, availForName)
+
+	// The snippet is cut from the match, so its highlight offsets stay valid
+	// as long as it is only truncated at the end.
+	snippet, highlights := "", [][2]int(nil)
+	if hasMatch {
+		snippet = controlCharsRe.ReplaceAllString(match.Snippet, " ")
+		availForSnippet := availForMsg - lipgloss.Width(displayText) - 2
+		if availForSnippet > 3 {
+			snippet = truncateRunes(snippet, availForSnippet)
+			highlights = match.Highlights
+		} else {
+			snippet = ""
+		}
+	}
+	sep := ""
+	if snippet != "" {
+		sep = "  "
+	}
 
-	msgW := lipgloss.Width(displayText)
+	msgW := lipgloss.Width(displayText + sep + snippet)
 	spacing := max(innerWidth-2-msgW-rightW, 1)
 
 	// Selected row: raw string, outer row style paints it.
 	if isCursor {
-		return indicator + displayText + strings.Repeat(" ", spacing) + right
+		return indicator + displayText + sep + snippet + strings.Repeat(" ", spacing) + right
 	}
 
 	// Color the message text by state.
@@ -415,7 +513,38 @@ func (ss *SessionSelectorComponent) renderEntry(item PopupItem, innerWidth int,
 		rightStyle = lipgloss.NewStyle().Foreground(theme.Muted)
 	}
 
-	return indicator + msgStyle.Render(displayText) + strings.Repeat(" ", spacing) + rightStyle.Render(right)
+	snippetText := renderSnippet(snippet, highlights,
+		lipgloss.NewStyle().Foreground(theme.Muted),
+		lipgloss.NewStyle().Foreground(theme.Accent).Bold(true))
+
+	return indicator + msgStyle.Render(displayText) + sep + snippetText + strings.Repeat(" ", spacing) + rightStyle.Render(right)
+}
+
+// renderSnippet styles the highlighted byte ranges of snippet with hl and
+// the rest with base. Ranges past the end of a truncated snippet are
+// dropped, and the "…" truncateRunes appends is never highlighted.
+func renderSnippet(snippet string, highlights [][2]int, base, hl lipgloss.Style) string {
+	if snippet == "" {
+		return ""
+	}
+	limit := len(strings.TrimSuffix(snippet, "…"))
+	var sb strings.Builder
+	pos := 0
+	for _, h := range highlights {
+		start, end := h[0], min(h[1], limit)
+		if start < pos || start >= end {
+			continue
+		}
+		if start > pos {
+			sb.WriteString(base.Render(snippet[pos:start]))
+		}
+		sb.WriteString(hl.Render(snippet[start:end]))
+		pos = end
+	}
+	if pos < len(snippet) {
+		sb.WriteString(base.Render(snippet[pos:]))
+	}
+	return sb.String()
 }
 
 // --- Package helpers ---
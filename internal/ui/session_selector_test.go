@@ -2,10 +2,13 @@ package ui
 
 import (
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	xansi "github.com/charmbracelet/x/ansi"
 
 	"github.com/mark3labs/kit/internal/app"
 )
@@ -19,11 +22,14 @@ type stubSessionStore struct {
 	cwdSessions []app.SessionSummary
 	allSessions []app.SessionSummary
 
+	searchResults []app.SessionSearchResult
+
 	listErr   error
 	deleteErr error
 
-	cwdArg  string
-	deleted []string
+	cwdArg   string
+	deleted  []string
+	searched []string
 }
 
 func (s *stubSessionStore) ListSessions(cwd string) ([]app.SessionSummary, error) {
@@ -43,6 +49,11 @@ func (s *stubSessionStore) DeleteSession(path string) error {
 	return nil
 }
 
+func (s *stubSessionStore) SearchSessions(query string) ([]app.SessionSearchResult, error) {
+	s.searched = append(s.searched, query)
+	return s.searchResults, nil
+}
+
 // summary builds a SessionSummary with a distinct modification time so
 // ordering is observable.
 func summary(path, name, first string) app.SessionSummary {
@@ -287,6 +298,56 @@ func TestSessionSelectorDeleteErrorKeepsSession(t *testing.T) {
 	}
 }
 
+// --------------------------------------------------------------------------
+// History search
+// --------------------------------------------------------------------------
+
+func TestSessionSelectorSearchesHistory(t *testing.T) {
+	match := app.SessionSearchMatch{EntryID: "e7", Kind: "user", Snippet: "now fix the parser", Highlights: [][2]int{{12, 18}}}
+	store := &stubSessionStore{
+		allSessions: []app.SessionSummary{
+			summary("/a.jsonl", "", "hello"),
+			summary("/b.jsonl", "", "add a rate limiter"),
+		},
+		searchResults: []app.SessionSearchResult{{
+			Session: summary("/b.jsonl", "", "add a rate limiter"),
+			Matches: []app.SessionSearchMatch{match},
+		}},
+	}
+	ss := NewSessionSelector(store, "", 80, 24)
+
+	ss.Update(keyPress("p"))
+	if len(store.searched) != 0 {
+		t.Errorf("searched history for %q, want single characters left to the list filter", store.searched)
+	}
+	for _, c := range "arser" {
+		ss.Update(keyPress(string(c)))
+	}
+	if len(ss.filtered) != 1 || ss.filtered[0].Path != "/b.jsonl" {
+		t.Fatalf("filtered = %+v, want the session whose history matched", ss.filtered)
+	}
+	if row := ss.renderEntry(ss.popup.Items()[0], 76, true); !strings.Contains(row, "now fix the parser") {
+		t.Errorf("row %q does not show the snippet", row)
+	}
+
+	_, cmd := ss.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected a command on selection")
+	}
+	if msg, ok := cmd().(SessionSelectedMsg); !ok || msg.Path != "/b.jsonl" || msg.EntryID != "e7" {
+		t.Errorf("got %+v, want a selection that jumps to entry e7", cmd())
+	}
+}
+
+func TestRenderSnippet(t *testing.T) {
+	mark := lipgloss.NewStyle().Transform(func(s string) string { return "[" + s + "]" })
+	got := xansi.Strip(renderSnippet("fix the pars…", [][2]int{{0, 3}, {8, 14}}, lipgloss.NewStyle(), mark))
+	// The second highlight is clipped where the snippet was truncated.
+	if want := "[fix] the [pars]…"; got != want {
+		t.Errorf("renderSnippet = %q, want %q", got, want)
+	}
+}
+
 // --------------------------------------------------------------------------
 // Display
 // --------------------------------------------------------------------------
@@ -89,6 +89,11 @@ var toolBodyCases = []struct {
 		args:   `{"path":"a.go","edits":[{"old_text":"one","new_text":"two"}]}`,
 		result: "edited",
 	},
+	{
+		name: "apply_patch", tool: "apply_patch",
+		args:   `{"patch":"*** Begin Patch\n*** Add File: a.go\n+package a\n*** Update File: b.go\n@@\n one\n-two\n+three\n*** End Patch"}`,
+		result: "Applied patch to 2 files:\nA a.go\nM b.go\n\n--- b.go\n+++ b.go\n@@ -4,2 +4,2 @@\n one\n-two\n+three\n",
+	},
 }
 
 // TestToolBodyStartsAtContentColumn pins every tool body to the same left
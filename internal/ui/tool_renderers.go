@@ -13,6 +13,7 @@ import (
 	xansi "github.com/charmbracelet/x/ansi"
 	"github.com/indaco/herald"
 
+	"github.com/mark3labs/kit/internal/core"
 	"github.com/mark3labs/kit/internal/ui/style"
 )
 
@@ -177,6 +178,10 @@ func renderToolBodyLimited(toolName, toolArgs, toolResult string, width int, lim
 		if body := renderEditBody(toolArgs, toolResult, width, lim.diff); body != "" {
 			return body
 		}
+	case toolName == "apply_patch":
+		if body := renderApplyPatchBody(toolArgs, toolResult, width, lim); body != "" {
+			return body
+		}
 	case toolName == "ls":
 		if body := renderLsBody(toolResult, width, lim.list); body != "" {
 			return body
@@ -248,6 +253,72 @@ func renderEditBody(toolArgs, toolResult string, width, maxLines int) string {
 	return ""
 }
 
+// ---------------------------------------------------------------------------
+// Apply patch tool — per-file headers with side-by-side diffs
+// ---------------------------------------------------------------------------
+
+// renderApplyPatchBody renders each file of an apply_patch call under a
+// header naming the change: diffs for modified files, the content of added
+// ones. Hunks come from the unified diff in the result when present, since
+// it carries the real line numbers, and from the patch itself otherwise.
+func renderApplyPatchBody(toolArgs, toolResult string, width int, lim toolLineLimits) string {
+	var args struct {
+		Patch string `json:"patch"`
+	}
+	if err := json.Unmarshal([]byte(toolArgs), &args); err != nil {
+		return ""
+	}
+	files, err := core.ParsePatch(args.Patch)
+	if err != nil {
+		return ""
+	}
+	applied := make(map[string]core.FilePatch)
+	if _, diff, ok := strings.Cut(toolResult, "\n\n"); ok {
+		if parsed, err := core.ParsePatch(diff); err == nil {
+			for _, f := range parsed {
+				applied[f.Path] = f
+			}
+		}
+	}
+
+	headerStyle := lipgloss.NewStyle().Foreground(GetTheme().Muted)
+	headerWidth := max(width-style.ContentOffset, style.MinContentWidth)
+	var results []string
+	for _, f := range files {
+		var header string
+		switch {
+		case f.Op == core.PatchAdd:
+			header = "Added " + f.Path
+		case f.Op == core.PatchDelete:
+			header = "Deleted " + f.Path
+		case f.MoveTo != "":
+			header = "Renamed " + f.Path + " → " + f.MoveTo
+		default:
+			header = "Modified " + f.Path
+		}
+		results = append(results, toolIndent()+headerStyle.Render(truncateLine(header, headerWidth)))
+
+		switch f.Op {
+		case core.PatchAdd:
+			if content := f.Hunks[0].NewText(); content != "" {
+				results = append(results, renderWriteBlock(content, f.Path, width, lim.write))
+			}
+		case core.PatchUpdate:
+			hunks := f.Hunks
+			if a, ok := applied[f.Path]; ok {
+				hunks = a.Hunks
+			}
+			for _, h := range hunks {
+				start := max(h.OldStart, 1)
+				if diff := renderDiffBlock(h.OldText(), h.NewText(), start, width, lim.diff); diff != "" {
+					results = append(results, diff)
+				}
+			}
+		}
+	}
+	return strings.Join(results, "\n")
+}
+
 // diffHunkPattern matches the first @@ hunk header in a unified diff.
 // Package-level so it compiles once, not on every edit-tool render.
 var diffHunkPattern = regexp.MustCompile(`@@ -(\d+)`)
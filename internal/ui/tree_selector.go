@@ -68,6 +68,7 @@ type TreeSelectorComponent struct {
 	active     bool
 	selectedID string // set when user selects a node
 	cancelled  bool
+	rewind     bool // selection rewinds files and conversation (/rewind)
 }
 
 // NewTreeSelector creates a tree selector over a session tree snapshot.
@@ -116,6 +117,41 @@ func NewTreeSelectorForFork(roots []app.TreeNodeView, leafID string, width, heig
 	return ts
 }
 
+// NewTreeSelectorForRewind creates a tree selector for the /rewind command.
+// Like the fork selector it lists user messages; selecting one restores the
+// conversation and the working tree to the moment before it was sent.
+func NewTreeSelectorForRewind(roots []app.TreeNodeView, leafID string, width, height int) *TreeSelectorComponent {
+	ts := NewTreeSelectorForFork(roots, leafID, width, height)
+	ts.rewind = true
+	ts.popup.Title = "Rewind"
+	ts.popup.Subtitle = "Restore files and conversation to before the selected message"
+	return ts
+}
+
+// FocusEntry moves the cursor to the entry with the given ID, switching to
+// the "all" filter when the current one hides it. It reports whether the
+// entry was found; if not, the selector is left as it was.
+func (ts *TreeSelectorComponent) FocusEntry(id string) bool {
+	filter := ts.filter
+	for _, f := range []TreeFilterMode{filter, TreeFilterAll} {
+		if f != ts.filter {
+			ts.filter = f
+			ts.rebuild()
+		}
+		for i, node := range ts.flatNodes {
+			if node.ID == id {
+				ts.popup.SetCursor(i)
+				return true
+			}
+		}
+	}
+	if ts.filter != filter {
+		ts.filter = filter
+		ts.rebuild()
+	}
+	return false
+}
+
 func (ts *TreeSelectorComponent) initPopup() {
 	ts.popup = NewPopupList("Session Tree", nil, ts.width, ts.height)
 	ts.popup.FullScreen = true
@@ -194,6 +230,7 @@ func (ts *TreeSelectorComponent) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						ParentID: node.ParentID,
 						IsUser:   node.Node.IsUserMessage(),
 						UserText: userText(node.Node),
+						Rewind:   ts.rewind,
 					}
 				}
 			}
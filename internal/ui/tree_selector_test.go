@@ -203,6 +203,30 @@ func TestTreeSelectorFlattensNestedTree(t *testing.T) {
 	}
 }
 
+func TestTreeSelectorFocusEntry(t *testing.T) {
+	tree := []app.TreeNodeView{{
+		ID: "u1", Kind: app.EntryKindMessage, Role: "user", Text: "hi",
+		Children: []app.TreeNodeView{{
+			ID: "t1", ParentID: "u1", Kind: app.EntryKindMessage, Role: "tool",
+			Children: []app.TreeNodeView{{
+				ID: "a1", ParentID: "t1", Kind: app.EntryKindMessage, Role: "assistant", Text: "hello",
+			}},
+		}},
+	}}
+	ts := NewTreeSelector(tree, "a1", 80, 24)
+
+	if !ts.FocusEntry("u1") || ts.flatNodes[ts.popup.Cursor()].ID != "u1" || ts.filter != TreeFilterDefault {
+		t.Errorf("FocusEntry(u1): cursor on %q, filter %s", ts.flatNodes[ts.popup.Cursor()].ID, ts.filter)
+	}
+	// The default filter hides the tool message, so focusing it shows all.
+	if !ts.FocusEntry("t1") || ts.flatNodes[ts.popup.Cursor()].ID != "t1" || ts.filter != TreeFilterAll {
+		t.Errorf("FocusEntry(t1): cursor on %q, filter %s", ts.flatNodes[ts.popup.Cursor()].ID, ts.filter)
+	}
+	if ts.FocusEntry("missing") || ts.filter != TreeFilterAll {
+		t.Errorf("FocusEntry(missing) should fail and keep filter %s", ts.filter)
+	}
+}
+
 func TestNewTreeSelectorForForkStartsOnLastUserMessage(t *testing.T) {
 	tree := []app.TreeNodeView{{
 		ID: "u1", Kind: app.EntryKindMessage, Role: "user", Text: "first",
@@ -0,0 +1,39 @@
+package usage
+
+import "errors"
+
+// DefaultWarnAt is the fraction of a limit at which a budget warning fires
+// when Budget.WarnAt is unset.
+const DefaultWarnAt = 0.8
+
+// Budget is the "budget" block of .kit.yml: spend limits, in US dollars,
+// for the project the config applies to. Zero limits are unset.
+type Budget struct {
+	// Daily caps the project's spend since local midnight.
+	Daily float64 `json:"daily,omitempty" yaml:"daily,omitempty"`
+	// Monthly caps the project's spend since the first of the month.
+	Monthly float64 `json:"monthly,omitempty" yaml:"monthly,omitempty"`
+	// WarnAt is the fraction of a limit, between 0 and 1, at which a
+	// warning is raised before the limit stops the agent. Defaults to
+	// DefaultWarnAt.
+	WarnAt float64 `json:"warnAt,omitempty" yaml:"warnAt,omitempty"`
+}
+
+// Validate reports negative limits and an out-of-range WarnAt.
+func (b Budget) Validate() error {
+	if b.Daily < 0 || b.Monthly < 0 {
+		return errors.New("limits must not be negative")
+	}
+	if b.WarnAt < 0 || b.WarnAt > 1 {
+		return errors.New("warnAt must be between 0 and 1")
+	}
+	return nil
+}
+
+// WarnFraction returns WarnAt, or DefaultWarnAt when it is unset.
+func (b Budget) WarnFraction() float64 {
+	if b.WarnAt == 0 {
+		return DefaultWarnAt
+	}
+	return b.WarnAt
+}
@@ -0,0 +1,164 @@
+// Package usage records what each LLM call cost in a ledger shared by every
+// Kit run on the machine, and summarizes it into reports and budget checks.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ledgerFileName is the basename of the ledger. It lives next to the
+// sessions directory in ~/.kit.
+const ledgerFileName = "usage.jsonl"
+
+// Entry is one LLM call in the ledger.
+type Entry struct {
+	Time             time.Time `json:"time"`
+	SessionID        string    `json:"session_id,omitempty"`
+	Project          string    `json:"project,omitempty"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	InputTokens      int64     `json:"input_tokens"`
+	OutputTokens     int64     `json:"output_tokens"`
+	CacheReadTokens  int64     `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int64     `json:"cache_write_tokens,omitempty"`
+	// CostUSD is zero for unpriced models and subscription (OAuth)
+	// credentials.
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// Filter selects ledger entries. Zero fields match everything.
+type Filter struct {
+	Since     time.Time // inclusive
+	Until     time.Time // exclusive
+	Project   string
+	SessionID string
+}
+
+func (f Filter) match(e Entry) bool {
+	switch {
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	case f.Project != "" && e.Project != f.Project:
+		return false
+	case f.SessionID != "" && e.SessionID != f.SessionID:
+		return false
+	}
+	return true
+}
+
+// Ledger is an append-only JSONL file of Entry records. Each entry is
+// written with a single append, so concurrent Kit processes can share one
+// ledger. The zero value is not usable — construct one with Open.
+type Ledger struct {
+	mu   sync.Mutex
+	path string
+}
+
+// DefaultPath returns ~/.kit/usage.jsonl, or the empty string when no home
+// directory can be determined.
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kit", ledgerFileName)
+}
+
+// Open returns the ledger at path. Pass an empty path to use DefaultPath.
+// The file is created on the first Append.
+func Open(path string) *Ledger {
+	if path == "" {
+		path = DefaultPath()
+	}
+	return &Ledger{path: path}
+}
+
+// Path returns the ledger file path.
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Append records e. The project directory is normalized so entries from a
+// symlinked checkout land in the same project.
+func (l *Ledger) Append(e Entry) error {
+	if l.path == "" {
+		return nil
+	}
+	e.Project = NormalizeProject(e.Project)
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns the entries matching f in file order. A missing ledger has
+// no entries; malformed lines (a write torn by a crash) are skipped.
+func (l *Ledger) Read(f Filter) ([]Entry, error) {
+	if l.path == "" {
+		return nil, nil
+	}
+	f.Project = NormalizeProject(f.Project)
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if f.match(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// NormalizeProject resolves dir to an absolute, symlink-evaluated path so
+// entries and filters compare equal. It falls back to the cleaned input
+// when resolution fails.
+func NormalizeProject(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return filepath.Clean(dir)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
@@ -0,0 +1,132 @@
+package usage
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Report groupings accepted by Summarize.
+const (
+	ByDay     = "day"
+	ByWeek    = "week"
+	ByMonth   = "month"
+	ByModel   = "model"
+	ByProject = "project"
+	BySession = "session"
+)
+
+// Groupings lists the values accepted by Summarize, for flag help.
+var Groupings = []string{ByDay, ByWeek, ByMonth, ByModel, ByProject, BySession}
+
+// Row is one line of a report: the entries sharing a key, totalled.
+type Row struct {
+	Key              string  `json:"key"`
+	Requests         int     `json:"requests"`
+	InputTokens      int64   `json:"input_tokens"`
+	OutputTokens     int64   `json:"output_tokens"`
+	CacheReadTokens  int64   `json:"cache_read_tokens"`
+	CacheWriteTokens int64   `json:"cache_write_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+func (r *Row) add(e Entry) {
+	r.Requests++
+	r.InputTokens += e.InputTokens
+	r.OutputTokens += e.OutputTokens
+	r.CacheReadTokens += e.CacheReadTokens
+	r.CacheWriteTokens += e.CacheWriteTokens
+	r.CostUSD += e.CostUSD
+}
+
+// Summarize groups entries by one of the Groupings. Time groupings use the
+// local calendar (weeks are ISO weeks, e.g. "2026-W07") and are sorted
+// oldest first; the others are sorted by cost, highest first.
+func Summarize(entries []Entry, by string) ([]Row, error) {
+	key, err := groupKey(by)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int)
+	var rows []Row
+	for _, e := range entries {
+		k := key(e)
+		i, ok := index[k]
+		if !ok {
+			i = len(rows)
+			index[k] = i
+			rows = append(rows, Row{Key: k})
+		}
+		rows[i].add(e)
+	}
+	switch by {
+	case ByDay, ByWeek, ByMonth:
+		slices.SortFunc(rows, func(a, b Row) int { return strings.Compare(a.Key, b.Key) })
+	default:
+		slices.SortStableFunc(rows, func(a, b Row) int {
+			switch {
+			case a.CostUSD > b.CostUSD:
+				return -1
+			case a.CostUSD < b.CostUSD:
+				return 1
+			}
+			return strings.Compare(a.Key, b.Key)
+		})
+	}
+	return rows, nil
+}
+
+// Total sums rows into a single row keyed "total".
+func Total(rows []Row) Row {
+	total := Row{Key: "total"}
+	for _, r := range rows {
+		total.Requests += r.Requests
+		total.InputTokens += r.InputTokens
+		total.OutputTokens += r.OutputTokens
+		total.CacheReadTokens += r.CacheReadTokens
+		total.CacheWriteTokens += r.CacheWriteTokens
+		total.CostUSD += r.CostUSD
+	}
+	return total
+}
+
+func groupKey(by string) (func(Entry) string, error) {
+	switch by {
+	case ByDay:
+		return func(e Entry) string { return e.Time.Local().Format(time.DateOnly) }, nil
+	case ByWeek:
+		return func(e Entry) string {
+			year, week := e.Time.Local().ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}, nil
+	case ByMonth:
+		return func(e Entry) string { return e.Time.Local().Format("2006-01") }, nil
+	case ByModel:
+		return func(e Entry) string { return e.Provider + "/" + e.Model }, nil
+	case ByProject:
+		return func(e Entry) string { return orUnknown(e.Project) }, nil
+	case BySession:
+		return func(e Entry) string { return orUnknown(e.SessionID) }, nil
+	}
+	return nil, fmt.Errorf("unknown grouping %q (want one of %s)", by, strings.Join(Groupings, ", "))
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// StartOfDay returns local midnight on t's day.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Local().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// StartOfMonth returns local midnight on the first day of t's month.
+func StartOfMonth(t time.Time) time.Time {
+	y, m, _ := t.Local().Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.Local)
+}
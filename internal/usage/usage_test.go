@@ -0,0 +1,104 @@
+package usage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLedger_AppendRead(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "usage.jsonl")
+	l := Open(path)
+
+	if entries, err := l.Read(Filter{}); err != nil || entries != nil {
+		t.Fatalf("Read of missing ledger = %v, %v", entries, err)
+	}
+
+	day := time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Time: day, SessionID: "a", Project: dir, Provider: "anthropic", Model: "m1", InputTokens: 10, CostUSD: 0.5},
+		{Time: day.AddDate(0, 0, 1), SessionID: "b", Project: filepath.Join(dir, "other"), Provider: "openai", Model: "m2", OutputTokens: 5, CostUSD: 0.25},
+		{Time: day.AddDate(0, 0, 2), SessionID: "a", Project: dir, Provider: "anthropic", Model: "m1", InputTokens: 1, CostUSD: 1},
+	} {
+		if err := l.Append(e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	// A torn line is skipped.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = f.WriteString(`{"time":"2026-03-`)
+	_ = f.Close()
+
+	all, err := l.Read(Filter{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Read all = %d entries, %v", len(all), err)
+	}
+	if got, _ := l.Read(Filter{Project: dir}); len(got) != 2 {
+		t.Errorf("project filter matched %d entries, want 2", len(got))
+	}
+	if got, _ := l.Read(Filter{Since: day.AddDate(0, 0, 1), Until: day.AddDate(0, 0, 2)}); len(got) != 1 || got[0].SessionID != "b" {
+		t.Errorf("time filter = %+v", got)
+	}
+	if got, _ := l.Read(Filter{SessionID: "a"}); len(got) != 2 {
+		t.Errorf("session filter matched %d entries, want 2", len(got))
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	mon := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local) // ISO week 10
+	entries := []Entry{
+		{Time: mon.AddDate(0, 0, 7), Provider: "openai", Model: "m2", InputTokens: 1, CostUSD: 3},
+		{Time: mon, Provider: "anthropic", Model: "m1", InputTokens: 10, OutputTokens: 2, CostUSD: 1},
+		{Time: mon.Add(time.Hour), Provider: "anthropic", Model: "m1", InputTokens: 5, CostUSD: 1},
+	}
+
+	days, err := Summarize(entries, ByDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 2 || days[0].Key != "2026-03-02" || days[0].Requests != 2 || days[0].InputTokens != 15 || days[0].CostUSD != 2 {
+		t.Errorf("by day = %+v", days)
+	}
+
+	weeks, _ := Summarize(entries, ByWeek)
+	if len(weeks) != 2 || weeks[0].Key != "2026-W10" || weeks[1].Key != "2026-W11" {
+		t.Errorf("by week = %+v", weeks)
+	}
+
+	// Non-time groupings are ordered by cost.
+	byModel, _ := Summarize(entries, ByModel)
+	if len(byModel) != 2 || byModel[0].Key != "openai/m2" || byModel[1].Key != "anthropic/m1" {
+		t.Errorf("by model = %+v", byModel)
+	}
+
+	if total := Total(days); total.Requests != 3 || total.CostUSD != 5 || total.OutputTokens != 2 {
+		t.Errorf("total = %+v", total)
+	}
+
+	if _, err := Summarize(entries, "hour"); err == nil {
+		t.Error("Summarize accepted an unknown grouping")
+	}
+}
+
+func TestBudget_Validate(t *testing.T) {
+	tests := []struct {
+		budget Budget
+		ok     bool
+	}{
+		{Budget{}, true},
+		{Budget{Daily: 5, Monthly: 50, WarnAt: 0.5}, true},
+		{Budget{Daily: -1}, false},
+		{Budget{WarnAt: 1.5}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.budget.Validate(); (err == nil) != tt.ok {
+			t.Errorf("Validate(%+v) = %v", tt.budget, err)
+		}
+	}
+	if got := (Budget{}).WarnFraction(); got != DefaultWarnAt {
+		t.Errorf("default WarnFraction = %v", got)
+	}
+}
@@ -0,0 +1,368 @@
+package kit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/kit/internal/usage"
+)
+
+// ---------------------------------------------------------------------------
+// Usage ledger and spend budgets
+// ---------------------------------------------------------------------------
+
+// ErrBudgetExceeded is returned by the prompt methods once a spend limit
+// ([Options.MaxCostUSD] or a limit of the "budget" config block) has been
+// reached. A turn in progress stops after the LLM call that crossed the
+// limit; later turns are refused before calling the model.
+var ErrBudgetExceeded = errors.New("cost budget exceeded")
+
+// Budget holds daily and monthly spend limits for a project. It has the
+// same shape as the "budget" block of .kit.yml.
+type Budget = usage.Budget
+
+// Scopes reported by BudgetWarningEvent.
+const (
+	// BudgetScopeRun is the cap on what one Kit instance may spend
+	// ([Options.MaxCostUSD] / "max-cost").
+	BudgetScopeRun = "run"
+	// BudgetScopeDaily is the project's limit for the current local day.
+	BudgetScopeDaily = "daily"
+	// BudgetScopeMonthly is the project's limit for the current month.
+	BudgetScopeMonthly = "monthly"
+)
+
+// budgetLimit is one spend limit and how far along it is.
+type budgetLimit struct {
+	scope string
+	limit float64
+	// base is the project's spend in the limit's window recorded before the
+	// current turn. Unused for the run scope.
+	base     float64
+	warned   bool
+	exceeded bool
+}
+
+// spendTracker prices each LLM call, records it in the usage ledger and
+// enforces the spend limits. All methods are safe for concurrent use.
+type spendTracker struct {
+	mu      sync.Mutex
+	ledger  *usage.Ledger // nil when the ledger is disabled
+	project string
+	warnAt  float64
+	limits  []*budgetLimit
+
+	spent     float64 // total since New
+	turnSpent float64 // since the current turn started
+
+	// parent is the tracker of the Kit that spawned this one as a
+	// subagent. Its limits apply here too. Nil for top-level instances.
+	parent *spendTracker
+
+	// cancel stops the running turn with ErrBudgetExceeded as its cause.
+	// Nil while idle.
+	cancel context.CancelCauseFunc
+}
+
+func newSpendTracker(ledger *usage.Ledger, project string, maxCost float64, budget Budget) *spendTracker {
+	t := &spendTracker{ledger: ledger, project: project, warnAt: budget.WarnFraction()}
+	if maxCost > 0 {
+		t.limits = append(t.limits, &budgetLimit{scope: BudgetScopeRun, limit: maxCost})
+	}
+	if budget.Daily > 0 {
+		t.limits = append(t.limits, &budgetLimit{scope: BudgetScopeDaily, limit: budget.Daily})
+	}
+	if budget.Monthly > 0 {
+		t.limits = append(t.limits, &budgetLimit{scope: BudgetScopeMonthly, limit: budget.Monthly})
+	}
+	return t
+}
+
+// spentIn returns the spend counted against l.
+func (t *spendTracker) spentIn(l *budgetLimit) float64 {
+	if l.scope == BudgetScopeRun {
+		return t.spent
+	}
+	return l.base + t.turnSpent
+}
+
+// beginTurn refreshes the daily and monthly spend from the ledger, so spend
+// by other Kit processes in the project counts too, and returns
+// ErrBudgetExceeded when a limit is already used up. On success cancel is
+// armed to stop the turn once a limit is crossed.
+func (t *spendTracker) beginTurn(cancel context.CancelCauseFunc) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.parent.exhausted(); err != nil {
+		return err
+	}
+	t.refreshLocked()
+	for _, l := range t.limits {
+		spent := t.spentIn(l)
+		if spent >= l.limit {
+			return budgetError(l, spent)
+		}
+		// A new day or month re-arms the warnings of its limit.
+		l.exceeded = false
+		l.warned = spent >= l.limit*t.warnAt
+	}
+	t.cancel = cancel
+	return nil
+}
+
+// allow refreshes the daily and monthly spend like beginTurn and returns
+// ErrBudgetExceeded when a limit is used up. LLM calls made outside a turn,
+// or alongside one, check it before calling the model.
+func (t *spendTracker) allow() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.parent.exhausted(); err != nil {
+		return err
+	}
+	t.refreshLocked()
+	for _, l := range t.limits {
+		if spent := t.spentIn(l); spent >= l.limit {
+			return budgetError(l, spent)
+		}
+	}
+	return nil
+}
+
+// refreshLocked reloads the daily and monthly spend from the ledger and
+// restarts turnSpent, which the ledger now covers. t.mu must be held.
+func (t *spendTracker) refreshLocked() {
+	var daily, monthly float64
+	windowed := false
+	for _, l := range t.limits {
+		windowed = windowed || l.scope != BudgetScopeRun
+	}
+	if windowed && t.ledger != nil {
+		now := time.Now()
+		entries, err := t.ledger.Read(usage.Filter{Since: usage.StartOfMonth(now), Project: t.project})
+		if err != nil {
+			log.Printf("Warning: failed to read usage ledger: %v", err)
+		}
+		dayStart := usage.StartOfDay(now)
+		for _, e := range entries {
+			monthly += e.CostUSD
+			if !e.Time.Before(dayStart) {
+				daily += e.CostUSD
+			}
+		}
+	} else {
+		// Without a ledger only this instance's own spend is known.
+		daily, monthly = t.spent, t.spent
+	}
+	t.turnSpent = 0
+	for _, l := range t.limits {
+		switch l.scope {
+		case BudgetScopeDaily:
+			l.base = daily
+		case BudgetScopeMonthly:
+			l.base = monthly
+		}
+	}
+}
+
+// endTurn disarms the cancel function installed by beginTurn.
+func (t *spendTracker) endTurn() {
+	t.mu.Lock()
+	t.cancel = nil
+	t.mu.Unlock()
+}
+
+// add counts cost against every limit and returns the warnings to emit.
+// When a limit is crossed the running turn is cancelled.
+func (t *spendTracker) add(cost float64) []BudgetWarningEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spent += cost
+	t.turnSpent += cost
+
+	var warnings []BudgetWarningEvent
+	for _, l := range t.limits {
+		spent := t.spentIn(l)
+		switch {
+		case spent >= l.limit && !l.exceeded:
+			l.warned, l.exceeded = true, true
+			warnings = append(warnings, BudgetWarningEvent{Scope: l.scope, SpentUSD: spent, LimitUSD: l.limit, Exceeded: true})
+			if t.cancel != nil {
+				t.cancel(budgetError(l, spent))
+			}
+		case spent >= l.limit*t.warnAt && !l.warned:
+			l.warned = true
+			warnings = append(warnings, BudgetWarningEvent{Scope: l.scope, SpentUSD: spent, LimitUSD: l.limit})
+		}
+	}
+	// A parent whose limit is used up stops this turn too, even when the
+	// parent itself is idle, as it is while a background subagent runs.
+	if err := t.parent.exhausted(); err != nil && t.cancel != nil {
+		t.cancel(err)
+	}
+	return warnings
+}
+
+// exhausted returns the error for the first limit that is used up, or nil.
+// A nil tracker has no limits.
+func (t *spendTracker) exhausted() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, l := range t.limits {
+		if spent := t.spentIn(l); spent >= l.limit {
+			return budgetError(l, spent)
+		}
+	}
+	return t.parent.exhausted()
+}
+
+func (t *spendTracker) total() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.spent
+}
+
+func budgetError(l *budgetLimit, spent float64) error {
+	return fmt.Errorf("%w: %s limit of $%.2f reached ($%.2f spent)", ErrBudgetExceeded, l.scope, l.limit, spent)
+}
+
+// recordUsage is subscribed to StepUsageEvent. It prices the call, appends
+// it to the usage ledger and applies the spend limits.
+func (m *Kit) recordUsage(ev StepUsageEvent) {
+	m.chargeUsage(m.GetModelString(), LLMUsage{
+		InputTokens:         int64(ev.InputTokens),
+		OutputTokens:        int64(ev.OutputTokens),
+		CacheReadTokens:     int64(ev.CacheReadTokens),
+		CacheCreationTokens: int64(ev.CacheWriteTokens),
+	})
+}
+
+// chargeUsage prices one LLM call made with modelString, appends it to the
+// usage ledger and applies the spend limits.
+func (m *Kit) chargeUsage(modelString string, u LLMUsage) {
+	provider, modelID, cost := priceUsage(m, modelString, u)
+	if m.spend.ledger != nil {
+		err := m.spend.ledger.Append(usage.Entry{
+			Time:             time.Now(),
+			SessionID:        m.GetSessionID(),
+			Project:          m.spend.project,
+			Provider:         provider,
+			Model:            modelID,
+			InputTokens:      u.InputTokens,
+			OutputTokens:     u.OutputTokens,
+			CacheReadTokens:  u.CacheReadTokens,
+			CacheWriteTokens: u.CacheCreationTokens,
+			CostUSD:          cost,
+		})
+		if err != nil && m.v.GetBool("debug") {
+			log.Printf("DEBUG failed to record usage: %v", err)
+		}
+	}
+	m.addSpend(cost)
+}
+
+// addSpend counts cost against the spend limits and emits any warnings.
+// A subagent charges its parent first, as the cost is incurred, so the
+// parent's limits stop every running child rather than only being checked
+// once a child finishes.
+func (m *Kit) addSpend(cost float64) {
+	if m.parent != nil {
+		m.parent.addSpend(cost)
+	}
+	for _, w := range m.spend.add(cost) {
+		m.events.emit(w)
+	}
+}
+
+// GetCostUSD returns what this Kit instance has spent, in US dollars, since
+// New, including its subagents. Calls to unpriced models and calls made with
+// subscription (OAuth) credentials cost nothing.
+func (m *Kit) GetCostUSD() float64 {
+	return m.spend.total()
+}
+
+// meteredModel charges each call made through it like a step of the agent
+// loop. Kit wraps the model with it for the LLM calls StepUsageEvent does
+// not see: compaction, branch summaries, MCP sampling and extension
+// completions. A used-up limit refuses the call before it is made.
+type meteredModel struct {
+	fantasy.LanguageModel
+	kit *Kit
+	// modelString is the "provider/model" the calls are priced as.
+	modelString string
+}
+
+// metered wraps model so its calls count against m's spend limits.
+func (m *Kit) metered(model fantasy.LanguageModel, modelString string) fantasy.LanguageModel {
+	return meteredModel{LanguageModel: model, kit: m, modelString: modelString}
+}
+
+func (mm meteredModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	if err := mm.kit.spend.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := mm.LanguageModel.Generate(ctx, call)
+	if resp != nil {
+		mm.kit.chargeUsage(mm.modelString, resp.Usage)
+	}
+	return resp, err
+}
+
+func (mm meteredModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	if err := mm.kit.spend.allow(); err != nil {
+		return nil, err
+	}
+	stream, err := mm.LanguageModel.Stream(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	return func(yield func(fantasy.StreamPart) bool) {
+		for part := range stream {
+			if part.Type == fantasy.StreamPartTypeFinish {
+				mm.kit.chargeUsage(mm.modelString, part.Usage)
+			}
+			if !yield(part) {
+				return
+			}
+		}
+	}, nil
+}
+
+func (mm meteredModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	if err := mm.kit.spend.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := mm.LanguageModel.GenerateObject(ctx, call)
+	if resp != nil {
+		mm.kit.chargeUsage(mm.modelString, resp.Usage)
+	}
+	return resp, err
+}
+
+func (mm meteredModel) StreamObject(ctx context.Context, call fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	if err := mm.kit.spend.allow(); err != nil {
+		return nil, err
+	}
+	stream, err := mm.LanguageModel.StreamObject(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	return func(yield func(fantasy.ObjectStreamPart) bool) {
+		for part := range stream {
+			if part.Type == fantasy.ObjectStreamPartTypeFinish {
+				mm.kit.chargeUsage(mm.modelString, part.Usage)
+			}
+			if !yield(part) {
+				return
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,141 @@
+package kit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+
+	"github.com/mark3labs/kit/internal/usage"
+)
+
+func TestSpendTracker_RunCap(t *testing.T) {
+	tr := newSpendTracker(nil, "", 1.0, Budget{})
+	ctx, cancel := context.WithCancelCause(context.Background())
+	if err := tr.beginTurn(cancel); err != nil {
+		t.Fatalf("beginTurn: %v", err)
+	}
+
+	if w := tr.add(0.5); len(w) != 0 {
+		t.Errorf("warned at 50%%: %+v", w)
+	}
+	w := tr.add(0.3)
+	if len(w) != 1 || w[0].Scope != BudgetScopeRun || w[0].Exceeded {
+		t.Fatalf("warnings at 80%% = %+v", w)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("turn cancelled before the limit")
+	}
+
+	w = tr.add(0.3)
+	if len(w) != 1 || !w[0].Exceeded || w[0].LimitUSD != 1.0 {
+		t.Fatalf("warnings at 110%% = %+v", w)
+	}
+	if !errors.Is(context.Cause(ctx), ErrBudgetExceeded) {
+		t.Errorf("turn cause = %v, want ErrBudgetExceeded", context.Cause(ctx))
+	}
+	if w := tr.add(0.1); len(w) != 0 {
+		t.Errorf("warned again after exceeding: %+v", w)
+	}
+	tr.endTurn()
+
+	if err := tr.beginTurn(func(error) {}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("next turn = %v, want ErrBudgetExceeded", err)
+	}
+}
+
+func TestSpendTracker_ParentCapStopsChildren(t *testing.T) {
+	parent := newSpendTracker(nil, "", 1.0, Budget{})
+	var children []*spendTracker
+	var turns []context.Context
+	for range 2 {
+		child := newSpendTracker(nil, "", 0, Budget{})
+		child.parent = parent
+		ctx, cancel := context.WithCancelCause(context.Background())
+		if err := child.beginTurn(cancel); err != nil {
+			t.Fatalf("beginTurn: %v", err)
+		}
+		children = append(children, child)
+		turns = append(turns, ctx)
+	}
+
+	// Each child charges the parent as it spends; the parent is idle.
+	parent.add(0.6)
+	children[0].add(0.6)
+	parent.add(0.5)
+	children[1].add(0.5)
+
+	if !errors.Is(context.Cause(turns[1]), ErrBudgetExceeded) {
+		t.Errorf("child crossing the parent cap: cause = %v, want ErrBudgetExceeded", context.Cause(turns[1]))
+	}
+	if err := children[0].beginTurn(func(error) {}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("next child turn = %v, want ErrBudgetExceeded", err)
+	}
+}
+
+func TestSpendTracker_DailyFromLedger(t *testing.T) {
+	dir := t.TempDir()
+	ledger := usage.Open(filepath.Join(dir, "usage.jsonl"))
+	now := time.Now()
+	for _, e := range []usage.Entry{
+		{Time: now, Project: dir, CostUSD: 4},
+		{Time: now, Project: filepath.Join(dir, "elsewhere"), CostUSD: 100},
+		{Time: usage.StartOfDay(now).Add(-time.Minute), Project: dir, CostUSD: 100},
+	} {
+		if err := ledger.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tr := newSpendTracker(ledger, usage.NormalizeProject(dir), 0, Budget{Daily: 5, WarnAt: 0.9})
+	if err := tr.beginTurn(func(error) {}); err != nil {
+		t.Fatalf("beginTurn with $4 of $5 spent today: %v", err)
+	}
+	if w := tr.add(0.6); len(w) != 1 || w[0].Scope != BudgetScopeDaily || math.Abs(w[0].SpentUSD-4.6) > 1e-9 {
+		t.Errorf("warnings = %+v", w)
+	}
+	tr.endTurn()
+
+	if err := ledger.Append(usage.Entry{Time: now, Project: dir, CostUSD: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.beginTurn(func(error) {}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("beginTurn with $5 spent today = %v, want ErrBudgetExceeded", err)
+	}
+}
+
+// usageModel answers every call with a fixed usage.
+type usageModel struct {
+	fantasy.LanguageModel
+	calls int
+}
+
+func (u *usageModel) Generate(context.Context, fantasy.Call) (*fantasy.Response, error) {
+	u.calls++
+	return &fantasy.Response{Usage: fantasy.Usage{InputTokens: 1_000_000}}, nil
+}
+
+func TestMeteredModel_ChargesAndRefuses(t *testing.T) {
+	k := &Kit{events: newEventBus(), spend: newSpendTracker(nil, "", 1.5, Budget{})}
+	inner := &usageModel{}
+	model := k.metered(inner, "zhipuai/glm-5")
+
+	for range 2 {
+		if _, err := model.Generate(context.Background(), fantasy.Call{}); err != nil {
+			t.Fatalf("Generate under the cap: %v", err)
+		}
+	}
+	if got := k.GetCostUSD(); math.Abs(got-2) > 1e-9 {
+		t.Errorf("cost = %v, want 2 (two calls of 1M input tokens at $1/M)", got)
+	}
+	if _, err := model.Generate(context.Background(), fantasy.Call{}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Generate over the cap = %v, want ErrBudgetExceeded", err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("model called %d times, want 2: the call over the cap must not be made", inner.calls)
+	}
+}
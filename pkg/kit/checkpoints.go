@@ -0,0 +1,501 @@
+package kit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/kit/internal/core"
+	"github.com/mark3labs/kit/internal/message"
+	"github.com/mark3labs/kit/internal/session"
+)
+
+// ---------------------------------------------------------------------------
+// File checkpoints
+// ---------------------------------------------------------------------------
+
+// CheckpointRestore reports what [Kit.RestoreCheckpoint] changed.
+type CheckpointRestore struct {
+	// Prompt is the text of the rewound user message, so callers can offer
+	// it for editing and resubmission.
+	Prompt string
+	// Restored lists files written back to their earlier content.
+	Restored []string
+	// Removed lists files deleted because they did not exist yet.
+	Removed []string
+	// GitRef is the git snapshot the tracked tree was restored from, or
+	// empty when no git snapshot covered the rewound range.
+	GitRef string
+}
+
+// checkpointRecorder captures the state files are in before a turn first
+// modifies them. Write and edit calls snapshot their target file,
+// apply_patch calls every file the patch touches and rename_symbol calls
+// every file the language server edits; when git snapshots are enabled the
+// first mutating call of a turn also records the tracked tree (`git stash
+// create`) so changes made by bash can be undone.
+// The snapshots are committed as one session checkpoint when the turn ends.
+type checkpointRecorder struct {
+	workDir string
+	git     bool
+	// fs is the file system the tools read and write through, so snapshots
+	// and restores see the same contents. Nil uses the disk.
+	fs FileSystem
+
+	mu      sync.Mutex
+	active  bool
+	seen    map[string]bool
+	files   []pendingSnapshot
+	gitRef  string
+	gitDone bool
+}
+
+// pendingSnapshot is a captured file held in memory until the turn commits.
+type pendingSnapshot struct {
+	path    string
+	existed bool
+	content []byte
+	mode    os.FileMode
+}
+
+// newCheckpointRecorder returns a recorder for workDir, or nil when
+// checkpoints are disabled.
+func newCheckpointRecorder(disabled, git bool, workDir string) *checkpointRecorder {
+	if disabled {
+		return nil
+	}
+	return &checkpointRecorder{workDir: workDir, git: git}
+}
+
+// begin starts capturing for a new turn.
+func (r *checkpointRecorder) begin() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.active = true
+	r.seen = make(map[string]bool)
+	r.files = nil
+	r.gitRef = ""
+	r.gitDone = false
+}
+
+// before is called ahead of every tool execution and snapshots whatever the
+// call is about to modify.
+func (r *checkpointRecorder) before(toolName, input string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.active {
+		return
+	}
+
+	var paths []string
+	switch toolName {
+	case "write", "edit", "rename_symbol":
+		// rename_symbol may also touch other files; afterPlan snapshots
+		// those once the language server has named them.
+		var args struct {
+			Path string `json:"path"`
+		}
+		if json.Unmarshal([]byte(input), &args) != nil || args.Path == "" {
+			return
+		}
+		paths = []string{args.Path}
+	case "apply_patch":
+		var args struct {
+			Patch string `json:"patch"`
+		}
+		if json.Unmarshal([]byte(input), &args) != nil {
+			return
+		}
+		files, err := core.ParsePatch(args.Patch)
+		if err != nil {
+			return
+		}
+		for _, f := range files {
+			// Snapshotting rename destinations records that they did
+			// not exist, so rewinding removes them again.
+			paths = append(paths, f.Paths()...)
+		}
+	case "bash":
+	default:
+		return
+	}
+
+	if r.git && !r.gitDone {
+		r.gitDone = true
+		r.gitRef = gitSnapshot(r.workDir)
+	}
+	for _, path := range paths {
+		r.snapshot(path)
+	}
+}
+
+// afterPlan snapshots paths a running tool call is about to modify, once
+// the tool knows them (see core.GuardFiles).
+func (r *checkpointRecorder) afterPlan(paths []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.active {
+		return
+	}
+	for _, path := range paths {
+		r.snapshot(path)
+	}
+}
+
+// snapshot records the current state of path unless this turn already
+// captured it. Callers must hold r.mu.
+func (r *checkpointRecorder) snapshot(path string) {
+	// Relative paths resolve the way the core tools resolve them: against
+	// their work directory, which is the recorder's, falling back to the
+	// process working directory.
+	abs := filepath.Join(r.workDir, path)
+	if filepath.IsAbs(path) {
+		abs = filepath.Clean(path)
+	} else if r.workDir == "" {
+		var err error
+		if abs, err = filepath.Abs(path); err != nil {
+			return
+		}
+	}
+	if r.seen[abs] {
+		return
+	}
+	r.seen[abs] = true
+	snap := pendingSnapshot{path: abs}
+	if info, err := os.Stat(abs); err == nil && info.Mode().IsRegular() {
+		content, err := readFileFS(r.fs, abs)
+		if err != nil {
+			return
+		}
+		snap.existed = true
+		snap.content = content
+		snap.mode = info.Mode().Perm()
+	} else if err == nil {
+		return // directories and special files are not snapshotted
+	}
+	r.files = append(r.files, snap)
+}
+
+// commit stops capturing and records the turn's snapshots as a checkpoint
+// for targetID. Turns that modified nothing record no checkpoint.
+func (r *checkpointRecorder) commit(ts *session.TreeManager, targetID string) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	files, gitRef := r.files, r.gitRef
+	r.active = false
+	r.files = nil
+	r.seen = nil
+	r.mu.Unlock()
+
+	if len(files) == 0 && gitRef == "" {
+		return nil
+	}
+	return appendCheckpoint(ts, targetID, files, gitRef)
+}
+
+// appendCheckpoint stores the snapshot contents as blobs and appends the
+// checkpoint entry.
+func appendCheckpoint(ts *session.TreeManager, targetID string, files []pendingSnapshot, gitRef string) error {
+	snaps := make([]session.FileSnapshot, 0, len(files))
+	for _, f := range files {
+		snap := session.FileSnapshot{Path: f.path, Existed: f.existed, Mode: f.mode}
+		if f.existed {
+			hash, err := ts.PutBlob(f.content)
+			if err != nil {
+				return err
+			}
+			snap.Blob = hash
+		}
+		snaps = append(snaps, snap)
+	}
+	_, err := ts.AppendCheckpoint(targetID, snaps, gitRef)
+	return err
+}
+
+// checkpointedTool snapshots files before the wrapped tool runs.
+type checkpointedTool struct {
+	inner    Tool
+	recorder *checkpointRecorder
+}
+
+func (c *checkpointedTool) Info() LLMToolInfo                       { return c.inner.Info() }
+func (c *checkpointedTool) ProviderOptions() LLMProviderOptions     { return c.inner.ProviderOptions() }
+func (c *checkpointedTool) SetProviderOptions(o LLMProviderOptions) { c.inner.SetProviderOptions(o) }
+
+func (c *checkpointedTool) Run(ctx context.Context, call LLMToolCall) (LLMToolResponse, error) {
+	c.recorder.before(c.inner.Info().Name, call.Input)
+	ctx = core.WithFileGuard(ctx, func(_ context.Context, paths []string) error {
+		c.recorder.afterPlan(paths)
+		return nil
+	})
+	return c.inner.Run(ctx, call)
+}
+
+// checkpointToolWrapper returns a tool wrapper feeding recorder. A nil
+// recorder yields a wrapper that returns tools unchanged.
+func checkpointToolWrapper(recorder *checkpointRecorder) func([]Tool) []Tool {
+	return func(tools []Tool) []Tool {
+		if recorder == nil {
+			return tools
+		}
+		wrapped := make([]Tool, len(tools))
+		for i, tool := range tools {
+			wrapped[i] = &checkpointedTool{inner: tool, recorder: recorder}
+		}
+		return wrapped
+	}
+}
+
+// RestoreCheckpoint rewinds both the conversation and the working tree to
+// the moment before the user message entryID was sent. Every file modified
+// by a checkpointed tool since then — on any branch — is written back to
+// the content it had at that point (or removed if it did not exist yet), and
+// the session leaf moves to the message's parent so the prompt can be
+// edited and resubmitted.
+//
+// The files' current state is itself recorded as a checkpoint first, so a
+// later rewind to a message sent after this point still restores correctly.
+// RestoreCheckpoint requires the built-in tree session and fails while a
+// turn is running.
+func (m *Kit) RestoreCheckpoint(entryID string) (*CheckpointRestore, error) {
+	ts := m.GetTreeSession()
+	if ts == nil {
+		return nil, fmt.Errorf("checkpoints require a tree session")
+	}
+	if m.IsGenerating() {
+		return nil, fmt.Errorf("cannot restore a checkpoint while the agent is working")
+	}
+	target, ok := ts.GetEntry(entryID).(*session.MessageEntry)
+	if !ok || target.Role != string(message.RoleUser) {
+		return nil, fmt.Errorf("entry %q is not a user message", entryID)
+	}
+	checkpoints, err := ts.CheckpointsAfter(entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := planRestore(checkpoints, m.checkpointWorkDir())
+	if err != nil {
+		return nil, err
+	}
+	var fs FileSystem
+	if m.checkpoints != nil {
+		fs = m.checkpoints.fs
+	}
+
+	// Record what is about to be overwritten before touching anything.
+	if len(plan.files) > 0 || plan.gitRef != "" {
+		current := make([]pendingSnapshot, 0, len(plan.files))
+		for _, f := range plan.files {
+			snap := pendingSnapshot{path: f.Path}
+			if info, err := os.Stat(f.Path); err == nil && info.Mode().IsRegular() {
+				if content, err := readFileFS(fs, f.Path); err == nil {
+					snap.existed = true
+					snap.content = content
+					snap.mode = info.Mode().Perm()
+				}
+			}
+			current = append(current, snap)
+		}
+		var currentRef string
+		if plan.gitRef != "" {
+			currentRef = gitSnapshot(plan.gitDir)
+		}
+		if err := appendCheckpoint(ts, entryID, current, currentRef); err != nil {
+			return nil, fmt.Errorf("failed to record pre-restore checkpoint: %w", err)
+		}
+	}
+
+	result := &CheckpointRestore{Prompt: target.Text(), GitRef: plan.gitRef}
+	if plan.gitRef != "" {
+		if err := gitRestore(plan.gitDir, plan.gitRef); err != nil {
+			return nil, err
+		}
+	}
+	var errs []error
+	for _, f := range plan.files {
+		if !f.Existed {
+			if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+				continue
+			}
+			result.Removed = append(result.Removed, f.Path)
+			continue
+		}
+		content, err := ts.ReadBlob(f.Blob)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.Path, err))
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		mode := f.Mode
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := writeFileFS(fs, f.Path, content, mode); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result.Restored = append(result.Restored, f.Path)
+	}
+	if len(errs) > 0 {
+		return result, fmt.Errorf("failed to restore some files: %w", errors.Join(errs...))
+	}
+
+	if err := m.session.Branch(target.ParentID); err != nil {
+		return result, fmt.Errorf("branch to parent: %w", err)
+	}
+	return result, nil
+}
+
+// readFileFS reads path through fs, or from the disk when fs is nil.
+func readFileFS(fs FileSystem, path string) ([]byte, error) {
+	if fs == nil {
+		return os.ReadFile(path)
+	}
+	return fs.ReadFile(context.Background(), path)
+}
+
+// writeFileFS writes path through fs, or to the disk with mode when fs is
+// nil. Deletions have no FileSystem counterpart and always use the disk.
+func writeFileFS(fs FileSystem, path string, data []byte, mode os.FileMode) error {
+	if fs != nil {
+		return fs.WriteFile(context.Background(), path, data)
+	}
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode) // WriteFile keeps the mode of existing files
+}
+
+// checkpointWorkDir returns the directory git snapshots are taken in.
+func (m *Kit) checkpointWorkDir() string {
+	if m.checkpoints != nil {
+		return m.checkpoints.workDir
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// restorePlan is the set of changes that rewinds the working tree.
+type restorePlan struct {
+	files  []session.FileSnapshot
+	gitRef string
+	gitDir string
+}
+
+// planRestore picks, for every file touched after the rewind point, the
+// earliest snapshot — the file's content at that point. When a git snapshot
+// was taken it restores the tracked tree, and file snapshots recorded at or
+// after it are dropped for tracked paths since git already covers them.
+func planRestore(checkpoints []*session.CheckpointEntry, workDir string) (restorePlan, error) {
+	var plan restorePlan
+	gitIndex := -1
+	for i, cp := range checkpoints {
+		if cp.GitRef != "" {
+			gitIndex = i
+			plan.gitRef = cp.GitRef
+			plan.gitDir = workDir
+			break
+		}
+	}
+
+	var tracked map[string]bool
+	if plan.gitRef != "" {
+		var err error
+		if tracked, err = gitTrackedFiles(workDir, plan.gitRef); err != nil {
+			return plan, err
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, cp := range checkpoints {
+		for _, f := range cp.Files {
+			if seen[f.Path] {
+				continue
+			}
+			seen[f.Path] = true
+			if gitIndex >= 0 && i >= gitIndex && tracked[f.Path] {
+				continue
+			}
+			plan.files = append(plan.files, f)
+		}
+	}
+	return plan, nil
+}
+
+// gitSnapshot records the tracked working tree of the repository containing
+// dir and returns the commit holding it, or "" when dir is not in a git
+// repository. A clean tree yields HEAD. The commit is kept reachable under
+// refs/kit/checkpoints/ so garbage collection does not prune it.
+func gitSnapshot(dir string) string {
+	ref, err := runGit(dir, "stash", "create")
+	if err != nil {
+		return ""
+	}
+	if ref == "" {
+		if ref, err = runGit(dir, "rev-parse", "HEAD"); err != nil {
+			return ""
+		}
+	}
+	_, _ = runGit(dir, "update-ref", "refs/kit/checkpoints/"+ref, ref)
+	return ref
+}
+
+// gitRestore resets the tracked working tree to ref. Untracked files are
+// left alone.
+func gitRestore(dir, ref string) error {
+	if _, err := runGit(dir, "restore", "--source="+ref, "--worktree", "--", ":/"); err != nil {
+		return fmt.Errorf("git restore: %w", err)
+	}
+	return nil
+}
+
+// gitTrackedFiles returns the absolute paths of the files in ref's tree.
+func gitTrackedFiles(dir, ref string) (map[string]bool, error) {
+	root, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("git snapshot %s: %w", ref, err)
+	}
+	out, err := runGit(dir, "ls-tree", "-r", "-z", "--name-only", "--full-tree", ref)
+	if err != nil {
+		return nil, fmt.Errorf("git snapshot %s: %w", ref, err)
+	}
+	tracked := make(map[string]bool)
+	for name := range strings.SplitSeq(out, "\x00") {
+		if name != "" {
+			tracked[filepath.Join(root, filepath.FromSlash(name))] = true
+		}
+	}
+	return tracked, nil
+}
+
+// runGit runs git in dir and returns its trimmed stdout.
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,215 @@
+package kit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/kit/internal/message"
+	"github.com/mark3labs/kit/internal/session"
+)
+
+func userMessage(text string) message.Message {
+	return message.Message{
+		Role:      message.RoleUser,
+		Parts:     []message.ContentPart{message.TextContent{Text: text}},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// simulateTurn records a turn for targetID that writes each file in edits,
+// going through the recorder the way checkpointed tools do.
+func simulateTurn(t *testing.T, rec *checkpointRecorder, ts *session.TreeManager, targetID string, edits map[string]string) {
+	t.Helper()
+	rec.begin()
+	for path, content := range edits {
+		rec.before("write", `{"path":"`+path+`","content":""}`)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.commit(ts, targetID); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRestoreCheckpoint_RewindsFilesAndConversation(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.txt")
+	created := filepath.Join(dir, "created.txt")
+	if err := os.WriteFile(existing, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := session.InMemoryTreeSession(dir)
+	rec := newCheckpointRecorder(false, false, dir)
+	k := &Kit{session: NewTreeManagerAdapter(ts), checkpoints: rec}
+
+	first, _ := ts.AppendMessage(userMessage("first"))
+	simulateTurn(t, rec, ts, first, map[string]string{existing: "v2", created: "new"})
+	second, _ := ts.AppendMessage(userMessage("second"))
+	simulateTurn(t, rec, ts, second, map[string]string{existing: "v3"})
+
+	// Rewinding the second turn restores only what it changed.
+	result, err := k.RestoreCheckpoint(second)
+	if err != nil {
+		t.Fatalf("RestoreCheckpoint(second): %v", err)
+	}
+	if result.Prompt != "second" {
+		t.Errorf("Prompt = %q, want %q", result.Prompt, "second")
+	}
+	if got := readFile(t, existing); got != "v2" {
+		t.Errorf("existing.txt = %q, want %q", got, "v2")
+	}
+	if got := readFile(t, created); got != "new" {
+		t.Errorf("created.txt = %q, want %q", got, "new")
+	}
+	if got := ts.GetLeafID(); got != first {
+		t.Errorf("leaf = %q, want %q", got, first)
+	}
+
+	// Rewinding further back also undoes the first turn, including the file
+	// it created.
+	result, err = k.RestoreCheckpoint(first)
+	if err != nil {
+		t.Fatalf("RestoreCheckpoint(first): %v", err)
+	}
+	if got := readFile(t, existing); got != "v1" {
+		t.Errorf("existing.txt = %q, want %q", got, "v1")
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("created.txt should have been removed, stat err = %v", err)
+	}
+	if len(result.Removed) != 1 || result.Removed[0] != created {
+		t.Errorf("Removed = %v, want [%s]", result.Removed, created)
+	}
+	if got := ts.GetLeafID(); got != "" {
+		t.Errorf("leaf = %q, want root", got)
+	}
+}
+
+func TestRestoreCheckpoint_AfterEarlierRewind(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := session.InMemoryTreeSession(dir)
+	rec := newCheckpointRecorder(false, false, dir)
+	k := &Kit{session: NewTreeManagerAdapter(ts), checkpoints: rec}
+
+	first, _ := ts.AppendMessage(userMessage("first"))
+	simulateTurn(t, rec, ts, first, map[string]string{file: "v2"})
+	if _, err := k.RestoreCheckpoint(first); err != nil {
+		t.Fatalf("RestoreCheckpoint: %v", err)
+	}
+
+	// A new branch starts from the restored state; rewinding it must bring
+	// back the restored content, not the abandoned branch's.
+	retry, _ := ts.AppendMessage(userMessage("retry"))
+	simulateTurn(t, rec, ts, retry, map[string]string{file: "v3"})
+	if _, err := k.RestoreCheckpoint(retry); err != nil {
+		t.Fatalf("RestoreCheckpoint: %v", err)
+	}
+	if got := readFile(t, file); got != "v1" {
+		t.Errorf("file.txt = %q, want %q", got, "v1")
+	}
+}
+
+func TestRestoreCheckpoint_RejectsNonUserEntries(t *testing.T) {
+	ts := session.InMemoryTreeSession(t.TempDir())
+	k := &Kit{session: NewTreeManagerAdapter(ts)}
+
+	reply, _ := ts.AppendMessage(message.Message{
+		Role:  message.RoleAssistant,
+		Parts: []message.ContentPart{message.TextContent{Text: "hi"}},
+	})
+	if _, err := k.RestoreCheckpoint(reply); err == nil {
+		t.Error("expected error for assistant entry")
+	}
+	if _, err := (&Kit{}).RestoreCheckpoint("x"); err == nil {
+		t.Error("expected error without a tree session")
+	}
+}
+
+func TestCheckpointRecorder_IgnoresCallsOutsideTurn(t *testing.T) {
+	dir := t.TempDir()
+	rec := newCheckpointRecorder(false, false, dir)
+	rec.before("write", `{"path":"`+filepath.Join(dir, "x")+`"}`)
+	if len(rec.files) != 0 {
+		t.Errorf("recorded %d snapshots outside a turn", len(rec.files))
+	}
+	if newCheckpointRecorder(true, false, dir) != nil {
+		t.Error("disabled recorder should be nil")
+	}
+}
+
+func TestRestoreCheckpoint_UndoesApplyPatch(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.txt")
+	moved := filepath.Join(dir, "moved.txt")
+	if err := os.WriteFile(old, []byte("a\nb\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := session.InMemoryTreeSession(dir)
+	rec := newCheckpointRecorder(false, false, dir)
+	k := &Kit{session: NewTreeManagerAdapter(ts), checkpoints: rec}
+
+	turn, _ := ts.AppendMessage(userMessage("rename"))
+	rec.begin()
+	input, _ := json.Marshal(map[string]string{"patch": "*** Begin Patch\n*** Update File: " + old +
+		"\n*** Move to: " + moved + "\n@@\n a\n-b\n+c\n*** End Patch"})
+	tool := &checkpointedTool{inner: NewApplyPatchTool(), recorder: rec}
+	resp, err := tool.Run(context.Background(), LLMToolCall{ID: "1", Name: "apply_patch", Input: string(input)})
+	if err != nil || resp.IsError {
+		t.Fatalf("apply_patch: %v %s", err, resp.Content)
+	}
+	if err := rec.commit(ts, turn); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	if _, err := k.RestoreCheckpoint(turn); err != nil {
+		t.Fatalf("RestoreCheckpoint: %v", err)
+	}
+	if got := readFile(t, old); got != "a\nb\n" {
+		t.Errorf("old.txt = %q", got)
+	}
+	if _, err := os.Stat(moved); !os.IsNotExist(err) {
+		t.Errorf("moved.txt should have been removed, stat err = %v", err)
+	}
+}
+
+func TestCheckpointRecorder_RelativePathsUseWorkDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The process runs elsewhere, as it does for a worktree-isolated
+	// subagent or an SDK caller using WithWorkDir.
+	t.Chdir(t.TempDir())
+
+	rec := newCheckpointRecorder(false, false, dir)
+	rec.begin()
+	rec.before("edit", `{"path":"a.txt"}`)
+	if len(rec.files) != 1 {
+		t.Fatalf("snapshots = %+v", rec.files)
+	}
+	if got := rec.files[0]; got.path != filepath.Join(dir, "a.txt") || !got.existed || string(got.content) != "v1" {
+		t.Errorf("snapshot = %s existed=%v %q, want %s in the work dir", got.path, got.existed, got.content, filepath.Join(dir, "a.txt"))
+	}
+}
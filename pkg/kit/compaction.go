@@ -197,7 +197,9 @@ func (m *Kit) compactImpl(ctx context.Context, opts *CompactionOptions, customIn
 		}
 	}
 
-	model := m.agent.GetModel()
+	// The summary is an LLM call outside the agent loop, so it is charged
+	// to the spend limits here.
+	model := m.metered(m.agent.GetModel(), m.GetModelString())
 
 	// Create a streaming callback to emit chunks as events.
 	streamCallback := func(delta string) error {
@@ -7,6 +7,7 @@ import (
 
 	"github.com/mark3labs/kit/internal/config"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 // defaultSystemPrompt is the built-in system prompt used when no custom
@@ -74,8 +75,8 @@ func setSDKDefaults(v *viper.Viper) {
 }
 
 // InitConfig initializes the process-global viper configuration system.
-// It searches for config files in standard locations and loads them with
-// environment variable substitution.
+// It merges the system, user, project and local config files (see
+// [config.LoadLayers]) with environment variable substitution.
 //
 // configFile: explicit config file path (empty = search defaults).
 // debug: if true, print warnings about missing configs to stderr.
@@ -114,34 +115,31 @@ func initConfig(v *viper.Viper, configFile string, debug bool) error {
 		}
 	}
 
-	home, err := os.UserHomeDir()
+	cwd, err := os.Getwd()
 	if err != nil {
-		return fmt.Errorf("error finding home directory: %w", err)
+		return fmt.Errorf("error finding working directory: %w", err)
 	}
 
-	// Current directory has higher priority than home directory.
-	v.AddConfigPath(".")
-	v.AddConfigPath(home)
-
-	configLoaded := false
-
-	v.SetConfigName(".kit")
-	if err := v.ReadInConfig(); err == nil {
-		configPath := v.ConfigFileUsed()
-		if err := loadConfigWithEnvSubstitution(v, configPath); err != nil {
-			if strings.Contains(err.Error(), "environment variable substitution failed") {
-				return fmt.Errorf("error reading config file '%s': %w", configPath, err)
-			}
-		} else {
-			configLoaded = true
-		}
+	// Merge the system, user, project and local files in that order, so a
+	// project file adds to the user's servers and models instead of hiding
+	// them. Env vars and bound flags still take precedence at read time.
+	// An untrusted project contributes no layers at all.
+	load := config.LoadLayers
+	if v.GetBool("untrusted-project") {
+		load = config.LoadUserLayers
 	}
-
-	if !configLoaded && debug {
-		fmt.Fprintf(os.Stderr, "No config file found in current directory or home directory\n")
+	layers, err := load(cwd)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	if len(layers) == 0 {
+		if debug {
+			fmt.Fprintf(os.Stderr, "No config file found\n")
+		}
+		return nil
+	}
+	_, err = config.ApplyLayers(v, layers)
+	return err
 }
 
 // LoadConfigWithEnvSubstitution loads a config file with ${ENV_VAR} expansion
@@ -174,5 +172,17 @@ func loadConfigWithEnvSubstitution(v *viper.Viper, configPath string) error {
 
 	config.SetConfigPath(configPath)
 	v.SetConfigType(configType)
-	return v.ReadConfig(strings.NewReader(processedContent))
+	if err := v.ReadConfig(strings.NewReader(processedContent)); err != nil {
+		return err
+	}
+
+	// Split a list-valued model (a failover chain) the same way the merged
+	// layers are split. JSON is valid YAML, so one decoder serves both.
+	var settings map[string]any
+	if yaml.Unmarshal([]byte(processedContent), &settings) == nil {
+		if chain := config.ModelChain(settings); chain != nil {
+			return v.MergeConfigMap(chain)
+		}
+	}
+	return nil
 }
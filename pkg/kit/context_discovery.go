@@ -0,0 +1,250 @@
+package kit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/kit/internal/config"
+)
+
+// ---------------------------------------------------------------------------
+// Context file discovery
+// ---------------------------------------------------------------------------
+//
+// At startup Kit loads the user-level AGENTS.md and the context file of every
+// directory from the project root down to the working directory, most
+// general first. Directories below (or beside) the working directory are
+// searched lazily: the first time a file tool touches a path, the context
+// files between that path and the already-searched directories are added.
+
+// contextFileNames are the instruction files recognised in a directory, in
+// preference order. Only the first one present is loaded, so a CLAUDE.md
+// kept next to AGENTS.md for another tool is not injected twice.
+var contextFileNames = []string{
+	"AGENTS.md",
+	"CLAUDE.md",
+	filepath.Join(".github", "copilot-instructions.md"),
+}
+
+// maxContextImportDepth bounds nested @path imports.
+const maxContextImportDepth = 5
+
+// contextDiscovery remembers which directories have been searched for
+// context files so each is loaded at most once.
+type contextDiscovery struct {
+	mu      sync.Mutex
+	cwd     string
+	root    string
+	checked map[string]bool
+}
+
+// userContextFilePath returns $XDG_CONFIG_HOME/kit/AGENTS.md, defaulting to
+// ~/.config/kit/AGENTS.md.
+func userContextFilePath() string {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		base = filepath.Join(home, ".config")
+	}
+	return filepath.Join(base, "kit", "AGENTS.md")
+}
+
+// discoverContextFiles loads the user-level context file and those from the
+// project root down to cwd. The returned discovery serves later lazy
+// lookups below cwd.
+func discoverContextFiles(cwd string) (*contextDiscovery, []*ContextFile) {
+	if abs, err := filepath.Abs(cwd); err == nil {
+		cwd = abs
+	}
+	d := &contextDiscovery{
+		cwd:     cwd,
+		root:    config.FindProjectRoot(cwd),
+		checked: make(map[string]bool),
+	}
+
+	return d, append(userContextFiles(), d.search(cwd)...)
+}
+
+// userContextFiles returns the user-level context file, if any. It is all
+// that is loaded for an untrusted project.
+func userContextFiles() []*ContextFile {
+	if path := userContextFilePath(); path != "" {
+		if cf := readContextFile(path); cf != nil {
+			return []*ContextFile{cf}
+		}
+	}
+	return nil
+}
+
+// forPath returns the context files of directories between path and the
+// project root that have not been searched yet, most general first. Paths
+// outside the project are ignored.
+func (d *contextDiscovery) forPath(path string) []*ContextFile {
+	if d == nil || path == "" {
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(d.cwd, path)
+	}
+	path = filepath.Clean(path)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		path = filepath.Dir(path)
+	}
+	return d.search(path)
+}
+
+// search walks from dir up to the project root, marking each directory as
+// searched, and returns the newly found context files root-first.
+func (d *contextDiscovery) search(dir string) []*ContextFile {
+	if !insideDir(d.root, dir) {
+		return nil
+	}
+
+	d.mu.Lock()
+	var dirs []string
+	for {
+		if d.checked[dir] {
+			break
+		}
+		d.checked[dir] = true
+		dirs = append(dirs, dir)
+		if dir == d.root {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	d.mu.Unlock()
+
+	var files []*ContextFile
+	for i := len(dirs) - 1; i >= 0; i-- {
+		for _, name := range contextFileNames {
+			if cf := readContextFile(filepath.Join(dirs[i], name)); cf != nil {
+				files = append(files, cf)
+				break
+			}
+		}
+	}
+	return files
+}
+
+// readContextFile loads path with its @imports expanded, or returns nil when
+// it does not exist.
+func readContextFile(path string) *ContextFile {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	content := expandContextImports(string(data), filepath.Dir(path), importRoots(path), map[string]bool{path: true}, 0)
+	return &ContextFile{Path: path, Content: strings.TrimSpace(content)}
+}
+
+// importRoots returns the directories path may import from. The user-level
+// file is the user's own and may import anything; a project file, which may
+// come from a cloned repository, is limited to its project and the user
+// config directory so it cannot pull ~/.aws/credentials into the prompt.
+func importRoots(path string) []string {
+	var userDir string
+	if p := userContextFilePath(); p != "" {
+		userDir = filepath.Dir(p)
+		if path == p {
+			return nil
+		}
+	}
+	roots := []string{config.FindProjectRoot(filepath.Dir(path))}
+	if userDir != "" {
+		roots = append(roots, userDir)
+	}
+	for i, root := range roots {
+		if real, err := filepath.EvalSymlinks(root); err == nil {
+			roots[i] = real
+		}
+	}
+	return roots
+}
+
+// insideDir reports whether path is root or lies below it.
+func insideDir(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// expandContextImports replaces each line consisting solely of "@path" with
+// the named file's content. Paths are relative to dir, and "~/" means the
+// home directory. When roots is non-nil, only files whose real path lies
+// under one of them are imported. Lines inside fenced code blocks, missing
+// or disallowed files, cycles and imports nested deeper than
+// maxContextImportDepth are left as written.
+func expandContextImports(content, dir string, roots []string, seen map[string]bool, depth int) string {
+	lines := strings.Split(content, "\n")
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || depth >= maxContextImportDepth || len(trimmed) < 2 || trimmed[0] != '@' || strings.ContainsAny(trimmed, " \t") {
+			continue
+		}
+
+		path := trimmed[1:]
+		if strings.HasPrefix(path, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				continue
+			}
+			path = filepath.Join(home, path[2:])
+		} else if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		path = filepath.Clean(path)
+		if seen[path] || !importAllowed(roots, path) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		seen[path] = true
+		lines[i] = strings.TrimSpace(expandContextImports(string(data), filepath.Dir(path), roots, seen, depth+1))
+		delete(seen, path)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// importAllowed reports whether path, after resolving symlinks, lies under
+// one of roots. A nil roots allows every path.
+func importAllowed(roots []string, path string) bool {
+	if roots == nil {
+		return true
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	for _, root := range roots {
+		if insideDir(root, real) {
+			return true
+		}
+	}
+	return false
+}
+
+// discoverToolContext adds the context files of directories a file tool
+// has just touched for the first time. The updated system prompt applies
+// from the next LLM call, so the model sees a package's instructions right
+// after it starts working in that package.
+func (m *Kit) discoverToolContext(e ToolResultEvent) {
+	if e.ToolKind != ToolKindRead && e.ToolKind != ToolKindEdit {
+		return
+	}
+	path, _ := e.ParsedArgs["path"].(string)
+	for _, cf := range m.contextDisc.forPath(path) {
+		_ = m.AddContextFile(cf)
+	}
+}
@@ -0,0 +1,113 @@
+package kit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeContextTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func contextPaths(files []*ContextFile) []string {
+	var paths []string
+	for _, cf := range files {
+		paths = append(paths, cf.Path)
+	}
+	return paths
+}
+
+func TestDiscoverContextFiles(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	repo := filepath.Join(root, "repo")
+	cwd := filepath.Join(repo, "services", "api")
+
+	writeContextTestFile(t, filepath.Join(root, "xdg", "kit", "AGENTS.md"), "user rules")
+	writeContextTestFile(t, filepath.Join(repo, ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeContextTestFile(t, filepath.Join(repo, ".github", "copilot-instructions.md"), "repo rules")
+	writeContextTestFile(t, filepath.Join(repo, "services", "CLAUDE.md"), "services rules\n@../docs/style.md\n")
+	writeContextTestFile(t, filepath.Join(repo, "docs", "style.md"), "use tabs")
+	writeContextTestFile(t, filepath.Join(cwd, "AGENTS.md"), "api rules")
+	writeContextTestFile(t, filepath.Join(cwd, "CLAUDE.md"), "shadowed by AGENTS.md")
+	writeContextTestFile(t, filepath.Join(repo, "services", "web", "AGENTS.md"), "web rules")
+	writeContextTestFile(t, filepath.Join(repo, "services", "web", "src", "app.ts"), "")
+	writeContextTestFile(t, filepath.Join(root, "outside", "AGENTS.md"), "not ours")
+
+	d, files := discoverContextFiles(cwd)
+	want := []string{
+		filepath.Join(root, "xdg", "kit", "AGENTS.md"),
+		filepath.Join(repo, ".github", "copilot-instructions.md"),
+		filepath.Join(repo, "services", "CLAUDE.md"),
+		filepath.Join(cwd, "AGENTS.md"),
+	}
+	if got := contextPaths(files); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("startup files =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := files[2].Content; got != "services rules\nuse tabs" {
+		t.Errorf("imported content = %q", got)
+	}
+
+	// A sibling package's file is picked up on first touch, once.
+	lazy := d.forPath("../web/src/app.ts")
+	if got := contextPaths(lazy); len(got) != 1 || got[0] != filepath.Join(repo, "services", "web", "AGENTS.md") {
+		t.Errorf("lazy files = %v", got)
+	}
+	if again := d.forPath(filepath.Join(repo, "services", "web", "src")); again != nil {
+		t.Errorf("second touch loaded %v", contextPaths(again))
+	}
+	if outside := d.forPath(filepath.Join(root, "outside", "x.go")); outside != nil {
+		t.Errorf("file outside the project loaded %v", contextPaths(outside))
+	}
+}
+
+func TestExpandContextImports(t *testing.T) {
+	dir := t.TempDir()
+	writeContextTestFile(t, filepath.Join(dir, "a.md"), "A\n@b.md")
+	writeContextTestFile(t, filepath.Join(dir, "b.md"), "B\n@a.md")
+
+	content := "top\n@a.md\n```\n@a.md\n```\n@missing.md\nmail @someone here"
+	got := expandContextImports(content, dir, nil, map[string]bool{}, 0)
+	want := "top\nA\nB\n@a.md\n```\n@a.md\n```\n@missing.md\nmail @someone here"
+	if got != want {
+		t.Errorf("expandContextImports =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestReadContextFile_ImportsStayInProject(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", filepath.Join(root, "home"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "home", ".config"))
+	repo := filepath.Join(root, "repo")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(root, "home", ".aws", "credentials")
+	writeContextTestFile(t, secret, "SECRET")
+	writeContextTestFile(t, filepath.Join(root, "home", ".config", "kit", "notes.md"), "NOTES")
+	writeContextTestFile(t, filepath.Join(repo, "docs", "style.md"), "STYLE")
+	if err := os.Symlink(secret, filepath.Join(repo, "docs", "link.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeContextTestFile(t, filepath.Join(repo, "AGENTS.md"),
+		"@docs/style.md\n@~/.config/kit/notes.md\n@~/.aws/credentials\n@"+secret+"\n@docs/link.md")
+
+	cf := readContextFile(filepath.Join(repo, "AGENTS.md"))
+	want := "STYLE\nNOTES\n@~/.aws/credentials\n@" + secret + "\n@docs/link.md"
+	if cf == nil || cf.Content != want {
+		t.Fatalf("project file = %+v, want content\n%s", cf, want)
+	}
+
+	writeContextTestFile(t, filepath.Join(root, "home", ".config", "kit", "AGENTS.md"), "@~/.aws/credentials")
+	if cf := userContextFiles(); len(cf) != 1 || cf[0].Content != "SECRET" {
+		t.Errorf("user file = %v, want the import expanded", contextPaths(cf))
+	}
+}
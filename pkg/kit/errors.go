@@ -2,7 +2,10 @@ package kit
 
 import (
 	"errors"
+	"net/http"
 	"strings"
+
+	"charm.land/fantasy"
 )
 
 // Provider-error sentinels. Provider and turn execution paths wrap these via
@@ -24,6 +27,11 @@ var (
 	// ErrAuth indicates a credential / authorization failure.
 	ErrAuth = errors.New("provider authentication failed")
 
+	// ErrOverloaded indicates the provider is temporarily out of capacity
+	// for the requested model (e.g. Anthropic's 529 "overloaded_error").
+	// Other models, or the same model later, usually still work.
+	ErrOverloaded = errors.New("provider overloaded")
+
 	// ErrProviderUnavailable indicates a transient provider/upstream failure
 	// (5xx, network error, timeout).
 	ErrProviderUnavailable = errors.New("provider unavailable")
@@ -35,23 +43,24 @@ var (
 
 // ClassifyProviderError inspects err and returns it wrapped with the matching
 // provider-error sentinel ([ErrContextOverflow], [ErrRateLimit], [ErrAuth],
-// [ErrProviderUnavailable], or [ErrInvalidRequest]) when the underlying cause
+// [ErrOverloaded], [ErrProviderUnavailable], or [ErrInvalidRequest]) when the underlying cause
 // can be recognized. The returned error satisfies errors.Is against both the
 // sentinel and the original cause, so the full chain stays inspectable.
 //
 // When err is nil it returns nil. When the cause cannot be classified the
 // original err is returned unchanged so callers never lose information.
 //
-// Classification is heuristic: it first honors any sentinel already present in
-// the chain (so double-classification is idempotent), then falls back to
-// matching common provider status codes and phrases in the error text.
+// Classification first honors any sentinel already present in the chain (so
+// double-classification is idempotent), then the HTTP status of a
+// [fantasy.ProviderError] in the chain, and finally falls back to matching
+// common provider status codes and phrases in the error text.
 func ClassifyProviderError(err error) error {
 	if err == nil {
 		return nil
 	}
 	// Already classified — keep as-is so the call is idempotent.
 	for _, sentinel := range []error{
-		ErrContextOverflow, ErrRateLimit, ErrAuth,
+		ErrContextOverflow, ErrRateLimit, ErrAuth, ErrOverloaded,
 		ErrProviderUnavailable, ErrInvalidRequest,
 	} {
 		if errors.Is(err, sentinel) {
@@ -59,6 +68,12 @@ func ClassifyProviderError(err error) error {
 		}
 	}
 
+	var pe *fantasy.ProviderError
+	if errors.As(err, &pe) {
+		if sentinel := classifyProviderStatus(pe); sentinel != nil {
+			return wrapSentinel(sentinel, err)
+		}
+	}
 	if sentinel := classifyProviderErrorText(err.Error()); sentinel != nil {
 		return wrapSentinel(sentinel, err)
 	}
@@ -86,6 +101,25 @@ func (e *sentinelError) Unwrap() []error {
 	return []error{e.sentinel, e.cause}
 }
 
+// classifyProviderStatus returns the sentinel matching a provider error's
+// HTTP status, or nil when the status alone does not decide it.
+func classifyProviderStatus(pe *fantasy.ProviderError) error {
+	switch {
+	case pe.IsContextTooLarge():
+		return ErrContextOverflow
+	case pe.StatusCode == 529:
+		return ErrOverloaded
+	case pe.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimit
+	case pe.AuthError, pe.StatusCode == http.StatusUnauthorized, pe.StatusCode == http.StatusForbidden:
+		return ErrAuth
+	case pe.StatusCode >= http.StatusInternalServerError && pe.StatusCode <= http.StatusGatewayTimeout:
+		return ErrProviderUnavailable
+	default:
+		return nil
+	}
+}
+
 // classifyProviderErrorText returns the sentinel matching common provider
 // error phrasings, or nil if none match.
 func classifyProviderErrorText(msg string) error {
@@ -93,6 +127,8 @@ func classifyProviderErrorText(msg string) error {
 	switch {
 	case containsAny(m, "context_length_exceeded", "context window", "maximum context length", "too many tokens", "prompt is too long"):
 		return ErrContextOverflow
+	case containsAny(m, "overloaded", "status 529"):
+		return ErrOverloaded
 	case containsAny(m, "rate limit", "rate_limit", "too many requests", "status 429", "429"):
 		return ErrRateLimit
 	case containsAny(m, "unauthorized", "authentication", "invalid api key", "invalid_api_key", "permission denied", "status 401", "status 403", "401", "403"):
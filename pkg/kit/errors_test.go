@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"testing"
 
+	"charm.land/fantasy"
+
 	"github.com/mark3labs/kit/pkg/kit"
 )
 
@@ -20,9 +22,13 @@ func TestClassifyProviderError(t *testing.T) {
 		{"rate limit", errors.New("HTTP status 429: rate limit exceeded"), kit.ErrRateLimit},
 		{"auth 401", errors.New("status 401 unauthorized"), kit.ErrAuth},
 		{"auth invalid key", errors.New("invalid api key provided"), kit.ErrAuth},
+		{"overloaded 529", errors.New(`status 529: {"type":"overloaded_error","message":"Overloaded"}`), kit.ErrOverloaded},
 		{"unavailable 503", errors.New("status 503 service unavailable"), kit.ErrProviderUnavailable},
 		{"invalid request", errors.New("status 400 bad request: malformed body"), kit.ErrInvalidRequest},
 		{"unclassified", errors.New("something totally unexpected"), nil},
+		{"number is not a status", errors.New("request req_5290 used 15297 tokens"), nil},
+		{"provider status 529", &fantasy.ProviderError{Title: "error", Message: "try again later", StatusCode: 529}, kit.ErrOverloaded},
+		{"provider status 502", &fantasy.ProviderError{Title: "error", Message: "upstream", StatusCode: 502}, kit.ErrProviderUnavailable},
 	}
 
 	for _, tc := range cases {
@@ -57,6 +57,11 @@ const (
 	EventStepUsage  EventType = "step_usage"
 	// EventPasswordPrompt fires when a sudo command needs a password.
 	EventPasswordPrompt EventType = "password_prompt"
+	// EventPermissionRequest fires when the permission policy requires user
+	// approval before a tool call runs.
+	EventPermissionRequest EventType = "permission_request"
+	// EventMCPElicitation fires when an MCP server asks the user for input.
+	EventMCPElicitation EventType = "mcp_elicitation"
 	// EventSteerConsumed fires when one or more steering messages have been
 	// injected into the agent turn via PrepareStep.
 	EventSteerConsumed EventType = "steer_consumed"
@@ -85,6 +90,14 @@ const (
 	// EventRetry fires when the LLM provider request is retried after a
 	// transient error.
 	EventRetry EventType = "retry"
+	// EventBudgetWarning fires when spend approaches or reaches a limit.
+	EventBudgetWarning EventType = "budget_warning"
+	// EventSkillScope fires when an activated skill starts or stops
+	// restricting the tools the agent may call.
+	EventSkillScope EventType = "skill_scope"
+	// EventModelFailover fires when a provider error makes Kit switch to the
+	// next fallback model mid-turn.
+	EventModelFailover EventType = "model_failover"
 )
 
 // ---------------------------------------------------------------------------
@@ -113,7 +126,7 @@ const (
 	ToolKindEdit     = extensions.ToolKindEdit     // File modification (edit, write)
 	ToolKindRead     = extensions.ToolKindRead     // File reading (read, ls)
 	ToolKindSearch   = extensions.ToolKindSearch   // Content/file search (grep, find)
-	ToolKindSubagent = extensions.ToolKindSubagent // Subagent spawning (subagent)
+	ToolKindSubagent = extensions.ToolKindSubagent // Subagent spawning (subagent, subagent_wait, subagent_cancel)
 )
 
 // toolKindFor returns the ToolKind for a given tool name, defaulting to
@@ -319,16 +332,19 @@ type ToolResultEvent struct {
 
 // ToolResultMetadata carries structured data from tool executions.
 type ToolResultMetadata struct {
-	FileDiffs         []FileDiffInfo `json:"file_diffs,omitempty"`          // Present for edit/write tools
+	FileDiffs         []FileDiffInfo `json:"file_diffs,omitempty"`          // Present for edit/write/apply_patch tools
 	SubagentSessionID string         `json:"subagent_session_id,omitempty"` // Present for subagent tool
 }
 
-// FileDiffInfo describes a file modification from an edit or write tool.
+// FileDiffInfo describes a file modification from an edit, write or
+// apply_patch tool.
 type FileDiffInfo struct {
-	Path       string      `json:"path"`             // Absolute file path
-	Additions  int         `json:"additions"`        // Lines added
-	Deletions  int         `json:"deletions"`        // Lines removed
-	IsNew      bool        `json:"is_new,omitempty"` // True if file was created (write only)
+	Path       string      `json:"path"`                 // Absolute file path
+	Additions  int         `json:"additions"`            // Lines added
+	Deletions  int         `json:"deletions"`            // Lines removed
+	IsNew      bool        `json:"is_new,omitempty"`     // True if file was created (write, apply_patch)
+	IsDeleted  bool        `json:"is_deleted,omitempty"` // True if file was deleted (apply_patch only)
+	OldPath    string      `json:"old_path,omitempty"`   // Previous absolute path of a renamed file (apply_patch only)
 	DiffBlocks []DiffBlock `json:"diff_blocks,omitempty"`
 }
 
@@ -370,6 +386,34 @@ type StepUsageEvent struct {
 // EventType implements Event.
 func (e StepUsageEvent) EventType() EventType { return EventStepUsage }
 
+// BudgetWarningEvent fires once when spend reaches the warning fraction of a
+// limit ("warnAt", 80% by default) and once more when it reaches the limit
+// itself. With Exceeded set the running turn is being stopped and further
+// turns fail with ErrBudgetExceeded.
+type BudgetWarningEvent struct {
+	// Scope is BudgetScopeRun, BudgetScopeDaily or BudgetScopeMonthly.
+	Scope    string
+	SpentUSD float64
+	LimitUSD float64
+	Exceeded bool
+}
+
+// EventType implements Event.
+func (e BudgetWarningEvent) EventType() EventType { return EventBudgetWarning }
+
+// SkillScopeEvent fires when a skill that declares allowed-tools is
+// activated (Active true) and again when its scope ends with the turn.
+// While any scope is active, tool calls outside every active skill's
+// allowed-tools fail with an error result.
+type SkillScopeEvent struct {
+	Skill        string
+	AllowedTools string // the skill's allowed-tools frontmatter, verbatim
+	Active       bool
+}
+
+// EventType implements Event.
+func (e SkillScopeEvent) EventType() EventType { return EventSkillScope }
+
 // CompactionEvent fires after a compaction attempt. On success Err is nil and
 // the summary/token/file fields are populated. On failure Err is non-nil and
 // the remaining fields are zero-valued, so embedders can wire symmetric
@@ -494,6 +538,21 @@ type RetryEvent struct {
 // EventType implements Event.
 func (e RetryEvent) EventType() EventType { return EventRetry }
 
+// ModelFailoverEvent fires when From failed with an overload, rate-limit,
+// context-overflow or auth error and Kit switched to To, the next model of
+// the fallback chain. The turn resumes on To, which stays active afterwards.
+type ModelFailoverEvent struct {
+	From string
+	To   string
+	// Reason is "overloaded", "rate_limit", "context_overflow" or "auth".
+	Reason string
+	// Error is the classified provider error that ended the attempt on From.
+	Error error
+}
+
+// EventType implements Event.
+func (e ModelFailoverEvent) EventType() EventType { return EventModelFailover }
+
 // PasswordPromptEvent fires when a sudo command needs a password.
 // The TUI should display a password prompt and send the result back via ResponseCh.
 type PasswordPromptEvent struct {
@@ -520,6 +579,55 @@ type PasswordPromptResponse struct {
 // EventType implements Event.
 func (e PasswordPromptEvent) EventType() EventType { return EventPasswordPrompt }
 
+// PermissionRequestEvent fires when a tool call matches an "ask" rule of the
+// permission policy (or no rule under an "ask" default). The UI should ask
+// the user and send the decision back via ResponseCh.
+type PermissionRequestEvent struct {
+	ToolCallID string
+	ToolName   string
+	ToolArgs   string
+	// Subject is the value the policy matched: the command for bash, the
+	// path for file tools, or empty for tools without one.
+	Subject string
+	// Rule lists the rules that PermissionAllowSession /
+	// PermissionAllowProject will grant, separated by ", ", e.g.
+	// "bash(go test ./...), bash(go vet ./...)".
+	Rule string
+	// ResponseCh receives the decision. The listener must send exactly one
+	// value, synchronously, before returning (the channel is buffered so
+	// this never blocks). When no reply is buffered after dispatch the call
+	// is denied, so a missing responder can never let a tool run unapproved.
+	ResponseCh chan<- PermissionResponse
+}
+
+// PermissionResponse carries the user's answer to a PermissionRequestEvent.
+type PermissionResponse struct {
+	Decision PermissionDecision
+}
+
+// EventType implements Event.
+func (e PermissionRequestEvent) EventType() EventType { return EventPermissionRequest }
+
+// MCPElicitationEvent fires when an MCP server sends elicitation/create to
+// ask the user for structured input. The UI should present Fields as a form
+// and send the answer back via ResponseCh.
+type MCPElicitationEvent struct {
+	// ServerName is the configured name of the asking server.
+	ServerName string
+	// Message explains what the server needs and why.
+	Message string
+	// Fields are the inputs requested, in schema order.
+	Fields []ElicitationField
+	// ResponseCh receives the answer. The listener must send exactly one
+	// value, synchronously, before returning (the channel is buffered so
+	// this never blocks). When no reply is buffered after dispatch the
+	// request is declined, so servers never wait on a missing responder.
+	ResponseCh chan<- MCPElicitationResponse
+}
+
+// EventType implements Event.
+func (e MCPElicitationEvent) EventType() EventType { return EventMCPElicitation }
+
 // ---------------------------------------------------------------------------
 // EventBus
 // ---------------------------------------------------------------------------
@@ -710,6 +818,18 @@ func (m *Kit) OnStepUsage(handler func(StepUsageEvent)) func() {
 	return subscribeTyped(m, handler)
 }
 
+// OnBudgetWarning registers a handler that fires only for
+// BudgetWarningEvent. Returns an unsubscribe function.
+func (m *Kit) OnBudgetWarning(handler func(BudgetWarningEvent)) func() {
+	return subscribeTyped(m, handler)
+}
+
+// OnSkillScope registers a handler that fires only for SkillScopeEvent.
+// Returns an unsubscribe function.
+func (m *Kit) OnSkillScope(handler func(SkillScopeEvent)) func() {
+	return subscribeTyped(m, handler)
+}
+
 // OnCompaction registers a handler that fires only for CompactionEvent.
 // Returns an unsubscribe function.
 func (m *Kit) OnCompaction(handler func(CompactionEvent)) func() {
@@ -786,6 +906,26 @@ func (m *Kit) OnRetry(handler func(RetryEvent)) func() {
 	return subscribeTyped(m, handler)
 }
 
+// OnModelFailover registers a handler that fires only for
+// ModelFailoverEvent. Returns an unsubscribe function.
+func (m *Kit) OnModelFailover(handler func(ModelFailoverEvent)) func() {
+	return subscribeTyped(m, handler)
+}
+
+// OnPermissionRequest registers a handler that fires only for
+// PermissionRequestEvent. The handler must reply on the event's ResponseCh
+// before returning. Returns an unsubscribe function.
+func (m *Kit) OnPermissionRequest(handler func(PermissionRequestEvent)) func() {
+	return subscribeTyped(m, handler)
+}
+
+// OnMCPElicitation registers a handler that fires only for
+// MCPElicitationEvent. The handler must reply on the event's ResponseCh
+// before returning. Returns an unsubscribe function.
+func (m *Kit) OnMCPElicitation(handler func(MCPElicitationEvent)) func() {
+	return subscribeTyped(m, handler)
+}
+
 // ---------------------------------------------------------------------------
 // Subagent event subscriptions
 // ---------------------------------------------------------------------------
@@ -207,10 +207,13 @@ func TestNewEventTypes(t *testing.T) {
 		{StreamFinishEvent{FinishReason: "stop"}, EventStreamFinish},
 		{ErrorEvent{Error: fmt.Errorf("test error")}, EventError},
 		{RetryEvent{Attempt: 1, Error: fmt.Errorf("retry error")}, EventRetry},
+		{ModelFailoverEvent{From: "a/b", To: "c/d", Reason: "overloaded"}, EventModelFailover},
 		{ToolCallStartEvent{}, EventToolCallStart},
 		{ToolCallDeltaEvent{}, EventToolCallDelta},
 		{ToolCallEndEvent{}, EventToolCallEnd},
 		{PasswordPromptEvent{}, EventPasswordPrompt},
+		{PermissionRequestEvent{}, EventPermissionRequest},
+		{MCPElicitationEvent{}, EventMCPElicitation},
 	}
 
 	for _, tt := range tests {
@@ -393,6 +393,24 @@ func (m *Kit) bridgeExtensions(runner *extensions.Runner) {
 		}
 	})
 
+	bridgeObserve(m, runner, extensions.SkillScope, func(ev SkillScopeEvent) extensions.Event {
+		return extensions.SkillScopeEvent{
+			Skill:        ev.Skill,
+			AllowedTools: ev.AllowedTools,
+			Active:       ev.Active,
+		}
+	})
+
+	// Failover switches the model without going through ctx.SetModel, so
+	// extensions hear about it as a ModelChange with source "failover".
+	bridgeObserve(m, runner, extensions.ModelChange, func(ev ModelFailoverEvent) extensions.Event {
+		return extensions.ModelChangeEvent{
+			NewModel:      ev.To,
+			PreviousModel: ev.From,
+			Source:        "failover",
+		}
+	})
+
 	// --- PrepareStep hook ---
 	// Extension PrepareStep → SDK PrepareStep hook.
 	// Same pattern as ContextPrepare: convert LLMMessage ↔ ContextMessage.
@@ -550,7 +568,12 @@ func llmUsageMeta(m *Kit, usage LLMUsage) (provider, modelID string, cost float6
 	if m == nil {
 		return "", "", 0
 	}
-	modelString := m.GetModelString()
+	return priceUsage(m, m.GetModelString(), usage)
+}
+
+// priceUsage is llmUsageMeta for a call made with modelString rather than
+// the current model.
+func priceUsage(m *Kit, modelString string, usage LLMUsage) (provider, modelID string, cost float64) {
 	if modelString == "" {
 		return "", "", 0
 	}
@@ -0,0 +1,174 @@
+package kit
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"charm.land/fantasy"
+)
+
+// This file implements model failover: when the active model fails with an
+// error another model may not share — overload, rate limit, context
+// overflow, auth — and the provider retries and reactive compaction could
+// not clear it, Kit switches to the next model of the configured chain and
+// resumes the turn there.
+//
+// The chain is the configured "model" followed by "fallback-models" (a
+// list-valued "model" in .kit.yml sets both). Failover only walks forward:
+// the fallback stays active for later turns, so a provider that is down is
+// not hit again on every prompt.
+
+// failoverReasons maps the sentinels that trigger failover to the reason
+// reported in ModelFailoverEvent.
+var failoverReasons = []struct {
+	sentinel error
+	reason   string
+}{
+	{ErrOverloaded, "overloaded"},
+	{ErrRateLimit, "rate_limit"},
+	{ErrContextOverflow, "context_overflow"},
+	{ErrAuth, "auth"},
+}
+
+// failoverReason returns why err warrants switching models, or "" when it
+// does not (invalid requests and unclassified errors would fail the same way
+// on any model).
+func failoverReason(err error) string {
+	if err == nil {
+		return ""
+	}
+	err = ClassifyProviderError(err)
+	for _, r := range failoverReasons {
+		if errors.Is(err, r.sentinel) {
+			return r.reason
+		}
+	}
+	return ""
+}
+
+// FallbackModels returns the models Kit fails over to, in order, after the
+// configured model: [Options.FallbackModels], or "fallback-models" from the
+// configuration.
+func (m *Kit) FallbackModels() []string {
+	return m.v.GetStringSlice("fallback-models")
+}
+
+// failoverCandidates returns the models to try after current fails: the
+// chain entries past current, or every fallback when current is not part of
+// the chain (it was picked with /model or SetModel). Models already tried
+// this turn are skipped.
+func (m *Kit) failoverCandidates(current string, tried map[string]bool) []string {
+	var chain []string
+	for _, model := range append([]string{m.v.GetString("model")}, m.FallbackModels()...) {
+		if model != "" && !slices.Contains(chain, model) {
+			chain = append(chain, model)
+		}
+	}
+	rest := chain[min(1, len(chain)):]
+	if i := slices.Index(chain, current); i >= 0 {
+		rest = chain[i+1:]
+	}
+	var out []string
+	for _, model := range rest {
+		if model != current && !tried[model] {
+			out = append(out, model)
+		}
+	}
+	return out
+}
+
+// failover switches to the next usable model after cause ended the current
+// attempt, records the change in the session and rebuilds the request
+// context for the new model. It returns false when the chain is exhausted,
+// leaving the model unchanged.
+func (m *Kit) failover(ctx context.Context, cause error, tried map[string]bool) ([]fantasy.Message, bool) {
+	from := m.modelString
+	tried[from] = true
+	for _, to := range m.failoverCandidates(from, tried) {
+		tried[to] = true
+		if err := m.SetModel(ctx, to); err != nil {
+			// Unusable entry (unknown provider, missing key): try the next.
+			continue
+		}
+		if provider, modelID, err := ParseModelString(to); err == nil {
+			_, _ = m.session.AppendModelChange(provider, modelID)
+		}
+		m.events.emit(ModelFailoverEvent{
+			From:   from,
+			To:     to,
+			Reason: failoverReason(cause),
+			Error:  ClassifyProviderError(cause),
+		})
+
+		return m.turnContext(), true
+	}
+	return nil, false
+}
+
+// turnContext builds the request context for the active model from the
+// session and runs the ContextPrepare hooks on it.
+func (m *Kit) turnContext() []fantasy.Message {
+	messages, provider, _ := m.session.BuildContext()
+	if hookResult := m.contextPrepare.run(ContextPrepareHook{Messages: messages}); hookResult != nil && hookResult.Messages != nil {
+		messages = hookResult.Messages
+	}
+	return m.forActiveProvider(messages, provider)
+}
+
+// forActiveProvider translates messages built from the session when some of
+// them may come from another provider: this Kit has switched providers, by
+// failover or SetModel, or the session last recorded a different provider
+// than the active one. The session keeps what the previous provider produced,
+// so every later turn needs the translation, not only the failover retry.
+func (m *Kit) forActiveProvider(messages []fantasy.Message, sessionProvider string) []fantasy.Message {
+	if m.switchedProvider || (sessionProvider != "" && sessionProvider != providerOf(m.modelString)) {
+		return translateForProvider(messages)
+	}
+	return messages
+}
+
+// providerOf returns the provider part of a "provider/model" string.
+func providerOf(model string) string {
+	provider, _, _ := ParseModelString(model)
+	return provider
+}
+
+// translateForProvider returns a copy of messages another provider accepts.
+// Reasoning parts are dropped: their signatures only verify with the
+// provider that produced them, and some providers reject reasoning they
+// cannot verify. Provider options (cache control, reasoning metadata) are
+// cleared, since they are keyed by and meaningful to one provider only.
+// Assistant messages left empty are dropped.
+func translateForProvider(messages []fantasy.Message) []fantasy.Message {
+	out := make([]fantasy.Message, 0, len(messages))
+	for _, msg := range messages {
+		parts := make([]fantasy.MessagePart, 0, len(msg.Content))
+		for _, part := range msg.Content {
+			switch p := part.(type) {
+			case fantasy.ReasoningPart:
+				continue
+			case fantasy.TextPart:
+				p.ProviderOptions = nil
+				part = p
+			case fantasy.FilePart:
+				p.ProviderOptions = nil
+				part = p
+			case fantasy.ToolCallPart:
+				p.ProviderOptions = nil
+				part = p
+			case fantasy.ToolResultPart:
+				p.ProviderOptions = nil
+				part = p
+			}
+			parts = append(parts, part)
+		}
+		if len(parts) == 0 && msg.Role == fantasy.MessageRoleAssistant {
+			continue
+		}
+		msg.Content = parts
+		msg.ProviderOptions = nil
+		out = append(out, msg)
+	}
+	return out
+}
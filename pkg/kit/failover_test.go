@@ -0,0 +1,134 @@
+package kit
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/kit/internal/session"
+	"github.com/spf13/viper"
+)
+
+func TestFailoverReason(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{errors.New(`status 529: {"type":"overloaded_error"}`), "overloaded"},
+		{errors.New("HTTP status 429: rate limit exceeded"), "rate_limit"},
+		{errors.New("prompt is too long: 210000 tokens"), "context_overflow"},
+		{errors.New("status 401 unauthorized"), "auth"},
+		{errors.New("status 400 bad request"), ""},
+		{errors.New("something unexpected"), ""},
+	}
+	for _, tc := range cases {
+		if got := failoverReason(tc.err); got != tc.want {
+			t.Errorf("failoverReason(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestFailoverCandidates(t *testing.T) {
+	v := viper.New()
+	v.Set("model", "anthropic/a")
+	v.Set("fallback-models", []string{"openrouter/a", "ollama/b", "openrouter/a"})
+	k := &Kit{v: v}
+
+	cases := []struct {
+		current string
+		tried   map[string]bool
+		want    []string
+	}{
+		{"anthropic/a", nil, []string{"openrouter/a", "ollama/b"}},
+		{"openrouter/a", nil, []string{"ollama/b"}},
+		{"ollama/b", nil, nil},
+		// A model picked outside the chain fails over to the whole list.
+		{"openai/c", nil, []string{"openrouter/a", "ollama/b"}},
+		{"anthropic/a", map[string]bool{"openrouter/a": true}, []string{"ollama/b"}},
+	}
+	for _, tc := range cases {
+		if got := k.failoverCandidates(tc.current, tc.tried); !slices.Equal(got, tc.want) {
+			t.Errorf("failoverCandidates(%q, %v) = %v, want %v", tc.current, tc.tried, got, tc.want)
+		}
+	}
+}
+
+func TestTranslateForProvider(t *testing.T) {
+	opts := fantasy.ProviderOptions{"anthropic": nil}
+	msgs := []fantasy.Message{
+		{Role: fantasy.MessageRoleUser, Content: []fantasy.MessagePart{fantasy.TextPart{Text: "hi"}}, ProviderOptions: opts},
+		{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{
+			fantasy.ReasoningPart{Text: "thinking", ProviderOptions: opts},
+			fantasy.TextPart{Text: "hello", ProviderOptions: opts},
+			fantasy.ToolCallPart{ToolCallID: "1", ToolName: "read", ProviderOptions: opts},
+		}},
+		{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{fantasy.ReasoningPart{Text: "only thoughts"}}},
+		{Role: fantasy.MessageRoleTool, Content: []fantasy.MessagePart{fantasy.ToolResultPart{ToolCallID: "1", ProviderOptions: opts}}},
+	}
+
+	out := translateForProvider(msgs)
+
+	if len(out) != 3 {
+		t.Fatalf("got %d messages, want the reasoning-only one dropped", len(out))
+	}
+	if len(out[1].Content) != 2 {
+		t.Fatalf("assistant parts = %v, want reasoning dropped", out[1].Content)
+	}
+	for _, msg := range out {
+		if msg.ProviderOptions != nil {
+			t.Errorf("%s message kept provider options", msg.Role)
+		}
+		for _, part := range msg.Content {
+			if part.Options() != nil {
+				t.Errorf("%T kept provider options", part)
+			}
+		}
+	}
+	if msgs[1].Content[1].Options() == nil || len(msgs[1].Content) != 3 {
+		t.Error("input messages were mutated")
+	}
+}
+
+func TestTurnContext_AfterProviderSwitch(t *testing.T) {
+	ts := session.InMemoryTreeSession(t.TempDir())
+	_, _ = ts.AppendLLMMessage(fantasy.NewUserMessage("hi"))
+	_, _ = ts.AppendLLMMessage(fantasy.Message{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{
+		fantasy.ReasoningPart{Text: "thinking"},
+		fantasy.TextPart{Text: "hello"},
+	}})
+	hasReasoning := func(messages []fantasy.Message) bool {
+		for _, msg := range messages {
+			for _, part := range msg.Content {
+				if _, ok := part.(fantasy.ReasoningPart); ok {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	k := &Kit{
+		session:        NewTreeManagerAdapter(ts),
+		modelString:    "anthropic/a",
+		contextPrepare: newHookRegistry[ContextPrepareHook, ContextPrepareResult](),
+	}
+	if !hasReasoning(k.turnContext()) {
+		t.Fatal("reasoning dropped without a provider switch")
+	}
+
+	// The turn after a failover to another provider is translated too, not
+	// only the retry inside failover.
+	k.modelString, k.switchedProvider = "openai/b", true
+	if got := k.turnContext(); hasReasoning(got) || len(got) != 2 {
+		t.Errorf("turn after the switch = %v, want reasoning dropped", got)
+	}
+
+	// So is a resumed session last recorded under another provider.
+	_, _ = ts.AppendModelChange("anthropic", "a")
+	k.switchedProvider = false
+	if hasReasoning(k.turnContext()) {
+		t.Error("resumed session from another provider kept its reasoning")
+	}
+}
@@ -234,7 +234,8 @@ func (hr *hookRegistry[In, Out]) hasHooks() bool {
 // Hook registration methods on Kit
 // ---------------------------------------------------------------------------
 
-// OnBeforeToolCall registers a hook that fires before each tool execution.
+// OnBeforeToolCall registers a hook that fires before each tool execution,
+// including those of the subagents this Kit spawns, after their own hooks.
 // Return a non-nil BeforeToolCallResult with Block=true to prevent the tool
 // from running. Hooks execute in priority order; the first non-nil result wins.
 // Returns an unregister function.
@@ -311,6 +312,9 @@ type hookedTool struct {
 	inner           Tool
 	beforeToolCall  *hookRegistry[BeforeToolCallHook, BeforeToolCallResult]
 	afterToolResult *hookRegistry[AfterToolResultHook, AfterToolResultResult]
+	// inherited are the BeforeToolCall registries of the Kits that spawned
+	// this one as a subagent, nearest first.
+	inherited []*hookRegistry[BeforeToolCallHook, BeforeToolCallResult]
 }
 
 func (h *hookedTool) Info() LLMToolInfo                       { return h.inner.Info() }
@@ -320,9 +324,13 @@ func (h *hookedTool) SetProviderOptions(o LLMProviderOptions) { h.inner.SetProvi
 func (h *hookedTool) Run(ctx context.Context, call LLMToolCall) (LLMToolResponse, error) {
 	toolName := h.inner.Info().Name
 
-	// 1. BeforeToolCall — can block execution.
-	if h.beforeToolCall.hasHooks() {
-		if result := h.beforeToolCall.run(BeforeToolCallHook{
+	// 1. BeforeToolCall — can block execution. The hooks of the parent
+	// Kits run too, so a subagent cannot do what its parent would refuse.
+	for _, registry := range append([]*hookRegistry[BeforeToolCallHook, BeforeToolCallResult]{h.beforeToolCall}, h.inherited...) {
+		if !registry.hasHooks() {
+			continue
+		}
+		if result := registry.run(BeforeToolCallHook{
 			ToolCallID: call.ID,
 			ToolName:   toolName,
 			ToolArgs:   call.Input,
@@ -366,6 +374,7 @@ func (h *hookedTool) Run(ctx context.Context, call LLMToolCall) (LLMToolResponse
 func hookToolWrapper(
 	beforeToolCall *hookRegistry[BeforeToolCallHook, BeforeToolCallResult],
 	afterToolResult *hookRegistry[AfterToolResultHook, AfterToolResultResult],
+	inherited []*hookRegistry[BeforeToolCallHook, BeforeToolCallResult],
 ) func([]Tool) []Tool {
 	return func(tools []Tool) []Tool {
 		wrapped := make([]Tool, len(tools))
@@ -374,6 +383,7 @@ func hookToolWrapper(
 				inner:           tool,
 				beforeToolCall:  beforeToolCall,
 				afterToolResult: afterToolResult,
+				inherited:       inherited,
 			}
 		}
 		return wrapped
@@ -3,6 +3,7 @@ package kit
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -376,7 +377,7 @@ func TestHookToolWrapper(t *testing.T) {
 	before := newHookRegistry[BeforeToolCallHook, BeforeToolCallResult]()
 	after := newHookRegistry[AfterToolResultHook, AfterToolResultResult]()
 
-	wrapper := hookToolWrapper(before, after)
+	wrapper := hookToolWrapper(before, after, nil)
 
 	tools := []Tool{
 		&mockAgentTool{name: "tool_a"},
@@ -414,6 +415,31 @@ func TestHookToolWrapper(t *testing.T) {
 	}
 }
 
+func TestHookToolWrapper_InheritedHooks(t *testing.T) {
+	before := newHookRegistry[BeforeToolCallHook, BeforeToolCallResult]()
+	after := newHookRegistry[AfterToolResultHook, AfterToolResultResult]()
+	parent := newHookRegistry[BeforeToolCallHook, BeforeToolCallResult]()
+
+	ran := false
+	mock := &mockAgentTool{name: "bash", runFn: func(context.Context, LLMToolCall) (LLMToolResponse, error) {
+		ran = true
+		return LLMToolResponse{}, nil
+	}}
+	wrapped := hookToolWrapper(before, after, []*hookRegistry[BeforeToolCallHook, BeforeToolCallResult]{parent})([]Tool{mock})
+
+	// The parent's hook, registered after the child was built, still
+	// blocks the child's call.
+	parent.register(HookPriorityNormal, func(h BeforeToolCallHook) *BeforeToolCallResult {
+		return &BeforeToolCallResult{Block: true, Reason: "not in read-only mode"}
+	})
+	if _, err := wrapped[0].Run(context.Background(), LLMToolCall{}); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("err = %v, want the parent's block", err)
+	}
+	if ran {
+		t.Error("tool ran although the parent's hook blocked it")
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Hook type tests (BeforeTurn, AfterTurn)
 // ---------------------------------------------------------------------------
@@ -0,0 +1,40 @@
+package kit
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/kit/internal/core"
+)
+
+// BackgroundJob is a snapshot of a bash command started with the bash
+// tool's run_in_background argument.
+type BackgroundJob = core.JobInfo
+
+// JobManager tracks background bash jobs. Pass one to [WithJobManager] when
+// building a custom tool set so bash, bash_output and bash_kill share it.
+type JobManager = core.JobManager
+
+// NewJobManager returns an empty background job manager.
+var NewJobManager = core.NewJobManager
+
+// WithJobManager enables background bash jobs tracked by the given manager.
+var WithJobManager = core.WithJobManager
+
+// ListBackgroundJobs returns every background job started by the agent in
+// this Kit instance, oldest first, including finished ones. Jobs started by
+// custom tool sets with their own [JobManager] are not included.
+func (m *Kit) ListBackgroundJobs() []BackgroundJob {
+	if m.jobs == nil {
+		return nil
+	}
+	return m.jobs.List()
+}
+
+// KillBackgroundJob stops the background job with the given ID and every
+// process it started. Killing a job that already exited is not an error.
+func (m *Kit) KillBackgroundJob(id string) (BackgroundJob, error) {
+	if m.jobs == nil {
+		return BackgroundJob{}, fmt.Errorf("no background job %q", id)
+	}
+	return m.jobs.Kill(id)
+}
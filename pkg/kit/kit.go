@@ -3,6 +3,7 @@ package kit
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -16,16 +17,20 @@ import (
 	"charm.land/fantasy"
 
 	"github.com/mark3labs/kit/internal/agent"
+	"github.com/mark3labs/kit/internal/agents"
 	"github.com/mark3labs/kit/internal/config"
 	"github.com/mark3labs/kit/internal/core"
 	"github.com/mark3labs/kit/internal/extensions"
 	"github.com/mark3labs/kit/internal/kitsetup"
+	"github.com/mark3labs/kit/internal/lsp"
 	"github.com/mark3labs/kit/internal/message"
 	"github.com/mark3labs/kit/internal/models"
 	"github.com/mark3labs/kit/internal/session"
 	"github.com/mark3labs/kit/internal/skills"
 	"github.com/mark3labs/kit/internal/skilltool"
 	"github.com/mark3labs/kit/internal/tools"
+	"github.com/mark3labs/kit/internal/trust"
+	"github.com/mark3labs/kit/internal/usage"
 
 	"github.com/spf13/viper"
 )
@@ -48,11 +53,14 @@ type Kit struct {
 	autoCompact    bool
 	compactionOpts *CompactionOptions
 	contextFiles   []*ContextFile
+	contextDisc    *contextDiscovery // lazy per-directory context lookup; nil when disabled
 	skills         []*skills.Skill
 	namedAgents    []*AgentDefinition // named agent definitions discovered at construction
 	extRunner      *extensions.Runner
+	extTrust       extensions.ProjectTrustFunc // gates project-local extensions; nil loads them all
 	bufferedLogger *tools.BufferedDebugLogger
 	authHandler    MCPAuthHandler // OAuth handler for remote MCP servers (may need Close)
+	mcpClient      *mcpClientBridge
 	opts           *Options       // stored for reload operations (skills, etc.)
 	mcpConfig      *config.Config // loaded MCP/server config, shared with subagents
 
@@ -64,6 +72,10 @@ type Kit struct {
 	// store so cobra flag bindings remain in effect.
 	v *viper.Viper
 
+	// switchedProvider is set once SetModel moves to another provider, after
+	// which context built from the session is translated for the active one.
+	switchedProvider bool
+
 	// hasCustomSystemPrompt is true when the user explicitly configured a
 	// system prompt (via --system-prompt flag, config file, or SDK option).
 	// When false, per-model system prompts from modelSettings/customModels
@@ -130,6 +142,44 @@ type Kit struct {
 	// are kept separately on the extension runner and recomposed with this
 	// slice when either side changes.
 	runtimeExtraTools []Tool
+
+	// checkpoints captures file state before tools modify it so turns can be
+	// rewound with RestoreCheckpoint. Nil when checkpoints are disabled.
+	checkpoints *checkpointRecorder
+
+	// jobs tracks bash commands started with run_in_background. They are
+	// killed when the Kit is closed.
+	jobs *core.JobManager
+
+	// subagents bounds concurrent subagents and tracks the ones started in
+	// the background. They are cancelled when the Kit is closed.
+	subagents *subagentJobs
+
+	// worktreeToolOpts configure the core tools rebuilt for subagents that
+	// run in their own git worktree; worktreeMu serializes the git commands
+	// that add and remove worktrees.
+	worktreeToolOpts []core.ToolOption
+	worktreeMu       sync.Mutex
+
+	// lsp runs the language servers behind the code intelligence tools.
+	// Nil when none are configured; servers are shut down on Close.
+	lsp *lsp.Manager
+
+	// spend prices LLM calls, records them in the usage ledger and
+	// enforces the spend limits.
+	spend *spendTracker
+
+	// skillScope restricts tools to the allowed-tools of skills activated
+	// during the current turn.
+	skillScope *skillScope
+
+	// parent is the Kit that spawned this one as a subagent, charged for
+	// its spend as it happens. Nil for top-level instances.
+	parent *Kit
+
+	// workDir is the directory the instance works in, reported in the
+	// system prompt.
+	workDir string
 }
 
 // Subscribe registers an EventListener that will be called for every lifecycle
@@ -153,12 +203,13 @@ func (m *Kit) GetToolNames() []string {
 	return names
 }
 
-// GetToolsForSubagent like GetTools but eliminates subagent tool
-// to avoid infinite recursion.
+// GetToolsForSubagent like GetTools but eliminates the subagent tools
+// (subagent, subagent_wait, subagent_cancel) to avoid infinite recursion.
 func (m *Kit) GetToolsForSubagent() []Tool {
 	var tools []Tool
 	for _, t := range m.agent.GetTools() {
-		if t.Info().Name == "subagent" {
+		switch t.Info().Name {
+		case "subagent", "subagent_wait", "subagent_cancel":
 			continue
 		}
 		tools = append(tools, t)
@@ -814,6 +865,9 @@ func (m *Kit) SetModel(ctx context.Context, modelString string) error {
 		return err
 	}
 
+	if m.modelString != "" && providerOf(m.modelString) != providerOf(modelString) {
+		m.switchedProvider = true
+	}
 	m.modelString = modelString
 
 	// Update extension context's Model field.
@@ -847,7 +901,10 @@ func (m *Kit) GetSystemPromptSource() string {
 // It acquires a read lock on runtimeMu while snapshotting contextFiles and
 // skills, so callers must not hold the write lock.
 func (m *Kit) composeSystemPrompt(basePrompt string) string {
-	cwd, _ := os.Getwd()
+	cwd := m.workDir
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
 	pb := skills.NewPromptBuilder(basePrompt)
 
 	m.runtimeMu.RLock()
@@ -929,7 +986,7 @@ func (m *Kit) ReloadExtensions() error {
 
 	// Re-load from disk.
 	extraPaths := m.v.GetStringSlice("extension")
-	loaded, err := extensions.LoadExtensions(extraPaths)
+	loaded, err := extensions.LoadExtensionsWithTrust(extraPaths, m.extTrust)
 	if err != nil {
 		return fmt.Errorf("reloading extensions: %w", err)
 	}
@@ -1009,7 +1066,7 @@ func (m *Kit) ExecuteCompletion(ctx context.Context, req CompleteRequest) (Compl
 		agentOpts = append(agentOpts, fantasy.WithProviderOptions(providerOps))
 	}
 
-	completionAgent := fantasy.NewAgent(llmModel, agentOpts...)
+	completionAgent := fantasy.NewAgent(m.metered(llmModel, usedModel), agentOpts...)
 
 	// Convert extension SessionMessage history to LLM message slice.
 	var messages []fantasy.Message
@@ -1077,6 +1134,15 @@ type Options struct {
 	ConfigFile   string // Override config file path
 	MaxSteps     int    // Override max steps (0 = use default)
 
+	// FallbackModels lists models, in "provider/model" format, to switch to
+	// in order when the active model fails with an overload, rate-limit,
+	// context-overflow or auth error the provider retries could not clear.
+	// The switch happens mid-turn: the turn resumes on the next model, the
+	// change is recorded in the session and a ModelFailoverEvent fires.
+	// Overrides "fallback-models" in .kit.yml, which a list-valued "model"
+	// also sets.
+	FallbackModels []string
+
 	// Streaming enables or disables streaming output. It is a pointer so the
 	// SDK can distinguish "unset" (nil) from an explicit choice, mirroring the
 	// sampling-parameter fields below. nil leaves streaming to the precedence
@@ -1190,12 +1256,78 @@ type Options struct {
 	// "bash-max-timeout" config value, then the built-in default (600s).
 	BashMaxTimeout int
 
+	// Sandbox selects where the bash tool runs commands: on the host, in a
+	// bubblewrap sandbox, or in a docker/podman container. Nil falls back
+	// to the "sandbox" block of the config file; when neither is set
+	// commands run on the host. New fails if the selected backend is not
+	// installed. Only consumed when core tools are built from CoreToolList.
+	Sandbox *SandboxConfig
+
+	// BashExecutor runs bash tool commands instead of the host executor.
+	// Implement [BashRunner] to run them where a local
+	// process cannot reach, such as an editor's terminal. It is ignored when
+	// the config file selects a sandbox, and New fails when Sandbox selects
+	// one too. Only consumed when core tools are built from CoreToolList.
+	BashExecutor BashExecutor
+
+	// FileSystem routes the file access of the file tools and of checkpoint
+	// snapshots and restores,
+	// e.g. through an editor's buffers. Nil uses the local disk. The tools
+	// only consume it when built from CoreToolList.
+	FileSystem FileSystem
+
+	// LSP configures the language servers behind the definition,
+	// references, hover, diagnostics, rename_symbol and workspace_symbols
+	// tools. Nil falls back to the "lsp" block of the config file; when
+	// neither names a server the tools are not registered. Servers start on
+	// first use. Only consumed when core tools are built from CoreToolList.
+	LSP LSPConfig
+
+	// PermissionPolicy sets the allow/ask/deny rules evaluated before every
+	// tool call. Nil falls back to the "permissions" block of the config
+	// file; when neither is set every tool call runs. Calls resolving to
+	// "ask" emit a PermissionRequestEvent and are denied when no listener
+	// replies, so headless embedders fail closed.
+	PermissionPolicy *PermissionPolicy
+
+	// MaxCostUSD stops the agent once this Kit instance, subagents included,
+	// has spent this many US dollars at the model registry's prices. Zero
+	// falls back to the "max-cost" config value; when neither is set there
+	// is no cap.
+	MaxCostUSD float64
+
+	// MaxParallelSubagents bounds how many subagents run at once, whether
+	// started by the model or through [Kit.Subagent], [Kit.StartSubagent]
+	// and [Kit.RunSubagents]; the rest queue. Zero falls back to the
+	// "max-parallel-subagents" config value, then to 4.
+	MaxParallelSubagents int
+
+	// Budget sets daily and monthly spend limits for the project, counted
+	// from the usage ledger. Nil falls back to the "budget" block of the
+	// config file.
+	Budget *Budget
+
+	// NoUsageLedger stops recording the tokens and cost of each LLM call to
+	// ~/.kit/usage.jsonl. Budgets then only see this instance's spend. Also
+	// settable via "no-usage-ledger".
+	NoUsageLedger bool
+
 	// Session configuration
 	SessionDir  string // Base directory for session discovery (default: cwd)
 	SessionPath string // Open a specific session file by path
 	Continue    bool   // Continue the most recent session for SessionDir
 	NoSession   bool   // Ephemeral mode — in-memory session, no persistence
 
+	// NoCheckpoints disables recording the prior content of files modified
+	// by the write and edit tools. Without checkpoints [Kit.RestoreCheckpoint]
+	// only rewinds the conversation. Also settable via "no-checkpoints".
+	NoCheckpoints bool
+
+	// GitCheckpoints additionally snapshots the tracked git working tree
+	// before the first mutating tool call of each turn, so changes made by
+	// bash can be rewound too. Also settable via "git-checkpoints".
+	GitCheckpoints bool
+
 	// Skills
 	Skills    []string // Explicit skill files/dirs to load (empty = auto-discover)
 	SkillsDir string   // Direct skills directory to scan (overrides auto-discovery; scanned as-is)
@@ -1216,13 +1348,29 @@ type Options struct {
 	// ~/.config/kit/trusted-projects.json and not prompted again.
 	SkillTrustPrompt func(projectDir string, skillCount int) TrustDecision
 
+	// ExtensionTrustPrompt is SkillTrustPrompt for project-local extensions
+	// (under <project>/.kit/extensions or <project>/.kit/git), which run
+	// code rather than add instructions. It shares the trust allowlist, and
+	// when nil project-local extensions load without prompting.
+	ExtensionTrustPrompt func(projectDir string, extensionCount int) TrustDecision
+
 	// NoExtensions disables Yaegi extension loading entirely.
 	NoExtensions bool
 
 	// NoContextFiles disables automatic loading of project context files
-	// (e.g. AGENTS.md) from the working directory.
+	// (AGENTS.md, CLAUDE.md, .github/copilot-instructions.md) from the
+	// user config directory, the project root down to the working
+	// directory, and directories the file tools touch later.
 	NoContextFiles bool
 
+	// UntrustedProject ignores everything the working directory's project
+	// could supply: its .kit.yml and .kit.local.yml (and so their MCP
+	// servers, permissions and sandbox settings), its context files, and its
+	// skills, agents and extensions. User-level settings still apply. Use it
+	// to run Kit in a checkout of someone else's code. Also settable via
+	// "untrusted-project".
+	UntrustedProject bool
+
 	// NoAgents disables discovery of named agent definitions (built-ins and
 	// .agents/agents/ / .kit/agents/ / ~/.config/kit/agents/ files). When
 	// set, the subagent tool advertises no named agents and
@@ -1358,6 +1506,11 @@ type Options struct {
 	// must not block; long work should run on a goroutine.
 	MCPTaskProgress MCPTaskProgressHandler
 
+	// MCPRoots lists extra directories advertised to MCP servers as roots,
+	// alongside the working directory. Nil falls back to the "mcpRoots"
+	// list of the config file. Change them later with [Kit.SetMCPRoots].
+	MCPRoots []string
+
 	// CLI is optional CLI-specific configuration. SDK users leave this nil.
 	CLI *CLIOptions
 
@@ -1365,6 +1518,16 @@ type Options struct {
 	// If nil (default), Kit uses the built-in file-based TreeManager.
 	// When provided, SessionPath, Continue, and NoSession options are ignored.
 	SessionManager SessionManager
+
+	// parent is set by [Kit.Subagent] on the child's Options so the child
+	// charges its spend to the parent as it goes.
+	parent *Kit
+
+	// workDir is set by [Kit.Subagent] for a child isolated in a git
+	// worktree. It replaces SessionDir and the process directory as the
+	// root of the permission gate, checkpoints, context and skill discovery
+	// and the system prompt; sessions are still stored under SessionDir.
+	workDir string
 }
 
 // CLIOptions holds fields only relevant to the CLI binary. SDK users should
@@ -1460,6 +1623,7 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 		modelString           string
 		cwd                   string
 		contextFiles          []*ContextFile
+		contextDisc           *contextDiscovery
 		loadedSkills          []*Skill
 		namedAgents           []*AgentDefinition
 		mcpConfig             *config.Config
@@ -1470,6 +1634,11 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 		streaming             bool
 		bashTimeout           int
 		bashMaxTimeout        int
+		noCheckpoints         bool
+		gitCheckpoints        bool
+		maxCost               float64
+		maxParallelSubagents  int
+		noUsageLedger         bool
 		hasCustomSystemPrompt bool
 		systemPromptSource    string
 		capturedBasePrompt    string
@@ -1487,6 +1656,9 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 		// We key off opts.CLI (not a config value) because setSDKDefaults always
 		// seeds "model", which would otherwise mask an empty store.
 		// SkipConfig bypasses .kit.yml file loading (viper defaults and env vars still apply).
+		if opts.UntrustedProject {
+			v.Set("untrusted-project", true)
+		}
 		if !opts.SkipConfig && opts.CLI == nil {
 			if err := initConfig(v, opts.ConfigFile, false); err != nil {
 				return fmt.Errorf("failed to initialize config: %w", err)
@@ -1502,6 +1674,9 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 		if opts.Model != "" {
 			v.Set("model", opts.Model)
 		}
+		if len(opts.FallbackModels) > 0 {
+			v.Set("fallback-models", opts.FallbackModels)
+		}
 		if opts.SystemPrompt != "" {
 			v.Set("system-prompt", opts.SystemPrompt)
 		}
@@ -1560,14 +1735,18 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 		}
 
 		// Resolve working directory for context/skill discovery.
-		cwd = opts.SessionDir
-		if cwd == "" {
-			cwd, _ = os.Getwd()
-		}
+		cwd = optsWorkDir(opts)
 
-		// Load context files (AGENTS.md) from the project root.
+		// Load context files (AGENTS.md and friends) from the user config
+		// directory and from the project root down to cwd. An untrusted
+		// project keeps only the user's file.
+		untrusted := v.GetBool("untrusted-project")
 		if !opts.NoContextFiles {
-			contextFiles = loadContextFiles(cwd)
+			if untrusted {
+				contextFiles = userContextFiles()
+			} else {
+				contextDisc, contextFiles = discoverContextFiles(cwd)
+			}
 		}
 
 		// Load skills — either from explicit paths or via auto-discovery.
@@ -1588,6 +1767,7 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 			mergedOpts := *opts
 			mergedOpts.Skills = skillPaths
 			mergedOpts.SkillsDir = skillsDir
+			mergedOpts.UntrustedProject = untrusted
 			var err error
 			loadedSkills, err = loadSkills(&mergedOpts)
 			if err != nil {
@@ -1611,7 +1791,11 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 		// a warning is printed unless quiet.
 		if !opts.NoAgents && !v.GetBool("no-agents") {
 			var agErr error
-			namedAgents, agErr = LoadAgentDefinitions(cwd)
+			if untrusted {
+				namedAgents, agErr = agents.LoadUserAgents()
+			} else {
+				namedAgents, agErr = LoadAgentDefinitions(cwd)
+			}
 			if agErr != nil && !opts.Quiet {
 				fmt.Fprintf(os.Stderr, "Warning: failed to load some agent definitions: %v\n", agErr)
 			}
@@ -1733,6 +1917,17 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 		if bashMaxTimeout == 0 {
 			bashMaxTimeout = v.GetInt("bash-max-timeout")
 		}
+		noCheckpoints = opts.NoCheckpoints || v.GetBool("no-checkpoints")
+		gitCheckpoints = opts.GitCheckpoints || v.GetBool("git-checkpoints")
+		maxCost = opts.MaxCostUSD
+		if maxCost == 0 {
+			maxCost = v.GetFloat64("max-cost")
+		}
+		maxParallelSubagents = opts.MaxParallelSubagents
+		if maxParallelSubagents == 0 {
+			maxParallelSubagents = v.GetInt("max-parallel-subagents")
+		}
+		noUsageLedger = opts.NoUsageLedger || v.GetBool("no-usage-ledger")
 
 		return nil
 	}(); err != nil {
@@ -1781,6 +1976,103 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 	beforeCompact := newHookRegistry[BeforeCompactHook, BeforeCompactResult]()
 	prepareStep := newHookRegistry[PrepareStepHook, PrepareStepResult]()
 
+	// The permission gate asks for approval through the event bus, so the
+	// bus is created up front and handed to the Kit below. Explicit options
+	// win over the "permissions" config block.
+	events := newEventBus()
+	permissionPolicy := mcpConfig.Permissions
+	if opts.PermissionPolicy != nil {
+		permissionPolicy = *opts.PermissionPolicy
+	}
+	permissionGate, err := newPermissionGate(permissionPolicy, cwd, events)
+	if err != nil {
+		return nil, fmt.Errorf("invalid permission policy: %w", err)
+	}
+	sandboxConfig := mcpConfig.Sandbox
+	if opts.Sandbox != nil {
+		sandboxConfig = *opts.Sandbox
+	}
+	bashExecutor := opts.BashExecutor
+	if bashExecutor != nil && sandboxConfig.Enabled() {
+		if opts.Sandbox != nil {
+			return nil, fmt.Errorf("invalid sandbox: Sandbox and BashExecutor cannot both be set")
+		}
+		// A sandbox from the config file outranks the embedder's executor,
+		// which would run commands outside it.
+		bashExecutor = nil
+	}
+	if bashExecutor == nil {
+		if bashExecutor, err = core.NewBashExecutor(sandboxConfig); err != nil {
+			return nil, fmt.Errorf("invalid sandbox: %w", err)
+		}
+	}
+	jobs := core.NewJobManager()
+	lspConfig := mcpConfig.LSP
+	if opts.LSP != nil {
+		lspConfig = opts.LSP
+	}
+	var lspManager *lsp.Manager
+	if len(lspConfig) > 0 {
+		if lspManager, err = lsp.NewManager(cwd, lspConfig); err != nil {
+			return nil, fmt.Errorf("invalid lsp config: %w", err)
+		}
+		if len(lspManager.Servers()) == 0 {
+			lspManager = nil
+		}
+	}
+	// Hooks run outside the permission check so an extension can block a
+	// call before the user is ever asked about it. Skill scopes come next so
+	// the user is never asked about a call the active skill forbids.
+	// Checkpoints are innermost so files are only snapshotted for calls that
+	// actually execute.
+	checkpoints := newCheckpointRecorder(noCheckpoints, gitCheckpoints, cwd)
+	if checkpoints != nil {
+		checkpoints.fs = opts.FileSystem
+	}
+	scope := newSkillScope(cwd, events)
+	var inheritedHooks []*hookRegistry[BeforeToolCallHook, BeforeToolCallResult]
+	for p := opts.parent; p != nil; p = p.parent {
+		inheritedHooks = append(inheritedHooks, p.beforeToolCall)
+	}
+	hookWrapper := hookToolWrapper(beforeToolCall, afterToolResult, inheritedHooks)
+	scopeWrapper := skillScopeToolWrapper(scope)
+	permissionWrapper := permissionToolWrapper(permissionGate)
+	checkpointWrapper := checkpointToolWrapper(checkpoints)
+	toolWrapper := func(tools []Tool) []Tool {
+		return hookWrapper(scopeWrapper(permissionWrapper(checkpointWrapper(tools))))
+	}
+	mcpRoots := mcpConfig.MCPRoots
+	if opts.MCPRoots != nil {
+		mcpRoots = opts.MCPRoots
+	}
+	mcpClient := newMCPClientBridge(cwd, mcpRoots, events)
+	budget := mcpConfig.Budget
+	if opts.Budget != nil {
+		budget = *opts.Budget
+	}
+	if err := budget.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid budget: %w", err)
+	}
+	if maxCost < 0 {
+		return nil, fmt.Errorf("invalid max cost: %v", maxCost)
+	}
+	if maxParallelSubagents < 0 {
+		return nil, fmt.Errorf("invalid max parallel subagents: %d", maxParallelSubagents)
+	}
+	if maxParallelSubagents == 0 {
+		maxParallelSubagents = defaultMaxParallelSubagents
+	}
+	var ledger *usage.Ledger
+	if !noUsageLedger {
+		ledger = usage.Open("")
+	}
+	spend := newSpendTracker(ledger, usage.NormalizeProject(cwd), maxCost, budget)
+	if opts.parent != nil {
+		// A worktree child's spend still belongs to the parent's project.
+		spend.project = opts.parent.spend.project
+		spend.parent = opts.parent.spend
+	}
+
 	// Build agent setup options, pulling CLI-specific fields when available.
 	// Pass the pre-built ProviderConfig and scalar viper snapshots so
 	// SetupAgent doesn't need to re-read viper (which would require the lock).
@@ -1809,6 +2101,14 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 		}
 	}
 
+	var extTrust extensions.ProjectTrustFunc
+	switch {
+	case v.GetBool("untrusted-project"):
+		extTrust = func(string, int) bool { return false }
+	case opts.ExtensionTrustPrompt != nil:
+		extTrust = trust.Gate("", opts.ExtensionTrustPrompt)
+	}
+
 	setupOpts := kitsetup.AgentSetupOptions{
 		MCPConfig:         mcpConfig,
 		Quiet:             opts.Quiet,
@@ -1818,11 +2118,16 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 		NamedAgents:       namedAgentSpecs(namedAgents),
 		BashTimeout:       bashTimeout,
 		BashMaxTimeout:    bashMaxTimeout,
-		ToolWrapper:       hookToolWrapper(beforeToolCall, afterToolResult),
+		BashExecutor:      bashExecutor,
+		FileSystem:        opts.FileSystem,
+		BashJobs:          jobs,
+		LSP:               lspManager,
+		ToolWrapper:       toolWrapper,
 		ProviderConfig:    providerConfig,
 		Debug:             debug,
 		DebugLogger:       opts.DebugLogger,
 		NoExtensions:      noExtensions,
+		ExtensionTrust:    extTrust,
 		MaxSteps:          maxSteps,
 		StreamingEnabled:  streaming,
 		OnMCPServerLoaded: opts.OnMCPServerLoaded,
@@ -1834,7 +2139,8 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 			timeout:         opts.MCPTaskTimeout,
 			progress:        opts.MCPTaskProgress,
 		}.toToolsConfig(),
-		Viper: v,
+		MCPClientHandlers: mcpClient.handlers(),
+		Viper:             v,
 	}
 
 	// Set up OAuth handler for remote MCP servers. The SDK does not create
@@ -1894,13 +2200,15 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 		agent:                 agentResult.Agent,
 		session:               sessionManager,
 		modelString:           modelString,
-		events:                newEventBus(),
+		events:                events,
 		autoCompact:           opts.AutoCompact,
 		compactionOpts:        opts.CompactionOptions,
 		contextFiles:          contextFiles,
+		contextDisc:           contextDisc,
 		skills:                loadedSkills,
 		namedAgents:           namedAgents,
 		extRunner:             agentResult.ExtRunner,
+		extTrust:              extTrust,
 		bufferedLogger:        agentResult.BufferedLogger,
 		authHandler:           setupOpts.AuthHandler,
 		opts:                  opts,
@@ -1917,6 +2225,22 @@ func New(ctx context.Context, opts *Options) (*Kit, error) {
 		beforeCompact:         beforeCompact,
 		prepareStep:           prepareStep,
 		runtimeExtraTools:     append([]Tool(nil), extraTools...),
+		checkpoints:           checkpoints,
+		jobs:                  jobs,
+		subagents:             newSubagentJobs(maxParallelSubagents),
+		worktreeToolOpts:      worktreeToolOptions(bashTimeout, bashMaxTimeout, bashExecutor, jobs),
+		lsp:                   lspManager,
+		mcpClient:             mcpClient,
+		spend:                 spend,
+		skillScope:            scope,
+		parent:                opts.parent,
+		workDir:               cwd,
+	}
+	mcpClient.kit.Store(k)
+	k.OnStepUsage(k.recordUsage)
+	k.OnToolResult(k.activateToolSkill)
+	if contextDisc != nil {
+		k.OnToolResult(k.discoverToolContext)
 	}
 
 	// Ensure the agent's extra-tool list reflects the current extension tools
@@ -1972,24 +2296,6 @@ func (m *Kit) GetSkills() []*Skill {
 	return out
 }
 
-// ---------------------------------------------------------------------------
-// Context file loading
-// ---------------------------------------------------------------------------
-
-// loadContextFiles discovers and loads project context files (AGENTS.md) from
-// the working directory. Returns nil if no context file is found.
-func loadContextFiles(cwd string) []*ContextFile {
-	path := filepath.Join(cwd, "AGENTS.md")
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil
-	}
-	return []*ContextFile{{
-		Path:    path,
-		Content: strings.TrimSpace(string(data)),
-	}}
-}
-
 // ---------------------------------------------------------------------------
 // Skill command expansion
 // ---------------------------------------------------------------------------
@@ -1997,6 +2303,7 @@ func loadContextFiles(cwd string) []*ContextFile {
 // expandSkillCommand checks whether prompt starts with "/skill:<name>" and, if
 // so, re-reads the skill file, strips its YAML frontmatter, wraps the body in
 // a <skill> block with baseDir metadata, and appends any trailing user args.
+// The skill's allowed-tools, if any, apply for the rest of the turn.
 // Returns the original text unchanged when the prefix is absent or the skill is
 // not found.
 func (m *Kit) expandSkillCommand(prompt string) string {
@@ -2032,6 +2339,8 @@ func (m *Kit) expandSkillCommand(prompt string) string {
 		return prompt
 	}
 
+	m.skillScope.activate(loaded)
+
 	baseDir := filepath.Dir(loaded.Path)
 	var buf strings.Builder
 	fmt.Fprintf(&buf, "<skill name=%q location=%q>\n", loaded.Name, loaded.Path)
@@ -2060,6 +2369,19 @@ func (m *Kit) expandSkillCommand(prompt string) string {
 // Skills loading
 // ---------------------------------------------------------------------------
 
+// optsWorkDir returns the directory an instance created with opts works in:
+// the subagent worktree, SessionDir or the process directory.
+func optsWorkDir(opts *Options) string {
+	if opts.workDir != "" {
+		return opts.workDir
+	}
+	if opts.SessionDir != "" {
+		return opts.SessionDir
+	}
+	cwd, _ := os.Getwd()
+	return cwd
+}
+
 // loadSkills loads skills based on Options. If explicit paths are provided
 // they are loaded directly. If SkillsDir is set it is treated as a direct
 // skills directory (scanned as-is, not as a parent of .agents/.kit). Otherwise
@@ -2078,10 +2400,7 @@ func loadSkills(opts *Options) ([]*skills.Skill, error) {
 	// Auto-discover from the standard scopes rooted at the session directory.
 	// Project-local skills are injected into the system prompt, so they are
 	// gated on a trust decision when a SkillTrustPrompt is configured.
-	cwd := opts.SessionDir
-	if cwd == "" {
-		cwd, _ = os.Getwd()
-	}
+	cwd := optsWorkDir(opts)
 	user := skills.LoadUserSkills()
 	project := skills.LoadProjectSkills(cwd)
 	if len(project) > 0 && !projectSkillsTrusted(opts, cwd, len(project)) {
@@ -2285,6 +2604,17 @@ type SubagentConfig struct {
 	// Timeout limits execution time. Zero means 5 minute default.
 	Timeout time.Duration
 
+	// Isolation selects where the subagent works. [IsolationWorktree] runs
+	// it in a temporary git worktree of the parent's repository on a new
+	// branch, so its edits never touch the parent's checkout; the branch is
+	// reported in SubagentResult.Worktree for [Kit.MergeWorktree] or
+	// [Kit.DiscardWorktree]. Empty uses the named agent's isolation, if
+	// any, and otherwise shares the parent's working directory.
+	Isolation string
+
+	// worktree is where an isolated subagent works.
+	worktree *worktree
+
 	// Temperature overrides the sampling temperature for the subagent.
 	// Nil inherits the parent's effective setting.
 	Temperature *float32
@@ -2306,6 +2636,12 @@ type SubagentResult struct {
 	StopReason string
 	// Usage contains token usage from the subagent's run.
 	Usage *LLMUsage
+	// Worktree reports the branch holding the subagent's changes when it
+	// ran with worktree isolation. Nil otherwise.
+	Worktree *SubagentWorktree
+	// CostUSD is what the subagent's LLM calls cost at the model registry's
+	// prices. It is already counted against the parent's spend limits.
+	CostUSD float64
 	// Elapsed is the total execution time.
 	Elapsed time.Duration
 }
@@ -2382,14 +2718,63 @@ func toolsIncludeMCP(tools []Tool, mcpNames []string) bool {
 //
 // This is the recommended way to run subagents in the SDK — no subprocess,
 // no kit binary dependency, native Go types for results.
+//
+// Subagent blocks until the child finishes. It waits for a free slot first
+// when [Options.MaxParallelSubagents] subagents are already running; use
+// [Kit.StartSubagent] or [Kit.RunSubagents] to run several at once.
 func (m *Kit) Subagent(ctx context.Context, cfg SubagentConfig) (*SubagentResult, error) {
+	if err := validateSubagentConfig(cfg); err != nil {
+		return nil, err
+	}
+	release, err := m.subagents.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return m.runSubagent(ctx, cfg)
+}
+
+// validateSubagentConfig rejects configurations Subagent can never run.
+func validateSubagentConfig(cfg SubagentConfig) error {
 	if cfg.Prompt == "" {
-		return nil, fmt.Errorf("subagent prompt is required")
+		return fmt.Errorf("subagent prompt is required")
 	}
 	if cfg.SessionID != "" && cfg.NoSession {
-		return nil, fmt.Errorf("subagent SessionID and NoSession are mutually exclusive")
+		return fmt.Errorf("subagent SessionID and NoSession are mutually exclusive")
+	}
+	return nil
+}
+
+// runSubagent runs cfg in a child Kit once a concurrency slot was taken,
+// inside a git worktree when cfg asks for isolation.
+func (m *Kit) runSubagent(ctx context.Context, cfg SubagentConfig) (*SubagentResult, error) {
+	switch isolation := m.subagentIsolation(cfg); isolation {
+	case "":
+		return m.runChild(ctx, cfg)
+	case IsolationWorktree:
+	default:
+		return nil, fmt.Errorf("unknown subagent isolation %q", isolation)
 	}
 
+	wt, err := m.addWorktree()
+	if err != nil {
+		return nil, err
+	}
+	cfg.worktree = wt
+	result, err := m.runChild(ctx, cfg)
+	changes, wtErr := m.finishWorktree(wt, cfg.Prompt)
+	if result == nil {
+		result = &SubagentResult{}
+	}
+	result.Worktree = changes
+	if err == nil && wtErr != nil {
+		err = wtErr
+	}
+	return result, err
+}
+
+// runChild creates the child Kit for cfg and runs its prompt.
+func (m *Kit) runChild(ctx context.Context, cfg SubagentConfig) (*SubagentResult, error) {
 	start := time.Now()
 
 	// Resolve a resumable session up front: map the session UUID to its
@@ -2475,6 +2860,10 @@ func (m *Kit) Subagent(ctx context.Context, cfg SubagentConfig) (*SubagentResult
 	if tools == nil {
 		tools = SubagentTools()
 	}
+	if cfg.worktree != nil {
+		tools = isolateTools(tools, cfg.worktree.work, m.worktreeToolOpts)
+		systemPrompt += worktreePrompt(cfg.worktree)
+	}
 
 	// Decide whether the child should re-load MCP servers. When the caller
 	// passes an explicit tool set that ALREADY contains the parent's loaded
@@ -2518,6 +2907,11 @@ func (m *Kit) Subagent(ctx context.Context, cfg SubagentConfig) (*SubagentResult
 		Streaming:    &streamOn,
 		MCPConfig:    childMCPConfig,
 	}
+	// An isolated child's tools work in the worktree, so its permission
+	// gate, checkpoints and context discovery must resolve paths there too.
+	if cfg.worktree != nil {
+		childOpts.workDir = cfg.worktree.work
+	}
 
 	// Inherit the parent's effective provider/runtime configuration. Since #40
 	// each Kit owns an isolated config store, so the child's New() only re-loads
@@ -2537,6 +2931,9 @@ func (m *Kit) Subagent(ctx context.Context, cfg SubagentConfig) (*SubagentResult
 	// polling and no progress feedback even when the parent had configured
 	// custom values.
 	inheritMCPTaskOptions(childOpts, m.opts)
+	// The child charges each LLM call to this instance as it is made, so
+	// this instance's limits stop every child running at once.
+	childOpts.parent = m
 	child, err := New(ctx, childOpts)
 	if err != nil {
 		return &SubagentResult{Elapsed: time.Since(start)}, fmt.Errorf("failed to create subagent: %w", err)
@@ -2581,6 +2978,7 @@ func (m *Kit) Subagent(ctx context.Context, cfg SubagentConfig) (*SubagentResult
 		Response:   result.Response,
 		SessionID:  child.GetSessionID(),
 		StopReason: result.StopReason,
+		CostUSD:    child.GetCostUSD(),
 		Elapsed:    elapsed,
 	}
 	if result.TotalUsage != nil {
@@ -2649,6 +3047,7 @@ func (m *Kit) generate(ctx context.Context, messages []fantasy.Message) (*agent.
 			SystemPrompt: req.SystemPrompt,
 			Timeout:      req.Timeout,
 			SessionID:    req.SessionID,
+			Isolation:    req.Isolation,
 			OnEvent:      onEvent,
 			Tools:        m.GetToolsForSubagent(),
 		})
@@ -2666,8 +3065,16 @@ func (m *Kit) generate(ctx context.Context, messages []fantasy.Message) (*agent.
 			sr.InputTokens = result.Usage.InputTokens
 			sr.OutputTokens = result.Usage.OutputTokens
 		}
+		if result.Worktree != nil {
+			sr.WorktreeBranch = result.Worktree.Branch
+			sr.WorktreeDiffStat = result.Worktree.DiffStat
+		}
 		return sr, err
 	})
+	// Background subagents (run_in_background, subagent_wait,
+	// subagent_cancel) go through the same instance-wide job manager as
+	// Kit.StartSubagent.
+	ctx = core.WithSubagentJobs(ctx, subagentToolJobs{kit: m})
 
 	return m.agent.GenerateWithCallbacks(ctx, messages, agent.GenerateCallbacks{
 		OnToolCall: func(toolCallID, toolName, toolArgs string) {
@@ -2929,6 +3336,17 @@ func (m *Kit) persistGenerationRemainder(result *agent.GenerateWithLoopResult, s
 // promptLabel is the human-readable label emitted in TurnStartEvent.Prompt.
 // prompt is the raw user text passed to BeforeTurn hooks.
 func (m *Kit) runTurn(ctx context.Context, promptLabel string, prompt string, preMessages []fantasy.Message) (*TurnResult, error) {
+	// Refuse the turn when a spend limit is used up, and otherwise let the
+	// spend tracker cancel it once an LLM call crosses one.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if err := m.spend.beginTurn(cancel); err != nil {
+		return nil, err
+	}
+	defer m.spend.endTurn()
+	// Skills activated during the turn restrict tools only until it ends.
+	defer m.skillScope.clear()
+
 	// Expand /skill:name commands — reads the skill file, wraps it in a
 	// <skill> block, and appends any trailing user args.
 	if expanded := m.expandSkillCommand(prompt); expanded != prompt {
@@ -2970,9 +3388,14 @@ func (m *Kit) runTurn(ctx context.Context, promptLabel string, prompt string, pr
 		}
 	}
 
-	// Persist pre-generation messages to session.
+	// Persist pre-generation messages to session. The last user message is
+	// the entry this turn's file checkpoint is keyed by.
+	var turnEntryID string
 	for _, msg := range preMessages {
-		_, _ = m.session.AppendMessage(msg)
+		id, _ := m.session.AppendMessage(msg)
+		if msg.Role == fantasy.MessageRoleUser && id != "" {
+			turnEntryID = id
+		}
 	}
 
 	// Auto-compact if enabled and conversation is near the context limit.
@@ -2981,12 +3404,8 @@ func (m *Kit) runTurn(ctx context.Context, promptLabel string, prompt string, pr
 	}
 
 	// Build context from the session so only the current branch is sent.
-	messages, _, _ := m.session.BuildContext()
-
-	// Run ContextPrepare hooks — extensions can filter, reorder, or inject messages.
-	if hookResult := m.contextPrepare.run(ContextPrepareHook{Messages: messages}); hookResult != nil && hookResult.Messages != nil {
-		messages = hookResult.Messages
-	}
+	// ContextPrepare hooks can filter, reorder, or inject messages.
+	messages := m.turnContext()
 
 	sentCount := len(messages)
 
@@ -2998,6 +3417,13 @@ func (m *Kit) runTurn(ctx context.Context, promptLabel string, prompt string, pr
 	ctx = context.WithValue(ctx, streamCollectorKey{}, collector)
 	ctx = context.WithValue(ctx, haltHolderKey{}, holder)
 
+	// Snapshot files the turn modifies so RestoreCheckpoint can rewind them.
+	// Recording is best-effort: a failure only loses the ability to rewind.
+	if ts := m.GetTreeSession(); ts != nil && turnEntryID != "" {
+		m.checkpoints.begin()
+		defer func() { _ = m.checkpoints.commit(ts, turnEntryID) }()
+	}
+
 	m.events.emit(TurnStartEvent{Prompt: promptLabel})
 	m.events.emit(MessageStartEvent{})
 
@@ -3034,6 +3460,27 @@ func (m *Kit) runTurn(ctx context.Context, promptLabel string, prompt string, pr
 		}
 	}
 
+	// Model failover: when the error is one another model may not share,
+	// resume the turn on the next model of the fallback chain, trying each
+	// model at most once per turn.
+	tried := make(map[string]bool)
+	for failoverReason(err) != "" && ctx.Err() == nil {
+		// As above, keep completed steps so the next model resumes the turn.
+		m.persistGenerationRemainder(result, sentCount)
+		result = nil
+
+		retryMessages, ok := m.failover(ctx, err, tried)
+		if !ok {
+			break
+		}
+		collector.drain()
+		sentCount = len(retryMessages)
+		result, err = m.generate(ctx, retryMessages)
+	}
+
+	if err != nil && errors.Is(context.Cause(ctx), ErrBudgetExceeded) {
+		err = context.Cause(ctx)
+	}
 	if err != nil {
 		// Persist any messages from completed steps that were NOT already
 		// persisted incrementally by the onStepMessages callback. The agent
@@ -3044,6 +3491,9 @@ func (m *Kit) runTurn(ctx context.Context, promptLabel string, prompt string, pr
 		m.events.emit(TurnEndEvent{Error: err})
 		// Run AfterTurn hooks even on error.
 		m.afterTurn.run(AfterTurnHook{Error: err})
+		if errors.Is(err, ErrBudgetExceeded) {
+			return nil, err
+		}
 		return nil, ClassifyProviderError(err)
 	}
 
@@ -3546,9 +3996,21 @@ func (m *Kit) CloseContext(ctx context.Context) error {
 	if m.extRunner != nil && m.extRunner.HasHandlers(extensions.SessionShutdown) {
 		_, _ = m.extRunner.Emit(extensions.SessionShutdownEvent{})
 	}
+	if m.extRunner != nil {
+		m.extRunner.Close()
+	}
 	if m.session != nil {
 		_ = m.session.Close()
 	}
+	if m.jobs != nil {
+		m.jobs.KillAll()
+	}
+	if m.subagents != nil {
+		m.subagents.cancelAll(ctx)
+	}
+	if m.lsp != nil {
+		m.lsp.Close()
+	}
 	// Release the OAuth callback port if we own the handler.
 	if closer, ok := m.authHandler.(interface{ Close() error }); ok {
 		_ = closer.Close()
@@ -0,0 +1,31 @@
+package kit
+
+import (
+	"github.com/mark3labs/kit/internal/core"
+	"github.com/mark3labs/kit/internal/lsp"
+)
+
+// LSPConfig maps language server names to their launch settings. It is the
+// "lsp" block of .kit.yml. Built-in presets (gopls, pyright, typescript,
+// rust-analyzer, clangd) can be enabled with an empty LSPServerConfig.
+type LSPConfig = lsp.Config
+
+// LSPServerConfig describes how to launch one language server and which
+// file extensions it handles.
+type LSPServerConfig = lsp.ServerConfig
+
+// LSPManager starts language servers on demand and keeps them running.
+// Pass one to [WithLSP] when building a custom tool set.
+type LSPManager = lsp.Manager
+
+// NewLSPManager returns a manager for the servers in cfg, rooted at the
+// given workspace directory. Call Close on it when done.
+var NewLSPManager = lsp.NewManager
+
+// WithLSP enables the code intelligence tools and post-edit diagnostics
+// in edit and write, backed by the given manager.
+var WithLSP = core.WithLSP
+
+// LSPTools returns the definition, references, hover, diagnostics,
+// rename_symbol and workspace_symbols tools. They need [WithLSP].
+var LSPTools = core.LSPTools
@@ -0,0 +1,404 @@
+package kit
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/mark3labs/kit/internal/tools"
+)
+
+// ElicitationAction is the user's answer to an MCP elicitation request.
+type ElicitationAction string
+
+const (
+	// ElicitationAccept submits the form content.
+	ElicitationAccept ElicitationAction = ElicitationAction(mcp.ElicitationResponseActionAccept)
+	// ElicitationDecline explicitly refuses to provide the information.
+	ElicitationDecline ElicitationAction = ElicitationAction(mcp.ElicitationResponseActionDecline)
+	// ElicitationCancel dismisses the request without a choice.
+	ElicitationCancel ElicitationAction = ElicitationAction(mcp.ElicitationResponseActionCancel)
+)
+
+// ElicitationField is one input of an MCP elicitation form. MCP restricts
+// requested schemas to flat objects of primitive properties.
+type ElicitationField struct {
+	// Name is the property name the answer is keyed by.
+	Name string
+	// Title is a display label; empty means use Name.
+	Title string
+	// Description is optional help text.
+	Description string
+	// Type is "string", "number", "integer" or "boolean".
+	Type string
+	// Required reports whether the form cannot be accepted without it.
+	Required bool
+	// Enum lists the allowed values of a string field, and EnumNames their
+	// display labels when the server supplied them.
+	Enum      []string
+	EnumNames []string
+	// Default is the server's suggested value, if any.
+	Default any
+}
+
+// MCPElicitationResponse answers an MCPElicitationEvent.
+type MCPElicitationResponse struct {
+	Action ElicitationAction
+	// Content holds the answers keyed by field name when Action is
+	// ElicitationAccept: string, float64, int64 or bool by field type.
+	Content map[string]any
+}
+
+// mcpClientBridge answers the requests MCP servers send to Kit: sampling
+// through the current model, elicitation through MCPElicitationEvent, and
+// roots from the working directory plus the configured extra directories.
+// It is created before the agent so its handlers can be handed to the
+// connection pool; kit is set once construction finishes.
+type mcpClientBridge struct {
+	cwd    string
+	events *eventBus
+	kit    atomic.Pointer[Kit]
+
+	mu    sync.RWMutex
+	roots []string // extra root directories, absolute
+}
+
+func newMCPClientBridge(cwd string, extraRoots []string, events *eventBus) *mcpClientBridge {
+	if abs, err := filepath.Abs(cwd); err == nil {
+		cwd = abs
+	}
+	b := &mcpClientBridge{cwd: cwd, events: events}
+	b.roots = b.resolveRoots(extraRoots)
+	return b
+}
+
+func (b *mcpClientBridge) handlers() tools.MCPClientHandlers {
+	return tools.MCPClientHandlers{
+		Sampling:    b.sample,
+		Elicitation: b.elicit,
+		Roots:       b.listRoots,
+	}
+}
+
+// resolveRoots makes dirs absolute against the working directory and drops
+// duplicates of it and of each other.
+func (b *mcpClientBridge) resolveRoots(dirs []string) []string {
+	var out []string
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(b.cwd, dir)
+		}
+		dir = filepath.Clean(dir)
+		if dir == b.cwd || slices.Contains(out, dir) {
+			continue
+		}
+		out = append(out, dir)
+	}
+	return out
+}
+
+func (b *mcpClientBridge) listRoots() []mcp.Root {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	roots := []mcp.Root{fileRoot(b.cwd)}
+	for _, dir := range b.roots {
+		roots = append(roots, fileRoot(dir))
+	}
+	return roots
+}
+
+func fileRoot(dir string) mcp.Root {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(dir)}
+	return mcp.Root{URI: u.String(), Name: filepath.Base(dir)}
+}
+
+// sample answers sampling/createMessage with the current model. The
+// connection pool has already clamped MaxTokens to the server's limits; the
+// call is charged to Kit's spend limits like any other.
+func (b *mcpClientBridge) sample(ctx context.Context, serverName string, req mcp.CreateMessageRequest) (*mcp.CreateMessageResult, int, error) {
+	k := b.kit.Load()
+	if k == nil {
+		return nil, 0, fmt.Errorf("kit is still starting")
+	}
+	messages, err := samplingMessages(req.Messages)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var agentOpts []fantasy.AgentOption
+	if req.SystemPrompt != "" {
+		agentOpts = append(agentOpts, fantasy.WithSystemPrompt(req.SystemPrompt))
+	}
+	if req.MaxTokens > 0 {
+		agentOpts = append(agentOpts, fantasy.WithMaxOutputTokens(int64(req.MaxTokens)))
+	}
+	if req.Temperature > 0 {
+		agentOpts = append(agentOpts, fantasy.WithTemperature(req.Temperature))
+	}
+	model := k.metered(k.agent.GetModel(), k.GetModelString())
+	result, err := fantasy.NewAgent(model, agentOpts...).Generate(ctx, fantasy.AgentCall{
+		Messages: messages,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("sampling for %s: %w", serverName, err)
+	}
+	usage := result.TotalUsage
+	return &mcp.CreateMessageResult{
+		SamplingMessage: mcp.SamplingMessage{
+			Role:    mcp.RoleAssistant,
+			Content: mcp.NewTextContent(result.Response.Content.Text()),
+		},
+		Model:      k.GetModelString(),
+		StopReason: samplingStopReason(result.Response.FinishReason),
+	}, int(usage.InputTokens + usage.OutputTokens), nil
+}
+
+// samplingMessages converts MCP sampling messages to LLM messages. Text,
+// image and audio content are supported.
+func samplingMessages(in []mcp.SamplingMessage) ([]fantasy.Message, error) {
+	out := make([]fantasy.Message, 0, len(in))
+	for i, msg := range in {
+		role := fantasy.MessageRoleUser
+		if msg.Role == mcp.RoleAssistant {
+			role = fantasy.MessageRoleAssistant
+		}
+		blocks := []any{msg.Content}
+		if list, ok := msg.Content.([]mcp.Content); ok {
+			blocks = blocks[:0]
+			for _, c := range list {
+				blocks = append(blocks, c)
+			}
+		}
+		var parts []fantasy.MessagePart
+		for _, block := range blocks {
+			part, err := samplingPart(block)
+			if err != nil {
+				return nil, fmt.Errorf("sampling message %d: %w", i, err)
+			}
+			parts = append(parts, part)
+		}
+		out = append(out, fantasy.Message{Role: role, Content: parts})
+	}
+	return out, nil
+}
+
+func samplingPart(content any) (fantasy.MessagePart, error) {
+	switch c := content.(type) {
+	case mcp.TextContent:
+		return fantasy.TextPart{Text: c.Text}, nil
+	case *mcp.TextContent:
+		return fantasy.TextPart{Text: c.Text}, nil
+	case mcp.ImageContent:
+		return mediaPart(c.Data, c.MIMEType)
+	case *mcp.ImageContent:
+		return mediaPart(c.Data, c.MIMEType)
+	case mcp.AudioContent:
+		return mediaPart(c.Data, c.MIMEType)
+	case *mcp.AudioContent:
+		return mediaPart(c.Data, c.MIMEType)
+	default:
+		return nil, fmt.Errorf("unsupported content %T", content)
+	}
+}
+
+func mediaPart(data, mimeType string) (fantasy.MessagePart, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s data: %w", mimeType, err)
+	}
+	return fantasy.FilePart{Data: raw, MediaType: mimeType}, nil
+}
+
+// samplingStopReason maps a finish reason to the stop reasons MCP names.
+func samplingStopReason(reason fantasy.FinishReason) string {
+	switch reason {
+	case fantasy.FinishReasonStop:
+		return "endTurn"
+	case fantasy.FinishReasonLength:
+		return "maxTokens"
+	default:
+		return string(reason)
+	}
+}
+
+// elicit answers elicitation/create by emitting MCPElicitationEvent. Only
+// form elicitation is advertised, so URL requests are declined.
+func (b *mcpClientBridge) elicit(_ context.Context, serverName string, req mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	if req.Params.Mode == mcp.ElicitationModeURL {
+		return elicitationResult(ElicitationDecline, nil), nil
+	}
+	fields, err := elicitationFields(req.Params.RequestedSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	responseCh := make(chan MCPElicitationResponse, 1)
+	b.events.emit(MCPElicitationEvent{
+		ServerName: serverName,
+		Message:    req.Params.Message,
+		Fields:     fields,
+		ResponseCh: responseCh,
+	})
+	// emit is synchronous; no reply means nobody can answer.
+	var resp MCPElicitationResponse
+	select {
+	case resp = <-responseCh:
+	default:
+		return elicitationResult(ElicitationDecline, nil), nil
+	}
+
+	switch resp.Action {
+	case ElicitationAccept:
+		for _, f := range fields {
+			if _, ok := resp.Content[f.Name]; f.Required && !ok {
+				return nil, fmt.Errorf("elicitation answer is missing required field %q", f.Name)
+			}
+		}
+		content := resp.Content
+		if content == nil {
+			content = map[string]any{}
+		}
+		return elicitationResult(ElicitationAccept, content), nil
+	case ElicitationDecline:
+		return elicitationResult(ElicitationDecline, nil), nil
+	default:
+		return elicitationResult(ElicitationCancel, nil), nil
+	}
+}
+
+func elicitationResult(action ElicitationAction, content map[string]any) *mcp.ElicitationResult {
+	res := &mcp.ElicitationResult{}
+	res.Action = mcp.ElicitationResponseAction(action)
+	if content != nil {
+		res.Content = content
+	}
+	return res
+}
+
+// elicitationFields parses a requested schema into form fields, keeping the
+// order the properties appear in.
+func elicitationFields(schema any) ([]ElicitationField, error) {
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid requested schema: %w", err)
+	}
+	var parsed struct {
+		Properties json.RawMessage `json:"properties"`
+		Required   []string        `json:"required"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid requested schema: %w", err)
+	}
+	if len(parsed.Properties) == 0 {
+		return nil, nil
+	}
+	names, err := objectKeys(parsed.Properties)
+	if err != nil {
+		return nil, fmt.Errorf("invalid requested schema: %w", err)
+	}
+	var props map[string]struct {
+		Type        string   `json:"type"`
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Enum        []string `json:"enum"`
+		EnumNames   []string `json:"enumNames"`
+		OneOf       []struct {
+			Const string `json:"const"`
+			Title string `json:"title"`
+		} `json:"oneOf"`
+		Default any `json:"default"`
+	}
+	if err := json.Unmarshal(parsed.Properties, &props); err != nil {
+		return nil, fmt.Errorf("invalid requested schema: %w", err)
+	}
+
+	fields := make([]ElicitationField, 0, len(names))
+	for _, name := range names {
+		p := props[name]
+		switch p.Type {
+		case "string", "number", "integer", "boolean":
+		default:
+			return nil, fmt.Errorf("requested field %q has unsupported type %q", name, p.Type)
+		}
+		f := ElicitationField{
+			Name:        name,
+			Title:       p.Title,
+			Description: p.Description,
+			Type:        p.Type,
+			Required:    slices.Contains(parsed.Required, name),
+			Enum:        p.Enum,
+			EnumNames:   p.EnumNames,
+			Default:     p.Default,
+		}
+		// Titled enums may be spelled as oneOf const/title pairs.
+		if len(f.Enum) == 0 && len(p.OneOf) > 0 {
+			for _, o := range p.OneOf {
+				f.Enum = append(f.Enum, o.Const)
+				f.EnumNames = append(f.EnumNames, o.Title)
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// objectKeys returns the keys of a JSON object in document order.
+func objectKeys(obj json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(obj))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("properties must be an object")
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, tok.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// MCPRoots returns the directories advertised to MCP servers as roots: the
+// working directory followed by the extra directories.
+func (m *Kit) MCPRoots() []string {
+	b := m.mcpClient
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]string{b.cwd}, b.roots...)
+}
+
+// SetMCPRoots replaces the extra directories advertised to MCP servers as
+// roots, alongside the working directory, and sends
+// notifications/roots/list_changed so connected servers re-read them.
+// Relative paths resolve against the working directory.
+func (m *Kit) SetMCPRoots(ctx context.Context, dirs []string) error {
+	b := m.mcpClient
+	roots := b.resolveRoots(dirs)
+	b.mu.Lock()
+	b.roots = roots
+	b.mu.Unlock()
+
+	mgr := m.agent.GetMCPToolManager()
+	if mgr == nil {
+		return nil
+	}
+	return mgr.NotifyRootsChanged(ctx)
+}
@@ -0,0 +1,159 @@
+package kit
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestElicitationFields(t *testing.T) {
+	schema := []byte(`{"type":"object","properties":{
+		"name":{"type":"string","title":"Your name"},
+		"color":{"type":"string","enum":["r","g"],"enumNames":["Red","Green"]},
+		"size":{"type":"string","oneOf":[{"const":"s","title":"Small"}]},
+		"age":{"type":"integer","default":30},
+		"ok":{"type":"boolean"}
+	},"required":["name"]}`)
+	fields, err := elicitationFields(json.RawMessage(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	if want := []string{"name", "color", "size", "age", "ok"}; !slices.Equal(names, want) {
+		t.Fatalf("fields = %v, want schema order %v", names, want)
+	}
+	if !fields[0].Required || fields[0].Title != "Your name" || fields[1].Required {
+		t.Errorf("name/color fields parsed wrong: %+v %+v", fields[0], fields[1])
+	}
+	if !slices.Equal(fields[1].EnumNames, []string{"Red", "Green"}) {
+		t.Errorf("color enum names = %v", fields[1].EnumNames)
+	}
+	if !slices.Equal(fields[2].Enum, []string{"s"}) || !slices.Equal(fields[2].EnumNames, []string{"Small"}) {
+		t.Errorf("oneOf enum not parsed: %+v", fields[2])
+	}
+
+	if _, err := elicitationFields(map[string]any{"properties": map[string]any{
+		"nested": map[string]any{"type": "object"},
+	}}); err == nil {
+		t.Error("expected an error for a non-primitive field")
+	}
+}
+
+func TestMCPClientBridge_Elicit(t *testing.T) {
+	req := mcp.ElicitationRequest{Params: mcp.ElicitationParams{
+		Message: "Who are you?",
+		RequestedSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"name": map[string]any{"type": "string"}},
+			"required":   []string{"name"},
+		},
+	}}
+
+	t.Run("no listener declines", func(t *testing.T) {
+		b := newMCPClientBridge(t.TempDir(), nil, newEventBus())
+		res, err := b.elicit(context.Background(), "srv", req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Action != mcp.ElicitationResponseActionDecline {
+			t.Errorf("action = %q, want decline", res.Action)
+		}
+	})
+
+	t.Run("accept", func(t *testing.T) {
+		bus := newEventBus()
+		var got MCPElicitationEvent
+		bus.subscribe(func(e Event) {
+			if ev, ok := e.(MCPElicitationEvent); ok {
+				got = ev
+				ev.ResponseCh <- MCPElicitationResponse{Action: ElicitationAccept, Content: map[string]any{"name": "Ada"}}
+			}
+		})
+		b := newMCPClientBridge(t.TempDir(), nil, bus)
+		res, err := b.elicit(context.Background(), "srv", req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ServerName != "srv" || got.Message != "Who are you?" || len(got.Fields) != 1 {
+			t.Errorf("event = %+v", got)
+		}
+		if res.Action != mcp.ElicitationResponseActionAccept || res.Content.(map[string]any)["name"] != "Ada" {
+			t.Errorf("result = %+v", res)
+		}
+	})
+
+	t.Run("accept without required field", func(t *testing.T) {
+		bus := newEventBus()
+		bus.subscribe(func(e Event) {
+			if ev, ok := e.(MCPElicitationEvent); ok {
+				ev.ResponseCh <- MCPElicitationResponse{Action: ElicitationAccept}
+			}
+		})
+		b := newMCPClientBridge(t.TempDir(), nil, bus)
+		if _, err := b.elicit(context.Background(), "srv", req); err == nil {
+			t.Error("expected an error for a missing required field")
+		}
+	})
+
+	t.Run("url mode declines", func(t *testing.T) {
+		b := newMCPClientBridge(t.TempDir(), nil, newEventBus())
+		res, err := b.elicit(context.Background(), "srv", mcp.ElicitationRequest{Params: mcp.ElicitationParams{
+			Mode: mcp.ElicitationModeURL, ElicitationID: "1", URL: "https://example.com",
+		}})
+		if err != nil || res.Action != mcp.ElicitationResponseActionDecline {
+			t.Errorf("url elicitation = %+v, %v", res, err)
+		}
+	})
+}
+
+func TestMCPClientBridge_Roots(t *testing.T) {
+	cwd := t.TempDir()
+	extra := filepath.Join(t.TempDir(), "shared")
+	b := newMCPClientBridge(cwd, []string{extra, "sub", ".", extra}, newEventBus())
+
+	roots := b.listRoots()
+	if len(roots) != 3 {
+		t.Fatalf("roots = %+v, want cwd, extra and sub", roots)
+	}
+	if want := fileRoot(cwd).URI; roots[0].URI != want {
+		t.Errorf("first root = %q, want %q", roots[0].URI, want)
+	}
+	if want := "file://" + filepath.ToSlash(filepath.Join(cwd, "sub")); roots[2].URI != want {
+		t.Errorf("relative root = %q, want %q", roots[2].URI, want)
+	}
+}
+
+func TestSamplingMessages(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G'}
+	msgs, err := samplingMessages([]mcp.SamplingMessage{
+		{Role: mcp.RoleUser, Content: mcp.NewTextContent("describe")},
+		{Role: mcp.RoleAssistant, Content: mcp.NewTextContent("ok")},
+		{Role: mcp.RoleUser, Content: mcp.NewImageContent(base64.StdEncoding.EncodeToString(png), "image/png")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 3 || msgs[1].Role != fantasy.MessageRoleAssistant {
+		t.Fatalf("messages = %+v", msgs)
+	}
+	file, ok := msgs[2].Content[0].(fantasy.FilePart)
+	if !ok || file.MediaType != "image/png" || string(file.Data) != string(png) {
+		t.Errorf("image part = %+v", msgs[2].Content[0])
+	}
+
+	if _, err := samplingMessages([]mcp.SamplingMessage{{Role: mcp.RoleUser, Content: 42}}); err == nil {
+		t.Error("expected an error for unsupported content")
+	}
+	if got := samplingStopReason(fantasy.FinishReasonLength); got != "maxTokens" {
+		t.Errorf("stop reason = %q, want maxTokens", got)
+	}
+}
@@ -40,6 +40,13 @@ func NewAgent(ctx context.Context, opts ...Option) (*Kit, error) {
 // (e.g. "anthropic/claude-sonnet-4-5-20250929").
 func WithModel(m string) Option { return func(o *Options) { o.Model = m } }
 
+// WithFallbackModels sets the models Kit switches to, in order, when the
+// active model fails with an overload, rate-limit, context-overflow or auth
+// error. See [Options.FallbackModels].
+func WithFallbackModels(models ...string) Option {
+	return func(o *Options) { o.FallbackModels = models }
+}
+
 // WithSystemPrompt sets the system prompt. The value may be inline text or a
 // path to a file whose contents are loaded as the prompt.
 func WithSystemPrompt(p string) Option { return func(o *Options) { o.SystemPrompt = p } }
@@ -82,6 +89,12 @@ func WithProviderURL(url string) Option { return func(o *Options) { o.ProviderUR
 // non-OpenAI-flavored proxy or a provider not in the model database.
 func WithProviderWire(wire string) Option { return func(o *Options) { o.ProviderWire = wire } }
 
+// WithPermissionPolicy sets the tool-call approval rules, overriding the
+// "permissions" block of the config file.
+func WithPermissionPolicy(p PermissionPolicy) Option {
+	return func(o *Options) { o.PermissionPolicy = &p }
+}
+
 // WithConfigFile sets an explicit config file path, overriding the default
 // .kit.yml search.
 func WithConfigFile(path string) Option { return func(o *Options) { o.ConfigFile = path } }
@@ -47,7 +47,7 @@ func (m *Kit) prepareOverflowRetry(ctx context.Context) ([]fantasy.Message, erro
 	// replaces the summarised prefix, and any completed steps persisted
 	// from the failed attempt are included so the replay resumes rather
 	// than restarts.
-	messages, _, _ := m.session.BuildContext()
+	messages, provider, _ := m.session.BuildContext()
 	messages = stripMediaParts(messages)
 
 	// Re-run ContextPrepare hooks on the rebuilt context, mirroring the
@@ -57,6 +57,7 @@ func (m *Kit) prepareOverflowRetry(ctx context.Context) ([]fantasy.Message, erro
 	if hookResult := m.contextPrepare.run(ContextPrepareHook{Messages: messages}); hookResult != nil && hookResult.Messages != nil {
 		messages = stripMediaParts(hookResult.Messages)
 	}
+	messages = m.forActiveProvider(messages, provider)
 
 	if len(messages) == 0 {
 		return nil, fmt.Errorf("compaction produced an empty context")
@@ -0,0 +1,205 @@
+package kit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/kit/internal/core"
+	"github.com/mark3labs/kit/internal/permission"
+)
+
+// ---------------------------------------------------------------------------
+// Tool-call approval policy
+// ---------------------------------------------------------------------------
+
+// PermissionPolicy holds the allow/ask/deny rules evaluated before every tool
+// call. It has the same shape as the "permissions" block of .kit.yml.
+type PermissionPolicy = permission.Config
+
+// PermissionAction is the outcome of evaluating a tool call against the
+// policy.
+type PermissionAction = permission.Action
+
+// Policy outcomes. They mirror the permission package actions.
+const (
+	// PermissionAllow runs the tool call without asking.
+	PermissionAllow = permission.Allow
+	// PermissionAsk requires approval via a PermissionRequestEvent.
+	PermissionAsk = permission.Ask
+	// PermissionDeny blocks the tool call.
+	PermissionDeny = permission.Deny
+)
+
+// PermissionDecision is the user's answer to a PermissionRequestEvent. The
+// zero value denies the call.
+type PermissionDecision int
+
+const (
+	// PermissionDenyOnce blocks this call.
+	PermissionDenyOnce PermissionDecision = iota
+	// PermissionAllowOnce runs this call without remembering the decision.
+	PermissionAllowOnce
+	// PermissionAllowSession grants the event's Rule until the Kit closes.
+	PermissionAllowSession
+	// PermissionAllowProject grants the event's Rule and persists it for the
+	// project directory next to the trusted-projects allowlist.
+	PermissionAllowProject
+)
+
+// permissionGate evaluates tool calls against the policy and, for "ask"
+// outcomes, asks the user through the event bus.
+type permissionGate struct {
+	policy     *permission.Policy
+	store      *permission.Store
+	projectDir string
+	events     *eventBus
+}
+
+// newPermissionGate builds a gate for cfg. It returns nil when cfg is empty,
+// so the default configuration adds no per-call overhead. Rules previously
+// granted for projectDir are loaded from the persisted store.
+func newPermissionGate(cfg PermissionPolicy, projectDir string, events *eventBus) (*permissionGate, error) {
+	if cfg.IsZero() {
+		return nil, nil
+	}
+	policy, err := permission.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	g := &permissionGate{policy: policy, projectDir: projectDir, events: events}
+
+	// A corrupt or unreadable store only loses remembered grants; the user
+	// is asked again rather than failing startup.
+	if store, err := permission.Load(""); err == nil {
+		g.store = store
+		for _, spec := range store.Rules(projectDir) {
+			if r, err := permission.ParseRule(spec, permission.Allow); err == nil {
+				policy.Grant(r)
+			}
+		}
+	}
+	return g, nil
+}
+
+// check decides whether call may run. When it may not, the returned reason
+// explains why.
+func (g *permissionGate) check(toolName string, call LLMToolCall) (bool, string) {
+	return g.decide(permission.Request{ToolName: toolName, Input: call.Input, WorkDir: g.projectDir}, call)
+}
+
+// checkPaths decides whether call may go on to modify paths, which the tool
+// only learned while running. Paths the call's own Subject names were
+// approved by check already and are not asked about again.
+func (g *permissionGate) checkPaths(toolName string, call LLMToolCall, paths []string) (bool, string) {
+	req := permission.Request{ToolName: toolName, Input: call.Input, WorkDir: g.projectDir}
+	approved := req.Subject()
+	if approved != "" && !filepath.IsAbs(approved) {
+		approved = filepath.Join(g.projectDir, approved)
+	}
+	for _, p := range paths {
+		if filepath.Clean(p) != filepath.Clean(approved) {
+			req.Paths = append(req.Paths, p)
+		}
+	}
+	if len(req.Paths) == 0 {
+		return true, ""
+	}
+	return g.decide(req, call)
+}
+
+// decide evaluates req for call, asking the user when the policy says ask.
+func (g *permissionGate) decide(req permission.Request, call LLMToolCall) (bool, string) {
+	toolName := req.ToolName
+	switch g.policy.Evaluate(req) {
+	case permission.Allow:
+		return true, ""
+	case permission.Deny:
+		return false, fmt.Sprintf("%s call denied by permission policy", toolName)
+	}
+
+	rules := permission.SuggestRules(req)
+	specs := make([]string, len(rules))
+	for i, r := range rules {
+		specs[i] = r.String()
+	}
+	responseCh := make(chan PermissionResponse, 1)
+	g.events.emit(PermissionRequestEvent{
+		ToolCallID: call.ID,
+		ToolName:   toolName,
+		ToolArgs:   call.Input,
+		Subject:    req.Subject(),
+		Rule:       strings.Join(specs, ", "),
+		ResponseCh: responseCh,
+	})
+	// emit is synchronous, so every listener has replied by now. No reply
+	// means nobody can approve the call — fail closed.
+	var resp PermissionResponse
+	select {
+	case resp = <-responseCh:
+	default:
+		return false, fmt.Sprintf("%s call requires approval but no interactive approver is available", toolName)
+	}
+
+	switch resp.Decision {
+	case PermissionAllowOnce:
+	case PermissionAllowSession:
+		for _, r := range rules {
+			g.policy.Grant(r)
+		}
+	case PermissionAllowProject:
+		for i, r := range rules {
+			g.policy.Grant(r)
+			if g.store != nil {
+				_ = g.store.Allow(g.projectDir, specs[i])
+			}
+		}
+	default:
+		return false, fmt.Sprintf("%s call denied by user", toolName)
+	}
+	return true, ""
+}
+
+// permissionedTool wraps an AgentTool so each execution is checked against
+// the permission gate first.
+type permissionedTool struct {
+	inner Tool
+	gate  *permissionGate
+}
+
+func (p *permissionedTool) Info() LLMToolInfo                       { return p.inner.Info() }
+func (p *permissionedTool) ProviderOptions() LLMProviderOptions     { return p.inner.ProviderOptions() }
+func (p *permissionedTool) SetProviderOptions(o LLMProviderOptions) { p.inner.SetProviderOptions(o) }
+
+func (p *permissionedTool) Run(ctx context.Context, call LLMToolCall) (LLMToolResponse, error) {
+	name := p.inner.Info().Name
+	if ok, reason := p.gate.check(name, call); !ok {
+		// Report the denial to the model as a tool error so it can adjust
+		// its plan instead of aborting the turn.
+		return newLLMTextErrorResponse(fmt.Sprintf("Error: %s", reason)), nil
+	}
+	ctx = core.WithFileGuard(ctx, func(_ context.Context, paths []string) error {
+		if ok, reason := p.gate.checkPaths(name, call, paths); !ok {
+			return errors.New(reason)
+		}
+		return nil
+	})
+	return p.inner.Run(ctx, call)
+}
+
+// permissionToolWrapper returns a tool wrapper enforcing gate. A nil gate
+// yields a wrapper that returns tools unchanged.
+func permissionToolWrapper(gate *permissionGate) func([]Tool) []Tool {
+	return func(tools []Tool) []Tool {
+		if gate == nil {
+			return tools
+		}
+		wrapped := make([]Tool, len(tools))
+		for i, tool := range tools {
+			wrapped[i] = &permissionedTool{inner: tool, gate: gate}
+		}
+		return wrapped
+	}
+}
@@ -0,0 +1,178 @@
+package kit
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/kit/internal/core"
+	"github.com/mark3labs/kit/internal/permission"
+)
+
+// newTestPermissionGate builds a gate with an isolated grant store.
+func newTestPermissionGate(t *testing.T, cfg PermissionPolicy) (*permissionGate, *eventBus) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	events := newEventBus()
+	gate, err := newPermissionGate(cfg, t.TempDir(), events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gate, events
+}
+
+func runPermissioned(t *testing.T, gate *permissionGate, name, input string) (LLMToolResponse, bool) {
+	t.Helper()
+	ran := false
+	mock := &mockAgentTool{
+		name: name,
+		runFn: func(_ context.Context, _ LLMToolCall) (LLMToolResponse, error) {
+			ran = true
+			return newLLMTextResponse("ok"), nil
+		},
+	}
+	tools := permissionToolWrapper(gate)([]Tool{mock})
+	resp, err := tools[0].Run(context.Background(), LLMToolCall{ID: "call-1", Input: input})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return resp, ran
+}
+
+func TestPermissionGate_EmptyPolicyIsNil(t *testing.T) {
+	gate, _ := newTestPermissionGate(t, PermissionPolicy{})
+	if gate != nil {
+		t.Fatal("empty policy should not install a gate")
+	}
+	mock := &mockAgentTool{name: "bash"}
+	if got := permissionToolWrapper(gate)([]Tool{mock}); got[0] != mock {
+		t.Fatal("nil gate should leave tools unwrapped")
+	}
+}
+
+func TestPermissionGate_AllowAndDeny(t *testing.T) {
+	gate, _ := newTestPermissionGate(t, PermissionPolicy{
+		Allow: []string{"bash(git status)"},
+		Deny:  []string{"bash(rm:*)"},
+	})
+
+	if _, ran := runPermissioned(t, gate, "bash", `{"command":"git status"}`); !ran {
+		t.Error("allowed command should run")
+	}
+	resp, ran := runPermissioned(t, gate, "bash", `{"command":"rm -rf /"}`)
+	if ran {
+		t.Fatal("denied command must not run")
+	}
+	if !resp.IsError || !strings.Contains(resp.Content, "denied by permission policy") {
+		t.Errorf("unexpected denial response: %+v", resp)
+	}
+}
+
+func TestPermissionGate_AskFailsClosedWithoutResponder(t *testing.T) {
+	gate, _ := newTestPermissionGate(t, PermissionPolicy{Default: PermissionAsk})
+
+	resp, ran := runPermissioned(t, gate, "write", `{"path":"a.txt","content":"x"}`)
+	if ran {
+		t.Fatal("ask without a responder must not run the tool")
+	}
+	if !strings.Contains(resp.Content, "requires approval") {
+		t.Errorf("unexpected response: %q", resp.Content)
+	}
+}
+
+func TestPermissionGate_AskDecisions(t *testing.T) {
+	gate, events := newTestPermissionGate(t, PermissionPolicy{Default: PermissionAsk})
+
+	var (
+		asked    int
+		decision PermissionDecision
+		last     PermissionRequestEvent
+	)
+	events.subscribe(func(e Event) {
+		if ev, ok := e.(PermissionRequestEvent); ok {
+			asked++
+			last = ev
+			ev.ResponseCh <- PermissionResponse{Decision: decision}
+		}
+	})
+
+	const input = `{"command":"go test ./..."}`
+
+	decision = PermissionDenyOnce
+	if _, ran := runPermissioned(t, gate, "bash", input); ran {
+		t.Fatal("denied call must not run")
+	}
+	if last.Subject != "go test ./..." || last.Rule != "bash(go test ./...)" || last.ToolCallID != "call-1" {
+		t.Errorf("unexpected request event: %+v", last)
+	}
+
+	decision = PermissionAllowOnce
+	if _, ran := runPermissioned(t, gate, "bash", input); !ran {
+		t.Fatal("allow-once call should run")
+	}
+
+	decision = PermissionAllowProject
+	if _, ran := runPermissioned(t, gate, "bash", input); !ran {
+		t.Fatal("allow-project call should run")
+	}
+
+	// The grant now covers the command without asking again.
+	asked = 0
+	if _, ran := runPermissioned(t, gate, "bash", input); !ran || asked != 0 {
+		t.Fatalf("granted call: ran=%v asked=%d, want ran without asking", ran, asked)
+	}
+
+	// The project grant was persisted and is picked up by a fresh gate.
+	store, err := permission.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules := store.Rules(gate.projectDir); len(rules) != 1 || rules[0] != "bash(go test ./...)" {
+		t.Fatalf("persisted rules = %v", rules)
+	}
+	fresh, err := newPermissionGate(PermissionPolicy{Default: PermissionAsk}, gate.projectDir, newEventBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ran := runPermissioned(t, fresh, "bash", input); !ran {
+		t.Fatal("persisted grant should apply to a new gate")
+	}
+}
+
+func TestPermissionGate_GuardsPlannedFiles(t *testing.T) {
+	gate, _ := newTestPermissionGate(t, PermissionPolicy{
+		Allow: []string{"rename_symbol"},
+		Deny:  []string{"rename_symbol(secrets/**)"},
+	})
+	var guardErr error
+	mock := &mockAgentTool{
+		name: "rename_symbol",
+		runFn: func(ctx context.Context, _ LLMToolCall) (LLMToolResponse, error) {
+			guardErr = core.GuardFiles(ctx, []string{
+				filepath.Join(gate.projectDir, "main.go"),
+				filepath.Join(gate.projectDir, "secrets", "keys.go"),
+			})
+			return newLLMTextResponse("ok"), nil
+		},
+	}
+	tools := permissionToolWrapper(gate)([]Tool{mock})
+	if _, err := tools[0].Run(context.Background(), LLMToolCall{ID: "call-1", Input: `{"path":"main.go"}`}); err != nil {
+		t.Fatal(err)
+	}
+	if guardErr == nil || !strings.Contains(guardErr.Error(), "denied by permission policy") {
+		t.Errorf("guard over a file under secrets/ = %v, want a denial", guardErr)
+	}
+
+	// The call's own path was approved up front and is not asked about again.
+	if ok, reason := gate.checkPaths("rename_symbol", LLMToolCall{Input: `{"path":"secrets/a.go"}`}, []string{filepath.Join(gate.projectDir, "secrets", "a.go")}); !ok {
+		t.Errorf("checkPaths of the approved path = %q", reason)
+	}
+}
+
+func TestPermissionGate_InvalidPolicy(t *testing.T) {
+	_, err := newPermissionGate(PermissionPolicy{Default: "maybe"}, t.TempDir(), newEventBus())
+	if err == nil {
+		t.Fatal("expected error for invalid default")
+	}
+}
@@ -38,6 +38,18 @@ func DeleteSession(path string) error {
 	return session.DeleteSession(path)
 }
 
+// SearchSessions runs a full-text query over every stored session: message
+// text, tool calls and their arguments (including file paths), labels and
+// session names. A session matches when one of its entries contains every
+// query word as a word prefix, ignoring case. Results are newest first; each
+// match carries the entry ID and a snippet with the matching ranges marked.
+//
+// The search index is cached under ~/.kit and updated incrementally, so
+// only sessions written since the last search are read.
+func SearchSessions(query string) ([]SessionSearchResult, error) {
+	return session.SearchSessions(query)
+}
+
 // OpenTreeSession opens an existing JSONL session file. This is a package-level
 // function (no Kit instance required) used by the CLI for session switching.
 func OpenTreeSession(path string) (*TreeManager, error) {
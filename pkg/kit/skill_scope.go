@@ -0,0 +1,167 @@
+package kit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/kit/internal/permission"
+	"github.com/mark3labs/kit/internal/skills"
+)
+
+// ---------------------------------------------------------------------------
+// Skill tool scopes
+// ---------------------------------------------------------------------------
+//
+// A skill whose frontmatter declares allowed-tools restricts the agent to
+// those tools from the moment it is activated — by activate_skill or a
+// /skill: prompt — until the turn ends. With several restricting skills
+// active, a call must be allowed by every one of them.
+
+// skillScopeToolName is always callable so the model can still activate
+// further skills while restricted.
+const skillScopeToolName = "activate_skill"
+
+// activeSkillScope is one activated skill and its compiled allowed-tools.
+type activeSkillScope struct {
+	name         string
+	allowedTools string
+	policy       *permission.Policy // nil when the list failed to parse
+	err          error
+}
+
+// skillScope tracks the restricting skills active in the current turn.
+type skillScope struct {
+	mu      sync.Mutex
+	workDir string
+	events  *eventBus
+	active  []activeSkillScope
+}
+
+func newSkillScope(workDir string, events *eventBus) *skillScope {
+	return &skillScope{workDir: workDir, events: events}
+}
+
+// activate starts restricting tools to s's allowed-tools. Skills without an
+// allowed-tools list, and skills already active, are ignored. A list that
+// does not parse blocks every tool rather than none, so a typo in a
+// third-party skill fails closed.
+func (sc *skillScope) activate(s *skills.Skill) {
+	if sc == nil {
+		return
+	}
+	rules := s.AllowedToolRules()
+	if len(rules) == 0 {
+		return
+	}
+	sc.mu.Lock()
+	for _, a := range sc.active {
+		if a.name == s.Name {
+			sc.mu.Unlock()
+			return
+		}
+	}
+	policy, err := permission.New(permission.Config{Default: permission.Deny, Allow: rules})
+	if err != nil {
+		policy = nil
+	}
+	sc.active = append(sc.active, activeSkillScope{
+		name:         s.Name,
+		allowedTools: s.AllowedTools,
+		policy:       policy,
+		err:          err,
+	})
+	sc.mu.Unlock()
+
+	sc.events.emit(SkillScopeEvent{Skill: s.Name, AllowedTools: s.AllowedTools, Active: true})
+}
+
+// clear ends every active scope. It is called when the turn finishes.
+func (sc *skillScope) clear() {
+	if sc == nil {
+		return
+	}
+	sc.mu.Lock()
+	ended := sc.active
+	sc.active = nil
+	sc.mu.Unlock()
+
+	for _, a := range ended {
+		sc.events.emit(SkillScopeEvent{Skill: a.name, AllowedTools: a.allowedTools})
+	}
+}
+
+// check decides whether call may run under the active scopes. When it may
+// not, the returned reason names the skill and its allowed tools so the
+// model can pick another approach.
+func (sc *skillScope) check(toolName string, call LLMToolCall) (bool, string) {
+	if toolName == skillScopeToolName {
+		return true, ""
+	}
+	sc.mu.Lock()
+	active := append([]activeSkillScope(nil), sc.active...)
+	sc.mu.Unlock()
+
+	req := permission.Request{ToolName: toolName, Input: call.Input, WorkDir: sc.workDir}
+	for _, a := range active {
+		if a.policy == nil {
+			return false, fmt.Sprintf("tool %q is blocked while skill %q is active: its allowed-tools are invalid (%v)", toolName, a.name, a.err)
+		}
+		if a.policy.Evaluate(req) != permission.Allow {
+			return false, fmt.Sprintf("tool %q is not allowed while skill %q is active (allowed-tools: %s)", toolName, a.name, strings.TrimSpace(a.allowedTools))
+		}
+	}
+	return true, ""
+}
+
+// skillScopedTool wraps an AgentTool so each execution is checked against
+// the active skill scopes first.
+type skillScopedTool struct {
+	inner Tool
+	scope *skillScope
+}
+
+func (s *skillScopedTool) Info() LLMToolInfo                       { return s.inner.Info() }
+func (s *skillScopedTool) ProviderOptions() LLMProviderOptions     { return s.inner.ProviderOptions() }
+func (s *skillScopedTool) SetProviderOptions(o LLMProviderOptions) { s.inner.SetProviderOptions(o) }
+
+func (s *skillScopedTool) Run(ctx context.Context, call LLMToolCall) (LLMToolResponse, error) {
+	if ok, reason := s.scope.check(s.inner.Info().Name, call); !ok {
+		return newLLMTextErrorResponse(fmt.Sprintf("Error: %s", reason)), nil
+	}
+	return s.inner.Run(ctx, call)
+}
+
+// skillScopeToolWrapper returns a tool wrapper enforcing scope.
+func skillScopeToolWrapper(scope *skillScope) func([]Tool) []Tool {
+	return func(tools []Tool) []Tool {
+		wrapped := make([]Tool, len(tools))
+		for i, tool := range tools {
+			wrapped[i] = &skillScopedTool{inner: tool, scope: scope}
+		}
+		return wrapped
+	}
+}
+
+// activateToolSkill starts the scope of a skill the model loaded through
+// the activate_skill tool.
+func (m *Kit) activateToolSkill(e ToolResultEvent) {
+	if e.ToolName != skillScopeToolName || e.IsError {
+		return
+	}
+	name, _ := e.ParsedArgs["name"].(string)
+	name = strings.TrimSpace(name)
+	m.runtimeMu.RLock()
+	var found *skills.Skill
+	for _, s := range m.skills {
+		if s.Name == name {
+			found = s
+			break
+		}
+	}
+	m.runtimeMu.RUnlock()
+	if found != nil {
+		m.skillScope.activate(found)
+	}
+}
@@ -0,0 +1,91 @@
+package kit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/kit/internal/skills"
+)
+
+func runSkillScoped(t *testing.T, scope *skillScope, name, input string) (LLMToolResponse, bool) {
+	t.Helper()
+	ran := false
+	mock := &mockAgentTool{
+		name: name,
+		runFn: func(_ context.Context, _ LLMToolCall) (LLMToolResponse, error) {
+			ran = true
+			return newLLMTextResponse("ok"), nil
+		},
+	}
+	tools := skillScopeToolWrapper(scope)([]Tool{mock})
+	resp, err := tools[0].Run(context.Background(), LLMToolCall{ID: "call-1", Input: input})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return resp, ran
+}
+
+func TestSkillScope(t *testing.T) {
+	events := newEventBus()
+	var got []SkillScopeEvent
+	events.subscribe(func(e Event) {
+		if ev, ok := e.(SkillScopeEvent); ok {
+			got = append(got, ev)
+		}
+	})
+	scope := newSkillScope(t.TempDir(), events)
+
+	if _, ran := runSkillScoped(t, scope, "write", `{"path":"a.txt"}`); !ran {
+		t.Fatal("tool blocked with no active skill")
+	}
+
+	scope.activate(&skills.Skill{Name: "plain"})
+	scope.activate(&skills.Skill{Name: "git-helper", AllowedTools: "Bash(git:*) Read"})
+	scope.activate(&skills.Skill{Name: "git-helper", AllowedTools: "Bash(git:*) Read"})
+	if len(got) != 1 || !got[0].Active || got[0].Skill != "git-helper" {
+		t.Fatalf("activation events = %+v", got)
+	}
+
+	calls := []struct {
+		tool, input string
+		allowed     bool
+	}{
+		{"read", `{"path":"main.go"}`, true},
+		{"bash", `{"command":"git status"}`, true},
+		{"bash", `{"command":"git status && rm -rf ."}`, false},
+		{"write", `{"path":"a.txt"}`, false},
+		{"activate_skill", `{"name":"other"}`, true},
+	}
+	for _, c := range calls {
+		resp, ran := runSkillScoped(t, scope, c.tool, c.input)
+		if ran != c.allowed {
+			t.Errorf("%s %s ran = %v, want %v", c.tool, c.input, ran, c.allowed)
+		}
+		if !c.allowed && (!resp.IsError || !strings.Contains(resp.Content, `skill "git-helper"`)) {
+			t.Errorf("%s denial = %+v", c.tool, resp)
+		}
+	}
+
+	// A second skill narrows the scope further.
+	scope.activate(&skills.Skill{Name: "reader", AllowedTools: "Read"})
+	if _, ran := runSkillScoped(t, scope, "bash", `{"command":"git status"}`); ran {
+		t.Error("bash ran although the reader skill does not allow it")
+	}
+
+	scope.clear()
+	if len(got) != 4 || got[2].Active || got[3].Active {
+		t.Fatalf("events after clear = %+v", got)
+	}
+	if _, ran := runSkillScoped(t, scope, "write", `{"path":"a.txt"}`); !ran {
+		t.Error("tool blocked after the scope ended")
+	}
+}
+
+func TestSkillScope_InvalidRulesFailClosed(t *testing.T) {
+	scope := newSkillScope(t.TempDir(), newEventBus())
+	scope.activate(&skills.Skill{Name: "broken", AllowedTools: "Bash(git"})
+	if _, ran := runSkillScoped(t, scope, "read", `{"path":"a"}`); ran {
+		t.Error("tool ran under a skill with unparseable allowed-tools")
+	}
+}
@@ -166,7 +166,9 @@ func (m *Kit) ClearSkillCache() {
 // is recomposed and applied to the running agent so subsequent turns see the
 // new skill set.
 func (m *Kit) ReloadSkills() error {
-	newSkills, err := loadSkills(m.opts)
+	opts := *m.opts
+	opts.UntrustedProject = opts.UntrustedProject || m.v.GetBool("untrusted-project")
+	newSkills, err := loadSkills(&opts)
 	if err != nil {
 		return fmt.Errorf("reloading skills: %w", err)
 	}
@@ -373,7 +375,8 @@ func (m *Kit) setSkillModelInvocation(name string, disabled bool) bool {
 // Project-skill trust gate (Issue #65, gap #8)
 // ---------------------------------------------------------------------------
 
-// TrustDecision is the outcome of a project-skill trust prompt.
+// TrustDecision is the outcome of a project-skill or project-extension trust
+// prompt.
 type TrustDecision = trust.Decision
 
 // Trust-prompt outcomes. They mirror the trust package decisions.
@@ -387,29 +390,17 @@ const (
 )
 
 // projectSkillsTrusted decides whether project-local skills discovered in dir
-// should be loaded. When no SkillTrustPrompt is configured the directory is
+// should be loaded. An untrusted project never loads them. When no
+// SkillTrustPrompt is configured the directory is
 // trusted by default (preserving historical behaviour). Otherwise a persisted
 // allowlist is consulted first, then the prompt is invoked for an unknown
 // directory and the decision is persisted when the user chooses TrustProject.
 func projectSkillsTrusted(opts *Options, dir string, count int) bool {
-	if opts.SkillTrustPrompt == nil {
-		return true
-	}
-
-	store, err := trust.Load("")
-	if err == nil && store.IsTrusted(dir) {
-		return true
+	if opts.UntrustedProject {
+		return false
 	}
-
-	switch opts.SkillTrustPrompt(dir, count) {
-	case TrustProject:
-		if store != nil {
-			_ = store.Trust(dir)
-		}
-		return true
-	case TrustProjectOnce:
+	if opts.SkillTrustPrompt == nil {
 		return true
-	default:
-		return false
 	}
+	return trust.Gate("", opts.SkillTrustPrompt)(dir, count)
 }
@@ -0,0 +1,465 @@
+package kit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/kit/internal/core"
+)
+
+// defaultMaxParallelSubagents is how many subagents run at once when
+// neither Options.MaxParallelSubagents nor "max-parallel-subagents" is set.
+const defaultMaxParallelSubagents = 4
+
+// SubagentJobStatus is the state of a subagent started with
+// [Kit.StartSubagent].
+type SubagentJobStatus string
+
+// Subagent job states.
+const (
+	// SubagentJobQueued means the job waits for a free slot (see
+	// [Options.MaxParallelSubagents]).
+	SubagentJobQueued SubagentJobStatus = core.SubagentJobQueued
+	// SubagentJobRunning means the child Kit is working on the prompt.
+	SubagentJobRunning SubagentJobStatus = core.SubagentJobRunning
+	// SubagentJobCompleted means the child finished without error.
+	SubagentJobCompleted SubagentJobStatus = core.SubagentJobCompleted
+	// SubagentJobFailed means the child returned an error or timed out.
+	SubagentJobFailed SubagentJobStatus = core.SubagentJobFailed
+	// SubagentJobCancelled means the job was stopped with
+	// [Kit.CancelSubagent], its context was cancelled or the Kit was closed.
+	SubagentJobCancelled SubagentJobStatus = core.SubagentJobCancelled
+)
+
+// Finished reports whether s is a final state.
+func (s SubagentJobStatus) Finished() bool {
+	return s != SubagentJobQueued && s != SubagentJobRunning
+}
+
+// SubagentJob is a snapshot of a subagent running in the background, started
+// by the SDK with [Kit.StartSubagent] or by the model with the subagent
+// tool's run_in_background argument.
+type SubagentJob struct {
+	// ID identifies the job ("sub-1", "sub-2", ...).
+	ID string
+	// Prompt and Agent are the task the job was started with.
+	Prompt string
+	Agent  string
+	Status SubagentJobStatus
+	// StartedAt is when the job was submitted; EndedAt is zero until it
+	// finished.
+	StartedAt time.Time
+	EndedAt   time.Time
+	// Result is set once the job finished. It may be partial for failed
+	// jobs; its SessionID locates the child session, whose header links
+	// back to this Kit's session.
+	Result *SubagentResult
+	// Err is why a failed or cancelled job did not complete.
+	Err error
+}
+
+// Elapsed returns how long the job has run, or its total run time once it
+// finished.
+func (j SubagentJob) Elapsed() time.Duration {
+	if j.EndedAt.IsZero() {
+		return time.Since(j.StartedAt)
+	}
+	return j.EndedAt.Sub(j.StartedAt)
+}
+
+// subagentJob is the manager's mutable record behind a SubagentJob.
+type subagentJob struct {
+	SubagentJob
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// subagentJobs bounds how many subagents run at once and tracks the ones
+// started in the background.
+type subagentJobs struct {
+	slots chan struct{}
+
+	mu     sync.Mutex
+	jobs   map[string]*subagentJob
+	order  []string
+	nextID int
+	closed bool
+}
+
+func newSubagentJobs(limit int) *subagentJobs {
+	return &subagentJobs{
+		slots: make(chan struct{}, limit),
+		jobs:  make(map[string]*subagentJob),
+	}
+}
+
+// acquire takes a concurrency slot, waiting until one is free or ctx is
+// done. The returned function gives the slot back. A nil manager (a Kit not
+// built by New) imposes no limit.
+func (s *subagentJobs) acquire(ctx context.Context) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+	// Like Subagent's own pre-flight check, a context that is already done
+	// does not stop a synchronous subagent from starting.
+	if ctx.Err() != nil {
+		ctx = context.Background()
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return func() { <-s.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// start registers a job and runs it in a goroutine with run. The job is
+// bounded by ctx and stops when it is cancelled.
+func (s *subagentJobs) start(ctx context.Context, cfg SubagentConfig, run func(context.Context, SubagentConfig) (*SubagentResult, error)) (SubagentJob, error) {
+	if err := validateSubagentConfig(cfg); err != nil {
+		return SubagentJob{}, err
+	}
+	if s == nil {
+		return SubagentJob{}, errors.New("background subagents are not available")
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return SubagentJob{}, errors.New("kit is closed")
+	}
+	s.nextID++
+	jobCtx, cancel := context.WithCancel(ctx)
+	job := &subagentJob{
+		SubagentJob: SubagentJob{
+			ID:        fmt.Sprintf("sub-%d", s.nextID),
+			Prompt:    cfg.Prompt,
+			Agent:     cfg.Agent,
+			Status:    SubagentJobQueued,
+			StartedAt: time.Now(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	snapshot := job.SubagentJob
+	s.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		var (
+			result *SubagentResult
+			err    error
+		)
+		select {
+		case s.slots <- struct{}{}:
+			// Both cases may have been ready; a job cancelled while queued
+			// must not start.
+			if err = jobCtx.Err(); err == nil {
+				s.update(job, func(j *subagentJob) { j.Status = SubagentJobRunning })
+				result, err = run(jobCtx, cfg)
+			}
+			<-s.slots
+		case <-jobCtx.Done():
+			err = jobCtx.Err()
+		}
+		s.update(job, func(j *subagentJob) {
+			j.EndedAt = time.Now()
+			j.Result = result
+			j.Err = err
+			switch {
+			case err == nil:
+				j.Status = SubagentJobCompleted
+			case jobCtx.Err() != nil:
+				j.Status = SubagentJobCancelled
+			default:
+				j.Status = SubagentJobFailed
+			}
+		})
+		close(job.done)
+	}()
+	return snapshot, nil
+}
+
+func (s *subagentJobs) update(job *subagentJob, fn func(*subagentJob)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(job)
+}
+
+// get returns the jobs with the given IDs, or every job when ids is empty.
+func (s *subagentJobs) get(ids []string) ([]*subagentJob, error) {
+	if s == nil {
+		if len(ids) > 0 {
+			return nil, fmt.Errorf("no subagent job %q", ids[0])
+		}
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(ids) == 0 {
+		ids = s.order
+	}
+	out := make([]*subagentJob, 0, len(ids))
+	for _, id := range ids {
+		job, ok := s.jobs[id]
+		if !ok {
+			return nil, fmt.Errorf("no subagent job %q", id)
+		}
+		out = append(out, job)
+	}
+	return out, nil
+}
+
+func (s *subagentJobs) snapshot(jobs []*subagentJob) []SubagentJob {
+	if len(jobs) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]SubagentJob, len(jobs))
+	for i, job := range jobs {
+		out[i] = job.SubagentJob
+	}
+	return out
+}
+
+// wait blocks until the jobs finished or ctx is done and returns their
+// state either way, along with ctx's error in the latter case.
+func (s *subagentJobs) wait(ctx context.Context, ids []string) ([]SubagentJob, error) {
+	jobs, err := s.get(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		select {
+		case <-job.done:
+		case <-ctx.Done():
+			return s.snapshot(jobs), ctx.Err()
+		}
+	}
+	return s.snapshot(jobs), nil
+}
+
+// cancel stops the job and returns its final state.
+func (s *subagentJobs) cancel(id string) (SubagentJob, error) {
+	jobs, err := s.get([]string{id})
+	if err != nil {
+		return SubagentJob{}, err
+	}
+	jobs[0].cancel()
+	<-jobs[0].done
+	return s.snapshot(jobs)[0], nil
+}
+
+// cancelAll stops every job and refuses new ones, waiting for the jobs to
+// wind down until ctx is done.
+func (s *subagentJobs) cancelAll(ctx context.Context) {
+	s.mu.Lock()
+	s.closed = true
+	jobs := make([]*subagentJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mu.Unlock()
+	for _, job := range jobs {
+		job.cancel()
+	}
+	for _, job := range jobs {
+		select {
+		case <-job.done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// StartSubagent runs a subagent in the background and returns at once with
+// its job ID. ctx bounds the job: cancelling it stops the subagent, so pass a
+// context that outlives the call. The job waits for a free slot when
+// [Options.MaxParallelSubagents] subagents are already running, then behaves
+// like [Kit.Subagent] with cfg. Collect the result with [Kit.WaitSubagents].
+func (m *Kit) StartSubagent(ctx context.Context, cfg SubagentConfig) (SubagentJob, error) {
+	return m.subagents.start(ctx, cfg, m.runSubagent)
+}
+
+// RunSubagents runs every cfg concurrently, at most
+// [Options.MaxParallelSubagents] at a time, and waits for all of them. The
+// jobs are returned in the order of cfgs; a subagent that failed has its
+// error in SubagentJob.Err rather than failing the call. When ctx is done
+// first the remaining jobs are cancelled and ctx's error is returned.
+//
+//	jobs, err := host.RunSubagents(ctx,
+//	    kit.SubagentConfig{Agent: "security-reviewer", Prompt: diff},
+//	    kit.SubagentConfig{Agent: "style-reviewer", Prompt: diff},
+//	)
+//	usage, cost := kit.SubagentUsage(jobs)
+func (m *Kit) RunSubagents(ctx context.Context, cfgs ...SubagentConfig) ([]SubagentJob, error) {
+	for _, cfg := range cfgs {
+		if err := validateSubagentConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+	ids := make([]string, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		job, err := m.StartSubagent(ctx, cfg)
+		if err != nil {
+			for _, id := range ids {
+				_, _ = m.subagents.cancel(id)
+			}
+			return nil, err
+		}
+		ids = append(ids, job.ID)
+	}
+	jobs, err := m.subagents.wait(ctx, ids)
+	if err != nil {
+		for _, id := range ids {
+			_, _ = m.subagents.cancel(id)
+		}
+		jobs, _ = m.subagents.wait(context.Background(), ids)
+	}
+	return jobs, err
+}
+
+// SubagentJobs returns every background subagent of this Kit instance,
+// oldest first, including finished ones.
+func (m *Kit) SubagentJobs() []SubagentJob {
+	jobs, _ := m.subagents.get(nil)
+	return m.subagents.snapshot(jobs)
+}
+
+// GetSubagentJob returns the background subagent with the given ID.
+func (m *Kit) GetSubagentJob(id string) (SubagentJob, error) {
+	jobs, err := m.subagents.get([]string{id})
+	if err != nil {
+		return SubagentJob{}, err
+	}
+	return m.subagents.snapshot(jobs)[0], nil
+}
+
+// WaitSubagents blocks until the background subagents with the given IDs
+// (every one when ids is empty) finished, and returns their state. When ctx
+// is done first it returns their current state along with ctx's error; the
+// jobs keep running. An unknown ID is an error.
+func (m *Kit) WaitSubagents(ctx context.Context, ids ...string) ([]SubagentJob, error) {
+	return m.subagents.wait(ctx, ids)
+}
+
+// CancelSubagent stops the background subagent with the given ID and returns
+// its final state. Cancelling a job that already finished is not an error.
+func (m *Kit) CancelSubagent(id string) (SubagentJob, error) {
+	return m.subagents.cancel(id)
+}
+
+// SubagentUsage sums the token usage and cost of the jobs that produced a
+// result.
+func SubagentUsage(jobs []SubagentJob) (LLMUsage, float64) {
+	var (
+		total LLMUsage
+		cost  float64
+	)
+	for _, job := range jobs {
+		if job.Result == nil {
+			continue
+		}
+		cost += job.Result.CostUSD
+		if u := job.Result.Usage; u != nil {
+			total.InputTokens += u.InputTokens
+			total.OutputTokens += u.OutputTokens
+			total.TotalTokens += u.TotalTokens
+			total.ReasoningTokens += u.ReasoningTokens
+			total.CacheCreationTokens += u.CacheCreationTokens
+			total.CacheReadTokens += u.CacheReadTokens
+		}
+	}
+	return total, cost
+}
+
+// subagentToolJobs adapts the manager to core.SubagentJobs for the
+// subagent, subagent_wait and subagent_cancel tools.
+type subagentToolJobs struct {
+	kit *Kit
+
+	// run executes a job; nil runs it as Kit.StartSubagent does. Tests
+	// replace it to avoid a model.
+	run func(context.Context, SubagentConfig) (*SubagentResult, error)
+}
+
+func (t subagentToolJobs) Start(ctx context.Context, req core.SubagentSpawnRequest) (core.SubagentJobInfo, error) {
+	// The job outlives the tool call, so it reports nothing to the
+	// per-call listeners registered for it.
+	t.kit.cleanupSubagentListeners(req.ToolCallID)
+	run := t.run
+	if run == nil {
+		run = t.kit.runSubagent
+	}
+	// ctx is the turn's, which is cancelled when the turn ends. Only
+	// subagent_cancel and Close may stop a background job, so it keeps the
+	// turn's values but not its cancellation.
+	job, err := t.kit.subagents.start(context.WithoutCancel(ctx), SubagentConfig{
+		Prompt:       req.Prompt,
+		Agent:        req.Agent,
+		Model:        req.Model,
+		SystemPrompt: req.SystemPrompt,
+		Timeout:      req.Timeout,
+		SessionID:    req.SessionID,
+		Isolation:    req.Isolation,
+		Tools:        t.kit.GetToolsForSubagent(),
+	}, run)
+	if err != nil {
+		return core.SubagentJobInfo{}, err
+	}
+	return subagentJobInfo(job), nil
+}
+
+func (t subagentToolJobs) Wait(ctx context.Context, ids []string, timeout time.Duration) ([]core.SubagentJobInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	jobs, err := t.kit.WaitSubagents(ctx, ids...)
+	if jobs == nil {
+		return nil, err
+	}
+	// Running out of time is not an error: the model gets the status of the
+	// jobs still running and can wait again.
+	infos := make([]core.SubagentJobInfo, len(jobs))
+	for i, job := range jobs {
+		infos[i] = subagentJobInfo(job)
+	}
+	return infos, nil
+}
+
+func (t subagentToolJobs) Cancel(id string) (core.SubagentJobInfo, error) {
+	job, err := t.kit.CancelSubagent(id)
+	if err != nil {
+		return core.SubagentJobInfo{}, err
+	}
+	return subagentJobInfo(job), nil
+}
+
+func subagentJobInfo(job SubagentJob) core.SubagentJobInfo {
+	info := core.SubagentJobInfo{
+		ID:      job.ID,
+		Task:    job.Prompt,
+		Status:  string(job.Status),
+		Elapsed: job.Elapsed(),
+	}
+	if job.Status.Finished() {
+		info.Result = &core.SubagentSpawnResult{Error: job.Err, Elapsed: job.Elapsed()}
+		if r := job.Result; r != nil {
+			info.Result.Response = r.Response
+			info.Result.SessionID = r.SessionID
+			if r.Usage != nil {
+				info.Result.InputTokens = r.Usage.InputTokens
+				info.Result.OutputTokens = r.Usage.OutputTokens
+			}
+			if r.Worktree != nil {
+				info.Result.WorktreeBranch = r.Worktree.Branch
+				info.Result.WorktreeDiffStat = r.Worktree.DiffStat
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,158 @@
+package kit
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/kit/internal/agent"
+	"github.com/mark3labs/kit/internal/core"
+)
+
+func TestSubagentJobs_BoundsConcurrency(t *testing.T) {
+	s := newSubagentJobs(2)
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	run := func(ctx context.Context, cfg SubagentConfig) (*SubagentResult, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		return &SubagentResult{Response: cfg.Prompt, Usage: &LLMUsage{InputTokens: 10, OutputTokens: 1}, CostUSD: 0.5}, nil
+	}
+
+	var ids []string
+	for _, prompt := range []string{"a", "b", "c", "d"} {
+		job, err := s.start(context.Background(), SubagentConfig{Prompt: prompt}, run)
+		if err != nil {
+			t.Fatalf("start: %v", err)
+		}
+		if job.Status != SubagentJobQueued {
+			t.Errorf("new job status = %s, want queued", job.Status)
+		}
+		ids = append(ids, job.ID)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	jobs, err := s.wait(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if peak.Load() != 2 {
+		t.Errorf("peak concurrency = %d, want 2", peak.Load())
+	}
+	for i, job := range jobs {
+		if job.ID != ids[i] || job.Status != SubagentJobCompleted || job.Result.Response != job.Prompt {
+			t.Errorf("job %d = %+v, want %s completed", i, job, ids[i])
+		}
+	}
+	usage, cost := SubagentUsage(jobs)
+	if usage.InputTokens != 40 || usage.OutputTokens != 4 || cost != 2 {
+		t.Errorf("SubagentUsage = %+v, %v; want 40/4 tokens and $2", usage, cost)
+	}
+}
+
+func TestSubagentJobs_CancelAndFail(t *testing.T) {
+	s := newSubagentJobs(1)
+	block := func(ctx context.Context, cfg SubagentConfig) (*SubagentResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	fail := func(ctx context.Context, cfg SubagentConfig) (*SubagentResult, error) {
+		return nil, errors.New("boom")
+	}
+
+	a, _ := s.start(context.Background(), SubagentConfig{Prompt: "a"}, block)
+	b, _ := s.start(context.Background(), SubagentConfig{Prompt: "b"}, block)
+
+	// A short wait returns the current state with the context error. Only
+	// one job fits in the single slot; either may have taken it.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	jobs, err := s.wait(ctx, []string{a.ID, b.ID})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait err = %v, want deadline exceeded", err)
+	}
+	if jobs[0].Status == jobs[1].Status || jobs[0].Status.Finished() || jobs[1].Status.Finished() {
+		t.Errorf("statuses = %s, %s; want one running and one queued", jobs[0].Status, jobs[1].Status)
+	}
+
+	// Cancel the queued job first so it must not start once the slot frees.
+	queued, running := jobs[0], jobs[1]
+	if queued.Status != SubagentJobQueued {
+		queued, running = running, queued
+	}
+	for _, id := range []string{queued.ID, running.ID} {
+		job, err := s.cancel(id)
+		if err != nil || job.Status != SubagentJobCancelled || job.EndedAt.IsZero() {
+			t.Errorf("cancel(%s) = %+v, %v; want cancelled", id, job, err)
+		}
+	}
+
+	failed, _ := s.start(context.Background(), SubagentConfig{Prompt: "fails"}, fail)
+	jobs, _ = s.wait(context.Background(), []string{failed.ID})
+	if jobs[0].Status != SubagentJobFailed || jobs[0].Err == nil {
+		t.Errorf("failed job = %+v, want failed with its error", jobs[0])
+	}
+
+	if _, err := s.cancel("sub-99"); err == nil {
+		t.Error("cancel of an unknown job should fail")
+	}
+	if _, err := s.start(context.Background(), SubagentConfig{}, fail); err == nil {
+		t.Error("start without a prompt should fail")
+	}
+}
+
+func TestSubagentJobs_CancelAll(t *testing.T) {
+	s := newSubagentJobs(4)
+	block := func(ctx context.Context, cfg SubagentConfig) (*SubagentResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	job, _ := s.start(context.Background(), SubagentConfig{Prompt: "a"}, block)
+
+	s.cancelAll(context.Background())
+
+	jobs, _ := s.get([]string{job.ID})
+	if got := s.snapshot(jobs)[0].Status; got != SubagentJobCancelled {
+		t.Errorf("status after cancelAll = %s, want cancelled", got)
+	}
+	if _, err := s.start(context.Background(), SubagentConfig{Prompt: "b"}, block); err == nil {
+		t.Error("start after cancelAll should fail")
+	}
+}
+
+func TestSubagentToolJobs_OutliveTheTurn(t *testing.T) {
+	k := &Kit{agent: &agent.Agent{}, subagents: newSubagentJobs(2)}
+	release := make(chan struct{})
+	jobs := subagentToolJobs{kit: k, run: func(ctx context.Context, cfg SubagentConfig) (*SubagentResult, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-release:
+			return &SubagentResult{Response: "done"}, nil
+		}
+	}}
+
+	turnCtx, endTurn := context.WithCancelCause(context.Background())
+	info, err := jobs.Start(turnCtx, core.SubagentSpawnRequest{Prompt: "review", ToolCallID: "tc1"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	// runTurn cancels its context on return; the job must keep going.
+	endTurn(nil)
+	close(release)
+
+	infos, err := jobs.Wait(context.Background(), []string{info.ID}, time.Second)
+	if err != nil || len(infos) != 1 || infos[0].Status != core.SubagentJobCompleted {
+		t.Fatalf("after the turn ended: %+v, %v; want completed", infos, err)
+	}
+}
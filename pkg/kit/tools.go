@@ -34,6 +34,33 @@ var WithBashTimeout = core.WithBashTimeout
 // A non-positive duration leaves the built-in default (600s) in place.
 var WithBashMaxTimeout = core.WithBashMaxTimeout
 
+// WithBashExecutor sets the backend the bash tool runs commands with.
+// Nil keeps the host backend.
+var WithBashExecutor = core.WithBashExecutor
+
+// BashExecutor decides where bash tool commands run.
+type BashExecutor = core.BashExecutor
+
+// BashRunner is a BashExecutor that runs commands itself rather than
+// returning a local process, e.g. in an editor's terminal.
+type BashRunner = core.BashRunner
+
+// FileSystem is where the file tools get and put file contents.
+type FileSystem = core.FileSystem
+
+// WithFileSystem routes the file access of the file tools through fs. Nil
+// keeps the local disk.
+var WithFileSystem = core.WithFileSystem
+
+// SandboxConfig selects and configures the bash execution backend: "none"
+// (host), "bwrap", "docker" or "podman".
+type SandboxConfig = core.SandboxConfig
+
+// NewBashExecutor returns the executor selected by cfg, failing when the
+// backend is unknown or not installed. Pass it to [WithBashExecutor] when
+// building custom tool sets.
+var NewBashExecutor = core.NewBashExecutor
+
 // --- Core Tool Validation ---
 // processes a list of tool names, if disableCoreTools is true, return an
 // empty list. Otherwise if coreTools is not empty, it will return a list of
@@ -368,6 +395,10 @@ func NewWriteTool(opts ...ToolOption) Tool { return core.NewWriteTool(opts...) }
 // NewEditTool creates a surgical text-editing tool.
 func NewEditTool(opts ...ToolOption) Tool { return core.NewEditTool(opts...) }
 
+// NewApplyPatchTool creates a tool that applies unified diffs and
+// multi-file patches atomically.
+func NewApplyPatchTool(opts ...ToolOption) Tool { return core.NewApplyPatchTool(opts...) }
+
 // NewBashTool creates a bash command execution tool.
 func NewBashTool(opts ...ToolOption) Tool { return core.NewBashTool(opts...) }
 
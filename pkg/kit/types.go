@@ -54,6 +54,13 @@ type Finish = message.Finish
 // and session picker display.
 type SessionInfo = session.SessionInfo
 
+// SessionSearchResult is a session matching a SearchSessions query, with
+// the entries that matched.
+type SessionSearchResult = session.SearchResult
+
+// SessionSearchMatch is one matching entry of a SessionSearchResult.
+type SessionSearchMatch = session.SearchMatch
+
 // TreeManager manages a tree-structured JSONL session with branching,
 // leaf-pointer tracking, and context building.
 type TreeManager = session.TreeManager
@@ -74,6 +81,10 @@ type Config = config.Config
 // local (stdio) and remote (StreamableHTTP/SSE) server types.
 type MCPServerConfig = config.MCPServerConfig
 
+// MCPSamplingConfig controls whether an MCP server may request completions
+// from Kit's model, and how many tokens it may spend.
+type MCPSamplingConfig = config.MCPSamplingConfig
+
 // ==== Agent Types ====
 
 // DebugLogger is an SDK-owned interface for low-level debug logging from
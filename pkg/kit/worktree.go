@@ -0,0 +1,236 @@
+package kit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/kit/internal/agents"
+	"github.com/mark3labs/kit/internal/core"
+)
+
+// This file implements worktree isolation for subagents: the child works in
+// a temporary git worktree on its own branch, so concurrent subagents cannot
+// trample each other's edits and a failed experiment never dirties the
+// parent's checkout. When the child finishes its changes are committed on
+// the branch and the worktree directory is removed; the branch is handed
+// back for the caller to merge, keep or discard.
+
+// IsolationWorktree is the [SubagentConfig.Isolation] (and agent definition
+// "isolation:") value that runs a subagent in its own git worktree.
+const IsolationWorktree = agents.IsolationWorktree
+
+// worktreeBranchPrefix names the branches created for isolated subagents.
+const worktreeBranchPrefix = "kit/subagent-"
+
+// SubagentWorktree reports what a subagent run with worktree isolation
+// changed.
+type SubagentWorktree struct {
+	// Branch holds the subagent's changes on top of Base. Empty when the
+	// subagent changed nothing, in which case the branch was deleted.
+	Branch string
+	// Base is the commit the worktree was created from: the parent's HEAD
+	// at spawn time. Uncommitted changes in the parent's checkout are not
+	// part of it.
+	Base string
+	// Files lists the paths changed on Branch, relative to the repository
+	// root.
+	Files []string
+	// DiffStat is the `git diff --stat` summary of Branch against Base.
+	DiffStat string
+}
+
+// worktree is a git worktree created for one subagent.
+type worktree struct {
+	repo   string // root of the parent's repository
+	dir    string // root of the worktree
+	work   string // the child's working directory inside dir
+	branch string
+	base   string
+}
+
+// worktreeToolOptions returns the tool options isolated subagents' core
+// tools are rebuilt with, on top of their worktree working directory.
+func worktreeToolOptions(bashTimeout, bashMaxTimeout int, executor core.BashExecutor, jobs *core.JobManager) []core.ToolOption {
+	opts := []core.ToolOption{
+		core.WithBashTimeout(time.Duration(bashTimeout) * time.Second),
+		core.WithBashMaxTimeout(time.Duration(bashMaxTimeout) * time.Second),
+		core.WithJobManager(jobs),
+	}
+	if executor != nil {
+		opts = append(opts, core.WithBashExecutor(executor))
+	}
+	return opts
+}
+
+// subagentIsolation returns the isolation cfg asks for, falling back to its
+// named agent's definition.
+func (m *Kit) subagentIsolation(cfg SubagentConfig) string {
+	if cfg.Isolation != "" || cfg.Agent == "" {
+		return cfg.Isolation
+	}
+	if def, ok := m.GetAgent(cfg.Agent); ok {
+		return def.Isolation
+	}
+	return ""
+}
+
+// addWorktree creates a worktree of the parent's repository at its HEAD, on
+// a new branch. The child works in the same subdirectory the parent is in.
+func (m *Kit) addWorktree() (*worktree, error) {
+	cwd := m.checkpointWorkDir()
+	repo, err := runGit(cwd, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("worktree isolation needs a git repository: %w", err)
+	}
+	prefix, _ := runGit(cwd, "rev-parse", "--show-prefix")
+	base, err := runGit(repo, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("worktree isolation needs a commit to branch from: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "kit-worktree-")
+	if err != nil {
+		return nil, fmt.Errorf("create worktree: %w", err)
+	}
+	branch := worktreeBranchPrefix + strings.TrimPrefix(filepath.Base(dir), "kit-worktree-")
+
+	m.worktreeMu.Lock()
+	_, err = runGit(repo, "worktree", "add", "--quiet", "-b", branch, dir, base)
+	m.worktreeMu.Unlock()
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("create worktree: %w", err)
+	}
+	// The parent's directory has no tracked files, so the worktree lacks it.
+	work := filepath.Join(dir, filepath.FromSlash(prefix))
+	if _, err := os.Stat(work); err != nil {
+		work = dir
+	}
+	return &worktree{repo: repo, dir: dir, work: work, branch: branch, base: base}, nil
+}
+
+// finishWorktree commits everything the child left in the worktree, removes
+// the worktree directory and reports the changes on its branch. A branch
+// without changes is deleted.
+func (m *Kit) finishWorktree(wt *worktree, task string) (*SubagentWorktree, error) {
+	var commitErr error
+	if _, err := runGit(wt.dir, "add", "-A"); err != nil {
+		commitErr = err
+	} else if status, _ := runGit(wt.dir, "status", "--porcelain"); status != "" {
+		commitErr = gitCommit(wt.dir, "kit subagent: "+worktreeCommitSubject(task))
+	}
+	head, _ := runGit(wt.dir, "rev-parse", "HEAD")
+
+	m.worktreeMu.Lock()
+	_, removeErr := runGit(wt.repo, "worktree", "remove", "--force", wt.dir)
+	m.worktreeMu.Unlock()
+	if removeErr != nil {
+		_ = os.RemoveAll(wt.dir)
+		_, _ = runGit(wt.repo, "worktree", "prune")
+	}
+	if commitErr != nil {
+		// Keep the branch: whatever the child committed itself is still
+		// there.
+		return &SubagentWorktree{Branch: wt.branch, Base: wt.base}, fmt.Errorf("commit worktree changes: %w", commitErr)
+	}
+
+	result := &SubagentWorktree{Base: wt.base}
+	if head == "" || head == wt.base {
+		_, _ = runGit(wt.repo, "branch", "-D", wt.branch)
+		return result, nil
+	}
+	result.Branch = wt.branch
+	if names, err := runGit(wt.repo, "diff", "--name-only", wt.base, wt.branch); err == nil && names != "" {
+		result.Files = strings.Split(names, "\n")
+	}
+	result.DiffStat, _ = runGit(wt.repo, "diff", "--stat", wt.base, wt.branch)
+	return result, nil
+}
+
+// gitCommit commits the staged changes in dir. Hooks and signing are
+// skipped — this is bookkeeping, not the user's commit — and a placeholder
+// identity is used when none is configured.
+func gitCommit(dir, message string) error {
+	args := []string{"-c", "commit.gpgsign=false"}
+	if email, _ := runGit(dir, "config", "user.email"); email == "" {
+		args = append(args, "-c", "user.name=Kit", "-c", "user.email=kit@localhost")
+	}
+	_, err := runGit(dir, append(args, "commit", "--quiet", "--no-verify", "-m", message)...)
+	return err
+}
+
+// worktreeCommitSubject shortens a task to a commit subject line.
+func worktreeCommitSubject(task string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(task), "\n")
+	if runes := []rune(subject); len(runes) > 72 {
+		subject = strings.TrimSpace(string(runes[:69])) + "..."
+	}
+	return subject
+}
+
+// isolateTools rebinds the core tools in tools to dir. Language-server tools
+// are dropped because their servers index the parent's checkout; MCP and
+// extension tools are kept as they are.
+func isolateTools(tools []Tool, dir string, opts []core.ToolOption) []Tool {
+	coreNames := make(map[string]bool)
+	for _, name := range core.ListAllCoreToolNames() {
+		coreNames[name] = true
+	}
+	lspNames := make(map[string]bool)
+	for _, t := range core.LSPTools() {
+		lspNames[t.Info().Name] = true
+	}
+	opts = append(append([]core.ToolOption(nil), opts...), core.WithWorkDir(dir))
+
+	out := make([]Tool, 0, len(tools))
+	for _, t := range tools {
+		name := t.Info().Name
+		switch {
+		case lspNames[name]:
+		case coreNames[name]:
+			out = append(out, core.ListedTools([]string{name}, opts...)...)
+		default:
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// worktreePrompt tells an isolated subagent where it works.
+func worktreePrompt(wt *worktree) string {
+	return fmt.Sprintf("\n\nYou are working in an isolated git worktree at %s, on branch %s. "+
+		"Your changes are committed to that branch when you finish and handed back for review; "+
+		"they do not appear in the main checkout. Do not switch branches.", wt.work, wt.branch)
+}
+
+// MergeWorktree applies the changes on a branch returned in
+// [SubagentWorktree] to this instance's checkout as uncommitted, staged
+// changes (`git merge --squash`), then deletes the branch. A conflict is
+// returned as an error, with the conflict left in the checkout to resolve.
+func (m *Kit) MergeWorktree(branch string) error {
+	if !strings.HasPrefix(branch, worktreeBranchPrefix) {
+		return fmt.Errorf("%q is not a subagent worktree branch", branch)
+	}
+	dir := m.checkpointWorkDir()
+	if _, err := runGit(dir, "merge", "--squash", branch); err != nil {
+		return fmt.Errorf("merge %s: %w", branch, err)
+	}
+	if _, err := runGit(dir, "branch", "-D", branch); err != nil {
+		return fmt.Errorf("delete %s: %w", branch, err)
+	}
+	return nil
+}
+
+// DiscardWorktree deletes a branch returned in [SubagentWorktree] along with
+// the subagent's changes on it.
+func (m *Kit) DiscardWorktree(branch string) error {
+	if !strings.HasPrefix(branch, worktreeBranchPrefix) {
+		return fmt.Errorf("%q is not a subagent worktree branch", branch)
+	}
+	if _, err := runGit(m.checkpointWorkDir(), "branch", "-D", branch); err != nil {
+		return fmt.Errorf("delete %s: %w", branch, err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package kit
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/kit/internal/core"
+)
+
+// gitRepo creates a repository with one commit holding a.txt and returns a
+// Kit whose working directory is its sub/ directory.
+func gitRepo(t *testing.T) (*Kit, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"a.txt": "one\n", "sub/b.txt": "two\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		if _, err := runGit(dir, args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+	return &Kit{checkpoints: newCheckpointRecorder(false, false, filepath.Join(dir, "sub"))}, dir
+}
+
+func TestWorktree_CommitsChangesOnBranch(t *testing.T) {
+	k, repo := gitRepo(t)
+
+	wt, err := k.addWorktree()
+	if err != nil {
+		t.Fatalf("addWorktree: %v", err)
+	}
+	if filepath.Base(wt.work) != "sub" {
+		t.Errorf("work dir = %s, want the parent's subdirectory", wt.work)
+	}
+	if err := os.WriteFile(filepath.Join(wt.work, "b.txt"), []byte("changed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wt.work, "new.txt"), []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := k.finishWorktree(wt, "Rewrite b\nwith details")
+	if err != nil {
+		t.Fatalf("finishWorktree: %v", err)
+	}
+	if changes.Branch != wt.branch || !strings.HasPrefix(changes.Branch, worktreeBranchPrefix) {
+		t.Errorf("Branch = %q, want %q", changes.Branch, wt.branch)
+	}
+	if want := []string{"sub/b.txt", "sub/new.txt"}; !slices.Equal(changes.Files, want) {
+		t.Errorf("Files = %v, want %v", changes.Files, want)
+	}
+	if !strings.Contains(changes.DiffStat, "2 files changed") {
+		t.Errorf("DiffStat = %q", changes.DiffStat)
+	}
+	if _, err := os.Stat(wt.dir); !os.IsNotExist(err) {
+		t.Errorf("worktree directory still exists: %v", err)
+	}
+	if got := readFile(t, filepath.Join(repo, "sub", "b.txt")); got != "two\n" {
+		t.Errorf("parent checkout changed: b.txt = %q", got)
+	}
+	if subject, _ := runGit(repo, "log", "-1", "--format=%s", changes.Branch); subject != "kit subagent: Rewrite b" {
+		t.Errorf("commit subject = %q", subject)
+	}
+
+	if err := k.MergeWorktree(changes.Branch); err != nil {
+		t.Fatalf("MergeWorktree: %v", err)
+	}
+	if got := readFile(t, filepath.Join(repo, "sub", "b.txt")); got != "changed\n" {
+		t.Errorf("after merge b.txt = %q", got)
+	}
+	if _, err := runGit(repo, "rev-parse", "--verify", changes.Branch); err == nil {
+		t.Error("merged branch was not deleted")
+	}
+}
+
+func TestWorktree_NoChangesDeletesBranch(t *testing.T) {
+	k, repo := gitRepo(t)
+
+	wt, err := k.addWorktree()
+	if err != nil {
+		t.Fatalf("addWorktree: %v", err)
+	}
+	changes, err := k.finishWorktree(wt, "look around")
+	if err != nil {
+		t.Fatalf("finishWorktree: %v", err)
+	}
+	if changes.Branch != "" || changes.Base == "" {
+		t.Errorf("changes = %+v, want no branch", changes)
+	}
+	if _, err := runGit(repo, "rev-parse", "--verify", wt.branch); err == nil {
+		t.Error("unchanged branch was not deleted")
+	}
+}
+
+func TestWorktree_Discard(t *testing.T) {
+	k, repo := gitRepo(t)
+
+	wt, _ := k.addWorktree()
+	_ = os.WriteFile(filepath.Join(wt.dir, "a.txt"), []byte("gone\n"), 0o644)
+	changes, err := k.finishWorktree(wt, "experiment")
+	if err != nil || changes.Branch == "" {
+		t.Fatalf("finishWorktree = %+v, %v", changes, err)
+	}
+	if err := k.DiscardWorktree(changes.Branch); err != nil {
+		t.Fatalf("DiscardWorktree: %v", err)
+	}
+	if _, err := runGit(repo, "rev-parse", "--verify", changes.Branch); err == nil {
+		t.Error("discarded branch still exists")
+	}
+	if err := k.DiscardWorktree("main"); err == nil {
+		t.Error("DiscardWorktree should refuse branches it did not create")
+	}
+}
+
+func TestWorktree_RequiresRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	k := &Kit{checkpoints: newCheckpointRecorder(false, false, t.TempDir())}
+	if _, err := k.addWorktree(); err == nil || !strings.Contains(err.Error(), "git repository") {
+		t.Errorf("addWorktree outside a repository = %v", err)
+	}
+}
+
+func TestIsolateTools(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "f.txt"), []byte("isolated"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	custom := NewTool("custom", "custom tool", func(ctx context.Context, in struct{}) (ToolOutput, error) {
+		return TextResult("ok"), nil
+	})
+	tools := []Tool{core.NewReadTool(), custom, core.NewDefinitionTool()}
+
+	isolated := isolateTools(tools, dir, nil)
+
+	var names []string
+	for _, tool := range isolated {
+		names = append(names, tool.Info().Name)
+	}
+	if !slices.Equal(names, []string{"read", "custom"}) {
+		t.Fatalf("tools = %v, want read rebuilt, custom kept and definition dropped", names)
+	}
+	resp, err := isolated[0].Run(context.Background(), LLMToolCall{Input: `{"path":"f.txt"}`})
+	if err != nil || !strings.Contains(resp.Content, "isolated") {
+		t.Errorf("read f.txt = %q, %v; want it resolved in the worktree", resp.Content, err)
+	}
+}
+
+func TestNew_WorkDirRootsChild(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "AGENTS.md"), []byte("worktree rules"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := New(context.Background(), &Options{
+		Model:            "openai/gpt-4o-mini",
+		Quiet:            true,
+		NoSession:        true,
+		NoExtensions:     true,
+		DisableCoreTools: true,
+		SkipConfig:       true,
+		NoSkills:         true,
+		workDir:          dir,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer func() { _ = k.Close() }()
+
+	if got := k.checkpointWorkDir(); got != dir {
+		t.Errorf("checkpoint work dir = %q, want %q", got, dir)
+	}
+	if files := k.GetContextFiles(); len(files) != 1 || files[0].Path != filepath.Join(dir, "AGENTS.md") {
+		t.Errorf("context files = %v", contextPaths(files))
+	}
+	if prompt := k.composeSystemPrompt(""); !strings.Contains(prompt, "Current working directory: "+dir) {
+		t.Errorf("system prompt does not name the work dir:\n%s", prompt)
+	}
+}